@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -11,25 +14,42 @@ import (
 	"github.com/dbackup/backend-go/internal/database"
 	"github.com/dbackup/backend-go/internal/encryption"
 	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/metrics"
 	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/routes"
 	"github.com/dbackup/backend-go/internal/server"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/tracing"
 	"github.com/dbackup/backend-go/internal/validation"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/dbackup/backend-go/internal/workers"
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		fmt.Printf("Failed to load configuration: %v\n", err)
+		slog.Error("Failed to load configuration", "error", err)
 		os.Exit(1)
 	}
 
+	// Log structured records with the request, user and job they belong to
+	logger, err := logging.New(cfg.Log, os.Stdout)
+	if err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// Initialize database
 	if err := database.Initialize(cfg); err != nil {
-		fmt.Printf("Failed to initialize database: %v\n", err)
+		slog.Error("Failed to initialize database", "error", err)
 		os.Exit(1)
 	}
 
@@ -60,22 +80,51 @@ func main() {
 	shutdownManager := server.GetDefaultShutdownManager(e)
 	shutdownManager.SetTimeout(30 * time.Second)
 	shutdownManager.SetDatabase(database.GetDB())
+	shutdownManager.SetLogger(logging.PrintfLogger{Logger: logger.With("component", "shutdown")})
+
+	// Trace requests and the backups they queue
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Monitoring, "dbackup-api")
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	e.Use(tracing.Middleware("dbackup-api"))
+
+	// Expose metrics on their own port; outermost so panics are counted too
+	appMetrics := setupMetrics(cfg, shutdownManager)
+	e.Use(appMetrics.Middleware())
 
 	// Setup middleware
-	setupMiddleware(e, cfg, shutdownManager)
+	setupMiddleware(e, cfg, logger, shutdownManager)
+
+	// Score audit events for risk; suspicious ones are notified once the queue is set up
+	riskEngine := services.NewRiskEngine(cfg.Risk, services.NewGormAuditHistoryProvider(database.GetDB()), nil)
+	auditService := services.NewAuditService(database.GetDB(), riskEngine)
 
 	// Setup routes
-	setupRoutes(e, jwtManager, passwordHasher, totpManager, encryptionService)
+	setupRoutes(e, cfg, jwtManager, passwordHasher, totpManager, encryptionService, auditService)
+
+	// Setup WebSocket events shared by all API nodes
+	wsService := setupWebSocket(e, cfg, jwtManager, shutdownManager, appMetrics)
+
+	// Setup health checks of the dependencies, storage and backup workers
+	healthHandler := setupHealth(e, cfg, encryptionService, shutdownManager)
+
+	// Setup backups, queue administration, webhooks and the queue stats stream
+	setupQueues(e, cfg, jwtManager, encryptionService, auditService, riskEngine, wsService, shutdownManager, appMetrics, healthHandler)
 
 	// Add custom shutdown hook for cleaning up temporary files
 	shutdownManager.AddShutdownHook(server.CleanupTempFilesHook("/tmp/dbackup"))
 
+	// Flush traces last, once nothing records spans any more
+	shutdownManager.AddShutdownHook(shutdownTracing)
+
 	// Start server
 	go func() {
 		addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
-		fmt.Printf("🚀 Server starting on %s\n", addr)
+		slog.Info("Server starting", "addr", addr)
 		if err := e.Start(addr); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("Server failed to start: %v\n", err)
+			slog.Error("Server failed to start", "error", err)
 			os.Exit(1)
 		}
 	}()
@@ -84,19 +133,16 @@ func main() {
 	shutdownManager.WaitForShutdown()
 }
 
-func setupMiddleware(e *echo.Echo, cfg *config.Config, shutdownManager *server.ShutdownManager) {
+func setupMiddleware(e *echo.Echo, cfg *config.Config, logger *slog.Logger, shutdownManager *server.ShutdownManager) {
+	// Request ID middleware, first so every log record of the request has it
+	e.Use(echoMiddleware.RequestID())
+
 	// Logger middleware
-	e.Use(echoMiddleware.LoggerWithConfig(echoMiddleware.LoggerConfig{
-		Format: `{"time":"${time_rfc3339}","method":"${method}","uri":"${uri}","status":${status},"error":"${error}","latency_human":"${latency_human}","bytes_in":${bytes_in},"bytes_out":${bytes_out}}` + "\n",
-		CustomTimeFormat: "2006-01-02T15:04:05.000Z07:00",
-	}))
+	e.Use(middleware.RequestLogger(logger))
 
 	// Recover middleware
 	e.Use(echoMiddleware.Recover())
 
-	// Request ID middleware
-	e.Use(echoMiddleware.RequestID())
-
 	// Timeout middleware
 	e.Use(echoMiddleware.TimeoutWithConfig(echoMiddleware.TimeoutConfig{
 		Timeout: 30 * time.Second,
@@ -113,12 +159,12 @@ func setupMiddleware(e *echo.Echo, cfg *config.Config, shutdownManager *server.S
 	e.Use(middleware.ShutdownMiddleware(shutdownManager))
 }
 
-func setupRoutes(e *echo.Echo, jm *auth.JWTManager, ph *auth.PasswordHasher, tm *auth.TOTPManager, encService *encryption.Service) {
+func setupRoutes(e *echo.Echo, cfg *config.Config, jm *auth.JWTManager, ph *auth.PasswordHasher, tm *auth.TOTPManager, encService *encryption.Service, auditService services.AuditServiceInterface) {
 	// Public routes (no authentication required)
 	e.GET("/health", handlers.HealthCheck)
 
 	// Setup authentication routes (handles its own auth logic)
-	routes.SetupAuthRoutes(e, jm, ph, tm)
+	routes.SetupAuthRoutes(e, jm, ph, tm, auditService)
 
 	// Protected API group (requires authentication)
 	api := e.Group("/api", middleware.CookieJWT(jm))
@@ -136,5 +182,167 @@ func setupRoutes(e *echo.Echo, jm *auth.JWTManager, ph *auth.PasswordHasher, tm
 
 	// Setup database routes (authentication handled by route setup)
 	db := database.GetDB()
-	routes.SetupDatabaseRoutes(e, db, jm, encService)
-}
\ No newline at end of file
+	routes.SetupDatabaseRoutes(e, db, jm, encService, auditService)
+	storageFactory := services.NewStorageFactory(db, encService).WithLocalStorageRoots(cfg.Backup.LocalStorageRoots)
+	routes.SetupStorageRoutes(e, db, jm, encService, storageFactory)
+	routes.SetupBackupFileRoutes(e, db, jm, storageFactory, services.NewBackupCipher(encService), auditService, cfg.Backup.DownloadURLExpiry)
+
+	// Events are delivered by cmd/worker; the API only sends test messages
+	routes.SetupNotificationRoutes(e, db, jm, encService, services.NewNotificationService(db, encService, nil, cfg.Notification))
+}
+func setupWebSocket(e *echo.Echo, cfg *config.Config, jm *auth.JWTManager, shutdownManager *server.ShutdownManager, appMetrics *metrics.Metrics) *websocket.WebSocketService {
+	db := database.GetDB()
+	opts := []websocket.ServiceOption{
+		websocket.WithTeamResolver(teamResolver(db)),
+		websocket.WithTopicAuthorizer(services.NewTopicAuthorizer(db)),
+		websocket.WithSessionValidator(sessionValidator(db)),
+		websocket.WithSessionCheckInterval(cfg.WebSocket.SessionCheckInterval),
+		websocket.WithAllowedOrigins(cfg.CORS.AllowedOrigins),
+		websocket.WithMaxConnectionsPerUser(cfg.WebSocket.MaxConnectionsPerUser),
+	}
+
+	// Fan events out through Redis so clients receive them whichever node they are connected to
+	redisOpts, err := cfg.Redis.Options()
+	if err != nil {
+		slog.Error("Invalid Redis URL, WebSocket events stay on this node", "error", err)
+	} else {
+		bus := websocket.NewRedisEventBus(redis.NewClient(redisOpts), cfg.WebSocket.RedisPrefix, cfg.WebSocket.ReplaySize, cfg.WebSocket.ReplayTTL)
+		opts = append(opts, websocket.WithEventBus(bus))
+	}
+
+	wsService := websocket.NewWebSocketService(jm, opts...)
+	shutdownManager.SetWebSocketHub(wsService.Hub())
+	appMetrics.RegisterWebSocketConnections(wsService.GetConnectionCount)
+
+	// The upgrade authenticates with the access token cookie or a bearer subprotocol
+	wsHandler := handlers.NewWebSocketHandler(wsService)
+	e.GET("/api/ws", wsHandler.HandleWebSocketConnection)
+	e.GET("/api/ws/schema", wsHandler.GetProtocolSchema)
+	return wsService
+}
+
+func setupQueues(e *echo.Echo, cfg *config.Config, jm *auth.JWTManager, encService *encryption.Service, auditService services.AuditServiceInterface, riskEngine *services.RiskEngine, wsService *websocket.WebSocketService, shutdownManager *server.ShutdownManager, appMetrics *metrics.Metrics, healthHandler *handlers.HealthHandler) {
+	redisOpts, err := cfg.Redis.Options()
+	if err != nil {
+		slog.Error("Invalid Redis URL, backups and queue administration are disabled", "error", err)
+		return
+	}
+
+	queueService, err := services.NewQueueService(&services.QueueConfig{
+		RedisAddr:     redisOpts.Addr,
+		RedisPassword: redisOpts.Password,
+		RedisDB:       redisOpts.DB,
+	})
+	if err != nil {
+		slog.Error("Failed to create queue service, backups and queue administration are disabled", "error", err)
+		return
+	}
+	routes.SetupQueueRoutes(e, jm, queueService, auditService)
+
+	// Suspicious activity is notified through the queue and delivered by cmd/worker
+	riskEngine.SetNotifier(services.NewNotificationService(database.GetDB(), encService, queueService, cfg.Notification))
+	appMetrics.RegisterQueueStats(queueService)
+	healthHandler.SetQueues(queueService, cfg.Monitoring.HealthQueueBacklog)
+
+	// Webhook redeliveries are queued here and sent by cmd/worker
+	routes.SetupWebhookRoutes(e, database.GetDB(), jm, encService, services.NewWebhookService(database.GetDB(), encService, queueService, cfg.Webhook))
+
+	// Backups are queued here and run by cmd/worker
+	redisClient := redis.NewClient(redisOpts)
+	setupBackups(e, cfg, jm, queueService, wsService, redisClient, auditService)
+
+	publisher := workers.NewQueueStatsPublisher(queueService, wsService, workers.DefaultQueueStatsInterval)
+	publisher.Start()
+	shutdownManager.AddShutdownHook(func(ctx context.Context) error {
+		if err := publisher.Stop(ctx); err != nil {
+			return err
+		}
+		return errors.Join(queueService.Close(), redisClient.Close())
+	})
+}
+
+func setupBackups(e *echo.Echo, cfg *config.Config, jm *auth.JWTManager, queueService *services.QueueService, wsService *websocket.WebSocketService, redisClient redis.UniversalClient, auditService services.AuditServiceInterface) {
+	backupService, err := services.NewBackupService()
+	if err != nil {
+		slog.Error("Failed to create backup service, backups are disabled", "error", err)
+		return
+	}
+
+	// The API only queues backups, so it needs neither storage nor concurrency limits
+	db := database.GetDB()
+	backupWorker := workers.NewBackupWorker(db, backupService, nil, queueService, wsService)
+	backupWorker.SetScheduler(services.NewBackupScheduler(db, services.NewRedisFairClock(redisClient, ""), services.BackupSchedulingConfig{
+		Slot:  cfg.Backup.FairShareSlot,
+		Burst: cfg.Backup.FairShareBurst,
+	}))
+	routes.SetupBackupRoutes(e, db, jm, backupService, queueService, backupWorker, auditService)
+}
+
+// setupHealth registers the health endpoints. Readiness fails without the
+// database or Redis, and reports degraded when storage, the backup tools,
+// the workers or the queues are in trouble.
+func setupHealth(e *echo.Echo, cfg *config.Config, encService *encryption.Service, shutdownManager *server.ShutdownManager) *handlers.HealthHandler {
+	db := database.GetDB()
+
+	var redisClient *redis.Client
+	if redisOpts, err := cfg.Redis.Options(); err == nil {
+		redisClient = redis.NewClient(redisOpts)
+		shutdownManager.AddShutdownHook(func(ctx context.Context) error {
+			return redisClient.Close()
+		})
+	}
+
+	healthHandler := handlers.NewHealthHandler(db, redisClient)
+	healthHandler.SetStorage(services.NewStorageFactory(db, encService).WithLocalStorageRoots(cfg.Backup.LocalStorageRoots), cfg.Monitoring.HealthStorageUID, cfg.Monitoring.HealthCacheTTL)
+	if backupService, err := services.NewBackupService(); err == nil {
+		healthHandler.SetBackupTools(backupService, cfg.Monitoring.HealthCacheTTL)
+	}
+	if redisClient != nil {
+		healthHandler.SetWorkers(services.NewRedisWorkerStatusStore(redisClient, ""))
+	}
+	healthHandler.RegisterRoutes(e)
+	return healthHandler
+}
+
+// setupMetrics serves metrics on the metrics port when enabled, and returns
+// nil otherwise, which records nothing
+func setupMetrics(cfg *config.Config, shutdownManager *server.ShutdownManager) *metrics.Metrics {
+	if !cfg.Monitoring.MetricsEnabled {
+		return nil
+	}
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterDatabaseStats(database.GetStats)
+	metricsServer := appMetrics.Serve(cfg.Monitoring.MetricsPort)
+	shutdownManager.AddShutdownHook(metricsServer.Shutdown)
+	slog.Info("Metrics served", "port", cfg.Monitoring.MetricsPort, "path", "/metrics")
+	return appMetrics
+}
+
+// teamResolver looks up the active team memberships of WebSocket users
+func teamResolver(db *gorm.DB) websocket.TeamResolver {
+	return func(ctx context.Context, userID uint) ([]uint, error) {
+		var teamIDs []uint
+		err := db.WithContext(ctx).Model(&models.TeamMember{}).
+			Where("user_id = ? AND is_active = ?", userID, true).
+			Pluck("team_id", &teamIDs).Error
+		return teamIDs, err
+	}
+}
+
+// sessionValidator ends the WebSocket sessions of deleted, deactivated and locked users
+func sessionValidator(db *gorm.DB) websocket.SessionValidator {
+	return func(ctx context.Context, claims *auth.Claims) error {
+		var user models.User
+		if err := db.WithContext(ctx).First(&user, claims.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return websocket.ErrSessionRevoked
+			}
+			return err
+		}
+		if !user.CanLogin() {
+			return websocket.ErrSessionRevoked
+		}
+		return nil
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/client"
+)
+
+func runLogin(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("login", "")
+	email := fs.String("email", "", "Account email, prompted for when not given")
+	remember := fs.Bool("remember", false, "Stay logged in for 30 days")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(false); err != nil {
+		return err
+	}
+
+	req := client.LoginRequest{Email: *email, RememberMe: *remember}
+	var err error
+	if req.Email == "" {
+		if req.Email, err = c.prompt("Email: "); err != nil {
+			return err
+		}
+	}
+	if req.Password, err = c.promptSecret("Password: "); err != nil {
+		return err
+	}
+
+	user, err := c.api.Login(ctx, req)
+	if errors.Is(err, client.ErrTwoFactorRequired) {
+		code, promptErr := c.promptSecret("Two-factor code or backup code: ")
+		if promptErr != nil {
+			return promptErr
+		}
+		if isTOTPCode(code) {
+			req.TOTPCode = code
+		} else {
+			req.BackupCode = code
+		}
+		user, err = c.api.Login(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := saveSession(c.sessionPath, &session{Server: c.server, Tokens: c.api.Tokens()}); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Logged in to %s as %s\n", c.server, user.Email)
+	return nil
+}
+
+// isTOTPCode checks if a code is a six digit TOTP code rather than a backup code
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func runLogout(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("logout", "")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := removeSession(path); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stderr, "Logged out")
+	return nil
+}
+
+func runWhoami(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("whoami", "")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	user, err := c.api.Session(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printer().print(user, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "Server:\t%s\n", c.server)
+		fmt.Fprintf(tw, "Email:\t%s\n", user.Email)
+		fmt.Fprintf(tw, "Name:\t%s\n", strings.TrimSpace(user.FirstName+" "+user.LastName))
+		fmt.Fprintf(tw, "Two-factor:\t%t\n", user.TwoFactorEnabled)
+	})
+}
+
+func runDatabases(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("databases", "")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	databases, err := c.api.ListDatabases(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printer().databases(databases)
+}
+
+func runBackups(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("backups", "")
+	var opts client.ListBackupsOptions
+	fs.StringVar(&opts.DatabaseUID, "database", "", "Only backups of this database connection UID")
+	fs.StringVar(&opts.Status, "status", "", "Only backups with this status")
+	fs.IntVar(&opts.Page, "page", 1, "Page to list")
+	fs.IntVar(&opts.Limit, "limit", 20, "Backups per page")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	list, err := c.api.ListBackups(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return c.printer().backups(list)
+}
+
+func runBackup(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("backup", "")
+	req := client.CreateBackupRequest{Type: "full"}
+	fs.StringVar(&req.DatabaseUID, "database", "", "UID of the database connection to back up (required)")
+	fs.StringVar(&req.Name, "name", "", "Backup name (default: cli- and the current time)")
+	fs.StringVar(&req.Type, "type", req.Type, "Backup type")
+	fs.StringVar(&req.StorageUID, "storage", "", "UID of the storage configuration (default: the default storage)")
+	fs.IntVar(&req.Priority, "priority", 0, "Priority from 1 to 10")
+	wait := fs.Bool("wait", false, "Follow the progress of the backup until it ends")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if req.DatabaseUID == "" {
+		fs.Usage()
+		return errors.New("-database is required")
+	}
+	if req.Name == "" {
+		req.Name = "cli-" + time.Now().Format("20060102-150405")
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	// Connected first so no event of the new job is missed
+	var watcher *client.Watcher
+	if *wait {
+		var err error
+		if watcher, err = c.api.Watch(ctx); err != nil {
+			return err
+		}
+		defer watcher.Close()
+	}
+
+	backup, err := c.api.CreateBackup(ctx, req)
+	if err != nil {
+		return err
+	}
+	if watcher == nil {
+		return c.printer().backup(backup)
+	}
+
+	fmt.Fprintf(c.stderr, "Backup %s queued\n", backup.UID)
+	return c.follow(ctx, watcher, backup.UID, client.JobTypeBackup)
+}
+
+func runShow(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("show", "BACKUP_UID")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	backup, err := c.api.GetBackup(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return c.printer().backup(backup)
+}
+
+func runFiles(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("files", "BACKUP_UID")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	files, err := c.api.ListFiles(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return c.printer().files(files)
+}
+
+func runDownload(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("download", "BACKUP_UID")
+	fileUID := fs.String("file", "", "UID of the file to download (default: the latest file)")
+	output := fs.String("o", "", "Where to write the file, - for stdout (default: the file name)")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	file, err := c.findFile(ctx, positional[0], *fileUID)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err := c.api.Download(ctx, file, c.stdout)
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Base(file.Name)
+	}
+
+	// Written next to its destination and only moved there once verified
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := c.api.Download(ctx, file, tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if file.Checksum == "" {
+		fmt.Fprintf(c.stderr, "Downloaded %s to %s (%s, no checksum to verify)\n", file.UID, path, size(&n))
+	} else {
+		fmt.Fprintf(c.stderr, "Downloaded %s to %s (%s, checksum verified)\n", file.UID, path, size(&n))
+	}
+	return nil
+}
+
+func runRestore(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("restore", "BACKUP_UID")
+	req := client.RestoreRequest{Options: &client.RestoreOptions{}}
+	fs.StringVar(&req.BackupFileUID, "file", "", "UID of the file to restore (default: the latest file)")
+	fs.BoolVar(&req.Options.CleanFirst, "clean", false, "Drop objects before recreating them")
+	fs.BoolVar(&req.Options.DropExisting, "drop-existing", false, "Drop existing objects before the restore")
+	fs.BoolVar(&req.Options.CreateDatabase, "create-database", false, "Create the database if it does not exist")
+	fs.IntVar(&req.Options.Jobs, "jobs", 0, "Parallel jobs of PostgreSQL restores")
+	fs.BoolVar(&req.Options.Force, "force", false, "Continue MySQL restores on errors")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	wait := fs.Bool("wait", false, "Follow the progress of the restore until it ends")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.connect(true); err != nil {
+		return err
+	}
+
+	backup, err := c.api.GetBackup(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		target := "its database"
+		if backup.Database != nil {
+			target = fmt.Sprintf("database %s (%s)", backup.Database.Name, backup.Database.Database)
+		}
+		ok, err := c.confirm(fmt.Sprintf("Restore backup %s into %s? Existing data may be overwritten.", backup.Name, target))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("restore cancelled")
+		}
+	}
+
+	// Connected first so no event of the restore is missed
+	var watcher *client.Watcher
+	if *wait {
+		if watcher, err = c.api.Watch(ctx); err != nil {
+			return err
+		}
+		defer watcher.Close()
+	}
+
+	if backup, err = c.api.Restore(ctx, backup.UID, req); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Restore of backup %s queued\n", backup.UID)
+	if watcher == nil {
+		return nil
+	}
+	return c.follow(ctx, watcher, backup.UID, client.JobTypeRestore)
+}
+
+// findFile finds a file of a backup, or its latest file
+func (c *cli) findFile(ctx context.Context, backupUID, fileUID string) (*client.BackupFile, error) {
+	files, err := c.api.ListFiles(ctx, backupUID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if fileUID == "" || files[i].UID == fileUID {
+			return &files[i], nil
+		}
+	}
+	if fileUID != "" {
+		return nil, fmt.Errorf("backup %s has no file %s", backupUID, fileUID)
+	}
+	return nil, fmt.Errorf("backup %s has no files", backupUID)
+}
+
+// follow prints the progress of a job until it ends, then the job. A job
+// that did not complete is returned as an error.
+func (c *cli) follow(ctx context.Context, watcher *client.Watcher, jobUID, jobType string) error {
+	progress := c.progress()
+	final, err := watcher.Wait(ctx, jobUID, jobType, progress.event)
+	if err != nil {
+		return err
+	}
+
+	backup, err := c.api.GetBackup(ctx, jobUID)
+	if err != nil {
+		return err
+	}
+	if err := c.printer().backup(backup); err != nil {
+		return err
+	}
+
+	if final.Status != "completed" {
+		if final.ErrorMessage != "" {
+			return fmt.Errorf("%s %s: %s", jobType, final.Status, final.ErrorMessage)
+		}
+		return fmt.Errorf("%s %s", jobType, final.Status)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Command dbackup is a command-line client of the dbackup API. It logs in
+// like the web UI and keeps the session in the user's config directory, or
+// uses the access token given with -token or DBACKUP_TOKEN.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/dbackup/backend-go/internal/client"
+	"golang.org/x/term"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// command is a subcommand of the CLI
+type command struct {
+	run     func(ctx context.Context, c *cli, args []string) error
+	summary string
+}
+
+var commands = map[string]command{
+	"login":     {runLogin, "Log in and store the session"},
+	"logout":    {runLogout, "Forget the stored session"},
+	"whoami":    {runWhoami, "Show the logged in user"},
+	"databases": {runDatabases, "List database connections"},
+	"backups":   {runBackups, "List backups"},
+	"backup":    {runBackup, "Start a backup of a database"},
+	"show":      {runShow, "Show a backup"},
+	"files":     {runFiles, "List the files of a backup"},
+	"download":  {runDownload, "Download a backup file and verify its checksum"},
+	"restore":   {runRestore, "Restore a backup into its database"},
+}
+
+// commandOrder is the order commands are listed in the usage
+var commandOrder = []string{"login", "logout", "whoami", "databases", "backups", "backup", "show", "files", "download", "restore"}
+
+// cli holds the options and I/O shared by commands
+type cli struct {
+	server string
+	token  string
+	output string
+
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	sessionPath string
+	api         *client.Client
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &cli{
+		server: os.Getenv("DBACKUP_URL"),
+		token:  os.Getenv("DBACKUP_TOKEN"),
+		output: outputTable,
+		stdin:  bufio.NewReader(os.Stdin),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	flag.Usage = printUsage
+	c.registerFlags(flag.CommandLine)
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(ctx, c, flag.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: dbackup [OPTIONS] COMMAND [ARGS]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(out, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Options, also accepted after the command:")
+	fmt.Fprintln(out, "  -server URL      API server (default: $DBACKUP_URL, the logged in server or "+defaultServer+")")
+	fmt.Fprintln(out, "  -token TOKEN     Access token to use instead of the stored session (default: $DBACKUP_TOKEN)")
+	fmt.Fprintln(out, "  -output FORMAT   Output format, table or json (default: table)")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run 'dbackup COMMAND -h' for the options of a command.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Examples:")
+	fmt.Fprintln(out, "  dbackup login -server https://dbackup.example.com -email me@example.com")
+	fmt.Fprintln(out, "  dbackup backup -database 5f0c... -wait")
+	fmt.Fprintln(out, "  dbackup download -o orders.dump 9b1e...")
+	fmt.Fprintln(out, "  dbackup backups -status failed -output json")
+}
+
+// registerFlags adds the options every command accepts
+func (c *cli) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.server, "server", c.server, "API server URL")
+	fs.StringVar(&c.token, "token", c.token, "Access token to use instead of the stored session")
+	fs.StringVar(&c.output, "output", c.output, "Output format, table or json")
+}
+
+// flags creates the flag set of a command
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: dbackup %s [OPTIONS] %s\n\nOptions:\n", name, args)
+		fs.PrintDefaults()
+	}
+	c.registerFlags(fs)
+	return fs
+}
+
+// parse parses the options of a command, which may come before or after
+// its arguments, and returns the arguments
+func (c *cli) parse(fs *flag.FlagSet, args []string, nargs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) != nargs {
+		fs.Usage()
+		return nil, flag.ErrHelp
+	}
+	if c.output != outputTable && c.output != outputJSON {
+		return nil, fmt.Errorf("unknown output format %q", c.output)
+	}
+	return positional, nil
+}
+
+// printer returns the printer of command results
+func (c *cli) printer() *printer {
+	return &printer{w: c.stdout, format: c.output}
+}
+
+// progress returns the printer of job progress, which goes to stderr so
+// results on stdout stay parseable
+func (c *cli) progress() *printer {
+	return &printer{w: c.stderr, format: c.output}
+}
+
+// connect creates the API client. The server and token given as options
+// take precedence over the stored session.
+func (c *cli) connect(requireAuth bool) error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	c.sessionPath = path
+
+	stored, err := loadSession(path)
+	if err != nil {
+		return err
+	}
+
+	server := c.server
+	if server == "" && stored != nil {
+		server = stored.Server
+	}
+	if server == "" {
+		server = defaultServer
+	}
+
+	var tokens client.Tokens
+	opts := []client.Option{}
+	switch {
+	case c.token != "":
+		tokens.AccessToken = c.token
+	case stored != nil && sameServer(stored.Server, server):
+		tokens = stored.Tokens
+		opts = append(opts, client.WithTokenRefresh(func(refreshed client.Tokens) {
+			if err := saveSession(path, &session{Server: stored.Server, Tokens: refreshed}); err != nil {
+				fmt.Fprintf(c.stderr, "Warning: %v\n", err)
+			}
+		}))
+	}
+
+	if requireAuth && tokens.AccessToken == "" {
+		return fmt.Errorf("not logged in to %s, run 'dbackup login' or set DBACKUP_TOKEN", server)
+	}
+
+	api, err := client.New(server, append(opts, client.WithTokens(tokens))...)
+	if err != nil {
+		return err
+	}
+	c.server = server
+	c.api = api
+	return nil
+}
+
+// sameServer compares server URLs, ignoring a trailing slash
+func sameServer(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// prompt asks for a line of input
+func (c *cli) prompt(label string) (string, error) {
+	fmt.Fprint(c.stderr, label)
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read %s", strings.TrimSuffix(strings.ToLower(label), ": "))
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptSecret asks for input without echoing it on a terminal
+func (c *cli) promptSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return c.prompt(label)
+	}
+
+	fmt.Fprint(c.stderr, label)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(c.stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", strings.TrimSuffix(strings.ToLower(label), ": "), err)
+	}
+	return string(secret), nil
+}
+
+// confirm asks a yes or no question, defaulting to no
+func (c *cli) confirm(question string) (bool, error) {
+	answer, err := c.prompt(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
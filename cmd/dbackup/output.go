@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/client"
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes command results as tables or JSON
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print writes v as JSON, or as the table made by table
+func (p *printer) print(v interface{}, table func(*tabwriter.Writer)) error {
+	if p.format == outputJSON {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+func (p *printer) databases(databases []client.Database) error {
+	return p.print(databases, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "UID\tNAME\tTYPE\tHOST\tDATABASE\tHEALTH")
+		for _, db := range databases {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s:%d\t%s\t%s\n", db.UID, db.Name, db.Type, db.Host, db.Port, db.Database, db.HealthStatus)
+		}
+	})
+}
+
+func (p *printer) backups(list *client.BackupList) error {
+	return p.print(list, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "UID\tNAME\tDATABASE\tSTATUS\tSIZE\tCREATED")
+		for _, backup := range list.Backups {
+			database := "-"
+			if backup.Database != nil {
+				database = backup.Database.Name
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", backup.UID, backup.Name, database, backup.Status, size(backupSize(&backup)), formatTime(&backup.CreatedAt))
+		}
+		fmt.Fprintf(tw, "\nPage %d of %d, %d backups\n", list.Page, list.TotalPages, list.Total)
+	})
+}
+
+func (p *printer) backup(backup *client.Backup) error {
+	return p.print(backup, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "UID:\t%s\n", backup.UID)
+		fmt.Fprintf(tw, "Name:\t%s\n", backup.Name)
+		if backup.Database != nil {
+			fmt.Fprintf(tw, "Database:\t%s (%s)\n", backup.Database.Name, backup.Database.UID)
+		}
+		fmt.Fprintf(tw, "Type:\t%s\n", backup.Type)
+		fmt.Fprintf(tw, "Status:\t%s\n", backup.Status)
+		if backup.Status == "running" {
+			fmt.Fprintf(tw, "Progress:\t%.0f%% %s\n", backup.Progress, backup.ProgressMessage)
+		}
+		if backup.ErrorMessage != "" {
+			fmt.Fprintf(tw, "Error:\t%s\n", backup.ErrorMessage)
+		}
+		fmt.Fprintf(tw, "Size:\t%s\n", size(backupSize(backup)))
+		fmt.Fprintf(tw, "Created:\t%s\n", formatTime(&backup.CreatedAt))
+		fmt.Fprintf(tw, "Completed:\t%s\n", formatTime(backup.CompletedAt))
+		fmt.Fprintf(tw, "Files:\t%d\n", len(backup.Files))
+	})
+}
+
+func (p *printer) files(files []client.BackupFile) error {
+	return p.print(files, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "UID\tNAME\tSIZE\tENCRYPTED\tCREATED\tEXPIRES")
+		for _, file := range files {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s\n", file.UID, file.Name, size(file.Size), file.IsEncrypted, formatTime(&file.CreatedAt), formatTime(file.ExpiresAt))
+		}
+	})
+}
+
+// event writes a progress line of a followed job
+func (p *printer) event(event *client.JobEvent) {
+	if p.format == outputJSON {
+		json.NewEncoder(p.w).Encode(event)
+		return
+	}
+
+	line := fmt.Sprintf("%-10s %3.0f%%", event.Status, event.Progress)
+	if event.Type == client.EventJobLifecycle {
+		line = event.Status
+	}
+	if message := strings.TrimSpace(event.Message + " " + event.ErrorMessage); message != "" {
+		line += "  " + message
+	}
+	fmt.Fprintln(p.w, line)
+}
+
+// backupSize is the stored size of a backup
+func backupSize(backup *client.Backup) *int64 {
+	if backup.CompressedSize != nil {
+		return backup.CompressedSize
+	}
+	return backup.OriginalSize
+}
+
+func size(bytes *int64) string {
+	if bytes == nil {
+		return "-"
+	}
+	return models.FormatBytes(*bytes)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
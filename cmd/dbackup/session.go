@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dbackup/backend-go/internal/client"
+)
+
+// session is a login stored between runs
+type session struct {
+	Server string `json:"server"`
+	client.Tokens
+}
+
+// sessionPath returns where the session is stored, DBACKUP_SESSION or
+// dbackup/session.json in the user's config directory
+func sessionPath() (string, error) {
+	if path := os.Getenv("DBACKUP_SESSION"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "dbackup", "session.json"), nil
+}
+
+// loadSession reads the stored session. Without one it returns nil.
+func loadSession(path string) (*session, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// saveSession stores a session readable only by the user
+func saveSession(path string, s *session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// removeSession forgets the stored session
+func removeSession(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
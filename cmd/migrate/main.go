@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/dbackup/backend-go/internal/config"
 	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/migrations"
 )
 
 var (
@@ -19,17 +22,23 @@ var (
 	migrationsDir = flag.String("migrations", "migrations", "Migrations directory path")
 	targetVersion = flag.String("version", "", "Target migration version")
 	migrationName = flag.String("name", "", "Migration name for create command")
+	steps         = flag.Int("steps", 1, "Number of migrations to roll back with down")
+	dryRun        = flag.Bool("dry-run", false, "Print the SQL that would run without running it")
 )
 
 func main() {
 	flag.Parse()
 
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	command := os.Args[len(os.Args)-1]
+	// Options may also follow the command
+	command := flag.Arg(0)
+	if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+		os.Exit(1)
+	}
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -38,6 +47,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Progress of the migration system goes to stderr, results to stdout
+	logger, err := logging.New(cfg.Log, os.Stderr)
+	if err != nil {
+		fmt.Printf("Error setting up logging: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// Initialize database
 	err = database.Initialize(cfg)
 	if err != nil {
@@ -57,17 +74,27 @@ func main() {
 
 	// Initialize migration system
 	migrationSystem := database.NewMigrationSystem(db, absDir)
+	migrationSystem.SetLogger(logging.PrintfLogger{Logger: logger.With("component", "migrations")})
 	if err := migrationSystem.Initialize(); err != nil {
 		fmt.Printf("Error initializing migration system: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Load migrations from directory
-	if err := migrationSystem.LoadMigrationsFromDir(); err != nil {
+	// Use the migrations built into the binary unless a directory is given
+	if flagSet("migrations") {
+		err = migrationSystem.LoadMigrationsFromDir()
+	} else {
+		err = migrationSystem.LoadMigrationsFromFS(migrations.FS)
+	}
+	if err != nil {
 		fmt.Printf("Error loading migrations: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *dryRun {
+		migrationSystem.SetDryRun(os.Stdout)
+	}
+
 	// Register built-in model migrations
 	if err := registerBuiltinMigrations(migrationSystem); err != nil {
 		fmt.Printf("Error registering builtin migrations: %v\n", err)
@@ -81,22 +108,51 @@ func main() {
 			fmt.Printf("Error running migrations up: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("Migrations completed successfully")
+		printDone("Migrations completed successfully")
 
 	case "down":
-		if err := migrationSystem.Down(*targetVersion); err != nil {
+		if *targetVersion != "" {
+			err = migrationSystem.Down(*targetVersion)
+		} else {
+			err = migrationSystem.Rollback(*steps)
+		}
+		if err != nil {
 			fmt.Printf("Error running migrations down: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("Rollback completed successfully")
+		printDone("Rollback completed successfully")
+
+	case "to-version":
+		if *targetVersion == "" {
+			fmt.Println("Target version is required for to-version command")
+			printUsage()
+			os.Exit(1)
+		}
+		if err := migrationSystem.MigrateTo(*targetVersion); err != nil {
+			fmt.Printf("Error migrating to version %s: %v\n", *targetVersion, err)
+			os.Exit(1)
+		}
+		printDone(fmt.Sprintf("Database is at version %s", *targetVersion))
 
 	case "status":
-		migrations, err := migrationSystem.Status()
+		reports, err := migrationSystem.Report()
 		if err != nil {
 			fmt.Printf("Error getting migration status: %v\n", err)
 			os.Exit(1)
 		}
-		printMigrationStatus(migrations)
+		printMigrationStatus(reports)
+
+	case "verify":
+		drifted, err := migrationSystem.Verify()
+		if err != nil {
+			fmt.Printf("Error verifying migrations: %v\n", err)
+			os.Exit(1)
+		}
+		if len(drifted) > 0 {
+			fmt.Printf("Applied migrations changed since they ran: %s\n", strings.Join(drifted, ", "))
+			os.Exit(1)
+		}
+		fmt.Println("All applied migrations match their checksums")
 
 	case "create":
 		if *migrationName == "" {
@@ -148,63 +204,91 @@ func printUsage() {
 	fmt.Println("Usage: migrate [OPTIONS] COMMAND")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  up        Run pending migrations")
-	fmt.Println("  down      Rollback migrations")
-	fmt.Println("  status    Show migration status")
-	fmt.Println("  create    Create a new migration")
-	fmt.Println("  reset     Reset database (rollback all migrations)")
-	fmt.Println("  refresh   Reset and rerun all migrations")
-	fmt.Println("  version   Show current migration version")
+	fmt.Println("  up          Run pending migrations")
+	fmt.Println("  down        Rollback the last migrations, or those after -version")
+	fmt.Println("  to-version  Run or rollback migrations until -version is the latest applied")
+	fmt.Println("  status      Show migration status")
+	fmt.Println("  verify      Check that applied migrations were not changed")
+	fmt.Println("  create      Create a new migration")
+	fmt.Println("  reset       Reset database (rollback all migrations)")
+	fmt.Println("  refresh     Reset and rerun all migrations")
+	fmt.Println("  version     Show current migration version")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -config string     Configuration file path (default: config.yaml)")
-	fmt.Println("  -migrations string Migrations directory path (default: migrations)")
+	fmt.Println("  -migrations string Load migrations from a directory instead of the embedded ones")
 	fmt.Println("  -version string    Target migration version")
+	fmt.Println("  -steps int         Number of migrations to roll back with down (default: 1)")
+	fmt.Println("  -dry-run           Print the SQL that would run without running it")
 	fmt.Println("  -name string       Migration name for create command")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  migrate up")
-	fmt.Println("  migrate down -version=20231201120000")
+	fmt.Println("  migrate up -dry-run")
+	fmt.Println("  migrate down -steps=2")
+	fmt.Println("  migrate to-version -version=20231201120000")
 	fmt.Println("  migrate create -name=\"add user table\"")
 	fmt.Println("  migrate status")
 }
 
-func printMigrationStatus(migrations []*models.Migration) {
-	if len(migrations) == 0 {
+// flagSet checks if an option was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// printDone reports success unless the command only printed a dry run
+func printDone(message string) {
+	if !*dryRun {
+		fmt.Println(message)
+	}
+}
+
+func printMigrationStatus(reports []*database.MigrationReport) {
+	if len(reports) == 0 {
 		fmt.Println("No migrations found")
 		return
 	}
 
-	fmt.Printf("%-14s %-10s %-30s %-20s %-15s\n", "VERSION", "STATUS", "NAME", "APPLIED AT", "EXECUTION TIME")
+	fmt.Printf("%-14s %-10s %-30s %-20s %-10s\n", "VERSION", "STATUS", "NAME", "APPLIED AT", "CHECKSUM")
 	fmt.Println(strings.Repeat("-", 90))
 
-	for _, migration := range migrations {
+	for _, report := range reports {
 		appliedAt := "-"
-		executionTime := "-"
-
-		if migration.AppliedAt != nil {
-			appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+		if report.AppliedAt != nil {
+			appliedAt = report.AppliedAt.Format("2006-01-02 15:04:05")
 		}
 
-		if migration.ExecutionTime > 0 {
-			executionTime = fmt.Sprintf("%dms", migration.ExecutionTime)
+		checksum := "ok"
+		switch {
+		case report.Missing:
+			checksum = "missing"
+		case report.Drifted:
+			checksum = "CHANGED"
+		case report.Status != models.MigrationStatusApplied:
+			checksum = "-"
 		}
 
-		name := migration.Name
+		name := report.Name
 		if len(name) > 30 {
 			name = name[:27] + "..."
 		}
 
-		fmt.Printf("%-14s %-10s %-30s %-20s %-15s\n",
-			migration.Version,
-			string(migration.Status),
+		fmt.Printf("%-14s %-10s %-30s %-20s %-10s\n",
+			report.Version,
+			string(report.Status),
 			name,
 			appliedAt,
-			executionTime,
+			checksum,
 		)
 
-		if migration.ErrorMessage != "" {
-			fmt.Printf("    Error: %s\n", migration.ErrorMessage)
+		if report.Error != "" {
+			fmt.Printf("    Error: %s\n", report.Error)
 		}
 	}
 }
@@ -236,5 +320,15 @@ func registerBuiltinMigrations(migrationSystem *database.MigrationSystem) error
 		},
 	}
 
-	return migrationSystem.RegisterMigration(autoMigration)
+	if err := migrationSystem.RegisterMigration(autoMigration); err != nil {
+		return err
+	}
+
+	// The core migration runs only once, so later model changes have their own
+	for _, migration := range modelMigrations {
+		if err := migrationSystem.RegisterMigration(migration); err != nil {
+			return err
+		}
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// modelMigrations bring databases created by the core migration up to date
+// with the models. Each one adds only what it names and skips what is already
+// there, since new databases get the current models from the core migration.
+var modelMigrations = []*database.MigrationDefinition{
+	{
+		Version:     "20240201000001",
+		Name:        "Add storage configuration to backup files",
+		Description: "Record the storage configuration each backup file was uploaded to",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.BackupFile{}, "StorageConfigurationID")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.BackupFile{}, "StorageConfigurationID")
+		},
+	},
+	{
+		Version:     "20240201000002",
+		Name:        "Add backup file replicas",
+		Description: "Track the copies of backup files on replica storage",
+		Up: func(db *gorm.DB) error {
+			if err := createTables(db, &models.BackupFileLocation{}); err != nil {
+				return err
+			}
+			return addColumns(db, &models.DatabaseConnection{}, "ReplicaStorageUIDs")
+		},
+		Down: func(db *gorm.DB) error {
+			if err := dropColumns(db, &models.DatabaseConnection{}, "ReplicaStorageUIDs"); err != nil {
+				return err
+			}
+			return dropTables(db, &models.BackupFileLocation{})
+		},
+	},
+	{
+		Version:     "20240201000003",
+		Name:        "Add SFTP host keys",
+		Description: "Store the known hosts and host key fingerprint SFTP storage is verified against",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.StorageConfiguration{}, "KnownHosts", "HostKeyFingerprint")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.StorageConfiguration{}, "KnownHosts", "HostKeyFingerprint")
+		},
+	},
+	{
+		Version:     "20240201000004",
+		Name:        "Add PII detection",
+		Description: "Catalogue database columns with the personal data found in them",
+		Up: func(db *gorm.DB) error {
+			if err := createTables(db, &models.DatabaseColumn{}); err != nil {
+				return err
+			}
+			return addColumns(db, &models.DatabaseConnection{}, "RequirePIIEncryption")
+		},
+		Down: func(db *gorm.DB) error {
+			if err := dropColumns(db, &models.DatabaseConnection{}, "RequirePIIEncryption"); err != nil {
+				return err
+			}
+			return dropTables(db, &models.DatabaseColumn{})
+		},
+	},
+	{
+		Version:     "20240201000005",
+		Name:        "Add backup job queues",
+		Description: "Record the priority lane and queue task of each backup job",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.BackupJob{}, "QueueName", "QueueTaskID")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.BackupJob{}, "QueueName", "QueueTaskID")
+		},
+	},
+	{
+		Version:     "20240201000006",
+		Name:        "Add health checks",
+		Description: "Record health checks of database connections and storage configurations",
+		Up: func(db *gorm.DB) error {
+			if err := createTables(db, &models.HealthCheck{}); err != nil {
+				return err
+			}
+			if err := addColumns(db, &models.DatabaseConnection{}, "HealthStatus", "ConsecutiveFailures", "NextHealthCheckAt"); err != nil {
+				return err
+			}
+			return addColumns(db, &models.StorageConfiguration{}, "HealthStatus", "ConsecutiveFailures", "NextHealthCheckAt")
+		},
+		Down: func(db *gorm.DB) error {
+			if err := dropColumns(db, &models.StorageConfiguration{}, "HealthStatus", "ConsecutiveFailures", "NextHealthCheckAt"); err != nil {
+				return err
+			}
+			if err := dropColumns(db, &models.DatabaseConnection{}, "HealthStatus", "ConsecutiveFailures", "NextHealthCheckAt"); err != nil {
+				return err
+			}
+			return dropTables(db, &models.HealthCheck{})
+		},
+	},
+	{
+		Version:     "20240201000007",
+		Name:        "Add notifications",
+		Description: "Add notification channels, subscriptions and deliveries, and storage quotas",
+		Up: func(db *gorm.DB) error {
+			if err := createTables(db, &models.NotificationChannel{}, &models.NotificationSubscription{}, &models.NotificationDelivery{}); err != nil {
+				return err
+			}
+			return addColumns(db, &models.StorageConfiguration{}, "QuotaBytes")
+		},
+		Down: func(db *gorm.DB) error {
+			if err := dropColumns(db, &models.StorageConfiguration{}, "QuotaBytes"); err != nil {
+				return err
+			}
+			return dropTables(db, &models.NotificationDelivery{}, &models.NotificationSubscription{}, &models.NotificationChannel{})
+		},
+	},
+	{
+		Version:     "20240201000008",
+		Name:        "Add team webhooks",
+		Description: "Add team webhook endpoints and their deliveries",
+		Up: func(db *gorm.DB) error {
+			return createTables(db, &models.WebhookEndpoint{}, &models.WebhookDelivery{})
+		},
+		Down: func(db *gorm.DB) error {
+			return dropTables(db, &models.WebhookDelivery{}, &models.WebhookEndpoint{})
+		},
+	},
+	{
+		Version:     "20240201000009",
+		Name:        "Add storage configuration to backup jobs",
+		Description: "Record the storage configuration each backup job uploads to, for retries",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.BackupJob{}, "StorageConfigurationID")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.BackupJob{}, "StorageConfigurationID")
+		},
+	},
+	{
+		Version:     "20240201000010",
+		Name:        "Add replica storage to backup jobs",
+		Description: "Record the replica storage requested for each backup job, for retries",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.BackupJob{}, "ReplicaStorageUIDs")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.BackupJob{}, "ReplicaStorageUIDs")
+		},
+	},
+	{
+		Version:     "20240201000011",
+		Name:        "Add options to backup jobs",
+		Description: "Record the requested backup and sanitized export options of each backup job, for retries",
+		Up: func(db *gorm.DB) error {
+			return addColumns(db, &models.BackupJob{}, "Options")
+		},
+		Down: func(db *gorm.DB) error {
+			return dropColumns(db, &models.BackupJob{}, "Options")
+		},
+	},
+	{
+		Version:     "20240201000012",
+		Name:        "Add restore jobs",
+		Description: "Track each restore of a backup in its own record, apart from the backup job's status",
+		Up: func(db *gorm.DB) error {
+			return createTables(db, &models.RestoreJob{})
+		},
+		Down: func(db *gorm.DB) error {
+			return dropTables(db, &models.RestoreJob{})
+		},
+	},
+}
+
+// createTables creates the tables of the given models that do not exist yet
+func createTables(db *gorm.DB, tables ...interface{}) error {
+	migrator := db.Migrator()
+
+	for _, table := range tables {
+		if migrator.HasTable(table) {
+			continue
+		}
+		if err := migrator.CreateTable(table); err != nil {
+			return fmt.Errorf("failed to create table for %T: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// dropTables drops the tables of the given models
+func dropTables(db *gorm.DB, tables ...interface{}) error {
+	if err := db.Migrator().DropTable(tables...); err != nil {
+		return fmt.Errorf("failed to drop tables: %w", err)
+	}
+	return nil
+}
+
+// addColumns adds the columns of the given model fields that the table does
+// not have yet, together with the indexes declared on them
+func addColumns(db *gorm.DB, model interface{}, fields ...string) error {
+	migrator := db.Migrator()
+
+	for _, field := range fields {
+		if !migrator.HasColumn(model, field) {
+			if err := migrator.AddColumn(model, field); err != nil {
+				return fmt.Errorf("failed to add column for %s: %w", field, err)
+			}
+		}
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		if stmt.Schema.LookIndex(field) == nil || migrator.HasIndex(model, field) {
+			continue
+		}
+		if err := migrator.CreateIndex(model, field); err != nil {
+			return fmt.Errorf("failed to create index for %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// dropColumns drops the columns of the given model fields that the table has
+func dropColumns(db *gorm.DB, model interface{}, fields ...string) error {
+	migrator := db.Migrator()
+
+	for _, field := range fields {
+		if !migrator.HasColumn(model, field) {
+			continue
+		}
+		if err := migrator.DropColumn(model, field); err != nil {
+			return fmt.Errorf("failed to drop column for %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
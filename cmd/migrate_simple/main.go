@@ -25,17 +25,27 @@ func main() {
 	// Get database instance
 	db := database.GetDB()
 
-	// Run AutoMigrate for essential models first (excluding BackupFile due to foreign key issue)
+	// Run AutoMigrate for essential models
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.Team{},
 		&models.TeamMember{},
 		&models.DatabaseConnection{},
 		&models.DatabaseTable{},
+		&models.DatabaseColumn{},
 		&models.StorageConfiguration{},
 		&models.BackupJob{},
+		&models.BackupFile{},
+		&models.BackupFileLocation{},
+		&models.RestoreJob{},
 		&models.TablePermission{},
 		&models.AuditLog{},
+		&models.HealthCheck{},
+		&models.NotificationChannel{},
+		&models.NotificationSubscription{},
+		&models.NotificationDelivery{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
 	)
 	if err != nil {
 		log.Fatalf("Error running migrations: %v", err)
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/metrics"
+	"github.com/dbackup/backend-go/internal/server"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/dbackup/backend-go/internal/workers"
+	"github.com/redis/go-redis/v9"
+)
+
+// shutdownGrace is what the shutdown gets on top of the time running backups
+// get to finish, to checkpoint them and close connections
+const shutdownGrace = 30 * time.Second
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Log structured records with the job and task they belong to
+	logger, err := logging.New(cfg.Log, os.Stdout)
+	if err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Initialize database
+	if err := database.Initialize(cfg); err != nil {
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
+	}
+	db := database.GetDB()
+
+	// Continue the traces of the requests that queued the jobs
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Monitoring, "dbackup-worker")
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	// Refuse to take jobs that would fail for lack of the dump tools
+	backupService, err := services.NewBackupService()
+	if err != nil {
+		slog.Error("Failed to create backup service", "error", err)
+		os.Exit(1)
+	}
+	if err := backupService.ValidateBackupTools(); err != nil {
+		slog.Error("Backup tools are not available", "error", err)
+		os.Exit(1)
+	}
+
+	redisOpts, err := cfg.Redis.Options()
+	if err != nil {
+		slog.Error("Invalid Redis URL", "error", err)
+		os.Exit(1)
+	}
+	redisClient := redis.NewClient(redisOpts)
+
+	queueConfig := &services.QueueConfig{
+		RedisAddr:       redisOpts.Addr,
+		RedisPassword:   redisOpts.Password,
+		RedisDB:         redisOpts.DB,
+		Concurrency:     cfg.Backup.MaxConcurrent,
+		Queues:          workers.Queues(),
+		ShutdownTimeout: cfg.Backup.WorkerShutdownTimeout,
+		RetryDelays: map[string]func(int, error) time.Duration{
+			services.WebhookTaskType: services.WebhookRetryDelay,
+		},
+	}
+	queueService, err := services.NewQueueService(queueConfig)
+	if err != nil {
+		slog.Error("Failed to create queue service", "error", err)
+		os.Exit(1)
+	}
+
+	// Progress reaches users connected to any API node through Redis
+	bus := websocket.NewRedisEventBus(redis.NewClient(redisOpts), cfg.WebSocket.RedisPrefix, cfg.WebSocket.ReplaySize, cfg.WebSocket.ReplayTTL)
+	wsService := websocket.NewWebSocketService(nil, websocket.WithEventBus(bus))
+
+	encryptionService := encryption.NewService(cfg.Encryption.MasterKey)
+	storageFactory := services.NewStorageFactory(db, encryptionService).WithLocalStorageRoots(cfg.Backup.LocalStorageRoots)
+	backupWorker := workers.NewBackupWorker(db, backupService, storageFactory, queueService, wsService)
+	backupWorker.SetBackupCipher(services.NewBackupCipher(encryptionService))
+	backupWorker.SetConcurrency(services.NewBackupConcurrency(db, services.NewRedisSemaphore(redisClient, ""), services.BackupConcurrencyLimits{
+		Global:        cfg.Backup.MaxConcurrent,
+		PerConnection: cfg.Backup.MaxConcurrentPerConnection,
+		PerHost:       cfg.Backup.MaxConcurrentPerHost,
+		PerUser:       cfg.Backup.MaxConcurrentPerUser,
+		RetryDelay:    cfg.Backup.ConcurrencyRetryDelay,
+	}))
+	backupWorker.SetScheduler(services.NewBackupScheduler(db, services.NewRedisFairClock(redisClient, ""), services.BackupSchedulingConfig{
+		Slot:  cfg.Backup.FairShareSlot,
+		Burst: cfg.Backup.FairShareBurst,
+	}))
+
+	// Notifications are queued per subscribed channel and retried with backoff
+	notificationService := services.NewNotificationService(db, encryptionService, queueService, cfg.Notification)
+	backupWorker.SetNotifier(notificationService)
+
+	// Team webhooks get every lifecycle event at least once, retried with exponential backoff
+	webhookService := services.NewWebhookService(db, encryptionService, queueService, cfg.Webhook)
+	backupWorker.SetWebhooks(webhookService)
+
+	queueWorker := services.NewQueueWorker(queueConfig)
+	backupWorker.RegisterHandlers(queueWorker)
+	workers.NewNotificationWorker(notificationService).RegisterHandlers(queueWorker)
+	workers.NewWebhookWorker(webhookService).RegisterHandlers(queueWorker)
+
+	var workerMetrics *metrics.Metrics
+	if cfg.Monitoring.MetricsEnabled {
+		workerMetrics = metrics.New()
+		workerMetrics.RegisterDatabaseStats(database.GetStats)
+		backupWorker.SetMetrics(workerMetrics)
+	}
+
+	// Re-test connections and storage in the background, pausing the
+	// schedules of those that keep failing
+	var healthMonitor *workers.HealthMonitor
+	if cfg.Monitoring.HealthCheckEnabled {
+		healthMonitor = workers.NewHealthMonitor(db, services.NewDatabaseService(db, encryptionService), storageFactory, wsService, workers.HealthMonitorConfig{
+			Interval:  cfg.Monitoring.HealthCheckInterval,
+			Retry:     cfg.Monitoring.HealthCheckRetry,
+			Failures:  cfg.Monitoring.HealthCheckFailures,
+			Retention: cfg.Monitoring.HealthCheckRetention,
+		})
+		healthMonitor.SetNotifier(notificationService)
+	}
+
+	status := workers.NewWorkerStatus(queueConfig.Concurrency, queueConfig.Queues)
+	heartbeat := workers.NewHeartbeat(services.NewRedisWorkerStatusStore(redisClient, ""), status, cfg.Backup.WorkerHeartbeatInterval)
+
+	shutdownManager := server.NewShutdownManager(nil)
+	shutdownManager.SetTimeout(cfg.Backup.WorkerShutdownTimeout + shutdownGrace)
+	shutdownManager.SetDatabase(db)
+	shutdownManager.SetLogger(logging.PrintfLogger{Logger: logger.With("component", "shutdown")})
+	shutdownManager.AddShutdownHook(func(ctx context.Context) error {
+		// Stop taking jobs, let running ones finish and checkpoint those
+		// still running at the shutdown timeout
+		var monitorErr error
+		if healthMonitor != nil {
+			monitorErr = healthMonitor.Stop(ctx)
+		}
+		heartbeat.SetDraining()
+		backupWorker.Drain()
+		queueWorker.Shutdown()
+		waitErr := backupWorker.Wait(ctx)
+
+		return errors.Join(
+			monitorErr,
+			waitErr,
+			heartbeat.Stop(ctx),
+			queueService.Close(),
+			wsService.Close(),
+			redisClient.Close(),
+		)
+	})
+
+	// Metrics stay available while running backups finish
+	if workerMetrics != nil {
+		metricsServer := workerMetrics.Serve(cfg.Monitoring.MetricsPort)
+		shutdownManager.AddShutdownHook(metricsServer.Shutdown)
+		slog.Info("Metrics served", "port", cfg.Monitoring.MetricsPort, "path", "/metrics")
+	}
+
+	// Flush traces last, once running backups finished
+	shutdownManager.AddShutdownHook(shutdownTracing)
+
+	if err := queueWorker.Start(); err != nil {
+		slog.Error("Failed to start worker", "error", err)
+		os.Exit(1)
+	}
+	heartbeat.Start()
+	if healthMonitor != nil {
+		healthMonitor.Start()
+	}
+	slog.Info("Worker started", "worker_id", status.ID, "concurrency", queueConfig.Concurrency)
+
+	// Wait for shutdown signals and handle graceful shutdown
+	shutdownManager.WaitForShutdown()
+}
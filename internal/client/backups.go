@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when a downloaded file does not match its checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// User is the account of a session
+type User struct {
+	UID              string `json:"uid"`
+	Email            string `json:"email"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	TwoFactorEnabled bool   `json:"has_2fa"`
+}
+
+// Database is a database connection
+type Database struct {
+	UID          string     `json:"uid"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Host         string     `json:"host"`
+	Port         int        `json:"port"`
+	Database     string     `json:"database"`
+	Username     string     `json:"username"`
+	IsActive     bool       `json:"is_active"`
+	HealthStatus string     `json:"health_status"`
+	LastTestedAt *time.Time `json:"last_tested_at,omitempty"`
+}
+
+// BackupDatabase is the connection a backup was taken from
+type BackupDatabase struct {
+	UID      string `json:"uid"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Database string `json:"database"`
+}
+
+// Backup is a backup job and the files it produced
+type Backup struct {
+	UID             string          `json:"uid"`
+	Name            string          `json:"name"`
+	Type            string          `json:"type"`
+	Status          string          `json:"status"`
+	Priority        int             `json:"priority"`
+	Progress        float64         `json:"progress"`
+	ProgressMessage string          `json:"progress_message,omitempty"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	CompletedAt     *time.Time      `json:"completed_at,omitempty"`
+	Duration        *int64          `json:"duration,omitempty"`
+	OriginalSize    *int64          `json:"original_size,omitempty"`
+	CompressedSize  *int64          `json:"compressed_size,omitempty"`
+	ErrorMessage    string          `json:"error_message,omitempty"`
+	ErrorCode       string          `json:"error_code,omitempty"`
+	IsScheduled     bool            `json:"is_scheduled"`
+	Database        *BackupDatabase `json:"database_connection,omitempty"`
+	Files           []BackupFile    `json:"backup_files,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// BackupFile is a file a backup produced
+type BackupFile struct {
+	UID           string     `json:"uid"`
+	Name          string     `json:"name"`
+	FileType      string     `json:"file_type"`
+	Size          *int64     `json:"size,omitempty"`
+	Checksum      string     `json:"checksum,omitempty"`
+	IsCompressed  bool       `json:"is_compressed"`
+	IsEncrypted   bool       `json:"is_encrypted"`
+	DownloadCount int        `json:"download_count"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// BackupList is a page of backups
+type BackupList struct {
+	Backups    []Backup `json:"backups"`
+	Total      int64    `json:"total"`
+	Page       int      `json:"page"`
+	Limit      int      `json:"limit"`
+	TotalPages int      `json:"total_pages"`
+}
+
+// ListBackupsOptions filters and pages the backups listed
+type ListBackupsOptions struct {
+	DatabaseUID string
+	Status      string
+	Page        int
+	Limit       int
+}
+
+// CreateBackupRequest describes a backup to start
+type CreateBackupRequest struct {
+	Name        string `json:"name"`
+	DatabaseUID string `json:"database_uid"`
+	Type        string `json:"type"`
+	StorageUID  string `json:"storage_configuration_uid,omitempty"`
+	Priority    int    `json:"priority,omitempty"`
+}
+
+// RestoreOptions control how a backup is restored
+type RestoreOptions struct {
+	DropExisting   bool `json:"drop_existing,omitempty"`
+	CreateDatabase bool `json:"create_database,omitempty"`
+	CleanFirst     bool `json:"clean_first,omitempty"`
+	Jobs           int  `json:"jobs,omitempty"`
+	Force          bool `json:"force,omitempty"`
+}
+
+// RestoreRequest describes a restore. Without a file UID the latest file of
+// the backup is restored.
+type RestoreRequest struct {
+	BackupFileUID string          `json:"backup_file_uid,omitempty"`
+	Options       *RestoreOptions `json:"options,omitempty"`
+}
+
+// DownloadLink is a short-lived URL a backup file can be fetched from
+type DownloadLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListDatabases returns the user's database connections
+func (c *Client) ListDatabases(ctx context.Context) ([]Database, error) {
+	query := url.Values{"limit": {"100"}}
+
+	var databases []Database
+	if err := c.doData(ctx, http.MethodGet, "/api/databases", query, nil, &databases); err != nil {
+		return nil, err
+	}
+	return databases, nil
+}
+
+// ListBackups returns a page of the user's backups
+func (c *Client) ListBackups(ctx context.Context, opts ListBackupsOptions) (*BackupList, error) {
+	query := url.Values{"include_database": {"true"}}
+	if opts.DatabaseUID != "" {
+		query.Set("database_uid", opts.DatabaseUID)
+	}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var list BackupList
+	if err := c.do(ctx, http.MethodGet, "/api/backups", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetBackup returns a backup with its files
+func (c *Client) GetBackup(ctx context.Context, uid string) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodGet, "/api/backups/"+url.PathEscape(uid), nil, nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// ListFiles returns the files of a backup, newest first
+func (c *Client) ListFiles(ctx context.Context, backupUID string) ([]BackupFile, error) {
+	var files []BackupFile
+	if err := c.doData(ctx, http.MethodGet, "/api/backups/"+url.PathEscape(backupUID)+"/files", nil, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// CreateBackup queues a backup
+func (c *Client) CreateBackup(ctx context.Context, req CreateBackupRequest) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/api/backups", nil, req, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Restore queues a restore of a completed backup into its database
+func (c *Client) Restore(ctx context.Context, backupUID string, req RestoreRequest) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/api/backups/"+url.PathEscape(backupUID)+"/restore", nil, req, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Download writes a backup file to w and verifies it against the file's
+// checksum, returning the number of bytes written. The server either
+// answers with a short-lived link to the file in storage or streams it.
+// A file that does not match is reported with ErrChecksumMismatch after
+// it was written, so callers should discard what they wrote.
+func (c *Client) Download(ctx context.Context, file *BackupFile, w io.Writer) (int64, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/api/backup-files/"+url.PathEscape(file.UID)+"/download", nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body := resp.Body
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read response: %w", err)
+		}
+		var link DownloadLink
+		if err := decodeEnvelope(data, &link); err != nil {
+			return 0, err
+		}
+
+		stored, err := c.fetch(ctx, link.URL)
+		if err != nil {
+			return 0, err
+		}
+		defer stored.Close()
+		body = stored
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), body)
+	if err != nil {
+		return n, fmt.Errorf("download failed: %w", err)
+	}
+
+	if file.Checksum != "" {
+		want := strings.ToLower(strings.TrimPrefix(file.Checksum, "sha256:"))
+		if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+			return n, fmt.Errorf("%w: got sha256:%s, want sha256:%s", ErrChecksumMismatch, got, want)
+		}
+	}
+	return n, nil
+}
+
+// fetch gets a download link. The link is signed for the storage provider,
+// so the session's cookies are not sent along.
+func (c *Client) fetch(ctx context.Context, link string) (io.ReadCloser, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid download URL %q", link)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download from %s failed: %w", u.Host, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download from %s failed with status %d", u.Host, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
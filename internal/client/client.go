@@ -0,0 +1,376 @@
+// Package client is a client of the dbackup HTTP API. It authenticates like
+// the web UI, with the access and refresh tokens login sets as cookies, and
+// follows jobs over the WebSocket endpoint.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+)
+
+var (
+	// ErrTwoFactorRequired is returned by Login when the account needs a TOTP or backup code
+	ErrTwoFactorRequired = errors.New("two-factor authentication code required")
+
+	// ErrSessionExpired is returned when the access token expired and could not be refreshed
+	ErrSessionExpired = errors.New("session expired, log in again")
+)
+
+// APIError is an error response of the API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound checks if err is a 404 response of the API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Tokens are the credentials of a session. Without a refresh token the
+// session ends when the access token expires.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Client calls the API of one dbackup server. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	onRefresh  func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+}
+
+// Option configures a client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTokens sets the tokens of an existing session
+func WithTokens(tokens Tokens) Option {
+	return func(c *Client) {
+		c.tokens = tokens
+	}
+}
+
+// WithTokenRefresh sets a function called with the new tokens whenever an
+// expired access token is refreshed, so they can be stored
+func WithTokenRefresh(fn func(Tokens)) Option {
+	return func(c *Client) {
+		c.onRefresh = fn
+	}
+}
+
+// New creates a client of the server at baseURL, e.g. https://dbackup.example.com
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Tokens returns the tokens of the current session
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+func (c *Client) setTokens(tokens Tokens) {
+	c.mu.Lock()
+	c.tokens = tokens
+	c.mu.Unlock()
+}
+
+// LoginRequest holds the credentials of a login. One of TOTPCode and
+// BackupCode is needed for accounts with two-factor authentication.
+type LoginRequest struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	TOTPCode   string `json:"totp_code,omitempty"`
+	BackupCode string `json:"backup_code,omitempty"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+// Login starts a session. It returns ErrTwoFactorRequired when the account
+// needs a code, after which the login is repeated with one.
+func (c *Client) Login(ctx context.Context, req LoginRequest) (*User, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendOnce(ctx, http.MethodPost, "/api/auth/login", nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var env struct {
+		Message string `json:"message"`
+		Data    struct {
+			Requires2FA bool `json:"requires_2fa"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(body, &env)
+
+	if resp.StatusCode == http.StatusUnauthorized && env.Data.Requires2FA {
+		return nil, ErrTwoFactorRequired
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: env.Message}
+	}
+
+	var tokens Tokens
+	for _, cookie := range resp.Cookies() {
+		switch cookie.Name {
+		case accessTokenCookie:
+			tokens.AccessToken = cookie.Value
+		case refreshTokenCookie:
+			tokens.RefreshToken = cookie.Value
+		}
+	}
+	if tokens.AccessToken == "" {
+		return nil, errors.New("login response did not set an access token")
+	}
+	c.setTokens(tokens)
+
+	var user User
+	if err := decodeEnvelope(body, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Session returns the user of the current session
+func (c *Client) Session(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.doData(ctx, http.MethodGet, "/api/auth/session", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// refresh trades the refresh token for new tokens
+func (c *Client) refresh(ctx context.Context) error {
+	refreshToken := c.Tokens().RefreshToken
+	if refreshToken == "" {
+		return ErrSessionExpired
+	}
+
+	payload, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendOnce(ctx, http.MethodPost, "/api/auth/refresh", nil, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrSessionExpired
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var tokens Tokens
+	if err := decodeEnvelope(body, &tokens); err != nil {
+		return err
+	}
+	if tokens.AccessToken == "" {
+		return ErrSessionExpired
+	}
+
+	c.setTokens(tokens)
+	if c.onRefresh != nil {
+		c.onRefresh(tokens)
+	}
+	return nil
+}
+
+// doData sends a request to an endpoint answering with the standard
+// {status, message, data} envelope and decodes its data into out
+func (c *Client) doData(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return decodeEnvelope(data, out)
+}
+
+// do sends a request to an endpoint answering with a plain JSON object and
+// decodes it into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send sends an authenticated request and returns its successful response.
+// An expired access token is refreshed once and the request repeated.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.sendOnce(ctx, method, path, query, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.Tokens().RefreshToken != "" {
+		resp.Body.Close()
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if resp, err = c.sendOnce(ctx, method, path, query, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// sendOnce sends a request with the current access token
+func (c *Client) sendOnce(ctx context.Context, method, path string, query url.Values, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Tokens().AccessToken; token != "" {
+		req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: token})
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", c.baseURL.Host, err)
+	}
+	return resp, nil
+}
+
+// url resolves an API path against the server URL
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// decodeEnvelope decodes the data of a {status, message, data} response
+func decodeEnvelope(body []byte, out interface{}) error {
+	var env struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// responseError reads the message of an error response. Both the standard
+// envelope and Echo's HTTP errors carry it in "message".
+func responseError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var env struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &env) == nil {
+		apiErr.Message = env.Message
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
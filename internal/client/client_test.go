@@ -0,0 +1,580 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/routes"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/validation"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/dbackup/backend-go/internal/workers"
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	testPassword = "CorrectHorse42!"
+	testSecret   = "test-secret-key-for-testing-purposes-only"
+)
+
+var testDump = []byte("-- PostgreSQL database dump\nCREATE TABLE orders (id integer);\n")
+
+// recordingWorker queues nothing and records what the API enqueued
+type recordingWorker struct {
+	mu       sync.Mutex
+	backups  []*workers.BackupTaskPayload
+	restores []*workers.RestoreTaskPayload
+}
+
+func (w *recordingWorker) EnqueueBackupJob(ctx context.Context, jobType string, payload *workers.BackupTaskPayload, options ...services.JobOption) (*services.JobInfo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.backups = append(w.backups, payload)
+	return &services.JobInfo{ID: "backup-task", Type: jobType, Queue: services.BackupLaneDefault}, nil
+}
+
+func (w *recordingWorker) EnqueueScheduledBackupJob(ctx context.Context, payload *workers.BackupTaskPayload, scheduledTime time.Time, options ...services.JobOption) (*services.JobInfo, error) {
+	return &services.JobInfo{ID: "scheduled-task"}, nil
+}
+
+func (w *recordingWorker) ReprioritizeBackupJob(ctx context.Context, backupJob *models.BackupJob) error {
+	return nil
+}
+
+func (w *recordingWorker) EnqueueRestoreJob(ctx context.Context, jobType string, payload *workers.RestoreTaskPayload, options ...services.JobOption) (*services.JobInfo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.restores = append(w.restores, payload)
+	return &services.JobInfo{ID: "restore-task", Type: jobType, Queue: services.BackupLaneHigh}, nil
+}
+
+// presignStore is a local store that links to the storage server while presigning is on
+type presignStore struct {
+	*services.LocalObjectStore
+	s *testServer
+}
+
+func (p *presignStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if !p.s.presign {
+		return "", services.ErrPresignNotSupported
+	}
+	return p.s.storage.URL + "/" + key, nil
+}
+
+// testStorage resolves every storage configuration to the same store
+type testStorage struct {
+	store services.ObjectStore
+}
+
+func (r *testStorage) ResolveStorage(ctx context.Context, uid string, userID uint, teamID *uint) (*models.StorageConfiguration, services.ObjectStore, error) {
+	return &models.StorageConfiguration{Provider: models.StorageProviderLocal}, r.store, nil
+}
+
+func (r *testStorage) ResolveStorageByID(ctx context.Context, id uint) (*models.StorageConfiguration, services.ObjectStore, error) {
+	return r.ResolveStorage(ctx, "", 0, nil)
+}
+
+func (r *testStorage) InvalidateCache(uid string) {}
+
+// testServer is an API server running in the test process
+type testServer struct {
+	*httptest.Server
+	db      *gorm.DB
+	jm      *auth.JWTManager
+	ws      *websocket.WebSocketService
+	worker  *recordingWorker
+	user    *models.User
+	conn    *models.DatabaseConnection
+	store   *services.LocalObjectStore
+	storage *httptest.Server
+	cipher  *services.BackupCipher
+
+	// presign makes downloads answer with a link to the storage server
+	presign bool
+}
+
+func newTestServer(t *testing.T) *testServer {
+	err := database.Initialize(&config.Config{Database: config.DatabaseConfig{
+		URL: "sqlite://" + filepath.Join(t.TempDir(), "api.db"),
+	}})
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	db := database.GetDB()
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.DatabaseConnection{},
+		&models.DatabaseTable{},
+		&models.TablePermission{},
+		&models.StorageConfiguration{},
+		&models.BackupJob{},
+		&models.BackupFile{},
+		&models.BackupFileLocation{},
+		&models.RestoreJob{},
+	))
+
+	hash, err := auth.NewPasswordHasher().HashPassword(testPassword)
+	require.NoError(t, err)
+	user := &models.User{Email: "ops@example.com", FirstName: "Ops", LastName: "Team", Password: hash, IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+
+	conn := &models.DatabaseConnection{
+		Name:     "Orders",
+		Type:     models.DatabaseTypePostgreSQL,
+		Host:     "db.internal",
+		Port:     5432,
+		Database: "orders",
+		Username: "backup",
+		Password: "encrypted",
+		UserID:   user.ID,
+	}
+	require.NoError(t, db.Create(conn).Error)
+
+	jm := auth.NewJWTManager(testSecret, 15*time.Minute, 24*time.Hour)
+	ws := websocket.NewWebSocketService(jm)
+	t.Cleanup(func() { ws.Close() })
+
+	s := &testServer{db: db, jm: jm, ws: ws, worker: &recordingWorker{}, user: user, conn: conn}
+	s.cipher = services.NewBackupCipher(encryption.NewService("test-key-for-testing"))
+
+	e := echo.New()
+	e.Validator = validation.NewValidator()
+	routes.SetupAuthRoutes(e, jm, auth.NewPasswordHasher(), auth.NewTOTPManager("dbackup-test"), nil)
+	routes.SetupDatabaseRoutes(e, db, jm, encryption.NewService("test-key-for-testing"), nil)
+	routes.SetupBackupRoutes(e, db, jm, nil, nil, s.worker, nil)
+	e.GET("/api/ws", handlers.NewWebSocketHandler(ws).HandleWebSocketConnection)
+
+	// Backup files are stored in a local directory, which presigned links point at
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "backups"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "backups", "orders.dump"), testDump, 0o644))
+	local, err := services.NewLocalObjectStore(root)
+	require.NoError(t, err)
+	s.store = local
+	routes.SetupBackupFileRoutes(e, db, jm, &testStorage{store: &presignStore{LocalObjectStore: local, s: s}}, s.cipher, nil, 5*time.Minute)
+
+	s.storage = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie(accessTokenCookie); err == nil {
+			http.Error(w, "unexpected session cookie", http.StatusBadRequest)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(root, filepath.FromSlash(r.URL.Path)))
+	}))
+	t.Cleanup(s.storage.Close)
+
+	s.Server = httptest.NewServer(e)
+	t.Cleanup(s.Server.Close)
+	return s
+}
+
+// login creates a client with a session of the test user
+func (s *testServer) login(t *testing.T, opts ...Option) *Client {
+	c, err := New(s.URL, opts...)
+	require.NoError(t, err)
+	_, err = c.Login(context.Background(), LoginRequest{Email: s.user.Email, Password: testPassword})
+	require.NoError(t, err)
+	return c
+}
+
+// completedBackup creates a completed backup of the test connection with one file
+func (s *testServer) completedBackup(t *testing.T, checksum string) (*models.BackupJob, *models.BackupFile) {
+	job := &models.BackupJob{
+		Name:                 "Nightly",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusCompleted,
+		UserID:               s.user.ID,
+		DatabaseConnectionID: s.conn.ID,
+	}
+	require.NoError(t, s.db.Create(job).Error)
+
+	file := &models.BackupFile{
+		Name:        "orders-backup.dump",
+		FileType:    "dump",
+		S3Bucket:    "backups",
+		S3Key:       "backups/orders.dump",
+		S3Region:    "us-east-1",
+		BackupJobID: job.ID,
+	}
+	file.SetChecksum(checksum)
+	require.NoError(t, s.db.Create(file).Error)
+	require.NoError(t, s.db.Model(file).Update("is_encrypted", false).Error)
+	return job, file
+}
+
+// waitForConnection waits until the user's WebSocket connection is registered
+func (s *testServer) waitForConnection(t *testing.T) {
+	require.Eventually(t, func() bool {
+		return s.ws.GetUserConnectionCount(s.user.ID) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func dumpChecksum() string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(testDump))
+}
+
+func TestClient_LoginWithTwoFactor(t *testing.T) {
+	s := newTestServer(t)
+	totp := auth.NewTOTPManager("dbackup-test")
+	key, err := totp.GenerateSecret(s.user.Email)
+	require.NoError(t, err)
+	secret := key.Secret()
+	require.NoError(t, s.db.Model(s.user).Updates(map[string]interface{}{
+		"two_factor_enabled": true,
+		"two_factor_secret":  secret,
+	}).Error)
+
+	c, err := New(s.URL + "/")
+	require.NoError(t, err)
+
+	req := LoginRequest{Email: s.user.Email, Password: testPassword}
+	_, err = c.Login(context.Background(), req)
+	require.ErrorIs(t, err, ErrTwoFactorRequired)
+	assert.Empty(t, c.Tokens().AccessToken)
+
+	req.TOTPCode, err = totp.GenerateCode(secret)
+	require.NoError(t, err)
+	user, err := c.Login(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, s.user.UID, user.UID)
+	assert.NotEmpty(t, c.Tokens().AccessToken)
+	assert.NotEmpty(t, c.Tokens().RefreshToken)
+
+	session, err := c.Session(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ops@example.com", session.Email)
+	assert.True(t, session.TwoFactorEnabled)
+}
+
+func TestClient_LoginInvalidCredentials(t *testing.T) {
+	s := newTestServer(t)
+	c, err := New(s.URL)
+	require.NoError(t, err)
+
+	_, err = c.Login(context.Background(), LoginRequest{Email: s.user.Email, Password: "wrong"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, "Invalid credentials", apiErr.Message)
+
+	_, err = New("dbackup.example.com")
+	assert.Error(t, err)
+}
+
+func TestClient_RefreshesExpiredToken(t *testing.T) {
+	s := newTestServer(t)
+	_, refreshToken, err := s.jm.GenerateTokenPair(s.user.ID, s.user.Email, nil)
+	require.NoError(t, err)
+	expired, err := auth.NewJWTManager(testSecret, -time.Minute, time.Hour).GenerateAccessToken(s.user.ID, s.user.Email, nil)
+	require.NoError(t, err)
+
+	var refreshed []Tokens
+	c, err := New(s.URL,
+		WithTokens(Tokens{AccessToken: expired, RefreshToken: refreshToken}),
+		WithTokenRefresh(func(tokens Tokens) { refreshed = append(refreshed, tokens) }),
+	)
+	require.NoError(t, err)
+
+	databases, err := c.ListDatabases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, databases, 1)
+	require.Len(t, refreshed, 1)
+	assert.NotEqual(t, expired, c.Tokens().AccessToken)
+	assert.Equal(t, refreshed[0], c.Tokens())
+
+	// Without a refresh token the session ends with the access token
+	c, err = New(s.URL, WithTokens(Tokens{AccessToken: expired}))
+	require.NoError(t, err)
+	_, err = c.ListDatabases(context.Background())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestClient_ListDatabasesAndBackups(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+	job, file := s.completedBackup(t, dumpChecksum())
+
+	databases, err := c.ListDatabases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, databases, 1)
+	assert.Equal(t, s.conn.UID, databases[0].UID)
+	assert.Equal(t, "postgresql", databases[0].Type)
+	assert.Equal(t, 5432, databases[0].Port)
+
+	list, err := c.ListBackups(context.Background(), ListBackupsOptions{Status: "completed", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), list.Total)
+	require.Len(t, list.Backups, 1)
+	assert.Equal(t, job.UID, list.Backups[0].UID)
+	require.NotNil(t, list.Backups[0].Database)
+	assert.Equal(t, "Orders", list.Backups[0].Database.Name)
+
+	list, err = c.ListBackups(context.Background(), ListBackupsOptions{Status: "failed"})
+	require.NoError(t, err)
+	assert.Empty(t, list.Backups)
+
+	files, err := c.ListFiles(context.Background(), job.UID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, file.UID, files[0].UID)
+	assert.Equal(t, dumpChecksum(), files[0].Checksum)
+
+	_, err = c.GetBackup(context.Background(), "missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestClient_CreateBackupAndWatch(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watcher, err := c.Watch(ctx)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	backup, err := c.CreateBackup(ctx, CreateBackupRequest{Name: "Before migration", DatabaseUID: s.conn.UID, Type: "full"})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", backup.Status)
+	require.Len(t, s.worker.backups, 1)
+	assert.Equal(t, s.conn.UID, s.worker.backups[0].DatabaseUID)
+
+	// The worker runs the backup; events of other jobs are skipped
+	s.waitForConnection(t)
+	go func() {
+		s.ws.BroadcastBackupProgress(s.user.ID, &websocket.BackupProgressMessage{BackupJobUID: "other", Status: "running", Progress: 10})
+		s.ws.BroadcastBackupProgress(s.user.ID, &websocket.BackupProgressMessage{BackupJobUID: backup.UID, Status: "running", Progress: 50, ProgressMessage: "Dumping tables"})
+		s.ws.PublishJobLifecycle(s.user.ID, &websocket.JobLifecycleMessage{JobUID: backup.UID, JobType: websocket.JobTypeBackup, Status: "completed"})
+	}()
+
+	var events []*JobEvent
+	final, err := watcher.Wait(ctx, backup.UID, JobTypeBackup, func(event *JobEvent) {
+		events = append(events, event)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "completed", final.Status)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventBackupProgress, events[0].Type)
+	assert.Equal(t, 50.0, events[0].Progress)
+	assert.Equal(t, "Dumping tables", events[0].Message)
+	assert.False(t, events[0].Final())
+	assert.True(t, events[1].Final())
+}
+
+func TestClient_WatchFailedRestore(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+	job, file := s.completedBackup(t, dumpChecksum())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watcher, err := c.Watch(ctx)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	_, err = c.Restore(ctx, job.UID, RestoreRequest{Options: &RestoreOptions{CleanFirst: true}})
+	require.NoError(t, err)
+	require.Len(t, s.worker.restores, 1)
+	assert.Equal(t, file.UID, s.worker.restores[0].BackupFileUID)
+	assert.True(t, s.worker.restores[0].Options.CleanFirst)
+
+	s.waitForConnection(t)
+	go func() {
+		// A lifecycle event of the backup itself is not the restore's
+		s.ws.PublishJobLifecycle(s.user.ID, &websocket.JobLifecycleMessage{JobUID: job.UID, JobType: websocket.JobTypeBackup, Status: "completed"})
+		s.ws.PublishRestoreProgress(s.user.ID, &websocket.RestoreProgressMessage{JobUID: job.UID, Status: "running", Progress: 40})
+		message := "relation already exists"
+		s.ws.PublishJobLifecycle(s.user.ID, &websocket.JobLifecycleMessage{JobUID: job.UID, JobType: websocket.JobTypeRestore, Status: "failed", ErrorMessage: &message})
+	}()
+
+	var events []*JobEvent
+	final, err := watcher.Wait(ctx, job.UID, JobTypeRestore, func(event *JobEvent) {
+		events = append(events, event)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "failed", final.Status)
+	assert.Equal(t, "relation already exists", final.ErrorMessage)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventRestoreProgress, events[0].Type)
+
+	// Only completed backups can be restored
+	require.NoError(t, s.db.Model(job).Update("status", models.BackupStatusFailed).Error)
+	_, err = c.Restore(ctx, job.UID, RestoreRequest{})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "Can only restore completed backups", apiErr.Message)
+}
+
+func TestClient_WatchStopsWithContext(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+
+	watcher, err := c.Watch(context.Background())
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = watcher.Wait(ctx, "job", JobTypeBackup, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Connecting needs a session
+	c, err = New(s.URL)
+	require.NoError(t, err)
+	_, err = c.Watch(context.Background())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestClient_Download(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+	_, file := s.completedBackup(t, dumpChecksum())
+	backupFile := &BackupFile{UID: file.UID, Checksum: dumpChecksum()}
+
+	// Streamed by the server
+	var streamed bytes.Buffer
+	n, err := c.Download(context.Background(), backupFile, &streamed)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testDump)), n)
+	assert.Equal(t, testDump, streamed.Bytes())
+
+	// Fetched from storage with a presigned link, without the session
+	s.presign = true
+	var linked bytes.Buffer
+	_, err = c.Download(context.Background(), backupFile, &linked)
+	require.NoError(t, err)
+	assert.Equal(t, testDump, linked.Bytes())
+
+	// A file that does not match its checksum is reported
+	tampered := &BackupFile{UID: file.UID, Checksum: fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other")))}
+	_, err = c.Download(context.Background(), tampered, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+}
+
+func TestClient_DownloadCompressed(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+
+	// Compressed backups are stored, and checksummed, as the gzipped dump
+	compressed := gzipped(t, testDump)
+	checksum := fmt.Sprintf("sha256:%x", sha256.Sum256(compressed))
+	_, file := s.completedBackup(t, checksum)
+	_, err := s.store.Put(context.Background(), "backups/orders.dump.gz", bytes.NewReader(compressed), "application/gzip")
+	require.NoError(t, err)
+	require.NoError(t, s.db.Model(file).Updates(map[string]interface{}{"s3_key": "backups/orders.dump.gz", "is_compressed": true}).Error)
+	backupFile := &BackupFile{UID: file.UID, Checksum: checksum}
+
+	var streamed bytes.Buffer
+	_, err = c.Download(context.Background(), backupFile, &streamed)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, streamed.Bytes())
+
+	s.presign = true
+	var linked bytes.Buffer
+	_, err = c.Download(context.Background(), backupFile, &linked)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, linked.Bytes())
+
+	// Encrypted files are decrypted by the server and match the same checksum
+	var sealed bytes.Buffer
+	key, err := s.cipher.Seal(&sealed, bytes.NewReader(compressed))
+	require.NoError(t, err)
+	_, err = s.store.Put(context.Background(), "backups/orders.dump.gz.enc", &sealed, "application/octet-stream")
+	require.NoError(t, err)
+	require.NoError(t, s.db.Model(file).Updates(map[string]interface{}{"s3_key": "backups/orders.dump.gz.enc", "is_encrypted": true, "encryption_key": key}).Error)
+
+	var decrypted bytes.Buffer
+	_, err = c.Download(context.Background(), backupFile, &decrypted)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, decrypted.Bytes())
+}
+
+func TestClient_RestoreEndToEnd(t *testing.T) {
+	s := newTestServer(t)
+	c := s.login(t)
+	ctx := context.Background()
+
+	// The stored object is the compressed dump, encrypted under its own data key
+	var sealed bytes.Buffer
+	key, err := s.cipher.Seal(&sealed, bytes.NewReader(gzipped(t, testDump)))
+	require.NoError(t, err)
+	_, err = s.store.Put(ctx, "backups/orders.dump.gz.enc", &sealed, "application/octet-stream")
+	require.NoError(t, err)
+
+	job, file := s.completedBackup(t, "")
+	require.NoError(t, s.db.Model(file).Updates(map[string]interface{}{
+		"s3_key":         "backups/orders.dump.gz.enc",
+		"is_compressed":  true,
+		"is_encrypted":   true,
+		"encryption_key": key,
+	}).Error)
+
+	_, err = c.Restore(ctx, job.UID, RestoreRequest{})
+	require.NoError(t, err)
+	require.Len(t, s.worker.restores, 1)
+
+	// The worker takes the queued restore and hands the dump to the restore tool
+	tool := &restoreTool{}
+	worker := workers.NewBackupWorker(s.db, tool, &testStorage{store: s.store}, nil, s.ws)
+	worker.SetBackupCipher(s.cipher)
+	payload, err := json.Marshal(s.worker.restores[0])
+	require.NoError(t, err)
+	require.NoError(t, worker.HandleRestorePostgreSQL(ctx, asynq.NewTask(workers.TypeRestorePostgreSQL, payload)))
+
+	assert.Equal(t, testDump, tool.restored)
+	backup, err := c.GetBackup(ctx, job.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", backup.Status)
+	var restoreJob models.RestoreJob
+	require.NoError(t, s.db.First(&restoreJob, s.worker.restores[0].RestoreJobID).Error)
+	assert.Equal(t, models.BackupStatusCompleted, restoreJob.Status)
+}
+
+// restoreTool records the dump it is asked to restore
+type restoreTool struct {
+	services.BackupServiceInterface
+	restored []byte
+}
+
+func (r *restoreTool) RestorePostgreSQLBackup(ctx context.Context, conn *models.DatabaseConnection, backupPath string, options *services.RestoreOptions) error {
+	var err error
+	r.restored, err = os.ReadFile(backupPath)
+	return err
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
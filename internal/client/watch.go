@@ -0,0 +1,228 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket subprotocol of the API and the prefix of the one carrying the token
+const (
+	wsSubprotocol    = "dbackup.v1"
+	wsBearerProtocol = "bearer."
+)
+
+// Job types and event types of job events
+const (
+	JobTypeBackup  = "backup"
+	JobTypeRestore = "restore"
+
+	EventBackupProgress  = "backup_progress"
+	EventRestoreProgress = "restore_progress"
+	EventJobLifecycle    = "job_lifecycle"
+)
+
+// ErrServerShutdown is returned by a watcher when the server closes for a restart
+var ErrServerShutdown = errors.New("server is shutting down")
+
+// JobEvent is a progress or status update of a backup or restore
+type JobEvent struct {
+	Type         string    `json:"type"`
+	JobUID       string    `json:"job_uid"`
+	JobType      string    `json:"job_type"`
+	Status       string    `json:"status"`
+	Progress     float64   `json:"progress,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Final checks if the event ends its job
+func (e *JobEvent) Final() bool {
+	if e.Type != EventJobLifecycle {
+		return false
+	}
+	switch e.Status {
+	case "completed", "failed", "cancelled", "partial", "timeout":
+		return true
+	}
+	return false
+}
+
+// Watcher receives the job events of the session's user
+type Watcher struct {
+	conn    *websocket.Conn
+	pending []wsMessage
+}
+
+// wsMessage is a message sent over the WebSocket
+type wsMessage struct {
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Watch connects to the WebSocket endpoint. Events of jobs started after
+// it returns are not missed, so connect before starting a job to follow.
+func (c *Client) Watch(ctx context.Context) (*Watcher, error) {
+	conn, resp, err := c.dialWebSocket(ctx)
+	if resp != nil && resp.StatusCode == http.StatusUnauthorized && c.Tokens().RefreshToken != "" {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		conn, resp, err = c.dialWebSocket(ctx)
+	}
+	if err != nil {
+		if resp != nil {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.baseURL.Host, err)
+	}
+	return &Watcher{conn: conn}, nil
+}
+
+// dialWebSocket opens a connection authenticated with the access token
+func (c *Client) dialWebSocket(ctx context.Context) (*websocket.Conn, *http.Response, error) {
+	u := *c.baseURL
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/ws"
+
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{wsSubprotocol}
+	if token := c.Tokens().AccessToken; token != "" {
+		dialer.Subprotocols = append(dialer.Subprotocols, wsBearerProtocol+token)
+	}
+	return dialer.DialContext(ctx, u.String(), nil)
+}
+
+// Next waits for the next job event, skipping other messages
+func (w *Watcher) Next() (*JobEvent, error) {
+	for {
+		message, err := w.read()
+		if err != nil {
+			return nil, err
+		}
+
+		event := &JobEvent{Type: message.Type, Timestamp: message.Timestamp}
+		switch message.Type {
+		case EventBackupProgress:
+			var data struct {
+				BackupJobUID    string  `json:"backup_job_uid"`
+				Status          string  `json:"status"`
+				Progress        float64 `json:"progress"`
+				ProgressMessage string  `json:"progress_message"`
+				ErrorMessage    *string `json:"error_message"`
+			}
+			if err := json.Unmarshal(message.Data, &data); err != nil {
+				continue
+			}
+			event.JobUID, event.JobType, event.Status = data.BackupJobUID, JobTypeBackup, data.Status
+			event.Progress, event.Message = data.Progress, data.ProgressMessage
+			if data.ErrorMessage != nil {
+				event.ErrorMessage = *data.ErrorMessage
+			}
+
+		case EventRestoreProgress:
+			var data struct {
+				JobUID          string  `json:"job_uid"`
+				Status          string  `json:"status"`
+				Progress        float64 `json:"progress"`
+				ProgressMessage string  `json:"progress_message"`
+			}
+			if err := json.Unmarshal(message.Data, &data); err != nil {
+				continue
+			}
+			event.JobUID, event.JobType, event.Status = data.JobUID, JobTypeRestore, data.Status
+			event.Progress, event.Message = data.Progress, data.ProgressMessage
+
+		case EventJobLifecycle:
+			var data struct {
+				JobUID       string  `json:"job_uid"`
+				JobType      string  `json:"job_type"`
+				Status       string  `json:"status"`
+				ErrorMessage *string `json:"error_message"`
+			}
+			if err := json.Unmarshal(message.Data, &data); err != nil {
+				continue
+			}
+			event.JobUID, event.JobType, event.Status = data.JobUID, data.JobType, data.Status
+			if data.ErrorMessage != nil {
+				event.ErrorMessage = *data.ErrorMessage
+			}
+
+		case "server_shutdown":
+			return nil, ErrServerShutdown
+
+		default:
+			continue
+		}
+		return event, nil
+	}
+}
+
+// read returns the next message. The server sends messages queued for a
+// connection together, separated by newlines, in one frame.
+func (w *Watcher) read() (wsMessage, error) {
+	for len(w.pending) == 0 {
+		_, data, err := w.conn.ReadMessage()
+		if err != nil {
+			return wsMessage{}, fmt.Errorf("connection lost: %w", err)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var message wsMessage
+			if err := decoder.Decode(&message); err != nil {
+				break
+			}
+			w.pending = append(w.pending, message)
+		}
+	}
+
+	message := w.pending[0]
+	w.pending = w.pending[1:]
+	return message, nil
+}
+
+// Wait follows a job until it ends, calling fn with each of its events, and
+// returns the final event. It gives up when ctx is done.
+func (w *Watcher) Wait(ctx context.Context, jobUID, jobType string, fn func(*JobEvent)) (*JobEvent, error) {
+	stop := context.AfterFunc(ctx, func() { w.conn.Close() })
+	defer stop()
+
+	for {
+		event, err := w.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if event.JobUID != jobUID || event.JobType != jobType {
+			continue
+		}
+		if fn != nil {
+			fn(event)
+		}
+		if event.Final() {
+			return event, nil
+		}
+	}
+}
+
+// Close closes the connection
+func (w *Watcher) Close() error {
+	return w.conn.Close()
+}
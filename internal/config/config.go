@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
@@ -48,6 +49,15 @@ type Config struct {
 
 	// WebSocket configuration
 	WebSocket WebSocketConfig
+
+	// Risk scoring configuration
+	Risk RiskConfig
+
+	// Notification delivery configuration
+	Notification NotificationConfig
+
+	// Outbound webhook delivery configuration
+	Webhook WebhookConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -110,6 +120,32 @@ type BackupConfig struct {
 	RetentionDays      int
 	MaxConcurrent      int
 	Timeout            time.Duration
+
+	// Concurrent backups allowed per database connection, per database host
+	// and per user without a team; zero or less removes a limit. Team limits
+	// come from the team's subscription.
+	MaxConcurrentPerConnection int
+	MaxConcurrentPerHost       int
+	MaxConcurrentPerUser       int
+	// How long a backup waits before trying again once a limit is reached
+	ConcurrencyRetryDelay time.Duration
+
+	// Worker time a team or user is charged per backup at the lowest tier,
+	// and how many such slots it may queue before its backups are spaced out
+	FairShareSlot  time.Duration
+	FairShareBurst int
+
+	// How long a stopping worker lets running backups finish before they
+	// are requeued for another worker, and how often a worker reports alive
+	WorkerShutdownTimeout   time.Duration
+	WorkerHeartbeatInterval time.Duration
+
+	// How long a presigned backup file download URL stays valid
+	DownloadURLExpiry time.Duration
+
+	// Directories, e.g. NFS mount points, that local storage configurations
+	// may point into; local storage is unavailable when empty
+	LocalStorageRoots []string
 }
 
 // CORSConfig holds CORS configuration
@@ -137,6 +173,32 @@ type LogConfig struct {
 type MonitoringConfig struct {
 	MetricsEnabled bool
 	MetricsPort    int
+
+	// OpenTelemetry tracing, exported over OTLP/HTTP to a collector or as
+	// JSON lines to a file
+	TracingEnabled     bool
+	TracingExporter    string
+	TracingEndpoint    string
+	TracingFile        string
+	TracingSampleRatio float64
+
+	// Readiness checks: the storage configuration probed, the oldest active
+	// default one when empty; how long storage and backup tool probes are
+	// cached; and the pending tasks above which the queues are backlogged
+	HealthStorageUID   string
+	HealthCacheTTL     time.Duration
+	HealthQueueBacklog int64
+
+	// Background re-tests of database connections and storage
+	// configurations. Failing ones are retried with backoff from
+	// HealthCheckRetry up to HealthCheckInterval, turn unhealthy after
+	// HealthCheckFailures consecutive failures, and their history is kept
+	// for HealthCheckRetention.
+	HealthCheckEnabled   bool
+	HealthCheckInterval  time.Duration
+	HealthCheckRetry     time.Duration
+	HealthCheckFailures  int
+	HealthCheckRetention time.Duration
 }
 
 // WebSocketConfig holds WebSocket configuration
@@ -145,6 +207,61 @@ type WebSocketConfig struct {
 	WriteBufferSize int
 	PingPeriod      time.Duration
 	PongWait        time.Duration
+	// Events kept per stream so reconnecting clients can catch up
+	ReplaySize int
+	ReplayTTL  time.Duration
+	// Redis channel and key prefix shared by all API nodes
+	RedisPrefix string
+	// Connections a user may open on one node
+	MaxConnectionsPerUser int
+	// How often open connections re-check their session
+	SessionCheckInterval time.Duration
+}
+
+// RiskConfig holds audit risk scoring configuration
+type RiskConfig struct {
+	Enabled               bool
+	MaxTravelSpeedKmh     float64
+	FailedLoginThreshold  int
+	FailedLoginWindow     time.Duration
+	MassDownloadThreshold int
+	MassDownloadWindow    time.Duration
+	BusinessHoursStart    int
+	BusinessHoursEnd      int
+	BusinessTimezone      string
+	NewDeviceLookback     time.Duration
+	BlockImpossibleTravel bool
+	BlockFailedLogins     bool
+	BlockMassDownloads    bool
+	BlockOffHoursRestores bool
+}
+
+// NotificationConfig holds notification delivery configuration
+type NotificationConfig struct {
+	// SMTP server used by email channels
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
+	// Attempts per delivery, retried with backoff through the job queue
+	MaxAttempts int
+	// Timeout of a single delivery attempt
+	Timeout time.Duration
+	// Deliver to loopback, private and link-local addresses, for Slack and
+	// webhook URLs on an internal network
+	AllowPrivateNetworks bool
+}
+
+// WebhookConfig holds outbound webhook delivery configuration
+type WebhookConfig struct {
+	// Attempts per delivery, retried with exponential backoff through the job queue
+	MaxAttempts int
+	// Timeout of a single delivery attempt
+	Timeout time.Duration
+	// Deliver to loopback, private and link-local addresses, for endpoints on
+	// an internal network
+	AllowPrivateNetworks bool
 }
 
 // Load loads configuration from environment variables and config files
@@ -218,6 +335,16 @@ func setDefaults() {
 	viper.SetDefault("backup.retentiondays", 30)
 	viper.SetDefault("backup.maxconcurrent", 5)
 	viper.SetDefault("backup.timeout", "3600s")
+	viper.SetDefault("backup.maxconcurrentperconnection", 1)
+	viper.SetDefault("backup.maxconcurrentperhost", 2)
+	viper.SetDefault("backup.maxconcurrentperuser", 2)
+	viper.SetDefault("backup.concurrencyretrydelay", "30s")
+	viper.SetDefault("backup.fairshareslot", "60s")
+	viper.SetDefault("backup.fairshareburst", 10)
+	viper.SetDefault("backup.workershutdowntimeout", "10m")
+	viper.SetDefault("backup.workerheartbeatinterval", "10s")
+	viper.SetDefault("backup.downloadurlexpiry", "15m")
+	viper.SetDefault("backup.localstorageroots", []string{})
 
 	// CORS defaults
 	viper.SetDefault("cors.allowedorigins", []string{"http://localhost:3000"})
@@ -237,12 +364,57 @@ func setDefaults() {
 	// Monitoring defaults
 	viper.SetDefault("monitoring.metricsenabled", true)
 	viper.SetDefault("monitoring.metricsport", 9090)
+	viper.SetDefault("monitoring.tracingenabled", false)
+	viper.SetDefault("monitoring.tracingexporter", "otlp")
+	viper.SetDefault("monitoring.tracingendpoint", "http://localhost:4318")
+	viper.SetDefault("monitoring.tracingfile", "traces.jsonl")
+	viper.SetDefault("monitoring.tracingsampleratio", 1.0)
+	viper.SetDefault("monitoring.healthstorageuid", "")
+	viper.SetDefault("monitoring.healthcachettl", "1m")
+	viper.SetDefault("monitoring.healthqueuebacklog", 1000)
+	viper.SetDefault("monitoring.healthcheckenabled", true)
+	viper.SetDefault("monitoring.healthcheckinterval", "15m")
+	viper.SetDefault("monitoring.healthcheckretry", "1m")
+	viper.SetDefault("monitoring.healthcheckfailures", 3)
+	viper.SetDefault("monitoring.healthcheckretention", "720h")
 
 	// WebSocket defaults
 	viper.SetDefault("websocket.readbuffersize", 1024)
 	viper.SetDefault("websocket.writebuffersize", 1024)
 	viper.SetDefault("websocket.pingperiod", "54s")
 	viper.SetDefault("websocket.pongwait", "60s")
+	viper.SetDefault("websocket.replaysize", 100)
+	viper.SetDefault("websocket.replayttl", "10m")
+	viper.SetDefault("websocket.redisprefix", "dbackup:ws")
+	viper.SetDefault("websocket.maxconnectionsperuser", 10)
+	viper.SetDefault("websocket.sessioncheckinterval", "1m")
+
+	// Risk scoring defaults
+	viper.SetDefault("risk.enabled", true)
+	viper.SetDefault("risk.maxtravelspeedkmh", 900.0)
+	viper.SetDefault("risk.failedloginthreshold", 5)
+	viper.SetDefault("risk.failedloginwindow", "10m")
+	viper.SetDefault("risk.massdownloadthreshold", 20)
+	viper.SetDefault("risk.massdownloadwindow", "1h")
+	viper.SetDefault("risk.businesshoursstart", 8)
+	viper.SetDefault("risk.businesshoursend", 18)
+	viper.SetDefault("risk.businesstimezone", "UTC")
+	viper.SetDefault("risk.newdevicelookback", "2160h") // 90 days
+	viper.SetDefault("risk.blockimpossibletravel", false)
+	viper.SetDefault("risk.blockfailedlogins", true)
+	viper.SetDefault("risk.blockmassdownloads", false)
+	viper.SetDefault("risk.blockoffhoursrestores", false)
+
+	// Notification defaults
+	viper.SetDefault("notification.smtpport", 587)
+	viper.SetDefault("notification.maxattempts", 5)
+	viper.SetDefault("notification.timeout", "10s")
+	viper.SetDefault("notification.allowprivatenetworks", false)
+
+	// Webhook defaults
+	viper.SetDefault("webhook.maxattempts", 10)
+	viper.SetDefault("webhook.timeout", "10s")
+	viper.SetDefault("webhook.allowprivatenetworks", false)
 }
 
 // validate validates the configuration
@@ -277,6 +449,18 @@ func validate(cfg *Config) error {
 	if cfg.Backup.MaxConcurrent <= 0 {
 		return fmt.Errorf("max concurrent backups must be positive")
 	}
+	if cfg.Backup.ConcurrencyRetryDelay <= 0 {
+		return fmt.Errorf("backup concurrency retry delay must be positive")
+	}
+	if cfg.Backup.FairShareSlot <= 0 || cfg.Backup.FairShareBurst <= 0 {
+		return fmt.Errorf("backup fair share slot and burst must be positive")
+	}
+	if cfg.Backup.WorkerShutdownTimeout <= 0 || cfg.Backup.WorkerHeartbeatInterval <= 0 {
+		return fmt.Errorf("backup worker shutdown timeout and heartbeat interval must be positive")
+	}
+	if cfg.Backup.DownloadURLExpiry <= 0 {
+		return fmt.Errorf("backup download URL expiry must be positive")
+	}
 
 	// Rate limit validation
 	if cfg.RateLimit.Enabled {
@@ -288,6 +472,44 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	// Logging validation
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log level must be debug, info, warn or error")
+	}
+	switch strings.ToLower(cfg.Log.Format) {
+	case "json", "text":
+	default:
+		return fmt.Errorf("log format must be json or text")
+	}
+
+	// Tracing validation
+	if cfg.Monitoring.TracingEnabled {
+		if cfg.Monitoring.TracingExporter != "otlp" && cfg.Monitoring.TracingExporter != "file" {
+			return fmt.Errorf("tracing exporter must be otlp or file")
+		}
+		if cfg.Monitoring.TracingSampleRatio < 0 || cfg.Monitoring.TracingSampleRatio > 1 {
+			return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+		}
+	}
+
+	// Health check validation
+	if cfg.Monitoring.HealthCacheTTL < 0 {
+		return fmt.Errorf("health cache TTL must not be negative")
+	}
+	if cfg.Monitoring.HealthQueueBacklog <= 0 {
+		return fmt.Errorf("health queue backlog must be positive")
+	}
+	if cfg.Monitoring.HealthCheckEnabled {
+		if cfg.Monitoring.HealthCheckInterval <= 0 || cfg.Monitoring.HealthCheckRetry <= 0 {
+			return fmt.Errorf("health check interval and retry must be positive")
+		}
+		if cfg.Monitoring.HealthCheckFailures <= 0 {
+			return fmt.Errorf("health check failures must be positive")
+		}
+	}
+
 	// WebSocket validation
 	if cfg.WebSocket.ReadBufferSize <= 0 {
 		return fmt.Errorf("websocket read buffer size must be positive")
@@ -296,6 +518,38 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("websocket write buffer size must be positive")
 	}
 
+	// Risk scoring validation
+	if cfg.Risk.Enabled {
+		if cfg.Risk.BusinessHoursStart < 0 || cfg.Risk.BusinessHoursStart > 23 ||
+			cfg.Risk.BusinessHoursEnd < 0 || cfg.Risk.BusinessHoursEnd > 24 {
+			return fmt.Errorf("risk business hours must be between 0 and 24")
+		}
+		if cfg.Risk.BusinessTimezone != "" {
+			if _, err := time.LoadLocation(cfg.Risk.BusinessTimezone); err != nil {
+				return fmt.Errorf("invalid risk business timezone: %w", err)
+			}
+		}
+	}
+
+	// Notification validation
+	if cfg.Notification.MaxAttempts <= 0 {
+		return fmt.Errorf("notification max attempts must be positive")
+	}
+	if cfg.Notification.Timeout <= 0 {
+		return fmt.Errorf("notification timeout must be positive")
+	}
+	if cfg.Notification.SMTPHost != "" && cfg.Notification.SMTPFrom == "" {
+		return fmt.Errorf("SMTP from address is required when an SMTP host is set")
+	}
+
+	// Webhook validation
+	if cfg.Webhook.MaxAttempts <= 0 {
+		return fmt.Errorf("webhook max attempts must be positive")
+	}
+	if cfg.Webhook.Timeout <= 0 {
+		return fmt.Errorf("webhook timeout must be positive")
+	}
+
 	return nil
 }
 
@@ -341,6 +595,16 @@ func bindEnvVars() {
 	viper.BindEnv("backup.retentiondays", "BACKUP_RETENTION_DAYS")
 	viper.BindEnv("backup.maxconcurrent", "BACKUP_MAX_CONCURRENT")
 	viper.BindEnv("backup.timeout", "BACKUP_TIMEOUT")
+	viper.BindEnv("backup.maxconcurrentperconnection", "BACKUP_MAX_CONCURRENT_PER_CONNECTION")
+	viper.BindEnv("backup.maxconcurrentperhost", "BACKUP_MAX_CONCURRENT_PER_HOST")
+	viper.BindEnv("backup.maxconcurrentperuser", "BACKUP_MAX_CONCURRENT_PER_USER")
+	viper.BindEnv("backup.concurrencyretrydelay", "BACKUP_CONCURRENCY_RETRY_DELAY")
+	viper.BindEnv("backup.fairshareslot", "BACKUP_FAIR_SHARE_SLOT")
+	viper.BindEnv("backup.fairshareburst", "BACKUP_FAIR_SHARE_BURST")
+	viper.BindEnv("backup.workershutdowntimeout", "BACKUP_WORKER_SHUTDOWN_TIMEOUT")
+	viper.BindEnv("backup.workerheartbeatinterval", "BACKUP_WORKER_HEARTBEAT_INTERVAL")
+	viper.BindEnv("backup.downloadurlexpiry", "BACKUP_DOWNLOAD_URL_EXPIRY")
+	viper.BindEnv("backup.localstorageroots", "BACKUP_LOCAL_STORAGE_ROOTS")
 	
 	// CORS
 	viper.BindEnv("cors.allowedorigins", "CORS_ALLOWED_ORIGINS")
@@ -360,12 +624,61 @@ func bindEnvVars() {
 	// Monitoring
 	viper.BindEnv("monitoring.metricsenabled", "METRICS_ENABLED")
 	viper.BindEnv("monitoring.metricsport", "METRICS_PORT")
+	viper.BindEnv("monitoring.tracingenabled", "TRACING_ENABLED")
+	viper.BindEnv("monitoring.tracingexporter", "TRACING_EXPORTER")
+	viper.BindEnv("monitoring.tracingendpoint", "TRACING_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	viper.BindEnv("monitoring.tracingfile", "TRACING_FILE")
+	viper.BindEnv("monitoring.tracingsampleratio", "TRACING_SAMPLE_RATIO")
+	viper.BindEnv("monitoring.healthstorageuid", "HEALTH_STORAGE_UID")
+	viper.BindEnv("monitoring.healthcachettl", "HEALTH_CACHE_TTL")
+	viper.BindEnv("monitoring.healthqueuebacklog", "HEALTH_QUEUE_BACKLOG")
+	viper.BindEnv("monitoring.healthcheckenabled", "HEALTH_CHECK_ENABLED")
+	viper.BindEnv("monitoring.healthcheckinterval", "HEALTH_CHECK_INTERVAL")
+	viper.BindEnv("monitoring.healthcheckretry", "HEALTH_CHECK_RETRY")
+	viper.BindEnv("monitoring.healthcheckfailures", "HEALTH_CHECK_FAILURES")
+	viper.BindEnv("monitoring.healthcheckretention", "HEALTH_CHECK_RETENTION")
 	
 	// WebSocket
 	viper.BindEnv("websocket.readbuffersize", "WEBSOCKET_READ_BUFFER_SIZE")
 	viper.BindEnv("websocket.writebuffersize", "WEBSOCKET_WRITE_BUFFER_SIZE")
 	viper.BindEnv("websocket.pingperiod", "WEBSOCKET_PING_PERIOD")
 	viper.BindEnv("websocket.pongwait", "WEBSOCKET_PONG_WAIT")
+	viper.BindEnv("websocket.replaysize", "WEBSOCKET_REPLAY_SIZE")
+	viper.BindEnv("websocket.replayttl", "WEBSOCKET_REPLAY_TTL")
+	viper.BindEnv("websocket.redisprefix", "WEBSOCKET_REDIS_PREFIX")
+	viper.BindEnv("websocket.maxconnectionsperuser", "WEBSOCKET_MAX_CONNECTIONS_PER_USER")
+	viper.BindEnv("websocket.sessioncheckinterval", "WEBSOCKET_SESSION_CHECK_INTERVAL")
+
+	// Risk scoring
+	viper.BindEnv("risk.enabled", "RISK_ENABLED")
+	viper.BindEnv("risk.maxtravelspeedkmh", "RISK_MAX_TRAVEL_SPEED_KMH")
+	viper.BindEnv("risk.failedloginthreshold", "RISK_FAILED_LOGIN_THRESHOLD")
+	viper.BindEnv("risk.failedloginwindow", "RISK_FAILED_LOGIN_WINDOW")
+	viper.BindEnv("risk.massdownloadthreshold", "RISK_MASS_DOWNLOAD_THRESHOLD")
+	viper.BindEnv("risk.massdownloadwindow", "RISK_MASS_DOWNLOAD_WINDOW")
+	viper.BindEnv("risk.businesshoursstart", "RISK_BUSINESS_HOURS_START")
+	viper.BindEnv("risk.businesshoursend", "RISK_BUSINESS_HOURS_END")
+	viper.BindEnv("risk.businesstimezone", "RISK_BUSINESS_TIMEZONE")
+	viper.BindEnv("risk.newdevicelookback", "RISK_NEW_DEVICE_LOOKBACK")
+	viper.BindEnv("risk.blockimpossibletravel", "RISK_BLOCK_IMPOSSIBLE_TRAVEL")
+	viper.BindEnv("risk.blockfailedlogins", "RISK_BLOCK_FAILED_LOGINS")
+	viper.BindEnv("risk.blockmassdownloads", "RISK_BLOCK_MASS_DOWNLOADS")
+	viper.BindEnv("risk.blockoffhoursrestores", "RISK_BLOCK_OFF_HOURS_RESTORES")
+
+	// Notifications
+	viper.BindEnv("notification.smtphost", "SMTP_HOST")
+	viper.BindEnv("notification.smtpport", "SMTP_PORT")
+	viper.BindEnv("notification.smtpuser", "SMTP_USER")
+	viper.BindEnv("notification.smtppassword", "SMTP_PASSWORD")
+	viper.BindEnv("notification.smtpfrom", "SMTP_FROM")
+	viper.BindEnv("notification.maxattempts", "NOTIFICATION_MAX_ATTEMPTS")
+	viper.BindEnv("notification.timeout", "NOTIFICATION_TIMEOUT")
+	viper.BindEnv("notification.allowprivatenetworks", "NOTIFICATION_ALLOW_PRIVATE_NETWORKS")
+
+	// Webhooks
+	viper.BindEnv("webhook.maxattempts", "WEBHOOK_MAX_ATTEMPTS")
+	viper.BindEnv("webhook.timeout", "WEBHOOK_TIMEOUT")
+	viper.BindEnv("webhook.allowprivatenetworks", "WEBHOOK_ALLOW_PRIVATE_NETWORKS")
 }
 
 // IsDevelopment returns true if the application is running in development mode
@@ -381,4 +694,19 @@ func (c *Config) IsProduction() bool {
 // IsTest returns true if the application is running in test mode
 func (c *Config) IsTest() bool {
 	return c.Server.Env == "test"
-}
\ No newline at end of file
+}
+
+// Options returns the go-redis client options for the configured Redis
+// connection. The password setting overrides one in the URL.
+func (c *RedisConfig) Options() (*redis.Options, error) {
+	opts, err := redis.ParseURL(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	if c.Password != "" {
+		opts.Password = c.Password
+	}
+	opts.MaxRetries = c.MaxRetries
+	opts.PoolSize = c.PoolSize
+	return opts, nil
+}
@@ -265,6 +265,80 @@ func TestValidation(t *testing.T) {
 			expectError: true,
 			errorString: "rate limit burst must be positive",
 		},
+		{
+			name: "Invalid log level",
+			envVars: map[string]string{
+				"LOG_LEVEL": "verbose",
+			},
+			expectError: true,
+			errorString: "log level must be debug, info, warn or error",
+		},
+		{
+			name: "Invalid log format",
+			envVars: map[string]string{
+				"LOG_FORMAT": "xml",
+			},
+			expectError: true,
+			errorString: "log format must be json or text",
+		},
+		{
+			name: "Invalid tracing exporter",
+			envVars: map[string]string{
+				"TRACING_ENABLED":  "true",
+				"TRACING_EXPORTER": "zipkin",
+			},
+			expectError: true,
+			errorString: "tracing exporter must be otlp or file",
+		},
+		{
+			name: "Invalid tracing sample ratio",
+			envVars: map[string]string{
+				"TRACING_ENABLED":      "true",
+				"TRACING_SAMPLE_RATIO": "1.5",
+			},
+			expectError: true,
+			errorString: "tracing sample ratio must be between 0 and 1",
+		},
+		{
+			name: "Invalid health queue backlog",
+			envVars: map[string]string{
+				"HEALTH_QUEUE_BACKLOG": "0",
+			},
+			expectError: true,
+			errorString: "health queue backlog must be positive",
+		},
+		{
+			name: "SMTP host without from address",
+			envVars: map[string]string{
+				"SMTP_HOST": "smtp.example.com",
+			},
+			expectError: true,
+			errorString: "SMTP from address is required",
+		},
+		{
+			name: "Invalid notification max attempts",
+			envVars: map[string]string{
+				"NOTIFICATION_MAX_ATTEMPTS": "0",
+			},
+			expectError: true,
+			errorString: "notification max attempts must be positive",
+		},
+		{
+			name: "Invalid webhook timeout",
+			envVars: map[string]string{
+				"WEBHOOK_TIMEOUT": "0s",
+			},
+			expectError: true,
+			errorString: "webhook timeout must be positive",
+		},
+		{
+			name: "Invalid health check failures",
+			envVars: map[string]string{
+				"HEALTH_CHECK_FAILURES": "0",
+			},
+			expectError: true,
+			errorString: "health check failures must be positive",
+		},
 		{
 			name: "Invalid websocket read buffer size",
 			envVars: map[string]string{
@@ -378,4 +452,24 @@ func TestConfigMethods(t *testing.T) {
 			assert.Equal(t, tt.expectTest, cfg.IsTest())
 		})
 	}
-}
\ No newline at end of file
+}
+func TestRedisConfigOptions(t *testing.T) {
+	cfg := &RedisConfig{URL: "redis://:url-secret@redis.internal:6380/2", Password: "config-secret", MaxRetries: 5, PoolSize: 20}
+
+	opts, err := cfg.Options()
+	require.NoError(t, err)
+	assert.Equal(t, "redis.internal:6380", opts.Addr)
+	assert.Equal(t, 2, opts.DB)
+	assert.Equal(t, "config-secret", opts.Password)
+	assert.Equal(t, 5, opts.MaxRetries)
+	assert.Equal(t, 20, opts.PoolSize)
+
+	// Without a password setting the one in the URL is kept
+	cfg.Password = ""
+	opts, err = cfg.Options()
+	require.NoError(t, err)
+	assert.Equal(t, "url-secret", opts.Password)
+
+	_, err = (&RedisConfig{URL: "not a url"}).Options()
+	assert.Error(t, err)
+}
@@ -3,11 +3,12 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -50,6 +51,11 @@ func Initialize(cfg *config.Config) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Queries run with the context of a traced request or job join its trace
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		return fmt.Errorf("failed to register database tracing: %w", err)
+	}
+
 	// Get underlying sql.DB to configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -66,7 +72,7 @@ func Initialize(cfg *config.Config) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Printf("Database connection established successfully")
+	slog.Info("Database connection established")
 	return nil
 }
 
@@ -93,7 +99,7 @@ func Close() error {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
 
-	log.Printf("Database connection closed")
+	slog.Info("Database connection closed")
 	return nil
 }
 
@@ -109,7 +115,7 @@ func Migrate(models ...interface{}) error {
 		}
 	}
 
-	log.Printf("Database migration completed for %d models", len(models))
+	slog.Info("Database migration completed", "models", len(models))
 	return nil
 }
 
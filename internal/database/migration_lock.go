@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+const (
+	// migrationAdvisoryLockID is the PostgreSQL advisory lock key migrations
+	// hold; any constant works as long as every process uses the same one
+	migrationAdvisoryLockID int64 = 7_305_826_451
+
+	// DefaultMigrationLockTimeout is how long a process waits for another
+	// one to finish migrating
+	DefaultMigrationLockTimeout = 5 * time.Minute
+
+	// migrationLockTTL is how long a lock row outlives a process that died
+	// while migrating
+	migrationLockTTL = 15 * time.Minute
+
+	migrationLockPollInterval = 500 * time.Millisecond
+)
+
+// createMigrationLockTable creates the table of models.MigrationLock
+const createMigrationLockTable = `CREATE TABLE IF NOT EXISTS schema_migration_locks (
+	id INTEGER PRIMARY KEY,
+	holder VARCHAR(255) NOT NULL,
+	locked_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+)`
+
+// ErrMigrationLocked is returned when another process keeps migrating past the lock timeout
+var ErrMigrationLocked = errors.New("another process is running migrations")
+
+// MigrationLocker serializes migrations across processes
+type MigrationLocker interface {
+	// Lock waits until no other process migrates and returns the function that unlocks
+	Lock(ctx context.Context) (unlock func() error, err error)
+}
+
+// newMigrationLocker picks the lock the database supports
+func newMigrationLocker(db *gorm.DB) MigrationLocker {
+	if db.Dialector.Name() == "postgres" {
+		return &advisoryLocker{db: db}
+	}
+	return &tableLocker{db: db}
+}
+
+// advisoryLocker holds a PostgreSQL session advisory lock, which the server
+// releases by itself if the process dies
+type advisoryLocker struct {
+	db *gorm.DB
+}
+
+func (l *advisoryLocker) Lock(ctx context.Context) (func() error, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	// Advisory locks belong to a session, so lock and unlock on one connection
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationAdvisoryLockID).Scan(&locked); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if locked {
+			break
+		}
+		if err := waitForLock(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return func() error {
+		defer conn.Close()
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationAdvisoryLockID); err != nil {
+			return fmt.Errorf("failed to release migration lock: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// tableLocker holds a row of schema_migration_locks, for databases without
+// advisory locks. Rows of processes that died expire after migrationLockTTL.
+type tableLocker struct {
+	db *gorm.DB
+}
+
+func (l *tableLocker) Lock(ctx context.Context) (func() error, error) {
+	db := l.db.WithContext(ctx)
+
+	// Unlike AutoMigrate this is safe when processes start together
+	if err := db.Exec(createMigrationLockTable).Error; err != nil {
+		return nil, fmt.Errorf("failed to create migration lock table: %w", err)
+	}
+
+	holder := migrationLockHolder()
+	for {
+		now := time.Now()
+		if err := db.Where("expires_at < ?", now).Delete(&models.MigrationLock{}).Error; err != nil {
+			if ctx.Err() != nil {
+				// The timeout ran out while the lock was held
+				return nil, ErrMigrationLocked
+			}
+			return nil, fmt.Errorf("failed to clear expired migration lock: %w", err)
+		}
+
+		lock := &models.MigrationLock{ID: 1, Holder: holder, LockedAt: now, ExpiresAt: now.Add(migrationLockTTL)}
+		if err := db.Create(lock).Error; err == nil {
+			break
+		}
+
+		if err := waitForLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() error {
+		err := l.db.Where("id = ? AND holder = ?", 1, holder).Delete(&models.MigrationLock{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to release migration lock: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// waitForLock waits before trying the lock again
+func waitForLock(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ErrMigrationLocked
+	case <-time.After(migrationLockPollInterval):
+		return nil
+	}
+}
+
+// migrationLockHolder names this process in lock rows
+func migrationLockHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
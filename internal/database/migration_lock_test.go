@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// openMigrationTestDB opens a SQLite file, so that every pooled connection
+// sees the same database
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	dsn := filepath.Join(t.TempDir(), "migrations.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestTableLocker(t *testing.T) {
+	db := openMigrationTestDB(t)
+	locker := newMigrationLocker(db)
+	require.IsType(t, &tableLocker{}, locker)
+
+	unlock, err := locker.Lock(context.Background())
+	require.NoError(t, err)
+
+	// A second process waits and gives up at its timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = locker.Lock(ctx)
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+
+	// and gets the lock once it is released
+	require.NoError(t, unlock())
+	unlock, err = locker.Lock(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+
+	var count int64
+	require.NoError(t, db.Model(&models.MigrationLock{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestTableLocker_TakesOverExpiredLock(t *testing.T) {
+	db := openMigrationTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.MigrationLock{}))
+
+	// A process died while migrating
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, db.Create(&models.MigrationLock{ID: 1, Holder: "crashed", LockedAt: past, ExpiresAt: past}).Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err := newMigrationLocker(db).Lock(ctx)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
+
+// recordingLocker notes whether the migration tables existed when it was locked
+type recordingLocker struct {
+	db          *gorm.DB
+	held        bool
+	tablesFirst bool
+}
+
+func (l *recordingLocker) Lock(ctx context.Context) (func() error, error) {
+	l.held = true
+	l.tablesFirst = l.db.Migrator().HasTable(&models.Migration{})
+	return func() error {
+		l.held = false
+		return nil
+	}, nil
+}
+
+func TestMigrationSystem_InitializeHoldsLock(t *testing.T) {
+	db := openMigrationTestDB(t)
+	locker := &recordingLocker{db: db}
+
+	ms := NewMigrationSystem(db, "")
+	ms.SetLocker(locker)
+	require.NoError(t, ms.Initialize())
+
+	// The tables were created while the lock was held, not before it
+	assert.False(t, locker.tablesFirst)
+	assert.False(t, locker.held)
+	assert.True(t, db.Migrator().HasTable(&models.Migration{}))
+	assert.True(t, db.Migrator().HasTable(&models.MigrationBatch{}))
+}
+
+func TestMigrationSystem_InitializeWaitsForLock(t *testing.T) {
+	db := openMigrationTestDB(t)
+	unlock, err := newMigrationLocker(db).Lock(context.Background())
+	require.NoError(t, err)
+	defer unlock()
+
+	// Another process is migrating, so no DDL runs until it is done
+	ms := NewMigrationSystem(db, "")
+	ms.SetLockTimeout(time.Second)
+	assert.ErrorIs(t, ms.Initialize(), ErrMigrationLocked)
+	assert.False(t, db.Migrator().HasTable(&models.Migration{}))
+}
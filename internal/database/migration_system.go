@@ -1,10 +1,13 @@
 package database
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -13,6 +16,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/dbackup/backend-go/internal/models"
 )
 
@@ -27,6 +31,7 @@ type MigrationDefinition struct {
 	Up          MigrationFunc
 	Down        MigrationFunc
 	FilePath    string
+	SQL         string // File content of SQL migrations
 }
 
 // MigrationSystem handles database migrations with versioning support
@@ -35,6 +40,31 @@ type MigrationSystem struct {
 	migrationsDir string
 	migrations   []*MigrationDefinition
 	logger       Logger
+	locker       MigrationLocker
+	lockTimeout  time.Duration
+	dryRun       io.Writer
+}
+
+// ChecksumMismatchError is returned when applied migrations were edited afterwards
+type ChecksumMismatchError struct {
+	Versions []string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("applied migrations changed since they ran: %s", strings.Join(e.Versions, ", "))
+}
+
+// MigrationReport is the state of a known or applied migration
+type MigrationReport struct {
+	Version   string
+	Name      string
+	Status    models.MigrationStatus
+	AppliedAt *time.Time
+	Error     string
+	// Drifted is set when the migration changed after it was applied
+	Drifted bool
+	// Missing is set when the database knows a migration this build does not
+	Missing bool
 }
 
 // Logger interface for migration logging
@@ -65,7 +95,8 @@ func NewMigrationSystem(db *gorm.DB, migrationsDir string) *MigrationSystem {
 		db:            db,
 		migrationsDir: migrationsDir,
 		migrations:    make([]*MigrationDefinition, 0),
-		logger:        DefaultLogger{},
+		logger:        logging.PrintfLogger{},
+		lockTimeout:   DefaultMigrationLockTimeout,
 	}
 }
 
@@ -74,19 +105,44 @@ func (ms *MigrationSystem) SetLogger(logger Logger) {
 	ms.logger = logger
 }
 
-// Initialize initializes the migration system by creating necessary tables
+// SetLocker replaces the lock that keeps processes from migrating at once
+func (ms *MigrationSystem) SetLocker(locker MigrationLocker) {
+	ms.locker = locker
+}
+
+// SetLockTimeout sets how long to wait for another process to finish migrating
+func (ms *MigrationSystem) SetLockTimeout(timeout time.Duration) {
+	ms.lockTimeout = timeout
+}
+
+// SetDryRun makes Up and Down write the SQL they would run to out instead of
+// running it; nil turns dry runs off
+func (ms *MigrationSystem) SetDryRun(out io.Writer) {
+	ms.dryRun = out
+}
+
+// Initialize initializes the migration system by creating necessary tables.
+// The tables are created under the migration lock, so processes starting
+// together do not race on the DDL.
 func (ms *MigrationSystem) Initialize() error {
 	ms.logger.Info("Initializing migration system...")
 
-	// Create migration tables
-	if err := ms.db.AutoMigrate(&models.Migration{}, &models.MigrationBatch{}); err != nil {
-		return fmt.Errorf("failed to create migration tables: %w", err)
+	if err := ms.locked(ms.createTables); err != nil {
+		return err
 	}
 
 	ms.logger.Info("Migration system initialized successfully")
 	return nil
 }
 
+// createTables creates the migration tables; the caller holds the migration lock
+func (ms *MigrationSystem) createTables() error {
+	if err := ms.db.AutoMigrate(&models.Migration{}, &models.MigrationBatch{}); err != nil {
+		return fmt.Errorf("failed to create migration tables: %w", err)
+	}
+	return nil
+}
+
 // RegisterMigration registers a migration definition
 func (ms *MigrationSystem) RegisterMigration(migration *MigrationDefinition) error {
 	if migration.Version == "" {
@@ -122,29 +178,47 @@ func (ms *MigrationSystem) LoadMigrationsFromDir() error {
 	}
 
 	ms.logger.Info("Loading migrations from directory: %s", ms.migrationsDir)
+	return ms.loadMigrations(os.DirFS(ms.migrationsDir), ms.migrationsDir)
+}
+
+// LoadMigrationsFromFS loads migration files from a file system, such as the
+// migrations embedded in the binary
+func (ms *MigrationSystem) LoadMigrationsFromFS(fsys fs.FS) error {
+	ms.logger.Info("Loading embedded migrations")
+	return ms.loadMigrations(fsys, "")
+}
 
-	err := filepath.WalkDir(ms.migrationsDir, func(path string, d fs.DirEntry, err error) error {
+// loadMigrations registers the SQL files of a file system; dir is prefixed
+// to the file paths recorded for them
+func (ms *MigrationSystem) loadMigrations(fsys fs.FS, dir string) error {
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if d.IsDir() || !strings.HasSuffix(path, ".sql") {
+		if d.IsDir() || !strings.HasSuffix(filePath, ".sql") {
 			return nil
 		}
 
-		migration, err := ms.parseMigrationFile(path)
+		content, err := fs.ReadFile(fsys, filePath)
 		if err != nil {
-			ms.logger.Error("Failed to parse migration file %s: %v", path, err)
+			return fmt.Errorf("failed to read migration file %s: %w", filePath, err)
+		}
+
+		if dir != "" {
+			filePath = filepath.Join(dir, filepath.FromSlash(filePath))
+		}
+		migration, err := ms.parseMigration(filePath, string(content))
+		if err != nil {
+			ms.logger.Error("Failed to parse migration file %s: %v", filePath, err)
 			return err
 		}
 
-		if migration != nil {
-			if err := ms.RegisterMigration(migration); err != nil {
-				ms.logger.Error("Failed to register migration %s: %v", path, err)
-				return err
-			}
-			ms.logger.Info("Loaded migration: %s - %s", migration.Version, migration.Name)
+		if err := ms.RegisterMigration(migration); err != nil {
+			ms.logger.Error("Failed to register migration %s: %v", filePath, err)
+			return err
 		}
+		ms.logger.Info("Loaded migration: %s - %s", migration.Version, migration.Name)
 
 		return nil
 	})
@@ -156,19 +230,13 @@ func (ms *MigrationSystem) LoadMigrationsFromDir() error {
 	// Sort migrations by version
 	ms.sortMigrations()
 
-	ms.logger.Info("Loaded %d migrations from directory", len(ms.migrations))
+	ms.logger.Info("Loaded %d migrations", len(ms.migrations))
 	return nil
 }
 
-// parseMigrationFile parses a SQL migration file
-func (ms *MigrationSystem) parseMigrationFile(filePath string) (*MigrationDefinition, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read migration file: %w", err)
-	}
-
-	filename := filepath.Base(filePath)
-	version, name, err := ms.parseMigrationFilename(filename)
+// parseMigration parses the content of a SQL migration file
+func (ms *MigrationSystem) parseMigration(filePath, content string) (*MigrationDefinition, error) {
+	version, name, err := ms.parseMigrationFilename(path.Base(filepath.ToSlash(filePath)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse migration filename: %w", err)
 	}
@@ -177,8 +245,9 @@ func (ms *MigrationSystem) parseMigrationFile(filePath string) (*MigrationDefini
 		Version:  version,
 		Name:     name,
 		FilePath: filePath,
-		Up:       ms.createSQLMigrationFunc(string(content), true),
-		Down:     ms.createSQLMigrationFunc(string(content), false),
+		SQL:      content,
+		Up:       ms.createSQLMigrationFunc(content, true),
+		Down:     ms.createSQLMigrationFunc(content, false),
 	}
 
 	return migration, nil
@@ -309,6 +378,12 @@ func (ms *MigrationSystem) sortMigrations() {
 
 // Up runs pending migrations
 func (ms *MigrationSystem) Up(targetVersion string) error {
+	return ms.withLock(func() error {
+		return ms.up(targetVersion)
+	})
+}
+
+func (ms *MigrationSystem) up(targetVersion string) error {
 	ms.logger.Info("Starting migration up to version: %s", targetVersion)
 
 	// Get pending migrations
@@ -322,6 +397,10 @@ func (ms *MigrationSystem) Up(targetVersion string) error {
 		return nil
 	}
 
+	if ms.dryRun != nil {
+		return ms.printPlan(pendingMigrations, true)
+	}
+
 	// Create migration batch
 	batch, err := ms.createMigrationBatch(len(pendingMigrations))
 	if err != nil {
@@ -362,6 +441,12 @@ func (ms *MigrationSystem) Up(targetVersion string) error {
 
 // Down rolls back migrations
 func (ms *MigrationSystem) Down(targetVersion string) error {
+	return ms.withLock(func() error {
+		return ms.down(targetVersion)
+	})
+}
+
+func (ms *MigrationSystem) down(targetVersion string) error {
 	ms.logger.Info("Starting migration down to version: %s", targetVersion)
 
 	// Get applied migrations to rollback
@@ -369,17 +454,60 @@ func (ms *MigrationSystem) Down(targetVersion string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get migrations to rollback: %w", err)
 	}
+	return ms.rollback(migrationsToRollback)
+}
+
+// Rollback rolls back the most recently applied migrations
+func (ms *MigrationSystem) Rollback(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be positive")
+	}
+
+	return ms.withLock(func() error {
+		migrationsToRollback, err := ms.getMigrationsToRollback("")
+		if err != nil {
+			return fmt.Errorf("failed to get migrations to rollback: %w", err)
+		}
+		// Migrations to roll back are ordered newest first
+		if steps < len(migrationsToRollback) {
+			migrationsToRollback = migrationsToRollback[:steps]
+		}
+		return ms.rollback(migrationsToRollback)
+	})
+}
+
+// MigrateTo runs or rolls back migrations until targetVersion is the latest applied one
+func (ms *MigrationSystem) MigrateTo(targetVersion string) error {
+	if targetVersion == "" {
+		return fmt.Errorf("target version cannot be empty")
+	}
+	if !ms.hasMigration(targetVersion) {
+		return fmt.Errorf("unknown migration version: %s", targetVersion)
+	}
 
+	return ms.withLock(func() error {
+		if err := ms.down(targetVersion); err != nil {
+			return err
+		}
+		return ms.up(targetVersion)
+	})
+}
+
+// rollback rolls back migrations, given newest first
+func (ms *MigrationSystem) rollback(migrationsToRollback []*MigrationDefinition) error {
 	if len(migrationsToRollback) == 0 {
 		ms.logger.Info("No migrations to rollback")
 		return nil
 	}
 
+	if ms.dryRun != nil {
+		return ms.printPlan(migrationsToRollback, false)
+	}
+
 	ms.logger.Info("Rolling back %d migrations", len(migrationsToRollback))
 
-	// Rollback migrations in reverse order
-	for i := len(migrationsToRollback) - 1; i >= 0; i-- {
-		migration := migrationsToRollback[i]
+	// Rollback migrations newest first
+	for _, migration := range migrationsToRollback {
 		if err := ms.runMigration(migration, false); err != nil {
 			ms.logger.Error("Rollback of migration %s failed: %v", migration.Version, err)
 			return fmt.Errorf("rollback failed at migration %s: %w", migration.Version, err)
@@ -391,6 +519,156 @@ func (ms *MigrationSystem) Down(targetVersion string) error {
 	return nil
 }
 
+// withLock runs fn while holding the migration lock, after making sure the
+// migration tables exist and checking that no applied migration changed
+func (ms *MigrationSystem) withLock(fn func() error) error {
+	return ms.locked(func() error {
+		if err := ms.createTables(); err != nil {
+			return err
+		}
+
+		drifted, err := ms.Verify()
+		if err != nil {
+			return err
+		}
+		if len(drifted) > 0 {
+			return &ChecksumMismatchError{Versions: drifted}
+		}
+
+		return fn()
+	})
+}
+
+// locked runs fn while holding the migration lock
+func (ms *MigrationSystem) locked(fn func() error) error {
+	if ms.locker == nil {
+		ms.locker = newMigrationLocker(ms.db)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ms.lockTimeout)
+	defer cancel()
+
+	unlock, err := ms.locker.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			ms.logger.Error("%v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// Verify compares applied migrations with the loaded ones and returns the
+// versions whose content changed since they were applied
+func (ms *MigrationSystem) Verify() ([]string, error) {
+	var applied []models.Migration
+	if err := ms.db.Where("status = ?", models.MigrationStatusApplied).Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	definitions := ms.definitionsByVersion()
+	var drifted []string
+	for _, record := range applied {
+		migration, ok := definitions[record.Version]
+		if !ok {
+			continue
+		}
+		if record.Checksum != ms.calculateChecksum(migration) {
+			drifted = append(drifted, record.Version)
+		}
+	}
+	return drifted, nil
+}
+
+// Report lists loaded and applied migrations with their state, oldest first
+func (ms *MigrationSystem) Report() ([]*MigrationReport, error) {
+	records, err := ms.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	definitions := ms.definitionsByVersion()
+	reports := make(map[string]*MigrationReport)
+	for _, record := range records {
+		report := &MigrationReport{
+			Version:   record.Version,
+			Name:      record.Name,
+			Status:    record.Status,
+			AppliedAt: record.AppliedAt,
+			Error:     record.ErrorMessage,
+		}
+		if migration, ok := definitions[record.Version]; ok {
+			report.Drifted = record.IsApplied() && record.Checksum != ms.calculateChecksum(migration)
+		} else {
+			report.Missing = true
+		}
+		reports[record.Version] = report
+	}
+	for _, migration := range ms.migrations {
+		if _, ok := reports[migration.Version]; !ok {
+			reports[migration.Version] = &MigrationReport{
+				Version: migration.Version,
+				Name:    migration.Name,
+				Status:  models.MigrationStatusPending,
+			}
+		}
+	}
+
+	result := make([]*MigrationReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, report)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// printPlan writes what a dry run would run
+func (ms *MigrationSystem) printPlan(migrations []*MigrationDefinition, isUp bool) error {
+	direction := "up"
+	if !isUp {
+		direction = "down"
+	}
+
+	for _, migration := range migrations {
+		fmt.Fprintf(ms.dryRun, "-- %s %s (%s)\n", migration.Version, migration.Name, direction)
+
+		if migration.SQL == "" {
+			fmt.Fprintln(ms.dryRun, "-- Go migration, SQL not available")
+			fmt.Fprintln(ms.dryRun)
+			continue
+		}
+
+		sql := ms.extractUpSQL(migration.SQL)
+		if !isUp {
+			sql = ms.extractDownSQL(migration.SQL)
+		}
+		for _, stmt := range ms.splitSQLStatements(sql) {
+			fmt.Fprintf(ms.dryRun, "%s;\n", stmt)
+		}
+		fmt.Fprintln(ms.dryRun)
+	}
+	return nil
+}
+
+// hasMigration checks if a version is loaded
+func (ms *MigrationSystem) hasMigration(version string) bool {
+	_, ok := ms.definitionsByVersion()[version]
+	return ok
+}
+
+func (ms *MigrationSystem) definitionsByVersion() map[string]*MigrationDefinition {
+	definitions := make(map[string]*MigrationDefinition, len(ms.migrations))
+	for _, migration := range ms.migrations {
+		definitions[migration.Version] = migration
+	}
+	return definitions
+}
+
 // Status returns the current migration status
 func (ms *MigrationSystem) Status() ([]*models.Migration, error) {
 	var migrations []*models.Migration
@@ -480,6 +758,11 @@ func (ms *MigrationSystem) runMigration(migration *MigrationDefinition, isUp boo
 		}
 	}
 
+	// A migration applied again records the content it now runs
+	if isUp {
+		migrationRecord.Checksum = ms.calculateChecksum(migration)
+	}
+
 	// Execute migration in transaction
 	err = ms.db.Transaction(func(tx *gorm.DB) error {
 		var migrationFunc MigrationFunc
@@ -544,7 +827,9 @@ func (ms *MigrationSystem) createMigrationBatch(count int) (*models.MigrationBat
 // calculateChecksum calculates a checksum for migration content
 func (ms *MigrationSystem) calculateChecksum(migration *MigrationDefinition) string {
 	content := fmt.Sprintf("%s:%s:%s", migration.Version, migration.Name, migration.Description)
-	if migration.FilePath != "" {
+	if migration.SQL != "" {
+		content += migration.SQL
+	} else if migration.FilePath != "" {
 		if fileContent, err := os.ReadFile(migration.FilePath); err == nil {
 			content += string(fileContent)
 		}
@@ -1,11 +1,12 @@
 package database
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/migrations"
 )
 
 type MigrationSystemTestSuite struct {
@@ -705,4 +707,137 @@ func CleanupTestDB(db *gorm.DB, dbName string) {
 		db.Exec("DROP TABLE IF EXISTS target_test3")
 		db.Exec("DROP TABLE IF EXISTS benchmark_test")
 	}
-}
\ No newline at end of file
+}
+func testMigrationFS(contents ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for i, content := range contents {
+		name := fmt.Sprintf("2024010100000%d_step_%d.sql", i+1, i+1)
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func testTableMigration(table string) string {
+	return fmt.Sprintf("-- +migrate Up\nCREATE TABLE %s (id INTEGER PRIMARY KEY);\n\n-- +migrate Down\nDROP TABLE %s;\n", table, table)
+}
+
+func newTestMigrationSystem(t *testing.T, db *gorm.DB, fsys fstest.MapFS) *MigrationSystem {
+	ms := NewMigrationSystem(db, "")
+	ms.SetLogger(testLogger{})
+	require.NoError(t, ms.Initialize())
+	require.NoError(t, ms.LoadMigrationsFromFS(fsys))
+	return ms
+}
+
+type testLogger struct{}
+
+func (testLogger) Info(msg string, args ...interface{})  {}
+func (testLogger) Error(msg string, args ...interface{}) {}
+func (testLogger) Warn(msg string, args ...interface{})  {}
+
+func TestMigrationSystem_LoadsEmbeddedMigrations(t *testing.T) {
+	ms := NewMigrationSystem(nil, "")
+	ms.SetLogger(testLogger{})
+	require.NoError(t, ms.LoadMigrationsFromFS(migrations.FS))
+
+	require.NotEmpty(t, ms.migrations)
+	assert.Equal(t, "20240101000001", ms.migrations[0].Version)
+	assert.NotEmpty(t, ms.migrations[0].SQL)
+}
+
+func TestMigrationSystem_RefusesChangedMigrations(t *testing.T) {
+	db := openMigrationTestDB(t)
+	require.NoError(t, newTestMigrationSystem(t, db, testMigrationFS(testTableMigration("drift_a"))).Up(""))
+
+	// The applied migration is edited and a new one added
+	edited := testMigrationFS(
+		"-- +migrate Up\nCREATE TABLE drift_a (id INTEGER PRIMARY KEY, name TEXT);\n",
+		testTableMigration("drift_b"),
+	)
+	ms := newTestMigrationSystem(t, db, edited)
+
+	drifted, err := ms.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20240101000001"}, drifted)
+
+	var mismatch *ChecksumMismatchError
+	require.ErrorAs(t, ms.Up(""), &mismatch)
+	assert.Equal(t, []string{"20240101000001"}, mismatch.Versions)
+	assert.False(t, db.Migrator().HasTable("drift_b"))
+
+	reports, err := ms.Report()
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.True(t, reports[0].Drifted)
+	assert.Equal(t, models.MigrationStatusPending, reports[1].Status)
+}
+
+func TestMigrationSystem_DryRun(t *testing.T) {
+	db := openMigrationTestDB(t)
+	ms := newTestMigrationSystem(t, db, testMigrationFS(testTableMigration("dry_a"), testTableMigration("dry_b")))
+
+	var out bytes.Buffer
+	ms.SetDryRun(&out)
+	require.NoError(t, ms.Up(""))
+
+	assert.Contains(t, out.String(), "-- 20240101000001 step 1 (up)\nCREATE TABLE dry_a (id INTEGER PRIMARY KEY);\n")
+	assert.Contains(t, out.String(), "CREATE TABLE dry_b")
+	assert.False(t, db.Migrator().HasTable("dry_a"))
+
+	version, err := ms.GetVersion()
+	require.NoError(t, err)
+	assert.Empty(t, version)
+}
+
+func TestMigrationSystem_MigrateToAndRollback(t *testing.T) {
+	db := openMigrationTestDB(t)
+	ms := newTestMigrationSystem(t, db, testMigrationFS(
+		testTableMigration("step_a"),
+		testTableMigration("step_b"),
+		testTableMigration("step_c"),
+	))
+
+	require.NoError(t, ms.MigrateTo("20240101000002"))
+	assert.True(t, db.Migrator().HasTable("step_b"))
+	assert.False(t, db.Migrator().HasTable("step_c"))
+
+	require.NoError(t, ms.Up(""))
+	assert.True(t, db.Migrator().HasTable("step_c"))
+
+	// Newest migrations are rolled back first
+	require.NoError(t, ms.Rollback(1))
+	assert.False(t, db.Migrator().HasTable("step_c"))
+	assert.True(t, db.Migrator().HasTable("step_b"))
+
+	require.NoError(t, ms.MigrateTo("20240101000001"))
+	assert.False(t, db.Migrator().HasTable("step_b"))
+	version, err := ms.GetVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "20240101000001", version)
+
+	assert.Error(t, ms.MigrateTo("20991231000000"))
+	assert.Error(t, ms.Rollback(0))
+}
+
+func TestMigrationSystem_ConcurrentUp(t *testing.T) {
+	db := openMigrationTestDB(t)
+	fsys := testMigrationFS(testTableMigration("race_a"), testTableMigration("race_b"))
+
+	// Two replicas start at once; the second waits and finds nothing to do
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		ms := newTestMigrationSystem(t, db, fsys)
+		go func() { errs <- ms.Up("") }()
+	}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	var applied int64
+	require.NoError(t, db.Model(&models.Migration{}).Where("status = ?", models.MigrationStatusApplied).Count(&applied).Error)
+	assert.Equal(t, int64(2), applied)
+
+	var batches int64
+	require.NoError(t, db.Model(&models.MigrationBatch{}).Count(&batches).Error)
+	assert.Equal(t, int64(1), batches)
+}
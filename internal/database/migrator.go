@@ -1,11 +1,14 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"reflect"
 
 	"gorm.io/gorm"
+
+	"github.com/dbackup/backend-go/migrations"
 )
 
 // MigratorInterface defines the interface for database migration operations
@@ -38,23 +41,32 @@ func (m *Migrator) AutoMigrate(models ...interface{}) error {
 		return fmt.Errorf("no models provided for migration")
 	}
 
-	log.Printf("Starting auto-migration for %d models", len(models))
+	slog.InfoContext(m.logContext(), "Starting auto-migration", "models", len(models))
 
 	for _, model := range models {
 		modelName := getModelName(model)
-		log.Printf("Migrating model: %s", modelName)
+		slog.DebugContext(m.logContext(), "Migrating model", "model", modelName)
 
 		if err := m.db.AutoMigrate(model); err != nil {
 			return fmt.Errorf("failed to migrate model %s: %w", modelName, err)
 		}
 
-		log.Printf("Successfully migrated model: %s", modelName)
+		slog.DebugContext(m.logContext(), "Migrated model", "model", modelName)
 	}
 
-	log.Printf("Auto-migration completed successfully")
+	slog.InfoContext(m.logContext(), "Auto-migration completed")
 	return nil
 }
 
+// logContext returns the context of the migrator's database session, which
+// carries the log fields of the request or job it runs for
+func (m *Migrator) logContext() context.Context {
+	if m.db.Statement != nil && m.db.Statement.Context != nil {
+		return m.db.Statement.Context
+	}
+	return context.Background()
+}
+
 // CreateTable creates tables for the given models
 func (m *Migrator) CreateTable(models ...interface{}) error {
 	migrator := m.db.Migrator()
@@ -63,7 +75,7 @@ func (m *Migrator) CreateTable(models ...interface{}) error {
 		modelName := getModelName(model)
 		
 		if migrator.HasTable(model) {
-			log.Printf("Table for model %s already exists, skipping", modelName)
+			slog.InfoContext(m.logContext(), "Table already exists, skipping", "model", modelName)
 			continue
 		}
 
@@ -71,7 +83,7 @@ func (m *Migrator) CreateTable(models ...interface{}) error {
 			return fmt.Errorf("failed to create table for model %s: %w", modelName, err)
 		}
 
-		log.Printf("Created table for model: %s", modelName)
+		slog.InfoContext(m.logContext(), "Created table", "model", modelName)
 	}
 
 	return nil
@@ -85,7 +97,7 @@ func (m *Migrator) DropTable(models ...interface{}) error {
 		modelName := getModelName(model)
 		
 		if !migrator.HasTable(model) {
-			log.Printf("Table for model %s does not exist, skipping", modelName)
+			slog.InfoContext(m.logContext(), "Table does not exist, skipping", "model", modelName)
 			continue
 		}
 
@@ -93,7 +105,7 @@ func (m *Migrator) DropTable(models ...interface{}) error {
 			return fmt.Errorf("failed to drop table for model %s: %w", modelName, err)
 		}
 
-		log.Printf("Dropped table for model: %s", modelName)
+		slog.InfoContext(m.logContext(), "Dropped table", "model", modelName)
 	}
 
 	return nil
@@ -109,7 +121,7 @@ func (m *Migrator) CreateIndex(model interface{}, indexName string) error {
 	migrator := m.db.Migrator()
 	
 	if migrator.HasIndex(model, indexName) {
-		log.Printf("Index %s already exists for model %s, skipping", indexName, getModelName(model))
+		slog.InfoContext(m.logContext(), "Index already exists, skipping", "index", indexName, "model", getModelName(model))
 		return nil
 	}
 
@@ -117,7 +129,7 @@ func (m *Migrator) CreateIndex(model interface{}, indexName string) error {
 		return fmt.Errorf("failed to create index %s for model %s: %w", indexName, getModelName(model), err)
 	}
 
-	log.Printf("Created index %s for model %s", indexName, getModelName(model))
+	slog.InfoContext(m.logContext(), "Created index", "index", indexName, "model", getModelName(model))
 	return nil
 }
 
@@ -126,7 +138,7 @@ func (m *Migrator) DropIndex(model interface{}, indexName string) error {
 	migrator := m.db.Migrator()
 	
 	if !migrator.HasIndex(model, indexName) {
-		log.Printf("Index %s does not exist for model %s, skipping", indexName, getModelName(model))
+		slog.InfoContext(m.logContext(), "Index does not exist, skipping", "index", indexName, "model", getModelName(model))
 		return nil
 	}
 
@@ -134,7 +146,7 @@ func (m *Migrator) DropIndex(model interface{}, indexName string) error {
 		return fmt.Errorf("failed to drop index %s for model %s: %w", indexName, getModelName(model), err)
 	}
 
-	log.Printf("Dropped index %s for model %s", indexName, getModelName(model))
+	slog.InfoContext(m.logContext(), "Dropped index", "index", indexName, "model", getModelName(model))
 	return nil
 }
 
@@ -148,7 +160,7 @@ func (m *Migrator) CreateConstraint(model interface{}, constraintName string) er
 	migrator := m.db.Migrator()
 	
 	if migrator.HasConstraint(model, constraintName) {
-		log.Printf("Constraint %s already exists for model %s, skipping", constraintName, getModelName(model))
+		slog.InfoContext(m.logContext(), "Constraint already exists, skipping", "constraint", constraintName, "model", getModelName(model))
 		return nil
 	}
 
@@ -156,7 +168,7 @@ func (m *Migrator) CreateConstraint(model interface{}, constraintName string) er
 		return fmt.Errorf("failed to create constraint %s for model %s: %w", constraintName, getModelName(model), err)
 	}
 
-	log.Printf("Created constraint %s for model %s", constraintName, getModelName(model))
+	slog.InfoContext(m.logContext(), "Created constraint", "constraint", constraintName, "model", getModelName(model))
 	return nil
 }
 
@@ -165,7 +177,7 @@ func (m *Migrator) DropConstraint(model interface{}, constraintName string) erro
 	migrator := m.db.Migrator()
 	
 	if !migrator.HasConstraint(model, constraintName) {
-		log.Printf("Constraint %s does not exist for model %s, skipping", constraintName, getModelName(model))
+		slog.InfoContext(m.logContext(), "Constraint does not exist, skipping", "constraint", constraintName, "model", getModelName(model))
 		return nil
 	}
 
@@ -173,7 +185,7 @@ func (m *Migrator) DropConstraint(model interface{}, constraintName string) erro
 		return fmt.Errorf("failed to drop constraint %s for model %s: %w", constraintName, getModelName(model), err)
 	}
 
-	log.Printf("Dropped constraint %s for model %s", constraintName, getModelName(model))
+	slog.InfoContext(m.logContext(), "Dropped constraint", "constraint", constraintName, "model", getModelName(model))
 	return nil
 }
 
@@ -188,14 +200,14 @@ func (m *Migrator) RunMigrations(migrations []func(*Migrator) error) error {
 		txMigrator := NewMigrator(tx)
 		
 		for i, migration := range migrations {
-			log.Printf("Running migration step %d/%d", i+1, len(migrations))
+			slog.InfoContext(txMigrator.logContext(), "Running migration step", "step", i+1, "steps", len(migrations))
 			
 			if err := migration(txMigrator); err != nil {
 				return fmt.Errorf("migration step %d failed: %w", i+1, err)
 			}
 		}
 		
-		log.Printf("All migration steps completed successfully")
+		slog.InfoContext(txMigrator.logContext(), "All migration steps completed")
 		return nil
 	})
 }
@@ -213,16 +225,15 @@ func RunFullMigration() error {
 	}
 
 	// Initialize versioned migration system
-	migrationSystem := NewMigrationSystem(db, "migrations")
-	migrationSystem.SetLogger(DefaultLogger{})
+	migrationSystem := NewMigrationSystem(db, "")
 
-	// Initialize migration tables
+	// Create the migration tables under the migration lock
 	if err := migrationSystem.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize migration system: %w", err)
 	}
 
-	// Load migrations from files
-	if err := migrationSystem.LoadMigrationsFromDir(); err != nil {
+	// Load the migrations embedded in the binary
+	if err := migrationSystem.LoadMigrationsFromFS(migrations.FS); err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
@@ -231,7 +242,7 @@ func RunFullMigration() error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	log.Printf("Migration system completed successfully")
+	slog.Info("Migration system completed")
 	return nil
 }
 
@@ -239,12 +250,11 @@ func RunFullMigration() error {
 func GetMigrationSystem(migrationsDir string) *MigrationSystem {
 	db := GetDB()
 	if db == nil {
-		log.Printf("Warning: database not initialized for migration system")
+		slog.Warn("Database not initialized for migration system")
 		return nil
 	}
 
 	migrationSystem := NewMigrationSystem(db, migrationsDir)
-	migrationSystem.SetLogger(DefaultLogger{})
 	return migrationSystem
 }
 
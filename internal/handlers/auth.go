@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"github.com/dbackup/backend-go/internal/middleware"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
 	"github.com/dbackup/backend-go/internal/utils"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -20,14 +23,17 @@ type AuthHandler struct {
 	jwtManager     *auth.JWTManager
 	passwordHasher *auth.PasswordHasher
 	totpManager    *auth.TOTPManager
+	auditService   services.AuditServiceInterface
 }
 
-// NewAuthHandler creates a new authentication handler
-func NewAuthHandler(jwtManager *auth.JWTManager, passwordHasher *auth.PasswordHasher, totpManager *auth.TOTPManager) *AuthHandler {
+// NewAuthHandler creates a new authentication handler. Logins are audited
+// and risk scored when auditService is set.
+func NewAuthHandler(jwtManager *auth.JWTManager, passwordHasher *auth.PasswordHasher, totpManager *auth.TOTPManager, auditService services.AuditServiceInterface) *AuthHandler {
 	return &AuthHandler{
 		jwtManager:     jwtManager,
 		passwordHasher: passwordHasher,
 		totpManager:    totpManager,
+		auditService:   auditService,
 	}
 }
 
@@ -183,6 +189,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	var user models.User
 	if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			h.recordLogin(c, nil, req.Email, http.StatusUnauthorized, "unknown email")
 			return responses.Unauthorized(c, "Invalid credentials")
 		}
 		return responses.InternalError(c, "Database error")
@@ -190,6 +197,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	// Check if account is active
 	if !user.IsActive {
+		h.recordLogin(c, &user, req.Email, http.StatusUnauthorized, "account disabled")
 		return responses.Unauthorized(c, "Account is disabled. Please contact support.")
 	}
 
@@ -212,6 +220,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 		db.Save(&user)
 
+		h.recordLogin(c, &user, req.Email, http.StatusUnauthorized, "invalid password")
 		return responses.Unauthorized(c, "Invalid credentials")
 	}
 
@@ -234,10 +243,16 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		}
 
 		if !totpValid {
+			h.recordLogin(c, &user, req.Email, http.StatusUnauthorized, "invalid two-factor code")
 			return responses.Unauthorized(c, "Invalid two-factor authentication code")
 		}
 	}
 
+	// The risk policy may block a login that follows a burst of failures or comes from too far away
+	if errors.Is(h.recordLogin(c, &user, req.Email, http.StatusOK, ""), services.ErrActionBlocked) {
+		return responses.Error(c, http.StatusForbidden, "Login blocked for security reasons. Please contact support.")
+	}
+
 	// Reset failed login attempts on successful login
 	user.LoginAttempts = 0
 	user.LockedUntil = nil
@@ -280,6 +295,44 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	return responses.Success(c, "Login successful", &user)
 }
 
+// recordLogin audits a login attempt with its outcome. It returns
+// services.ErrActionBlocked when the risk policy blocks the attempt.
+func (h *AuthHandler) recordLogin(c echo.Context, user *models.User, email string, statusCode int, failure string) error {
+	if h.auditService == nil {
+		return nil
+	}
+
+	req := c.Request()
+	event := &models.AuditLog{
+		Action:     models.AuditActionLogin,
+		Resource:   models.AuditResourceSession,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		IPAddress:  c.RealIP(),
+		StatusCode: statusCode,
+	}
+	if user != nil {
+		event.UserID = &user.ID
+		event.ResourceID = &user.ID
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		event.RequestID = &requestID
+	}
+	if failure != "" {
+		event.ErrorMessage = &failure
+	}
+	event.SetMetadata("email", email)
+
+	_, err := h.auditService.Record(req.Context(), event)
+	if err != nil && !errors.Is(err, services.ErrActionBlocked) {
+		slog.ErrorContext(req.Context(), "Failed to record login audit event", "error", err)
+	}
+	return err
+}
+
 // RefreshRequest represents the token refresh request
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
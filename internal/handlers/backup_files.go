@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Ways a backup file download is served
+const (
+	downloadMethodPresigned = "presigned"
+	downloadMethodProxy     = "proxy"
+)
+
+// BackupFileHandler lists the files of backups and hands out downloads
+type BackupFileHandler struct {
+	db           *gorm.DB
+	storage      services.StorageResolver
+	cipher       *services.BackupCipher
+	auditService services.AuditServiceInterface
+	tablePolicy  *services.TablePolicy
+	urlExpiry    time.Duration
+}
+
+// NewBackupFileHandler creates a new backup file handler. Presigned download
+// URLs are valid for urlExpiry; client-side encrypted files are decrypted
+// with the cipher.
+func NewBackupFileHandler(db *gorm.DB, storage services.StorageResolver, cipher *services.BackupCipher, auditService services.AuditServiceInterface, urlExpiry time.Duration) *BackupFileHandler {
+	return &BackupFileHandler{
+		db:           db,
+		storage:      storage,
+		cipher:       cipher,
+		auditService: auditService,
+		tablePolicy:  services.NewTablePolicy(db),
+		urlExpiry:    urlExpiry,
+	}
+}
+
+// DownloadLinkResponse is a short-lived link to a backup file in storage
+type DownloadLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListBackupFiles handles GET /api/backups/:uid/files, newest first
+func (h *BackupFileHandler) ListBackupFiles(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var job models.BackupJob
+	if err := h.db.Where("uid = ? AND user_id = ?", c.Param("uid"), user.ID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.NotFound(c, "Backup not found")
+		}
+		return responses.InternalError(c, "Failed to fetch backup")
+	}
+
+	var files []models.BackupFile
+	if err := h.db.Preload("Locations").Where("backup_job_id = ?", job.ID).
+		Order("created_at DESC, id DESC").Find(&files).Error; err != nil {
+		return responses.InternalError(c, "Failed to fetch backup files")
+	}
+
+	response := make([]BackupFileResponse, len(files))
+	for i, file := range files {
+		response[i] = newBackupFileResponse(file)
+	}
+	return responses.Success(c, "Backup files retrieved successfully", response)
+}
+
+// DownloadBackupFile handles POST /api/backup-files/:uid/download. It answers
+// with a presigned storage URL when the provider supports them, and otherwise
+// streams the file through the API. Client-side encrypted files are always
+// streamed, decrypted, so downloads get the file as it was before upload.
+func (h *BackupFileHandler) DownloadBackupFile(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+	ctx := c.Request().Context()
+
+	var file models.BackupFile
+	if err := h.db.Preload("Locations").Preload("BackupJob.DatabaseConnection").
+		Where("uid = ?", c.Param("uid")).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.NotFound(c, "Backup file not found")
+		}
+		return responses.InternalError(c, "Failed to fetch backup file")
+	}
+	job := &file.BackupJob
+	if job.ID == 0 || job.UserID != user.ID {
+		return responses.NotFound(c, "Backup file not found")
+	}
+
+	if !file.IsAccessible() {
+		return responses.Error(c, http.StatusGone, "Backup file has expired")
+	}
+
+	// A download holds the data of every table the backup was taken from;
+	// tables no longer catalogued cannot be checked and are refused
+	if err := h.tablePolicy.CheckTables(ctx, user.ID, &job.DatabaseConnection, job.Tables, job.ExcludeTables, services.TableActionRead); err != nil {
+		if errors.Is(err, services.ErrTableAccessDenied) || errors.Is(err, services.ErrTableNotCatalogued) || errors.Is(err, services.ErrInvalidTableName) {
+			return responses.Error(c, http.StatusForbidden, err.Error())
+		}
+		return responses.InternalError(c, "Failed to check table permissions")
+	}
+
+	// Links would hand out the encrypted bytes, so encrypted files are decrypted here
+	if file.EncryptionKey == nil {
+		link, err := h.presign(ctx, &file)
+		if err == nil {
+			if err := h.recordDownload(c, &file, downloadMethodPresigned); err != nil {
+				return responses.Error(c, http.StatusForbidden, "Download blocked by risk policy")
+			}
+			return responses.Success(c, "Download link created", link)
+		}
+		if !errors.Is(err, services.ErrPresignNotSupported) {
+			slog.WarnContext(ctx, "Failed to presign backup file, serving it through the API", "backup_file_uid", file.UID, "error", err)
+		}
+	}
+
+	reader, err := h.open(ctx, &file)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to open backup file for download", "backup_file_uid", file.UID, "error", err)
+		return responses.Error(c, http.StatusBadGateway, "Backup file is unavailable in storage")
+	}
+	defer reader.Close()
+
+	plain, err := h.cipher.OpenFile(reader, &file)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to decrypt backup file for download", "backup_file_uid", file.UID, "error", err)
+		return responses.InternalError(c, "Failed to decrypt backup file")
+	}
+
+	if err := h.recordDownload(c, &file, downloadMethodProxy); err != nil {
+		return responses.Error(c, http.StatusForbidden, "Download blocked by risk policy")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	if err := c.Stream(http.StatusOK, echo.MIMEOctetStream, plain); err != nil {
+		slog.WarnContext(ctx, "Backup file download was interrupted", "backup_file_uid", file.UID, "error", err)
+	}
+	return nil
+}
+
+// presign creates a download URL for the first copy of a file that can be reached
+func (h *BackupFileHandler) presign(ctx context.Context, file *models.BackupFile) (*DownloadLinkResponse, error) {
+	locations := file.ReadableLocations()
+
+	var lastErr error
+	for i := range locations {
+		store, err := h.resolveLocationStorage(ctx, &file.BackupJob, &locations[i])
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		expiresAt := time.Now().Add(h.urlExpiry)
+		url, err := store.PresignGet(ctx, locations[i].Key, h.urlExpiry)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return &DownloadLinkResponse{URL: url, ExpiresAt: expiresAt}, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("backup file %s has no available copies", file.UID)
+	}
+	return nil, lastErr
+}
+
+// open opens the first readable copy of a file, trying the primary before replicas
+func (h *BackupFileHandler) open(ctx context.Context, file *models.BackupFile) (io.ReadCloser, error) {
+	locations := file.ReadableLocations()
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("backup file %s has no available copies", file.UID)
+	}
+
+	var lastErr error
+	for i := range locations {
+		store, err := h.resolveLocationStorage(ctx, &file.BackupJob, &locations[i])
+		if err == nil {
+			var reader io.ReadCloser
+			if reader, err = store.Get(ctx, locations[i].Key); err == nil {
+				return reader, nil
+			}
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all copies of backup file %s are unavailable: %w", file.UID, lastErr)
+}
+
+// resolveLocationStorage returns the object store holding a copy. Copies
+// uploaded before storage was tracked per file use the job owner's default.
+func (h *BackupFileHandler) resolveLocationStorage(ctx context.Context, job *models.BackupJob, location *models.BackupFileLocation) (services.ObjectStore, error) {
+	var (
+		store services.ObjectStore
+		err   error
+	)
+	if location.StorageConfigurationID != nil {
+		_, store, err = h.storage.ResolveStorageByID(ctx, *location.StorageConfigurationID)
+	} else {
+		_, store, err = h.storage.ResolveStorage(ctx, "", job.UserID, job.DatabaseConnection.TeamID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return services.ObjectStoreForBucket(store, location.Bucket), nil
+}
+
+// recordDownload audits a download and counts it. It is recorded before the
+// file is handed out so the risk policy can block mass downloads, in which
+// case it returns services.ErrActionBlocked.
+func (h *BackupFileHandler) recordDownload(c echo.Context, file *models.BackupFile, method string) error {
+	if h.auditService != nil {
+		_, err := h.auditService.Record(c.Request().Context(), h.newAuditEvent(c, file, method))
+		if errors.Is(err, services.ErrActionBlocked) {
+			return err
+		}
+		if err != nil {
+			c.Logger().Errorf("Failed to record %s audit event: %v", models.AuditActionDownload, err)
+		}
+	}
+
+	file.IncrementDownloadCount()
+	if err := h.db.Model(file).Select("download_count", "last_accessed_at").Updates(file).Error; err != nil {
+		slog.WarnContext(c.Request().Context(), "Failed to count backup file download", "backup_file_uid", file.UID, "error", err)
+	}
+	return nil
+}
+
+// newAuditEvent builds the audit event of a backup file download
+func (h *BackupFileHandler) newAuditEvent(c echo.Context, file *models.BackupFile, method string) *models.AuditLog {
+	user := middleware.GetUserModel(c)
+	req := c.Request()
+
+	event := &models.AuditLog{
+		Action:      models.AuditActionDownload,
+		Resource:    models.AuditResourceBackupFile,
+		ResourceID:  &file.ID,
+		ResourceUID: &file.UID,
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		IPAddress:   c.RealIP(),
+		StatusCode:  http.StatusOK,
+		UserID:      &user.ID,
+		TeamID:      file.BackupJob.DatabaseConnection.TeamID,
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		event.RequestID = &requestID
+	}
+	event.SetMetadata("backup_job_uid", file.BackupJob.UID)
+	event.SetMetadata("database_connection_uid", file.BackupJob.DatabaseConnection.UID)
+	event.SetMetadata("download_method", method)
+
+	return event
+}
+
+// RegisterRoutes registers backup file routes
+func (h *BackupFileHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/backups/:uid/files", h.ListBackupFiles)
+	g.POST("/backup-files/:uid/download", h.DownloadBackupFile)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// presignS3Service is an in-memory S3 service that signs download URLs
+type presignS3Service struct {
+	probeS3Service
+}
+
+func (p *presignS3Service) GeneratePresignedURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	return "https://storage.example.com/" + bucket + "/" + key + "?expires=" + expiry.String(), nil
+}
+
+// blockingAuditService blocks every audited action
+type blockingAuditService struct {
+	events []*models.AuditLog
+}
+
+func (b *blockingAuditService) Record(ctx context.Context, event *models.AuditLog) (*services.RiskAssessment, error) {
+	event.IsBlocked = true
+	b.events = append(b.events, event)
+	return &services.RiskAssessment{Level: services.RiskLevelHigh}, services.ErrActionBlocked
+}
+
+func setupBackupFileHandler(t *testing.T, auditService services.AuditServiceInterface) (*BackupFileHandler, *gorm.DB, *models.User) {
+	db := setupTestDB()
+	require.NoError(t, db.AutoMigrate(&models.AuditLog{}))
+	user := createTestUser(db)
+
+	factory := services.NewStorageFactory(db, nil).WithS3ServiceConstructor(func(cfg *services.S3Config) (services.S3ServiceInterface, error) {
+		return &presignS3Service{probeS3Service{objects: make(map[string][]byte)}}, nil
+	}).WithLocalStorageRoots([]string{os.TempDir()})
+	return NewBackupFileHandler(db, factory, services.NewBackupCipher(encryption.NewService("test-key-for-testing")), auditService, 15*time.Minute), db, user
+}
+
+// createDownloadableFile stores a file of a completed backup in storage
+func createDownloadableFile(t *testing.T, db *gorm.DB, user *models.User, storage *models.StorageConfiguration, content []byte) *models.BackupFile {
+	conn := &models.DatabaseConnection{Name: "Orders", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "orders", Username: "backup", Password: "secret", UserID: user.ID}
+	require.NoError(t, db.Create(conn).Error)
+	job := &models.BackupJob{Name: "Nightly", Type: models.BackupTypeFull, Status: models.BackupStatusCompleted, UserID: user.ID, DatabaseConnectionID: conn.ID}
+	require.NoError(t, db.Create(job).Error)
+
+	key := "backups/" + job.UID + ".backup"
+	if storage.Provider == models.StorageProviderLocal {
+		path := filepath.Join(storage.Bucket, filepath.FromSlash(key))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+	}
+
+	size := int64(len(content))
+	file := &models.BackupFile{
+		Name:                   "orders-backup-20240101-020000",
+		FileType:               "dump",
+		S3Bucket:               storage.Bucket,
+		S3Key:                  key,
+		S3Region:               storage.Region,
+		Size:                   &size,
+		BackupJobID:            job.ID,
+		StorageConfigurationID: &storage.ID,
+	}
+	require.NoError(t, db.Create(file).Error)
+	require.NoError(t, db.Model(file).Update("is_encrypted", false).Error)
+	return file
+}
+
+func createBackupFileStorage(t *testing.T, db *gorm.DB, user *models.User, provider models.StorageProvider, bucket string) *models.StorageConfiguration {
+	endpoint := "http://localhost:9000"
+	storage := &models.StorageConfiguration{
+		Name:      string(provider),
+		Provider:  provider,
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    bucket,
+		IsActive:  true,
+		UserID:    user.ID,
+	}
+	if provider != models.StorageProviderLocal {
+		storage.Endpoint = &endpoint
+	}
+	require.NoError(t, db.Create(storage).Error)
+	return storage
+}
+
+func backupFileRequest(e *echo.Echo, method, path, uid string, user *models.User) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, path, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(uid)
+	c.Set("user_model", user)
+	return c, rec
+}
+
+func TestBackupFileHandler_ListBackupFiles(t *testing.T) {
+	handler, db, user := setupBackupFileHandler(t, nil)
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderMinIO, "backups")
+	file := createDownloadableFile(t, db, user, storage, []byte("dump"))
+
+	var job models.BackupJob
+	require.NoError(t, db.First(&job, file.BackupJobID).Error)
+	newer := &models.BackupFile{Name: "orders-schema", FileType: "schema", S3Bucket: "backups", S3Key: "backups/schema", S3Region: "us-east-1", BackupJobID: job.ID, CreatedAt: time.Now().Add(time.Hour)}
+	require.NoError(t, db.Create(newer).Error)
+
+	e := echo.New()
+	c, rec := backupFileRequest(e, http.MethodGet, "/api/backups/"+job.UID+"/files", job.UID, user)
+	require.NoError(t, handler.ListBackupFiles(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data []BackupFileResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	assert.Equal(t, newer.UID, response.Data[0].UID)
+	assert.Equal(t, file.UID, response.Data[1].UID)
+
+	other := &models.User{Email: "other@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	c, rec = backupFileRequest(e, http.MethodGet, "/api/backups/"+job.UID+"/files", job.UID, other)
+	require.NoError(t, handler.ListBackupFiles(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestBackupFileHandler_DownloadPresigned(t *testing.T) {
+	handler, db, user := setupBackupFileHandler(t, nil)
+	handler.auditService = services.NewAuditService(db, nil)
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderMinIO, "backups")
+	file := createDownloadableFile(t, db, user, storage, []byte("dump"))
+
+	e := echo.New()
+	c, rec := backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data DownloadLinkResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "https://storage.example.com/backups/"+file.S3Key+"?expires=15m0s", response.Data.URL)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), response.Data.ExpiresAt, time.Minute)
+
+	var stored models.BackupFile
+	require.NoError(t, db.First(&stored, file.ID).Error)
+	assert.Equal(t, 1, stored.DownloadCount)
+	assert.NotNil(t, stored.LastAccessedAt)
+
+	var event models.AuditLog
+	require.NoError(t, db.Where("action = ?", models.AuditActionDownload).First(&event).Error)
+	assert.Equal(t, models.AuditResourceBackupFile, event.Resource)
+	assert.Equal(t, file.UID, *event.ResourceUID)
+	assert.Equal(t, user.ID, *event.UserID)
+	method, _ := event.GetMetadata("download_method")
+	assert.Equal(t, downloadMethodPresigned, method)
+}
+
+func TestBackupFileHandler_DownloadProxied(t *testing.T) {
+	handler, db, user := setupBackupFileHandler(t, nil)
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderLocal, t.TempDir())
+	content := []byte("-- PostgreSQL database dump\n")
+	file := createDownloadableFile(t, db, user, storage, content)
+
+	e := echo.New()
+	c, rec := backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEOctetStream, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), file.Name)
+	assert.Equal(t, content, rec.Body.Bytes())
+
+	var stored models.BackupFile
+	require.NoError(t, db.First(&stored, file.ID).Error)
+	assert.Equal(t, 1, stored.DownloadCount)
+}
+
+func TestBackupFileHandler_DownloadEncryptedIsProxied(t *testing.T) {
+	handler, db, user := setupBackupFileHandler(t, nil)
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderMinIO, "backups")
+	file := createDownloadableFile(t, db, user, storage, nil)
+	require.NoError(t, db.Model(file).Updates(map[string]interface{}{"is_encrypted": true, "encryption_key": "wrapped-key"}).Error)
+
+	// Storage could presign it, but a link would serve the encrypted bytes.
+	// The in-memory store holds no object, so serving it through the API fails.
+	e := echo.New()
+	c, rec := backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "storage.example.com")
+}
+
+func TestBackupFileHandler_DownloadDecryptsEncryptedFile(t *testing.T) {
+	handler, db, user := setupBackupFileHandler(t, nil)
+	content := []byte("-- PostgreSQL database dump\n")
+	var sealed bytes.Buffer
+	key, err := handler.cipher.Seal(&sealed, bytes.NewReader(content))
+	require.NoError(t, err)
+
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderLocal, t.TempDir())
+	file := createDownloadableFile(t, db, user, storage, sealed.Bytes())
+	require.NoError(t, db.Model(file).Updates(map[string]interface{}{"is_encrypted": true, "encryption_key": key}).Error)
+
+	e := echo.New()
+	c, rec := backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, content, rec.Body.Bytes())
+
+	// Without the cipher the file cannot be served
+	handler.cipher = nil
+	c, rec = backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestBackupFileHandler_DownloadDenied(t *testing.T) {
+	audit := &blockingAuditService{}
+	handler, db, user := setupBackupFileHandler(t, audit)
+	storage := createBackupFileStorage(t, db, user, models.StorageProviderMinIO, "backups")
+	file := createDownloadableFile(t, db, user, storage, []byte("dump"))
+	e := echo.New()
+
+	other := &models.User{Email: "other@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	c, rec := backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, other)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Blocked by the risk policy
+	c, rec = backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Len(t, audit.events, 1)
+
+	var stored models.BackupFile
+	require.NoError(t, db.First(&stored, file.ID).Error)
+	assert.Equal(t, 0, stored.DownloadCount)
+
+	// Expired
+	require.NoError(t, db.Model(file).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+	c, rec = backupFileRequest(e, http.MethodPost, "/api/backup-files/"+file.UID+"/download", file.UID, user)
+	require.NoError(t, handler.DownloadBackupFile(c))
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/dbackup/backend-go/internal/middleware"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/services"
 	"github.com/dbackup/backend-go/internal/workers"
@@ -17,6 +20,8 @@ import (
 type BackupWorkerInterface interface {
 	EnqueueBackupJob(ctx context.Context, jobType string, payload *workers.BackupTaskPayload, options ...services.JobOption) (*services.JobInfo, error)
 	EnqueueScheduledBackupJob(ctx context.Context, payload *workers.BackupTaskPayload, scheduledTime time.Time, options ...services.JobOption) (*services.JobInfo, error)
+	ReprioritizeBackupJob(ctx context.Context, backupJob *models.BackupJob) error
+	EnqueueRestoreJob(ctx context.Context, jobType string, payload *workers.RestoreTaskPayload, options ...services.JobOption) (*services.JobInfo, error)
 }
 
 // BackupHandler handles backup-related HTTP requests
@@ -26,6 +31,8 @@ type BackupHandler struct {
 	s3Service     services.S3ServiceInterface
 	queueService  services.QueueServiceInterface
 	backupWorker  BackupWorkerInterface
+	tablePolicy   *services.TablePolicy
+	auditService  services.AuditServiceInterface
 }
 
 // NewBackupHandler creates a new backup handler
@@ -36,9 +43,15 @@ func NewBackupHandler(db *gorm.DB, backupService services.BackupServiceInterface
 		s3Service:     s3Service,
 		queueService:  queueService,
 		backupWorker:  backupWorker,
+		tablePolicy:   services.NewTablePolicy(db),
 	}
 }
 
+// SetAuditService audits and risk scores restores. Without it restores are not audited.
+func (h *BackupHandler) SetAuditService(auditService services.AuditServiceInterface) {
+	h.auditService = auditService
+}
+
 // BackupResponse represents a backup job response
 type BackupResponse struct {
 	ID                   uint                        `json:"id"`
@@ -46,6 +59,8 @@ type BackupResponse struct {
 	Name                 string                      `json:"name"`
 	Type                 models.BackupType           `json:"type"`
 	Status               models.BackupStatus         `json:"status"`
+	Priority             int                         `json:"priority"`
+	QueueName            string                      `json:"queue_name,omitempty"`
 	Progress             float64                     `json:"progress"`
 	ProgressMessage      string                      `json:"progress_message,omitempty"`
 	StartedAt            *time.Time                  `json:"started_at,omitempty"`
@@ -71,6 +86,7 @@ type BackupFileResponse struct {
 	FileType         string     `json:"file_type"`
 	Size             *int64     `json:"size,omitempty"`
 	OriginalSize     *int64     `json:"original_size,omitempty"`
+	Checksum         *string    `json:"checksum,omitempty"` // SHA256 of the dump
 	CompressionAlgo  string     `json:"compression_algo,omitempty"`
 	IsCompressed     bool       `json:"is_compressed"`
 	IsEncrypted      bool       `json:"is_encrypted"`
@@ -84,6 +100,8 @@ type BackupFileResponse struct {
 	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
+
+	Locations []models.BackupFileLocation `json:"locations,omitempty"`
 }
 
 // DatabaseConnectionResponse represents a database connection response (simplified)
@@ -131,12 +149,26 @@ type CreateBackupRequest struct {
 	Type                     models.BackupType       `json:"type" validate:"required"`
 	Options                  *services.BackupOptions `json:"options,omitempty"`
 	StorageConfigurationUID  *string                 `json:"storage_configuration_uid,omitempty"`
+	ReplicaStorageUIDs       []string                `json:"replica_storage_configuration_uids,omitempty" validate:"max=5"`
 	ScheduleAt               *time.Time              `json:"schedule_at,omitempty"`
+	Priority                 int                     `json:"priority,omitempty" validate:"omitempty,min=1,max=10"`
+}
+
+// RestoreBackupRequest represents a request to restore a backup into its
+// database. Without a file UID the latest file of the backup is restored.
+type RestoreBackupRequest struct {
+	BackupFileUID string                  `json:"backup_file_uid,omitempty"`
+	Options       *services.RestoreOptions `json:"options,omitempty"`
+}
+
+// UpdateBackupPriorityRequest represents a request to change the priority of a pending backup
+type UpdateBackupPriorityRequest struct {
+	Priority int `json:"priority" validate:"required,min=1,max=10"`
 }
 
 // GetBackups handles GET /api/backups
 func (h *BackupHandler) GetBackups(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 	
 	// Parse query parameters
 	var query BackupListQuery
@@ -252,7 +284,7 @@ func (h *BackupHandler) GetBackups(c echo.Context) error {
 
 // GetBackup handles GET /api/backups/:uid
 func (h *BackupHandler) GetBackup(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 	backupUID := c.Param("uid")
 
 	if backupUID == "" {
@@ -260,7 +292,7 @@ func (h *BackupHandler) GetBackup(c echo.Context) error {
 	}
 
 	var backupJob models.BackupJob
-	if err := h.db.Preload("DatabaseConnection").Preload("BackupFiles").
+	if err := h.db.Preload("DatabaseConnection").Preload("BackupFiles.Locations").
 		Where("uid = ? AND user_id = ?", backupUID, user.ID).
 		First(&backupJob).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -275,7 +307,7 @@ func (h *BackupHandler) GetBackup(c echo.Context) error {
 
 // CreateBackup handles POST /api/backups
 func (h *BackupHandler) CreateBackup(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 
 	var req CreateBackupRequest
 	if err := c.Bind(&req); err != nil {
@@ -285,28 +317,83 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 	if err := c.Validate(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	if req.Options != nil && req.Options.Sanitize != nil {
+		if err := req.Options.Sanitize.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
 
-	// Find database connection
-	var dbConn models.DatabaseConnection
-	if err := h.db.Where("uid = ? AND user_id = ?", req.DatabaseUID, user.ID).
-		First(&dbConn).Error; err != nil {
+	// Find database connection owned by the user or shared with their teams
+	dbConn, err := h.tablePolicy.FindConnection(c.Request().Context(), user.ID, req.DatabaseUID)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Database connection not found")
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find database connection")
 	}
 
-	// Find storage configuration if specified
-	var storageConfig *models.StorageConfiguration
-	if req.StorageConfigurationUID != nil {
-		storageConfig = &models.StorageConfiguration{}
-		if err := h.db.Where("uid = ? AND user_id = ?", *req.StorageConfigurationUID, user.ID).
-			First(storageConfig).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return echo.NewHTTPError(http.StatusNotFound, "Storage configuration not found")
+	// Pin the requested tables to catalogued ones, then reject backups that
+	// include tables the user may not back up
+	var tables, excludeTables []string
+	if req.Options != nil {
+		tables, err = h.tablePolicy.ResolveTables(c.Request().Context(), dbConn, req.Options.Tables)
+		if err == nil {
+			excludeTables, err = h.tablePolicy.ResolveTables(c.Request().Context(), dbConn, req.Options.ExcludeTables)
+		}
+		if err != nil {
+			if errors.Is(err, services.ErrTableNotCatalogued) || errors.Is(err, services.ErrInvalidTableName) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 			}
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to find storage configuration")
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check table permissions")
+		}
+		req.Options.Tables = tables
+		req.Options.ExcludeTables = excludeTables
+	}
+	if err := h.tablePolicy.CheckTables(c.Request().Context(), user.ID, dbConn, tables, excludeTables, services.TableActionBackup); err != nil {
+		if errors.Is(err, services.ErrTableAccessDenied) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check table permissions")
+	}
+
+	// Keep the requested options, sanitize rules included, for retries
+	var options *string
+	if req.Options != nil {
+		encoded, err := json.Marshal(req.Options)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to encode backup options")
+		}
+		value := string(encoded)
+		options = &value
+	}
+
+	// Verify the storage configurations if specified; the worker resolves them by UID
+	var storageUID string
+	var storageConfigID *uint
+	if req.StorageConfigurationUID != nil {
+		storageConfig, err := findAccessibleStorage(h.db, user.ID, *req.StorageConfigurationUID)
+		if err != nil {
+			return err
 		}
+		storageUID = storageConfig.UID
+		storageConfigID = &storageConfig.ID
+	}
+
+	for _, uid := range req.ReplicaStorageUIDs {
+		if _, err := findAccessibleStorage(h.db, user.ID, uid); err != nil {
+			return err
+		}
+	}
+	// Without replicas of its own the backup is copied where its connection's backups go
+	replicaUIDs := req.ReplicaStorageUIDs
+	if len(replicaUIDs) == 0 {
+		replicaUIDs = dbConn.ReplicaStorageUIDs
+	}
+
+	// Only higher subscription tiers may move their backups ahead of others
+	tier, err := services.ConnectionTier(c.Request().Context(), h.db, dbConn)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check subscription tier")
 	}
 
 	// Create backup job
@@ -314,9 +401,17 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 		Name:                 req.Name,
 		Type:                 req.Type,
 		Status:               models.BackupStatusPending,
+		Priority:             services.CapBackupPriority(req.Priority, tier),
 		UserID:               user.ID,
 		DatabaseConnectionID: dbConn.ID,
+		IsTableSpecific:      len(tables) > 0,
+		Tables:               tables,
+		ExcludeTables:        excludeTables,
+		Options:              options,
 		IsScheduled:          req.ScheduleAt != nil,
+
+		StorageConfigurationID: storageConfigID,
+		ReplicaStorageUIDs:     req.ReplicaStorageUIDs,
 	}
 
 	if err := h.db.Create(backupJob).Error; err != nil {
@@ -325,11 +420,13 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 
 	// Prepare backup task payload
 	payload := &workers.BackupTaskPayload{
-		BackupJobID:   backupJob.ID,
-		UserID:        user.ID,
-		DatabaseUID:   req.DatabaseUID,
-		Options:       req.Options,
-		StorageConfig: storageConfig,
+		BackupJobID: backupJob.ID,
+		UserID:      user.ID,
+		DatabaseUID: req.DatabaseUID,
+		Options:     req.Options,
+		StorageUID:  storageUID,
+
+		ReplicaStorageUIDs: replicaUIDs,
 	}
 
 	// Determine job type based on database type
@@ -346,14 +443,17 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 	}
 
 	// Enqueue the backup job
-	var err error
-
 	if req.ScheduleAt != nil {
 		// Schedule for later
 		_, err = h.backupWorker.EnqueueScheduledBackupJob(c.Request().Context(), payload, *req.ScheduleAt)
 	} else {
-		// Execute immediately
-		_, err = h.backupWorker.EnqueueBackupJob(c.Request().Context(), jobType, payload)
+		// Execute immediately in the lane of its priority
+		var jobInfo *services.JobInfo
+		jobInfo, err = h.backupWorker.EnqueueBackupJob(c.Request().Context(), jobType, payload)
+		if err == nil {
+			backupJob.QueueName = jobInfo.Queue
+			backupJob.QueueTaskID = jobInfo.ID
+		}
 	}
 
 	if err != nil {
@@ -362,8 +462,6 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enqueue backup job: "+err.Error())
 	}
 
-	// Note: BackupJob model doesn't have QueueJobID field yet
-	// This would need to be added to the model or stored separately
 	h.db.Save(backupJob)
 
 	// Return the created backup job
@@ -373,7 +471,7 @@ func (h *BackupHandler) CreateBackup(c echo.Context) error {
 
 // CancelBackup handles DELETE /api/backups/:uid
 func (h *BackupHandler) CancelBackup(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 	backupUID := c.Param("uid")
 
 	if backupUID == "" {
@@ -410,7 +508,7 @@ func (h *BackupHandler) CancelBackup(c echo.Context) error {
 
 // RetryBackup handles POST /api/backups/:uid/retry
 func (h *BackupHandler) RetryBackup(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 	backupUID := c.Param("uid")
 
 	if backupUID == "" {
@@ -418,7 +516,7 @@ func (h *BackupHandler) RetryBackup(c echo.Context) error {
 	}
 
 	var backupJob models.BackupJob
-	if err := h.db.Preload("DatabaseConnection").
+	if err := h.db.Preload("DatabaseConnection").Preload("StorageConfiguration").
 		Where("uid = ? AND user_id = ?", backupUID, user.ID).
 		First(&backupJob).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -432,6 +530,40 @@ func (h *BackupHandler) RetryBackup(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Can only retry failed or cancelled backups")
 	}
 
+	// Retries run with the options and tables the backup was created for
+	options := &services.BackupOptions{}
+	if backupJob.Options != nil {
+		if err := json.Unmarshal([]byte(*backupJob.Options), options); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to decode backup options")
+		}
+	}
+	options.Tables = backupJob.Tables
+	options.ExcludeTables = backupJob.ExcludeTables
+
+	// Retries upload to the storage the backup was created for, if the user still has it
+	var storageUID string
+	if backupJob.StorageConfigurationID != nil {
+		if backupJob.StorageConfiguration == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Storage configuration not found")
+		}
+		storageConfig, err := findAccessibleStorage(h.db, user.ID, backupJob.StorageConfiguration.UID)
+		if err != nil {
+			return err
+		}
+		storageUID = storageConfig.UID
+	}
+
+	// Replicas too, falling back to the connection's like new backups do
+	for _, uid := range backupJob.ReplicaStorageUIDs {
+		if _, err := findAccessibleStorage(h.db, user.ID, uid); err != nil {
+			return err
+		}
+	}
+	replicaUIDs := backupJob.ReplicaStorageUIDs
+	if len(replicaUIDs) == 0 {
+		replicaUIDs = backupJob.DatabaseConnection.ReplicaStorageUIDs
+	}
+
 	// Reset backup job status
 	backupJob.Status = models.BackupStatusPending
 	backupJob.Progress = 0
@@ -461,24 +593,242 @@ func (h *BackupHandler) RetryBackup(c echo.Context) error {
 		BackupJobID: backupJob.ID,
 		UserID:      user.ID,
 		DatabaseUID: backupJob.DatabaseConnection.UID,
+		Options:     options,
+		StorageUID:  storageUID,
+
+		ReplicaStorageUIDs: replicaUIDs,
 	}
 
-	_, err := h.backupWorker.EnqueueBackupJob(c.Request().Context(), jobType, payload)
+	jobInfo, err := h.backupWorker.EnqueueBackupJob(c.Request().Context(), jobType, payload)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retry backup job: "+err.Error())
 	}
 
-	// Note: BackupJob model doesn't have QueueJobID field yet
-	// This would need to be added to the model or stored separately
+	backupJob.QueueName = jobInfo.Queue
+	backupJob.QueueTaskID = jobInfo.ID
 	h.db.Save(&backupJob)
 
 	response := h.convertBackupJobToResponse(backupJob)
 	return c.JSON(http.StatusOK, response)
 }
 
+// RestoreBackup handles POST /api/backups/:uid/restore. The restore runs as
+// the backup job, which reports its progress over WebSocket as a restore.
+func (h *BackupHandler) RestoreBackup(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+	backupUID := c.Param("uid")
+
+	if backupUID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Backup UID is required")
+	}
+
+	var req RestoreBackupRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	var backupJob models.BackupJob
+	if err := h.db.Preload("DatabaseConnection.Tags").
+		Preload("BackupFiles", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC, id DESC") }).
+		Where("uid = ? AND user_id = ?", backupUID, user.ID).
+		First(&backupJob).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, "Backup not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch backup")
+	}
+
+	// Only completed backups have files
+	if backupJob.Status != models.BackupStatusCompleted {
+		return echo.NewHTTPError(http.StatusBadRequest, "Can only restore completed backups")
+	}
+
+	// A restore of the backup that is queued or running must finish first
+	var activeRestores int64
+	if err := h.db.Model(&models.RestoreJob{}).
+		Where("backup_job_id = ? AND status IN ?", backupJob.ID, []models.BackupJobStatus{models.BackupStatusPending, models.BackupStatusRunning}).
+		Count(&activeRestores).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check running restores")
+	}
+	if activeRestores > 0 {
+		return echo.NewHTTPError(http.StatusConflict, "A restore of this backup is already in progress")
+	}
+
+	var backupFile *models.BackupFile
+	for i := range backupJob.BackupFiles {
+		if req.BackupFileUID == "" || backupJob.BackupFiles[i].UID == req.BackupFileUID {
+			backupFile = &backupJob.BackupFiles[i]
+			break
+		}
+	}
+	if backupFile == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Backup file not found")
+	}
+
+	// The restore covers the tables the backup was taken from; tables no
+	// longer catalogued cannot be checked and are refused
+	if err := h.tablePolicy.CheckTables(c.Request().Context(), user.ID, &backupJob.DatabaseConnection, backupJob.Tables, backupJob.ExcludeTables, services.TableActionRestore); err != nil {
+		if errors.Is(err, services.ErrTableAccessDenied) || errors.Is(err, services.ErrTableNotCatalogued) || errors.Is(err, services.ErrInvalidTableName) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check table permissions")
+	}
+
+	var jobType string
+	switch backupJob.DatabaseConnection.Type {
+	case models.DatabaseTypePostgreSQL:
+		jobType = workers.TypeRestorePostgreSQL
+	case models.DatabaseTypeMySQL:
+		jobType = workers.TypeRestoreMySQL
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported database type")
+	}
+
+	// Recorded before queueing so the risk policy can block restores, such as off-hours ones into production
+	if err := h.recordRestore(c, &backupJob, backupFile); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "Restore blocked for security reasons")
+	}
+
+	// The restore tracks its status apart from the backup, which stays restorable
+	restoreJob := &models.RestoreJob{
+		Status:       models.BackupStatusPending,
+		BackupJobID:  backupJob.ID,
+		BackupFileID: backupFile.ID,
+		UserID:       user.ID,
+	}
+	if err := h.db.Create(restoreJob).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create restore job")
+	}
+
+	payload := &workers.RestoreTaskPayload{
+		RestoreJobID:  restoreJob.ID,
+		BackupJobID:   backupJob.ID,
+		UserID:        user.ID,
+		DatabaseUID:   backupJob.DatabaseConnection.UID,
+		BackupFileUID: backupFile.UID,
+		Options:       req.Options,
+	}
+
+	if _, err := h.backupWorker.EnqueueRestoreJob(c.Request().Context(), jobType, payload); err != nil {
+		h.db.Delete(restoreJob)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enqueue restore job: "+err.Error())
+	}
+
+	response := h.convertBackupJobToResponse(backupJob)
+	return c.JSON(http.StatusAccepted, response)
+}
+
+// recordRestore audits a restore request. It returns services.ErrActionBlocked
+// when the risk policy blocks the restore.
+func (h *BackupHandler) recordRestore(c echo.Context, backupJob *models.BackupJob, backupFile *models.BackupFile) error {
+	if h.auditService == nil {
+		return nil
+	}
+
+	user := middleware.GetUserModel(c)
+	req := c.Request()
+	conn := &backupJob.DatabaseConnection
+	event := &models.AuditLog{
+		Action:      models.AuditActionRestore,
+		Resource:    models.AuditResourceDatabaseConnection,
+		ResourceID:  &conn.ID,
+		ResourceUID: &conn.UID,
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		IPAddress:   c.RealIP(),
+		StatusCode:  http.StatusAccepted,
+		UserID:      &user.ID,
+		TeamID:      conn.TeamID,
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		event.RequestID = &requestID
+	}
+	event.SetMetadata("backup_job_uid", backupJob.UID)
+	event.SetMetadata("backup_file_uid", backupFile.UID)
+	event.SetMetadata("production", isProductionConnection(conn))
+
+	_, err := h.auditService.Record(req.Context(), event)
+	if errors.Is(err, services.ErrActionBlocked) {
+		return err
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to record %s audit event: %v", models.AuditActionRestore, err)
+	}
+	return nil
+}
+
+// isProductionConnection checks if a connection is tagged as production
+func isProductionConnection(conn *models.DatabaseConnection) bool {
+	for _, tag := range conn.Tags {
+		if strings.EqualFold(tag.Name, "production") || strings.EqualFold(tag.Name, "prod") {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateBackupPriority handles PUT /api/backups/:uid/priority and moves a
+// pending backup to the lane of its new priority
+func (h *BackupHandler) UpdateBackupPriority(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+	backupUID := c.Param("uid")
+
+	if backupUID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Backup UID is required")
+	}
+
+	var req UpdateBackupPriorityRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var backupJob models.BackupJob
+	if err := h.db.Preload("DatabaseConnection").
+		Where("uid = ? AND user_id = ?", backupUID, user.ID).
+		First(&backupJob).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, "Backup not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch backup")
+	}
+
+	// Only jobs that have not started can change lanes
+	if backupJob.Status != models.BackupStatusPending {
+		return echo.NewHTTPError(http.StatusConflict, "Can only change the priority of pending backups")
+	}
+
+	tier, err := services.ConnectionTier(c.Request().Context(), h.db, &backupJob.DatabaseConnection)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check subscription tier")
+	}
+	backupJob.Priority = services.CapBackupPriority(req.Priority, tier)
+	if err := h.backupWorker.ReprioritizeBackupJob(c.Request().Context(), &backupJob); err != nil {
+		if errors.Is(err, workers.ErrBackupNotQueued) {
+			return echo.NewHTTPError(http.StatusConflict, "Backup is no longer waiting in the queue")
+		}
+		if backupJob.Status == models.BackupStatusFailed {
+			h.db.Save(&backupJob)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to change backup priority: "+err.Error())
+	}
+
+	if err := h.db.Save(&backupJob).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update backup job")
+	}
+
+	response := h.convertBackupJobToResponse(backupJob)
+	return c.JSON(http.StatusOK, response)
+}
+
 // GetBackupProgress handles GET /api/backups/:uid/progress
 func (h *BackupHandler) GetBackupProgress(c echo.Context) error {
-	user := c.Get("user").(*models.User)
+	user := middleware.GetUserModel(c)
 	backupUID := c.Param("uid")
 
 	if backupUID == "" {
@@ -508,6 +858,23 @@ func (h *BackupHandler) GetBackupProgress(c echo.Context) error {
 	return c.JSON(http.StatusOK, progressResponse)
 }
 
+// findAccessibleStorage loads an active storage configuration owned by the user or shared with their teams
+func findAccessibleStorage(db *gorm.DB, userID uint, uid string) (*models.StorageConfiguration, *echo.HTTPError) {
+	teamIDs := db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	var storageConfig models.StorageConfiguration
+	if err := db.Where("uid = ? AND is_active = ?", uid, true).
+		Where("user_id = ? OR team_id IN (?)", userID, teamIDs).
+		First(&storageConfig).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Storage configuration not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to find storage configuration")
+	}
+	return &storageConfig, nil
+}
+
 // convertBackupJobToResponse converts a BackupJob model to response format
 func (h *BackupHandler) convertBackupJobToResponse(job models.BackupJob) BackupResponse {
 	var errorMessage, errorCode string
@@ -524,6 +891,8 @@ func (h *BackupHandler) convertBackupJobToResponse(job models.BackupJob) BackupR
 		Name:            job.Name,
 		Type:            job.Type,
 		Status:          job.Status,
+		Priority:        job.Priority,
+		QueueName:       job.QueueName,
 		Progress:        job.Progress,
 		ProgressMessage: job.CurrentStep,
 		StartedAt:       job.StartedAt,
@@ -561,34 +930,41 @@ func (h *BackupHandler) convertBackupJobToResponse(job models.BackupJob) BackupR
 	if len(job.BackupFiles) > 0 {
 		response.BackupFiles = make([]BackupFileResponse, len(job.BackupFiles))
 		for i, file := range job.BackupFiles {
-			response.BackupFiles[i] = BackupFileResponse{
-				ID:               file.ID,
-				UID:              file.UID,
-				Name:             file.Name,
-				OriginalName:     file.OriginalName,
-				FileType:         file.FileType,
-				Size:             file.Size,
-				OriginalSize:     file.OriginalSize,
-				CompressionAlgo:  file.CompressionAlgo,
-				IsCompressed:     file.IsCompressed,
-				IsEncrypted:      file.IsEncrypted,
-				IsArchived:       file.IsArchived,
-				S3Bucket:         file.S3Bucket,
-				S3Key:            file.S3Key,
-				S3Region:         file.S3Region,
-				S3Endpoint:       file.S3Endpoint,
-				DownloadCount:    file.DownloadCount,
-				LastDownloadedAt: nil, // field not available in model yet
-				ExpiresAt:        file.ExpiresAt,
-				CreatedAt:        file.CreatedAt,
-				UpdatedAt:        file.UpdatedAt,
-			}
+			response.BackupFiles[i] = newBackupFileResponse(file)
 		}
 	}
 
 	return response
 }
 
+// newBackupFileResponse converts a backup file to its API response
+func newBackupFileResponse(file models.BackupFile) BackupFileResponse {
+	return BackupFileResponse{
+		ID:               file.ID,
+		UID:              file.UID,
+		Name:             file.Name,
+		OriginalName:     file.OriginalName,
+		FileType:         file.FileType,
+		Size:             file.Size,
+		OriginalSize:     file.OriginalSize,
+		Checksum:         file.Checksum,
+		CompressionAlgo:  file.CompressionAlgo,
+		IsCompressed:     file.IsCompressed,
+		IsEncrypted:      file.IsEncrypted,
+		IsArchived:       file.IsArchived,
+		S3Bucket:         file.S3Bucket,
+		S3Key:            file.S3Key,
+		S3Region:         file.S3Region,
+		S3Endpoint:       file.S3Endpoint,
+		DownloadCount:    file.DownloadCount,
+		LastDownloadedAt: file.LastAccessedAt,
+		ExpiresAt:        file.ExpiresAt,
+		CreatedAt:        file.CreatedAt,
+		UpdatedAt:        file.UpdatedAt,
+		Locations:        file.Locations,
+	}
+}
+
 // RegisterBackupRoutes registers backup-related routes
 func (h *BackupHandler) RegisterRoutes(g *echo.Group) {
 	backups := g.Group("/backups")
@@ -598,5 +974,7 @@ func (h *BackupHandler) RegisterRoutes(g *echo.Group) {
 	backups.GET("/:uid", h.GetBackup)
 	backups.DELETE("/:uid", h.CancelBackup)
 	backups.POST("/:uid/retry", h.RetryBackup)
+	backups.POST("/:uid/restore", h.RestoreBackup)
+	backups.PUT("/:uid/priority", h.UpdateBackupPriority)
 	backups.GET("/:uid/progress", h.GetBackupProgress)
 }
\ No newline at end of file
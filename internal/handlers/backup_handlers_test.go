@@ -257,6 +257,19 @@ func (m *MockBackupWorker) EnqueueScheduledBackupJob(ctx context.Context, payloa
 	return args.Get(0).(*services.JobInfo), args.Error(1)
 }
 
+func (m *MockBackupWorker) ReprioritizeBackupJob(ctx context.Context, backupJob *models.BackupJob) error {
+	args := m.Called(ctx, backupJob)
+	return args.Error(0)
+}
+
+func (m *MockBackupWorker) EnqueueRestoreJob(ctx context.Context, jobType string, payload *workers.RestoreTaskPayload, options ...services.JobOption) (*services.JobInfo, error) {
+	args := m.Called(ctx, jobType, payload, options)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.JobInfo), args.Error(1)
+}
+
 
 // Test helper functions
 func setupTestDB() *gorm.DB {
@@ -271,7 +284,12 @@ func setupTestDB() *gorm.DB {
 		&models.DatabaseConnection{},
 		&models.BackupJob{},
 		&models.BackupFile{},
+		&models.BackupFileLocation{},
+		&models.RestoreJob{},
 		&models.StorageConfiguration{},
+		&models.TeamMember{},
+		&models.DatabaseTable{},
+		&models.TablePermission{},
 	)
 
 	return db
@@ -336,7 +354,7 @@ func TestBackupHandler_GetBackups_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/backups", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.GetBackups(c)
@@ -426,7 +444,7 @@ func TestBackupHandler_GetBackups_WithFilters(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/api/backups?"+tt.queryParams, nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			c.Set("user", user)
+			c.Set("user_model", user)
 
 			err := handler.GetBackups(c)
 			require.NoError(t, err)
@@ -475,7 +493,7 @@ func TestBackupHandler_GetBackups_Pagination(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/backups?page=1&limit=10", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	err := handler.GetBackups(c)
 	require.NoError(t, err)
@@ -495,7 +513,7 @@ func TestBackupHandler_GetBackups_Pagination(t *testing.T) {
 	req = httptest.NewRequest(http.MethodGet, "/api/backups?page=2&limit=10", nil)
 	rec = httptest.NewRecorder()
 	c = e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	err = handler.GetBackups(c)
 	require.NoError(t, err)
@@ -529,7 +547,7 @@ func TestBackupHandler_GetBackup_Success(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues(job.UID)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.GetBackup(c)
@@ -568,7 +586,7 @@ func TestBackupHandler_GetBackup_NotFound(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues("nonexistent")
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.GetBackup(c)
@@ -605,6 +623,7 @@ func TestBackupHandler_CreateBackup_Success(t *testing.T) {
 		Name:        "Test Backup",
 		DatabaseUID: dbConn.UID,
 		Type:        models.BackupTypeFull,
+		Options:     &services.BackupOptions{SchemaOnly: true},
 	}
 
 	payloadBytes, _ := json.Marshal(reqPayload)
@@ -616,7 +635,7 @@ func TestBackupHandler_CreateBackup_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.CreateBackup(c)
@@ -640,11 +659,73 @@ func TestBackupHandler_CreateBackup_Success(t *testing.T) {
 	assert.Equal(t, "Test Backup", createdJob.Name)
 	assert.Equal(t, user.ID, createdJob.UserID)
 	assert.Equal(t, dbConn.ID, createdJob.DatabaseConnectionID)
+	require.NotNil(t, createdJob.Options)
+	assert.Contains(t, *createdJob.Options, `"schema_only":true`)
 
 	// Verify mock was called
 	mockBackupWorker.AssertExpectations(t)
 }
 
+func TestBackupHandler_CreateBackup_WithStorageConfiguration(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+
+	storageConfig := &models.StorageConfiguration{
+		Name:      "Team bucket",
+		Provider:  models.StorageProviderAWS,
+		Region:    "us-east-1",
+		Bucket:    "team-backups",
+		AccessKey: "encrypted-access-key",
+		SecretKey: "encrypted-secret-key",
+		IsActive:  true,
+		UserID:    user.ID + 1,
+		TeamID:    func() *uint { id := uint(7); return &id }(),
+	}
+	require.NoError(t, db.Create(storageConfig).Error)
+
+	mockBackupWorker := &MockBackupWorker{}
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	create := func() *httptest.ResponseRecorder {
+		reqPayload := CreateBackupRequest{
+			Name:                    "Team Backup",
+			DatabaseUID:             dbConn.UID,
+			Type:                    models.BackupTypeFull,
+			StorageConfigurationUID: &storageConfig.UID,
+		}
+		payloadBytes, _ := json.Marshal(reqPayload)
+
+		e := echo.New()
+		e.Validator = &CustomValidator{validator: validator.New()}
+		req := httptest.NewRequest(http.MethodPost, "/api/backups", bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_model", user)
+
+		if err := handler.CreateBackup(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	// Not a member of the owning team yet
+	rec := create()
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: 7, UserID: user.ID, Role: models.TeamRoleMember, IsActive: true}).Error)
+
+	// Only the storage UID is passed to the worker
+	mockBackupWorker.On("EnqueueBackupJob", mock.Anything, workers.TypeBackupPostgreSQL, mock.MatchedBy(func(p *workers.BackupTaskPayload) bool {
+		return p.StorageUID == storageConfig.UID
+	}), mock.Anything).Return(&services.JobInfo{ID: "job-1"}, nil)
+
+	rec = create()
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockBackupWorker.AssertExpectations(t)
+}
+
 func TestBackupHandler_CreateBackup_DatabaseNotFound(t *testing.T) {
 	db := setupTestDB()
 	user := createTestUser(db)
@@ -673,7 +754,7 @@ func TestBackupHandler_CreateBackup_DatabaseNotFound(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.CreateBackup(c)
@@ -686,6 +767,85 @@ func TestBackupHandler_CreateBackup_DatabaseNotFound(t *testing.T) {
 	assert.Contains(t, httpErr.Message, "Database connection not found")
 }
 
+func TestBackupHandler_CreateBackup_TablePermissions(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+
+	// A connection shared with a team the user is a guest of
+	owner := &models.User{Email: "owner@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(owner).Error)
+	dbConn := createTestDatabaseConnection(db, owner.ID)
+	require.NoError(t, db.Model(dbConn).Update("team_id", 7).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: 7, UserID: user.ID, Role: models.TeamRoleGuest, IsActive: true}).Error)
+
+	table := &models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: dbConn.ID, LastDiscoveredAt: time.Now()}
+	require.NoError(t, db.Create(table).Error)
+
+	mockBackupWorker := &MockBackupWorker{}
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	create := func(options *services.BackupOptions) *httptest.ResponseRecorder {
+		payloadBytes, _ := json.Marshal(CreateBackupRequest{
+			Name:        "Guest Backup",
+			DatabaseUID: dbConn.UID,
+			Type:        models.BackupTypeFull,
+			Options:     options,
+		})
+
+		e := echo.New()
+		e.Validator = &CustomValidator{validator: validator.New()}
+		req := httptest.NewRequest(http.MethodPost, "/api/backups", bytes.NewReader(payloadBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user_model", user)
+
+		if err := handler.CreateBackup(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	// Guests may not back up tables without a grant
+	rec := create(&services.BackupOptions{Tables: []string{"users"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "users")
+
+	require.NoError(t, db.Create(&models.TablePermission{
+		UserID:          user.ID,
+		DatabaseTableID: table.ID,
+		AccessLevel:     models.TableAccessRead,
+		CanRead:         true,
+		CanBackup:       true,
+	}).Error)
+
+	// A grant covers the table but not the whole database
+	rec = create(nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Patterns and unknown names could make pg_dump select other tables
+	for _, name := range []string{"user*", "public.u%", "us?rs", "[u]sers", `"users"`, "salaries"} {
+		rec = create(&services.BackupOptions{Tables: []string{name}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+	rec = create(&services.BackupOptions{Tables: []string{"users"}, ExcludeTables: []string{"Salar*"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mockBackupWorker.On("EnqueueBackupJob", mock.Anything, workers.TypeBackupPostgreSQL, mock.Anything, mock.Anything).Return(&services.JobInfo{ID: "job-1"}, nil)
+
+	// Requested names are stored as the catalogued table they denote
+	rec = create(&services.BackupOptions{Tables: []string{"PUBLIC.Users"}})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response BackupResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	var job models.BackupJob
+	require.NoError(t, db.Where("uid = ?", response.UID).First(&job).Error)
+	assert.True(t, job.IsTableSpecific)
+	assert.Equal(t, []string{"users"}, job.Tables)
+	mockBackupWorker.AssertExpectations(t)
+}
+
 func TestBackupHandler_CreateBackup_ScheduledBackup(t *testing.T) {
 	db := setupTestDB()
 	user := createTestUser(db)
@@ -724,7 +884,7 @@ func TestBackupHandler_CreateBackup_ScheduledBackup(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.CreateBackup(c)
@@ -780,7 +940,7 @@ func TestBackupHandler_CancelBackup_Success(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues(job.UID)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.CancelBackup(c)
@@ -833,7 +993,7 @@ func TestBackupHandler_CancelBackup_AlreadyCompleted(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues(job.UID)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.CancelBackup(c)
@@ -886,7 +1046,7 @@ func TestBackupHandler_RetryBackup_Success(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues(job.UID)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.RetryBackup(c)
@@ -916,6 +1076,374 @@ func TestBackupHandler_RetryBackup_Success(t *testing.T) {
 	mockBackupWorker.AssertExpectations(t)
 }
 
+func TestBackupHandler_RetryBackup_ReplaysRequest(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+
+	storageConfig := &models.StorageConfiguration{
+		Name:      "Archive bucket",
+		Provider:  models.StorageProviderAWS,
+		Region:    "us-east-1",
+		Bucket:    "archive-backups",
+		AccessKey: "encrypted-access-key",
+		SecretKey: "encrypted-secret-key",
+		IsActive:  true,
+		UserID:    user.ID,
+	}
+	require.NoError(t, db.Create(storageConfig).Error)
+
+	// A sanitized export masking the users' emails
+	options, err := json.Marshal(&services.BackupOptions{
+		Tables: []string{"public.users"},
+		Sanitize: &services.SanitizeOptions{Rules: []services.TableSanitizeRule{
+			{Table: "public.users", Masks: map[string]services.MaskRule{"email": {Strategy: services.MaskHash}}},
+		}},
+	})
+	require.NoError(t, err)
+	encodedOptions := string(options)
+
+	job := &models.BackupJob{
+		Name:                   "Archive Backup",
+		Type:                   models.BackupTypeFull,
+		Status:                 models.BackupStatusFailed,
+		UserID:                 user.ID,
+		DatabaseConnectionID:   dbConn.ID,
+		IsTableSpecific:        true,
+		Tables:                 []string{"public.users"},
+		ExcludeTables:          []string{"public.sessions"},
+		Options:                &encodedOptions,
+		StorageConfigurationID: &storageConfig.ID,
+		ReplicaStorageUIDs:     []string{storageConfig.UID},
+	}
+	require.NoError(t, db.Create(job).Error)
+
+	// The retry exports the same tables with the same masks, to the storage the
+	// backup was created for and its replicas
+	mockBackupWorker := &MockBackupWorker{}
+	mockBackupWorker.On("EnqueueBackupJob", mock.Anything, workers.TypeBackupPostgreSQL, mock.MatchedBy(func(p *workers.BackupTaskPayload) bool {
+		return p.BackupJobID == job.ID && p.StorageUID == storageConfig.UID &&
+			assert.ObjectsAreEqual([]string{storageConfig.UID}, p.ReplicaStorageUIDs) &&
+			p.Options != nil &&
+			assert.ObjectsAreEqual([]string{"public.users"}, p.Options.Tables) &&
+			assert.ObjectsAreEqual([]string{"public.sessions"}, p.Options.ExcludeTables) &&
+			p.Options.Sanitize != nil && len(p.Options.Sanitize.Rules) == 1 &&
+			p.Options.Sanitize.Rules[0].Masks["email"].Strategy == services.MaskHash
+	}), mock.Anything).Return(&services.JobInfo{ID: "retry-job-1"}, nil)
+
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/backups/"+job.UID+"/retry", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	require.NoError(t, handler.RetryBackup(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockBackupWorker.AssertExpectations(t)
+}
+
+func TestBackupHandler_RestoreBackup_Success(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	job := createTestBackupJob(db, user.ID, dbConn.ID)
+
+	older := &models.BackupFile{Name: "older.sql", FileType: "sql", S3Bucket: "backups", S3Key: "older.sql", S3Region: "us-east-1", BackupJobID: job.ID}
+	db.Create(older)
+	latest := &models.BackupFile{Name: "latest.sql", FileType: "sql", S3Bucket: "backups", S3Key: "latest.sql", S3Region: "us-east-1", BackupJobID: job.ID}
+	db.Create(latest)
+
+	mockBackupWorker := &MockBackupWorker{}
+	mockBackupWorker.On("EnqueueRestoreJob", mock.Anything, workers.TypeRestorePostgreSQL, mock.MatchedBy(func(p *workers.RestoreTaskPayload) bool {
+		return p.RestoreJobID != 0 && p.BackupJobID == job.ID && p.BackupFileUID == older.UID && p.DatabaseUID == dbConn.UID && p.Options.CleanFirst
+	}), mock.Anything).Return(&services.JobInfo{ID: "restore-job-123"}, nil)
+
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	e := echo.New()
+	body := `{"backup_file_uid":"` + older.UID + `","options":{"clean_first":true}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/backups/"+job.UID+"/restore", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	err := handler.RestoreBackup(c)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	mockBackupWorker.AssertExpectations(t)
+
+	var response BackupResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, job.UID, response.UID)
+	assert.Len(t, response.BackupFiles, 2)
+
+	// The restore is tracked on its own record, the backup stays completed
+	var restoreJob models.RestoreJob
+	require.NoError(t, db.Where("backup_job_id = ?", job.ID).First(&restoreJob).Error)
+	assert.Equal(t, models.BackupStatusPending, restoreJob.Status)
+	assert.Equal(t, older.ID, restoreJob.BackupFileID)
+	assert.Equal(t, user.ID, restoreJob.UserID)
+	var backup models.BackupJob
+	require.NoError(t, db.First(&backup, job.ID).Error)
+	assert.Equal(t, models.BackupStatusCompleted, backup.Status)
+}
+
+func TestBackupHandler_RestoreBackup_InProgress(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	job := createTestBackupJob(db, user.ID, dbConn.ID)
+	file := &models.BackupFile{Name: "latest.sql", FileType: "sql", S3Bucket: "backups", S3Key: "latest.sql", S3Region: "us-east-1", BackupJobID: job.ID}
+	db.Create(file)
+	require.NoError(t, db.Create(&models.RestoreJob{Status: models.BackupStatusRunning, BackupJobID: job.ID, BackupFileID: file.ID, UserID: user.ID}).Error)
+
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, &MockBackupWorker{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/backups/"+job.UID+"/restore", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	err := handler.RestoreBackup(c)
+
+	require.Error(t, err)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+}
+
+func TestBackupHandler_RestoreBackup_Blocked(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	require.NoError(t, db.Model(dbConn).Association("Tags").Append(&models.DatabaseTag{Name: "Production"}))
+	job := createTestBackupJob(db, user.ID, dbConn.ID)
+	file := &models.BackupFile{Name: "latest.sql", FileType: "sql", S3Bucket: "backups", S3Key: "latest.sql", S3Region: "us-east-1", BackupJobID: job.ID}
+	db.Create(file)
+
+	auditService := &blockingAuditService{}
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, &MockBackupWorker{})
+	handler.SetAuditService(auditService)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/backups/"+job.UID+"/restore", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	err := handler.RestoreBackup(c)
+
+	// Blocked restores are never queued
+	require.Error(t, err)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	require.Len(t, auditService.events, 1)
+	event := auditService.events[0]
+	assert.Equal(t, models.AuditActionRestore, event.Action)
+	assert.Equal(t, user.ID, *event.UserID)
+	production, _ := event.GetMetadata("production")
+	assert.Equal(t, true, production)
+	backupFileUID, _ := event.GetMetadata("backup_file_uid")
+	assert.Equal(t, file.UID, backupFileUID)
+}
+
+func TestBackupHandler_RestoreBackup_NotCompleted(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	job := createTestBackupJob(db, user.ID, dbConn.ID)
+	db.Model(job).Update("status", models.BackupStatusFailed)
+
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, &MockBackupWorker{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/backups/"+job.UID+"/restore", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	err := handler.RestoreBackup(c)
+
+	require.Error(t, err)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestBackupHandler_UpdateBackupPriority(t *testing.T) {
+	db := setupTestDB()
+	require.NoError(t, db.AutoMigrate(&models.Team{}))
+	user := createTestUser(db)
+	team := &models.Team{Name: "Ops", Slug: "ops", SubscriptionTier: "enterprise"}
+	require.NoError(t, db.Create(team).Error)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	require.NoError(t, db.Model(dbConn).Update("team_id", team.ID).Error)
+	job := &models.BackupJob{
+		Name:                 "Test Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		Priority:             5,
+		UserID:               user.ID,
+		DatabaseConnectionID: dbConn.ID,
+		QueueName:            services.BackupLaneDefault,
+		QueueTaskID:          "task-1",
+		Tags:                 nil,
+	}
+	db.Create(job)
+
+	mockBackupWorker := &MockBackupWorker{}
+	mockBackupWorker.On("ReprioritizeBackupJob", mock.Anything, mock.MatchedBy(func(j *models.BackupJob) bool {
+		return j.ID == job.ID && j.Priority == 9
+	})).Run(func(args mock.Arguments) {
+		j := args.Get(1).(*models.BackupJob)
+		j.QueueName = services.BackupLaneCritical
+		j.QueueTaskID = "task-2"
+	}).Return(nil)
+
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator.New()}
+	req := httptest.NewRequest(http.MethodPut, "/api/backups/"+job.UID+"/priority", bytes.NewBufferString(`{"priority":9}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	require.NoError(t, handler.UpdateBackupPriority(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response BackupResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 9, response.Priority)
+	assert.Equal(t, services.BackupLaneCritical, response.QueueName)
+
+	var updated models.BackupJob
+	require.NoError(t, db.First(&updated, job.ID).Error)
+	assert.Equal(t, 9, updated.Priority)
+	assert.Equal(t, "task-2", updated.QueueTaskID)
+	mockBackupWorker.AssertExpectations(t)
+}
+
+func TestBackupHandler_UpdateBackupPriority_CappedByTier(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	job := &models.BackupJob{
+		Name:                 "Test Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		Priority:             3,
+		UserID:               user.ID,
+		DatabaseConnectionID: dbConn.ID,
+		QueueName:            services.BackupLaneLow,
+		QueueTaskID:          "task-1",
+		Tags:                 nil,
+	}
+	db.Create(job)
+
+	// Personal connections are on the free tier, which cannot rise above the default
+	mockBackupWorker := &MockBackupWorker{}
+	mockBackupWorker.On("ReprioritizeBackupJob", mock.Anything, mock.MatchedBy(func(j *models.BackupJob) bool {
+		return j.ID == job.ID && j.Priority == services.DefaultBackupPriority
+	})).Return(nil)
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator.New()}
+	req := httptest.NewRequest(http.MethodPut, "/api/backups/"+job.UID+"/priority", bytes.NewBufferString(`{"priority":10}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("uid")
+	c.SetParamValues(job.UID)
+	c.Set("user_model", user)
+
+	require.NoError(t, handler.UpdateBackupPriority(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var updated models.BackupJob
+	require.NoError(t, db.First(&updated, job.ID).Error)
+	assert.Equal(t, services.DefaultBackupPriority, updated.Priority)
+	mockBackupWorker.AssertExpectations(t)
+}
+
+func TestBackupHandler_UpdateBackupPriority_Conflicts(t *testing.T) {
+	db := setupTestDB()
+	user := createTestUser(db)
+	dbConn := createTestDatabaseConnection(db, user.ID)
+	completed := createTestBackupJob(db, user.ID, dbConn.ID)
+	started := &models.BackupJob{
+		Name:                 "Test Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		UserID:               user.ID,
+		DatabaseConnectionID: dbConn.ID,
+		QueueTaskID:          "task-1",
+		Tags:                 nil,
+	}
+	db.Create(started)
+
+	mockBackupWorker := &MockBackupWorker{}
+	mockBackupWorker.On("ReprioritizeBackupJob", mock.Anything, mock.Anything).Return(workers.ErrBackupNotQueued)
+	handler := NewBackupHandler(db, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, mockBackupWorker)
+
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator.New()}
+	update := func(uid, body string) error {
+		req := httptest.NewRequest(http.MethodPut, "/api/backups/"+uid+"/priority", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("uid")
+		c.SetParamValues(uid)
+		c.Set("user_model", user)
+		return handler.UpdateBackupPriority(c)
+	}
+
+	tests := []struct {
+		name string
+		uid  string
+		body string
+		code int
+	}{
+		{"out of range", started.UID, `{"priority":11}`, http.StatusBadRequest},
+		{"not pending", completed.UID, `{"priority":8}`, http.StatusConflict},
+		{"already started", started.UID, `{"priority":8}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := update(tt.uid, tt.body)
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok)
+			assert.Equal(t, tt.code, httpErr.Code)
+		})
+	}
+
+	// The priority of a job that could not move is unchanged
+	var unchanged models.BackupJob
+	require.NoError(t, db.First(&unchanged, started.ID).Error)
+	assert.Equal(t, 5, unchanged.Priority)
+}
+
 func TestBackupHandler_GetBackupProgress_Success(t *testing.T) {
 	db := setupTestDB()
 	user := createTestUser(db)
@@ -949,7 +1477,7 @@ func TestBackupHandler_GetBackupProgress_Success(t *testing.T) {
 	c := e.NewContext(req, rec)
 	c.SetParamNames("uid")
 	c.SetParamValues(job.UID)
-	c.Set("user", user)
+	c.Set("user_model", user)
 
 	// Execute
 	err := handler.GetBackupProgress(c)
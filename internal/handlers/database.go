@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -19,6 +20,7 @@ type DatabaseHandler struct {
 	db         *gorm.DB
 	dbService  *services.DatabaseService
 	encService *encryption.Service
+	policy     *services.TablePolicy
 }
 
 // NewDatabaseHandler creates a new database handler
@@ -27,6 +29,7 @@ func NewDatabaseHandler(db *gorm.DB, encService *encryption.Service) *DatabaseHa
 		db:         db,
 		dbService:  services.NewDatabaseService(db, encService),
 		encService: encService,
+		policy:     services.NewTablePolicy(db),
 	}
 }
 
@@ -117,6 +120,11 @@ func (h *DatabaseHandler) CreateDatabaseConnection(c echo.Context) error {
 		return responses.Error(c, http.StatusBadRequest, err.Error())
 	}
 
+	// Replicas of the connection's backups must go to storage the user can use
+	if err := h.checkReplicaStorage(user.ID, req.ReplicaStorageUIDs); err != nil {
+		return responses.Error(c, err.Code, fmt.Sprint(err.Message))
+	}
+
 	// Convert to model
 	conn := req.ToModel()
 	conn.UserID = user.ID
@@ -206,6 +214,10 @@ func (h *DatabaseHandler) UpdateDatabaseConnection(c echo.Context) error {
 		return responses.InternalError(c, "Failed to fetch database connection")
 	}
 
+	if err := h.checkReplicaStorage(user.ID, req.ReplicaStorageUIDs); err != nil {
+		return responses.Error(c, err.Code, fmt.Sprint(err.Message))
+	}
+
 	// Update fields
 	updatedConn := req.ToModel()
 	updatedConn.ID = conn.ID
@@ -239,6 +251,16 @@ func (h *DatabaseHandler) UpdateDatabaseConnection(c echo.Context) error {
 	return responses.Success(c, "Database connection updated successfully", updatedConn.ToPublic())
 }
 
+// checkReplicaStorage checks the user may store backups on each replica storage configuration
+func (h *DatabaseHandler) checkReplicaStorage(userID uint, uids []string) *echo.HTTPError {
+	for _, uid := range uids {
+		if _, err := findAccessibleStorage(h.db, userID, uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DeleteDatabaseConnection handles DELETE /api/databases/:uid
 func (h *DatabaseHandler) DeleteDatabaseConnection(c echo.Context) error {
 	// Get authenticated user (guaranteed to exist after auth middleware)
@@ -378,6 +400,7 @@ func (h *DatabaseHandler) ListTables(c echo.Context) error {
 	req := &models.TableDiscoveryRequest{
 		IncludeViews:  includeViews,
 		IncludeSystem: includeSystem,
+		SkipColumns:   true,
 	}
 
 	if schemaPattern != "" {
@@ -387,9 +410,8 @@ func (h *DatabaseHandler) ListTables(c echo.Context) error {
 		req.TablePattern = &tablePattern
 	}
 
-	// Find connection
-	var conn models.DatabaseConnection
-	err := h.db.Where("uid = ? AND user_id = ?", uid, user.ID).First(&conn).Error
+	// Find connection owned by the user or shared with their teams
+	conn, err := h.policy.FindConnection(c.Request().Context(), user.ID, uid)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return responses.NotFound(c, "Database connection not found")
@@ -397,12 +419,20 @@ func (h *DatabaseHandler) ListTables(c echo.Context) error {
 		return responses.InternalError(c, "Failed to fetch database connection")
 	}
 
+	access, err := h.policy.EffectivePermissions(c.Request().Context(), user.ID, conn)
+	if err != nil {
+		return responses.InternalError(c, "Failed to resolve table permissions")
+	}
+
 	// List tables dynamically (without saving to database)
-	tables, err := h.dbService.DiscoverTables(c.Request().Context(), &conn, req)
+	tables, err := h.dbService.DiscoverTables(c.Request().Context(), conn, req)
 	if err != nil {
 		return responses.InternalError(c, "Failed to list tables: "+err.Error())
 	}
 
+	// Only list tables the user may read
+	tables = h.policy.FilterTables(access, tables)
+
 	// Convert to public format
 	publicTables := make([]*models.DatabaseTablePublic, len(tables))
 	for i, table := range tables {
@@ -423,6 +453,52 @@ func (h *DatabaseHandler) ListTables(c echo.Context) error {
 	return responses.Success(c, "Tables listed successfully", response)
 }
 
+// ListPIIColumns handles GET /api/databases/:uid/pii
+func (h *DatabaseHandler) ListPIIColumns(c echo.Context) error {
+	// Get authenticated user (guaranteed to exist after auth middleware)
+	user := middleware.GetUserModel(c)
+
+	uid := c.Param("uid")
+	if uid == "" {
+		return responses.Error(c, http.StatusBadRequest, "Connection UID is required")
+	}
+
+	// Find connection owned by the user or shared with their teams
+	conn, err := h.policy.FindConnection(c.Request().Context(), user.ID, uid)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return responses.NotFound(c, "Database connection not found")
+		}
+		return responses.InternalError(c, "Failed to fetch database connection")
+	}
+
+	access, err := h.policy.EffectivePermissions(c.Request().Context(), user.ID, conn)
+	if err != nil {
+		return responses.InternalError(c, "Failed to resolve table permissions")
+	}
+
+	columns, err := h.dbService.ListPIIColumns(c.Request().Context(), conn.ID)
+	if err != nil {
+		return responses.InternalError(c, "Failed to list PII columns")
+	}
+
+	// Only list columns of tables the user may read
+	publicColumns := make([]*models.PIIColumnPublic, 0, len(columns))
+	for _, column := range columns {
+		if !access.Allows(column.DatabaseTable.GetFullName(), services.TableActionRead) {
+			continue
+		}
+		publicColumns = append(publicColumns, column.ToPIIPublic())
+	}
+
+	response := &models.PIIColumnListResponse{
+		Columns: publicColumns,
+		Count:   len(publicColumns),
+	}
+
+	return responses.Success(c, "PII columns listed successfully", response)
+}
+
 // DatabaseStats returns database connection statistics
 func DatabaseStats(c echo.Context) error {
 	stats, err := database.GetStats()
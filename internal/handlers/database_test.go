@@ -11,6 +11,7 @@ import (
 
 	"github.com/dbackup/backend-go/internal/encryption"
 	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
 	"github.com/dbackup/backend-go/internal/validation"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -920,6 +921,67 @@ func TestDatabaseHandler_DiscoverTables(t *testing.T) {
 	}
 }
 
+func TestDatabaseHandler_ListPIIColumns(t *testing.T) {
+	handler, db, owner, _ := setupDatabaseHandler(t)
+	require.NoError(t, db.AutoMigrate(&models.TeamMember{}, &models.TablePermission{}, &models.DatabaseColumn{}))
+	e := setupEchoWithValidator()
+
+	teamID := uint(5)
+	conn := &models.DatabaseConnection{Name: "App", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "app", Username: "app", UserID: owner.ID, TeamID: &teamID}
+	require.NoError(t, db.Create(conn).Error)
+
+	users := &models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now(),
+		Columns: []models.DatabaseColumn{
+			{Name: "id", DataType: "integer", OrdinalPosition: 1},
+			{Name: "email", DataType: "varchar", OrdinalPosition: 2},
+		}}
+	users.Columns[1].SetPIIClassification(models.PIITypeEmail, 0.95, services.PIISourceNameAndSample)
+	ledger := &models.DatabaseTable{Name: "ledger", Schema: "finance", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now(),
+		Columns: []models.DatabaseColumn{{Name: "tax_id", DataType: "varchar", OrdinalPosition: 1}}}
+	ledger.Columns[0].SetPIIClassification(models.PIITypeNationalID, 0.7, services.PIISourceName)
+	require.NoError(t, db.Create(users).Error)
+	require.NoError(t, db.Create(ledger).Error)
+
+	list := func(user *models.User) (*httptest.ResponseRecorder, models.PIIColumnListResponse) {
+		c, rec := storageRequest(e, http.MethodGet, "/api/databases/"+conn.UID+"/pii", conn.UID, nil, user)
+		require.NoError(t, handler.ListPIIColumns(c))
+
+		var response struct {
+			Data models.PIIColumnListResponse `json:"data"`
+		}
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		}
+		return rec, response.Data
+	}
+
+	rec, response := list(owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, 2, response.Count)
+	assert.Equal(t, "finance.ledger", response.Columns[0].Table)
+	assert.Equal(t, models.PIITypeNationalID, response.Columns[0].PIIType)
+	assert.Equal(t, "users", response.Columns[1].Table)
+	assert.Equal(t, "email", response.Columns[1].Column)
+	assert.Equal(t, services.PIISourceNameAndSample, response.Columns[1].Source)
+	assert.NotNil(t, response.Columns[1].DetectedAt)
+
+	// Team members only see columns of tables they may read
+	member := &models.User{UID: "member", Email: "member@example.com", Password: "hashedpassword", IsActive: true}
+	require.NoError(t, db.Create(member).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: member.ID, Role: models.TeamRoleMember, IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.TablePermission{UserID: member.ID, DatabaseTableID: ledger.ID, AccessLevel: models.TableAccessNone, GrantedAt: time.Now()}).Error)
+
+	rec, response = list(member)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "users", response.Columns[0].Table)
+
+	outsider := &models.User{UID: "outsider", Email: "outsider@example.com", Password: "hashedpassword", IsActive: true}
+	require.NoError(t, db.Create(outsider).Error)
+	rec, _ = list(outsider)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
 func TestDatabaseStats(t *testing.T) {
 	// This test would require a real database connection
 	// For now, we'll test that the handler exists and has the right signature
@@ -2,21 +2,68 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/workers"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// Health statuses. A degraded service still serves requests, but some
+// features, such as running backups, may not work.
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// DefaultHealthCacheTTL is how long storage and backup tool probes are reused
+const DefaultHealthCacheTTL = time.Minute
+
+// DefaultHealthQueueBacklog is the pending tasks above which the queues are backlogged
+const DefaultHealthQueueBacklog = 1000
+
+// criticalServices are the services the API cannot serve requests without
+var criticalServices = map[string]bool{
+	"database": true,
+	"redis":    true,
+}
+
+// ObjectStoreOpener opens the object store of a storage configuration
+type ObjectStoreOpener interface {
+	GetObjectStore(config *models.StorageConfiguration) (services.ObjectStore, error)
+}
+
+// BackupToolChecker reports on the dump and restore tools backups run
+type BackupToolChecker interface {
+	ValidateBackupTools() error
+	BackupToolVersions(ctx context.Context) map[string]string
+}
+
 // HealthHandler handles health check endpoints
 type HealthHandler struct {
 	db    *gorm.DB
 	redis *redis.Client
+
+	// Optional checks, each reported once set
+	storage      ObjectStoreOpener
+	storageUID   string
+	storageCache *cachedHealth
+	tools        BackupToolChecker
+	toolsCache   *cachedHealth
+	workers      services.WorkerStatusStore
+	queues       workers.QueueStatsSource
+	queueBacklog int64
 }
 
 // NewHealthHandler creates a new health handler
@@ -27,6 +74,35 @@ func NewHealthHandler(db *gorm.DB, redis *redis.Client) *HealthHandler {
 	}
 }
 
+// SetStorage probes the storage configuration with the given UID, or the
+// oldest active default configuration when empty, at most once per ttl
+func (h *HealthHandler) SetStorage(storage ObjectStoreOpener, storageUID string, ttl time.Duration) {
+	h.storage = storage
+	h.storageUID = storageUID
+	h.storageCache = newCachedHealth(ttl)
+}
+
+// SetBackupTools checks the backup tools and their versions at most once per ttl
+func (h *HealthHandler) SetBackupTools(tools BackupToolChecker, ttl time.Duration) {
+	h.tools = tools
+	h.toolsCache = newCachedHealth(ttl)
+}
+
+// SetWorkers checks that backup workers are alive from their heartbeats
+func (h *HealthHandler) SetWorkers(store services.WorkerStatusStore) {
+	h.workers = store
+}
+
+// SetQueues checks that the queues are served and have fewer than backlog
+// pending tasks
+func (h *HealthHandler) SetQueues(queues workers.QueueStatsSource, backlog int64) {
+	if backlog <= 0 {
+		backlog = DefaultHealthQueueBacklog
+	}
+	h.queues = queues
+	h.queueBacklog = backlog
+}
+
 // HealthStatus represents the health status of a service
 type HealthStatus struct {
 	Status      string            `json:"status"`
@@ -39,11 +115,12 @@ type HealthStatus struct {
 
 // Health represents the health of an individual service
 type Health struct {
-	Status    string        `json:"status"`
-	Message   string        `json:"message,omitempty"`
-	Error     string        `json:"error,omitempty"`
-	Latency   time.Duration `json:"latency,omitempty"`
-	Timestamp time.Time     `json:"timestamp"`
+	Status    string                 `json:"status"`
+	Message   string                 `json:"message,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Latency   time.Duration          `json:"latency,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
 
 var startTime = time.Now()
@@ -51,104 +128,99 @@ var startTime = time.Now()
 // Liveness returns a simple liveness check
 // GET /api/health/live
 func (h *HealthHandler) Liveness(c echo.Context) error {
-	return responses.Success(c, "Health check completed", map[string]interface{}{
+	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":    "alive",
 		"timestamp": time.Now(),
 	})
 }
 
-// Readiness returns a readiness check with dependencies
+// Readiness returns a readiness check with dependencies. It fails only when
+// a critical service is unhealthy and reports degraded otherwise.
 // GET /api/health/ready
 func (h *HealthHandler) Readiness(c echo.Context) error {
-	ctx := c.Request().Context()
 	status := &HealthStatus{
-		Status:      "healthy",
 		Timestamp:   time.Now(),
 		Uptime:      time.Since(startTime).String(),
 		Environment: getEnvironment(),
-		Services:    make(map[string]Health),
-	}
-
-	// Check database connectivity
-	dbHealth := h.checkDatabase(ctx)
-	status.Services["database"] = dbHealth
-
-	// Check Redis connectivity (if available)
-	if h.redis != nil {
-		redisHealth := h.checkRedis(ctx)
-		status.Services["redis"] = redisHealth
+		Services:    h.checkServices(c.Request().Context()),
 	}
-
-	// Determine overall status
-	overallHealthy := true
-	for _, service := range status.Services {
-		if service.Status != "healthy" {
-			overallHealthy = false
-			break
-		}
-	}
-
-	if !overallHealthy {
-		status.Status = "degraded"
-		return responses.Error(c, http.StatusServiceUnavailable, "Service unavailable") // Data: status)
-	}
-
-	return responses.Success(c, "Health check completed", status)
+	return h.respond(c, status)
 }
 
 // Health returns a comprehensive health check
 // GET /api/health
 func (h *HealthHandler) Health(c echo.Context) error {
-	ctx := c.Request().Context()
 	status := &HealthStatus{
-		Status:      "healthy",
 		Timestamp:   time.Now(),
 		Version:     getVersion(),
 		Uptime:      time.Since(startTime).String(),
 		Environment: getEnvironment(),
-		Services:    make(map[string]Health),
+		Services:    h.checkServices(c.Request().Context()),
 	}
+	return h.respond(c, status)
+}
 
-	// Check all services
-	dbHealth := h.checkDatabase(ctx)
-	status.Services["database"] = dbHealth
-
+// checkServices runs the checks of every configured service concurrently
+func (h *HealthHandler) checkServices(ctx context.Context) map[string]Health {
+	checks := map[string]func(context.Context) Health{
+		"database": h.checkDatabase,
+		"storage":  h.checkStorage,
+	}
 	if h.redis != nil {
-		redisHealth := h.checkRedis(ctx)
-		status.Services["redis"] = redisHealth
-	}
-
-	// Check external dependencies
-	s3Health := h.checkS3Connectivity(ctx)
-	status.Services["s3"] = s3Health
-
-	// Determine overall status
-	overallHealthy := true
-	degraded := false
-
-	for serviceName, service := range status.Services {
-		switch service.Status {
-		case "unhealthy":
-			// Critical services
-			if serviceName == "database" {
-				overallHealthy = false
-			} else {
-				degraded = true
-			}
-		case "degraded":
-			degraded = true
-		}
+		checks["redis"] = h.checkRedis
+	}
+	if h.tools != nil {
+		checks["backup_tools"] = h.checkBackupTools
+	}
+	if h.workers != nil {
+		checks["workers"] = h.checkWorkers
 	}
+	if h.queues != nil {
+		checks["queues"] = h.checkQueues
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]Health, len(checks))
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) Health) {
+			defer wg.Done()
+			health := check(ctx)
+			mu.Lock()
+			results[name] = health
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
 
-	if !overallHealthy {
-		status.Status = "unhealthy"
-		return responses.Error(c, http.StatusServiceUnavailable, "Service unavailable") // Data: status)
-	} else if degraded {
-		status.Status = "degraded"
-		return responses.Success(c, "Health check completed", status)
+// respond answers with the overall status of the services: unhealthy with
+// 503 when a critical service is unhealthy, degraded when any other service
+// is not healthy, and healthy otherwise
+func (h *HealthHandler) respond(c echo.Context, status *HealthStatus) error {
+	status.Status = overallStatus(status.Services)
+	if status.Status == HealthStatusUnhealthy {
+		return c.JSON(http.StatusServiceUnavailable, status)
 	}
+	return c.JSON(http.StatusOK, status)
+}
 
-	return responses.Success(c, "Health check completed", status)
+func overallStatus(services map[string]Health) string {
+	overall := HealthStatusHealthy
+	for name, service := range services {
+		switch {
+		case service.Status == HealthStatusHealthy:
+		case service.Status == HealthStatusUnhealthy && criticalServices[name]:
+			return HealthStatusUnhealthy
+		default:
+			overall = HealthStatusDegraded
+		}
+	}
+	return overall
 }
 
 // checkDatabase checks database connectivity and performance
@@ -159,7 +231,7 @@ func (h *HealthHandler) checkDatabase(ctx context.Context) Health {
 	}
 
 	if h.db == nil {
-		health.Status = "unhealthy"
+		health.Status = HealthStatusUnhealthy
 		health.Error = "database connection not configured"
 		return health
 	}
@@ -167,7 +239,7 @@ func (h *HealthHandler) checkDatabase(ctx context.Context) Health {
 	// Get underlying SQL DB for ping
 	sqlDB, err := h.db.DB()
 	if err != nil {
-		health.Status = "unhealthy"
+		health.Status = HealthStatusUnhealthy
 		health.Error = "failed to get database connection: " + err.Error()
 		health.Latency = time.Since(start)
 		return health
@@ -179,7 +251,7 @@ func (h *HealthHandler) checkDatabase(ctx context.Context) Health {
 
 	// Ping database
 	if err := sqlDB.PingContext(dbCtx); err != nil {
-		health.Status = "unhealthy"
+		health.Status = HealthStatusUnhealthy
 		health.Error = "database ping failed: " + err.Error()
 		health.Latency = time.Since(start)
 		return health
@@ -191,14 +263,14 @@ func (h *HealthHandler) checkDatabase(ctx context.Context) Health {
 
 	// Evaluate database health based on connection pool
 	if stats.MaxOpenConnections > 0 && stats.OpenConnections >= 0 && stats.OpenConnections <= stats.MaxOpenConnections {
-		health.Status = "healthy"
+		health.Status = HealthStatusHealthy
 		health.Message = "database connection pool healthy"
 	} else if stats.MaxOpenConnections == 0 && stats.OpenConnections >= 0 {
 		// No max limit configured, consider healthy if we have connections
-		health.Status = "healthy"
+		health.Status = HealthStatusHealthy
 		health.Message = "database connection pool healthy (no max limit)"
 	} else {
-		health.Status = "degraded"
+		health.Status = HealthStatusDegraded
 		health.Message = "database connection pool may be stressed"
 	}
 
@@ -213,7 +285,7 @@ func (h *HealthHandler) checkRedis(ctx context.Context) Health {
 	}
 
 	if h.redis == nil {
-		health.Status = "unhealthy"
+		health.Status = HealthStatusUnhealthy
 		health.Error = "redis connection not configured"
 		return health
 	}
@@ -224,36 +296,226 @@ func (h *HealthHandler) checkRedis(ctx context.Context) Health {
 
 	// Ping Redis
 	if err := h.redis.Ping(redisCtx).Err(); err != nil {
-		health.Status = "unhealthy"
+		health.Status = HealthStatusUnhealthy
 		health.Error = "redis ping failed: " + err.Error()
 		health.Latency = time.Since(start)
 		return health
 	}
 
-	health.Status = "healthy"
+	health.Status = HealthStatusHealthy
 	health.Message = "redis connection healthy"
 	health.Latency = time.Since(start)
 	return health
 }
 
-// checkS3Connectivity checks S3 service availability
-func (h *HealthHandler) checkS3Connectivity(ctx context.Context) Health {
+// checkStorage lists the probed storage configuration, reusing the last
+// result while it is fresh
+func (h *HealthHandler) checkStorage(ctx context.Context) Health {
+	if h.storage == nil || h.db == nil {
+		return Health{
+			Status:    HealthStatusHealthy,
+			Message:   "storage check skipped - no storage configured",
+			Timestamp: time.Now(),
+		}
+	}
+	// The result outlives the request, so is not cut short with it
+	return h.storageCache.get(func() Health {
+		return h.probeStorage(context.WithoutCancel(ctx))
+	})
+}
+
+func (h *HealthHandler) probeStorage(ctx context.Context) Health {
+	start := time.Now()
+	health := Health{
+		Timestamp: start,
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var config models.StorageConfiguration
+	query := h.db.WithContext(probeCtx)
+	if h.storageUID != "" {
+		query = query.Where("uid = ?", h.storageUID)
+	} else {
+		query = query.Where("is_default = ? AND is_active = ?", true, true).Order("id")
+	}
+	if err := query.First(&config).Error; err != nil {
+		health.Latency = time.Since(start)
+		if errors.Is(err, gorm.ErrRecordNotFound) && h.storageUID == "" {
+			health.Status = HealthStatusHealthy
+			health.Message = "storage check skipped - no default storage configuration"
+			return health
+		}
+		health.Status = HealthStatusDegraded
+		health.Error = "failed to load storage configuration: " + err.Error()
+		return health
+	}
+	health.Details = map[string]interface{}{
+		"storage_uid": config.UID,
+		"provider":    config.Provider,
+	}
+
+	store, err := h.storage.GetObjectStore(&config)
+	if err == nil {
+		_, err = store.List(probeCtx, "", 1)
+	}
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Status = HealthStatusDegraded
+		health.Error = "storage probe failed: " + err.Error()
+		return health
+	}
+
+	health.Status = HealthStatusHealthy
+	health.Message = fmt.Sprintf("%s storage reachable", config.GetProviderDisplayName())
+	return health
+}
+
+// checkBackupTools checks that the dump and restore tools are installed and
+// reports their versions, reusing the last result while it is fresh
+func (h *HealthHandler) checkBackupTools(ctx context.Context) Health {
+	return h.toolsCache.get(func() Health {
+		start := time.Now()
+		health := Health{
+			Timestamp: start,
+		}
+
+		versionCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		versions := h.tools.BackupToolVersions(versionCtx)
+		health.Details = make(map[string]interface{}, len(versions))
+		for tool, version := range versions {
+			health.Details[tool] = version
+		}
+
+		err := h.tools.ValidateBackupTools()
+		health.Latency = time.Since(start)
+		if err != nil {
+			health.Status = HealthStatusDegraded
+			health.Error = err.Error()
+			return health
+		}
+
+		health.Status = HealthStatusHealthy
+		health.Message = "backup tools available"
+		return health
+	})
+}
+
+// checkWorkers checks that some backup worker is alive and taking jobs
+func (h *HealthHandler) checkWorkers(ctx context.Context) Health {
 	start := time.Now()
 	health := Health{
 		Timestamp: start,
-		Status:    "healthy",
-		Message:   "s3 check skipped - configuration dependent",
-		Latency:   time.Since(start),
 	}
 
-	// Note: S3 health check would depend on storage configuration
-	// which is user-specific. For now, we return a neutral status.
-	// In a real implementation, you might check if default S3 
-	// credentials are configured and test connectivity.
+	statuses, err := h.workers.List(ctx)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Status = HealthStatusDegraded
+		health.Error = "failed to read worker heartbeats: " + err.Error()
+		return health
+	}
+
+	active, concurrency := 0, 0
+	for _, status := range statuses {
+		if !status.Draining {
+			active++
+			concurrency += status.Concurrency
+		}
+	}
+	health.Details = map[string]interface{}{
+		"workers":     len(statuses),
+		"active":      active,
+		"concurrency": concurrency,
+	}
+
+	if active == 0 {
+		health.Status = HealthStatusDegraded
+		health.Message = "no backup worker is taking jobs"
+		return health
+	}
 
+	health.Status = HealthStatusHealthy
+	health.Message = fmt.Sprintf("%d backup workers taking jobs", active)
 	return health
 }
 
+// checkQueues checks that no queue is paused and the backlog is below its threshold
+func (h *HealthHandler) checkQueues(ctx context.Context) Health {
+	start := time.Now()
+	health := Health{
+		Timestamp: start,
+	}
+
+	stats, err := h.queues.GetQueueStats(ctx)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Status = HealthStatusDegraded
+		health.Error = "failed to read queue stats: " + err.Error()
+		return health
+	}
+
+	var paused []string
+	for name, queue := range stats.Queues {
+		if queue.Paused {
+			paused = append(paused, name)
+		}
+	}
+	health.Details = map[string]interface{}{
+		"pending":   stats.Pending,
+		"active":    stats.Active,
+		"scheduled": stats.Scheduled,
+		"retry":     stats.Retry,
+		"backlog":   h.queueBacklog,
+	}
+
+	switch {
+	case stats.Pending >= h.queueBacklog:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d tasks pending, backlog threshold is %d", stats.Pending, h.queueBacklog)
+	case len(paused) > 0:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d queues paused", len(paused))
+		health.Details["paused"] = paused
+	default:
+		health.Status = HealthStatusHealthy
+		health.Message = "queues within backlog threshold"
+	}
+	return health
+}
+
+// cachedHealth reuses the result of a costly check while it is fresh.
+// Concurrent callers wait for the running check rather than start their own.
+type cachedHealth struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	health  Health
+	expires time.Time
+}
+
+func newCachedHealth(ttl time.Duration) *cachedHealth {
+	if ttl <= 0 {
+		ttl = DefaultHealthCacheTTL
+	}
+	return &cachedHealth{ttl: ttl}
+}
+
+func (c *cachedHealth) get(check func() Health) Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expires) {
+		return c.health
+	}
+	c.health = check()
+	c.expires = time.Now().Add(c.ttl)
+	return c.health
+}
+
 // getVersion returns the application version
 func getVersion() string {
 	// In a real application, this would be injected at build time
@@ -273,14 +535,18 @@ func getEnvironment() string {
 // RegisterRoutes registers health check routes
 func (h *HealthHandler) RegisterRoutes(e *echo.Echo) {
 	health := e.Group("/api/health")
-	
+
 	// Kubernetes-style probes
 	health.GET("/live", h.Liveness)   // Liveness probe
 	health.GET("/ready", h.Readiness) // Readiness probe
-	
+
 	// Comprehensive health check
-	health.GET("", h.Health)          // Detailed health status
-	health.GET("/", h.Health)         // Alternative path
+	health.GET("", h.Health)  // Detailed health status
+	health.GET("/", h.Health) // Alternative path
+
+	// Probes next to the legacy /health check
+	e.GET("/health/live", h.Liveness)
+	e.GET("/health/ready", h.Readiness)
 }
 
 // Legacy health check for backward compatibility
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -11,10 +12,15 @@ import (
 
 	"github.com/dbackup/backend-go/internal/config"
 	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // Mock Redis client for testing
@@ -75,7 +81,7 @@ func TestHealthHandler_Readiness_NoDependencies(t *testing.T) {
 	err = json.Unmarshal(rec.Body.Bytes(), &response)
 	require.NoError(t, err)
 
-	assert.Equal(t, "degraded", response.Status)
+	assert.Equal(t, "unhealthy", response.Status)
 	assert.NotNil(t, response.Services["database"])
 	assert.Equal(t, "unhealthy", response.Services["database"].Status)
 	assert.Contains(t, response.Services["database"].Error, "database connection not configured")
@@ -196,9 +202,9 @@ func TestHealthHandler_Health_Comprehensive(t *testing.T) {
 	assert.NotEmpty(t, response.Uptime)
 	assert.NotEmpty(t, response.Environment)
 	assert.NotNil(t, response.Services["database"])
-	assert.NotNil(t, response.Services["s3"])
+	assert.NotNil(t, response.Services["storage"])
 	assert.Equal(t, "healthy", response.Services["database"].Status)
-	assert.Equal(t, "healthy", response.Services["s3"].Status)
+	assert.Equal(t, "healthy", response.Services["storage"].Status)
 }
 
 func TestHealthHandler_checkDatabase_Success(t *testing.T) {
@@ -251,16 +257,15 @@ func TestHealthHandler_checkRedis_NoConnection(t *testing.T) {
 	assert.False(t, health.Timestamp.IsZero())
 }
 
-func TestHealthHandler_checkS3Connectivity(t *testing.T) {
+func TestHealthHandler_checkStorage_NotConfigured(t *testing.T) {
 	handler := NewHealthHandler(nil, nil)
 	
 	ctx := context.Background()
-	health := handler.checkS3Connectivity(ctx)
+	health := handler.checkStorage(ctx)
 
 	assert.Equal(t, "healthy", health.Status)
-	assert.Contains(t, health.Message, "s3 check skipped")
+	assert.Contains(t, health.Message, "storage check skipped")
 	assert.Empty(t, health.Error)
-	assert.Greater(t, health.Latency, time.Duration(0))
 	assert.False(t, health.Timestamp.IsZero())
 }
 
@@ -554,3 +559,171 @@ func TestLegacyHealthCheck(t *testing.T) {
 		assert.Greater(t, timestamp, float64(0))
 	})
 }
+
+type fakeStorageOpener struct {
+	root   string
+	err    error
+	opened int
+}
+
+func (f *fakeStorageOpener) GetObjectStore(config *models.StorageConfiguration) (services.ObjectStore, error) {
+	f.opened++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return services.NewLocalObjectStore(f.root)
+}
+
+type fakeBackupTools struct {
+	missing error
+	checked int
+}
+
+func (f *fakeBackupTools) ValidateBackupTools() error {
+	f.checked++
+	return f.missing
+}
+
+func (f *fakeBackupTools) BackupToolVersions(ctx context.Context) map[string]string {
+	return map[string]string{"pg_dump": "pg_dump (PostgreSQL) 16.2"}
+}
+
+type fakeQueueStats struct {
+	stats *services.QueueStats
+}
+
+func (f *fakeQueueStats) GetQueueStats(ctx context.Context) (*services.QueueStats, error) {
+	return f.stats, nil
+}
+
+func setupHealthTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.StorageConfiguration{}))
+	return db
+}
+
+func createHealthTestStorage(t *testing.T, db *gorm.DB, root string) *models.StorageConfiguration {
+	user := setupTestUser(t, db)
+	storage := &models.StorageConfiguration{
+		Name:      "Default",
+		Provider:  models.StorageProviderLocal,
+		Region:    "local",
+		AccessKey: "none",
+		SecretKey: "none",
+		Bucket:    root,
+		IsDefault: true,
+		IsActive:  true,
+		UserID:    user.ID,
+	}
+	require.NoError(t, db.Create(storage).Error)
+	return storage
+}
+
+func readinessResponse(t *testing.T, handler *HealthHandler) (int, HealthStatus) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/health/ready", nil), rec)
+	require.NoError(t, handler.Readiness(c))
+
+	var response HealthStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return rec.Code, response
+}
+
+func TestHealthHandler_Readiness_AllChecksHealthy(t *testing.T) {
+	db := setupHealthTestDB(t)
+	root := t.TempDir()
+	storage := createHealthTestStorage(t, db, root)
+
+	workerStore := services.NewLocalWorkerStatusStore()
+	require.NoError(t, workerStore.Publish(context.Background(), &services.WorkerStatus{ID: "w1", Concurrency: 4}, time.Minute))
+
+	handler := NewHealthHandler(db, nil)
+	handler.SetStorage(&fakeStorageOpener{root: root}, "", time.Minute)
+	handler.SetBackupTools(&fakeBackupTools{}, time.Minute)
+	handler.SetWorkers(workerStore)
+	handler.SetQueues(&fakeQueueStats{stats: &services.QueueStats{Pending: 3}}, 100)
+
+	code, response := readinessResponse(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", response.Status)
+	for _, name := range []string{"database", "storage", "backup_tools", "workers", "queues"} {
+		assert.Equal(t, "healthy", response.Services[name].Status, name)
+	}
+	assert.Equal(t, storage.UID, response.Services["storage"].Details["storage_uid"])
+	assert.Equal(t, "pg_dump (PostgreSQL) 16.2", response.Services["backup_tools"].Details["pg_dump"])
+	assert.Equal(t, float64(1), response.Services["workers"].Details["active"])
+}
+
+func TestHealthHandler_Readiness_Degraded(t *testing.T) {
+	db := setupHealthTestDB(t)
+	createHealthTestStorage(t, db, t.TempDir())
+
+	workerStore := services.NewLocalWorkerStatusStore()
+	require.NoError(t, workerStore.Publish(context.Background(), &services.WorkerStatus{ID: "w1", Draining: true}, time.Minute))
+
+	handler := NewHealthHandler(db, nil)
+	handler.SetStorage(&fakeStorageOpener{err: errors.New("access denied")}, "", time.Minute)
+	handler.SetBackupTools(&fakeBackupTools{missing: errors.New("missing backup tools: mysqldump")}, time.Minute)
+	handler.SetWorkers(workerStore)
+	handler.SetQueues(&fakeQueueStats{stats: &services.QueueStats{Pending: 150}}, 100)
+
+	// Backups would not run, but the API still serves requests
+	code, response := readinessResponse(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", response.Status)
+	assert.Equal(t, "healthy", response.Services["database"].Status)
+	assert.Contains(t, response.Services["storage"].Error, "access denied")
+	assert.Contains(t, response.Services["backup_tools"].Error, "mysqldump")
+	assert.Equal(t, "no backup worker is taking jobs", response.Services["workers"].Message)
+	assert.Contains(t, response.Services["queues"].Message, "150 tasks pending")
+	for _, name := range []string{"storage", "backup_tools", "workers", "queues"} {
+		assert.Equal(t, "degraded", response.Services[name].Status, name)
+	}
+}
+
+func TestHealthHandler_checkQueues_Paused(t *testing.T) {
+	handler := NewHealthHandler(nil, nil)
+	handler.SetQueues(&fakeQueueStats{stats: &services.QueueStats{Queues: map[string]*services.QueueInfo{
+		"critical": {Name: "critical"},
+		"low":      {Name: "low", Paused: true},
+	}}}, 0)
+
+	health := handler.checkQueues(context.Background())
+	assert.Equal(t, "degraded", health.Status)
+	assert.Equal(t, []string{"low"}, health.Details["paused"])
+}
+
+func TestHealthHandler_checkStorage_Cached(t *testing.T) {
+	db := setupHealthTestDB(t)
+	root := t.TempDir()
+	createHealthTestStorage(t, db, root)
+
+	opener := &fakeStorageOpener{root: root}
+	tools := &fakeBackupTools{}
+	handler := NewHealthHandler(db, nil)
+	handler.SetStorage(opener, "", time.Minute)
+	handler.SetBackupTools(tools, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "healthy", handler.checkStorage(context.Background()).Status)
+		assert.Equal(t, "healthy", handler.checkBackupTools(context.Background()).Status)
+	}
+	assert.Equal(t, 1, opener.opened)
+	assert.Equal(t, 1, tools.checked)
+}
+
+func TestHealthHandler_checkStorage_ConfiguredUIDMissing(t *testing.T) {
+	db := setupHealthTestDB(t)
+	handler := NewHealthHandler(db, nil)
+	handler.SetStorage(&fakeStorageOpener{root: t.TempDir()}, "missing-uid", time.Minute)
+
+	// Without a default configuration there is nothing to probe, but a
+	// configured one must exist
+	health := handler.checkStorage(context.Background())
+	assert.Equal(t, "degraded", health.Status)
+	assert.Contains(t, health.Error, "failed to load storage configuration")
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	errChannelUIDRequired = errors.New("notification channel UID is required")
+	errChannelForbidden   = errors.New("insufficient permissions to manage notification channel")
+)
+
+// NotificationHandler handles notification channels, their subscriptions
+// and delivery logs
+type NotificationHandler struct {
+	db            *gorm.DB
+	encService    *encryption.Service
+	notifications *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(db *gorm.DB, encService *encryption.Service, notifications *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		db:            db,
+		encService:    encService,
+		notifications: notifications,
+	}
+}
+
+// ListNotificationEvents handles GET /api/notifications/events
+func (h *NotificationHandler) ListNotificationEvents(c echo.Context) error {
+	return responses.Success(c, "Notification events retrieved successfully", models.NotificationEvents)
+}
+
+// ListChannels handles GET /api/notifications/channels
+func (h *NotificationHandler) ListChannels(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var channels []models.NotificationChannel
+	err := h.accessibleQuery(user.ID).
+		Preload("Subscriptions").
+		Order("created_at DESC").
+		Find(&channels).Error
+	if err != nil {
+		return responses.InternalError(c, "Failed to fetch notification channels")
+	}
+
+	publicChannels := make([]*models.NotificationChannelPublic, len(channels))
+	for i := range channels {
+		settings, err := channels[i].GetSettings(h.encService)
+		if err != nil {
+			return responses.InternalError(c, "Failed to decrypt channel settings")
+		}
+		publicChannels[i] = channels[i].ToPublic(settings)
+	}
+
+	return responses.Success(c, "Notification channels retrieved successfully", publicChannels)
+}
+
+// CreateChannel handles POST /api/notifications/channels
+func (h *NotificationHandler) CreateChannel(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.NotificationChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if req.TeamID != nil && !h.canManageTeam(user.ID, *req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	channel := &models.NotificationChannel{UserID: user.ID, IsActive: true}
+	settings := &models.NotificationChannelSettings{}
+	req.ApplyTo(channel, settings)
+
+	if err := settings.Validate(channel.Type); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := channel.SetSettings(settings, h.encService); err != nil {
+		return responses.InternalError(c, "Failed to encrypt channel settings")
+	}
+
+	if err := h.db.Create(channel).Error; err != nil {
+		return responses.InternalError(c, "Failed to create notification channel")
+	}
+
+	return responses.Created(c, "Notification channel created successfully", channel.ToPublic(settings))
+}
+
+// GetChannel handles GET /api/notifications/channels/:uid
+func (h *NotificationHandler) GetChannel(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	settings, err := channel.GetSettings(h.encService)
+	if err != nil {
+		return responses.InternalError(c, "Failed to decrypt channel settings")
+	}
+
+	return responses.Success(c, "Notification channel retrieved successfully", channel.ToPublic(settings))
+}
+
+// UpdateChannel handles PUT /api/notifications/channels/:uid
+func (h *NotificationHandler) UpdateChannel(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.NotificationChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	if req.TeamID != nil && (channel.TeamID == nil || *channel.TeamID != *req.TeamID) &&
+		!h.canManageTeam(user.ID, *req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	// Decrypt stored settings so an unchanged URL and secret are kept
+	settings, err := channel.GetSettings(h.encService)
+	if err != nil {
+		return responses.InternalError(c, "Failed to decrypt channel settings")
+	}
+
+	req.ApplyTo(channel, settings)
+
+	if err := settings.Validate(channel.Type); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := channel.SetSettings(settings, h.encService); err != nil {
+		return responses.InternalError(c, "Failed to encrypt channel settings")
+	}
+
+	if err := h.db.Omit("Subscriptions").Save(channel).Error; err != nil {
+		return responses.InternalError(c, "Failed to update notification channel")
+	}
+
+	return responses.Success(c, "Notification channel updated successfully", channel.ToPublic(settings))
+}
+
+// DeleteChannel handles DELETE /api/notifications/channels/:uid
+func (h *NotificationHandler) DeleteChannel(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", channel.ID).Delete(&models.NotificationSubscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(channel).Error
+	})
+	if err != nil {
+		return responses.InternalError(c, "Failed to delete notification channel")
+	}
+
+	return responses.Success(c, "Notification channel deleted successfully", nil)
+}
+
+// TestChannel handles POST /api/notifications/channels/:uid/test
+func (h *NotificationHandler) TestChannel(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	delivery, err := h.notifications.SendTest(c.Request().Context(), channel)
+	if err != nil {
+		return responses.InternalError(c, "Failed to send test notification")
+	}
+
+	// Record the outcome but don't fail the request
+	return responses.Success(c, "Test notification sent", delivery)
+}
+
+// CreateSubscription handles POST /api/notifications/channels/:uid/subscriptions
+func (h *NotificationHandler) CreateSubscription(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.NotificationSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if !req.Event.IsValid() {
+		return responses.Error(c, http.StatusBadRequest, "Unknown notification event")
+	}
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	subscription := &models.NotificationSubscription{ChannelID: channel.ID, Event: req.Event}
+	if req.DatabaseUID != "" {
+		teamIDs := h.db.Model(&models.TeamMember{}).Select("team_id").
+			Where("user_id = ? AND is_active = ?", user.ID, true)
+
+		var conn models.DatabaseConnection
+		err := h.db.Where("uid = ?", req.DatabaseUID).
+			Where("user_id = ? OR team_id IN (?)", user.ID, teamIDs).
+			First(&conn).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return responses.NotFound(c, "Database connection not found")
+			}
+			return responses.InternalError(c, "Failed to fetch database connection")
+		}
+		subscription.DatabaseConnectionID = &conn.ID
+	}
+
+	if err := h.db.Create(subscription).Error; err != nil {
+		return responses.InternalError(c, "Failed to create subscription")
+	}
+
+	return responses.Created(c, "Subscription created successfully", subscription)
+}
+
+// DeleteSubscription handles DELETE /api/notifications/channels/:uid/subscriptions/:subscriptionUid
+func (h *NotificationHandler) DeleteSubscription(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	result := h.db.Where("uid = ? AND channel_id = ?", c.Param("subscriptionUid"), channel.ID).
+		Delete(&models.NotificationSubscription{})
+	if result.Error != nil {
+		return responses.InternalError(c, "Failed to delete subscription")
+	}
+	if result.RowsAffected == 0 {
+		return responses.NotFound(c, "Subscription not found")
+	}
+
+	return responses.Success(c, "Subscription deleted successfully", nil)
+}
+
+// ListDeliveries handles GET /api/notifications/channels/:uid/deliveries
+func (h *NotificationHandler) ListDeliveries(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	channel, err := h.findChannel(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return channelLookupError(c, err)
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.Model(&models.NotificationDelivery{}).Where("channel_id = ?", channel.ID)
+
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.NotificationDelivery
+	err = query.Offset((page - 1) * limit).
+		Limit(limit).
+		Order("created_at DESC, id DESC").
+		Find(&deliveries).Error
+	if err != nil {
+		return responses.InternalError(c, "Failed to fetch notification deliveries")
+	}
+
+	paginationMeta := map[string]interface{}{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	}
+
+	return responses.SuccessWithMeta(c, "Notification deliveries retrieved successfully", deliveries, paginationMeta)
+}
+
+// accessibleQuery returns channels owned by the user or shared with their teams
+func (h *NotificationHandler) accessibleQuery(userID uint) *gorm.DB {
+	teamIDs := h.db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	return h.db.Model(&models.NotificationChannel{}).
+		Where("user_id = ? OR team_id IN (?)", userID, teamIDs)
+}
+
+// findChannel loads a channel by UID and checks access.
+// When manage is true the user must own it or be an admin of its team.
+func (h *NotificationHandler) findChannel(userID uint, uid string, manage bool) (*models.NotificationChannel, error) {
+	if uid == "" {
+		return nil, errChannelUIDRequired
+	}
+
+	var channel models.NotificationChannel
+	if err := h.accessibleQuery(userID).Preload("Subscriptions").Where("uid = ?", uid).First(&channel).Error; err != nil {
+		return nil, err
+	}
+
+	if manage && channel.UserID != userID &&
+		(channel.TeamID == nil || !h.canManageTeam(userID, *channel.TeamID)) {
+		return nil, errChannelForbidden
+	}
+
+	return &channel, nil
+}
+
+// channelLookupError writes the response for a failed channel lookup
+func channelLookupError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errChannelUIDRequired):
+		return responses.Error(c, http.StatusBadRequest, "Notification channel UID is required")
+	case errors.Is(err, errChannelForbidden):
+		return responses.Error(c, http.StatusForbidden, "Insufficient permissions to manage notification channel")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return responses.NotFound(c, "Notification channel not found")
+	default:
+		return responses.InternalError(c, "Failed to fetch notification channel")
+	}
+}
+
+// canManageTeam checks if the user is an owner or admin of the team
+func (h *NotificationHandler) canManageTeam(userID, teamID uint) bool {
+	var member models.TeamMember
+	err := h.db.Where("team_id = ? AND user_id = ? AND is_active = ?", teamID, userID, true).First(&member).Error
+	if err != nil {
+		return false
+	}
+	return member.IsAdmin()
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupNotificationHandler(t *testing.T) (*NotificationHandler, *gorm.DB, *models.User) {
+	db := setupTestDatabase(t)
+	require.NoError(t, db.AutoMigrate(&models.TeamMember{}, &models.NotificationChannel{}, &models.NotificationSubscription{}, &models.NotificationDelivery{}))
+	user := setupTestUser(t, db)
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+
+	notifications := services.NewNotificationService(db, encService, nil, config.NotificationConfig{MaxAttempts: 3, AllowPrivateNetworks: true})
+	return NewNotificationHandler(db, encService, notifications), db, user
+}
+
+// notificationRequest builds a request context; params are name, value pairs
+func notificationRequest(e *echo.Echo, method, path string, body interface{}, user *models.User, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	c.Set("user_model", user)
+	return c, rec
+}
+
+func createNotificationChannel(t *testing.T, handler *NotificationHandler, user *models.User, body map[string]interface{}) map[string]interface{} {
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodPost, "/api/notifications/channels", body, user)
+	require.NoError(t, handler.CreateChannel(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response["data"].(map[string]interface{})
+}
+
+func TestNotificationHandler_CreateChannel(t *testing.T) {
+	handler, db, user := setupNotificationHandler(t)
+
+	data := createNotificationChannel(t, handler, user, map[string]interface{}{
+		"name":   "Ops webhook",
+		"type":   "webhook",
+		"url":    "https://hooks.example.com/dbackup/T0KEN",
+		"secret": "signing-secret",
+	})
+	assert.Equal(t, "hooks.example.com", data["url_host"])
+	assert.Equal(t, true, data["has_secret"])
+	assert.NotContains(t, data, "secret")
+	assert.NotContains(t, data, "url")
+
+	var stored models.NotificationChannel
+	require.NoError(t, db.Where("uid = ?", data["uid"]).First(&stored).Error)
+	assert.NotContains(t, stored.Settings, "T0KEN", "settings must be encrypted at rest")
+	assert.NotContains(t, stored.Settings, "signing-secret")
+
+	e := setupEchoWithValidator()
+	for name, body := range map[string]map[string]interface{}{
+		"webhook without secret": {"name": "No secret", "type": "webhook", "url": "https://hooks.example.com"},
+		"slack without URL":      {"name": "No URL", "type": "slack"},
+		"invalid recipient":      {"name": "Mail", "type": "email", "recipients": []string{"not an address"}},
+		"unknown type":           {"name": "Pager", "type": "pager", "url": "https://pager.example.com"},
+	} {
+		c, rec := notificationRequest(e, http.MethodPost, "/api/notifications/channels", body, user)
+		require.NoError(t, handler.CreateChannel(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+}
+
+func TestNotificationHandler_UpdateKeepsSecret(t *testing.T) {
+	handler, db, user := setupNotificationHandler(t)
+
+	data := createNotificationChannel(t, handler, user, map[string]interface{}{
+		"name":   "Ops webhook",
+		"type":   "webhook",
+		"url":    "https://hooks.example.com/a",
+		"secret": "signing-secret",
+	})
+	uid := data["uid"].(string)
+
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodPut, "/api/notifications/channels/"+uid, map[string]interface{}{
+		"name":      "Renamed",
+		"type":      "webhook",
+		"is_active": false,
+	}, user, "uid", uid)
+	require.NoError(t, handler.UpdateChannel(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var stored models.NotificationChannel
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	assert.Equal(t, "Renamed", stored.Name)
+	assert.False(t, stored.IsActive)
+
+	settings, err := stored.GetSettings(handler.encService)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/a", settings.URL)
+	assert.Equal(t, "signing-secret", settings.Secret)
+}
+
+func TestNotificationHandler_Subscriptions(t *testing.T) {
+	handler, db, user := setupNotificationHandler(t)
+
+	data := createNotificationChannel(t, handler, user, map[string]interface{}{
+		"name":       "Team mail",
+		"type":       "email",
+		"recipients": []string{"ops@example.com"},
+	})
+	uid := data["uid"].(string)
+
+	conn := &models.DatabaseConnection{Name: "Orders", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "orders", Username: "app", Password: "x", UserID: user.ID}
+	require.NoError(t, db.Create(conn).Error)
+
+	other := &models.User{Email: "other@example.com", FirstName: "Other", LastName: "User", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	foreign := &models.DatabaseConnection{Name: "Foreign", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "foreign", Username: "app", Password: "x", UserID: other.ID}
+	require.NoError(t, db.Create(foreign).Error)
+
+	e := setupEchoWithValidator()
+	path := "/api/notifications/channels/" + uid + "/subscriptions"
+
+	c, rec := notificationRequest(e, http.MethodPost, path, map[string]interface{}{"event": "backup.failed", "database_uid": conn.UID}, user, "uid", uid)
+	require.NoError(t, handler.CreateSubscription(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	subscriptionUID := response["data"].(map[string]interface{})["uid"].(string)
+
+	var subscription models.NotificationSubscription
+	require.NoError(t, db.Where("uid = ?", subscriptionUID).First(&subscription).Error)
+	require.NotNil(t, subscription.DatabaseConnectionID)
+	assert.Equal(t, conn.ID, *subscription.DatabaseConnectionID)
+
+	c, rec = notificationRequest(e, http.MethodPost, path, map[string]interface{}{"event": "backup.exploded"}, user, "uid", uid)
+	require.NoError(t, handler.CreateSubscription(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = notificationRequest(e, http.MethodPost, path, map[string]interface{}{"event": "backup.failed", "database_uid": foreign.UID}, user, "uid", uid)
+	require.NoError(t, handler.CreateSubscription(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "connections of other users cannot be subscribed to")
+
+	c, rec = notificationRequest(e, http.MethodGet, "/api/notifications/channels/"+uid, nil, user, "uid", uid)
+	require.NoError(t, handler.GetChannel(c))
+	assert.Contains(t, rec.Body.String(), subscriptionUID)
+
+	c, rec = notificationRequest(e, http.MethodDelete, path+"/"+subscriptionUID, nil, user, "uid", uid, "subscriptionUid", subscriptionUID)
+	require.NoError(t, handler.DeleteSubscription(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	c, rec = notificationRequest(e, http.MethodDelete, path+"/"+subscriptionUID, nil, user, "uid", uid, "subscriptionUid", subscriptionUID)
+	require.NoError(t, handler.DeleteSubscription(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNotificationHandler_TestChannelAndDeliveries(t *testing.T) {
+	handler, _, user := setupNotificationHandler(t)
+
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get(services.WebhookSignatureHeader))
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	data := createNotificationChannel(t, handler, user, map[string]interface{}{
+		"name":   "Local",
+		"type":   "webhook",
+		"url":    server.URL,
+		"secret": "signing-secret",
+	})
+	uid := data["uid"].(string)
+
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodPost, "/api/notifications/channels/"+uid+"/test", nil, user, "uid", uid)
+	require.NoError(t, handler.TestChannel(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"status":"delivered"`)
+	assert.Equal(t, "test", received["event"])
+
+	c, rec = notificationRequest(e, http.MethodGet, "/api/notifications/channels/"+uid+"/deliveries", nil, user, "uid", uid)
+	require.NoError(t, handler.ListDeliveries(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data []models.NotificationDelivery `json:"data"`
+		Meta map[string]interface{}        `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, models.NotificationDeliveryDelivered, response.Data[0].Status)
+	assert.Equal(t, 1, response.Data[0].Attempts)
+}
+
+func TestNotificationHandler_TeamChannelAccess(t *testing.T) {
+	handler, db, user := setupNotificationHandler(t)
+
+	teamID := uint(5)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: user.ID, Role: models.TeamRoleAdmin, IsActive: true}).Error)
+
+	member := &models.User{Email: "member@example.com", FirstName: "Team", LastName: "Member", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(member).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: member.ID, Role: models.TeamRoleMember, IsActive: true}).Error)
+
+	body := map[string]interface{}{
+		"name":    "Team Slack",
+		"type":    "slack",
+		"url":     "https://hooks.slack.com/services/T/B/X",
+		"team_id": teamID,
+	}
+	uid := createNotificationChannel(t, handler, user, body)["uid"].(string)
+
+	e := setupEchoWithValidator()
+
+	// Team members can read but not manage
+	c, rec := notificationRequest(e, http.MethodGet, "/api/notifications/channels", nil, member)
+	require.NoError(t, handler.ListChannels(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), uid)
+	assert.NotContains(t, rec.Body.String(), "/services/T/B/X")
+
+	c, rec = notificationRequest(e, http.MethodDelete, "/api/notifications/channels/"+uid, nil, member, "uid", uid)
+	require.NoError(t, handler.DeleteChannel(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Non-admins cannot create team channels
+	c, rec = notificationRequest(e, http.MethodPost, "/api/notifications/channels", body, member)
+	require.NoError(t, handler.CreateChannel(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, rec = notificationRequest(e, http.MethodDelete, "/api/notifications/channels/"+uid, nil, user, "uid", uid)
+	require.NoError(t, handler.DeleteChannel(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// QueueAdminHandler lets administrators inspect and maintain the job queues
+type QueueAdminHandler struct {
+	queues       services.QueueAdmin
+	auditService services.AuditServiceInterface
+}
+
+// NewQueueAdminHandler creates a new queue administration handler
+func NewQueueAdminHandler(queues services.QueueAdmin, auditService services.AuditServiceInterface) *QueueAdminHandler {
+	return &QueueAdminHandler{
+		queues:       queues,
+		auditService: auditService,
+	}
+}
+
+// QueueJobsRequest selects jobs of a queue; no IDs selects all jobs in the state the action applies to
+type QueueJobsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// QueueJobsResponse reports how many jobs an action applied to
+type QueueJobsResponse struct {
+	Queue    string `json:"queue"`
+	Affected int    `json:"affected"`
+}
+
+// GetQueueStats handles GET /api/admin/queues
+func (h *QueueAdminHandler) GetQueueStats(c echo.Context) error {
+	stats, err := h.queues.GetQueueStats(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to get queue stats: %v", err)
+		return responses.InternalError(c, "Failed to get queue statistics")
+	}
+	return responses.Success(c, "Queue statistics retrieved successfully", stats)
+}
+
+// ListWorkers handles GET /api/admin/queues/workers
+func (h *QueueAdminHandler) ListWorkers(c echo.Context) error {
+	workers, err := h.queues.ListWorkers(c.Request().Context())
+	if err != nil {
+		c.Logger().Errorf("Failed to list workers: %v", err)
+		return responses.InternalError(c, "Failed to list workers")
+	}
+	return responses.Success(c, "Workers retrieved successfully", workers)
+}
+
+// ListJobs handles GET /api/admin/queues/:queue/jobs?state=&page=&page_size=
+func (h *QueueAdminHandler) ListJobs(c echo.Context) error {
+	queue := c.Param("queue")
+
+	state := services.JobState(c.QueryParam("state"))
+	if state == "" {
+		state = services.JobStatePending
+	}
+	if !isListableJobState(state) {
+		return responses.ValidationError(c, "Invalid query parameters", map[string]string{
+			"state": "must be one of pending, active, scheduled, retry, archived, completed",
+		})
+	}
+
+	page, err := queryInt(c, "page", 1)
+	if err != nil {
+		return responses.ValidationError(c, "Invalid query parameters", map[string]string{"page": "must be a positive number"})
+	}
+	pageSize, err := queryInt(c, "page_size", services.DefaultJobPageSize)
+	if err != nil {
+		return responses.ValidationError(c, "Invalid query parameters", map[string]string{"page_size": "must be a positive number"})
+	}
+	if pageSize > services.MaxJobPageSize {
+		pageSize = services.MaxJobPageSize
+	}
+
+	jobs, err := h.queues.ListQueueJobs(c.Request().Context(), queue, state, page, pageSize)
+	if err != nil {
+		return h.queueError(c, err, "Failed to list jobs")
+	}
+
+	return responses.SuccessWithMeta(c, "Jobs retrieved successfully", jobs, map[string]interface{}{
+		"queue":     queue,
+		"state":     state,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// PauseQueue handles POST /api/admin/queues/:queue/pause
+func (h *QueueAdminHandler) PauseQueue(c echo.Context) error {
+	queue := c.Param("queue")
+	if err := h.queues.PauseQueue(c.Request().Context(), queue); err != nil {
+		return h.queueError(c, err, "Failed to pause queue")
+	}
+
+	event := h.newAuditEvent(c, queue, "pause")
+	event.SetChanges(map[string]interface{}{"paused": false}, map[string]interface{}{"paused": true})
+	h.recordAudit(c, event)
+
+	return responses.Success(c, "Queue paused successfully", map[string]interface{}{"queue": queue, "paused": true})
+}
+
+// UnpauseQueue handles POST /api/admin/queues/:queue/unpause
+func (h *QueueAdminHandler) UnpauseQueue(c echo.Context) error {
+	queue := c.Param("queue")
+	if err := h.queues.UnpauseQueue(c.Request().Context(), queue); err != nil {
+		return h.queueError(c, err, "Failed to unpause queue")
+	}
+
+	event := h.newAuditEvent(c, queue, "unpause")
+	event.SetChanges(map[string]interface{}{"paused": true}, map[string]interface{}{"paused": false})
+	h.recordAudit(c, event)
+
+	return responses.Success(c, "Queue unpaused successfully", map[string]interface{}{"queue": queue, "paused": false})
+}
+
+// RetryJobs handles POST /api/admin/queues/:queue/jobs/retry and runs archived jobs again
+func (h *QueueAdminHandler) RetryJobs(c echo.Context) error {
+	return h.applyToJobs(c, "retry", "Jobs queued for retry", h.queues.RetryArchivedJobs)
+}
+
+// ArchiveJobs handles POST /api/admin/queues/:queue/jobs/archive and stops retrying failed jobs
+func (h *QueueAdminHandler) ArchiveJobs(c echo.Context) error {
+	return h.applyToJobs(c, "archive", "Jobs archived", h.queues.ArchiveRetryJobs)
+}
+
+// applyToJobs runs a bulk job action and audits it, including partial failures
+func (h *QueueAdminHandler) applyToJobs(c echo.Context, operation, message string, action func(ctx context.Context, queue string, ids []string) (int, error)) error {
+	queue := c.Param("queue")
+
+	var req QueueJobsRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return responses.Error(c, http.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	affected, err := action(c.Request().Context(), queue, req.IDs)
+	if affected > 0 {
+		event := h.newAuditEvent(c, queue, operation)
+		event.SetMetadata("job_ids", req.IDs)
+		event.SetMetadata("affected", affected)
+		h.recordAudit(c, event)
+	}
+	if err != nil {
+		return h.queueError(c, err, "Failed to "+operation+" jobs")
+	}
+
+	return responses.Success(c, message, QueueJobsResponse{Queue: queue, Affected: affected})
+}
+
+// queueError maps queue errors to responses
+func (h *QueueAdminHandler) queueError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrQueueNotFound):
+		return responses.NotFound(c, "Queue not found")
+	case errors.Is(err, services.ErrJobNotFound):
+		return responses.NotFound(c, err.Error())
+	default:
+		c.Logger().Errorf("%s: %v", message, err)
+		return responses.InternalError(c, message)
+	}
+}
+
+// newAuditEvent builds an audit event for a queue operation
+func (h *QueueAdminHandler) newAuditEvent(c echo.Context, queue, operation string) *models.AuditLog {
+	user := middleware.GetUserModel(c)
+	req := c.Request()
+
+	event := &models.AuditLog{
+		Action:     models.AuditActionUpdate,
+		Resource:   models.AuditResourceQueue,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		IPAddress:  c.RealIP(),
+		StatusCode: http.StatusOK,
+		UserID:     &user.ID,
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		event.RequestID = &requestID
+	}
+	event.SetMetadata("queue", queue)
+	event.SetMetadata("operation", operation)
+
+	return event
+}
+
+// recordAudit stores an audit event; the queue operation has already been applied
+func (h *QueueAdminHandler) recordAudit(c echo.Context, event *models.AuditLog) {
+	if h.auditService == nil {
+		return
+	}
+	if _, err := h.auditService.Record(c.Request().Context(), event); err != nil {
+		c.Logger().Errorf("Failed to record %s audit event: %v", event.Action, err)
+	}
+}
+
+// isListableJobState checks if jobs can be listed in a state
+func isListableJobState(state services.JobState) bool {
+	switch state {
+	case services.JobStatePending, services.JobStateActive, services.JobStateScheduled,
+		services.JobStateRetry, services.JobStateArchived, services.JobStateCompleted:
+		return true
+	}
+	return false
+}
+
+// queryInt reads a positive integer query parameter
+func queryInt(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive number")
+	}
+	return n, nil
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueueAdmin records queue operations instead of talking to Redis
+type fakeQueueAdmin struct {
+	paused    map[string]bool
+	archived  map[string][]string
+	listed    []interface{}
+	retryErr  error
+	retryDone int
+}
+
+func newFakeQueueAdmin() *fakeQueueAdmin {
+	return &fakeQueueAdmin{
+		paused:   make(map[string]bool),
+		archived: map[string][]string{"backups": {"a", "b", "c"}},
+	}
+}
+
+func (f *fakeQueueAdmin) GetQueueStats(ctx context.Context) (*services.QueueStats, error) {
+	return &services.QueueStats{Pending: 2, Queues: map[string]*services.QueueInfo{
+		"backups": {Name: "backups", Pending: 2, Paused: f.paused["backups"]},
+	}}, nil
+}
+
+func (f *fakeQueueAdmin) ListQueueJobs(ctx context.Context, queue string, state services.JobState, page, pageSize int) ([]*services.JobInfo, error) {
+	if queue != "backups" {
+		return nil, fmt.Errorf("%w: %s", services.ErrQueueNotFound, queue)
+	}
+	f.listed = []interface{}{state, page, pageSize}
+	return []*services.JobInfo{{ID: "a", Queue: queue, State: state, PayloadPreview: `{"password":"***"}`}}, nil
+}
+
+func (f *fakeQueueAdmin) PauseQueue(ctx context.Context, queue string) error {
+	f.paused[queue] = true
+	return nil
+}
+
+func (f *fakeQueueAdmin) UnpauseQueue(ctx context.Context, queue string) error {
+	delete(f.paused, queue)
+	return nil
+}
+
+func (f *fakeQueueAdmin) RetryArchivedJobs(ctx context.Context, queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		count := len(f.archived[queue])
+		f.archived[queue] = nil
+		return count, nil
+	}
+	return f.retryDone, f.retryErr
+}
+
+func (f *fakeQueueAdmin) ArchiveRetryJobs(ctx context.Context, queue string, ids []string) (int, error) {
+	return len(ids), nil
+}
+
+func (f *fakeQueueAdmin) ListWorkers(ctx context.Context) ([]*services.WorkerHeartbeat, error) {
+	return []*services.WorkerHeartbeat{{ID: "worker-1", Host: "node-a", Concurrency: 10}}, nil
+}
+
+func setupQueueAdminHandler(t *testing.T) (*QueueAdminHandler, *fakeQueueAdmin, *models.User, func() []models.AuditLog) {
+	db := setupTestDatabase(t)
+	require.NoError(t, db.AutoMigrate(&models.AuditLog{}))
+	admin := setupTestUser(t, db)
+
+	queues := newFakeQueueAdmin()
+	handler := NewQueueAdminHandler(queues, services.NewAuditService(db, nil))
+	audits := func() []models.AuditLog {
+		var logs []models.AuditLog
+		require.NoError(t, db.Order("id").Find(&logs).Error)
+		return logs
+	}
+	return handler, queues, admin, audits
+}
+
+func TestQueueAdminHandler_ListJobs(t *testing.T) {
+	handler, queues, admin, _ := setupQueueAdminHandler(t)
+	e := setupEchoWithValidator()
+
+	c, rec := storageRequest(e, http.MethodGet, "/api/admin/queues/backups/jobs?state=archived&page=2&page_size=500", "", nil, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.ListJobs(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []interface{}{services.JobStateArchived, 2, services.MaxJobPageSize}, queues.listed)
+
+	var response struct {
+		Data []services.JobInfo     `json:"data"`
+		Meta map[string]interface{} `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, `{"password":"***"}`, response.Data[0].PayloadPreview)
+	assert.Nil(t, response.Data[0].Payload)
+
+	tests := []struct {
+		name   string
+		queue  string
+		query  string
+		status int
+	}{
+		{"unknown state", "backups", "?state=failed", http.StatusBadRequest},
+		{"invalid page", "backups", "?page=0", http.StatusBadRequest},
+		{"unknown queue", "missing", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := storageRequest(e, http.MethodGet, "/api/admin/queues/"+tt.queue+"/jobs"+tt.query, "", nil, admin)
+			c.SetParamNames("queue")
+			c.SetParamValues(tt.queue)
+			require.NoError(t, handler.ListJobs(c))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestQueueAdminHandler_PauseAndUnpause(t *testing.T) {
+	handler, queues, admin, audits := setupQueueAdminHandler(t)
+	e := setupEchoWithValidator()
+
+	c, rec := storageRequest(e, http.MethodPost, "/api/admin/queues/backups/pause", "", nil, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.PauseQueue(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, queues.paused["backups"])
+
+	c, rec = storageRequest(e, http.MethodPost, "/api/admin/queues/backups/unpause", "", nil, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.UnpauseQueue(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, queues.paused["backups"])
+
+	logs := audits()
+	require.Len(t, logs, 2)
+	for i, operation := range []string{"pause", "unpause"} {
+		assert.Equal(t, models.AuditResourceQueue, logs[i].Resource)
+		assert.Equal(t, models.AuditActionUpdate, logs[i].Action)
+		assert.Equal(t, admin.ID, *logs[i].UserID)
+		value, _ := logs[i].GetMetadata("operation")
+		assert.Equal(t, operation, value)
+	}
+}
+
+func TestQueueAdminHandler_BulkActions(t *testing.T) {
+	handler, queues, admin, audits := setupQueueAdminHandler(t)
+	e := setupEchoWithValidator()
+
+	// Without IDs every archived job is retried
+	c, rec := storageRequest(e, http.MethodPost, "/api/admin/queues/backups/jobs/retry", "", nil, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.RetryJobs(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response struct {
+		Data QueueJobsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Data.Affected)
+
+	c, rec = storageRequest(e, http.MethodPost, "/api/admin/queues/backups/jobs/archive", "", map[string]interface{}{"ids": []string{"x", "y"}}, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.ArchiveJobs(c))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Data.Affected)
+
+	// Partial failures still audit the jobs that were changed
+	queues.retryDone = 1
+	queues.retryErr = fmt.Errorf("%w: y", services.ErrJobNotFound)
+	c, rec = storageRequest(e, http.MethodPost, "/api/admin/queues/backups/jobs/retry", "", map[string]interface{}{"ids": []string{"x", "y"}}, admin)
+	c.SetParamNames("queue")
+	c.SetParamValues("backups")
+	require.NoError(t, handler.RetryJobs(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	logs := audits()
+	require.Len(t, logs, 3)
+	affected, _ := logs[2].GetMetadata("affected")
+	assert.EqualValues(t, 1, affected)
+}
+
+func TestQueueAdminHandler_StatsAndWorkers(t *testing.T) {
+	handler, _, admin, _ := setupQueueAdminHandler(t)
+	e := setupEchoWithValidator()
+
+	c, rec := storageRequest(e, http.MethodGet, "/api/admin/queues", "", nil, admin)
+	require.NoError(t, handler.GetQueueStats(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"backups"`)
+
+	c, rec = storageRequest(e, http.MethodGet, "/api/admin/queues/workers", "", nil, admin)
+	require.NoError(t, handler.ListWorkers(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"worker-1"`)
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	errStorageUIDRequired = errors.New("storage configuration UID is required")
+	errStorageForbidden   = errors.New("insufficient permissions to manage storage configuration")
+)
+
+// StorageHandler handles storage configuration management
+type StorageHandler struct {
+	db             *gorm.DB
+	storageFactory *services.StorageFactory
+	encService     *encryption.Service
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(db *gorm.DB, encService *encryption.Service) *StorageHandler {
+	return NewStorageHandlerWithFactory(db, encService, services.NewStorageFactory(db, encService))
+}
+
+// NewStorageHandlerWithFactory creates a new storage handler with a custom storage factory
+func NewStorageHandlerWithFactory(db *gorm.DB, encService *encryption.Service, factory *services.StorageFactory) *StorageHandler {
+	return &StorageHandler{
+		db:             db,
+		storageFactory: factory,
+		encService:     encService,
+	}
+}
+
+// ListStorageConfigurations handles GET /api/storage
+func (h *StorageHandler) ListStorageConfigurations(c echo.Context) error {
+	// Get authenticated user (guaranteed to exist after auth middleware)
+	user := middleware.GetUserModel(c)
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.accessibleQuery(user.ID)
+
+	if provider := c.QueryParam("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	if isActive := c.QueryParam("active"); isActive != "" {
+		active, _ := strconv.ParseBool(isActive)
+		query = query.Where("is_active = ?", active)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var configs []models.StorageConfiguration
+	err := query.Offset((page - 1) * limit).
+		Limit(limit).
+		Order("created_at DESC").
+		Find(&configs).Error
+	if err != nil {
+		return responses.InternalError(c, "Failed to fetch storage configurations")
+	}
+
+	publicConfigs := make([]*models.StorageConfigurationPublic, len(configs))
+	for i := range configs {
+		publicConfigs[i] = configs[i].ToPublic()
+	}
+
+	paginationMeta := map[string]interface{}{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	}
+
+	return responses.SuccessWithMeta(c, "Storage configurations retrieved successfully", publicConfigs, paginationMeta)
+}
+
+// CreateStorageConfiguration handles POST /api/storage
+func (h *StorageHandler) CreateStorageConfiguration(c echo.Context) error {
+	// Get authenticated user (guaranteed to exist after auth middleware)
+	user := middleware.GetUserModel(c)
+
+	var req models.StorageConfigurationRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if req.Provider.RequiresCredentials() && (req.AccessKey == "" || req.SecretKey == "") {
+		return responses.Error(c, http.StatusBadRequest, "Access key and secret key are required")
+	}
+
+	if req.TeamID != nil && !h.canManageTeam(user.ID, *req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	config := req.ToModel()
+	config.UserID = user.ID
+	config.SetDefaultValues()
+
+	if err := h.validateConfiguration(config); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := config.EncryptCredentials(h.encService); err != nil {
+		return responses.InternalError(c, "Failed to encrypt credentials")
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(config).Error; err != nil {
+			return err
+		}
+		if config.IsDefault {
+			return clearOtherDefaults(tx, config)
+		}
+		return nil
+	})
+	if err != nil {
+		return responses.InternalError(c, "Failed to create storage configuration")
+	}
+
+	return responses.Created(c, "Storage configuration created successfully", config.ToPublic())
+}
+
+// GetStorageConfiguration handles GET /api/storage/:uid
+func (h *StorageHandler) GetStorageConfiguration(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	config, err := h.findConfiguration(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return storageLookupError(c, err)
+	}
+
+	return responses.Success(c, "Storage configuration retrieved successfully", config.ToPublic())
+}
+
+// UpdateStorageConfiguration handles PUT /api/storage/:uid
+func (h *StorageHandler) UpdateStorageConfiguration(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.StorageConfigurationRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	config, err := h.findConfiguration(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return storageLookupError(c, err)
+	}
+
+	if req.TeamID != nil && (config.TeamID == nil || *config.TeamID != *req.TeamID) &&
+		!h.canManageTeam(user.ID, *req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	// Decrypt stored credentials so unchanged secrets are re-encrypted consistently
+	if err := config.DecryptCredentials(h.encService); err != nil {
+		return responses.InternalError(c, "Failed to decrypt credentials")
+	}
+
+	req.ApplyTo(config)
+	config.SetDefaultValues()
+
+	if err := h.validateConfiguration(config); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := config.EncryptCredentials(h.encService); err != nil {
+		return responses.InternalError(c, "Failed to encrypt credentials")
+	}
+
+	// Connection details changed, so previous test results no longer apply
+	config.LastTestedAt = nil
+	config.LastTestError = nil
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(config).Error; err != nil {
+			return err
+		}
+		if config.IsDefault {
+			return clearOtherDefaults(tx, config)
+		}
+		return nil
+	})
+	if err != nil {
+		return responses.InternalError(c, "Failed to update storage configuration")
+	}
+	h.storageFactory.InvalidateCache(config.UID)
+
+	return responses.Success(c, "Storage configuration updated successfully", config.ToPublic())
+}
+
+// DeleteStorageConfiguration handles DELETE /api/storage/:uid
+func (h *StorageHandler) DeleteStorageConfiguration(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	config, err := h.findConfiguration(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return storageLookupError(c, err)
+	}
+
+	if err := h.db.Delete(config).Error; err != nil {
+		return responses.InternalError(c, "Failed to delete storage configuration")
+	}
+	h.storageFactory.InvalidateCache(config.UID)
+
+	return responses.Success(c, "Storage configuration deleted successfully", nil)
+}
+
+// TestStorageConfiguration handles POST /api/storage/:uid/test
+func (h *StorageHandler) TestStorageConfiguration(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	config, err := h.findConfiguration(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return storageLookupError(c, err)
+	}
+
+	result := h.storageFactory.TestStorageConfiguration(c.Request().Context(), config)
+
+	// Record the test result but don't fail the request
+	errorMsg := ""
+	if !result.Success {
+		errorMsg = result.Message
+		for _, step := range result.Steps {
+			if step.Error != "" {
+				errorMsg = result.Message + ": " + step.Error
+			}
+		}
+	}
+	config.SetTestResult(result.Success, errorMsg)
+	if err := h.db.Model(config).Select("last_tested_at", "last_test_error").Updates(config).Error; err != nil {
+		c.Logger().Errorf("Failed to update storage test result: %v", err)
+	}
+
+	return responses.Success(c, "Storage connection test completed", result)
+}
+
+// SetDefaultStorageConfiguration handles POST /api/storage/:uid/default
+func (h *StorageHandler) SetDefaultStorageConfiguration(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	config, err := h.findConfiguration(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return storageLookupError(c, err)
+	}
+
+	if !config.IsActive {
+		return responses.Error(c, http.StatusBadRequest, "Inactive storage configuration cannot be the default")
+	}
+
+	config.SetAsDefault()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(config).Update("is_default", true).Error; err != nil {
+			return err
+		}
+		return clearOtherDefaults(tx, config)
+	})
+	if err != nil {
+		return responses.InternalError(c, "Failed to set default storage configuration")
+	}
+
+	return responses.Success(c, "Default storage configuration updated successfully", config.ToPublic())
+}
+
+// accessibleQuery returns configurations owned by the user or shared with their teams
+func (h *StorageHandler) accessibleQuery(userID uint) *gorm.DB {
+	teamIDs := h.db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	return h.db.Model(&models.StorageConfiguration{}).
+		Where("user_id = ? OR team_id IN (?)", userID, teamIDs)
+}
+
+// findConfiguration loads a configuration by UID and checks access.
+// When manage is true the user must own it or be an admin of its team.
+func (h *StorageHandler) findConfiguration(userID uint, uid string, manage bool) (*models.StorageConfiguration, error) {
+	if uid == "" {
+		return nil, errStorageUIDRequired
+	}
+
+	var config models.StorageConfiguration
+	if err := h.accessibleQuery(userID).Where("uid = ?", uid).First(&config).Error; err != nil {
+		return nil, err
+	}
+
+	if manage && config.UserID != userID &&
+		(config.TeamID == nil || !h.canManageTeam(userID, *config.TeamID)) {
+		return nil, errStorageForbidden
+	}
+
+	return &config, nil
+}
+
+// storageLookupError writes the response for a failed configuration lookup
+func storageLookupError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errStorageUIDRequired):
+		return responses.Error(c, http.StatusBadRequest, "Storage configuration UID is required")
+	case errors.Is(err, errStorageForbidden):
+		return responses.Error(c, http.StatusForbidden, "Insufficient permissions to manage storage configuration")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return responses.NotFound(c, "Storage configuration not found")
+	default:
+		return responses.InternalError(c, "Failed to fetch storage configuration")
+	}
+}
+
+// canManageTeam checks if the user is an owner or admin of the team
+func (h *StorageHandler) canManageTeam(userID, teamID uint) bool {
+	var member models.TeamMember
+	err := h.db.Where("team_id = ? AND user_id = ? AND is_active = ?", teamID, userID, true).First(&member).Error
+	if err != nil {
+		return false
+	}
+	return member.IsAdmin()
+}
+
+// validateConfiguration runs provider and model validation on a plaintext configuration
+func (h *StorageHandler) validateConfiguration(config *models.StorageConfiguration) error {
+	if err := h.storageFactory.ValidateStorageConfiguration(config); err != nil {
+		return err
+	}
+	if errs := config.ValidateConfiguration(); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// clearOtherDefaults removes the default flag from other configurations in the same scope
+func clearOtherDefaults(tx *gorm.DB, config *models.StorageConfiguration) error {
+	query := tx.Model(&models.StorageConfiguration{}).Where("id <> ? AND is_default = ?", config.ID, true)
+	if config.TeamID != nil {
+		query = query.Where("team_id = ?", *config.TeamID)
+	} else {
+		query = query.Where("user_id = ? AND team_id IS NULL", config.UserID)
+	}
+	return query.Update("is_default", false).Error
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// probeS3Service is a minimal S3 service that stores objects in memory
+type probeS3Service struct {
+	MockS3Service
+	objects    map[string][]byte
+	connectErr error
+}
+
+func (p *probeS3Service) UploadFile(ctx context.Context, bucket, key string, data io.Reader, contentType string) (*services.S3UploadResult, error) {
+	content, _ := io.ReadAll(data)
+	p.objects[key] = content
+	return &services.S3UploadResult{Bucket: bucket, Key: key, Size: int64(len(content))}, nil
+}
+
+func (p *probeS3Service) DownloadFile(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	content, ok := p.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (p *probeS3Service) DeleteFile(ctx context.Context, bucket, key string) error {
+	delete(p.objects, key)
+	return nil
+}
+
+func (p *probeS3Service) TestConnection(ctx context.Context) error {
+	return p.connectErr
+}
+
+func setupStorageHandler(t *testing.T) (*StorageHandler, *gorm.DB, *models.User, *encryption.Service, *probeS3Service) {
+	db := setupTestDatabase(t)
+	require.NoError(t, db.AutoMigrate(&models.StorageConfiguration{}, &models.TeamMember{}))
+	user := setupTestUser(t, db)
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+
+	fake := &probeS3Service{objects: make(map[string][]byte)}
+	factory := services.NewStorageFactory(db, encService).WithS3ServiceConstructor(func(cfg *services.S3Config) (services.S3ServiceInterface, error) {
+		return fake, nil
+	}).WithLocalStorageRoots([]string{os.TempDir()})
+
+	return NewStorageHandlerWithFactory(db, encService, factory), db, user, encService, fake
+}
+
+func storageRequest(e *echo.Echo, method, path, uid string, body interface{}, user *models.User) (echo.Context, *httptest.ResponseRecorder) {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if uid != "" {
+		c.SetParamNames("uid")
+		c.SetParamValues(uid)
+	}
+	c.Set("user_model", user)
+	return c, rec
+}
+
+func validStorageRequest(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       name,
+		"provider":   "minio",
+		"region":     "us-east-1",
+		"endpoint":   "http://localhost:9000",
+		"access_key": "minio-access",
+		"secret_key": "minio-secret",
+		"bucket":     "backups",
+		"use_ssl":    false,
+	}
+}
+
+func createStorageConfig(t *testing.T, handler *StorageHandler, user *models.User, body map[string]interface{}) string {
+	e := setupEchoWithValidator()
+	c, rec := storageRequest(e, http.MethodPost, "/api/storage", "", body, user)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	return data["uid"].(string)
+}
+
+func TestStorageHandler_CreateStorageConfiguration(t *testing.T) {
+	handler, db, user, encService, _ := setupStorageHandler(t)
+
+	uid := createStorageConfig(t, handler, user, validStorageRequest("Primary"))
+
+	var stored models.StorageConfiguration
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	assert.NotEqual(t, "minio-access", stored.AccessKey, "credentials must be encrypted at rest")
+	assert.NotEqual(t, "minio-secret", stored.SecretKey)
+
+	require.NoError(t, stored.DecryptCredentials(encService))
+	assert.Equal(t, "minio-access", stored.AccessKey)
+	assert.Equal(t, "minio-secret", stored.SecretKey)
+
+	e := setupEchoWithValidator()
+
+	missingEndpoint := validStorageRequest("No endpoint")
+	delete(missingEndpoint, "endpoint")
+	c, rec := storageRequest(e, http.MethodPost, "/api/storage", "", missingEndpoint, user)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	missingSecret := validStorageRequest("No secret")
+	delete(missingSecret, "secret_key")
+	c, rec = storageRequest(e, http.MethodPost, "/api/storage", "", missingSecret, user)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Response must never contain secrets
+	c, rec = storageRequest(e, http.MethodGet, "/api/storage/"+uid, uid, nil, user)
+	require.NoError(t, handler.GetStorageConfiguration(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, strings.Contains(rec.Body.String(), "minio-secret"))
+}
+
+func TestStorageHandler_CreateLocalStorageConfiguration(t *testing.T) {
+	handler, db, user, _, _ := setupStorageHandler(t)
+	e := setupEchoWithValidator()
+
+	// Local directories need neither region nor credentials
+	uid := createStorageConfig(t, handler, user, map[string]interface{}{
+		"name":     "NFS share",
+		"provider": "local",
+		"bucket":   t.TempDir(),
+	})
+
+	var stored models.StorageConfiguration
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	assert.Equal(t, models.StorageProviderLocal, stored.Provider)
+
+	c, rec := storageRequest(e, http.MethodPost, "/api/storage/"+uid+"/test", uid, nil, user)
+	require.NoError(t, handler.TestStorageConfiguration(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	result := response["data"].(map[string]interface{})
+	assert.Equal(t, true, result["success"], rec.Body.String())
+
+	// S3-compatible providers still require a region
+	missingRegion := validStorageRequest("No region")
+	delete(missingRegion, "region")
+	c, rec = storageRequest(e, http.MethodPost, "/api/storage", "", missingRegion, user)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Directories outside the allowed roots are refused
+	outside := map[string]interface{}{"name": "Host files", "provider": "local", "bucket": "/etc"}
+	c, rec = storageRequest(e, http.MethodPost, "/api/storage", "", outside, user)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "allowed directories")
+}
+
+func TestStorageHandler_UpdateKeepsCredentials(t *testing.T) {
+	handler, db, user, encService, _ := setupStorageHandler(t)
+	uid := createStorageConfig(t, handler, user, validStorageRequest("Primary"))
+
+	update := validStorageRequest("Renamed")
+	delete(update, "access_key")
+	delete(update, "secret_key")
+
+	e := setupEchoWithValidator()
+	c, rec := storageRequest(e, http.MethodPut, "/api/storage/"+uid, uid, update, user)
+	require.NoError(t, handler.UpdateStorageConfiguration(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var stored models.StorageConfiguration
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	assert.Equal(t, "Renamed", stored.Name)
+	require.NoError(t, stored.DecryptCredentials(encService))
+	assert.Equal(t, "minio-access", stored.AccessKey)
+	assert.Equal(t, "minio-secret", stored.SecretKey)
+}
+
+func TestStorageHandler_SetDefaultAndDelete(t *testing.T) {
+	handler, db, user, _, _ := setupStorageHandler(t)
+
+	first := validStorageRequest("First")
+	first["is_default"] = true
+	firstUID := createStorageConfig(t, handler, user, first)
+	secondUID := createStorageConfig(t, handler, user, validStorageRequest("Second"))
+
+	e := setupEchoWithValidator()
+	c, rec := storageRequest(e, http.MethodPost, "/api/storage/"+secondUID+"/default", secondUID, nil, user)
+	require.NoError(t, handler.SetDefaultStorageConfiguration(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var configs []models.StorageConfiguration
+	require.NoError(t, db.Order("id").Find(&configs).Error)
+	require.Len(t, configs, 2)
+	assert.Equal(t, firstUID, configs[0].UID)
+	assert.False(t, configs[0].IsDefault)
+	assert.True(t, configs[1].IsDefault)
+
+	// Another user cannot see or delete the configuration
+	other := &models.User{Email: "other@example.com", FirstName: "Other", LastName: "User", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	c, rec = storageRequest(e, http.MethodDelete, "/api/storage/"+secondUID, secondUID, nil, other)
+	require.NoError(t, handler.DeleteStorageConfiguration(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = storageRequest(e, http.MethodDelete, "/api/storage/"+secondUID, secondUID, nil, user)
+	require.NoError(t, handler.DeleteStorageConfiguration(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var count int64
+	db.Model(&models.StorageConfiguration{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestStorageHandler_TeamConfigurationAccess(t *testing.T) {
+	handler, db, user, _, _ := setupStorageHandler(t)
+
+	teamID := uint(5)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: user.ID, Role: models.TeamRoleAdmin, IsActive: true}).Error)
+
+	member := &models.User{Email: "member@example.com", FirstName: "Team", LastName: "Member", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(member).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: member.ID, Role: models.TeamRoleMember, IsActive: true}).Error)
+
+	body := validStorageRequest("Team storage")
+	body["team_id"] = teamID
+	uid := createStorageConfig(t, handler, user, body)
+
+	e := setupEchoWithValidator()
+
+	// Team members can read but not manage
+	c, rec := storageRequest(e, http.MethodGet, "/api/storage", "", nil, member)
+	require.NoError(t, handler.ListStorageConfigurations(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), uid)
+
+	c, rec = storageRequest(e, http.MethodDelete, "/api/storage/"+uid, uid, nil, member)
+	require.NoError(t, handler.DeleteStorageConfiguration(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Non-admins cannot create team configurations
+	c, rec = storageRequest(e, http.MethodPost, "/api/storage", "", body, member)
+	require.NoError(t, handler.CreateStorageConfiguration(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestStorageHandler_TestStorageConfiguration(t *testing.T) {
+	handler, db, user, _, fake := setupStorageHandler(t)
+	uid := createStorageConfig(t, handler, user, validStorageRequest("Primary"))
+
+	e := setupEchoWithValidator()
+	c, rec := storageRequest(e, http.MethodPost, "/api/storage/"+uid+"/test", uid, nil, user)
+	require.NoError(t, handler.TestStorageConfiguration(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data models.StorageTestResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.True(t, response.Data.Success)
+	assert.Empty(t, fake.objects)
+
+	var stored models.StorageConfiguration
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	require.NotNil(t, stored.LastTestedAt)
+	assert.WithinDuration(t, time.Now(), *stored.LastTestedAt, time.Minute)
+	assert.Nil(t, stored.LastTestError)
+
+	fake.connectErr = errors.New("invalid credentials")
+	c, rec = storageRequest(e, http.MethodPost, "/api/storage/"+uid+"/test", uid, nil, user)
+	require.NoError(t, handler.TestStorageConfiguration(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.NoError(t, db.Where("uid = ?", uid).First(&stored).Error)
+	require.NotNil(t, stored.LastTestError)
+	assert.Contains(t, *stored.LastTestError, "invalid credentials")
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var errConnectionUIDRequired = errors.New("connection UID is required")
+
+// TablePermissionHandler handles per-table permission grants on database connections
+type TablePermissionHandler struct {
+	db           *gorm.DB
+	policy       *services.TablePolicy
+	auditService services.AuditServiceInterface
+}
+
+// NewTablePermissionHandler creates a new table permission handler
+func NewTablePermissionHandler(db *gorm.DB, auditService services.AuditServiceInterface) *TablePermissionHandler {
+	return &TablePermissionHandler{
+		db:           db,
+		policy:       services.NewTablePolicy(db),
+		auditService: auditService,
+	}
+}
+
+// TablePermissionResponse represents a table permission grant
+type TablePermissionResponse struct {
+	ID              uint                    `json:"id"`
+	UserID          uint                    `json:"user_id"`
+	Table           string                  `json:"table"`
+	AccessLevel     models.TableAccessLevel `json:"access_level"`
+	CanRead         bool                    `json:"can_read"`
+	CanWrite        bool                    `json:"can_write"`
+	CanBackup       bool                    `json:"can_backup"`
+	CanRestore      bool                    `json:"can_restore"`
+	CanDelete       bool                    `json:"can_delete"`
+	RowLevelFilter  models.RowFilter        `json:"row_level_filter,omitempty"`
+	ColumnMask      []string                `json:"column_mask,omitempty"`
+	TimeRestriction *string                 `json:"time_restriction,omitempty"`
+	GrantedBy       *uint                   `json:"granted_by,omitempty"`
+	GrantedAt       time.Time               `json:"granted_at"`
+	ExpiresAt       *time.Time              `json:"expires_at,omitempty"`
+	Description     *string                 `json:"description,omitempty"`
+	IsActive        bool                    `json:"is_active"`
+}
+
+// ListPermissions handles GET /api/databases/:uid/permissions
+func (h *TablePermissionHandler) ListPermissions(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	conn, err := h.findManagedConnection(c, user.ID)
+	if err != nil {
+		return tablePermissionError(c, err)
+	}
+
+	permissions, err := h.policy.ListPermissions(c.Request().Context(), conn)
+	if err != nil {
+		return responses.InternalError(c, "Failed to fetch table permissions")
+	}
+
+	data := make([]TablePermissionResponse, len(permissions))
+	for i := range permissions {
+		data[i] = toTablePermissionResponse(&permissions[i])
+	}
+
+	return responses.Success(c, "Table permissions retrieved successfully", data)
+}
+
+// GrantPermission handles POST /api/databases/:uid/permissions
+func (h *TablePermissionHandler) GrantPermission(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.TablePermissionRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	conn, err := h.findManagedConnection(c, user.ID)
+	if err != nil {
+		return tablePermissionError(c, err)
+	}
+
+	permission, previous, err := h.policy.Grant(c.Request().Context(), user.ID, conn, &req)
+	if err != nil {
+		return tablePermissionError(c, err)
+	}
+
+	response := toTablePermissionResponse(permission)
+	event := h.newAuditEvent(c, models.AuditActionPermissionGrant, conn, permission, http.StatusCreated)
+	event.NewValues = permissionAuditValues(&response)
+	if previous != nil {
+		previousResponse := toTablePermissionResponse(previous)
+		previousResponse.Table = response.Table
+		event.OldValues = permissionAuditValues(&previousResponse)
+	}
+	h.recordAudit(c, event)
+
+	return responses.Created(c, "Table permission granted successfully", response)
+}
+
+// RevokePermission handles DELETE /api/databases/:uid/permissions/:id
+func (h *TablePermissionHandler) RevokePermission(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid permission ID")
+	}
+
+	conn, err := h.findManagedConnection(c, user.ID)
+	if err != nil {
+		return tablePermissionError(c, err)
+	}
+
+	permission, err := h.policy.Revoke(c.Request().Context(), user.ID, conn, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.NotFound(c, "Table permission not found")
+		}
+		return tablePermissionError(c, err)
+	}
+
+	response := toTablePermissionResponse(permission)
+	event := h.newAuditEvent(c, models.AuditActionPermissionRevoke, conn, permission, http.StatusOK)
+	event.OldValues = permissionAuditValues(&response)
+	h.recordAudit(c, event)
+
+	return responses.Success(c, "Table permission revoked successfully", nil)
+}
+
+// findManagedConnection loads the connection and checks the user may manage its permissions
+func (h *TablePermissionHandler) findManagedConnection(c echo.Context, userID uint) (*models.DatabaseConnection, error) {
+	uid := c.Param("uid")
+	if uid == "" {
+		return nil, errConnectionUIDRequired
+	}
+
+	conn, err := h.policy.FindConnection(c.Request().Context(), userID, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := h.policy.CanManagePermissions(c.Request().Context(), userID, conn)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, services.ErrPermissionManagementDenied
+	}
+
+	return conn, nil
+}
+
+// tablePermissionError maps permission management errors to responses
+func tablePermissionError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errConnectionUIDRequired):
+		return responses.Error(c, http.StatusBadRequest, "Connection UID is required")
+	case errors.Is(err, services.ErrPermissionManagementDenied):
+		return responses.Error(c, http.StatusForbidden, "Insufficient permissions to manage table permissions")
+	case errors.Is(err, services.ErrTableNotCatalogued), errors.Is(err, services.ErrInvalidTablePermission):
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return responses.NotFound(c, "Database connection not found")
+	default:
+		return responses.InternalError(c, "Failed to manage table permissions")
+	}
+}
+
+// newAuditEvent builds an audit event for a permission change
+func (h *TablePermissionHandler) newAuditEvent(c echo.Context, action models.AuditAction, conn *models.DatabaseConnection, permission *models.TablePermission, status int) *models.AuditLog {
+	user := middleware.GetUserModel(c)
+	req := c.Request()
+
+	event := &models.AuditLog{
+		Action:     action,
+		Resource:   models.AuditResourceTablePermission,
+		ResourceID: &permission.ID,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		IPAddress:  c.RealIP(),
+		StatusCode: status,
+		UserID:     &user.ID,
+		TeamID:     conn.TeamID,
+	}
+	if userAgent := req.UserAgent(); userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		event.RequestID = &requestID
+	}
+	event.SetMetadata("database_connection_uid", conn.UID)
+	event.SetMetadata("table", permission.DatabaseTable.GetFullName())
+	event.SetMetadata("grantee_user_id", permission.UserID)
+
+	return event
+}
+
+// recordAudit stores an audit event; the permission change has already been applied
+func (h *TablePermissionHandler) recordAudit(c echo.Context, event *models.AuditLog) {
+	if h.auditService == nil {
+		return
+	}
+	if _, err := h.auditService.Record(c.Request().Context(), event); err != nil {
+		c.Logger().Errorf("Failed to record %s audit event: %v", event.Action, err)
+	}
+}
+
+// permissionAuditValues captures the audited fields of a permission
+func permissionAuditValues(permission *TablePermissionResponse) map[string]interface{} {
+	values := map[string]interface{}{
+		"user_id":      permission.UserID,
+		"table":        permission.Table,
+		"access_level": permission.AccessLevel,
+		"can_read":     permission.CanRead,
+		"can_backup":   permission.CanBackup,
+		"can_restore":  permission.CanRestore,
+	}
+	if len(permission.RowLevelFilter) > 0 {
+		values["row_level_filter"] = permission.RowLevelFilter
+	}
+	if len(permission.ColumnMask) > 0 {
+		values["column_mask"] = permission.ColumnMask
+	}
+	if permission.TimeRestriction != nil {
+		values["time_restriction"] = *permission.TimeRestriction
+	}
+	if permission.ExpiresAt != nil {
+		values["expires_at"] = permission.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+// toTablePermissionResponse converts a TablePermission model to response format
+func toTablePermissionResponse(permission *models.TablePermission) TablePermissionResponse {
+	return TablePermissionResponse{
+		ID:              permission.ID,
+		UserID:          permission.UserID,
+		Table:           permission.DatabaseTable.GetFullName(),
+		AccessLevel:     permission.AccessLevel,
+		CanRead:         permission.CanRead,
+		CanWrite:        permission.CanWrite,
+		CanBackup:       permission.CanBackup,
+		CanRestore:      permission.CanRestore,
+		CanDelete:       permission.CanDelete,
+		RowLevelFilter:  permission.RowLevelFilter,
+		ColumnMask:      permission.ColumnMask,
+		TimeRestriction: permission.TimeRestriction,
+		GrantedBy:       permission.GrantedBy,
+		GrantedAt:       permission.GrantedAt,
+		ExpiresAt:       permission.ExpiresAt,
+		Description:     permission.Description,
+		IsActive:        permission.IsActive(),
+	}
+}
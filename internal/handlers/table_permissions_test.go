@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTablePermissionHandler(t *testing.T) (*TablePermissionHandler, *gorm.DB, *models.User, *models.DatabaseConnection) {
+	db := setupTestDatabase(t)
+	require.NoError(t, db.AutoMigrate(&models.TeamMember{}, &models.TablePermission{}, &models.AuditLog{}))
+	owner := setupTestUser(t, db)
+
+	teamID := uint(3)
+	conn := &models.DatabaseConnection{
+		Name:     "App",
+		Type:     models.DatabaseTypePostgreSQL,
+		Host:     "localhost",
+		Port:     5432,
+		Database: "app",
+		Username: "app",
+		UserID:   owner.ID,
+		TeamID:   &teamID,
+	}
+	require.NoError(t, db.Create(conn).Error)
+	require.NoError(t, db.Create(&models.DatabaseTable{Name: "orders", Schema: "public", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now()}).Error)
+
+	handler := NewTablePermissionHandler(db, services.NewAuditService(db, nil))
+	return handler, db, owner, conn
+}
+
+func createTeamUser(t *testing.T, db *gorm.DB, email string, teamID uint, role models.TeamRole) *models.User {
+	user := &models.User{UID: email, Email: email, Password: "hashedpassword", IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: user.ID, Role: role, IsActive: true}).Error)
+	return user
+}
+
+func TestTablePermissionHandler_GrantAndRevoke(t *testing.T) {
+	handler, db, owner, conn := setupTablePermissionHandler(t)
+	analyst := createTeamUser(t, db, "analyst@example.com", *conn.TeamID, models.TeamRoleMember)
+	e := setupEchoWithValidator()
+
+	path := "/api/databases/" + conn.UID + "/permissions"
+	body := map[string]interface{}{
+		"user_id":          analyst.ID,
+		"table":            "orders",
+		"access_level":     "read",
+		"can_backup":       false,
+		"column_mask":      []string{"card_number"},
+		"time_restriction": "mon-fri 09:00-17:00",
+	}
+
+	c, rec := storageRequest(e, http.MethodPost, path, conn.UID, body, owner)
+	require.NoError(t, handler.GrantPermission(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response struct {
+		Data TablePermissionResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "orders", response.Data.Table)
+	assert.True(t, response.Data.CanRead)
+	assert.False(t, response.Data.CanBackup)
+	assert.Equal(t, []string{"card_number"}, response.Data.ColumnMask)
+
+	var grant models.AuditLog
+	require.NoError(t, db.Where("action = ?", models.AuditActionPermissionGrant).First(&grant).Error)
+	assert.Equal(t, models.AuditResourceTablePermission, grant.Resource)
+	require.NotNil(t, grant.ResourceID)
+	assert.Equal(t, response.Data.ID, *grant.ResourceID)
+	assert.Equal(t, owner.ID, *grant.UserID)
+	assert.Equal(t, conn.TeamID, grant.TeamID)
+	assert.Equal(t, "orders", grant.NewValues["table"])
+	assert.Nil(t, grant.OldValues)
+
+	c, rec = storageRequest(e, http.MethodGet, path, conn.UID, nil, owner)
+	require.NoError(t, handler.ListPermissions(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Data []TablePermissionResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+
+	c, rec = storageRequest(e, http.MethodDelete, path, conn.UID, nil, owner)
+	c.SetParamNames("uid", "id")
+	c.SetParamValues(conn.UID, fmt.Sprint(response.Data.ID))
+	require.NoError(t, handler.RevokePermission(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var revoke models.AuditLog
+	require.NoError(t, db.Where("action = ?", models.AuditActionPermissionRevoke).First(&revoke).Error)
+	assert.Equal(t, "orders", revoke.OldValues["table"])
+	assert.Equal(t, conn.UID, revoke.Metadata["database_connection_uid"])
+
+	var remaining int64
+	db.Model(&models.TablePermission{}).Count(&remaining)
+	assert.Zero(t, remaining)
+
+	// Revoking again reports the missing grant
+	c, rec = storageRequest(e, http.MethodDelete, path, conn.UID, nil, owner)
+	c.SetParamNames("uid", "id")
+	c.SetParamValues(conn.UID, fmt.Sprint(response.Data.ID))
+	require.NoError(t, handler.RevokePermission(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTablePermissionHandler_Errors(t *testing.T) {
+	handler, db, owner, conn := setupTablePermissionHandler(t)
+	member := createTeamUser(t, db, "member@example.com", *conn.TeamID, models.TeamRoleMember)
+	outsider := &models.User{UID: "outsider", Email: "outsider@example.com", Password: "hashedpassword", IsActive: true}
+	require.NoError(t, db.Create(outsider).Error)
+	e := setupEchoWithValidator()
+
+	path := "/api/databases/" + conn.UID + "/permissions"
+	grant := func(user *models.User, body map[string]interface{}) int {
+		c, rec := storageRequest(e, http.MethodPost, path, conn.UID, body, user)
+		require.NoError(t, handler.GrantPermission(c))
+		return rec.Code
+	}
+	valid := map[string]interface{}{"user_id": member.ID, "table": "orders", "access_level": "read"}
+
+	assert.Equal(t, http.StatusForbidden, grant(member, valid))
+	assert.Equal(t, http.StatusNotFound, grant(outsider, valid))
+	assert.Equal(t, http.StatusBadRequest, grant(owner, map[string]interface{}{"user_id": member.ID, "table": "orders", "access_level": "superuser"}))
+	assert.Equal(t, http.StatusBadRequest, grant(owner, map[string]interface{}{"user_id": member.ID, "table": "invoices", "access_level": "read"}))
+	assert.Equal(t, http.StatusBadRequest, grant(owner, map[string]interface{}{"user_id": outsider.ID, "table": "orders", "access_level": "read"}))
+	assert.Equal(t, http.StatusBadRequest, grant(owner, map[string]interface{}{"user_id": member.ID, "table": "orders", "access_level": "read", "time_restriction": "office hours"}))
+
+	var events int64
+	db.Model(&models.AuditLog{}).Count(&events)
+	assert.Zero(t, events, "failed grants are not audited")
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/responses"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	errWebhookUIDRequired = errors.New("webhook endpoint UID is required")
+	errWebhookForbidden   = errors.New("insufficient permissions to manage webhook endpoint")
+)
+
+// WebhookHandler handles team webhook endpoints, their delivery history
+// and redeliveries
+type WebhookHandler struct {
+	db         *gorm.DB
+	encService *encryption.Service
+	webhooks   *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(db *gorm.DB, encService *encryption.Service, webhooks *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		db:         db,
+		encService: encService,
+		webhooks:   webhooks,
+	}
+}
+
+// ListWebhookEvents handles GET /api/webhooks/events
+func (h *WebhookHandler) ListWebhookEvents(c echo.Context) error {
+	return responses.Success(c, "Webhook events retrieved successfully", models.WebhookEvents)
+}
+
+// ListEndpoints handles GET /api/webhooks
+func (h *WebhookHandler) ListEndpoints(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	query := h.accessibleQuery(user.ID)
+	if teamID := c.QueryParam("team_id"); teamID != "" {
+		query = query.Where("team_id = ?", teamID)
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := query.Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		return responses.InternalError(c, "Failed to fetch webhook endpoints")
+	}
+
+	return responses.Success(c, "Webhook endpoints retrieved successfully", endpoints)
+}
+
+// CreateEndpoint handles POST /api/webhooks
+func (h *WebhookHandler) CreateEndpoint(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.WebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if !h.canManageTeam(user.ID, req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := models.GenerateWebhookSecret()
+		if err != nil {
+			return responses.InternalError(c, "Failed to generate webhook secret")
+		}
+		secret = generated
+	}
+
+	endpoint := &models.WebhookEndpoint{UserID: user.ID, IsActive: true}
+	req.ApplyTo(endpoint)
+
+	if err := endpoint.SetSecret(secret, h.encService); err != nil {
+		return responses.InternalError(c, "Failed to encrypt webhook secret")
+	}
+
+	if err := h.db.Create(endpoint).Error; err != nil {
+		return responses.InternalError(c, "Failed to create webhook endpoint")
+	}
+
+	// Creation is the only time the signing secret is shown
+	data := responses.SerializeData(endpoint).(map[string]interface{})
+	data["secret"] = secret
+
+	return responses.Created(c, "Webhook endpoint created successfully", data)
+}
+
+// GetEndpoint handles GET /api/webhooks/:uid
+func (h *WebhookHandler) GetEndpoint(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	endpoint, err := h.findEndpoint(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return webhookLookupError(c, err)
+	}
+
+	return responses.Success(c, "Webhook endpoint retrieved successfully", endpoint)
+}
+
+// UpdateEndpoint handles PUT /api/webhooks/:uid
+func (h *WebhookHandler) UpdateEndpoint(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	var req models.WebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return responses.Error(c, http.StatusBadRequest, err.Error())
+	}
+
+	endpoint, err := h.findEndpoint(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return webhookLookupError(c, err)
+	}
+
+	if req.TeamID != endpoint.TeamID && !h.canManageTeam(user.ID, req.TeamID) {
+		return responses.Error(c, http.StatusForbidden, "Insufficient team permissions")
+	}
+
+	req.ApplyTo(endpoint)
+
+	// An empty secret keeps the stored one
+	if req.Secret != "" {
+		if err := endpoint.SetSecret(req.Secret, h.encService); err != nil {
+			return responses.InternalError(c, "Failed to encrypt webhook secret")
+		}
+	}
+
+	if err := h.db.Save(endpoint).Error; err != nil {
+		return responses.InternalError(c, "Failed to update webhook endpoint")
+	}
+
+	return responses.Success(c, "Webhook endpoint updated successfully", endpoint)
+}
+
+// DeleteEndpoint handles DELETE /api/webhooks/:uid
+func (h *WebhookHandler) DeleteEndpoint(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	endpoint, err := h.findEndpoint(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return webhookLookupError(c, err)
+	}
+
+	// Queued deliveries find the endpoint gone and fail
+	if err := h.db.Delete(endpoint).Error; err != nil {
+		return responses.InternalError(c, "Failed to delete webhook endpoint")
+	}
+
+	return responses.Success(c, "Webhook endpoint deleted successfully", nil)
+}
+
+// ListDeliveries handles GET /api/webhooks/:uid/deliveries
+func (h *WebhookHandler) ListDeliveries(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	endpoint, err := h.findEndpoint(user.ID, c.Param("uid"), false)
+	if err != nil {
+		return webhookLookupError(c, err)
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if event := c.QueryParam("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	err = query.Offset((page - 1) * limit).
+		Limit(limit).
+		Order("created_at DESC, id DESC").
+		Find(&deliveries).Error
+	if err != nil {
+		return responses.InternalError(c, "Failed to fetch webhook deliveries")
+	}
+
+	paginationMeta := map[string]interface{}{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	}
+
+	return responses.SuccessWithMeta(c, "Webhook deliveries retrieved successfully", deliveries, paginationMeta)
+}
+
+// Redeliver handles POST /api/webhooks/:uid/deliveries/:deliveryUid/redeliver
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	user := middleware.GetUserModel(c)
+
+	endpoint, err := h.findEndpoint(user.ID, c.Param("uid"), true)
+	if err != nil {
+		return webhookLookupError(c, err)
+	}
+
+	var delivery models.WebhookDelivery
+	err = h.db.Where("uid = ? AND endpoint_id = ?", c.Param("deliveryUid"), endpoint.ID).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.NotFound(c, "Webhook delivery not found")
+		}
+		return responses.InternalError(c, "Failed to fetch webhook delivery")
+	}
+
+	redelivery, err := h.webhooks.Redeliver(c.Request().Context(), &delivery)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookQueueUnavailable) {
+			return responses.Error(c, http.StatusServiceUnavailable, "Webhook queue is not available")
+		}
+		return responses.InternalError(c, "Failed to queue webhook redelivery")
+	}
+
+	return responses.Success(c, "Webhook redelivery queued", redelivery)
+}
+
+// accessibleQuery returns endpoints of the teams the user is a member of
+func (h *WebhookHandler) accessibleQuery(userID uint) *gorm.DB {
+	teamIDs := h.db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	return h.db.Model(&models.WebhookEndpoint{}).Where("team_id IN (?)", teamIDs)
+}
+
+// findEndpoint loads an endpoint by UID and checks access.
+// When manage is true the user must be an admin of its team.
+func (h *WebhookHandler) findEndpoint(userID uint, uid string, manage bool) (*models.WebhookEndpoint, error) {
+	if uid == "" {
+		return nil, errWebhookUIDRequired
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := h.accessibleQuery(userID).Where("uid = ?", uid).First(&endpoint).Error; err != nil {
+		return nil, err
+	}
+
+	if manage && !h.canManageTeam(userID, endpoint.TeamID) {
+		return nil, errWebhookForbidden
+	}
+
+	return &endpoint, nil
+}
+
+// webhookLookupError writes the response for a failed endpoint lookup
+func webhookLookupError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errWebhookUIDRequired):
+		return responses.Error(c, http.StatusBadRequest, "Webhook endpoint UID is required")
+	case errors.Is(err, errWebhookForbidden):
+		return responses.Error(c, http.StatusForbidden, "Insufficient permissions to manage webhook endpoint")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return responses.NotFound(c, "Webhook endpoint not found")
+	default:
+		return responses.InternalError(c, "Failed to fetch webhook endpoint")
+	}
+}
+
+// canManageTeam checks if the user is an owner or admin of the team
+func (h *WebhookHandler) canManageTeam(userID, teamID uint) bool {
+	var member models.TeamMember
+	err := h.db.Where("team_id = ? AND user_id = ? AND is_active = ?", teamID, userID, true).First(&member).Error
+	if err != nil {
+		return false
+	}
+	return member.IsAdmin()
+}
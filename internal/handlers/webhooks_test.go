@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type countingEnqueuer struct {
+	queued int
+}
+
+func (e *countingEnqueuer) EnqueueJob(ctx context.Context, jobType string, payload interface{}, opts ...services.JobOption) (*services.JobInfo, error) {
+	e.queued++
+	return &services.JobInfo{ID: "task", Type: jobType}, nil
+}
+
+// setupWebhookHandler creates a handler and a team the user is an admin of
+func setupWebhookHandler(t *testing.T) (*WebhookHandler, *gorm.DB, *models.User, uint, *countingEnqueuer) {
+	db := setupTestDatabase(t)
+	require.NoError(t, db.AutoMigrate(&models.TeamMember{}, &models.WebhookEndpoint{}, &models.WebhookDelivery{}))
+	user := setupTestUser(t, db)
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+
+	teamID := uint(5)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: user.ID, Role: models.TeamRoleAdmin, IsActive: true}).Error)
+
+	queue := &countingEnqueuer{}
+	webhooks := services.NewWebhookService(db, encService, queue, config.WebhookConfig{MaxAttempts: 3})
+	return NewWebhookHandler(db, encService, webhooks), db, user, teamID, queue
+}
+
+func createWebhookEndpoint(t *testing.T, handler *WebhookHandler, user *models.User, body map[string]interface{}) map[string]interface{} {
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodPost, "/api/webhooks", body, user)
+	require.NoError(t, handler.CreateEndpoint(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response["data"].(map[string]interface{})
+}
+
+func TestWebhookHandler_CreateEndpoint(t *testing.T) {
+	handler, db, user, teamID, _ := setupWebhookHandler(t)
+
+	data := createWebhookEndpoint(t, handler, user, map[string]interface{}{
+		"name":    "CI",
+		"url":     "https://ci.example.com/dbackup",
+		"events":  []string{"backup.completed", "backup.failed"},
+		"team_id": teamID,
+	})
+	secret, _ := data["secret"].(string)
+	assert.Contains(t, secret, "whsec_", "a generated secret is returned on creation")
+
+	var stored models.WebhookEndpoint
+	require.NoError(t, db.Where("uid = ?", data["uid"]).First(&stored).Error)
+	assert.NotContains(t, stored.Secret, secret[len("whsec_"):], "secrets must be encrypted at rest")
+	assert.Equal(t, []models.WebhookEvent{models.WebhookBackupCompleted, models.WebhookBackupFailed}, stored.Events)
+
+	// The secret is not shown again
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodGet, "/api/webhooks/"+stored.UID, nil, user, "uid", stored.UID)
+	require.NoError(t, handler.GetEndpoint(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "whsec_")
+
+	for name, body := range map[string]map[string]interface{}{
+		"unknown event": {"name": "CI", "url": "https://ci.example.com", "events": []string{"backup.exploded"}, "team_id": teamID},
+		"no events":     {"name": "CI", "url": "https://ci.example.com", "events": []string{}, "team_id": teamID},
+		"ftp URL":       {"name": "CI", "url": "ftp://ci.example.com", "events": []string{"backup.failed"}, "team_id": teamID},
+		"short secret":  {"name": "CI", "url": "https://ci.example.com", "events": []string{"backup.failed"}, "team_id": teamID, "secret": "short"},
+		"no team":       {"name": "CI", "url": "https://ci.example.com", "events": []string{"backup.failed"}},
+	} {
+		c, rec := notificationRequest(e, http.MethodPost, "/api/webhooks", body, user)
+		require.NoError(t, handler.CreateEndpoint(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+}
+
+func TestWebhookHandler_TeamAccess(t *testing.T) {
+	handler, db, user, teamID, _ := setupWebhookHandler(t)
+
+	member := &models.User{Email: "member@example.com", FirstName: "Team", LastName: "Member", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(member).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: member.ID, Role: models.TeamRoleMember, IsActive: true}).Error)
+	outsider := &models.User{Email: "outsider@example.com", FirstName: "Out", LastName: "Sider", Password: "x", IsActive: true}
+	require.NoError(t, db.Create(outsider).Error)
+
+	body := map[string]interface{}{
+		"name":    "CI",
+		"url":     "https://ci.example.com/dbackup",
+		"events":  []string{"backup.started"},
+		"team_id": teamID,
+		"secret":  "a-long-enough-signing-secret",
+	}
+	uid := createWebhookEndpoint(t, handler, user, body)["uid"].(string)
+
+	e := setupEchoWithValidator()
+
+	// Team members can read but not manage
+	c, rec := notificationRequest(e, http.MethodGet, "/api/webhooks", nil, member)
+	require.NoError(t, handler.ListEndpoints(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), uid)
+
+	c, rec = notificationRequest(e, http.MethodDelete, "/api/webhooks/"+uid, nil, member, "uid", uid)
+	require.NoError(t, handler.DeleteEndpoint(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	c, rec = notificationRequest(e, http.MethodPost, "/api/webhooks", body, member)
+	require.NoError(t, handler.CreateEndpoint(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Other users do not see it
+	c, rec = notificationRequest(e, http.MethodGet, "/api/webhooks/"+uid, nil, outsider, "uid", uid)
+	require.NoError(t, handler.GetEndpoint(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// An update without a secret keeps the stored one
+	var before models.WebhookEndpoint
+	require.NoError(t, db.Where("uid = ?", uid).First(&before).Error)
+	update := map[string]interface{}{
+		"name":      "CI (paused)",
+		"url":       "https://ci.example.com/dbackup",
+		"events":    []string{"backup.started", "backup.failed"},
+		"team_id":   teamID,
+		"is_active": false,
+	}
+	c, rec = notificationRequest(e, http.MethodPut, "/api/webhooks/"+uid, update, user, "uid", uid)
+	require.NoError(t, handler.UpdateEndpoint(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var after models.WebhookEndpoint
+	require.NoError(t, db.Where("uid = ?", uid).First(&after).Error)
+	assert.Equal(t, before.Secret, after.Secret)
+	assert.False(t, after.IsActive)
+	assert.Len(t, after.Events, 2)
+
+	c, rec = notificationRequest(e, http.MethodDelete, "/api/webhooks/"+uid, nil, user, "uid", uid)
+	require.NoError(t, handler.DeleteEndpoint(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestWebhookHandler_DeliveriesAndRedeliver(t *testing.T) {
+	handler, db, user, teamID, queue := setupWebhookHandler(t)
+
+	uid := createWebhookEndpoint(t, handler, user, map[string]interface{}{
+		"name":    "CI",
+		"url":     "https://ci.example.com/dbackup",
+		"events":  []string{"backup.failed"},
+		"team_id": teamID,
+	})["uid"].(string)
+
+	var endpoint models.WebhookEndpoint
+	require.NoError(t, db.Where("uid = ?", uid).First(&endpoint).Error)
+	lastError := "endpoint returned 500"
+	failed := &models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    "3c5a4b4e-7d0b-4a8f-9d0a-0d6b1c2e3f40",
+		Event:      models.WebhookBackupFailed,
+		Payload:    `{"event":"backup.failed"}`,
+		Status:     models.WebhookDeliveryFailed,
+		Attempts:   10,
+		LastError:  &lastError,
+	}
+	require.NoError(t, db.Create(failed).Error)
+	require.NoError(t, db.Create(&models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b",
+		Event:      models.WebhookBackupFailed,
+		Payload:    `{"event":"backup.failed"}`,
+		Status:     models.WebhookDeliveryDelivered,
+		Attempts:   1,
+	}).Error)
+
+	e := setupEchoWithValidator()
+	c, rec := notificationRequest(e, http.MethodGet, "/api/webhooks/"+uid+"/deliveries?status=failed", nil, user, "uid", uid)
+	require.NoError(t, handler.ListDeliveries(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data []models.WebhookDelivery `json:"data"`
+		Meta map[string]interface{}   `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, failed.UID, response.Data[0].UID)
+	assert.Equal(t, float64(1), response.Meta["total"])
+
+	c, rec = notificationRequest(e, http.MethodPost, "/api/webhooks/"+uid+"/deliveries/"+failed.UID+"/redeliver", nil, user,
+		"uid", uid, "deliveryUid", failed.UID)
+	require.NoError(t, handler.Redeliver(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 1, queue.queued)
+
+	var redelivery models.WebhookDelivery
+	require.NoError(t, db.Where("redelivery = ?", true).First(&redelivery).Error)
+	assert.Equal(t, failed.EventID, redelivery.EventID)
+	assert.Equal(t, models.WebhookDeliveryPending, redelivery.Status)
+
+	c, rec = notificationRequest(e, http.MethodPost, "/api/webhooks/"+uid+"/deliveries/missing/redeliver", nil, user,
+		"uid", uid, "deliveryUid", "missing")
+	require.NoError(t, handler.Redeliver(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
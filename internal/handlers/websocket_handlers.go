@@ -77,10 +77,16 @@ func (h *WebSocketHandler) SendTestMessage(c echo.Context) error {
 	})
 }
 
+// GetProtocolSchema returns the JSON schema of the WebSocket messages
+func (h *WebSocketHandler) GetProtocolSchema(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, websocket.ProtocolSchema)
+}
+
 // RegisterWebSocketRoutes registers WebSocket-related routes
 func (h *WebSocketHandler) RegisterRoutes(g *echo.Group) {
 	// WebSocket upgrade endpoint
 	g.GET("/ws", h.HandleWebSocketConnection)
+	g.GET("/ws/schema", h.GetProtocolSchema)
 	
 	// WebSocket management endpoints (require authentication)
 	g.GET("/ws/stats", h.GetConnectionStats)
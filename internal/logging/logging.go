@@ -0,0 +1,129 @@
+// Package logging provides the structured logger of the API, worker and
+// migration tools. Records carry the request, user, team, job and task they
+// were logged for, taken from the context, and secrets are redacted.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+)
+
+// Correlation field keys
+const (
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+	KeyTeamID    = "team_id"
+	KeyJobUID    = "job_uid"
+	KeyTaskID    = "task_id"
+	KeyTaskType  = "task_type"
+)
+
+// New creates a logger writing to w at the level and in the format of the
+// configuration
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", cfg.Level)
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json", "":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", cfg.Format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+type fieldsKey struct{}
+
+// With returns ctx carrying the key-value pairs, which every record logged
+// with it includes. A key set again replaces its earlier value.
+func With(ctx context.Context, args ...any) context.Context {
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+
+	fields := append([]slog.Attr{}, Fields(ctx)...)
+	record.Attrs(func(attr slog.Attr) bool {
+		for i := range fields {
+			if fields[i].Key == attr.Key {
+				fields[i] = attr
+				return true
+			}
+		}
+		fields = append(fields, attr)
+		return true
+	})
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns the fields ctx carries
+func Fields(ctx context.Context) []slog.Attr {
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return fields
+}
+
+// contextHandler adds the fields of the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(Fields(ctx)...)
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// PrintfLogger adapts slog to the printf-style Logger interfaces of the
+// migration system and the shutdown manager. The zero value logs to the
+// default logger.
+type PrintfLogger struct {
+	Logger *slog.Logger
+}
+
+// Info logs a formatted message at info level
+func (l PrintfLogger) Info(msg string, args ...interface{}) {
+	l.logger().Info(format(msg, args))
+}
+
+// Warn logs a formatted message at warn level
+func (l PrintfLogger) Warn(msg string, args ...interface{}) {
+	l.logger().Warn(format(msg, args))
+}
+
+// Error logs a formatted message at error level
+func (l PrintfLogger) Error(msg string, args ...interface{}) {
+	l.logger().Error(format(msg, args))
+}
+
+func (l PrintfLogger) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+	return l.Logger
+}
+
+func format(msg string, args []interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
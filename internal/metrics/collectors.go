@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds how long reading queue statistics may delay a scrape
+const scrapeTimeout = 5 * time.Second
+
+// QueueStatsSource provides queue statistics
+type QueueStatsSource interface {
+	GetQueueStats(ctx context.Context) (*services.QueueStats, error)
+}
+
+// DatabaseStatsFunc returns database connection pool statistics, such as database.GetStats
+type DatabaseStatsFunc func() (map[string]interface{}, error)
+
+// RegisterQueueStats exposes the jobs in each queue by state, read at every scrape
+func (m *Metrics) RegisterQueueStats(source QueueStatsSource) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(&queueCollector{
+		source: source,
+		jobs: prometheus.NewDesc(prometheus.BuildFQName(namespace, "queue", "jobs"),
+			"Jobs in each queue by state.", []string{"queue", "state"}, nil),
+		paused: prometheus.NewDesc(prometheus.BuildFQName(namespace, "queue", "paused"),
+			"Whether a queue is paused.", []string{"queue"}, nil),
+	})
+}
+
+// RegisterWebSocketConnections exposes the number of WebSocket connections
+// to this node, such as WebSocketService.GetConnectionCount
+func (m *Metrics) RegisterWebSocketConnections(count func() int) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "WebSocket connections open on this node.",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
+
+// RegisterDatabaseStats exposes the database connection pool statistics
+func (m *Metrics) RegisterDatabaseStats(stats DatabaseStatsFunc) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(newDatabaseCollector(stats))
+}
+
+type queueCollector struct {
+	source QueueStatsSource
+	jobs   *prometheus.Desc
+	paused *prometheus.Desc
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.jobs
+	ch <- c.paused
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	stats, err := c.source.GetQueueStats(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read queue stats for metrics", "error", err)
+		return
+	}
+
+	for name, queue := range stats.Queues {
+		for state, count := range map[string]int64{
+			"pending":   queue.Pending,
+			"active":    queue.Active,
+			"scheduled": queue.Scheduled,
+			"retry":     queue.Retry,
+			"archived":  queue.Archived,
+		} {
+			ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(count), name, state)
+		}
+
+		paused := 0.0
+		if queue.Paused {
+			paused = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.paused, prometheus.GaugeValue, paused, name)
+	}
+}
+
+// databaseStat maps a database.GetStats entry to a metric
+type databaseStat struct {
+	key       string
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+type databaseCollector struct {
+	stats DatabaseStatsFunc
+	descs []databaseStat
+}
+
+func newDatabaseCollector(stats DatabaseStatsFunc) *databaseCollector {
+	stat := func(key, name, help string, valueType prometheus.ValueType) databaseStat {
+		return databaseStat{
+			key:       key,
+			desc:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil),
+			valueType: valueType,
+		}
+	}
+
+	return &databaseCollector{
+		stats: stats,
+		descs: []databaseStat{
+			stat("max_open_connections", "max_open_connections", "Maximum number of open connections to the database.", prometheus.GaugeValue),
+			stat("open_connections", "open_connections", "Established connections, in use and idle.", prometheus.GaugeValue),
+			stat("in_use", "in_use_connections", "Connections currently in use.", prometheus.GaugeValue),
+			stat("idle", "idle_connections", "Idle connections.", prometheus.GaugeValue),
+			stat("wait_count", "wait_count_total", "Connections waited for.", prometheus.CounterValue),
+			stat("wait_duration", "wait_duration_seconds_total", "Time blocked waiting for a connection.", prometheus.CounterValue),
+			stat("max_idle_closed", "max_idle_closed_total", "Connections closed because of the idle connection limit.", prometheus.CounterValue),
+			stat("max_idle_time_closed", "max_idle_time_closed_total", "Connections closed because they were idle too long.", prometheus.CounterValue),
+			stat("max_lifetime_closed", "max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.", prometheus.CounterValue),
+		},
+	}
+}
+
+func (c *databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, stat := range c.descs {
+		ch <- stat.desc
+	}
+}
+
+func (c *databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.stats()
+	if err != nil {
+		slog.Warn("Failed to read database stats for metrics", "error", err)
+		return
+	}
+
+	for _, stat := range c.descs {
+		value, ok := statValue(stats[stat.key])
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(stat.desc, stat.valueType, value)
+	}
+}
+
+// statValue converts a pool statistic, durations to seconds
+func statValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case time.Duration:
+		return v.Seconds(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing
+// random paths cannot create a series per path
+const unmatchedRoute = "unmatched"
+
+// Middleware records the latency and status of HTTP requests by route
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m == nil {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" || route == "/*" {
+				route = unmatchedRoute
+			}
+			method := c.Request().Method
+			status := responseStatus(c, err)
+
+			m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status a request is answered with. Errors are
+// turned into responses after the middleware returns.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
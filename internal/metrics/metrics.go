@@ -0,0 +1,160 @@
+// Package metrics exposes Prometheus metrics of the API and worker processes
+// on a port of their own, apart from the public API.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dbackup"
+
+// Job kinds
+const (
+	JobBackup  = "backup"
+	JobRestore = "restore"
+)
+
+// Job outcomes
+const (
+	OutcomeCompleted   = "completed"
+	OutcomeFailed      = "failed"
+	OutcomeInterrupted = "interrupted"
+)
+
+// Metrics holds the metrics of one process. A nil *Metrics records nothing,
+// so callers need not check whether metrics are enabled.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	jobs             *prometheus.CounterVec
+	jobDuration      *prometheus.HistogramVec
+	dumpedBytes      *prometheus.CounterVec
+	uploadedBytes    *prometheus.CounterVec
+	compressionRatio *prometheus.HistogramVec
+}
+
+// New creates the metrics of a process, including Go runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		jobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_total",
+			Help:      "Backup and restore jobs run by kind, engine and outcome.",
+		}, []string{"kind", "engine", "outcome"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_duration_seconds",
+			Help:      "Backup and restore job duration by kind, engine and outcome.",
+			// 1 second to about 4.5 hours
+			Buckets: prometheus.ExponentialBuckets(1, 2, 15),
+		}, []string{"kind", "engine", "outcome"}),
+		dumpedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backup_dumped_bytes_total",
+			Help:      "Bytes written by database dumps by engine.",
+		}, []string{"engine"}),
+		uploadedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backup_uploaded_bytes_total",
+			Help:      "Bytes of backup files uploaded to storage by engine.",
+		}, []string{"engine"}),
+		compressionRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "backup_compression_ratio",
+			Help:      "Dump size divided by compressed size by engine.",
+			Buckets:   []float64{1, 1.5, 2, 3, 4, 6, 8, 12, 16, 24, 32},
+		}, []string{"engine"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.jobs,
+		m.jobDuration,
+		m.dumpedBytes,
+		m.uploadedBytes,
+		m.compressionRatio,
+	)
+	return m
+}
+
+// Registry returns the registry holding the metrics
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// NewServer creates the HTTP server exposing the metrics on /metrics
+func (m *Metrics) NewServer(port int) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// Serve exposes the metrics on the port in the background until the returned
+// server is shut down
+func (m *Metrics) Serve(port int) *http.Server {
+	server := m.NewServer(port)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server failed", "error", err)
+		}
+	}()
+	return server
+}
+
+// ObserveJob records a finished backup or restore job
+func (m *Metrics) ObserveJob(kind, engine, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.jobs.WithLabelValues(kind, engine, outcome).Inc()
+	m.jobDuration.WithLabelValues(kind, engine, outcome).Observe(duration.Seconds())
+}
+
+// ObserveBackupSize records the size of a dump and of the file uploaded from it
+func (m *Metrics) ObserveBackupSize(engine string, dumped, uploaded int64) {
+	if m == nil {
+		return
+	}
+	m.dumpedBytes.WithLabelValues(engine).Add(float64(dumped))
+	m.uploadedBytes.WithLabelValues(engine).Add(float64(uploaded))
+	if dumped > 0 && uploaded > 0 {
+		m.compressionRatio.WithLabelValues(engine).Observe(float64(dumped) / float64(uploaded))
+	}
+}
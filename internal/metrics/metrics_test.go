@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQueueStats struct {
+	stats *services.QueueStats
+	err   error
+}
+
+func (f *fakeQueueStats) GetQueueStats(ctx context.Context) (*services.QueueStats, error) {
+	return f.stats, f.err
+}
+
+// scrape returns what Prometheus would read from the metrics server
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.NewServer(0).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_Jobs(t *testing.T) {
+	m := New()
+
+	m.ObserveJob(JobBackup, "postgresql", OutcomeCompleted, 90*time.Second)
+	m.ObserveJob(JobBackup, "postgresql", OutcomeCompleted, 30*time.Second)
+	m.ObserveJob(JobRestore, "mysql", OutcomeFailed, time.Second)
+	m.ObserveBackupSize("postgresql", 4000, 1000)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.jobs.WithLabelValues(JobBackup, "postgresql", OutcomeCompleted)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.jobs.WithLabelValues(JobRestore, "mysql", OutcomeFailed)))
+	assert.Equal(t, 4000.0, testutil.ToFloat64(m.dumpedBytes.WithLabelValues("postgresql")))
+	assert.Equal(t, 1000.0, testutil.ToFloat64(m.uploadedBytes.WithLabelValues("postgresql")))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `dbackup_job_duration_seconds_sum{engine="postgresql",kind="backup",outcome="completed"} 120`)
+	assert.Contains(t, body, `dbackup_backup_compression_ratio_sum{engine="postgresql"} 4`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestMetrics_NilRecordsNothing(t *testing.T) {
+	var m *Metrics
+
+	assert.NotPanics(t, func() {
+		m.ObserveJob(JobBackup, "mysql", OutcomeFailed, time.Second)
+		m.ObserveBackupSize("mysql", 10, 5)
+		m.RegisterWebSocketConnections(func() int { return 1 })
+		m.RegisterQueueStats(&fakeQueueStats{})
+		m.RegisterDatabaseStats(func() (map[string]interface{}, error) { return nil, nil })
+	})
+
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New()
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/api/backups/:uid", func(c echo.Context) error {
+		switch c.Param("uid") {
+		case "missing":
+			return echo.NewHTTPError(http.StatusNotFound, "not found")
+		case "broken":
+			return errors.New("boom")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"uid": c.Param("uid")})
+	})
+
+	for _, path := range []string{"/api/backups/a", "/api/backups/b", "/api/backups/missing", "/api/backups/broken", "/nope/1", "/nope/2"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Requests are counted by route, not by path
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/api/backups/:uid", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/api/backups/:uid", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/api/backups/:uid", "500")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestMetrics_Collectors(t *testing.T) {
+	m := New()
+	queues := &fakeQueueStats{stats: &services.QueueStats{Queues: map[string]*services.QueueInfo{
+		"backups-critical": {Name: "backups-critical", Pending: 3, Active: 1},
+		"backups-low":      {Name: "backups-low", Retry: 2, Paused: true},
+	}}}
+	m.RegisterQueueStats(queues)
+	m.RegisterWebSocketConnections(func() int { return 7 })
+	m.RegisterDatabaseStats(func() (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"max_open_connections": 25,
+			"open_connections":     4,
+			"in_use":               1,
+			"idle":                 3,
+			"wait_count":           int64(12),
+			"wait_duration":        1500 * time.Millisecond,
+		}, nil
+	})
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `dbackup_queue_jobs{queue="backups-critical",state="pending"} 3`)
+	assert.Contains(t, body, `dbackup_queue_jobs{queue="backups-critical",state="active"} 1`)
+	assert.Contains(t, body, `dbackup_queue_jobs{queue="backups-low",state="retry"} 2`)
+	assert.Contains(t, body, `dbackup_queue_paused{queue="backups-low"} 1`)
+	assert.Contains(t, body, "dbackup_websocket_connections 7")
+	assert.Contains(t, body, "dbackup_db_open_connections 4")
+	assert.Contains(t, body, "dbackup_db_wait_count_total 12")
+	assert.Contains(t, body, "dbackup_db_wait_duration_seconds_total 1.5")
+
+	// A queue outage leaves the other metrics scrapeable
+	queues.err = errors.New("redis down")
+	body = scrape(t, m)
+	assert.NotContains(t, body, "dbackup_queue_jobs{")
+	assert.Contains(t, body, "dbackup_websocket_connections 7")
+}
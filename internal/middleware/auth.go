@@ -6,6 +6,7 @@ import (
 
 	"github.com/dbackup/backend-go/internal/auth"
 	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/labstack/echo/v4"
 )
@@ -162,6 +163,13 @@ func JWTWithConfig(config AuthConfig) echo.MiddlewareFunc {
 				c.Set("team_id", *claims.TeamID)
 			}
 
+			// Logs of the request carry the user from here on
+			logCtx := logging.With(c.Request().Context(), logging.KeyUserID, claims.UserID)
+			if claims.TeamID != nil {
+				logCtx = logging.With(logCtx, logging.KeyTeamID, *claims.TeamID)
+			}
+			c.SetRequest(c.Request().WithContext(logCtx))
+
 			config.SuccessHandler(c)
 
 			return next(c)
@@ -286,6 +294,23 @@ func RequireRole(permission string) echo.MiddlewareFunc {
 	}
 }
 
+// RequireAdmin returns middleware that only lets administrators through. It
+// needs the user model set by CookieJWT.
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := GetUserModel(c)
+			if user == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+			}
+			if !user.IsAdmin || !user.IsActive {
+				return echo.NewHTTPError(http.StatusForbidden, "admin privileges required")
+			}
+			return next(c)
+		}
+	}
+}
+
 // RefreshTokenOnly returns middleware that only accepts refresh tokens
 func RefreshTokenOnly() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/models"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -451,6 +452,45 @@ func TestRequireRole(t *testing.T) {
 	})
 }
 
+func TestRequireAdmin(t *testing.T) {
+	e := echo.New()
+	h := RequireAdmin()(func(c echo.Context) error {
+		return c.String(http.StatusOK, "success")
+	})
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		status int
+	}{
+		{"administrator", &models.User{ID: 1, IsAdmin: true, IsActive: true}, http.StatusOK},
+		{"member", &models.User{ID: 2, IsActive: true}, http.StatusForbidden},
+		{"deactivated administrator", &models.User{ID: 3, IsAdmin: true}, http.StatusForbidden},
+		{"no user in context", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if tt.user != nil {
+				c.Set("user_model", tt.user)
+			}
+
+			err := h(c)
+			if tt.status == http.StatusOK {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var he *echo.HTTPError
+			require.ErrorAs(t, err, &he)
+			assert.Equal(t, tt.status, he.Code)
+		})
+	}
+}
+
 func TestRefreshTokenOnly(t *testing.T) {
 	middleware := RefreshTokenOnly()
 
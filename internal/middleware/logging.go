@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestLogger logs each request once answered. It puts the request ID into
+// the request context, so what handlers log carries it too, and must come
+// after the RequestID middleware. The authenticated user is added further
+// down the chain by the JWT middleware.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+			c.SetRequest(req.WithContext(logging.With(req.Context(), logging.KeyRequestID, requestID)))
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Answer the error now so its status is logged
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			// The query string is left out, it may carry tokens
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int64("bytes_in", req.ContentLength),
+				slog.Int64("bytes_out", c.Response().Size),
+				slog.String("remote_ip", c.RealIP()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.Any("error", err))
+			}
+			logger.LogAttrs(c.Request().Context(), level, "request", attrs...)
+			return err
+		}
+	}
+}
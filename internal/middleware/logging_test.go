@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRequestLogger(t *testing.T) (*echo.Echo, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "debug", Format: "json"}, &buf)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(echoMiddleware.RequestID())
+	e.Use(RequestLogger(logger))
+	return e, &buf
+}
+
+func lastRecord(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &record))
+	return record
+}
+
+func TestRequestLogger(t *testing.T) {
+	e, buf := newTestRequestLogger(t)
+	e.GET("/api/backups/:uid", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backups/abc?token=secret", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-123")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	record := lastRecord(t, buf)
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "req-123", record[logging.KeyRequestID])
+	assert.Equal(t, "/api/backups/abc", record["path"])
+	assert.Equal(t, "/api/backups/:uid", record["route"])
+	assert.Equal(t, float64(http.StatusOK), record["status"])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestRequestLogger_AuthenticatedUser(t *testing.T) {
+	e, buf := newTestRequestLogger(t)
+	jm := createTestJWTManager()
+	e.GET("/api/me", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, JWTWithConfig(AuthConfig{
+		JWTManager:     jm,
+		Skipper:        DefaultSkipper,
+		ErrorHandler:   DefaultAuthErrorHandler,
+		SuccessHandler: DefaultAuthSuccessHandler,
+	}))
+
+	teamID := uint(9)
+	token, err := jm.GenerateAccessToken(123, "test@example.com", &teamID)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	record := lastRecord(t, buf)
+	assert.NotEmpty(t, record[logging.KeyRequestID])
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), record[logging.KeyRequestID])
+	assert.Equal(t, float64(123), record[logging.KeyUserID])
+	assert.Equal(t, float64(9), record[logging.KeyTeamID])
+}
+
+func TestRequestLogger_Levels(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		status  int
+		level   string
+	}{
+		{
+			name:    "client error",
+			handler: func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound, "not found") },
+			status:  http.StatusNotFound,
+			level:   "WARN",
+		},
+		{
+			name:    "server error",
+			handler: func(c echo.Context) error { return errors.New("dial postgres://app:hunter2@db/app: refused") },
+			status:  http.StatusInternalServerError,
+			level:   "ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, buf := newTestRequestLogger(t)
+			e.GET("/", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			// The error is answered once
+			assert.Equal(t, tt.status, rec.Code)
+			record := lastRecord(t, buf)
+			assert.Equal(t, tt.level, record["level"])
+			assert.Equal(t, float64(tt.status), record["status"])
+			assert.NotEmpty(t, record["error"])
+			assert.NotContains(t, buf.String(), "hunter2")
+		})
+	}
+}
@@ -23,6 +23,7 @@ const (
 	AuditActionImport AuditAction = "import"
 	AuditActionPermissionGrant AuditAction = "permission_grant"
 	AuditActionPermissionRevoke AuditAction = "permission_revoke"
+	AuditActionDownload AuditAction = "download"
 )
 
 // AuditResource represents the type of resource being accessed
@@ -38,6 +39,7 @@ const (
 	AuditResourceTablePermission    AuditResource = "table_permission"
 	AuditResourceStorageConfig      AuditResource = "storage_config"
 	AuditResourceSession            AuditResource = "session"
+	AuditResourceQueue              AuditResource = "queue"
 )
 
 // AuditLog represents an audit log entry
@@ -64,12 +66,12 @@ type AuditLog struct {
 	ErrorMessage *string `json:"error_message,omitempty" gorm:"type:text"`
 	
 	// Data changes
-	OldValues map[string]interface{} `json:"old_values,omitempty" gorm:"type:json"`
-	NewValues map[string]interface{} `json:"new_values,omitempty" gorm:"type:json"`
-	Changes   []string               `json:"changes,omitempty" gorm:"type:json"` // List of changed fields
+	OldValues map[string]interface{} `json:"old_values,omitempty" gorm:"type:json;serializer:json"`
+	NewValues map[string]interface{} `json:"new_values,omitempty" gorm:"type:json;serializer:json"`
+	Changes   []string               `json:"changes,omitempty" gorm:"type:json;serializer:json"` // List of changed fields
 	
 	// Additional metadata
-	Metadata    map[string]interface{} `json:"metadata,omitempty" gorm:"type:json"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" gorm:"type:json;serializer:json"`
 	Description *string                `json:"description,omitempty" gorm:"type:text"`
 	
 	// Risk assessment
@@ -155,6 +157,8 @@ func (al *AuditLog) GetActionDescription() string {
 		return "Granted permission"
 	case AuditActionPermissionRevoke:
 		return "Revoked permission"
+	case AuditActionDownload:
+		return "Downloaded"
 	default:
 		return string(al.Action)
 	}
@@ -181,6 +185,8 @@ func (al *AuditLog) GetResourceDescription() string {
 		return "Storage Configuration"
 	case AuditResourceSession:
 		return "Session"
+	case AuditResourceQueue:
+		return "Job Queue"
 	default:
 		return string(al.Resource)
 	}
@@ -27,19 +27,19 @@ type BackupFile struct {
 	StorageClass string  `json:"storage_class" gorm:"type:varchar(50);default:'STANDARD'"`
 	
 	// Encryption
-	IsEncrypted     bool    `json:"is_encrypted" gorm:"default:true"`
-	EncryptionKey   *string `json:"-" gorm:"type:varchar(255)"` // Encrypted encryption key
+	IsEncrypted     bool    `json:"is_encrypted" gorm:"default:false"`
+	EncryptionKey   *string `json:"-" gorm:"type:varchar(255)"` // Data key wrapped by the master key
 	EncryptionAlgo  string  `json:"encryption_algo" gorm:"type:varchar(50);default:'AES256'"`
 	
 	// Compression
-	IsCompressed   bool    `json:"is_compressed" gorm:"default:true"`
+	IsCompressed   bool    `json:"is_compressed" gorm:"default:false"`
 	CompressionAlgo string `json:"compression_algo" gorm:"type:varchar(50);default:'gzip'"`
 	OriginalSize   *int64  `json:"original_size,omitempty"`
 	
 	// File metadata
 	FileType     string                 `json:"file_type" gorm:"type:varchar(50);not null"` // dump, schema, data, log
 	ContentType  string                 `json:"content_type" gorm:"type:varchar(100);default:'application/sql'"`
-	Metadata     map[string]interface{} `json:"metadata" gorm:"type:json"`
+	Metadata     map[string]interface{} `json:"metadata" gorm:"type:json;serializer:json"`
 	
 	// Retention and lifecycle
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
@@ -56,6 +56,13 @@ type BackupFile struct {
 	BackupJobID uint      `json:"backup_job_id" gorm:"not null;index"`
 	BackupJob   BackupJob `json:"backup_job,omitempty" gorm:"foreignKey:BackupJobID"`
 	
+	// Storage configuration the file was uploaded with
+	StorageConfigurationID *uint                 `json:"storage_configuration_id,omitempty" gorm:"index"`
+	StorageConfiguration   *StorageConfiguration `json:"storage_configuration,omitempty" gorm:"foreignKey:StorageConfigurationID"`
+	
+	// Primary copy and replicas on other storage configurations
+	Locations []BackupFileLocation `json:"locations,omitempty" gorm:"foreignKey:BackupFileID"`
+	
 	// Timestamps
 	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
@@ -90,7 +97,7 @@ func (bf *BackupFile) GetFormattedSize() string {
 		return "Unknown"
 	}
 	
-	return formatBytes(*size)
+	return FormatBytes(*size)
 }
 
 // GetCompressionRatio calculates the compression ratio if both sizes are available
@@ -221,6 +228,41 @@ func (bf *BackupFile) SetRetentionPolicy(retentionDays int) {
 	}
 }
 
+// PrimaryLocation returns the primary copy, falling back to the file's own
+// storage fields for files uploaded before locations were tracked
+func (bf *BackupFile) PrimaryLocation() BackupFileLocation {
+	for _, location := range bf.Locations {
+		if location.IsPrimary {
+			return location
+		}
+	}
+	return BackupFileLocation{
+		Bucket:                 bf.S3Bucket,
+		Key:                    bf.S3Key,
+		Region:                 bf.S3Region,
+		Endpoint:               bf.S3Endpoint,
+		IsPrimary:              true,
+		Status:                 BackupFileLocationStatusAvailable,
+		Size:                   bf.Size,
+		BackupFileID:           bf.ID,
+		StorageConfigurationID: bf.StorageConfigurationID,
+	}
+}
+
+// ReadableLocations returns available copies with the primary first
+func (bf *BackupFile) ReadableLocations() []BackupFileLocation {
+	var locations []BackupFileLocation
+	if primary := bf.PrimaryLocation(); primary.IsAvailable() {
+		locations = append(locations, primary)
+	}
+	for _, location := range bf.Locations {
+		if !location.IsPrimary && location.IsAvailable() {
+			locations = append(locations, location)
+		}
+	}
+	return locations
+}
+
 // GetTypeIcon returns an icon name for the file type
 func (bf *BackupFile) GetTypeIcon() string {
 	switch bf.FileType {
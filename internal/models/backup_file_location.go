@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BackupFileLocationStatus represents the state of a stored copy of a backup file
+type BackupFileLocationStatus string
+
+const (
+	BackupFileLocationStatusPending   BackupFileLocationStatus = "pending"
+	BackupFileLocationStatusAvailable BackupFileLocationStatus = "available"
+	BackupFileLocationStatusFailed    BackupFileLocationStatus = "failed"
+	BackupFileLocationStatusDeleted   BackupFileLocationStatus = "deleted"
+)
+
+// BackupFileLocation represents one stored copy of a backup file.
+// Every backup file has a primary location and may have replicas on other storage.
+type BackupFileLocation struct {
+	ID  uint   `json:"id" gorm:"primaryKey"`
+	UID string `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+
+	// Object location
+	Bucket   string  `json:"bucket" gorm:"type:varchar(255);not null"`
+	Key      string  `json:"key" gorm:"type:varchar(1000);not null"`
+	Region   string  `json:"region" gorm:"type:varchar(50)"`
+	Endpoint *string `json:"endpoint,omitempty" gorm:"type:varchar(255)"`
+
+	// Replication state
+	IsPrimary    bool                     `json:"is_primary" gorm:"default:false"`
+	Status       BackupFileLocationStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Size         *int64                   `json:"size,omitempty"`
+	Attempts     int                      `json:"attempts" gorm:"default:0"`
+	ErrorMessage *string                  `json:"error_message,omitempty" gorm:"type:text"`
+	ReplicatedAt *time.Time               `json:"replicated_at,omitempty"`
+
+	// Relationships
+	BackupFileID           uint                  `json:"backup_file_id" gorm:"not null;index"`
+	StorageConfigurationID *uint                 `json:"storage_configuration_id,omitempty" gorm:"index"`
+	StorageConfiguration   *StorageConfiguration `json:"storage_configuration,omitempty" gorm:"foreignKey:StorageConfigurationID"`
+
+	// Timestamps
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName returns the table name for the BackupFileLocation model
+func (BackupFileLocation) TableName() string {
+	return "backup_file_locations"
+}
+
+// BeforeCreate hook to generate UID before creating backup file location
+func (bfl *BackupFileLocation) BeforeCreate(tx *gorm.DB) error {
+	if bfl.UID == "" {
+		bfl.UID = generateUID()
+	}
+	return nil
+}
+
+// IsAvailable checks if the copy can be read
+func (bfl *BackupFileLocation) IsAvailable() bool {
+	return bfl.Status == BackupFileLocationStatusAvailable
+}
+
+// MarkAvailable marks the copy as successfully stored
+func (bfl *BackupFileLocation) MarkAvailable(size int64) {
+	now := time.Now()
+	bfl.Status = BackupFileLocationStatusAvailable
+	bfl.Size = &size
+	bfl.ReplicatedAt = &now
+	bfl.ErrorMessage = nil
+}
+
+// MarkFailed records a failed attempt to store the copy
+func (bfl *BackupFileLocation) MarkFailed(errorMessage string) {
+	bfl.Status = BackupFileLocationStatusFailed
+	bfl.ErrorMessage = &errorMessage
+}
+
+// MarkDeleted marks the copy as removed from storage
+func (bfl *BackupFileLocation) MarkDeleted() {
+	bfl.Status = BackupFileLocationStatusDeleted
+}
@@ -708,4 +708,64 @@ func TestBackupFile_IntegrationWorkflow(t *testing.T) {
 
 	// Test table name
 	assert.Equal(t, "backup_files", file.TableName())
-}
\ No newline at end of file
+}
+func TestBackupFile_Locations(t *testing.T) {
+	storageID := uint(3)
+	file := &BackupFile{
+		ID:                     1,
+		S3Bucket:               "legacy-bucket",
+		S3Key:                  "backups/legacy.backup",
+		S3Region:               "us-east-1",
+		StorageConfigurationID: &storageID,
+	}
+
+	// Files without location rows fall back to their own storage fields
+	primary := file.PrimaryLocation()
+	assert.True(t, primary.IsPrimary)
+	assert.True(t, primary.IsAvailable())
+	assert.Equal(t, "legacy-bucket", primary.Bucket)
+	assert.Equal(t, &storageID, primary.StorageConfigurationID)
+
+	file.Locations = []BackupFileLocation{
+		{Bucket: "replica-failed", Status: BackupFileLocationStatusFailed},
+		{Bucket: "replica-ok", Status: BackupFileLocationStatusAvailable},
+		{Bucket: "primary", IsPrimary: true, Status: BackupFileLocationStatusAvailable},
+		{Bucket: "replica-pending", Status: BackupFileLocationStatusPending},
+	}
+	assert.Equal(t, "primary", file.PrimaryLocation().Bucket)
+
+	readable := file.ReadableLocations()
+	require.Len(t, readable, 2)
+	assert.Equal(t, "primary", readable[0].Bucket)
+	assert.Equal(t, "replica-ok", readable[1].Bucket)
+
+	// An unavailable primary leaves only replicas
+	file.Locations[2].MarkFailed("bucket deleted")
+	readable = file.ReadableLocations()
+	require.Len(t, readable, 1)
+	assert.Equal(t, "replica-ok", readable[0].Bucket)
+}
+
+func TestBackupFileLocation_StatusTransitions(t *testing.T) {
+	db := setupBackupFileTestDB(t)
+	require.NoError(t, db.AutoMigrate(&BackupFileLocation{}))
+
+	location := &BackupFileLocation{Bucket: "bucket", Key: "key", BackupFileID: 1}
+	require.NoError(t, db.Create(location).Error)
+	assert.NotEmpty(t, location.UID)
+	assert.Equal(t, BackupFileLocationStatusPending, location.Status)
+
+	location.MarkFailed("timeout")
+	assert.False(t, location.IsAvailable())
+	require.NotNil(t, location.ErrorMessage)
+
+	location.MarkAvailable(42)
+	assert.True(t, location.IsAvailable())
+	assert.Nil(t, location.ErrorMessage)
+	assert.Equal(t, int64(42), *location.Size)
+	assert.NotNil(t, location.ReplicatedAt)
+
+	location.MarkDeleted()
+	assert.Equal(t, BackupFileLocationStatusDeleted, location.Status)
+	assert.Equal(t, "backup_file_locations", location.TableName())
+}
@@ -53,11 +53,16 @@ type BackupJob struct {
 	Type        BackupType `json:"type" gorm:"type:varchar(50);not null;default:'full'"`
 	Status      BackupStatus `json:"status" gorm:"type:varchar(50);not null;default:'pending'"`
 	Priority    int        `json:"priority" gorm:"default:5"` // 1-10, higher is more important
+	QueueName   string     `json:"queue_name,omitempty" gorm:"type:varchar(100)"` // Priority lane the job waits in
+	QueueTaskID string     `json:"-" gorm:"type:varchar(100);index"`              // Queue task that runs the job
 	
 	// Backup scope
 	IsTableSpecific bool     `json:"is_table_specific" gorm:"default:false"`
-	Tables         []string `json:"tables" gorm:"type:json"` // Table names to backup
-	ExcludeTables  []string `json:"exclude_tables" gorm:"type:json"` // Tables to exclude
+	Tables         []string `json:"tables" gorm:"type:json;serializer:json"` // Table names to backup
+	ExcludeTables  []string `json:"exclude_tables" gorm:"type:json;serializer:json"` // Tables to exclude
+	
+	// Requested dump and sanitized export options as JSON, replayed when the job is retried
+	Options *string `json:"-" gorm:"type:text"`
 	
 	// Scheduling
 	IsScheduled     bool       `json:"is_scheduled" gorm:"default:false"`
@@ -98,6 +103,13 @@ type BackupJob struct {
 	DatabaseTableID      *uint               `json:"database_table_id,omitempty" gorm:"index"`
 	DatabaseTable        *DatabaseTable      `json:"database_table,omitempty" gorm:"foreignKey:DatabaseTableID"`
 	
+	// Storage chosen for the backup, kept so retries upload to it again; none means the default
+	StorageConfigurationID *uint                 `json:"storage_configuration_id,omitempty" gorm:"index"`
+	StorageConfiguration   *StorageConfiguration `json:"storage_configuration,omitempty" gorm:"foreignKey:StorageConfigurationID"`
+	
+	// Replica storage requested for the backup; none means the connection's replicas
+	ReplicaStorageUIDs []string `json:"replica_storage_uids,omitempty" gorm:"type:json;serializer:json"`
+	
 	BackupFiles []BackupFile `json:"backup_files,omitempty" gorm:"foreignKey:BackupJobID"`
 	
 	// Timestamps
@@ -188,7 +200,7 @@ func (bj *BackupJob) GetFormattedSize() string {
 		return "Unknown"
 	}
 	
-	return formatBytes(*size)
+	return FormatBytes(*size)
 }
 
 // GetCompressionRatio calculates the compression ratio
@@ -245,6 +257,16 @@ func (bj *BackupJob) Fail(errorMsg, errorCode string) {
 	}
 }
 
+// Interrupt puts a running backup job back to pending when its worker stops
+// before finishing, so the worker that picks it up again starts it afresh
+func (bj *BackupJob) Interrupt() {
+	bj.Status = BackupStatusPending
+	bj.StartedAt = nil
+	bj.Progress = 0
+	bj.CompletedSteps = 0
+	bj.CurrentStep = "Interrupted by worker shutdown, waiting to resume"
+}
+
 // Cancel marks the backup job as cancelled
 func (bj *BackupJob) Cancel() {
 	now := time.Now()
@@ -332,8 +354,8 @@ func (bj *BackupJob) GetStatusColor() string {
 	}
 }
 
-// formatBytes formats bytes to human readable string
-func formatBytes(bytes int64) string {
+// FormatBytes formats bytes to human readable string
+func FormatBytes(bytes int64) string {
 	if bytes < 1024 {
 		return fmt.Sprintf("%d B", bytes)
 	} else if bytes < 1024*1024 {
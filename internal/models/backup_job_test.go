@@ -439,6 +439,27 @@ func TestBackupJob_Complete(t *testing.T) {
 	assert.True(t, *job.Duration > 3590 && *job.Duration < 3610) // ~1 hour
 }
 
+func TestBackupJob_Interrupt(t *testing.T) {
+	startTime := time.Now().Add(-10 * time.Minute)
+	job := &BackupJob{
+		Status:         BackupStatusRunning,
+		StartedAt:      &startTime,
+		Progress:       40,
+		CompletedSteps: 2,
+		TotalSteps:     5,
+	}
+
+	job.Interrupt()
+
+	assert.Equal(t, BackupStatusPending, job.Status)
+	assert.Nil(t, job.StartedAt)
+	assert.Nil(t, job.CompletedAt)
+	assert.Nil(t, job.ErrorMessage)
+	assert.Equal(t, 0.0, job.Progress)
+	assert.Equal(t, 0, job.CompletedSteps)
+	assert.True(t, job.CanCancel())
+}
+
 func TestBackupJob_Fail(t *testing.T) {
 	startTime := time.Now().Add(-30 * time.Minute)
 	job := &BackupJob{
@@ -638,7 +659,7 @@ func TestFormatBytes(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.expected, func(t *testing.T) {
-			result := formatBytes(tt.bytes)
+			result := FormatBytes(tt.bytes)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -48,11 +48,23 @@ type DatabaseConnection struct {
 	ConnectionTimeout  time.Duration `json:"connection_timeout" gorm:"default:30000000000"` // 30 seconds in nanoseconds
 	QueryTimeout       time.Duration `json:"query_timeout" gorm:"default:300000000000"`     // 5 minutes in nanoseconds
 	
+	// Data protection
+	RequirePIIEncryption bool `json:"require_pii_encryption" gorm:"default:false"` // Backups with unmasked PII need client-side encryption
+	
+	// Storage configurations that receive copies of backups that name no replicas, including scheduled ones
+	ReplicaStorageUIDs []string `json:"replica_storage_uids,omitempty" gorm:"type:json;serializer:json"`
+	
 	// Status and health
 	IsActive      bool       `json:"is_active" gorm:"default:true"`
 	LastTestedAt  *time.Time `json:"last_tested_at"`
 	LastTestError *string    `json:"last_test_error,omitempty" gorm:"type:text"`
 	
+	// Background health monitoring; schedules are paused while unhealthy
+	HealthStatus        HealthStatus `json:"health_status" gorm:"type:varchar(20);not null;default:'unknown'"`
+	ConsecutiveFailures int          `json:"consecutive_failures" gorm:"default:0"`
+	NextHealthCheckAt   *time.Time   `json:"-" gorm:"index"`
+	SchedulesPausedAt   *time.Time   `json:"schedules_paused_at,omitempty"`
+	
 	// Metadata
 	Description *string                `json:"description,omitempty" gorm:"type:text"`
 	Tags        []DatabaseTag          `json:"tags,omitempty" gorm:"many2many:database_connection_tags;"`
@@ -187,6 +199,29 @@ func (dc *DatabaseConnection) SetTestResult(success bool, errorMsg string) {
 	}
 }
 
+// RecordHealthCheck records the result of a background test like
+// SetTestResult and counts consecutive failures. The connection turns
+// unhealthy after threshold of them, which pauses its schedules, and healthy
+// again on the next success. It reports whether it went down or recovered.
+func (dc *DatabaseConnection) RecordHealthCheck(success bool, errorMsg string, threshold int) bool {
+	dc.SetTestResult(success, errorMsg)
+	if success {
+		dc.ConsecutiveFailures = 0
+	} else {
+		dc.ConsecutiveFailures++
+	}
+
+	previous := dc.HealthStatus
+	dc.HealthStatus = nextHealthStatus(previous, dc.ConsecutiveFailures, success, threshold)
+	switch {
+	case dc.HealthStatus == HealthStatusUnhealthy && dc.SchedulesPausedAt == nil:
+		dc.SchedulesPausedAt = dc.LastTestedAt
+	case dc.HealthStatus == HealthStatusHealthy:
+		dc.SchedulesPausedAt = nil
+	}
+	return (dc.HealthStatus == HealthStatusUnhealthy) != (previous == HealthStatusUnhealthy)
+}
+
 // GetTableCount returns the number of discovered tables
 func (dc *DatabaseConnection) GetTableCount() int {
 	return len(dc.Tables)
@@ -430,6 +465,8 @@ func (dc *DatabaseConnection) ToPublic() *DatabaseConnectionPublic {
 		MaxConnections:    dc.MaxConnections,
 		ConnectionTimeout: dc.ConnectionTimeout,
 		QueryTimeout:      dc.QueryTimeout,
+		RequirePIIEncryption: dc.RequirePIIEncryption,
+		ReplicaStorageUIDs: dc.ReplicaStorageUIDs,
 		IsActive:          dc.IsActive,
 		LastTestedAt:      dc.LastTestedAt,
 		HasSSLCert:        dc.SSLCert != nil && *dc.SSLCert != "",
@@ -440,6 +477,8 @@ func (dc *DatabaseConnection) ToPublic() *DatabaseConnectionPublic {
 		ConnectionString:  dc.GetConnectionString(),
 		IsHealthy:         dc.IsHealthy(),
 		NeedsRetesting:    dc.NeedsRetesting(),
+		HealthStatus:      dc.HealthStatus,
+		SchedulesPausedAt: dc.SchedulesPausedAt,
 		CreatedAt:         dc.CreatedAt,
 		UpdatedAt:         dc.UpdatedAt,
 	}
@@ -488,6 +527,8 @@ type DatabaseConnectionPublic struct {
 	MaxConnections    int                 `json:"max_connections"`
 	ConnectionTimeout time.Duration       `json:"connection_timeout"`
 	QueryTimeout      time.Duration       `json:"query_timeout"`
+	RequirePIIEncryption bool             `json:"require_pii_encryption"`
+	ReplicaStorageUIDs []string           `json:"replica_storage_uids,omitempty"`
 	IsActive          bool                `json:"is_active"`
 	LastTestedAt      *time.Time          `json:"last_tested_at"`
 	LastTestError     string              `json:"last_test_error,omitempty"`
@@ -497,6 +538,8 @@ type DatabaseConnectionPublic struct {
 	ConnectionString  string              `json:"connection_string"`
 	IsHealthy         bool                `json:"is_healthy"`
 	NeedsRetesting    bool                `json:"needs_retesting"`
+	HealthStatus      HealthStatus        `json:"health_status"`
+	SchedulesPausedAt *time.Time          `json:"schedules_paused_at,omitempty"`
 	CreatedAt         time.Time           `json:"created_at"`
 	UpdatedAt         time.Time           `json:"updated_at"`
 }
@@ -526,6 +569,8 @@ type ConnectionRequest struct {
 	MaxConnections    int                 `json:"max_connections" validate:"min=1,max=100"`
 	ConnectionTimeout int                 `json:"connection_timeout" validate:"min=1,max=300"` // seconds
 	QueryTimeout      int                 `json:"query_timeout" validate:"min=1,max=3600"`     // seconds
+	RequirePIIEncryption bool             `json:"require_pii_encryption"`
+	ReplicaStorageUIDs []string           `json:"replica_storage_uids,omitempty" validate:"max=5"`
 	TagIDs            []uint              `json:"tag_ids,omitempty"`
 }
 
@@ -543,6 +588,8 @@ func (cr *ConnectionRequest) ToModel() *DatabaseConnection {
 		MaxConnections:    cr.MaxConnections,
 		ConnectionTimeout: time.Duration(cr.ConnectionTimeout) * time.Second,
 		QueryTimeout:      time.Duration(cr.QueryTimeout) * time.Second,
+		RequirePIIEncryption: cr.RequirePIIEncryption,
+		ReplicaStorageUIDs: cr.ReplicaStorageUIDs,
 		IsActive:          true,
 	}
 	
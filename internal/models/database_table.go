@@ -97,6 +97,13 @@ type DatabaseColumn struct {
 	// Position in table
 	OrdinalPosition int `json:"ordinal_position" gorm:"not null"`
 	
+	// Personal data classification from discovery
+	IsPII         bool       `json:"is_pii" gorm:"default:false;index"`
+	PIIType       *PIIType   `json:"pii_type,omitempty" gorm:"type:varchar(50)"`
+	PIIConfidence float64    `json:"pii_confidence" gorm:"default:0"`
+	PIISource     *string    `json:"pii_source,omitempty" gorm:"type:varchar(20)"` // name, type, sample or name+sample
+	PIIDetectedAt *time.Time `json:"pii_detected_at,omitempty" gorm:"column:pii_detected_at"`
+	
 	// Relationships
 	DatabaseTableID uint          `json:"database_table_id" gorm:"not null;index"`
 	DatabaseTable   DatabaseTable `json:"database_table,omitempty" gorm:"foreignKey:DatabaseTableID"`
@@ -107,6 +114,18 @@ type DatabaseColumn struct {
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// PIIType is the kind of personal data a column holds
+type PIIType string
+
+const (
+	PIITypeEmail      PIIType = "email"
+	PIITypePhone      PIIType = "phone"
+	PIITypeName       PIIType = "name"
+	PIITypeNationalID PIIType = "national_id"
+	PIITypeCardNumber PIIType = "card_number"
+	PIITypeIPAddress  PIIType = "ip_address"
+)
+
 // IndexType represents the type of database index
 type IndexType string
 
@@ -232,7 +251,7 @@ func (dt *DatabaseTable) GetSizeInBytes() int64 {
 
 // GetFormattedSize returns a human-readable size string
 func (dt *DatabaseTable) GetFormattedSize() string {
-	return formatBytes(dt.GetSizeInBytes())
+	return FormatBytes(dt.GetSizeInBytes())
 }
 
 // IsEmpty returns true if the table has no rows
@@ -311,6 +330,17 @@ func (dt *DatabaseTable) GetPrimaryKeyColumns() []DatabaseColumn {
 	return pkColumns
 }
 
+// GetPIIColumns returns the columns classified as personal data
+func (dt *DatabaseTable) GetPIIColumns() []DatabaseColumn {
+	var piiColumns []DatabaseColumn
+	for _, column := range dt.Columns {
+		if column.IsPII {
+			piiColumns = append(piiColumns, column)
+		}
+	}
+	return piiColumns
+}
+
 // HasPrimaryKey returns true if the table has a primary key
 func (dt *DatabaseTable) HasPrimaryKey() bool {
 	return len(dt.GetPrimaryKeyColumns()) > 0
@@ -473,6 +503,27 @@ type TableDiscoveryRequest struct {
 	TablePattern  *string `json:"table_pattern,omitempty" validate:"max=255"`
 	IncludeViews  bool    `json:"include_views"`
 	IncludeSystem bool    `json:"include_system"`
+	
+	// Column discovery and PII classification
+	SkipColumns   bool `json:"skip_columns"`
+	PIISampleSize *int `json:"pii_sample_size,omitempty" validate:"omitempty,min=0,max=1000"` // Values sampled per table, 0 disables sampling
+}
+
+// PIIColumnPublic represents a column classified as personal data
+type PIIColumnPublic struct {
+	Table      string     `json:"table"`
+	Column     string     `json:"column"`
+	DataType   string     `json:"data_type"`
+	PIIType    PIIType    `json:"pii_type"`
+	Confidence float64    `json:"confidence"`
+	Source     string     `json:"source"`
+	DetectedAt *time.Time `json:"detected_at,omitempty"`
+}
+
+// PIIColumnListResponse represents the response for listing PII columns
+type PIIColumnListResponse struct {
+	Columns []*PIIColumnPublic `json:"columns"`
+	Count   int                `json:"count"`
 }
 
 // TableUpdateRequest represents a request to update table settings
@@ -658,3 +709,40 @@ func (dc *DatabaseColumn) IsDateTime() bool {
 	return dc.GetDataTypeCategory() == "datetime"
 }
 
+// SetPIIClassification records the kind of personal data a column holds
+func (dc *DatabaseColumn) SetPIIClassification(piiType PIIType, confidence float64, source string) {
+	now := time.Now()
+	dc.IsPII = true
+	dc.PIIType = &piiType
+	dc.PIIConfidence = confidence
+	dc.PIISource = &source
+	dc.PIIDetectedAt = &now
+}
+
+// ToPIIPublic converts a classified column to its public PII representation
+func (dc *DatabaseColumn) ToPIIPublic() *PIIColumnPublic {
+	public := &PIIColumnPublic{
+		Table:      dc.DatabaseTable.GetFullName(),
+		Column:     dc.Name,
+		DataType:   dc.DataType,
+		Confidence: dc.PIIConfidence,
+		DetectedAt: dc.PIIDetectedAt,
+	}
+	if dc.PIIType != nil {
+		public.PIIType = *dc.PIIType
+	}
+	if dc.PIISource != nil {
+		public.Source = *dc.PIISource
+	}
+	return public
+}
+
+// ClearPIIClassification marks a column as not holding personal data
+func (dc *DatabaseColumn) ClearPIIClassification() {
+	dc.IsPII = false
+	dc.PIIType = nil
+	dc.PIIConfidence = 0
+	dc.PIISource = nil
+	dc.PIIDetectedAt = nil
+}
+
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// HealthStatus is the health of a monitored database connection or storage
+// configuration, as found by the background health monitor
+type HealthStatus string
+
+const (
+	HealthStatusUnknown   HealthStatus = "unknown"
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// HealthCheckTarget is the kind of resource a health check tested
+type HealthCheckTarget string
+
+const (
+	HealthCheckTargetDatabase HealthCheckTarget = "database_connection"
+	HealthCheckTargetStorage  HealthCheckTarget = "storage_configuration"
+)
+
+// HealthCheck is one background test of a database connection or storage
+// configuration. Together they are the latency and server version history
+// of the resource.
+type HealthCheck struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	TargetType    HealthCheckTarget `json:"target_type" gorm:"type:varchar(50);not null;index:idx_health_checks_target"`
+	TargetID      uint              `json:"target_id" gorm:"not null;index:idx_health_checks_target"`
+	Success       bool              `json:"success"`
+	LatencyMs     int64             `json:"latency_ms"`
+	ServerVersion string            `json:"server_version,omitempty" gorm:"type:varchar(255)"`
+	Error         *string           `json:"error,omitempty" gorm:"type:text"`
+	CheckedAt     time.Time         `json:"checked_at" gorm:"not null;index"`
+}
+
+// TableName returns the table name for the HealthCheck model
+func (HealthCheck) TableName() string {
+	return "health_checks"
+}
+
+// nextHealthStatus returns the status after a check, given the status and
+// consecutive failures before it: unhealthy once failures reach threshold,
+// healthy on any success, and unchanged otherwise
+func nextHealthStatus(status HealthStatus, failures int, success bool, threshold int) HealthStatus {
+	if success {
+		return HealthStatusHealthy
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	if failures >= threshold {
+		return HealthStatusUnhealthy
+	}
+	if status == "" {
+		return HealthStatusUnknown
+	}
+	return status
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseConnection_RecordHealthCheck(t *testing.T) {
+	conn := &DatabaseConnection{HealthStatus: HealthStatusUnknown}
+
+	assert.False(t, conn.RecordHealthCheck(true, "", 3))
+	assert.Equal(t, HealthStatusHealthy, conn.HealthStatus)
+	assert.Nil(t, conn.LastTestError)
+
+	// Failures below the threshold keep the connection healthy
+	assert.False(t, conn.RecordHealthCheck(false, "connection refused", 3))
+	assert.False(t, conn.RecordHealthCheck(false, "connection refused", 3))
+	assert.Equal(t, HealthStatusHealthy, conn.HealthStatus)
+	assert.Equal(t, 2, conn.ConsecutiveFailures)
+	assert.Nil(t, conn.SchedulesPausedAt)
+
+	// The third one takes it down and pauses its schedules
+	assert.True(t, conn.RecordHealthCheck(false, "connection refused", 3))
+	assert.Equal(t, HealthStatusUnhealthy, conn.HealthStatus)
+	require.NotNil(t, conn.SchedulesPausedAt)
+	assert.Equal(t, conn.LastTestedAt, conn.SchedulesPausedAt)
+	require.NotNil(t, conn.LastTestError)
+	assert.Equal(t, "connection refused", *conn.LastTestError)
+
+	pausedAt := *conn.SchedulesPausedAt
+	assert.False(t, conn.RecordHealthCheck(false, "connection refused", 3))
+	assert.Equal(t, pausedAt, *conn.SchedulesPausedAt)
+
+	// One success recovers it
+	assert.True(t, conn.RecordHealthCheck(true, "", 3))
+	assert.Equal(t, HealthStatusHealthy, conn.HealthStatus)
+	assert.Zero(t, conn.ConsecutiveFailures)
+	assert.Nil(t, conn.SchedulesPausedAt)
+}
+
+func TestStorageConfiguration_RecordHealthCheck(t *testing.T) {
+	config := &StorageConfiguration{}
+
+	assert.False(t, config.RecordHealthCheck(false, "access denied", 2))
+	assert.Equal(t, HealthStatusUnknown, config.HealthStatus)
+
+	assert.True(t, config.RecordHealthCheck(false, "access denied", 2))
+	assert.Equal(t, HealthStatusUnhealthy, config.HealthStatus)
+
+	assert.True(t, config.RecordHealthCheck(true, "", 2))
+	assert.Equal(t, HealthStatusHealthy, config.HealthStatus)
+	assert.Nil(t, config.LastTestError)
+}
@@ -137,4 +137,17 @@ func (mb *MigrationBatch) MarkCompleted() {
 	} else {
 		mb.Status = "partial"
 	}
-}
\ No newline at end of file
+}
+// MigrationLock is the row a process holds while it migrates on databases
+// without advisory locks
+type MigrationLock struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	Holder    string    `gorm:"type:varchar(255);not null" json:"holder"`
+	LockedAt  time.Time `gorm:"not null" json:"locked_at"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// TableName returns the table name for the MigrationLock model
+func (MigrationLock) TableName() string {
+	return "schema_migration_locks"
+}
@@ -0,0 +1,340 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// NotificationChannelType is the way a notification channel delivers messages
+type NotificationChannelType string
+
+const (
+	NotificationChannelEmail   NotificationChannelType = "email"
+	NotificationChannelSlack   NotificationChannelType = "slack"
+	NotificationChannelWebhook NotificationChannelType = "webhook"
+)
+
+// IsValid checks if the channel type is supported
+func (t NotificationChannelType) IsValid() bool {
+	switch t {
+	case NotificationChannelEmail, NotificationChannelSlack, NotificationChannelWebhook:
+		return true
+	}
+	return false
+}
+
+// NotificationEvent is something users can be notified about
+type NotificationEvent string
+
+const (
+	NotificationBackupFailed        NotificationEvent = "backup.failed"
+	NotificationBackupSucceeded     NotificationEvent = "backup.succeeded"
+	NotificationRestoreCompleted    NotificationEvent = "restore.completed"
+	NotificationStorageQuotaNearing NotificationEvent = "storage.quota_near_limit"
+	NotificationConnectionUnhealthy NotificationEvent = "connection.unhealthy"
+	NotificationConnectionRecovered NotificationEvent = "connection.recovered"
+	NotificationSuspiciousActivity  NotificationEvent = "security.suspicious_activity"
+)
+
+// NotificationEvents lists the events channels can subscribe to
+var NotificationEvents = []NotificationEvent{
+	NotificationBackupFailed,
+	NotificationBackupSucceeded,
+	NotificationRestoreCompleted,
+	NotificationStorageQuotaNearing,
+	NotificationConnectionUnhealthy,
+	NotificationConnectionRecovered,
+	NotificationSuspiciousActivity,
+}
+
+// IsValid checks if the event can be subscribed to
+func (e NotificationEvent) IsValid() bool {
+	for _, event := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationChannel is a destination for notifications of a user or team
+type NotificationChannel struct {
+	ID   uint                    `json:"id" gorm:"primaryKey"`
+	UID  string                  `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+	Name string                  `json:"name" gorm:"type:varchar(255);not null"`
+	Type NotificationChannelType `json:"type" gorm:"type:varchar(20);not null"`
+
+	// Settings holds the encrypted JSON of the NotificationChannelSettings,
+	// as webhook URLs and signing secrets are credentials
+	Settings string `json:"-" gorm:"type:text;not null"`
+
+	IsActive bool `json:"is_active" gorm:"default:true"`
+
+	// Relationships
+	TeamID *uint `json:"team_id,omitempty" gorm:"index"`
+	Team   *Team `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+	UserID uint  `json:"user_id" gorm:"not null;index"`
+	User   User  `json:"user,omitempty" gorm:"foreignKey:UserID"`
+
+	Subscriptions []NotificationSubscription `json:"subscriptions,omitempty" gorm:"foreignKey:ChannelID"`
+
+	// Timestamps
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName returns the table name for the NotificationChannel model
+func (NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// BeforeCreate hook to generate UID before creating a notification channel
+func (nc *NotificationChannel) BeforeCreate(tx *gorm.DB) error {
+	if nc.UID == "" {
+		nc.UID = generateUID()
+	}
+	return nil
+}
+
+// NotificationChannelSettings is the destination of a channel: the
+// recipients of an email channel, or the URL of a Slack or webhook channel
+// with the secret webhook payloads are signed with
+type NotificationChannelSettings struct {
+	Recipients []string `json:"recipients,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+// Validate checks the settings are complete for the channel type
+func (s *NotificationChannelSettings) Validate(channelType NotificationChannelType) error {
+	switch channelType {
+	case NotificationChannelEmail:
+		if len(s.Recipients) == 0 {
+			return errors.New("email channels need at least one recipient")
+		}
+		for _, recipient := range s.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid recipient %q", recipient)
+			}
+		}
+	case NotificationChannelSlack, NotificationChannelWebhook:
+		u, err := url.Parse(s.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.New("a valid http or https URL is required")
+		}
+		if channelType == NotificationChannelWebhook && s.Secret == "" {
+			return errors.New("webhook channels need a signing secret")
+		}
+	default:
+		return fmt.Errorf("unsupported channel type: %s", channelType)
+	}
+	return nil
+}
+
+// SetSettings encrypts and stores the channel settings
+func (nc *NotificationChannel) SetSettings(settings *NotificationChannelSettings, encService *encryption.Service) error {
+	if encService == nil {
+		return errors.New("encryption service is required")
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode channel settings: %w", err)
+	}
+	encrypted, err := encService.Encrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt channel settings: %w", err)
+	}
+	nc.Settings = encrypted
+	return nil
+}
+
+// GetSettings decrypts the channel settings
+func (nc *NotificationChannel) GetSettings(encService *encryption.Service) (*NotificationChannelSettings, error) {
+	if encService == nil {
+		return nil, errors.New("encryption service is required")
+	}
+
+	decrypted, err := encService.Decrypt(nc.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt channel settings: %w", err)
+	}
+	var settings NotificationChannelSettings
+	if err := json.Unmarshal([]byte(decrypted), &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode channel settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// ToPublic converts a channel to its public view. Only the host of its URL
+// is shown, as Slack webhook URLs carry their credentials in the path.
+func (nc *NotificationChannel) ToPublic(settings *NotificationChannelSettings) *NotificationChannelPublic {
+	public := &NotificationChannelPublic{
+		ID:            nc.ID,
+		UID:           nc.UID,
+		Name:          nc.Name,
+		Type:          nc.Type,
+		IsActive:      nc.IsActive,
+		TeamID:        nc.TeamID,
+		Subscriptions: make([]NotificationSubscription, len(nc.Subscriptions)),
+		CreatedAt:     nc.CreatedAt,
+		UpdatedAt:     nc.UpdatedAt,
+	}
+	copy(public.Subscriptions, nc.Subscriptions)
+
+	if settings != nil {
+		public.Recipients = settings.Recipients
+		public.HasSecret = settings.Secret != ""
+		if u, err := url.Parse(settings.URL); err == nil {
+			public.URLHost = u.Host
+		}
+	}
+
+	return public
+}
+
+// NotificationChannelPublic represents the public view of a notification channel
+type NotificationChannelPublic struct {
+	ID            uint                       `json:"id"`
+	UID           string                     `json:"uid"`
+	Name          string                     `json:"name"`
+	Type          NotificationChannelType    `json:"type"`
+	Recipients    []string                   `json:"recipients,omitempty"`
+	URLHost       string                     `json:"url_host,omitempty"`
+	HasSecret     bool                       `json:"has_secret"`
+	IsActive      bool                       `json:"is_active"`
+	TeamID        *uint                      `json:"team_id,omitempty"`
+	Subscriptions []NotificationSubscription `json:"subscriptions"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+// NotificationChannelRequest represents a request to create/update a notification channel
+type NotificationChannelRequest struct {
+	Name       string                  `json:"name" validate:"required,min=1,max=255"`
+	Type       NotificationChannelType `json:"type" validate:"required"`
+	Recipients []string                `json:"recipients,omitempty"`
+	URL        string                  `json:"url,omitempty"`
+	Secret     string                  `json:"secret,omitempty"`
+	IsActive   *bool                   `json:"is_active,omitempty"`
+	TeamID     *uint                   `json:"team_id,omitempty"`
+}
+
+// ApplyTo copies the request onto a channel and its decrypted settings.
+// An empty URL or secret keeps the stored one so clients need not resend it.
+func (r *NotificationChannelRequest) ApplyTo(nc *NotificationChannel, settings *NotificationChannelSettings) {
+	nc.Name = r.Name
+	nc.Type = r.Type
+	nc.TeamID = r.TeamID
+	if r.IsActive != nil {
+		nc.IsActive = *r.IsActive
+	}
+
+	settings.Recipients = r.Recipients
+	if r.URL != "" {
+		settings.URL = r.URL
+	}
+	if r.Secret != "" {
+		settings.Secret = r.Secret
+	}
+}
+
+// NotificationSubscription sends an event to a channel, optionally only for
+// one database connection
+type NotificationSubscription struct {
+	ID                   uint              `json:"-" gorm:"primaryKey"`
+	UID                  string            `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+	ChannelID            uint              `json:"-" gorm:"not null;index"`
+	Event                NotificationEvent `json:"event" gorm:"type:varchar(50);not null;index"`
+	DatabaseConnectionID *uint             `json:"database_connection_id,omitempty" gorm:"index"`
+	CreatedAt            time.Time         `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName returns the table name for the NotificationSubscription model
+func (NotificationSubscription) TableName() string {
+	return "notification_subscriptions"
+}
+
+// BeforeCreate hook to generate UID before creating a subscription
+func (ns *NotificationSubscription) BeforeCreate(tx *gorm.DB) error {
+	if ns.UID == "" {
+		ns.UID = generateUID()
+	}
+	return nil
+}
+
+// NotificationSubscriptionRequest represents a request to subscribe a channel to an event
+type NotificationSubscriptionRequest struct {
+	Event       NotificationEvent `json:"event" validate:"required"`
+	DatabaseUID string            `json:"database_uid,omitempty"`
+}
+
+// NotificationDeliveryStatus is the state of a notification delivery
+type NotificationDeliveryStatus string
+
+const (
+	NotificationDeliveryPending   NotificationDeliveryStatus = "pending"
+	NotificationDeliveryRetrying  NotificationDeliveryStatus = "retrying"
+	NotificationDeliveryDelivered NotificationDeliveryStatus = "delivered"
+	NotificationDeliveryFailed    NotificationDeliveryStatus = "failed"
+)
+
+// NotificationDelivery is one message sent, or being sent, to a channel.
+// Together they are the channel's delivery log.
+type NotificationDelivery struct {
+	ID          uint                       `json:"-" gorm:"primaryKey"`
+	UID         string                     `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+	ChannelID   uint                       `json:"-" gorm:"not null;index"`
+	Event       NotificationEvent          `json:"event" gorm:"type:varchar(50);not null"`
+	Subject     string                     `json:"subject" gorm:"type:varchar(500);not null"`
+	Body        string                     `json:"body" gorm:"type:text;not null"`
+	Data        map[string]string          `json:"data,omitempty" gorm:"type:json;serializer:json"`
+	Status      NotificationDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Attempts    int                        `json:"attempts" gorm:"default:0"`
+	LastError   *string                    `json:"last_error,omitempty" gorm:"type:text"`
+	OccurredAt  time.Time                  `json:"occurred_at"`
+	DeliveredAt *time.Time                 `json:"delivered_at,omitempty"`
+	CreatedAt   time.Time                  `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt   time.Time                  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for the NotificationDelivery model
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
+
+// BeforeCreate hook to generate UID before creating a delivery
+func (nd *NotificationDelivery) BeforeCreate(tx *gorm.DB) error {
+	if nd.UID == "" {
+		nd.UID = generateUID()
+	}
+	return nil
+}
+
+// RecordAttempt records the outcome of a delivery attempt. A failed attempt
+// leaves the delivery retrying unless it was the last one.
+func (nd *NotificationDelivery) RecordAttempt(err error, final bool) {
+	nd.Attempts++
+	if err == nil {
+		now := time.Now()
+		nd.Status = NotificationDeliveryDelivered
+		nd.DeliveredAt = &now
+		nd.LastError = nil
+		return
+	}
+
+	message := err.Error()
+	nd.LastError = &message
+	nd.Status = NotificationDeliveryRetrying
+	if final {
+		nd.Status = NotificationDeliveryFailed
+	}
+}
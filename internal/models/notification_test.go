@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationChannelSettings_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType NotificationChannelType
+		settings    NotificationChannelSettings
+		wantErr     bool
+	}{
+		{"email", NotificationChannelEmail, NotificationChannelSettings{Recipients: []string{"ops@example.com", "DBA <dba@example.com>"}}, false},
+		{"email without recipients", NotificationChannelEmail, NotificationChannelSettings{}, true},
+		{"email with invalid recipient", NotificationChannelEmail, NotificationChannelSettings{Recipients: []string{"ops"}}, true},
+		{"slack", NotificationChannelSlack, NotificationChannelSettings{URL: "https://hooks.slack.com/services/T/B/X"}, false},
+		{"slack without scheme", NotificationChannelSlack, NotificationChannelSettings{URL: "hooks.slack.com/services"}, true},
+		{"webhook", NotificationChannelWebhook, NotificationChannelSettings{URL: "http://localhost:8080/hook", Secret: "s"}, false},
+		{"webhook without secret", NotificationChannelWebhook, NotificationChannelSettings{URL: "https://example.com/hook"}, true},
+		{"unknown type", NotificationChannelType("pager"), NotificationChannelSettings{URL: "https://example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate(tt.channelType)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNotificationChannel_Settings(t *testing.T) {
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	channel := &NotificationChannel{Name: "Ops", Type: NotificationChannelWebhook}
+
+	settings := &NotificationChannelSettings{URL: "https://example.com/hooks/abc123", Secret: "signing-secret"}
+	require.NoError(t, channel.SetSettings(settings, encService))
+	assert.NotContains(t, channel.Settings, "abc123")
+
+	decrypted, err := channel.GetSettings(encService)
+	require.NoError(t, err)
+	assert.Equal(t, settings, decrypted)
+
+	public := channel.ToPublic(decrypted)
+	assert.Equal(t, "example.com", public.URLHost)
+	assert.True(t, public.HasSecret)
+
+	// An update without URL or secret keeps them
+	req := &NotificationChannelRequest{Name: "Renamed", Type: NotificationChannelWebhook}
+	req.ApplyTo(channel, decrypted)
+	assert.Equal(t, "Renamed", channel.Name)
+	assert.Equal(t, "https://example.com/hooks/abc123", decrypted.URL)
+	assert.Equal(t, "signing-secret", decrypted.Secret)
+}
+
+func TestNotificationDelivery_RecordAttempt(t *testing.T) {
+	delivery := &NotificationDelivery{Status: NotificationDeliveryPending}
+
+	delivery.RecordAttempt(errors.New("endpoint returned 502"), false)
+	assert.Equal(t, NotificationDeliveryRetrying, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.LastError)
+	assert.Equal(t, "endpoint returned 502", *delivery.LastError)
+
+	delivery.RecordAttempt(nil, false)
+	assert.Equal(t, NotificationDeliveryDelivered, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	failed := &NotificationDelivery{Status: NotificationDeliveryRetrying, Attempts: 4}
+	failed.RecordAttempt(errors.New("timeout"), true)
+	assert.Equal(t, NotificationDeliveryFailed, failed.Status)
+	assert.Equal(t, 5, failed.Attempts)
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RestoreJob is one restore of a backup file into the database it was taken
+// from. Restores keep their own status, so a failed restore leaves the backup
+// it restores completed and restorable.
+type RestoreJob struct {
+	ID  uint   `json:"id" gorm:"primaryKey"`
+	UID string `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+
+	Status      BackupJobStatus `json:"status" gorm:"type:varchar(50);not null;default:'pending';index"`
+	Progress    float64         `json:"progress" gorm:"default:0"` // 0-100
+	CurrentStep string          `json:"current_step" gorm:"type:varchar(255)"`
+
+	// Execution details
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Duration    *int64     `json:"duration,omitempty"` // Duration in seconds
+
+	// Error handling
+	ErrorMessage *string `json:"error_message,omitempty" gorm:"type:text"`
+	ErrorCode    *string `json:"error_code,omitempty" gorm:"type:varchar(50)"`
+
+	// Relationships
+	BackupJobID  uint        `json:"backup_job_id" gorm:"not null;index"`
+	BackupJob    *BackupJob  `json:"backup_job,omitempty" gorm:"foreignKey:BackupJobID"`
+	BackupFileID uint        `json:"backup_file_id" gorm:"not null;index"`
+	BackupFile   *BackupFile `json:"backup_file,omitempty" gorm:"foreignKey:BackupFileID"`
+	UserID       uint        `json:"user_id" gorm:"not null;index"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for the RestoreJob model
+func (RestoreJob) TableName() string {
+	return "restore_jobs"
+}
+
+// BeforeCreate hook to generate UID before creating a restore job
+func (rj *RestoreJob) BeforeCreate(tx *gorm.DB) error {
+	if rj.UID == "" {
+		rj.UID = generateUID()
+	}
+	return nil
+}
+
+// IsActive checks if the restore is waiting to run or running
+func (rj *RestoreJob) IsActive() bool {
+	return rj.Status == BackupStatusPending || rj.Status == BackupStatusRunning
+}
+
+// Start marks the restore job as running
+func (rj *RestoreJob) Start() {
+	now := time.Now()
+	rj.Status = BackupStatusRunning
+	rj.StartedAt = &now
+	rj.Progress = 0
+	rj.CurrentStep = "Starting restore"
+}
+
+// UpdateProgress updates the progress of the restore job
+func (rj *RestoreJob) UpdateProgress(progress float64, currentStep string) {
+	rj.Progress = progress
+	rj.CurrentStep = currentStep
+}
+
+// Complete marks the restore job as completed
+func (rj *RestoreJob) Complete() {
+	now := time.Now()
+	rj.Status = BackupStatusCompleted
+	rj.CompletedAt = &now
+	rj.Progress = 100
+	rj.CurrentStep = "Restore completed"
+	rj.setDuration(now)
+}
+
+// Fail marks the restore job as failed
+func (rj *RestoreJob) Fail(errorMsg, errorCode string) {
+	now := time.Now()
+	rj.Status = BackupStatusFailed
+	rj.CompletedAt = &now
+	rj.ErrorMessage = &errorMsg
+	rj.ErrorCode = &errorCode
+	rj.CurrentStep = "Restore failed"
+	rj.setDuration(now)
+}
+
+// setDuration records how long the restore ran until now
+func (rj *RestoreJob) setDuration(now time.Time) {
+	if rj.StartedAt != nil {
+		duration := int64(now.Sub(*rj.StartedAt).Seconds())
+		rj.Duration = &duration
+	}
+}
+
+// GetFormattedDuration returns a human-readable duration string
+func (rj *RestoreJob) GetFormattedDuration() string {
+	if rj.Duration == nil || *rj.Duration == 0 {
+		return "N/A"
+	}
+
+	duration := time.Duration(*rj.Duration) * time.Second
+	if duration < time.Minute {
+		return fmt.Sprintf("%.0fs", duration.Seconds())
+	} else if duration < time.Hour {
+		return fmt.Sprintf("%.1fm", duration.Minutes())
+	}
+	return fmt.Sprintf("%.1fh", duration.Hours())
+}
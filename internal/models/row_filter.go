@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Row filter operators
+const (
+	RowOperatorEqual        = "="
+	RowOperatorNotEqual     = "!="
+	RowOperatorLess         = "<"
+	RowOperatorLessEqual    = "<="
+	RowOperatorGreater      = ">"
+	RowOperatorGreaterEqual = ">="
+	RowOperatorIn           = "in"
+	RowOperatorNotIn        = "not_in"
+	RowOperatorLike         = "like"
+	RowOperatorIsNull       = "is_null"
+	RowOperatorNotNull      = "not_null"
+)
+
+// Limits that keep filters small enough to store and render
+const (
+	maxRowConditions   = 20
+	maxRowFilterValues = 1000
+	maxRowColumnLength = 128
+)
+
+// RowCondition compares one column with a value. Values are bound as query
+// parameters, so a condition can never change the query it is part of.
+type RowCondition struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator"`
+	// Value is a string, number or boolean; a list for in and not_in; omitted for is_null and not_null
+	Value interface{} `json:"value,omitempty"`
+}
+
+// RowFilter keeps the rows that match every condition
+type RowFilter []RowCondition
+
+// Validate checks the filter's columns, operators and values
+func (f RowFilter) Validate() error {
+	if len(f) > maxRowConditions {
+		return fmt.Errorf("row filter has more than %d conditions", maxRowConditions)
+	}
+	for _, condition := range f {
+		if err := condition.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a single condition
+func (c RowCondition) Validate() error {
+	if c.Column == "" || len(c.Column) > maxRowColumnLength {
+		return fmt.Errorf("row filter column must be 1 to %d characters", maxRowColumnLength)
+	}
+	if strings.IndexFunc(c.Column, unicode.IsControl) >= 0 {
+		return fmt.Errorf("row filter column %q contains control characters", c.Column)
+	}
+
+	switch c.Operator {
+	case RowOperatorIsNull, RowOperatorNotNull:
+		if c.Value != nil {
+			return fmt.Errorf("row filter operator %s takes no value", c.Operator)
+		}
+	case RowOperatorIn, RowOperatorNotIn:
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) == 0 || len(values) > maxRowFilterValues {
+			return fmt.Errorf("row filter operator %s needs a list of 1 to %d values", c.Operator, maxRowFilterValues)
+		}
+		for _, value := range values {
+			if !isRowFilterScalar(value) {
+				return fmt.Errorf("row filter operator %s needs string, number or boolean values", c.Operator)
+			}
+		}
+	case RowOperatorEqual, RowOperatorNotEqual, RowOperatorLess, RowOperatorLessEqual,
+		RowOperatorGreater, RowOperatorGreaterEqual, RowOperatorLike:
+		if !isRowFilterScalar(c.Value) {
+			return fmt.Errorf("row filter operator %s needs a string, number or boolean value", c.Operator)
+		}
+	default:
+		return fmt.Errorf("unsupported row filter operator %q", c.Operator)
+	}
+	return nil
+}
+
+// isRowFilterScalar checks for a single value as decoded from JSON
+func isRowFilterScalar(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool, int, int64:
+		return true
+	default:
+		return false
+	}
+}
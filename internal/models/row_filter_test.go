@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowFilter_Validate(t *testing.T) {
+	var filter RowFilter
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"column": "region", "operator": "=", "value": "eu"},
+		{"column": "id", "operator": "in", "value": [1, 2, 3]},
+		{"column": "deleted_at", "operator": "is_null"}
+	]`), &filter))
+	assert.NoError(t, filter.Validate())
+	assert.NoError(t, RowFilter(nil).Validate())
+
+	invalid := []RowCondition{
+		{Column: "", Operator: RowOperatorEqual, Value: "eu"},
+		{Column: "region", Operator: "or", Value: "eu"},
+		{Column: "region", Operator: RowOperatorEqual},
+		{Column: "region", Operator: RowOperatorEqual, Value: []interface{}{"eu"}},
+		{Column: "region", Operator: RowOperatorIn, Value: []interface{}{}},
+		{Column: "region", Operator: RowOperatorIn, Value: []interface{}{map[string]interface{}{}}},
+		{Column: "region", Operator: RowOperatorIsNull, Value: "eu"},
+		{Column: "region\n", Operator: RowOperatorEqual, Value: "eu"},
+	}
+	for _, condition := range invalid {
+		assert.Error(t, RowFilter{condition}.Validate(), "%+v", condition)
+	}
+}
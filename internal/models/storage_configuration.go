@@ -1,13 +1,15 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/dbackup/backend-go/internal/encryption"
 	"gorm.io/gorm"
 )
 
-// StorageProvider represents different S3-compatible storage providers
+// StorageProvider represents the different storage backends
 type StorageProvider string
 
 const (
@@ -18,9 +20,27 @@ const (
 	StorageProviderWasabi     StorageProvider = "wasabi"
 	StorageProviderGoogle     StorageProvider = "google"
 	StorageProviderCustom     StorageProvider = "custom"
+	StorageProviderLocal      StorageProvider = "local" // Local directory or NFS mount; Bucket is the root path
+	StorageProviderSFTP       StorageProvider = "sftp"  // SFTP server; Bucket is the remote root path
 )
 
-// StorageConfiguration represents S3-compatible storage configuration
+// IsS3Compatible checks if the provider is accessed through the S3 API
+func (p StorageProvider) IsS3Compatible() bool {
+	switch p {
+	case StorageProviderLocal, StorageProviderSFTP:
+		return false
+	default:
+		return true
+	}
+}
+
+// RequiresCredentials checks if the provider needs an access key and secret key.
+// For SFTP the access key is the username and the secret key a password or private key.
+func (p StorageProvider) RequiresCredentials() bool {
+	return p != StorageProviderLocal
+}
+
+// StorageConfiguration represents a storage backend configuration
 type StorageConfiguration struct {
 	ID   uint   `json:"id" gorm:"primaryKey"`
 	UID  string `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
@@ -46,6 +66,11 @@ type StorageConfiguration struct {
 	SkipSSLVerify   bool    `json:"skip_ssl_verify" gorm:"default:false"`
 	CustomCACert    *string `json:"-" gorm:"type:text"` // Encrypted
 	
+	// SFTP host key verification (SHA256 fingerprint as printed by ssh-keygen -l)
+	HostKeyFingerprint *string `json:"host_key_fingerprint,omitempty" gorm:"type:varchar(100)"`
+	// or known_hosts lines for the server when no fingerprint is given
+	KnownHosts *string `json:"known_hosts,omitempty" gorm:"type:text"`
+	
 	// Storage class and lifecycle
 	DefaultStorageClass string  `json:"default_storage_class" gorm:"type:varchar(50);default:'STANDARD'"`
 	LifecyclePolicy     *string `json:"lifecycle_policy,omitempty" gorm:"type:text"`
@@ -67,14 +92,22 @@ type StorageConfiguration struct {
 	LastTestError    *string    `json:"last_test_error,omitempty" gorm:"type:text"`
 	IsDefault        bool       `json:"is_default" gorm:"default:false"`
 	
+	// Background health monitoring
+	HealthStatus        HealthStatus `json:"health_status" gorm:"type:varchar(20);not null;default:'unknown'"`
+	ConsecutiveFailures int          `json:"consecutive_failures" gorm:"default:0"`
+	NextHealthCheckAt   *time.Time   `json:"-" gorm:"index"`
+	
 	// Usage statistics
 	TotalObjects     *int64 `json:"total_objects,omitempty"`
 	TotalSize        *int64 `json:"total_size,omitempty"`
 	LastStatsUpdate  *time.Time `json:"last_stats_update,omitempty"`
 	
+	// Space backups may use on the storage; users are warned as it fills up
+	QuotaBytes *int64 `json:"quota_bytes,omitempty"`
+	
 	// Metadata
 	Description *string                `json:"description,omitempty" gorm:"type:text"`
-	Tags        map[string]interface{} `json:"tags" gorm:"type:json"`
+	Tags        map[string]interface{} `json:"tags" gorm:"type:json;serializer:json"`
 	
 	// Relationships
 	TeamID *uint `json:"team_id,omitempty" gorm:"index"`
@@ -82,7 +115,7 @@ type StorageConfiguration struct {
 	UserID uint  `json:"user_id" gorm:"not null;index"`
 	User   User  `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	
-	BackupFiles []BackupFile `json:"backup_files,omitempty" gorm:"foreignKey:S3Bucket;references:Bucket"`
+	BackupFiles []BackupFile `json:"backup_files,omitempty" gorm:"foreignKey:StorageConfigurationID"`
 	
 	// Timestamps
 	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
@@ -177,6 +210,23 @@ func (sc *StorageConfiguration) SetTestResult(success bool, errorMsg string) {
 	}
 }
 
+// RecordHealthCheck records the result of a background test like
+// SetTestResult and counts consecutive failures. The configuration turns
+// unhealthy after threshold of them and healthy again on the next success.
+// It reports whether it went down or recovered.
+func (sc *StorageConfiguration) RecordHealthCheck(success bool, errorMsg string, threshold int) bool {
+	sc.SetTestResult(success, errorMsg)
+	if success {
+		sc.ConsecutiveFailures = 0
+	} else {
+		sc.ConsecutiveFailures++
+	}
+
+	previous := sc.HealthStatus
+	sc.HealthStatus = nextHealthStatus(previous, sc.ConsecutiveFailures, success, threshold)
+	return (sc.HealthStatus == HealthStatusUnhealthy) != (previous == HealthStatusUnhealthy)
+}
+
 // GetProviderDisplayName returns a human-readable name for the provider
 func (sc *StorageConfiguration) GetProviderDisplayName() string {
 	switch sc.Provider {
@@ -194,6 +244,10 @@ func (sc *StorageConfiguration) GetProviderDisplayName() string {
 		return "Google Cloud Storage"
 	case StorageProviderCustom:
 		return "Custom S3-Compatible"
+	case StorageProviderLocal:
+		return "Local Directory"
+	case StorageProviderSFTP:
+		return "SFTP"
 	default:
 		return string(sc.Provider)
 	}
@@ -266,7 +320,7 @@ func (sc *StorageConfiguration) GetFormattedSize() string {
 	if sc.TotalSize == nil {
 		return "Unknown"
 	}
-	return formatBytes(*sc.TotalSize)
+	return FormatBytes(*sc.TotalSize)
 }
 
 // GetObjectCount returns the number of objects in storage
@@ -287,6 +341,9 @@ func (sc *StorageConfiguration) IsStatsStale() bool {
 
 // GetConnectionString returns a connection string (without credentials)
 func (sc *StorageConfiguration) GetConnectionString() string {
+	if sc.Provider == StorageProviderLocal {
+		return fmt.Sprintf("file://%s", sc.Bucket)
+	}
 	endpoint := sc.GetEndpointURL()
 	return fmt.Sprintf("%s/%s", endpoint, sc.Bucket)
 }
@@ -309,16 +366,18 @@ func (sc *StorageConfiguration) ValidateConfiguration() []string {
 		errors = append(errors, "Bucket name is required")
 	}
 	
-	if sc.Region == "" {
+	if sc.Region == "" && sc.Provider.IsS3Compatible() {
 		errors = append(errors, "Region is required")
 	}
 	
-	if sc.AccessKey == "" {
-		errors = append(errors, "Access key is required")
-	}
-	
-	if sc.SecretKey == "" {
-		errors = append(errors, "Secret key is required")
+	if sc.Provider.RequiresCredentials() {
+		if sc.AccessKey == "" {
+			errors = append(errors, "Access key is required")
+		}
+		
+		if sc.SecretKey == "" {
+			errors = append(errors, "Secret key is required")
+		}
 	}
 	
 	if sc.PartSize < sc.GetMinPartSize() {
@@ -335,4 +394,334 @@ func (sc *StorageConfiguration) ValidateConfiguration() []string {
 // GetAge returns the age of the storage configuration
 func (sc *StorageConfiguration) GetAge() time.Duration {
 	return time.Since(sc.CreatedAt)
-}
\ No newline at end of file
+}
+// EncryptCredentials encrypts the storage credentials
+func (sc *StorageConfiguration) EncryptCredentials(encService *encryption.Service) error {
+	if encService == nil {
+		return errors.New("encryption service is required")
+	}
+
+	if sc.AccessKey != "" {
+		encrypted, err := encService.Encrypt(sc.AccessKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt access key: %w", err)
+		}
+		sc.AccessKey = encrypted
+	}
+
+	if sc.SecretKey != "" {
+		encrypted, err := encService.Encrypt(sc.SecretKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secret key: %w", err)
+		}
+		sc.SecretKey = encrypted
+	}
+
+	if sc.SessionToken != nil && *sc.SessionToken != "" {
+		encrypted, err := encService.Encrypt(*sc.SessionToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt session token: %w", err)
+		}
+		*sc.SessionToken = encrypted
+	}
+
+	if sc.CustomCACert != nil && *sc.CustomCACert != "" {
+		encrypted, err := encService.Encrypt(*sc.CustomCACert)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt CA certificate: %w", err)
+		}
+		*sc.CustomCACert = encrypted
+	}
+
+	return nil
+}
+
+// DecryptCredentials decrypts the storage credentials
+func (sc *StorageConfiguration) DecryptCredentials(encService *encryption.Service) error {
+	if encService == nil {
+		return errors.New("encryption service is required")
+	}
+
+	if sc.AccessKey != "" {
+		decrypted, err := encService.Decrypt(sc.AccessKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt access key: %w", err)
+		}
+		sc.AccessKey = decrypted
+	}
+
+	if sc.SecretKey != "" {
+		decrypted, err := encService.Decrypt(sc.SecretKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret key: %w", err)
+		}
+		sc.SecretKey = decrypted
+	}
+
+	if sc.SessionToken != nil && *sc.SessionToken != "" {
+		decrypted, err := encService.Decrypt(*sc.SessionToken)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt session token: %w", err)
+		}
+		sc.SessionToken = &decrypted
+	}
+
+	if sc.CustomCACert != nil && *sc.CustomCACert != "" {
+		decrypted, err := encService.Decrypt(*sc.CustomCACert)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt CA certificate: %w", err)
+		}
+		sc.CustomCACert = &decrypted
+	}
+
+	return nil
+}
+
+// SetDefaultValues sets default values for the storage configuration
+func (sc *StorageConfiguration) SetDefaultValues() {
+	if sc.DefaultStorageClass == "" {
+		sc.DefaultStorageClass = "STANDARD"
+	}
+	if sc.EncryptionType == "" {
+		sc.EncryptionType = "AES256"
+	}
+	if sc.MaxRetries == 0 {
+		sc.MaxRetries = 3
+	}
+	if sc.Timeout == 0 {
+		sc.Timeout = 5 * time.Minute
+	}
+	if sc.PartSize == 0 {
+		sc.PartSize = 5 * 1024 * 1024 // 5MB
+	}
+	if sc.Concurrency == 0 {
+		sc.Concurrency = 5
+	}
+}
+
+// ToPublic converts StorageConfiguration to public representation
+func (sc *StorageConfiguration) ToPublic() *StorageConfigurationPublic {
+	public := &StorageConfigurationPublic{
+		ID:                   sc.ID,
+		UID:                  sc.UID,
+		Name:                 sc.Name,
+		Provider:             sc.Provider,
+		ProviderDisplayName:  sc.GetProviderDisplayName(),
+		Region:               sc.Region,
+		Endpoint:             sc.GetEndpointURL(),
+		Bucket:               sc.Bucket,
+		ForcePathStyle:       sc.ForcePathStyle,
+		UseSSL:               sc.UseSSL,
+		SkipSSLVerify:        sc.SkipSSLVerify,
+		HasSessionToken:      sc.SessionToken != nil && *sc.SessionToken != "",
+		HasCustomCACert:      sc.CustomCACert != nil && *sc.CustomCACert != "",
+		DefaultStorageClass:  sc.DefaultStorageClass,
+		EncryptionType:       sc.EncryptionType,
+		ClientSideEncryption: sc.ClientSideEncryption,
+		Timeout:              int(sc.Timeout / time.Second),
+		PartSize:             sc.PartSize,
+		QuotaBytes:           sc.QuotaBytes,
+		IsActive:             sc.IsActive,
+		IsDefault:            sc.IsDefault,
+		IsHealthy:            sc.IsHealthy(),
+		NeedsRetesting:       sc.NeedsRetesting(),
+		HealthStatus:         sc.HealthStatus,
+		LastTestedAt:         sc.LastTestedAt,
+		TeamID:               sc.TeamID,
+		CreatedAt:            sc.CreatedAt,
+		UpdatedAt:            sc.UpdatedAt,
+	}
+
+	if sc.PathPrefix != nil {
+		public.PathPrefix = *sc.PathPrefix
+	}
+
+	if sc.Description != nil {
+		public.Description = *sc.Description
+	}
+
+	if sc.HostKeyFingerprint != nil {
+		public.HostKeyFingerprint = *sc.HostKeyFingerprint
+	}
+
+	if sc.KnownHosts != nil {
+		public.KnownHosts = *sc.KnownHosts
+	}
+
+	if sc.LastTestError != nil {
+		public.LastTestError = *sc.LastTestError
+	}
+
+	return public
+}
+
+// StorageConfigurationPublic represents the public view of a storage configuration
+type StorageConfigurationPublic struct {
+	ID                   uint            `json:"id"`
+	UID                  string          `json:"uid"`
+	Name                 string          `json:"name"`
+	Description          string          `json:"description,omitempty"`
+	Provider             StorageProvider `json:"provider"`
+	ProviderDisplayName  string          `json:"provider_display_name"`
+	Region               string          `json:"region"`
+	Endpoint             string          `json:"endpoint,omitempty"`
+	Bucket               string          `json:"bucket"`
+	PathPrefix           string          `json:"path_prefix,omitempty"`
+	ForcePathStyle       bool            `json:"force_path_style"`
+	UseSSL               bool            `json:"use_ssl"`
+	SkipSSLVerify        bool            `json:"skip_ssl_verify"`
+	HasSessionToken      bool            `json:"has_session_token"`
+	HasCustomCACert      bool            `json:"has_custom_ca_cert"`
+	HostKeyFingerprint   string          `json:"host_key_fingerprint,omitempty"`
+	KnownHosts           string          `json:"known_hosts,omitempty"`
+	DefaultStorageClass  string          `json:"default_storage_class"`
+	EncryptionType       string          `json:"encryption_type"`
+	ClientSideEncryption bool            `json:"client_side_encryption"`
+	Timeout              int             `json:"timeout"` // seconds
+	PartSize             int64           `json:"part_size"`
+	QuotaBytes           *int64          `json:"quota_bytes,omitempty"`
+	IsActive             bool            `json:"is_active"`
+	IsDefault            bool            `json:"is_default"`
+	IsHealthy            bool            `json:"is_healthy"`
+	NeedsRetesting       bool            `json:"needs_retesting"`
+	HealthStatus         HealthStatus    `json:"health_status"`
+	LastTestedAt         *time.Time      `json:"last_tested_at,omitempty"`
+	LastTestError        string          `json:"last_test_error,omitempty"`
+	TeamID               *uint           `json:"team_id,omitempty"`
+	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+// StorageConfigurationRequest represents a request to create/update a storage configuration
+type StorageConfigurationRequest struct {
+	Name                 string          `json:"name" validate:"required,min=1,max=255"`
+	Description          string          `json:"description,omitempty" validate:"max=1000"`
+	Provider             StorageProvider `json:"provider" validate:"required"`
+	Region               string          `json:"region" validate:"max=100"`
+	Endpoint             string          `json:"endpoint,omitempty" validate:"omitempty,url"`
+	AccessKey            string          `json:"access_key,omitempty"`
+	SecretKey            string          `json:"secret_key,omitempty"`
+	SessionToken         string          `json:"session_token,omitempty"`
+	CustomCACert         string          `json:"custom_ca_cert,omitempty"`
+	HostKeyFingerprint   string          `json:"host_key_fingerprint,omitempty" validate:"max=100"`
+	KnownHosts           string          `json:"known_hosts,omitempty" validate:"max=65536"`
+	Bucket               string          `json:"bucket" validate:"required,min=3,max=255"`
+	PathPrefix           string          `json:"path_prefix,omitempty" validate:"max=500"`
+	ForcePathStyle       bool            `json:"force_path_style"`
+	UseSSL               *bool           `json:"use_ssl,omitempty"`
+	SkipSSLVerify        bool            `json:"skip_ssl_verify"`
+	DefaultStorageClass  string          `json:"default_storage_class,omitempty"`
+	EncryptionType       string          `json:"encryption_type,omitempty"`
+	ClientSideEncryption *bool           `json:"client_side_encryption,omitempty"`
+	Timeout              int             `json:"timeout,omitempty" validate:"omitempty,min=1,max=86400"` // seconds
+	PartSize             int64           `json:"part_size,omitempty"`
+	QuotaBytes           *int64          `json:"quota_bytes,omitempty" validate:"omitempty,min=1"`
+	TeamID               *uint           `json:"team_id,omitempty"`
+	IsDefault            bool            `json:"is_default"`
+}
+
+// ToModel converts StorageConfigurationRequest to StorageConfiguration model
+func (r *StorageConfigurationRequest) ToModel() *StorageConfiguration {
+	sc := &StorageConfiguration{
+		UseSSL:               true,
+		ClientSideEncryption: true,
+		IsActive:             true,
+	}
+	r.ApplyTo(sc)
+	return sc
+}
+
+// ApplyTo copies the request fields onto an existing configuration.
+// Empty credentials keep the stored values so clients need not resend secrets.
+func (r *StorageConfigurationRequest) ApplyTo(sc *StorageConfiguration) {
+	sc.Name = r.Name
+	sc.Provider = r.Provider
+	sc.Region = r.Region
+	sc.Bucket = r.Bucket
+	sc.ForcePathStyle = r.ForcePathStyle
+	sc.SkipSSLVerify = r.SkipSSLVerify
+	sc.IsDefault = r.IsDefault
+	sc.TeamID = r.TeamID
+	sc.QuotaBytes = r.QuotaBytes
+
+	sc.Description = nil
+	if r.Description != "" {
+		description := r.Description
+		sc.Description = &description
+	}
+
+	sc.Endpoint = nil
+	if r.Endpoint != "" {
+		endpoint := r.Endpoint
+		sc.Endpoint = &endpoint
+	}
+
+	sc.PathPrefix = nil
+	if r.PathPrefix != "" {
+		prefix := r.PathPrefix
+		sc.PathPrefix = &prefix
+	}
+
+	if r.AccessKey != "" {
+		sc.AccessKey = r.AccessKey
+	}
+	if r.SecretKey != "" {
+		sc.SecretKey = r.SecretKey
+	}
+	if r.SessionToken != "" {
+		token := r.SessionToken
+		sc.SessionToken = &token
+	}
+	if r.CustomCACert != "" {
+		cert := r.CustomCACert
+		sc.CustomCACert = &cert
+	}
+
+	sc.HostKeyFingerprint = nil
+	if r.HostKeyFingerprint != "" {
+		fingerprint := r.HostKeyFingerprint
+		sc.HostKeyFingerprint = &fingerprint
+	}
+
+	sc.KnownHosts = nil
+	if r.KnownHosts != "" {
+		knownHosts := r.KnownHosts
+		sc.KnownHosts = &knownHosts
+	}
+
+	if r.UseSSL != nil {
+		sc.UseSSL = *r.UseSSL
+	}
+	if r.ClientSideEncryption != nil {
+		sc.ClientSideEncryption = *r.ClientSideEncryption
+	}
+	if r.DefaultStorageClass != "" {
+		sc.DefaultStorageClass = r.DefaultStorageClass
+	}
+	if r.EncryptionType != "" {
+		sc.EncryptionType = r.EncryptionType
+	}
+	if r.Timeout > 0 {
+		sc.Timeout = time.Duration(r.Timeout) * time.Second
+	}
+	if r.PartSize > 0 {
+		sc.PartSize = r.PartSize
+	}
+}
+
+// StorageTestStep represents the result of a single storage connectivity check
+type StorageTestStep struct {
+	Name     string `json:"name"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"` // milliseconds
+}
+
+// StorageTestResult represents the result of testing a storage configuration
+type StorageTestResult struct {
+	Success  bool              `json:"success"`
+	Message  string            `json:"message"`
+	Steps    []StorageTestStep `json:"steps"`
+	Duration int64             `json:"duration"` // milliseconds
+	TestedAt time.Time         `json:"tested_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,8 +21,8 @@ type TablePermission struct {
 	CanDelete  bool `json:"can_delete" gorm:"default:false"`
 	
 	// Conditions and restrictions
-	RowLevelFilter *string `json:"row_level_filter,omitempty" gorm:"type:text"` // SQL WHERE clause
-	ColumnMask     []string `json:"column_mask" gorm:"type:json"`               // Columns to mask/hide
+	RowLevelFilter RowFilter `json:"row_level_filter,omitempty" gorm:"type:text;serializer:json"` // Conditions exported rows must match
+	ColumnMask     []string `json:"column_mask" gorm:"type:json;serializer:json"`               // Columns to mask/hide
 	TimeRestriction *string `json:"time_restriction,omitempty" gorm:"type:varchar(255)"` // Time-based access
 	
 	// Metadata
@@ -139,6 +141,122 @@ func (tp *TablePermission) IsRestrictedByTime() bool {
 	return tp.TimeRestriction != nil && *tp.TimeRestriction != ""
 }
 
+// AllowsAccessAt checks if the time restriction permits access at the given time.
+// Restrictions have the form "[days ]HH:MM-HH:MM" in UTC, e.g. "mon-fri 09:00-17:00"
+// or "22:00-06:00"; days may be a range or a comma-separated list. A restriction
+// that cannot be parsed denies access.
+func (tp *TablePermission) AllowsAccessAt(t time.Time) bool {
+	if !tp.IsRestrictedByTime() {
+		return true
+	}
+	window, err := parseTimeRestriction(*tp.TimeRestriction)
+	if err != nil {
+		return false
+	}
+	return window.contains(t.UTC())
+}
+
+// ValidateTimeRestriction checks that a time restriction can be parsed
+func ValidateTimeRestriction(restriction string) error {
+	if restriction == "" {
+		return nil
+	}
+	_, err := parseTimeRestriction(restriction)
+	return err
+}
+
+// timeWindow is a parsed time restriction
+type timeWindow struct {
+	days       map[time.Weekday]bool // nil means every day
+	start, end int                   // minutes since midnight
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseTimeRestriction(restriction string) (*timeWindow, error) {
+	fields := strings.Fields(strings.ToLower(restriction))
+	window := &timeWindow{}
+
+	switch len(fields) {
+	case 1:
+	case 2:
+		days, err := parseWeekdays(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		window.days = days
+		fields = fields[1:]
+	default:
+		return nil, fmt.Errorf("invalid time restriction %q", restriction)
+	}
+
+	hours := strings.SplitN(fields[0], "-", 2)
+	if len(hours) != 2 {
+		return nil, fmt.Errorf("invalid time range %q", fields[0])
+	}
+	var err error
+	if window.start, err = parseClock(hours[0]); err != nil {
+		return nil, err
+	}
+	if window.end, err = parseClock(hours[1]); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+func parseWeekdays(spec string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(spec, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, ok := weekdayNames[bounds[0]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdayNames[bounds[1]]; !ok {
+				return nil, fmt.Errorf("invalid weekday %q", bounds[1])
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// contains checks if t falls in the window. Windows that end before they start
+// wrap past midnight and belong to the day they start on.
+func (w *timeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	if w.start <= w.end {
+		return (w.days == nil || w.days[day]) && minute >= w.start && minute < w.end
+	}
+	if minute >= w.start {
+		return w.days == nil || w.days[day]
+	}
+	if minute < w.end {
+		return w.days == nil || w.days[(day+6)%7]
+	}
+	return false
+}
+
 // HasColumnMask checks if columns are masked
 func (tp *TablePermission) HasColumnMask() bool {
 	return len(tp.ColumnMask) > 0
@@ -146,7 +264,7 @@ func (tp *TablePermission) HasColumnMask() bool {
 
 // HasRowLevelFilter checks if row-level filtering is applied
 func (tp *TablePermission) HasRowLevelFilter() bool {
-	return tp.RowLevelFilter != nil && *tp.RowLevelFilter != ""
+	return len(tp.RowLevelFilter) > 0
 }
 
 // GetMaskedColumns returns the list of masked columns
@@ -188,11 +306,11 @@ func (tp *TablePermission) RemoveMaskedColumn(column string) {
 }
 
 // SetRowLevelFilter sets the row-level security filter
-func (tp *TablePermission) SetRowLevelFilter(filter string) {
-	if filter == "" {
+func (tp *TablePermission) SetRowLevelFilter(filter RowFilter) {
+	if len(filter) == 0 {
 		tp.RowLevelFilter = nil
 	} else {
-		tp.RowLevelFilter = &filter
+		tp.RowLevelFilter = filter
 	}
 }
 
@@ -260,4 +378,38 @@ func (tp *TablePermission) Clone() *TablePermission {
 	clone.UpdatedAt = time.Time{}
 	clone.DeletedAt = gorm.DeletedAt{}
 	return &clone
-}
\ No newline at end of file
+}
+// TablePermissionRequest represents a request to grant a table permission
+type TablePermissionRequest struct {
+	UserID          uint             `json:"user_id" validate:"required"`
+	Table           string           `json:"table" validate:"required,max=511"` // Table name, optionally schema-qualified
+	AccessLevel     TableAccessLevel `json:"access_level" validate:"required,oneof=none read write admin"`
+	CanBackup       *bool            `json:"can_backup,omitempty"`  // Overrides the access level default
+	CanRestore      *bool            `json:"can_restore,omitempty"` // Overrides the access level default
+	RowLevelFilter  RowFilter        `json:"row_level_filter,omitempty"`
+	ColumnMask      []string         `json:"column_mask,omitempty" validate:"max=200"`
+	TimeRestriction string           `json:"time_restriction,omitempty" validate:"max=255"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	Description     string           `json:"description,omitempty" validate:"max=1000"`
+}
+
+// ApplyTo applies the request to a table permission
+func (req *TablePermissionRequest) ApplyTo(tp *TablePermission) {
+	tp.SetPermissionLevel(req.AccessLevel)
+	if req.CanBackup != nil {
+		tp.CanBackup = *req.CanBackup
+	}
+	if req.CanRestore != nil {
+		tp.CanRestore = *req.CanRestore
+	}
+
+	tp.SetRowLevelFilter(req.RowLevelFilter)
+	tp.ColumnMask = req.ColumnMask
+	tp.SetTimeRestriction(req.TimeRestriction)
+	tp.ExpiresAt = req.ExpiresAt
+	if req.Description == "" {
+		tp.Description = nil
+	} else {
+		tp.Description = &req.Description
+	}
+}
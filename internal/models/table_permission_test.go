@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTablePermission_AllowsAccessAt(t *testing.T) {
+	tuesday := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 12, hour, minute, 0, 0, time.UTC)
+	}
+	saturday := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 16, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		restriction string
+		at          time.Time
+		allowed     bool
+	}{
+		{"no restriction", "", saturday(3, 0), true},
+		{"inside hours", "09:00-17:00", tuesday(9, 0), true},
+		{"end is exclusive", "09:00-17:00", tuesday(17, 0), false},
+		{"weekday range", "mon-fri 09:00-17:00", tuesday(12, 30), true},
+		{"outside weekday range", "mon-fri 09:00-17:00", saturday(12, 30), false},
+		{"day list", "sat,sun 00:00-23:59", saturday(8, 0), true},
+		{"overnight before midnight", "22:00-06:00", tuesday(23, 0), true},
+		{"overnight after midnight", "22:00-06:00", tuesday(5, 59), true},
+		{"overnight daytime", "22:00-06:00", tuesday(12, 0), false},
+		{"overnight belongs to start day", "fri 22:00-06:00", saturday(2, 0), true},
+		{"overnight wrong start day", "sat 22:00-06:00", saturday(2, 0), false},
+		{"wrapping day range", "fri-mon 00:00-23:59", saturday(8, 0), true},
+		{"other timezone is converted", "09:00-17:00", time.Date(2024, 3, 12, 10, 0, 0, 0, time.FixedZone("EST", -5*3600)), true},
+		{"invalid restriction denies", "business hours", tuesday(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permission := &TablePermission{}
+			permission.SetTimeRestriction(tt.restriction)
+			assert.Equal(t, tt.allowed, permission.AllowsAccessAt(tt.at))
+		})
+	}
+}
+
+func TestValidateTimeRestriction(t *testing.T) {
+	assert.NoError(t, ValidateTimeRestriction(""))
+	assert.NoError(t, ValidateTimeRestriction("Mon-Fri 08:30-18:00"))
+	assert.NoError(t, ValidateTimeRestriction("sat,sun 10:00-12:00"))
+
+	assert.Error(t, ValidateTimeRestriction("weekdays 09:00-17:00"))
+	assert.Error(t, ValidateTimeRestriction("09:00"))
+	assert.Error(t, ValidateTimeRestriction("25:00-26:00"))
+	assert.Error(t, ValidateTimeRestriction("mon 09:00-17:00 extra"))
+}
+
+func TestTablePermissionRequest_ApplyTo(t *testing.T) {
+	restore := false
+	req := &TablePermissionRequest{
+		AccessLevel:     TableAccessWrite,
+		CanRestore:      &restore,
+		RowLevelFilter:  RowFilter{{Column: "region", Operator: RowOperatorEqual, Value: "eu"}},
+		ColumnMask:      []string{"email"},
+		TimeRestriction: "mon-fri 09:00-17:00",
+		Description:     "EU analysts",
+	}
+
+	permission := &TablePermission{}
+	req.ApplyTo(permission)
+
+	assert.Equal(t, TableAccessWrite, permission.AccessLevel)
+	assert.True(t, permission.CanBackup)
+	assert.False(t, permission.CanRestore)
+	assert.True(t, permission.HasRowLevelFilter())
+	assert.Equal(t, []string{"email"}, permission.GetMaskedColumns())
+	assert.True(t, permission.IsRestrictedByTime())
+	assert.Equal(t, "EU analysts", *permission.Description)
+}
@@ -146,6 +146,7 @@ func (t *Team) GetSubscriptionLimits() TeamLimits {
 			MaxStorageGB:          250,
 			BackupRetentionDays:   30,
 			APIRateLimit:          1000,
+			MaxConcurrentBackups:  5,
 		}
 	case "business":
 		return TeamLimits{
@@ -154,6 +155,7 @@ func (t *Team) GetSubscriptionLimits() TeamLimits {
 			MaxStorageGB:          1000,
 			BackupRetentionDays:   90,
 			APIRateLimit:          5000,
+			MaxConcurrentBackups:  10,
 		}
 	case "enterprise":
 		return TeamLimits{
@@ -162,6 +164,7 @@ func (t *Team) GetSubscriptionLimits() TeamLimits {
 			MaxStorageGB:          -1,  // unlimited
 			BackupRetentionDays:   365,
 			APIRateLimit:          10000,
+			MaxConcurrentBackups:  -1, // unlimited
 		}
 	default: // free tier
 		return TeamLimits{
@@ -170,6 +173,7 @@ func (t *Team) GetSubscriptionLimits() TeamLimits {
 			MaxStorageGB:          100,
 			BackupRetentionDays:   7,
 			APIRateLimit:          100,
+			MaxConcurrentBackups:  2,
 		}
 	}
 }
@@ -241,6 +245,7 @@ type TeamLimits struct {
 	MaxStorageGB          int
 	BackupRetentionDays   int
 	APIRateLimit          int
+	MaxConcurrentBackups  int
 }
 
 // SetPermissionsByRole sets permissions based on the team role
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// WebhookEvent is a backup lifecycle event sent to team webhooks
+type WebhookEvent string
+
+const (
+	WebhookBackupStarted     WebhookEvent = "backup.started"
+	WebhookBackupCompleted   WebhookEvent = "backup.completed"
+	WebhookBackupFailed      WebhookEvent = "backup.failed"
+	WebhookRestoreCompleted  WebhookEvent = "restore.completed"
+	WebhookBackupFileDeleted WebhookEvent = "backup_file.deleted"
+)
+
+// WebhookEvents lists the events endpoints can subscribe to
+var WebhookEvents = []WebhookEvent{
+	WebhookBackupStarted,
+	WebhookBackupCompleted,
+	WebhookBackupFailed,
+	WebhookRestoreCompleted,
+	WebhookBackupFileDeleted,
+}
+
+// IsValid checks if the event is known
+func (e WebhookEvent) IsValid() bool {
+	for _, event := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSecretBytes is the length of generated signing secrets
+const webhookSecretBytes = 32
+
+// WebhookEndpoint is a URL of a team that receives signed backup events
+type WebhookEndpoint struct {
+	ID     uint           `json:"id" gorm:"primaryKey"`
+	UID    string         `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+	Name   string         `json:"name" gorm:"type:varchar(255);not null"`
+	URL    string         `json:"url" gorm:"type:varchar(1000);not null"`
+	Events []WebhookEvent `json:"events" gorm:"type:json;serializer:json"`
+
+	// Secret is the encrypted key deliveries are signed with
+	Secret string `json:"-" gorm:"type:text;not null"`
+
+	IsActive bool `json:"is_active" gorm:"default:true"`
+
+	// Relationships
+	TeamID uint  `json:"team_id" gorm:"not null;index"`
+	Team   *Team `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+	UserID uint  `json:"user_id" gorm:"not null;index"` // Creator
+
+	// Timestamps
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName returns the table name for the WebhookEndpoint model
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// BeforeCreate hook to generate UID before creating a webhook endpoint
+func (we *WebhookEndpoint) BeforeCreate(tx *gorm.DB) error {
+	if we.UID == "" {
+		we.UID = generateUID()
+	}
+	return nil
+}
+
+// Subscribes checks if the endpoint receives an event
+func (we *WebhookEndpoint) Subscribes(event WebhookEvent) bool {
+	for _, subscribed := range we.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSecret encrypts and stores the signing secret
+func (we *WebhookEndpoint) SetSecret(secret string, encService *encryption.Service) error {
+	if encService == nil {
+		return errors.New("encryption service is required")
+	}
+
+	encrypted, err := encService.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	we.Secret = encrypted
+	return nil
+}
+
+// GetSecret decrypts the signing secret
+func (we *WebhookEndpoint) GetSecret(encService *encryption.Service) (string, error) {
+	if encService == nil {
+		return "", errors.New("encryption service is required")
+	}
+
+	secret, err := encService.Decrypt(we.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// GenerateWebhookSecret returns a random signing secret
+func GenerateWebhookSecret() (string, error) {
+	token, err := generateSecureToken(webhookSecretBytes)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// WebhookEndpointRequest represents a request to create/update a webhook endpoint.
+// An empty secret generates one on creation and keeps the stored one on update.
+type WebhookEndpointRequest struct {
+	Name     string         `json:"name" validate:"required,min=1,max=255"`
+	URL      string         `json:"url" validate:"required,url,max=1000"`
+	Events   []WebhookEvent `json:"events" validate:"required,min=1"`
+	Secret   string         `json:"secret,omitempty" validate:"omitempty,min=16"`
+	IsActive *bool          `json:"is_active,omitempty"`
+	TeamID   uint           `json:"team_id" validate:"required"`
+}
+
+// Validate checks the URL is http or https and the subscribed events are known
+func (r *WebhookEndpointRequest) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("a valid http or https URL is required")
+	}
+	for _, event := range r.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+	return nil
+}
+
+// ApplyTo copies the request onto an endpoint
+func (r *WebhookEndpointRequest) ApplyTo(we *WebhookEndpoint) {
+	we.Name = r.Name
+	we.URL = r.URL
+	we.Events = r.Events
+	we.TeamID = r.TeamID
+	if r.IsActive != nil {
+		we.IsActive = *r.IsActive
+	}
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryRetrying  WebhookDeliveryStatus = "retrying"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent, or being sent, to an endpoint.
+// A redelivery is a new delivery of the same event, which keeps its
+// EventID so receivers can drop events they have already handled.
+type WebhookDelivery struct {
+	ID             uint                  `json:"-" gorm:"primaryKey"`
+	UID            string                `json:"uid" gorm:"type:varchar(36);uniqueIndex;not null"`
+	EndpointID     uint                  `json:"-" gorm:"not null;index"`
+	EventID        string                `json:"event_id" gorm:"type:varchar(36);not null;index"`
+	Event          WebhookEvent          `json:"event" gorm:"type:varchar(50);not null"`
+	Payload        string                `json:"payload" gorm:"type:text;not null"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Attempts       int                   `json:"attempts" gorm:"default:0"`
+	ResponseStatus *int                  `json:"response_status,omitempty"`
+	LastError      *string               `json:"last_error,omitempty" gorm:"type:text"`
+	Redelivery     bool                  `json:"redelivery" gorm:"default:false"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt      time.Time             `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for the WebhookDelivery model. The
+// webhook_deliveries table belongs to the webhooks table of the SQL migrations.
+func (WebhookDelivery) TableName() string {
+	return "webhook_endpoint_deliveries"
+}
+
+// BeforeCreate hook to generate UID before creating a delivery
+func (wd *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if wd.UID == "" {
+		wd.UID = generateUID()
+	}
+	return nil
+}
+
+// RecordAttempt records the outcome of a delivery attempt and the HTTP
+// status the endpoint answered with, if any. A failed attempt leaves the
+// delivery retrying unless it was the last one.
+func (wd *WebhookDelivery) RecordAttempt(statusCode int, err error, final bool) {
+	wd.Attempts++
+	wd.ResponseStatus = nil
+	if statusCode != 0 {
+		wd.ResponseStatus = &statusCode
+	}
+
+	if err == nil {
+		now := time.Now()
+		wd.Status = WebhookDeliveryDelivered
+		wd.DeliveredAt = &now
+		wd.LastError = nil
+		return
+	}
+
+	message := err.Error()
+	wd.LastError = &message
+	wd.Status = WebhookDeliveryRetrying
+	if final {
+		wd.Status = WebhookDeliveryFailed
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEndpointRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     WebhookEndpointRequest
+		wantErr bool
+	}{
+		{"https", WebhookEndpointRequest{URL: "https://ci.example.com/hooks", Events: []WebhookEvent{WebhookBackupCompleted}}, false},
+		{"http", WebhookEndpointRequest{URL: "http://localhost:8080/hook", Events: WebhookEvents}, false},
+		{"other scheme", WebhookEndpointRequest{URL: "ftp://example.com/hook", Events: []WebhookEvent{WebhookBackupFailed}}, true},
+		{"no host", WebhookEndpointRequest{URL: "https:///hook", Events: []WebhookEvent{WebhookBackupFailed}}, true},
+		{"unknown event", WebhookEndpointRequest{URL: "https://example.com", Events: []WebhookEvent{"backup.deleted"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebhookEndpoint_Secret(t *testing.T) {
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+
+	secret, err := GenerateWebhookSecret()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "whsec_"))
+
+	endpoint := &WebhookEndpoint{Events: []WebhookEvent{WebhookBackupStarted, WebhookRestoreCompleted}}
+	require.NoError(t, endpoint.SetSecret(secret, encService))
+	assert.NotContains(t, endpoint.Secret, strings.TrimPrefix(secret, "whsec_"))
+
+	decrypted, err := endpoint.GetSecret(encService)
+	require.NoError(t, err)
+	assert.Equal(t, secret, decrypted)
+
+	assert.True(t, endpoint.Subscribes(WebhookRestoreCompleted))
+	assert.False(t, endpoint.Subscribes(WebhookBackupFailed))
+}
+
+func TestWebhookDelivery_RecordAttempt(t *testing.T) {
+	delivery := &WebhookDelivery{Status: WebhookDeliveryPending}
+
+	delivery.RecordAttempt(http.StatusServiceUnavailable, errors.New("endpoint returned 503"), false)
+	assert.Equal(t, WebhookDeliveryRetrying, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, http.StatusServiceUnavailable, *delivery.ResponseStatus)
+
+	// A connection error has no response status
+	delivery.RecordAttempt(0, errors.New("connection refused"), false)
+	assert.Nil(t, delivery.ResponseStatus)
+
+	delivery.RecordAttempt(http.StatusOK, nil, false)
+	assert.Equal(t, WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	failed := &WebhookDelivery{Status: WebhookDeliveryRetrying, Attempts: 9}
+	failed.RecordAttempt(http.StatusInternalServerError, errors.New("endpoint returned 500"), true)
+	assert.Equal(t, WebhookDeliveryFailed, failed.Status)
+	assert.Equal(t, 10, failed.Attempts)
+}
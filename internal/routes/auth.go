@@ -4,13 +4,15 @@ import (
 	"github.com/dbackup/backend-go/internal/auth"
 	"github.com/dbackup/backend-go/internal/handlers"
 	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
 	"github.com/labstack/echo/v4"
 )
 
-// SetupAuthRoutes sets up authentication routes
-func SetupAuthRoutes(e *echo.Echo, jm *auth.JWTManager, ph *auth.PasswordHasher, tm *auth.TOTPManager) {
+// SetupAuthRoutes sets up authentication routes. Logins are audited when
+// auditService is not nil.
+func SetupAuthRoutes(e *echo.Echo, jm *auth.JWTManager, ph *auth.PasswordHasher, tm *auth.TOTPManager, auditService services.AuditServiceInterface) {
 	// Create auth handler
-	authHandler := handlers.NewAuthHandler(jm, ph, tm)
+	authHandler := handlers.NewAuthHandler(jm, ph, tm, auditService)
 	
 	// Create 2FA handler
 	twoFAHandler := handlers.NewTwoFAHandler(tm, ph)
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// SetupBackupRoutes sets up backup job routes. Jobs are queued here and run
+// by the worker processes; restores are audited when auditService is not nil.
+func SetupBackupRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, backupService services.BackupServiceInterface, queueService services.QueueServiceInterface, backupWorker handlers.BackupWorkerInterface, auditService services.AuditServiceInterface) {
+	backupHandler := handlers.NewBackupHandler(db, backupService, nil, queueService, backupWorker)
+	if auditService != nil {
+		backupHandler.SetAuditService(auditService)
+	}
+
+	// Backup routes with authentication required (cookie-based)
+	backupHandler.RegisterRoutes(e.Group("/api", middleware.CookieJWT(jm)))
+}
+
+// SetupBackupFileRoutes sets up the routes listing and downloading backup
+// files. Downloads are read from storage here, without the workers.
+func SetupBackupFileRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, storage services.StorageResolver, cipher *services.BackupCipher, auditService services.AuditServiceInterface, urlExpiry time.Duration) {
+	backupFileHandler := handlers.NewBackupFileHandler(db, storage, cipher, auditService, urlExpiry)
+
+	// Backup file routes with authentication required (cookie-based)
+	backupFileHandler.RegisterRoutes(e.Group("/api", middleware.CookieJWT(jm)))
+}
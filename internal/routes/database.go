@@ -5,14 +5,16 @@ import (
 	"github.com/dbackup/backend-go/internal/encryption"
 	"github.com/dbackup/backend-go/internal/handlers"
 	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
 
 // SetupDatabaseRoutes sets up database connection management routes
-func SetupDatabaseRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encService *encryption.Service) {
-	// Create database handler
+func SetupDatabaseRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encService *encryption.Service, auditService services.AuditServiceInterface) {
+	// Create database handlers
 	dbHandler := handlers.NewDatabaseHandler(db, encService)
+	permissionHandler := handlers.NewTablePermissionHandler(db, auditService)
 
 	// Database routes group with authentication required (cookie-based)
 	dbGroup := e.Group("/api/databases", middleware.CookieJWT(jm))
@@ -28,6 +30,12 @@ func SetupDatabaseRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encServ
 	dbGroup.POST("/:uid/test", dbHandler.TestDatabaseConnection)
 	dbGroup.POST("/:uid/discover", dbHandler.DiscoverTables)
 	dbGroup.GET("/:uid/tables", dbHandler.ListTables)
+	dbGroup.GET("/:uid/pii", dbHandler.ListPIIColumns)
+
+	// Per-table permission grants
+	dbGroup.GET("/:uid/permissions", permissionHandler.ListPermissions)
+	dbGroup.POST("/:uid/permissions", permissionHandler.GrantPermission)
+	dbGroup.DELETE("/:uid/permissions/:id", permissionHandler.RevokePermission)
 
 	// Database statistics (public endpoint for health checks)
 	e.GET("/api/stats/database", handlers.DatabaseStats)
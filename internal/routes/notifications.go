@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// SetupNotificationRoutes sets up notification channel, subscription and delivery log routes
+func SetupNotificationRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encService *encryption.Service, notifications *services.NotificationService) {
+	// Create notification handler
+	notificationHandler := handlers.NewNotificationHandler(db, encService, notifications)
+
+	// Notification routes group with authentication required (cookie-based)
+	notificationGroup := e.Group("/api/notifications", middleware.CookieJWT(jm))
+
+	notificationGroup.GET("/events", notificationHandler.ListNotificationEvents)
+
+	// CRUD operations for notification channels
+	notificationGroup.GET("/channels", notificationHandler.ListChannels)
+	notificationGroup.POST("/channels", notificationHandler.CreateChannel)
+	notificationGroup.GET("/channels/:uid", notificationHandler.GetChannel)
+	notificationGroup.PUT("/channels/:uid", notificationHandler.UpdateChannel)
+	notificationGroup.DELETE("/channels/:uid", notificationHandler.DeleteChannel)
+
+	// Channel operations
+	notificationGroup.POST("/channels/:uid/test", notificationHandler.TestChannel)
+	notificationGroup.POST("/channels/:uid/subscriptions", notificationHandler.CreateSubscription)
+	notificationGroup.DELETE("/channels/:uid/subscriptions/:subscriptionUid", notificationHandler.DeleteSubscription)
+	notificationGroup.GET("/channels/:uid/deliveries", notificationHandler.ListDeliveries)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// SetupQueueRoutes sets up the administrators' job queue routes
+func SetupQueueRoutes(e *echo.Echo, jm *auth.JWTManager, queues services.QueueAdmin, auditService services.AuditServiceInterface) {
+	queueHandler := handlers.NewQueueAdminHandler(queues, auditService)
+
+	// Queue administration is restricted to administrators (cookie-based)
+	queueGroup := e.Group("/api/admin/queues", middleware.CookieJWT(jm), middleware.RequireAdmin())
+
+	// Queue and worker status
+	queueGroup.GET("", queueHandler.GetQueueStats)
+	queueGroup.GET("/workers", queueHandler.ListWorkers)
+	queueGroup.GET("/:queue/jobs", queueHandler.ListJobs)
+
+	// Maintenance operations
+	queueGroup.POST("/:queue/pause", queueHandler.PauseQueue)
+	queueGroup.POST("/:queue/unpause", queueHandler.UnpauseQueue)
+	queueGroup.POST("/:queue/jobs/retry", queueHandler.RetryJobs)
+	queueGroup.POST("/:queue/jobs/archive", queueHandler.ArchiveJobs)
+}
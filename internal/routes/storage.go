@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// SetupStorageRoutes sets up storage configuration management routes
+func SetupStorageRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encService *encryption.Service, factory *services.StorageFactory) {
+	// Create storage handler
+	storageHandler := handlers.NewStorageHandlerWithFactory(db, encService, factory)
+
+	// Storage routes group with authentication required (cookie-based)
+	storageGroup := e.Group("/api/storage", middleware.CookieJWT(jm))
+
+	// CRUD operations for storage configurations
+	storageGroup.GET("", storageHandler.ListStorageConfigurations)
+	storageGroup.POST("", storageHandler.CreateStorageConfiguration)
+	storageGroup.GET("/:uid", storageHandler.GetStorageConfiguration)
+	storageGroup.PUT("/:uid", storageHandler.UpdateStorageConfiguration)
+	storageGroup.DELETE("/:uid", storageHandler.DeleteStorageConfiguration)
+
+	// Storage configuration operations
+	storageGroup.POST("/:uid/test", storageHandler.TestStorageConfiguration)
+	storageGroup.POST("/:uid/default", storageHandler.SetDefaultStorageConfiguration)
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/handlers"
+	"github.com/dbackup/backend-go/internal/middleware"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// SetupWebhookRoutes sets up team webhook endpoint, delivery history and redelivery routes
+func SetupWebhookRoutes(e *echo.Echo, db *gorm.DB, jm *auth.JWTManager, encService *encryption.Service, webhooks *services.WebhookService) {
+	// Create webhook handler
+	webhookHandler := handlers.NewWebhookHandler(db, encService, webhooks)
+
+	// Webhook routes group with authentication required (cookie-based)
+	webhookGroup := e.Group("/api/webhooks", middleware.CookieJWT(jm))
+
+	webhookGroup.GET("/events", webhookHandler.ListWebhookEvents)
+
+	// CRUD operations for webhook endpoints
+	webhookGroup.GET("", webhookHandler.ListEndpoints)
+	webhookGroup.POST("", webhookHandler.CreateEndpoint)
+	webhookGroup.GET("/:uid", webhookHandler.GetEndpoint)
+	webhookGroup.PUT("/:uid", webhookHandler.UpdateEndpoint)
+	webhookGroup.DELETE("/:uid", webhookHandler.DeleteEndpoint)
+
+	// Delivery history
+	webhookGroup.GET("/:uid/deliveries", webhookHandler.ListDeliveries)
+	webhookGroup.POST("/:uid/deliveries/:deliveryUid/redeliver", webhookHandler.Redeliver)
+}
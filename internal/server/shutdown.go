@@ -13,6 +13,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/dbackup/backend-go/internal/database"
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/dbackup/backend-go/internal/queue"
 	"github.com/dbackup/backend-go/internal/websocket"
 )
@@ -59,7 +60,7 @@ func (l DefaultShutdownLogger) Warn(msg string, args ...interface{}) {
 func NewShutdownManager(server *echo.Echo) *ShutdownManager {
 	return &ShutdownManager{
 		server:  server,
-		logger:  DefaultShutdownLogger{},
+		logger:  logging.PrintfLogger{},
 		timeout: 30 * time.Second,
 		hooks:   make([]ShutdownHook, 0),
 	}
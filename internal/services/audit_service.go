@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrActionBlocked is returned when risk scoring blocks an audited action
+var ErrActionBlocked = errors.New("action blocked by risk policy")
+
+// AuditServiceInterface defines the interface for recording audit events
+type AuditServiceInterface interface {
+	Record(ctx context.Context, event *models.AuditLog) (*RiskAssessment, error)
+}
+
+// AuditService records audit events and scores them for risk
+type AuditService struct {
+	db         *gorm.DB
+	riskEngine *RiskEngine
+}
+
+// NewAuditService creates a new audit service. riskEngine may be nil to disable scoring.
+func NewAuditService(db *gorm.DB, riskEngine *RiskEngine) *AuditService {
+	return &AuditService{
+		db:         db,
+		riskEngine: riskEngine,
+	}
+}
+
+// Record scores and stores an audit event. It returns ErrActionBlocked if the
+// event was blocked; the event is persisted either way.
+func (s *AuditService) Record(ctx context.Context, event *models.AuditLog) (*RiskAssessment, error) {
+	assessment := &RiskAssessment{Level: RiskLevelLow}
+	if s.riskEngine != nil {
+		var err error
+		assessment, err = s.riskEngine.Assess(ctx, event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assess audit event: %w", err)
+		}
+	}
+	if event.RiskLevel == "" {
+		event.RiskLevel = RiskLevelLow
+	}
+
+	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
+		return nil, fmt.Errorf("failed to save audit event: %w", err)
+	}
+
+	if event.IsBlocked {
+		return assessment, ErrActionBlocked
+	}
+	return assessment, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// Sealed backups start with a magic header and a random nonce prefix, followed by
+// chunks of at most sealedChunkSize plaintext bytes. Each chunk is a flag byte, the
+// ciphertext length and the AES-256-GCM ciphertext; its nonce is the prefix and the
+// chunk number, and the flag marking the last chunk is authenticated, so reordered,
+// dropped or truncated chunks fail to open.
+const (
+	sealedMagic       = "DBKSEAL1"
+	sealedPrefixSize  = 4
+	sealedChunkSize   = 64 * 1024
+	sealedFinalChunk  = 1
+	sealedDataKeySize = 32
+)
+
+// ErrSealedBackupCorrupt is returned when a sealed backup was modified or cut short
+var ErrSealedBackupCorrupt = errors.New("encrypted backup is corrupt or truncated")
+
+// BackupCipher encrypts backup files with a fresh data key per file. The data key
+// is stored with the file, encrypted with the master key.
+type BackupCipher struct {
+	keys *encryption.Service
+}
+
+// NewBackupCipher creates a cipher whose data keys are wrapped by the master key
+func NewBackupCipher(keys *encryption.Service) *BackupCipher {
+	return &BackupCipher{keys: keys}
+}
+
+// Seal encrypts src into dst under a new data key and returns the wrapped key
+func (c *BackupCipher) Seal(dst io.Writer, src io.Reader) (string, error) {
+	dataKey := make([]byte, sealedDataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := c.keys.Encrypt(base64.StdEncoding.EncodeToString(dataKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	gcm, err := newSealGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	prefix := make([]byte, sealedPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := dst.Write(append([]byte(sealedMagic), prefix...)); err != nil {
+		return "", err
+	}
+
+	// Read one chunk ahead so the last one can be flagged
+	reader := bufio.NewReaderSize(src, sealedChunkSize)
+	plain := make([]byte, sealedChunkSize)
+	var sealed []byte
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		var flag byte
+		if _, err := reader.Peek(1); err == io.EOF {
+			flag = sealedFinalChunk
+		} else if err != nil {
+			return "", err
+		}
+
+		sealed = gcm.Seal(sealed[:0], sealNonce(prefix, counter), plain[:n], []byte{flag})
+		header := make([]byte, 5)
+		header[0] = flag
+		binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+		if _, err := dst.Write(header); err != nil {
+			return "", err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return "", err
+		}
+		if flag == sealedFinalChunk {
+			return wrapped, nil
+		}
+	}
+}
+
+// Open returns a reader of the plaintext of src sealed under the wrapped key
+func (c *BackupCipher) Open(src io.Reader, wrappedKey string) (io.Reader, error) {
+	encoded, err := c.keys.Decrypt(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(dataKey) != sealedDataKeySize {
+		return nil, fmt.Errorf("invalid data key")
+	}
+	gcm, err := newSealGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(sealedMagic)+sealedPrefixSize)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[:len(sealedMagic)]) != sealedMagic {
+		return nil, ErrSealedBackupCorrupt
+	}
+	return &sealedReader{src: src, gcm: gcm, prefix: header[len(sealedMagic):]}, nil
+}
+
+// OpenFile returns a reader of a backup file's content as it was before upload.
+// Files without a data key were stored unencrypted and are returned unchanged.
+func (c *BackupCipher) OpenFile(src io.ReadCloser, file *models.BackupFile) (io.ReadCloser, error) {
+	if file.EncryptionKey == nil {
+		return src, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("backup file %s is encrypted but no backup cipher is configured", file.UID)
+	}
+	reader, err := c.Open(src, *file.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, src}, nil
+}
+
+// sealedReader decrypts a sealed backup chunk by chunk
+type sealedReader struct {
+	src     io.Reader
+	gcm     cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     []byte
+	done    bool
+}
+
+func (r *sealedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next reads and authenticates the following chunk
+func (r *sealedReader) next() error {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r.src, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrSealedBackupCorrupt
+		}
+		return err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > sealedChunkSize+uint32(r.gcm.Overhead()) {
+		return ErrSealedBackupCorrupt
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrSealedBackupCorrupt
+		}
+		return err
+	}
+
+	plain, err := r.gcm.Open(sealed[:0], sealNonce(r.prefix, r.counter), sealed, header[:1])
+	if err != nil {
+		return ErrSealedBackupCorrupt
+	}
+	r.counter++
+	r.buf = plain
+	r.done = header[0] == sealedFinalChunk
+	return nil
+}
+
+// newSealGCM creates the AEAD for a data key
+func newSealGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealNonce builds the nonce of a chunk from the file's prefix and the chunk number
+func sealNonce(prefix []byte, counter uint64) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[sealedPrefixSize:], counter)
+	return nonce
+}
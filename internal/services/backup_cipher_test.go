@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupCipher_RoundTrip(t *testing.T) {
+	backupCipher := NewBackupCipher(encryption.NewService("test-key-for-testing"))
+
+	sizes := []int{0, 1, sealedChunkSize - 1, sealedChunkSize, sealedChunkSize + 1, 3*sealedChunkSize + 17}
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		_, err := rand.Read(plain)
+		require.NoError(t, err)
+
+		var sealed bytes.Buffer
+		key, err := backupCipher.Seal(&sealed, bytes.NewReader(plain))
+		require.NoError(t, err)
+		require.NotEmpty(t, key)
+		if size > 0 {
+			assert.False(t, bytes.Contains(sealed.Bytes(), plain), "size %d", size)
+		}
+
+		reader, err := backupCipher.Open(bytes.NewReader(sealed.Bytes()), key)
+		require.NoError(t, err)
+		opened, err := io.ReadAll(reader)
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, plain, opened, "size %d", size)
+	}
+}
+
+func TestBackupCipher_RejectsTampering(t *testing.T) {
+	backupCipher := NewBackupCipher(encryption.NewService("test-key-for-testing"))
+	plain := bytes.Repeat([]byte("backup data "), sealedChunkSize/4)
+
+	var sealed bytes.Buffer
+	key, err := backupCipher.Seal(&sealed, bytes.NewReader(plain))
+	require.NoError(t, err)
+
+	open := func(data []byte, key string) error {
+		reader, err := backupCipher.Open(bytes.NewReader(data), key)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(reader)
+		return err
+	}
+
+	// Flipped bits
+	modified := append([]byte(nil), sealed.Bytes()...)
+	modified[len(modified)/2] ^= 0x01
+	assert.ErrorIs(t, open(modified, key), ErrSealedBackupCorrupt)
+
+	// Dropping the last chunk leaves a valid prefix that is not flagged as final
+	truncated := sealed.Bytes()[:len(sealedMagic)+sealedPrefixSize+5+sealedChunkSize+16]
+	assert.ErrorIs(t, open(truncated, key), ErrSealedBackupCorrupt)
+
+	// Another file's data key
+	_, otherKey, err := sealString(backupCipher, "other")
+	require.NoError(t, err)
+	assert.ErrorIs(t, open(sealed.Bytes(), otherKey), ErrSealedBackupCorrupt)
+
+	// Another master key cannot unwrap the data key
+	_, err = NewBackupCipher(encryption.NewService("another-key")).Open(bytes.NewReader(sealed.Bytes()), key)
+	assert.Error(t, err)
+}
+
+func TestBackupCipher_OpenFile(t *testing.T) {
+	backupCipher := NewBackupCipher(encryption.NewService("test-key-for-testing"))
+
+	sealed, key, err := sealString(backupCipher, "pg_dump output")
+	require.NoError(t, err)
+
+	reader, err := backupCipher.OpenFile(io.NopCloser(bytes.NewReader(sealed)), &models.BackupFile{IsEncrypted: true, EncryptionKey: &key})
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "pg_dump output", string(data))
+
+	// Files stored before encryption existed have no data key and are served as stored
+	reader, err = backupCipher.OpenFile(io.NopCloser(bytes.NewReader([]byte("plain"))), &models.BackupFile{IsEncrypted: true})
+	require.NoError(t, err)
+	data, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", string(data))
+
+	// Encrypted files cannot be read without a cipher
+	var missing *BackupCipher
+	_, err = missing.OpenFile(io.NopCloser(bytes.NewReader(sealed)), &models.BackupFile{EncryptionKey: &key})
+	assert.Error(t, err)
+}
+
+func sealString(backupCipher *BackupCipher, plain string) ([]byte, string, error) {
+	var sealed bytes.Buffer
+	key, err := backupCipher.Seal(&sealed, bytes.NewReader([]byte(plain)))
+	return sealed.Bytes(), key, err
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultBackupLeaseTTL is how long a backup keeps its places without renewing them
+	DefaultBackupLeaseTTL = 2 * time.Minute
+	// DefaultConcurrencyRetryDelay is how long a deferred backup waits before trying again
+	DefaultConcurrencyRetryDelay = 30 * time.Second
+)
+
+// Concurrency scopes a backup is counted in
+const (
+	ConcurrencyScopeConnection = "connection"
+	ConcurrencyScopeHost       = "host"
+	ConcurrencyScopeTeam       = "team"
+	ConcurrencyScopeUser       = "user"
+	ConcurrencyScopeGlobal     = "global"
+)
+
+// BackupConcurrencyLimits bounds how many backups run at once. Zero or less
+// removes a limit. Team limits come from the team's subscription instead of
+// PerUser.
+type BackupConcurrencyLimits struct {
+	Global        int
+	PerConnection int
+	PerHost       int
+	PerUser       int
+
+	// LeaseTTL is how long places outlive a worker that stopped renewing them
+	LeaseTTL time.Duration
+	// RetryDelay is how long a deferred backup waits before trying again
+	RetryDelay time.Duration
+}
+
+// ConcurrencyLimitError is returned when a backup has to wait for others to finish
+type ConcurrencyLimitError struct {
+	Scope   string
+	Subject string
+	Limit   int
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	return "concurrency limit reached: " + e.WaitingReason()
+}
+
+// WaitingReason describes what a deferred backup is waiting for
+func (e *ConcurrencyLimitError) WaitingReason() string {
+	running := fmt.Sprintf("%d backup", e.Limit)
+	if e.Limit != 1 {
+		running += "s"
+	}
+
+	switch e.Scope {
+	case ConcurrencyScopeConnection:
+		return fmt.Sprintf("Waiting: %s already running on database %s", running, e.Subject)
+	case ConcurrencyScopeHost:
+		return fmt.Sprintf("Waiting: %s already running on host %s", running, e.Subject)
+	case ConcurrencyScopeTeam:
+		return fmt.Sprintf("Waiting: team limit of %s running at once reached", running)
+	case ConcurrencyScopeUser:
+		return fmt.Sprintf("Waiting: user limit of %s running at once reached", running)
+	default:
+		return fmt.Sprintf("Waiting: %s already running", running)
+	}
+}
+
+// BackupConcurrency limits concurrent backups per database connection, per
+// database host, per team or user, and globally
+type BackupConcurrency struct {
+	db        *gorm.DB
+	semaphore Semaphore
+	limits    BackupConcurrencyLimits
+}
+
+// NewBackupConcurrency creates a backup concurrency limiter
+func NewBackupConcurrency(db *gorm.DB, semaphore Semaphore, limits BackupConcurrencyLimits) *BackupConcurrency {
+	if limits.LeaseTTL <= 0 {
+		limits.LeaseTTL = DefaultBackupLeaseTTL
+	}
+	if limits.RetryDelay <= 0 {
+		limits.RetryDelay = DefaultConcurrencyRetryDelay
+	}
+	return &BackupConcurrency{db: db, semaphore: semaphore, limits: limits}
+}
+
+// RetryDelay returns how long deferred backups wait before trying again
+func (bc *BackupConcurrency) RetryDelay() time.Duration {
+	return bc.limits.RetryDelay
+}
+
+// concurrencySlot is a semaphore slot with what it counts, for waiting reasons
+type concurrencySlot struct {
+	SemaphoreSlot
+	scope   string
+	subject string
+}
+
+// Acquire takes the places of a backup job, whose database connection must be
+// loaded. It returns a *ConcurrencyLimitError when the job has to wait.
+func (bc *BackupConcurrency) Acquire(ctx context.Context, job *models.BackupJob) (*BackupLease, error) {
+	slots, err := bc.slots(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	semaphoreSlots := make([]SemaphoreSlot, len(slots))
+	keys := make([]string, len(slots))
+	for i, slot := range slots {
+		semaphoreSlots[i] = slot.SemaphoreSlot
+		keys[i] = slot.Key
+	}
+
+	holder := "backup_job:" + strconv.FormatUint(uint64(job.ID), 10)
+	if err := bc.semaphore.Acquire(ctx, holder, semaphoreSlots, bc.limits.LeaseTTL); err != nil {
+		var full *SemaphoreFullError
+		if errors.As(err, &full) {
+			for _, slot := range slots {
+				if slot.Key == full.Slot.Key {
+					return nil, &ConcurrencyLimitError{Scope: slot.scope, Subject: slot.subject, Limit: slot.Limit}
+				}
+			}
+		}
+		return nil, err
+	}
+
+	return newBackupLease(context.WithoutCancel(ctx), bc.semaphore, holder, keys, bc.limits.LeaseTTL), nil
+}
+
+// slots lists the limits a job counts against, most specific first
+func (bc *BackupConcurrency) slots(ctx context.Context, job *models.BackupJob) ([]concurrencySlot, error) {
+	conn := job.DatabaseConnection
+	var slots []concurrencySlot
+	add := func(scope, id, subject string, limit int) {
+		if limit > 0 {
+			slots = append(slots, concurrencySlot{
+				SemaphoreSlot: SemaphoreSlot{Key: scope + ":" + id, Limit: limit},
+				scope:         scope,
+				subject:       subject,
+			})
+		}
+	}
+
+	add(ConcurrencyScopeConnection, strconv.FormatUint(uint64(conn.ID), 10), conn.Name, bc.limits.PerConnection)
+
+	host := strings.ToLower(conn.Host) + ":" + strconv.Itoa(conn.Port)
+	add(ConcurrencyScopeHost, host, host, bc.limits.PerHost)
+
+	if conn.TeamID != nil {
+		var team models.Team
+		if err := bc.db.WithContext(ctx).First(&team, *conn.TeamID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load team limits: %w", err)
+		}
+		add(ConcurrencyScopeTeam, strconv.FormatUint(uint64(team.ID), 10), team.Name, team.GetSubscriptionLimits().MaxConcurrentBackups)
+	} else {
+		add(ConcurrencyScopeUser, strconv.FormatUint(uint64(job.UserID), 10), "", bc.limits.PerUser)
+	}
+
+	add(ConcurrencyScopeGlobal, "all", "", bc.limits.Global)
+	return slots, nil
+}
+
+// BackupLease holds a backup's places and renews them until released
+type BackupLease struct {
+	semaphore Semaphore
+	holder    string
+	keys      []string
+	ttl       time.Duration
+	// Context the lease was acquired in, used for its log fields
+	logCtx context.Context
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newBackupLease(logCtx context.Context, semaphore Semaphore, holder string, keys []string, ttl time.Duration) *BackupLease {
+	lease := &BackupLease{
+		logCtx:    logCtx,
+		semaphore: semaphore,
+		holder:    holder,
+		keys:      keys,
+		ttl:       ttl,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go lease.renew()
+	return lease
+}
+
+// renew extends the places well before they expire
+func (l *BackupLease) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			if err := l.semaphore.Renew(ctx, l.holder, l.keys, l.ttl); err != nil {
+				slog.WarnContext(l.logCtx, "Failed to renew concurrency lease", "holder", l.holder, "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Release stops renewing and gives the places back. Releasing a nil lease does nothing.
+func (l *BackupLease) Release(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+		err = l.semaphore.Release(ctx, l.holder, l.keys)
+	})
+	return err
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupBackupConcurrencyTest(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Team{}))
+	return db
+}
+
+func concurrencyTestJob(id, userID uint, conn models.DatabaseConnection) *models.BackupJob {
+	return &models.BackupJob{ID: id, UserID: userID, DatabaseConnection: conn}
+}
+
+func TestBackupConcurrency_Limits(t *testing.T) {
+	db := setupBackupConcurrencyTest(t)
+	team := &models.Team{Name: "Ops", Slug: "ops", SubscriptionTier: "free"}
+	require.NoError(t, db.Create(team).Error)
+
+	concurrency := NewBackupConcurrency(db, NewLocalSemaphore(), BackupConcurrencyLimits{
+		Global:        10,
+		PerConnection: 1,
+		PerHost:       2,
+		PerUser:       1,
+	})
+	ctx := context.Background()
+
+	orders := models.DatabaseConnection{ID: 1, Name: "orders", Host: "db1.internal", Port: 5432}
+	billing := models.DatabaseConnection{ID: 2, Name: "billing", Host: "DB1.internal", Port: 5432}
+	analytics := models.DatabaseConnection{ID: 3, Name: "analytics", Host: "db2.internal", Port: 5432}
+
+	first, err := concurrency.Acquire(ctx, concurrencyTestJob(1, 7, orders))
+	require.NoError(t, err)
+
+	limitReason := func(err error) string {
+		var limitErr *ConcurrencyLimitError
+		require.True(t, errors.As(err, &limitErr), "unexpected error: %v", err)
+		return limitErr.WaitingReason()
+	}
+
+	// Two schedules never dump the same database at once
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(2, 8, orders))
+	assert.Equal(t, "Waiting: 1 backup already running on database orders", limitReason(err))
+
+	// Users without a team run one backup at a time
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(3, 7, analytics))
+	assert.Equal(t, "Waiting: user limit of 1 backup running at once reached", limitReason(err))
+
+	// Team connections count against the team's subscription
+	billing.TeamID = &team.ID
+	second, err := concurrency.Acquire(ctx, concurrencyTestJob(4, 8, billing))
+	require.NoError(t, err)
+
+	// The host now runs two backups
+	hostMate := models.DatabaseConnection{ID: 4, Name: "crm", Host: "db1.internal", Port: 5432, TeamID: &team.ID}
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(5, 8, hostMate))
+	assert.Equal(t, "Waiting: 2 backups already running on host db1.internal:5432", limitReason(err))
+
+	analytics.TeamID = &team.ID
+	third, err := concurrency.Acquire(ctx, concurrencyTestJob(6, 8, analytics))
+	require.NoError(t, err)
+
+	// The free tier runs two team backups at once
+	reporting := models.DatabaseConnection{ID: 5, Name: "reporting", Host: "db3.internal", Port: 5432, TeamID: &team.ID}
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(7, 8, reporting))
+	assert.Equal(t, "Waiting: team limit of 2 backups running at once reached", limitReason(err))
+
+	// Released places are free again
+	require.NoError(t, first.Release(ctx))
+	require.NoError(t, first.Release(ctx))
+	require.NoError(t, second.Release(ctx))
+	require.NoError(t, third.Release(ctx))
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(2, 8, orders))
+	require.NoError(t, err)
+}
+
+func TestBackupConcurrency_GlobalLimit(t *testing.T) {
+	concurrency := NewBackupConcurrency(setupBackupConcurrencyTest(t), NewLocalSemaphore(), BackupConcurrencyLimits{Global: 1})
+	ctx := context.Background()
+
+	_, err := concurrency.Acquire(ctx, concurrencyTestJob(1, 1, models.DatabaseConnection{ID: 1, Host: "a"}))
+	require.NoError(t, err)
+
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(2, 2, models.DatabaseConnection{ID: 2, Host: "b"}))
+	var limitErr *ConcurrencyLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, ConcurrencyScopeGlobal, limitErr.Scope)
+	assert.Equal(t, "Waiting: 1 backup already running", limitErr.WaitingReason())
+}
+
+func TestBackupLease_Renews(t *testing.T) {
+	semaphore := NewLocalSemaphore()
+	concurrency := NewBackupConcurrency(setupBackupConcurrencyTest(t), semaphore, BackupConcurrencyLimits{
+		PerConnection: 1,
+		LeaseTTL:      60 * time.Millisecond,
+	})
+	ctx := context.Background()
+	conn := models.DatabaseConnection{ID: 1, Name: "orders"}
+
+	lease, err := concurrency.Acquire(ctx, concurrencyTestJob(1, 1, conn))
+	require.NoError(t, err)
+
+	// The running backup keeps its place beyond the lease TTL
+	time.Sleep(150 * time.Millisecond)
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(2, 1, conn))
+	require.Error(t, err)
+
+	require.NoError(t, lease.Release(ctx))
+	_, err = concurrency.Acquire(ctx, concurrencyTestJob(2, 1, conn))
+	require.NoError(t, err)
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Backup priority lanes, most urgent first. BackupLaneDefault keeps the name
+// of the single queue backups used before lanes existed.
+const (
+	BackupLaneCritical = "backups-critical"
+	BackupLaneHigh     = "backups-high"
+	BackupLaneDefault  = "backups"
+	BackupLaneLow      = "backups-low"
+)
+
+// BackupLanes lists the backup lanes, most urgent first
+var BackupLanes = []string{BackupLaneCritical, BackupLaneHigh, BackupLaneDefault, BackupLaneLow}
+
+// BackupLaneWeights are the relative shares of worker time each lane gets
+var BackupLaneWeights = map[string]int{
+	BackupLaneCritical: 8,
+	BackupLaneHigh:     4,
+	BackupLaneDefault:  2,
+	BackupLaneLow:      1,
+}
+
+const (
+	// MinBackupPriority and MaxBackupPriority bound BackupJob.Priority
+	MinBackupPriority = 1
+	MaxBackupPriority = 10
+	// DefaultBackupPriority is the priority of jobs that did not set one
+	DefaultBackupPriority = 5
+
+	// largeBackupSize and hugeBackupSize move big backups to slower lanes so
+	// they do not hold up small ones
+	largeBackupSize = 10 << 30
+	hugeBackupSize  = 50 << 30
+
+	// DefaultFairShareSlot is the worker time a tenant's backup is charged
+	// at weight 1
+	DefaultFairShareSlot = time.Minute
+	// DefaultFairShareBurst is how many slots a tenant may run ahead before
+	// its backups are spaced out
+	DefaultFairShareBurst = 10
+
+	// DefaultFairClockPrefix prefixes the Redis keys of fair share clocks
+	DefaultFairClockPrefix = "dbackup:fairshare"
+)
+
+// tierWeights are the fair shares of subscription tiers
+var tierWeights = map[string]int{
+	"free":       1,
+	"startup":    2,
+	"business":   4,
+	"enterprise": 8,
+}
+
+// tierPriorityBoost raises or lowers the lane of a subscription tier
+var tierPriorityBoost = map[string]int{
+	"free":       -1,
+	"business":   1,
+	"enterprise": 2,
+}
+
+// tierMaxPriority is the highest priority users of a subscription tier may
+// give their backups; unknown tiers may not raise it above the default
+var tierMaxPriority = map[string]int{
+	"free":       DefaultBackupPriority,
+	"startup":    7,
+	"business":   8,
+	"enterprise": MaxBackupPriority,
+}
+
+// ClampBackupPriority keeps a priority within 1–10; zero means the default
+func ClampBackupPriority(priority int) int {
+	switch {
+	case priority == 0:
+		return DefaultBackupPriority
+	case priority < MinBackupPriority:
+		return MinBackupPriority
+	case priority > MaxBackupPriority:
+		return MaxBackupPriority
+	}
+	return priority
+}
+
+// CapBackupPriority clamps a user-set priority and keeps it within the
+// maximum of the subscription tier
+func CapBackupPriority(priority int, tier string) int {
+	priority = ClampBackupPriority(priority)
+	limit, ok := tierMaxPriority[tier]
+	if !ok {
+		limit = DefaultBackupPriority
+	}
+	if priority > limit {
+		return limit
+	}
+	return priority
+}
+
+// SelectBackupLane picks the lane of a backup from its priority, its
+// estimated size in bytes (zero when unknown) and its subscription tier
+func SelectBackupLane(priority int, estimatedSize int64, tier string) string {
+	score := ClampBackupPriority(priority) + tierPriorityBoost[tier]
+	switch {
+	case estimatedSize >= hugeBackupSize:
+		score -= 2
+	case estimatedSize >= largeBackupSize:
+		score--
+	}
+
+	switch {
+	case score >= 9:
+		return BackupLaneCritical
+	case score >= 7:
+		return BackupLaneHigh
+	case score >= 4:
+		return BackupLaneDefault
+	default:
+		return BackupLaneLow
+	}
+}
+
+// FairClock keeps a virtual clock per tenant for weighted fair queuing.
+// Every backup advances its tenant's clock by its cost; a tenant whose clock
+// runs more than the burst allowance ahead of real time has its backups
+// start later, so tenants with many backups queued cannot starve others.
+type FairClock interface {
+	// Advance charges a tenant for a backup and returns when it may start
+	Advance(ctx context.Context, tenant string, cost, burst time.Duration) (time.Time, error)
+}
+
+// LocalFairClock keeps fair share clocks in memory, for a single process
+type LocalFairClock struct {
+	mu     sync.Mutex
+	clocks map[string]time.Time
+}
+
+// NewLocalFairClock creates an in-memory fair share clock
+func NewLocalFairClock() *LocalFairClock {
+	return &LocalFairClock{clocks: make(map[string]time.Time)}
+}
+
+// Advance charges a tenant for a backup and returns when it may start
+func (c *LocalFairClock) Advance(ctx context.Context, tenant string, cost, burst time.Duration) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	start := c.clocks[tenant]
+	if floor := now.Add(-burst); start.Before(floor) {
+		start = floor
+	}
+	c.clocks[tenant] = start.Add(cost)
+
+	if start.Before(now) {
+		return now, nil
+	}
+	return start, nil
+}
+
+// advanceScript moves a tenant's clock the way LocalFairClock does.
+// KEYS: clock; ARGV: now (ms), cost (ms), burst (ms). Returns the start (ms).
+var advanceScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local start = tonumber(redis.call('GET', KEYS[1]) or 0)
+local floor = now - tonumber(ARGV[3])
+if start < floor then
+	start = floor
+end
+local finish = start + tonumber(ARGV[2])
+redis.call('SET', KEYS[1], finish, 'PX', math.max(finish - now, 0) + tonumber(ARGV[3]) + 1000)
+if start < now then
+	return now
+end
+return start
+`)
+
+// RedisFairClock keeps fair share clocks in Redis so every API node and
+// worker shares them
+type RedisFairClock struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisFairClock creates a fair share clock on an existing Redis client
+func NewRedisFairClock(client redis.UniversalClient, prefix string) *RedisFairClock {
+	if prefix == "" {
+		prefix = DefaultFairClockPrefix
+	}
+	return &RedisFairClock{client: client, prefix: prefix}
+}
+
+// Advance charges a tenant for a backup and returns when it may start
+func (c *RedisFairClock) Advance(ctx context.Context, tenant string, cost, burst time.Duration) (time.Time, error) {
+	start, err := advanceScript.Run(ctx, c.client, []string{c.prefix + ":" + tenant},
+		time.Now().UnixMilli(), cost.Milliseconds(), burst.Milliseconds()).Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to advance fair share clock: %w", err)
+	}
+	return time.UnixMilli(start), nil
+}
+
+// BackupSchedulingConfig tunes fair scheduling. Zero values use the defaults.
+type BackupSchedulingConfig struct {
+	// Slot is the worker time a backup is charged at weight 1
+	Slot time.Duration
+	// Burst is how many slots a tenant may run ahead before being spaced out
+	Burst int
+}
+
+// BackupSchedule is where and when a backup is queued
+type BackupSchedule struct {
+	Lane      string
+	ProcessAt time.Time
+	Tenant    string
+}
+
+// BackupScheduler routes backups to priority lanes and spaces out the
+// backups of tenants that queue more than their fair share
+type BackupScheduler struct {
+	db     *gorm.DB
+	clock  FairClock
+	config BackupSchedulingConfig
+}
+
+// NewBackupScheduler creates a backup scheduler
+func NewBackupScheduler(db *gorm.DB, clock FairClock, config BackupSchedulingConfig) *BackupScheduler {
+	if config.Slot <= 0 {
+		config.Slot = DefaultFairShareSlot
+	}
+	if config.Burst <= 0 {
+		config.Burst = DefaultFairShareBurst
+	}
+	return &BackupScheduler{db: db, clock: clock, config: config}
+}
+
+// Lane picks the lane of a backup job, whose database connection must be loaded
+func (s *BackupScheduler) Lane(ctx context.Context, job *models.BackupJob) (string, error) {
+	tier, err := s.tier(ctx, &job.DatabaseConnection)
+	if err != nil {
+		return "", err
+	}
+	size, err := s.estimatedSize(ctx, job)
+	if err != nil {
+		return "", err
+	}
+	return SelectBackupLane(job.Priority, size, tier), nil
+}
+
+// Schedule picks the lane of a backup job and charges its tenant for it.
+// The job's database connection must be loaded.
+func (s *BackupScheduler) Schedule(ctx context.Context, job *models.BackupJob) (*BackupSchedule, error) {
+	conn := &job.DatabaseConnection
+	tier, err := s.tier(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	size, err := s.estimatedSize(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	weight := tierWeights[tier]
+	if weight == 0 {
+		weight = 1
+	}
+	tenant := backupTenant(job)
+	processAt, err := s.clock.Advance(ctx, tenant, s.config.Slot/time.Duration(weight), time.Duration(s.config.Burst)*s.config.Slot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackupSchedule{
+		Lane:      SelectBackupLane(job.Priority, size, tier),
+		ProcessAt: processAt,
+		Tenant:    tenant,
+	}, nil
+}
+
+// backupTenant names who a backup is charged to: the connection's team, or its user
+func backupTenant(job *models.BackupJob) string {
+	if teamID := job.DatabaseConnection.TeamID; teamID != nil {
+		return "team:" + strconv.FormatUint(uint64(*teamID), 10)
+	}
+	return "user:" + strconv.FormatUint(uint64(job.UserID), 10)
+}
+
+// tier returns the subscription tier of a connection's team
+func (s *BackupScheduler) tier(ctx context.Context, conn *models.DatabaseConnection) (string, error) {
+	return ConnectionTier(ctx, s.db, conn)
+}
+
+// ConnectionTier returns the subscription tier of a connection's team;
+// personal connections are on the free tier
+func ConnectionTier(ctx context.Context, db *gorm.DB, conn *models.DatabaseConnection) (string, error) {
+	if conn.TeamID == nil {
+		return "free", nil
+	}
+
+	var team models.Team
+	if err := db.WithContext(ctx).Select("id", "subscription_tier").First(&team, *conn.TeamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "free", nil
+		}
+		return "", fmt.Errorf("failed to load team tier: %w", err)
+	}
+	if team.SubscriptionTier == "" {
+		return "free", nil
+	}
+	return team.SubscriptionTier, nil
+}
+
+// estimatedSize is the size of the connection's last completed backup
+func (s *BackupScheduler) estimatedSize(ctx context.Context, job *models.BackupJob) (int64, error) {
+	var last models.BackupJob
+	err := s.db.WithContext(ctx).
+		Select("id", "original_size").
+		Where("database_connection_id = ? AND status = ? AND original_size IS NOT NULL", job.DatabaseConnectionID, models.BackupStatusCompleted).
+		Order("completed_at DESC").
+		First(&last).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to estimate backup size: %w", err)
+	}
+	return *last.OriginalSize, nil
+}
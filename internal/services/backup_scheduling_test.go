@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupBackupSchedulingTest(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Team{}, &models.BackupJob{}))
+	return db
+}
+
+func TestClampBackupPriority(t *testing.T) {
+	assert.Equal(t, DefaultBackupPriority, ClampBackupPriority(0))
+	assert.Equal(t, MinBackupPriority, ClampBackupPriority(-3))
+	assert.Equal(t, MaxBackupPriority, ClampBackupPriority(42))
+	assert.Equal(t, 7, ClampBackupPriority(7))
+}
+
+func TestCapBackupPriority(t *testing.T) {
+	assert.Equal(t, DefaultBackupPriority, CapBackupPriority(10, "free"))
+	assert.Equal(t, 3, CapBackupPriority(3, "free"))
+	assert.Equal(t, 7, CapBackupPriority(10, "startup"))
+	assert.Equal(t, 8, CapBackupPriority(9, "business"))
+	assert.Equal(t, MaxBackupPriority, CapBackupPriority(42, "enterprise"))
+	assert.Equal(t, DefaultBackupPriority, CapBackupPriority(0, "enterprise"))
+	assert.Equal(t, DefaultBackupPriority, CapBackupPriority(9, "legacy"))
+}
+
+func TestSelectBackupLane(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority int
+		size     int64
+		tier     string
+		lane     string
+	}{
+		{"default priority", 5, 0, "startup", BackupLaneDefault},
+		{"unset priority", 0, 0, "startup", BackupLaneDefault},
+		{"urgent", 10, 0, "startup", BackupLaneCritical},
+		{"high", 7, 0, "startup", BackupLaneHigh},
+		{"low", 2, 0, "startup", BackupLaneLow},
+		{"free tier drops a lane", 7, 0, "free", BackupLaneDefault},
+		{"enterprise tier rises", 7, 0, "enterprise", BackupLaneCritical},
+		{"large backups wait longer", 7, 20 << 30, "startup", BackupLaneDefault},
+		{"huge backups wait longer still", 9, 60 << 30, "startup", BackupLaneHigh},
+		{"unknown tier", 5, 0, "legacy", BackupLaneDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.lane, SelectBackupLane(tt.priority, tt.size, tt.tier))
+		})
+	}
+}
+
+func testFairClock(t *testing.T, clock FairClock, tenant string) {
+	ctx := context.Background()
+	cost := time.Minute
+	burst := 2 * time.Minute
+
+	// A tenant may run its burst ahead of now, so the first three start right away
+	for i := 0; i < 3; i++ {
+		start, err := clock.Advance(ctx, tenant, cost, burst)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), start, time.Second)
+	}
+
+	// Beyond it its backups are spaced out by their cost
+	next, err := clock.Advance(ctx, tenant, cost, burst)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), next, time.Second)
+
+	after, err := clock.Advance(ctx, tenant, cost, burst)
+	require.NoError(t, err)
+	assert.WithinDuration(t, next.Add(time.Minute), after, 10*time.Millisecond)
+
+	// Other tenants are not held up
+	other, err := clock.Advance(ctx, tenant+":other", cost, burst)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), other, time.Second)
+}
+
+func TestLocalFairClock(t *testing.T) {
+	testFairClock(t, NewLocalFairClock(), "team:1")
+}
+
+func TestRedisFairClock(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	prefix := "dbackup:test:fairshare:" + time.Now().Format("150405.000000")
+	testFairClock(t, NewRedisFairClock(client, prefix), "team:1")
+}
+
+func TestBackupScheduler_Schedule(t *testing.T) {
+	db := setupBackupSchedulingTest(t)
+	team := &models.Team{Name: "Ops", Slug: "ops", SubscriptionTier: "business"}
+	require.NoError(t, db.Create(team).Error)
+
+	scheduler := NewBackupScheduler(db, NewLocalFairClock(), BackupSchedulingConfig{Slot: time.Minute, Burst: 1})
+	ctx := context.Background()
+
+	personal := &models.BackupJob{
+		UserID:               3,
+		Priority:             7,
+		DatabaseConnectionID: 1,
+		DatabaseConnection:   models.DatabaseConnection{ID: 1},
+	}
+	teamJob := &models.BackupJob{
+		UserID:               3,
+		Priority:             8,
+		DatabaseConnectionID: 2,
+		DatabaseConnection:   models.DatabaseConnection{ID: 2, TeamID: &team.ID},
+	}
+
+	// Personal connections are on the free tier
+	schedule, err := scheduler.Schedule(ctx, personal)
+	require.NoError(t, err)
+	assert.Equal(t, BackupLaneDefault, schedule.Lane)
+	assert.Equal(t, "user:3", schedule.Tenant)
+
+	// Team connections use the team's tier
+	schedule, err = scheduler.Schedule(ctx, teamJob)
+	require.NoError(t, err)
+	assert.Equal(t, BackupLaneCritical, schedule.Lane)
+	assert.Equal(t, "team:"+strconv.FormatUint(uint64(team.ID), 10), schedule.Tenant)
+
+	// A business team is charged a quarter slot per backup, so it can queue
+	// more before being spaced out than a free user
+	for i := 0; i < 4; i++ {
+		schedule, err = scheduler.Schedule(ctx, teamJob)
+		require.NoError(t, err)
+	}
+	assert.WithinDuration(t, time.Now(), schedule.ProcessAt, time.Second)
+
+	schedule, err = scheduler.Schedule(ctx, personal)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), schedule.ProcessAt, time.Second)
+
+	schedule, err = scheduler.Schedule(ctx, personal)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), schedule.ProcessAt, time.Second)
+
+	// Big backups of a connection go to a slower lane
+	size := int64(20 << 30)
+	completedAt := time.Now()
+	require.NoError(t, db.Create(&models.BackupJob{
+		UID:                  "done",
+		Name:                 "done",
+		Status:               models.BackupStatusCompleted,
+		UserID:               3,
+		DatabaseConnectionID: 2,
+		OriginalSize:         &size,
+		CompletedAt:          &completedAt,
+	}).Error)
+
+	lane, err := scheduler.Lane(ctx, teamJob)
+	require.NoError(t, err)
+	assert.Equal(t, BackupLaneHigh, lane)
+}
@@ -3,7 +3,10 @@ package services
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"database/sql"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +16,9 @@ import (
 	"time"
 
 	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // BackupServiceInterface defines the interface for backup operations
@@ -39,6 +45,7 @@ type BackupService struct {
 	pgRestorePath string
 	mysqlDumpPath string
 	mysqlPath    string
+	psqlPath     string
 }
 
 // BackupOptions contains options for backup operations
@@ -61,6 +68,9 @@ type BackupOptions struct {
 	SkipLockTables   bool     `json:"skip_lock_tables"`   // Skip locking tables
 	QuickDump        bool     `json:"quick_dump"`         // Quick dump mode
 	
+	// Sanitized export: row filters and column masks, written as plain SQL
+	Sanitize *SanitizeOptions `json:"sanitize,omitempty"`
+	
 	// Progress tracking
 	ProgressCallback func(progress float64, message string) `json:"-"`
 }
@@ -142,6 +152,12 @@ func (bs *BackupService) findBackupTools() error {
 		bs.mysqlPath = "" // Not an error, just not available
 	}
 	
+	// psql is only needed to restore plain SQL dumps such as sanitized exports
+	bs.psqlPath, err = exec.LookPath("psql")
+	if err != nil {
+		bs.psqlPath = ""
+	}
+	
 	return nil
 }
 
@@ -169,8 +185,38 @@ func (bs *BackupService) ValidateBackupTools() error {
 	return nil
 }
 
+// BackupToolVersions reports the version of each backup tool found, by tool
+// name; a tool whose version cannot be read is reported as unknown
+func (bs *BackupService) BackupToolVersions(ctx context.Context) map[string]string {
+	tools := []struct{ name, path string }{
+		{"pg_dump", bs.pgDumpPath},
+		{"pg_restore", bs.pgRestorePath},
+		{"mysqldump", bs.mysqlDumpPath},
+		{"mysql", bs.mysqlPath},
+	}
+
+	versions := make(map[string]string)
+	for _, tool := range tools {
+		if tool.path == "" {
+			continue
+		}
+		output, err := exec.CommandContext(ctx, tool.path, "--version").Output()
+		if err != nil {
+			versions[tool.name] = "unknown"
+			continue
+		}
+		line, _, _ := strings.Cut(string(output), "\n")
+		versions[tool.name] = strings.TrimSpace(line)
+	}
+	return versions
+}
+
 // CreatePostgreSQLBackup creates a PostgreSQL backup using pg_dump
 func (bs *BackupService) CreatePostgreSQLBackup(ctx context.Context, conn *models.DatabaseConnection, options *BackupOptions) (*BackupResult, error) {
+	if options != nil && options.Sanitize != nil {
+		return bs.createSanitizedBackup(ctx, conn, options)
+	}
+	
 	if bs.pgDumpPath == "" {
 		return nil, fmt.Errorf("pg_dump not found")
 	}
@@ -235,12 +281,6 @@ func (bs *BackupService) CreatePostgreSQLBackup(ctx context.Context, conn *model
 		return nil, fmt.Errorf("failed to get backup file info: %w", err)
 	}
 	
-	// Calculate checksum
-	checksum, err := bs.calculateChecksum(backupPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
-	}
-	
 	result := &BackupResult{
 		FilePath:     backupPath,
 		OriginalSize: fileInfo.Size(),
@@ -251,7 +291,6 @@ func (bs *BackupService) CreatePostgreSQLBackup(ctx context.Context, conn *model
 			"format":        options.Format,
 			"timestamp":     timestamp,
 		},
-		Checksum: checksum,
 	}
 	
 	// Compress if requested
@@ -275,6 +314,13 @@ func (bs *BackupService) CreatePostgreSQLBackup(ctx context.Context, conn *model
 		os.Remove(backupPath)
 	}
 	
+	// Checksum the file clients download, compressed but not encrypted
+	checksum, err := bs.calculateChecksum(result.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	result.Checksum = checksum
+	
 	return result, nil
 }
 
@@ -305,14 +351,14 @@ func (bs *BackupService) buildPgDumpArgs(conn *models.DatabaseConnection, output
 		args = append(args, "--jobs", fmt.Sprintf("%d", options.Jobs))
 	}
 	
-	// Add specific tables
+	// Add specific tables, quoted so pg_dump matches each name exactly
 	for _, table := range options.Tables {
-		args = append(args, "--table", table)
+		args = append(args, "--table", pgDumpTableName(table))
 	}
 	
 	// Exclude tables
 	for _, table := range options.ExcludeTables {
-		args = append(args, "--exclude-table", table)
+		args = append(args, "--exclude-table", pgDumpTableName(table))
 	}
 	
 	return args
@@ -341,6 +387,10 @@ func (bs *BackupService) trackPostgreSQLProgress(stderr io.Reader, callback func
 
 // CreateMySQLBackup creates a MySQL backup using mysqldump
 func (bs *BackupService) CreateMySQLBackup(ctx context.Context, conn *models.DatabaseConnection, options *BackupOptions) (*BackupResult, error) {
+	if options != nil && options.Sanitize != nil {
+		return bs.createSanitizedBackup(ctx, conn, options)
+	}
+	
 	if bs.mysqlDumpPath == "" {
 		return nil, fmt.Errorf("mysqldump not found")
 	}
@@ -400,12 +450,6 @@ func (bs *BackupService) CreateMySQLBackup(ctx context.Context, conn *models.Dat
 		return nil, fmt.Errorf("failed to get backup file info: %w", err)
 	}
 	
-	// Calculate checksum
-	checksum, err := bs.calculateChecksum(backupPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
-	}
-	
 	result := &BackupResult{
 		FilePath:     backupPath,
 		OriginalSize: fileInfo.Size(),
@@ -415,7 +459,6 @@ func (bs *BackupService) CreateMySQLBackup(ctx context.Context, conn *models.Dat
 			"database_type": "mysql",
 			"timestamp":     timestamp,
 		},
-		Checksum: checksum,
 	}
 	
 	// Compress if requested
@@ -439,6 +482,13 @@ func (bs *BackupService) CreateMySQLBackup(ctx context.Context, conn *models.Dat
 		os.Remove(backupPath)
 	}
 	
+	// Checksum the file clients download, compressed but not encrypted
+	checksum, err := bs.calculateChecksum(result.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	result.Checksum = checksum
+	
 	return result, nil
 }
 
@@ -480,14 +530,30 @@ func (bs *BackupService) buildMySQLDumpArgs(conn *models.DatabaseConnection, opt
 	// Add database name
 	args = append(args, conn.Database)
 	
-	// Add specific tables
-	if len(options.Tables) > 0 {
-		args = append(args, options.Tables...)
+	// Add specific tables; MySQL catalogues them under the database name
+	for _, table := range options.Tables {
+		if _, name, ok := strings.Cut(table, "."); ok {
+			table = name
+		}
+		args = append(args, table)
 	}
 	
 	return args
 }
 
+// pgDumpTableName quotes a catalogued table name, "schema.table" or "table" in
+// the public schema, so pg_dump reads it as one exact name rather than a pattern
+func pgDumpTableName(table string) string {
+	schema, name, ok := strings.Cut(table, ".")
+	if !ok {
+		schema, name = "public", table
+	}
+	quote := func(identifier string) string {
+		return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+	}
+	return quote(schema) + "." + quote(name)
+}
+
 // trackMySQLProgress tracks the progress of mysqldump
 func (bs *BackupService) trackMySQLProgress(stderr io.Reader, callback func(float64, string)) {
 	scanner := bufio.NewScanner(stderr)
@@ -512,6 +578,17 @@ func (bs *BackupService) trackMySQLProgress(stderr io.Reader, callback func(floa
 
 // RestorePostgreSQLBackup restores a PostgreSQL backup using pg_restore
 func (bs *BackupService) RestorePostgreSQLBackup(ctx context.Context, conn *models.DatabaseConnection, backupPath string, options *RestoreOptions) error {
+	backupPath, cleanup, err := ungzipBackup(backupPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Plain SQL dumps, such as sanitized exports, are replayed with psql
+	if (options != nil && options.Format == "plain") || isPlainSQLDump(backupPath) {
+		return bs.restorePlainPostgreSQLBackup(ctx, conn, backupPath)
+	}
+	
 	if bs.pgRestorePath == "" {
 		return fmt.Errorf("pg_restore not found")
 	}
@@ -567,6 +644,12 @@ func (bs *BackupService) RestoreMySQLBackup(ctx context.Context, conn *models.Da
 	if options == nil {
 		options = &RestoreOptions{}
 	}
+
+	backupPath, cleanup, err := ungzipBackup(backupPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	
 	// Build mysql command
 	args := []string{
@@ -616,7 +699,10 @@ func (bs *BackupService) GetBackupEstimate(ctx context.Context, conn *models.Dat
 }
 
 // CompressBackup compresses a backup file
-func (bs *BackupService) CompressBackup(ctx context.Context, inputPath, outputPath string, algorithm string) error {
+func (bs *BackupService) CompressBackup(ctx context.Context, inputPath, outputPath string, algorithm string) (err error) {
+	_, span := tracing.Start(ctx, "backup.compress", trace.WithAttributes(attribute.String("compression.algorithm", algorithm)))
+	defer func() { tracing.End(span, err) }()
+
 	var cmd *exec.Cmd
 	
 	switch algorithm {
@@ -682,11 +768,309 @@ func (bs *BackupService) calculateChecksum(filePath string) (string, error) {
 	}
 	defer file.Close()
 	
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, file); err != nil {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
 		return "", err
 	}
 	
-	// For simplicity, using a basic hash - in production you'd use crypto/sha256
-	return fmt.Sprintf("sha256:%x", buf.Bytes()[:32]), nil
-}
\ No newline at end of file
+	// Clients downloading the file verify it against this
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// restorePlainPostgreSQLBackup restores a plain SQL dump using psql
+func (bs *BackupService) restorePlainPostgreSQLBackup(ctx context.Context, conn *models.DatabaseConnection, backupPath string) error {
+	if bs.psqlPath == "" {
+		return fmt.Errorf("psql not found")
+	}
+
+	args := []string{
+		"--host", conn.Host,
+		"--port", fmt.Sprintf("%d", conn.Port),
+		"--username", conn.Username,
+		"--dbname", conn.Database,
+		"--set", "ON_ERROR_STOP=1",
+		"--quiet",
+		"--file", backupPath,
+	}
+
+	cmd := exec.CommandContext(ctx, bs.psqlPath, args...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("PGPASSWORD=%s", conn.Password),
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("psql restore failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// hasFileHeader reports whether a regular file starts with the given bytes
+func hasFileHeader(path string, header []byte) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buf := make([]byte, len(header))
+	n, _ := io.ReadFull(file, buf)
+	return n == len(header) && bytes.Equal(buf, header)
+}
+
+// ungzipBackup decompresses a gzip-compressed backup, such as a compressed
+// sanitized export, into a temporary file so restore tools read the dump
+// itself. Other files are returned unchanged.
+func ungzipBackup(path string) (string, func(), error) {
+	noop := func() {}
+	if !hasFileHeader(path, gzipMagic) {
+		return path, noop, nil
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer in.Close()
+
+	reader, err := gzip.NewReader(in)
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to read compressed backup: %w", err)
+	}
+	defer reader.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(path), "restore-*")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create decompressed file: %w", err)
+	}
+	cleanup := func() { os.Remove(out.Name()) }
+
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		cleanup()
+		return "", noop, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write decompressed backup: %w", err)
+	}
+	return out.Name(), cleanup, nil
+}
+
+// isPlainSQLDump reports whether a backup file is plain SQL rather than a
+// pg_dump archive, which always starts with the PGDMP signature, or a
+// still-compressed file
+func isPlainSQLDump(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	header := make([]byte, 5)
+	n, _ := io.ReadFull(file, header)
+	return n > 0 && string(header[:n]) != "PGDMP" && !bytes.HasPrefix(header[:n], gzipMagic)
+}
+
+// createSanitizedBackup writes the schema with the native dump tool followed by
+// the filtered and masked data as INSERT statements, in one plain SQL file
+func (bs *BackupService) createSanitizedBackup(ctx context.Context, conn *models.DatabaseConnection, options *BackupOptions) (*BackupResult, error) {
+	if options.SchemaOnly {
+		return nil, fmt.Errorf("sanitized backups cannot be schema only")
+	}
+
+	var prefix string
+	switch conn.Type {
+	case models.DatabaseTypePostgreSQL:
+		prefix = "postgres"
+	case models.DatabaseTypeMySQL:
+		prefix = "mysql"
+	default:
+		return nil, fmt.Errorf("sanitized backups are not supported for %s", conn.Type)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	filename := fmt.Sprintf("%s_%s_%s_sanitized.sql", prefix, conn.Database, timestamp)
+	backupPath := filepath.Join(bs.tempDir, filename)
+
+	startTime := time.Now()
+
+	if !options.DataOnly {
+		if err := bs.dumpSchema(ctx, conn, backupPath, options); err != nil {
+			os.Remove(backupPath)
+			return nil, err
+		}
+	}
+
+	export, err := bs.writeSanitizedData(ctx, conn, backupPath, options)
+	if err != nil {
+		os.Remove(backupPath)
+		return nil, err
+	}
+
+	duration := time.Since(startTime)
+
+	fileInfo, err := os.Stat(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup file info: %w", err)
+	}
+
+	tables := make([]string, len(export.Tables))
+	filtered := 0
+	for i, table := range export.Tables {
+		tables[i] = table.Table
+		if table.Where != "" {
+			filtered++
+		}
+	}
+
+	result := &BackupResult{
+		FilePath:     backupPath,
+		OriginalSize: fileInfo.Size(),
+		Duration:     duration,
+		Tables:       tables,
+		Metadata: map[string]string{
+			"database_type":   string(conn.Type),
+			"format":          "plain",
+			"timestamp":       timestamp,
+			"sanitized":       "true",
+			"masked_columns":  fmt.Sprintf("%d", export.MaskedColumnCount()),
+			"filtered_tables": fmt.Sprintf("%d", filtered),
+		},
+	}
+
+	if options.Compress {
+		compressedPath := backupPath + ".gz"
+		if err := bs.CompressBackup(ctx, backupPath, compressedPath, "gzip"); err != nil {
+			return nil, fmt.Errorf("failed to compress backup: %w", err)
+		}
+
+		compressedInfo, err := os.Stat(compressedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get compressed file info: %w", err)
+		}
+
+		compressedSize := compressedInfo.Size()
+		result.CompressedSize = &compressedSize
+		result.FilePath = compressedPath
+
+		os.Remove(backupPath)
+	}
+
+	// Checksum the file clients download, compressed but not encrypted
+	checksum, err := bs.calculateChecksum(result.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	result.Checksum = checksum
+
+	return result, nil
+}
+
+// dumpSchema writes the schema of the exported tables as plain SQL
+func (bs *BackupService) dumpSchema(ctx context.Context, conn *models.DatabaseConnection, outputPath string, options *BackupOptions) error {
+	schemaOptions := &BackupOptions{
+		Tables:        options.Tables,
+		ExcludeTables: options.ExcludeTables,
+		SchemaOnly:    true,
+		Format:        "plain",
+	}
+
+	switch conn.Type {
+	case models.DatabaseTypePostgreSQL:
+		if bs.pgDumpPath == "" {
+			return fmt.Errorf("pg_dump not found")
+		}
+
+		cmd := exec.CommandContext(ctx, bs.pgDumpPath, bs.buildPgDumpArgs(conn, outputPath, schemaOptions)...)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", conn.Password))
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("pg_dump schema failed: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+
+	case models.DatabaseTypeMySQL:
+		if bs.mysqlDumpPath == "" {
+			return fmt.Errorf("mysqldump not found")
+		}
+
+		outFile, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create backup file: %w", err)
+		}
+		defer outFile.Close()
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, bs.mysqlDumpPath, bs.buildMySQLDumpArgs(conn, schemaOptions)...)
+		cmd.Stdout = outFile
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("mysqldump schema failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("sanitized backups are not supported for %s", conn.Type)
+	}
+}
+
+// writeSanitizedData appends the sanitized rows to the backup file
+func (bs *BackupService) writeSanitizedData(ctx context.Context, conn *models.DatabaseConnection, outputPath string, options *BackupOptions) (*SanitizedExportResult, error) {
+	source, err := openSanitizeSource(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	exporter, err := NewSanitizedExporter(source, conn.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
+	export, err := exporter.Export(ctx, file, options.Tables, options.ExcludeTables, options.Sanitize, options.ProgressCallback)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write backup file: %w", closeErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sanitized export failed: %w", err)
+	}
+
+	return export, nil
+}
+
+// openSanitizeSource opens the source database of a sanitized export with the
+// same credentials the dump tools use
+func openSanitizeSource(conn *models.DatabaseConnection) (*sql.DB, error) {
+	var connStrings DatabaseService
+
+	var driver, dsn string
+	switch conn.Type {
+	case models.DatabaseTypePostgreSQL:
+		driver, dsn = "postgres", connStrings.buildPostgreSQLConnectionString(conn, conn.Password)
+	case models.DatabaseTypeMySQL:
+		driver, dsn = "mysql", connStrings.buildMySQLConnectionString(conn, conn.Password)
+	default:
+		return nil, fmt.Errorf("sanitized backups are not supported for %s", conn.Type)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
@@ -1,7 +1,12 @@
 package services
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -34,6 +39,22 @@ func TestBackupService_ValidateBackupTools(t *testing.T) {
 	}
 }
 
+func TestBackupService_BackupToolVersions(t *testing.T) {
+	dir := t.TempDir()
+	pgDump := filepath.Join(dir, "pg_dump")
+	require.NoError(t, os.WriteFile(pgDump, []byte("#!/bin/sh\necho 'pg_dump (PostgreSQL) 16.2'\necho 'extra line'\n"), 0o755))
+	broken := filepath.Join(dir, "mysql")
+	require.NoError(t, os.WriteFile(broken, []byte("#!/bin/sh\nexit 1\n"), 0o755))
+
+	service := &BackupService{pgDumpPath: pgDump, mysqlPath: broken}
+	versions := service.BackupToolVersions(context.Background())
+
+	assert.Equal(t, map[string]string{
+		"pg_dump": "pg_dump (PostgreSQL) 16.2",
+		"mysql":   "unknown",
+	}, versions)
+}
+
 func TestBackupService_findBackupTools(t *testing.T) {
 	service := &BackupService{
 		tempDir: os.TempDir(),
@@ -66,7 +87,7 @@ func TestBackupService_buildPgDumpArgs(t *testing.T) {
 		SchemaOnly:    false,
 		DataOnly:      false,
 		Jobs:          2,
-		Tables:        []string{"table1", "table2"},
+		Tables:        []string{"table1", "sales.Orders"},
 		ExcludeTables: []string{"temp_table"},
 	}
 
@@ -81,9 +102,9 @@ func TestBackupService_buildPgDumpArgs(t *testing.T) {
 		"--format", "custom",
 		"--file", "/tmp/test.backup",
 		"--verbose",
-		"--table", "table1",
-		"--table", "table2",
-		"--exclude-table", "temp_table",
+		"--table", `"public"."table1"`,
+		"--table", `"sales"."Orders"`,
+		"--exclude-table", `"public"."temp_table"`,
 	}
 
 	// Check that all expected args are present
@@ -442,6 +463,7 @@ func TestBackupService_calculateChecksum(t *testing.T) {
 	
 	assert.NotEmpty(t, checksum)
 	assert.Contains(t, checksum, "sha256:")
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(testContent))), checksum)
 	
 	// Calculate checksum again to ensure consistency
 	checksum2, err := service.calculateChecksum(testFile)
@@ -450,6 +472,31 @@ func TestBackupService_calculateChecksum(t *testing.T) {
 	assert.Equal(t, checksum, checksum2)
 }
 
+func TestBackupService_PostgreSQLBackup_ChecksumsCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	pgDump := filepath.Join(dir, "pg_dump")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do\n  if [ \"$1\" = --file ]; then echo 'CREATE TABLE orders (id integer);' > \"$2\"; fi\n  shift\ndone\n"
+	require.NoError(t, os.WriteFile(pgDump, []byte(script), 0o755))
+
+	service := &BackupService{tempDir: dir, pgDumpPath: pgDump}
+	conn := &models.DatabaseConnection{Host: "localhost", Port: 5432, Username: "backup", Database: "orders"}
+
+	result, err := service.CreatePostgreSQLBackup(context.Background(), conn, &BackupOptions{Format: "plain", Compress: true})
+	require.NoError(t, err)
+	assert.Equal(t, ".gz", filepath.Ext(result.FilePath))
+
+	// The checksum is that of the compressed file, which is what gets uploaded
+	compressed, err := os.ReadFile(result.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(compressed)), result.Checksum)
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	dump, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE orders (id integer);\n", string(dump))
+}
+
 func TestBackupService_PostgreSQLBackup_MissingTool(t *testing.T) {
 	service := &BackupService{
 		tempDir:    os.TempDir(),
@@ -530,6 +577,63 @@ func TestBackupService_MySQLRestore_MissingTool(t *testing.T) {
 	assert.Contains(t, err.Error(), "mysql not found")
 }
 
+func TestIsPlainSQLDump(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain")
+	require.NoError(t, os.WriteFile(plain, []byte("-- Sanitized data export\nBEGIN;\n"), 0600))
+	archive := filepath.Join(dir, "archive")
+	require.NoError(t, os.WriteFile(archive, []byte("PGDMP\x01\x0e"), 0600))
+
+	assert.True(t, isPlainSQLDump(plain))
+	assert.False(t, isPlainSQLDump(archive))
+	assert.False(t, isPlainSQLDump(dir))
+	assert.False(t, isPlainSQLDump(filepath.Join(dir, "missing")))
+
+	// A compressed sanitized export is decompressed before it is classified
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte("-- Sanitized data export\nBEGIN;\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	gzipped := filepath.Join(dir, "export.sql.gz")
+	require.NoError(t, os.WriteFile(gzipped, compressed.Bytes(), 0600))
+	assert.False(t, isPlainSQLDump(gzipped))
+
+	path, cleanup, err := ungzipBackup(gzipped)
+	require.NoError(t, err)
+	assert.NotEqual(t, gzipped, path)
+	assert.True(t, isPlainSQLDump(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "-- Sanitized data export\nBEGIN;\n", string(data))
+	cleanup()
+	assert.NoFileExists(t, path)
+
+	path, cleanup, err = ungzipBackup(plain)
+	require.NoError(t, err)
+	assert.Equal(t, plain, path)
+	cleanup()
+	assert.FileExists(t, plain)
+}
+
+func TestBackupService_SanitizedBackup_Validation(t *testing.T) {
+	service := &BackupService{tempDir: t.TempDir()}
+	ctx := context.Background()
+
+	conn := &models.DatabaseConnection{Type: models.DatabaseTypePostgreSQL, Database: "testdb"}
+	_, err := service.CreatePostgreSQLBackup(ctx, conn, &BackupOptions{SchemaOnly: true, Sanitize: &SanitizeOptions{}})
+	assert.ErrorContains(t, err, "cannot be schema only")
+
+	// The schema comes from pg_dump, so sanitizing still needs the tool
+	_, err = service.CreatePostgreSQLBackup(ctx, conn, &BackupOptions{Sanitize: &SanitizeOptions{}})
+	assert.ErrorContains(t, err, "pg_dump not found")
+
+	sqlite := &models.DatabaseConnection{Type: models.DatabaseTypeSQLite, Database: "testdb"}
+	_, err = service.CreateMySQLBackup(ctx, sqlite, &BackupOptions{Sanitize: &SanitizeOptions{}})
+	assert.ErrorContains(t, err, "not supported")
+}
+
 func TestBackupOptions_DefaultValues(t *testing.T) {
 	options := &BackupOptions{}
 	
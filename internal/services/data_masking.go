@@ -0,0 +1,274 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// MaskStrategy is how a column value is replaced in a sanitized export
+type MaskStrategy string
+
+const (
+	// MaskNull replaces the value with NULL
+	MaskNull MaskStrategy = "null"
+	// MaskHash replaces the value with a keyed hash of the same type where possible
+	MaskHash MaskStrategy = "hash"
+	// MaskFixed replaces every value with MaskRule.Value
+	MaskFixed MaskStrategy = "fixed"
+	// MaskFakeEmail replaces the value with a fake address of the same shape
+	MaskFakeEmail MaskStrategy = "fake_email"
+	// MaskFakePhone replaces every digit and keeps the punctuation
+	MaskFakePhone MaskStrategy = "fake_phone"
+	// MaskTruncateDate truncates dates to the year, month (default) or day
+	MaskTruncateDate MaskStrategy = "truncate_date"
+)
+
+// fakeEmailDomain is reserved for documentation and never delivers mail
+const fakeEmailDomain = "example.com"
+
+// MaskRule describes how one column is masked
+type MaskRule struct {
+	Strategy MaskStrategy `json:"strategy"`
+	// Value is the replacement for fixed masks and the unit for truncate_date
+	Value string `json:"value,omitempty"`
+}
+
+// Validate checks the strategy and its value
+func (r MaskRule) Validate() error {
+	switch r.Strategy {
+	case MaskNull, MaskHash, MaskFixed, MaskFakeEmail, MaskFakePhone:
+		return nil
+	case MaskTruncateDate:
+		switch r.Value {
+		case "", "year", "month", "day":
+			return nil
+		}
+		return fmt.Errorf("truncate_date unit must be year, month or day, got %q", r.Value)
+	case "":
+		return fmt.Errorf("mask strategy is required")
+	default:
+		return fmt.Errorf("unknown mask strategy %q", r.Strategy)
+	}
+}
+
+// ParseColumnMask parses a TablePermission.ColumnMask entry of the form
+// "column", "column:strategy" or "column:strategy:value". A bare column
+// name returns a zero rule so the strategy is chosen from the column type.
+func ParseColumnMask(entry string) (string, MaskRule, error) {
+	parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+	column := strings.TrimSpace(parts[0])
+	if column == "" {
+		return "", MaskRule{}, fmt.Errorf("column mask %q has no column", entry)
+	}
+	if len(parts) == 1 {
+		return column, MaskRule{}, nil
+	}
+
+	rule := MaskRule{Strategy: MaskStrategy(strings.ToLower(strings.TrimSpace(parts[1])))}
+	if len(parts) == 3 {
+		rule.Value = parts[2]
+	}
+	if err := rule.Validate(); err != nil {
+		return "", MaskRule{}, fmt.Errorf("column mask %q: %w", entry, err)
+	}
+	return column, rule, nil
+}
+
+// DefaultMaskRule picks a masking strategy from a column's name and data type
+func DefaultMaskRule(column *models.DatabaseColumn) MaskRule {
+	name := strings.ToLower(column.Name)
+	category := column.GetDataTypeCategory()
+
+	if category == "string" {
+		switch {
+		case strings.Contains(name, "email"):
+			return MaskRule{Strategy: MaskFakeEmail}
+		case strings.Contains(name, "phone"), strings.Contains(name, "mobile"):
+			return MaskRule{Strategy: MaskFakePhone}
+		}
+	}
+
+	switch category {
+	case "string", "integer", "decimal", "uuid":
+		return MaskRule{Strategy: MaskHash}
+	case "datetime":
+		return MaskRule{Strategy: MaskTruncateDate}
+	default:
+		return MaskRule{Strategy: MaskNull}
+	}
+}
+
+// Masker applies mask rules to column values. Hash-based strategies are keyed
+// and deterministic, so the same value masks identically across tables within
+// one export and masked join keys still match.
+type Masker struct {
+	key []byte
+}
+
+// NewMasker creates a masker with the given hashing key
+func NewMasker(key []byte) *Masker {
+	return &Masker{key: key}
+}
+
+// Mask replaces a scanned column value. The category is the column's
+// models.DatabaseColumn data type category and maxLength limits hashed
+// strings when the column length is known (0 for unlimited).
+func (m *Masker) Mask(rule MaskRule, category string, maxLength int, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch rule.Strategy {
+	case MaskNull:
+		return nil
+	case MaskFixed:
+		return rule.Value
+	case MaskHash:
+		return m.hash(category, maxLength, value)
+	case MaskFakeEmail:
+		return m.fakeEmail(maskText(value))
+	case MaskFakePhone:
+		return m.fakePhone(maskText(value))
+	case MaskTruncateDate:
+		return truncateDate(value, rule.Value)
+	default:
+		return nil
+	}
+}
+
+// hash replaces a value with a keyed hash that still fits the column type
+func (m *Masker) hash(category string, maxLength int, value interface{}) interface{} {
+	sum := m.digest(maskText(value))
+
+	switch category {
+	case "integer", "decimal":
+		// Positive and within a 32-bit column
+		return int64(binary.BigEndian.Uint32(sum[:4])&0x7fffffff) + 1
+	case "uuid":
+		sum[6] = (sum[6] & 0x0f) | 0x40
+		sum[8] = (sum[8] & 0x3f) | 0x80
+		h := hex.EncodeToString(sum[:16])
+		return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+	case "binary":
+		return sum
+	default:
+		h := hex.EncodeToString(sum)
+		if maxLength > 0 && len(h) > maxLength {
+			h = h[:maxLength]
+		}
+		return h
+	}
+}
+
+// fakeEmail keeps the length of the local part and uses a reserved domain
+func (m *Masker) fakeEmail(email string) string {
+	local := email
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		local = email[:at]
+	}
+	if local == "" {
+		local = email
+	}
+
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	stream := m.stream(email, len(local))
+	fake := make([]byte, len(local))
+	for i := range fake {
+		if local[i] == '.' || local[i] == '_' || local[i] == '-' || local[i] == '+' {
+			fake[i] = local[i]
+			continue
+		}
+		fake[i] = letters[int(stream[i])%len(letters)]
+	}
+	return string(fake) + "@" + fakeEmailDomain
+}
+
+// fakePhone replaces digits and keeps separators and a leading plus sign
+func (m *Masker) fakePhone(phone string) string {
+	stream := m.stream(phone, len(phone))
+	fake := []byte(phone)
+	for i, c := range fake {
+		if c >= '0' && c <= '9' {
+			fake[i] = '0' + stream[i]%10
+		}
+	}
+	return string(fake)
+}
+
+// digest returns the keyed hash of a value
+func (m *Masker) digest(value string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// stream derives n deterministic bytes for a value
+func (m *Masker) stream(value string, n int) []byte {
+	out := make([]byte, 0, n+sha256.Size)
+	for counter := uint32(0); len(out) < n; counter++ {
+		mac := hmac.New(sha256.New, m.key)
+		var prefix [4]byte
+		binary.BigEndian.PutUint32(prefix[:], counter)
+		mac.Write(prefix[:])
+		mac.Write([]byte(value))
+		out = mac.Sum(out)
+	}
+	return out[:n]
+}
+
+// truncateDate truncates a date or timestamp to the given unit
+func truncateDate(value interface{}, unit string) interface{} {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string, []byte:
+		parsed, ok := parseMaskedTime(maskText(v))
+		if !ok {
+			return nil
+		}
+		t = parsed
+	default:
+		return nil
+	}
+
+	switch unit {
+	case "year":
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// parseMaskedTime parses dates returned as text by the database driver
+func parseMaskedTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// maskText returns the text form of a scanned value
+func maskText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColumnMask(t *testing.T) {
+	column, rule, err := ParseColumnMask("email")
+	require.NoError(t, err)
+	assert.Equal(t, "email", column)
+	assert.Equal(t, MaskStrategy(""), rule.Strategy)
+
+	column, rule, err = ParseColumnMask("status:fixed:redacted:yes")
+	require.NoError(t, err)
+	assert.Equal(t, "status", column)
+	assert.Equal(t, MaskRule{Strategy: MaskFixed, Value: "redacted:yes"}, rule)
+
+	_, rule, err = ParseColumnMask("born_at:TRUNCATE_DATE:year")
+	require.NoError(t, err)
+	assert.Equal(t, MaskRule{Strategy: MaskTruncateDate, Value: "year"}, rule)
+
+	_, _, err = ParseColumnMask("email:scramble")
+	assert.Error(t, err)
+	_, _, err = ParseColumnMask("born_at:truncate_date:week")
+	assert.Error(t, err)
+	_, _, err = ParseColumnMask(":hash")
+	assert.Error(t, err)
+}
+
+func TestDefaultMaskRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		strategy MaskStrategy
+	}{
+		{"email", "varchar", MaskFakeEmail},
+		{"mobile_phone", "text", MaskFakePhone},
+		{"full_name", "character varying", MaskHash},
+		{"customer_id", "bigint", MaskHash},
+		{"external_id", "uuid", MaskHash},
+		{"birth_date", "date", MaskTruncateDate},
+		{"preferences", "jsonb", MaskNull},
+		{"avatar", "bytea", MaskNull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := DefaultMaskRule(&models.DatabaseColumn{Name: tt.name, DataType: tt.dataType})
+			assert.Equal(t, tt.strategy, rule.Strategy)
+		})
+	}
+}
+
+func TestMasker_Mask(t *testing.T) {
+	masker := NewMasker([]byte("test-key"))
+
+	t.Run("null and fixed", func(t *testing.T) {
+		assert.Nil(t, masker.Mask(MaskRule{Strategy: MaskNull}, "string", 0, "secret"))
+		assert.Equal(t, "n/a", masker.Mask(MaskRule{Strategy: MaskFixed, Value: "n/a"}, "string", 0, "secret"))
+		assert.Nil(t, masker.Mask(MaskRule{Strategy: MaskFixed, Value: "n/a"}, "string", 0, nil), "NULL stays NULL")
+	})
+
+	t.Run("hash is deterministic and fits the column", func(t *testing.T) {
+		rule := MaskRule{Strategy: MaskHash}
+		first := masker.Mask(rule, "string", 0, "alice")
+		assert.Equal(t, first, masker.Mask(rule, "string", 0, []byte("alice")))
+		assert.NotEqual(t, first, masker.Mask(rule, "string", 0, "bob"))
+		assert.Len(t, masker.Mask(rule, "string", 12, "alice"), 12)
+
+		id, ok := masker.Mask(rule, "integer", 0, int64(42)).(int64)
+		require.True(t, ok)
+		assert.Positive(t, id)
+		assert.Equal(t, id, masker.Mask(rule, "integer", 0, int64(42)))
+
+		uuid, ok := masker.Mask(rule, "uuid", 0, "0b9f4e7e-9a3c-4c55-8d0a-000000000001").(string)
+		require.True(t, ok)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+
+		other := NewMasker([]byte("other-key"))
+		assert.NotEqual(t, first, other.Mask(rule, "string", 0, "alice"), "hashes are keyed")
+	})
+
+	t.Run("fake email keeps its shape", func(t *testing.T) {
+		fake, ok := masker.Mask(MaskRule{Strategy: MaskFakeEmail}, "string", 0, "jane.doe@corp.io").(string)
+		require.True(t, ok)
+		assert.Regexp(t, regexp.MustCompile(`^[a-z0-9]{4}\.[a-z0-9]{3}@example\.com$`), fake)
+		assert.Equal(t, fake, masker.Mask(MaskRule{Strategy: MaskFakeEmail}, "string", 0, "jane.doe@corp.io"))
+	})
+
+	t.Run("fake phone keeps its format", func(t *testing.T) {
+		fake, ok := masker.Mask(MaskRule{Strategy: MaskFakePhone}, "string", 0, "+1 (555) 010-9999").(string)
+		require.True(t, ok)
+		assert.Regexp(t, `^\+\d \(\d{3}\) \d{3}-\d{4}$`, fake)
+		assert.NotEqual(t, "+1 (555) 010-9999", fake)
+	})
+
+	t.Run("truncate date", func(t *testing.T) {
+		at := time.Date(1990, time.July, 14, 13, 45, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(1990, time.July, 1, 0, 0, 0, 0, time.UTC), masker.Mask(MaskRule{Strategy: MaskTruncateDate}, "datetime", 0, at))
+		assert.Equal(t, time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), masker.Mask(MaskRule{Strategy: MaskTruncateDate, Value: "year"}, "datetime", 0, at))
+		assert.Equal(t, time.Date(1990, time.July, 14, 0, 0, 0, 0, time.UTC), masker.Mask(MaskRule{Strategy: MaskTruncateDate, Value: "day"}, "datetime", 0, []byte("1990-07-14 13:45:00")))
+		assert.Nil(t, masker.Mask(MaskRule{Strategy: MaskTruncateDate}, "datetime", 0, "not a date"))
+	})
+}
@@ -478,6 +478,10 @@ func (ds *DatabaseService) discoverPostgreSQLTables(ctx context.Context, conn *m
 		return nil, fmt.Errorf("error iterating table rows: %w", err)
 	}
 
+	if err := ds.discoverColumns(discoverCtx, db, conn.Type, tables, req); err != nil {
+		return nil, err
+	}
+
 	return tables, nil
 }
 
@@ -579,9 +583,138 @@ func (ds *DatabaseService) discoverMySQLTables(ctx context.Context, conn *models
 		return nil, fmt.Errorf("error iterating table rows: %w", err)
 	}
 
+	if err := ds.discoverColumns(discoverCtx, db, conn.Type, tables, req); err != nil {
+		return nil, err
+	}
+
 	return tables, nil
 }
 
+// discoverColumns loads the columns of discovered tables and classifies
+// the ones likely to hold personal data
+func (ds *DatabaseService) discoverColumns(ctx context.Context, db *sql.DB, dbType models.DatabaseType, tables []models.DatabaseTable, req *models.TableDiscoveryRequest) error {
+	if len(tables) == 0 || (req != nil && req.SkipColumns) {
+		return nil
+	}
+
+	dialect, err := newSQLDialect(dbType)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]*models.DatabaseTable, len(tables))
+	for i := range tables {
+		byName[tables[i].Schema+"."+tables[i].Name] = &tables[i]
+	}
+
+	rows, err := db.QueryContext(ctx, dialect.columnsQuery())
+	if err != nil {
+		return fmt.Errorf("failed to discover columns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, tableName string
+		var column models.DatabaseColumn
+		var maxLength sql.NullInt64
+
+		if err := rows.Scan(&schema, &tableName, &column.Name, &column.DataType, &column.IsNullable,
+			&maxLength, &column.OrdinalPosition, &column.IsPrimaryKey); err != nil {
+			return fmt.Errorf("failed to scan column row: %w", err)
+		}
+
+		table, ok := byName[schema+"."+tableName]
+		if !ok {
+			continue
+		}
+		if maxLength.Valid {
+			length := int(maxLength.Int64)
+			column.MaxLength = &length
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	sampleSize := DefaultPIISampleSize
+	if req != nil && req.PIISampleSize != nil {
+		sampleSize = *req.PIISampleSize
+	}
+
+	for i := range tables {
+		table := &tables[i]
+
+		var samples map[string][]string
+		if sampleSize > 0 && table.Type == models.TableTypeTable {
+			samples, err = samplePIIValues(ctx, db, dialect, table, sampleSize)
+			if err != nil {
+				return err
+			}
+		}
+
+		for j := range table.Columns {
+			column := &table.Columns[j]
+			if classification := ClassifyColumn(table.Name, column, samples[column.Name]); classification != nil {
+				column.SetPIIClassification(classification.Type, classification.Confidence, classification.Source)
+			}
+		}
+	}
+
+	return nil
+}
+
+// samplePIIValues reads a bounded number of non-null values from the columns
+// whose classification depends on their contents
+func samplePIIValues(ctx context.Context, db *sql.DB, dialect sqlDialect, table *models.DatabaseTable, limit int) (map[string][]string, error) {
+	var names, selects, conditions []string
+	for i := range table.Columns {
+		column := &table.Columns[i]
+		if !NeedsPIISample(column) {
+			continue
+		}
+		quoted := dialect.quote(column.Name)
+		names = append(names, column.Name)
+		selects = append(selects, dialect.textCast(quoted))
+		conditions = append(conditions, quoted+" IS NOT NULL")
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d",
+		strings.Join(selects, ", "), dialect.qualify(table.Schema, table.Name), strings.Join(conditions, " OR "), limit)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample table %s: %w", table.GetFullName(), err)
+	}
+	defer rows.Close()
+
+	samples := make(map[string][]string, len(names))
+	values := make([]sql.NullString, len(names))
+	dest := make([]interface{}, len(names))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan sample row: %w", err)
+		}
+		for i, value := range values {
+			if value.Valid {
+				samples[names[i]] = append(samples[names[i]], value.String)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sample rows: %w", err)
+	}
+
+	return samples, nil
+}
+
 // buildPostgreSQLTablesQuery builds the PostgreSQL table discovery query
 func (ds *DatabaseService) buildPostgreSQLTablesQuery(req *models.TableDiscoveryRequest) string {
 	query := `
@@ -764,11 +897,17 @@ func (ds *DatabaseService) SaveDiscoveredTables(ctx context.Context, tables []mo
 				First(&existing).Error
 
 			if err == gorm.ErrRecordNotFound {
-				// Create new table
+				// Create new table together with its columns
 				if err := tx.Create(&table).Error; err != nil {
 					return fmt.Errorf("failed to create table %s.%s: %w", table.Schema, table.Name, err)
 				}
 			} else if err == nil {
+				if table.Columns != nil {
+					if err := syncDiscoveredColumns(tx, existing.ID, table.Columns); err != nil {
+						return fmt.Errorf("failed to update columns of %s.%s: %w", table.Schema, table.Name, err)
+					}
+				}
+
 				// Update existing table statistics
 				updates := map[string]interface{}{
 					"type":               table.Type,
@@ -792,6 +931,68 @@ func (ds *DatabaseService) SaveDiscoveredTables(ctx context.Context, tables []mo
 	})
 }
 
+// ListPIIColumns returns the catalogued columns classified as personal data
+func (ds *DatabaseService) ListPIIColumns(ctx context.Context, connID uint) ([]models.DatabaseColumn, error) {
+	var columns []models.DatabaseColumn
+	err := ds.db.WithContext(ctx).Preload("DatabaseTable").
+		Joins("JOIN database_tables ON database_tables.id = database_columns.database_table_id").
+		Where("database_tables.database_connection_id = ? AND database_tables.deleted_at IS NULL", connID).
+		Where("database_columns.is_pii = ?", true).
+		Order("database_tables.schema, database_tables.name, database_columns.ordinal_position").
+		Find(&columns).Error
+	return columns, err
+}
+
+// syncDiscoveredColumns updates the stored columns of a table to match discovery
+func syncDiscoveredColumns(tx *gorm.DB, tableID uint, columns []models.DatabaseColumn) error {
+	var existing []models.DatabaseColumn
+	if err := tx.Where("database_table_id = ?", tableID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	stored := make(map[string]models.DatabaseColumn, len(existing))
+	for _, column := range existing {
+		stored[column.Name] = column
+	}
+
+	for _, column := range columns {
+		current, ok := stored[column.Name]
+		if !ok {
+			column.DatabaseTableID = tableID
+			if err := tx.Create(&column).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		delete(stored, column.Name)
+
+		updates := map[string]interface{}{
+			"data_type":        column.DataType,
+			"is_nullable":      column.IsNullable,
+			"max_length":       column.MaxLength,
+			"is_primary_key":   column.IsPrimaryKey,
+			"ordinal_position": column.OrdinalPosition,
+			"is_pii":           column.IsPII,
+			"pii_type":         column.PIIType,
+			"pii_confidence":   column.PIIConfidence,
+			"pii_source":       column.PIISource,
+			"pii_detected_at":  column.PIIDetectedAt,
+		}
+		if err := tx.Model(&current).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	// Columns no longer present in the source database
+	for _, column := range stored {
+		if err := tx.Delete(&column).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // formatBytes formats bytes to human readable string
 func formatBytes(bytes int64) string {
 	if bytes < 1024 {
@@ -487,6 +487,116 @@ func TestDatabaseService_SaveDiscoveredTables(t *testing.T) {
 	})
 }
 
+func TestDatabaseService_DiscoverColumns(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	service := NewDatabaseService(nil, nil)
+	dialect := postgresDialect{}
+	tables := []models.DatabaseTable{
+		{Name: "users", Schema: "public", Type: models.TableTypeTable},
+		{Name: "active_users", Schema: "public", Type: models.TableTypeView},
+	}
+
+	mock.ExpectQuery(dialect.columnsQuery()).WillReturnRows(sqlmock.NewRows([]string{
+		"table_schema", "table_name", "column_name", "data_type", "is_nullable", "character_maximum_length", "ordinal_position", "is_primary_key",
+	}).
+		AddRow("public", "active_users", "email", "character varying", true, 255, 1, false).
+		AddRow("public", "users", "id", "integer", false, nil, 1, true).
+		AddRow("public", "users", "email", "character varying", false, 255, 2, false).
+		AddRow("public", "users", "notes", "text", true, nil, 3, false).
+		AddRow("public", "users", "last_seen_from", "inet", true, nil, 4, false).
+		AddRow("audit", "events", "payload", "jsonb", true, nil, 1, false))
+	mock.ExpectQuery(`SELECT CAST("email" AS text), CAST("notes" AS text) FROM "public"."users" WHERE "email" IS NOT NULL OR "notes" IS NOT NULL LIMIT 25`).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "notes"}).
+			AddRow("ann@corp.io", "called twice").
+			AddRow("bo@mail.org", nil))
+
+	err = service.discoverColumns(context.Background(), sqlDB, models.DatabaseTypePostgreSQL, tables, &models.TableDiscoveryRequest{})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	users := tables[0]
+	require.Len(t, users.Columns, 4)
+	assert.True(t, users.Columns[0].IsPrimaryKey)
+	require.NotNil(t, users.Columns[1].MaxLength)
+	assert.Equal(t, 255, *users.Columns[1].MaxLength)
+
+	piiColumns := users.GetPIIColumns()
+	require.Len(t, piiColumns, 2)
+	assert.Equal(t, "email", piiColumns[0].Name)
+	assert.Equal(t, models.PIITypeEmail, *piiColumns[0].PIIType)
+	assert.Equal(t, PIISourceNameAndSample, *piiColumns[0].PIISource)
+	assert.NotNil(t, piiColumns[0].PIIDetectedAt)
+	assert.Equal(t, models.PIITypeIPAddress, *piiColumns[1].PIIType)
+
+	// Views are classified by name only
+	require.Len(t, tables[1].Columns, 1)
+	assert.Equal(t, PIISourceName, *tables[1].Columns[0].PIISource)
+
+	t.Run("skip columns", func(t *testing.T) {
+		tables := []models.DatabaseTable{{Name: "users", Schema: "public", Type: models.TableTypeTable}}
+		err := service.discoverColumns(context.Background(), sqlDB, models.DatabaseTypePostgreSQL, tables, &models.TableDiscoveryRequest{SkipColumns: true})
+		require.NoError(t, err)
+		assert.Empty(t, tables[0].Columns)
+	})
+}
+
+func TestDatabaseService_SaveDiscoveredTables_Columns(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.DatabaseColumn{}))
+	service := NewDatabaseService(db, nil)
+
+	conn := &models.DatabaseConnection{Name: "Test Connection", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "testdb", Username: "testuser"}
+	require.NoError(t, db.Create(conn).Error)
+
+	discovered := func(columns ...models.DatabaseColumn) []models.DatabaseTable {
+		return []models.DatabaseTable{{
+			Name: "users", Schema: "public", Type: models.TableTypeTable,
+			DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now(), Columns: columns,
+		}}
+	}
+
+	email := models.DatabaseColumn{Name: "email", DataType: "varchar", OrdinalPosition: 2}
+	email.SetPIIClassification(models.PIITypeEmail, 0.7, PIISourceName)
+	require.NoError(t, service.SaveDiscoveredTables(context.Background(), discovered(
+		models.DatabaseColumn{Name: "id", DataType: "integer", OrdinalPosition: 1},
+		email,
+		models.DatabaseColumn{Name: "legacy", DataType: "text", OrdinalPosition: 3},
+	)))
+
+	columns, err := service.ListPIIColumns(context.Background(), conn.ID)
+	require.NoError(t, err)
+	require.Len(t, columns, 1)
+	assert.Equal(t, "users", columns[0].DatabaseTable.Name)
+
+	// Rediscovery updates classifications and drops vanished columns
+	phone := models.DatabaseColumn{Name: "phone", DataType: "varchar", OrdinalPosition: 3}
+	phone.SetPIIClassification(models.PIITypePhone, 0.95, PIISourceNameAndSample)
+	require.NoError(t, service.SaveDiscoveredTables(context.Background(), discovered(
+		models.DatabaseColumn{Name: "id", DataType: "bigint", OrdinalPosition: 1},
+		models.DatabaseColumn{Name: "email", DataType: "varchar", OrdinalPosition: 2},
+		phone,
+	)))
+
+	var stored []models.DatabaseColumn
+	require.NoError(t, db.Order("ordinal_position").Find(&stored).Error)
+	require.Len(t, stored, 3)
+	assert.Equal(t, "bigint", stored[0].DataType)
+	assert.False(t, stored[1].IsPII)
+	assert.Nil(t, stored[1].PIIType)
+	assert.Equal(t, "phone", stored[2].Name)
+
+	columns, err = service.ListPIIColumns(context.Background(), conn.ID)
+	require.NoError(t, err)
+	require.Len(t, columns, 1)
+	public := columns[0].ToPIIPublic()
+	assert.Equal(t, "users", public.Table)
+	assert.Equal(t, models.PIITypePhone, public.PIIType)
+	assert.Equal(t, PIISourceNameAndSample, public.Source)
+}
+
 func TestFormatBytes(t *testing.T) {
 	tests := []struct {
 		bytes    int64
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempObjectPrefix marks partially written file-backed objects so listings skip them
+const tempObjectPrefix = ".dbackup-tmp-"
+
+// LocalObjectStore stores objects as files under a root directory, e.g. an NFS mount.
+// The root must already exist; it is never created so an unmounted share fails
+// loudly instead of silently filling the local disk.
+type LocalObjectStore struct {
+	root string
+}
+
+// NewLocalObjectStore creates an object store rooted at an absolute directory path
+func NewLocalObjectStore(root string) (*LocalObjectStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("root directory is required")
+	}
+	if !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("root directory must be an absolute path: %s", root)
+	}
+	return &LocalObjectStore{root: filepath.Clean(root)}, nil
+}
+
+// Put writes an object atomically by renaming a fully written temporary file into place
+func (s *LocalObjectStore) Put(ctx context.Context, key string, data io.Reader, contentType string) (*ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRoot(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, tempObjectPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, &contextReader{ctx: ctx, reader: data})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, fmt.Errorf("failed to move object into place: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         size,
+		LastModified: time.Now().UTC(),
+		ContentType:  contentType,
+	}, nil
+}
+
+// Get opens an object for reading
+func (s *LocalObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	return file, nil
+}
+
+// Stat returns metadata for an object
+func (s *LocalObjectStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+
+	return &ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// List walks the directory tree for objects whose keys start with prefix
+func (s *LocalObjectStore) List(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	// Only walk the deepest directory the prefix pins down
+	start := s.root
+	if dir := path.Dir(prefix); dir != "." {
+		var err error
+		if start, err = s.path(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []ObjectInfo
+	errLimit := errors.New("limit reached")
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == start {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempObjectPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		if len(objects) >= limit {
+			return errLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// Delete removes an object and any directories left empty by its removal
+func (s *LocalObjectStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	for dir := filepath.Dir(target); dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// PresignGet is not supported for local directories
+func (s *LocalObjectStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// TestConnection checks that the root directory exists and is writable
+func (s *LocalObjectStore) TestConnection(ctx context.Context) error {
+	if err := s.checkRoot(); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(s.root, tempObjectPrefix+"probe-*")
+	if err != nil {
+		return fmt.Errorf("root directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// checkRoot verifies the root directory exists
+func (s *LocalObjectStore) checkRoot() error {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return fmt.Errorf("root directory is unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("root path is not a directory: %s", s.root)
+	}
+	return nil
+}
+
+// path maps an object key to a file path that cannot escape the root
+func (s *LocalObjectStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" {
+		return "", fmt.Errorf("object key is required")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// contextReader stops a copy once its context is cancelled
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
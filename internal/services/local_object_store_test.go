@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exerciseObjectStore runs the common ObjectStore contract against a store
+func exerciseObjectStore(t *testing.T, store ObjectStore) {
+	ctx := context.Background()
+	key := "backups/2024/03/12/app.backup"
+	content := []byte("backup contents")
+
+	require.NoError(t, store.TestConnection(ctx))
+
+	info, err := store.Put(ctx, key, bytes.NewReader(content), "application/octet-stream")
+	require.NoError(t, err)
+	assert.Equal(t, key, info.Key)
+	assert.Equal(t, int64(len(content)), info.Size)
+
+	// Overwrites replace the object
+	_, err = store.Put(ctx, key, strings.NewReader(string(content)), "application/octet-stream")
+	require.NoError(t, err)
+
+	reader, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, content, data)
+
+	stat, err := store.Stat(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), stat.Size)
+
+	_, err = store.Put(ctx, "backups/2024/03/13/other.backup", strings.NewReader("x"), "")
+	require.NoError(t, err)
+	_, err = store.Put(ctx, "elsewhere/file.backup", strings.NewReader("y"), "")
+	require.NoError(t, err)
+
+	objects, err := store.List(ctx, "backups/", 0)
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+	for _, object := range objects {
+		assert.True(t, strings.HasPrefix(object.Key, "backups/"), object.Key)
+	}
+
+	objects, err = store.List(ctx, "backups/2024/03/12/", 0)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, key, objects[0].Key)
+
+	objects, err = store.List(ctx, "missing/", 0)
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	objects, err = store.List(ctx, "", 1)
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Stat(ctx, key)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Deleting a missing object is not an error
+	assert.NoError(t, store.Delete(ctx, key))
+
+	_, err = store.PresignGet(ctx, "elsewhere/file.backup", time.Hour)
+	assert.ErrorIs(t, err, ErrPresignNotSupported)
+}
+
+func TestLocalObjectStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalObjectStore(root)
+	require.NoError(t, err)
+
+	exerciseObjectStore(t, store)
+
+	// Empty date directories are pruned and no temporary files are left behind
+	_, err = os.Stat(filepath.Join(root, "backups", "2024", "03", "12"))
+	assert.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(filepath.Join(root, "elsewhere"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "file.backup", entries[0].Name())
+}
+
+func TestLocalObjectStore_KeysCannotEscapeRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "store")
+	require.NoError(t, os.Mkdir(root, 0o750))
+
+	store, err := NewLocalObjectStore(root)
+	require.NoError(t, err)
+
+	_, err = store.Put(context.Background(), "../../outside.backup", strings.NewReader("data"), "")
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(parent, "outside.backup"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, "outside.backup"))
+	assert.NoError(t, err)
+
+	_, err = store.Put(context.Background(), "/", strings.NewReader("data"), "")
+	assert.Error(t, err)
+}
+
+func TestLocalObjectStore_MissingRoot(t *testing.T) {
+	_, err := NewLocalObjectStore("relative/path")
+	assert.Error(t, err)
+
+	root := filepath.Join(t.TempDir(), "unmounted")
+	store, err := NewLocalObjectStore(root)
+	require.NoError(t, err)
+
+	// An unmounted share must not be created on the local disk
+	assert.Error(t, store.TestConnection(context.Background()))
+	_, err = store.Put(context.Background(), "backup.dump", strings.NewReader("data"), "")
+	assert.Error(t, err)
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// Headers of signed webhook requests
+const (
+	WebhookSignatureHeader = "X-DBackup-Signature"
+	WebhookTimestampHeader = "X-DBackup-Timestamp"
+	WebhookEventHeader     = "X-DBackup-Event"
+	WebhookDeliveryHeader  = "X-DBackup-Delivery"
+)
+
+// NotificationMessage is a rendered notification
+type NotificationMessage struct {
+	ID         string                   `json:"id"`
+	Event      models.NotificationEvent `json:"event"`
+	Subject    string                   `json:"subject"`
+	Text       string                   `json:"text"`
+	Data       map[string]string        `json:"data,omitempty"`
+	OccurredAt time.Time                `json:"occurred_at"`
+}
+
+// NotificationSender delivers messages to one type of channel
+type NotificationSender interface {
+	Send(ctx context.Context, settings *models.NotificationChannelSettings, message *NotificationMessage) error
+}
+
+// SignWebhook returns the signature of a webhook body sent at the given time:
+// the hex HMAC-SHA256, keyed with the secret, of the Unix timestamp, a dot
+// and the body. Receivers recompute it and reject old timestamps to stop
+// replays.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postJSON posts a JSON body and fails on any non-2xx response. It returns
+// the response status, or zero when there was no response.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dbackup-notifications")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The response is not recorded: it is only the status that tells whether
+	// the message arrived, and a body could expose whatever the URL reached
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// WebhookSender posts signed JSON messages to generic webhooks
+type WebhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookSender creates a webhook sender
+func NewWebhookSender(client *http.Client) *WebhookSender {
+	return &WebhookSender{client: client, now: time.Now}
+}
+
+// Send posts the message as JSON, signed with the channel's secret
+func (s *WebhookSender) Send(ctx context.Context, settings *models.NotificationChannelSettings, message *NotificationMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	timestamp := s.now()
+	header := http.Header{}
+	header.Set(WebhookEventHeader, string(message.Event))
+	header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(WebhookSignatureHeader, SignWebhook(settings.Secret, timestamp, body))
+	_, err = postJSON(ctx, s.client, settings.URL, body, header)
+	return err
+}
+
+// SlackSender posts messages to Slack incoming webhooks
+type SlackSender struct {
+	client *http.Client
+}
+
+// NewSlackSender creates a Slack sender
+func NewSlackSender(client *http.Client) *SlackSender {
+	return &SlackSender{client: client}
+}
+
+// Send posts the message with its subject in bold
+func (s *SlackSender) Send(ctx context.Context, settings *models.NotificationChannelSettings, message *NotificationMessage) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", message.Subject, message.Text),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack payload: %w", err)
+	}
+	_, err = postJSON(ctx, s.client, settings.URL, body, nil)
+	return err
+}
+
+// sendMailFunc matches smtp.SendMail
+type sendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailSender sends plain text email through an SMTP server
+type EmailSender struct {
+	cfg      config.NotificationConfig
+	sendMail sendMailFunc
+}
+
+// NewEmailSender creates an email sender for the configured SMTP server
+func NewEmailSender(cfg config.NotificationConfig) *EmailSender {
+	return &EmailSender{cfg: cfg, sendMail: smtp.SendMail}
+}
+
+// Send mails the message to the channel's recipients. The SMTP client takes
+// no context, so a cancelled delivery only stops waiting for it.
+func (s *EmailSender) Send(ctx context.Context, settings *models.NotificationChannelSettings, message *NotificationMessage) error {
+	if s.cfg.SMTPHost == "" {
+		return errors.New("no SMTP server is configured")
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, s.cfg.SMTPHost)
+	}
+	addr := net.JoinHostPort(s.cfg.SMTPHost, strconv.Itoa(s.cfg.SMTPPort))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.sendMail(addr, auth, s.cfg.SMTPFrom, settings.Recipients, s.compose(settings.Recipients, message))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose builds the email with its headers
+func (s *EmailSender) compose(to []string, message *NotificationMessage) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.cfg.SMTPFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", sanitizeHeader(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", message.OccurredAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// sanitizeHeader keeps a header value on one line
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// NotificationTaskType is the queue task delivering one notification
+const NotificationTaskType = "notification:deliver"
+
+// NotificationTaskPayload is the payload of a notification delivery task
+type NotificationTaskPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// TaskEnqueuer queues notification and webhook deliveries
+type TaskEnqueuer interface {
+	EnqueueJob(ctx context.Context, jobType string, payload interface{}, opts ...JobOption) (*JobInfo, error)
+}
+
+// Notification is an event to tell a user or team about. It reaches the
+// user's own channels and, for team resources, the team's channels that
+// subscribe to the event.
+type Notification struct {
+	Event  models.NotificationEvent
+	UserID uint
+	TeamID *uint
+	// The database connection the event is about, for subscriptions
+	// limited to one connection
+	DatabaseConnectionID *uint
+	// Values filled into the event's message template
+	Data       map[string]string
+	OccurredAt time.Time
+}
+
+// notificationTemplate is the message of an event
+type notificationTemplate struct {
+	subject *template.Template
+	text    *template.Template
+}
+
+// newNotificationTemplate parses a message template. Missing data renders
+// empty rather than as "<no value>".
+func newNotificationTemplate(event models.NotificationEvent, subject, text string) notificationTemplate {
+	parse := func(name, source string) *template.Template {
+		return template.Must(template.New(string(event) + "." + name).Option("missingkey=zero").Parse(source))
+	}
+	return notificationTemplate{subject: parse("subject", subject), text: parse("text", text)}
+}
+
+// notificationTest is the event of test messages, which channels cannot subscribe to
+const notificationTest models.NotificationEvent = "test"
+
+var notificationTemplates = map[models.NotificationEvent]notificationTemplate{
+	models.NotificationBackupFailed: newNotificationTemplate(models.NotificationBackupFailed,
+		`Backup of {{.Data.database}} failed`,
+		"The backup job \"{{.Data.job}}\" of {{.Data.database}} failed at {{.Time}}.\n\nError: {{.Data.error}}"),
+	models.NotificationBackupSucceeded: newNotificationTemplate(models.NotificationBackupSucceeded,
+		`Backup of {{.Data.database}} completed`,
+		"The backup job \"{{.Data.job}}\" of {{.Data.database}} completed at {{.Time}}.\n\nSize: {{.Data.size}}\nDuration: {{.Data.duration}}"),
+	models.NotificationRestoreCompleted: newNotificationTemplate(models.NotificationRestoreCompleted,
+		`Restore of {{.Data.database}} completed`,
+		"The restore job \"{{.Data.job}}\" into {{.Data.database}} completed at {{.Time}}.\n\nDuration: {{.Data.duration}}"),
+	models.NotificationStorageQuotaNearing: newNotificationTemplate(models.NotificationStorageQuotaNearing,
+		`Storage {{.Data.storage}} is {{.Data.percent}}% full`,
+		"Backups on {{.Data.storage}} use {{.Data.used}} of its {{.Data.quota}} quota as of {{.Time}}.\n\nDelete old backups or raise the quota before new backups fail."),
+	models.NotificationConnectionUnhealthy: newNotificationTemplate(models.NotificationConnectionUnhealthy,
+		`Database {{.Data.database}} is unreachable`,
+		"{{.Data.database}} failed {{.Data.failures}} health checks in a row as of {{.Time}}, so its backup schedules are paused until it recovers.\n\nError: {{.Data.error}}"),
+	models.NotificationConnectionRecovered: newNotificationTemplate(models.NotificationConnectionRecovered,
+		`Database {{.Data.database}} recovered`,
+		"{{.Data.database}} passed its health check at {{.Time}}, and its backup schedules are resumed."),
+	models.NotificationSuspiciousActivity: newNotificationTemplate(models.NotificationSuspiciousActivity,
+		`Suspicious {{.Data.action}} flagged as {{.Data.level}} risk`,
+		"A {{.Data.action}} from {{.Data.ip_address}} at {{.Time}} was flagged as {{.Data.level}} risk{{if eq .Data.blocked \"true\"}} and blocked{{end}}.\n\nReasons: {{.Data.reasons}}"),
+	notificationTest: newNotificationTemplate(notificationTest,
+		`Test notification`,
+		"This is a test message for the notification channel \"{{.Data.channel}}\", sent at {{.Time}}."),
+}
+
+// renderNotification renders the subject and text of a notification
+func renderNotification(n *Notification) (string, string, error) {
+	tmpl, ok := notificationTemplates[n.Event]
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification event %q", n.Event)
+	}
+
+	data := struct {
+		Event models.NotificationEvent
+		Data  map[string]string
+		Time  string
+	}{
+		Event: n.Event,
+		Data:  n.Data,
+		Time:  n.OccurredAt.UTC().Format("2006-01-02 15:04:05 MST"),
+	}
+
+	var subject, text strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render notification subject: %w", err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return "", "", fmt.Errorf("failed to render notification text: %w", err)
+	}
+	return subject.String(), text.String(), nil
+}
+
+// NotificationService sends notifications to the channels subscribed to
+// their events. Each channel gets a delivery, sent by a queue task and
+// retried with backoff, and recorded in the channel's delivery log.
+type NotificationService struct {
+	db          *gorm.DB
+	encService  *encryption.Service
+	queue       TaskEnqueuer
+	senders     map[models.NotificationChannelType]NotificationSender
+	maxAttempts int
+	timeout     time.Duration
+}
+
+// NewNotificationService creates a notification service. The queue may be
+// nil where only test messages are sent.
+func NewNotificationService(db *gorm.DB, encService *encryption.Service, queue TaskEnqueuer, cfg config.NotificationConfig) *NotificationService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	client := newOutboundClient(cfg.Timeout, cfg.AllowPrivateNetworks)
+	return &NotificationService{
+		db:         db,
+		encService: encService,
+		queue:      queue,
+		senders: map[models.NotificationChannelType]NotificationSender{
+			models.NotificationChannelEmail:   NewEmailSender(cfg),
+			models.NotificationChannelSlack:   NewSlackSender(client),
+			models.NotificationChannelWebhook: NewWebhookSender(client),
+		},
+		maxAttempts: cfg.MaxAttempts,
+		timeout:     cfg.Timeout,
+	}
+}
+
+// SetSender replaces the sender of a channel type
+func (ns *NotificationService) SetSender(channelType models.NotificationChannelType, sender NotificationSender) {
+	ns.senders[channelType] = sender
+}
+
+// Notify queues a delivery of the notification to every subscribed channel
+func (ns *NotificationService) Notify(ctx context.Context, n *Notification) error {
+	if !n.Event.IsValid() {
+		return fmt.Errorf("unknown notification event %q", n.Event)
+	}
+	if ns.queue == nil {
+		return errors.New("notification queue is not available")
+	}
+	if n.OccurredAt.IsZero() {
+		n.OccurredAt = time.Now()
+	}
+
+	channelIDs, err := ns.subscribedChannels(ctx, n)
+	if err != nil {
+		return fmt.Errorf("failed to find subscribed channels: %w", err)
+	}
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	subject, text, err := renderNotification(n)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, channelID := range channelIDs {
+		delivery := &models.NotificationDelivery{
+			ChannelID:  channelID,
+			Event:      n.Event,
+			Subject:    subject,
+			Body:       text,
+			Data:       n.Data,
+			Status:     models.NotificationDeliveryPending,
+			OccurredAt: n.OccurredAt,
+		}
+		if err := ns.db.WithContext(ctx).Create(delivery).Error; err != nil {
+			errs = append(errs, fmt.Errorf("failed to record delivery: %w", err))
+			continue
+		}
+
+		_, err := ns.queue.EnqueueJob(ctx, NotificationTaskType, NotificationTaskPayload{DeliveryID: delivery.ID},
+			WithQueue("default"),
+			WithMaxRetry(ns.maxAttempts-1),
+			WithTimeout(2*ns.timeout),
+		)
+		if err != nil {
+			delivery.RecordAttempt(fmt.Errorf("failed to queue delivery: %w", err), true)
+			ns.saveDelivery(ctx, delivery)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyRisk tells the user of a suspicious audit event, and their team for
+// team resources. It makes NotificationService a RiskNotifier.
+func (ns *NotificationService) NotifyRisk(ctx context.Context, event *models.AuditLog, assessment *RiskAssessment) error {
+	// Events of unknown users, such as logins to missing accounts, have nobody to tell
+	if event.UserID == nil {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(assessment.Findings))
+	for _, finding := range assessment.Findings {
+		reasons = append(reasons, finding.Reason)
+	}
+
+	return ns.Notify(ctx, &Notification{
+		Event:  models.NotificationSuspiciousActivity,
+		UserID: *event.UserID,
+		TeamID: event.TeamID,
+		Data: map[string]string{
+			"action":     string(event.Action),
+			"ip_address": event.IPAddress,
+			"level":      assessment.Level,
+			"reasons":    strings.Join(reasons, "; "),
+			"blocked":    strconv.FormatBool(assessment.Blocked),
+		},
+		OccurredAt: event.CreatedAt,
+	})
+}
+
+// subscribedChannels finds the active channels subscribed to a notification
+func (ns *NotificationService) subscribedChannels(ctx context.Context, n *Notification) ([]uint, error) {
+	query := ns.db.WithContext(ctx).Model(&models.NotificationChannel{}).
+		Joins("JOIN notification_subscriptions ON notification_subscriptions.channel_id = notification_channels.id").
+		Where("notification_channels.is_active = ?", true).
+		Where("notification_subscriptions.event = ?", n.Event)
+
+	if n.TeamID != nil {
+		query = query.Where("(notification_channels.user_id = ? AND notification_channels.team_id IS NULL) OR notification_channels.team_id = ?", n.UserID, *n.TeamID)
+	} else {
+		query = query.Where("notification_channels.user_id = ? AND notification_channels.team_id IS NULL", n.UserID)
+	}
+
+	if n.DatabaseConnectionID != nil {
+		query = query.Where("notification_subscriptions.database_connection_id IS NULL OR notification_subscriptions.database_connection_id = ?", *n.DatabaseConnectionID)
+	} else {
+		query = query.Where("notification_subscriptions.database_connection_id IS NULL")
+	}
+
+	var channelIDs []uint
+	err := query.Distinct().Order("notification_channels.id").Pluck("notification_channels.id", &channelIDs).Error
+	return channelIDs, err
+}
+
+// Deliver makes one attempt to send a queued delivery. It returns an error
+// for the queue to retry; final is set on the last attempt, after which a
+// failed delivery is given up.
+func (ns *NotificationService) Deliver(ctx context.Context, deliveryID uint, final bool) error {
+	var delivery models.NotificationDelivery
+	if err := ns.db.WithContext(ctx).First(&delivery, deliveryID).Error; err != nil {
+		return fmt.Errorf("failed to load notification delivery: %w", err)
+	}
+	if delivery.Status == models.NotificationDeliveryDelivered || delivery.Status == models.NotificationDeliveryFailed {
+		return nil
+	}
+
+	var channel models.NotificationChannel
+	if err := ns.db.WithContext(ctx).First(&channel, delivery.ChannelID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted since: nothing left to retry
+			delivery.RecordAttempt(errors.New("notification channel was deleted"), true)
+			ns.saveDelivery(ctx, &delivery)
+			return nil
+		}
+		return fmt.Errorf("failed to load notification channel: %w", err)
+	}
+	if !channel.IsActive {
+		delivery.RecordAttempt(errors.New("notification channel is disabled"), true)
+		ns.saveDelivery(ctx, &delivery)
+		return nil
+	}
+
+	err := ns.send(ctx, &channel, &delivery)
+	delivery.RecordAttempt(err, final)
+	ns.saveDelivery(ctx, &delivery)
+	if err != nil {
+		return fmt.Errorf("failed to deliver notification to %s channel %s: %w", channel.Type, channel.UID, err)
+	}
+	return nil
+}
+
+// SendTest sends a test message to a channel right away and logs it
+func (ns *NotificationService) SendTest(ctx context.Context, channel *models.NotificationChannel) (*models.NotificationDelivery, error) {
+	n := &Notification{
+		Event:      notificationTest,
+		UserID:     channel.UserID,
+		Data:       map[string]string{"channel": channel.Name},
+		OccurredAt: time.Now(),
+	}
+	subject, text, err := renderNotification(n)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.NotificationDelivery{
+		ChannelID:  channel.ID,
+		Event:      n.Event,
+		Subject:    subject,
+		Body:       text,
+		Data:       n.Data,
+		Status:     models.NotificationDeliveryPending,
+		OccurredAt: n.OccurredAt,
+	}
+	if err := ns.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	delivery.RecordAttempt(ns.send(ctx, channel, delivery), true)
+	ns.saveDelivery(ctx, delivery)
+	return delivery, nil
+}
+
+// send renders a delivery for its channel and sends it
+func (ns *NotificationService) send(ctx context.Context, channel *models.NotificationChannel, delivery *models.NotificationDelivery) error {
+	sender, ok := ns.senders[channel.Type]
+	if !ok {
+		return fmt.Errorf("unsupported channel type: %s", channel.Type)
+	}
+	settings, err := channel.GetSettings(ns.encService)
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, ns.timeout)
+	defer cancel()
+
+	return sender.Send(sendCtx, settings, &NotificationMessage{
+		ID:         delivery.UID,
+		Event:      delivery.Event,
+		Subject:    delivery.Subject,
+		Text:       delivery.Body,
+		Data:       delivery.Data,
+		OccurredAt: delivery.OccurredAt,
+	})
+}
+
+// saveDelivery records the outcome of a delivery attempt
+func (ns *NotificationService) saveDelivery(ctx context.Context, delivery *models.NotificationDelivery) {
+	err := ns.db.WithContext(context.WithoutCancel(ctx)).Model(delivery).
+		Select("status", "attempts", "last_error", "delivered_at").
+		Updates(delivery).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record notification delivery", "delivery_uid", delivery.UID, "error", err)
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type recordingEnqueuer struct {
+	payloads []NotificationTaskPayload
+	err      error
+}
+
+func (e *recordingEnqueuer) EnqueueJob(ctx context.Context, jobType string, payload interface{}, opts ...JobOption) (*JobInfo, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.payloads = append(e.payloads, payload.(NotificationTaskPayload))
+	return &JobInfo{ID: "task", Type: jobType}, nil
+}
+
+func setupNotificationTest(t *testing.T) (*gorm.DB, *encryption.Service) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Team{},
+		&models.NotificationChannel{},
+		&models.NotificationSubscription{},
+		&models.NotificationDelivery{},
+	))
+	return db, encryption.NewService("test-key-for-testing")
+}
+
+func createTestChannel(t *testing.T, db *gorm.DB, encService *encryption.Service, channel *models.NotificationChannel, settings *models.NotificationChannelSettings, events ...models.NotificationEvent) *models.NotificationChannel {
+	if channel.Name == "" {
+		channel.Name = "Alerts"
+	}
+	if channel.Type == "" {
+		channel.Type = models.NotificationChannelWebhook
+	}
+	require.NoError(t, channel.SetSettings(settings, encService))
+	require.NoError(t, db.Create(channel).Error)
+	for _, event := range events {
+		require.NoError(t, db.Create(&models.NotificationSubscription{ChannelID: channel.ID, Event: event}).Error)
+	}
+	return channel
+}
+
+func TestSignWebhook(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	signature := SignWebhook("secret", timestamp, []byte(`{"ok":true}`))
+
+	assert.True(t, strings.HasPrefix(signature, "sha256="))
+	assert.Equal(t, signature, SignWebhook("secret", timestamp, []byte(`{"ok":true}`)))
+	assert.NotEqual(t, signature, SignWebhook("other", timestamp, []byte(`{"ok":true}`)))
+	assert.NotEqual(t, signature, SignWebhook("secret", timestamp.Add(time.Second), []byte(`{"ok":true}`)))
+}
+
+func TestWebhookSender(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewWebhookSender(server.Client())
+	message := &NotificationMessage{ID: "delivery-1", Event: models.NotificationBackupFailed, Subject: "Backup failed", Text: "details"}
+	require.NoError(t, sender.Send(context.Background(), &models.NotificationChannelSettings{URL: server.URL, Secret: "s3cret"}, message))
+
+	require.NotNil(t, received)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, string(models.NotificationBackupFailed), received.Header.Get(WebhookEventHeader))
+
+	unix, err := strconv.ParseInt(received.Header.Get(WebhookTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, SignWebhook("s3cret", time.Unix(unix, 0), body), received.Header.Get(WebhookSignatureHeader))
+
+	var payload NotificationMessage
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "delivery-1", payload.ID)
+	assert.Equal(t, "Backup failed", payload.Subject)
+}
+
+func TestWebhookSender_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewWebhookSender(server.Client()).Send(context.Background(), &models.NotificationChannelSettings{URL: server.URL, Secret: "s"}, &NotificationMessage{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	// Response bodies are not passed on to the delivery log
+	assert.NotContains(t, err.Error(), "down for maintenance")
+}
+
+func TestSlackSender(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	err := NewSlackSender(server.Client()).Send(context.Background(), &models.NotificationChannelSettings{URL: server.URL}, &NotificationMessage{Subject: "Backup failed", Text: "details"})
+	require.NoError(t, err)
+	assert.Equal(t, "*Backup failed*\ndetails", payload["text"])
+}
+
+func TestEmailSender(t *testing.T) {
+	sender := NewEmailSender(config.NotificationConfig{SMTPHost: "smtp.example.com", SMTPPort: 2525, SMTPUser: "mailer", SMTPPassword: "pw", SMTPFrom: "dbackup@example.com"})
+
+	var addr, from string
+	var to []string
+	var msg []byte
+	sender.sendMail = func(a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		addr, from, to, msg = a, f, t, m
+		return nil
+	}
+
+	settings := &models.NotificationChannelSettings{Recipients: []string{"ops@example.com", "dba@example.com"}}
+	message := &NotificationMessage{Subject: "Backup failed\r\nBcc: evil@example.com", Text: "line one\nline two", OccurredAt: time.Now()}
+	require.NoError(t, sender.Send(context.Background(), settings, message))
+
+	assert.Equal(t, "smtp.example.com:2525", addr)
+	assert.Equal(t, "dbackup@example.com", from)
+	assert.Equal(t, settings.Recipients, to)
+	assert.Contains(t, string(msg), "To: ops@example.com, dba@example.com\r\n")
+	assert.Contains(t, string(msg), "Subject: Backup failed  Bcc: evil@example.com\r\n")
+	assert.Contains(t, string(msg), "line one\r\nline two")
+
+	err := NewEmailSender(config.NotificationConfig{}).Send(context.Background(), settings, message)
+	assert.Error(t, err)
+}
+
+func TestRenderNotification(t *testing.T) {
+	for _, event := range append(models.NotificationEvents, notificationTest) {
+		subject, text, err := renderNotification(&Notification{Event: event, OccurredAt: time.Now()})
+		require.NoError(t, err, event)
+		assert.NotEmpty(t, subject, event)
+		assert.NotContains(t, subject+text, "<no value>", event)
+	}
+
+	subject, text, err := renderNotification(&Notification{
+		Event:      models.NotificationBackupFailed,
+		Data:       map[string]string{"database": "orders", "job": "Nightly", "error": "disk full"},
+		OccurredAt: time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Backup of orders failed", subject)
+	assert.Contains(t, text, `"Nightly" of orders failed at 2024-03-01 02:00:00 UTC`)
+	assert.Contains(t, text, "Error: disk full")
+}
+
+func TestNotificationService_NotifySubscribedChannels(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	settings := &models.NotificationChannelSettings{URL: "https://hooks.example.com/a", Secret: "s"}
+	teamID, otherTeamID := uint(7), uint(8)
+	databaseID, otherDatabaseID := uint(3), uint(4)
+
+	personal := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, settings, models.NotificationBackupFailed, models.NotificationBackupSucceeded)
+	team := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 2, TeamID: &teamID, IsActive: true}, settings, models.NotificationBackupFailed)
+	createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 2, TeamID: &otherTeamID, IsActive: true}, settings, models.NotificationBackupFailed)
+	createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 2, IsActive: true}, settings, models.NotificationBackupFailed)
+	createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, settings, models.NotificationRestoreCompleted)
+	disabled := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, settings, models.NotificationBackupFailed)
+	require.NoError(t, db.Model(disabled).Update("is_active", false).Error)
+
+	scoped := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, settings)
+	require.NoError(t, db.Create(&models.NotificationSubscription{ChannelID: scoped.ID, Event: models.NotificationBackupFailed, DatabaseConnectionID: &databaseID}).Error)
+	otherScoped := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, settings)
+	require.NoError(t, db.Create(&models.NotificationSubscription{ChannelID: otherScoped.ID, Event: models.NotificationBackupFailed, DatabaseConnectionID: &otherDatabaseID}).Error)
+
+	queue := &recordingEnqueuer{}
+	service := NewNotificationService(db, encService, queue, config.NotificationConfig{MaxAttempts: 3})
+	err := service.Notify(context.Background(), &Notification{
+		Event:                models.NotificationBackupFailed,
+		UserID:               1,
+		TeamID:               &teamID,
+		DatabaseConnectionID: &databaseID,
+		Data:                 map[string]string{"database": "orders"},
+	})
+	require.NoError(t, err)
+
+	var deliveries []models.NotificationDelivery
+	require.NoError(t, db.Order("channel_id").Find(&deliveries).Error)
+	require.Len(t, deliveries, 3)
+	assert.Equal(t, []uint{personal.ID, team.ID, scoped.ID}, []uint{deliveries[0].ChannelID, deliveries[1].ChannelID, deliveries[2].ChannelID})
+	for i, delivery := range deliveries {
+		assert.Equal(t, models.NotificationDeliveryPending, delivery.Status)
+		assert.Equal(t, "Backup of orders failed", delivery.Subject)
+		assert.Equal(t, "orders", delivery.Data["database"])
+		assert.Equal(t, delivery.ID, queue.payloads[i].DeliveryID)
+	}
+
+	assert.Error(t, service.Notify(context.Background(), &Notification{Event: "backup.exploded", UserID: 1}))
+}
+
+func TestNotificationService_NotifyRisk(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	channel := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: "https://hooks.example.com", Secret: "s"}, models.NotificationSuspiciousActivity)
+
+	queue := &recordingEnqueuer{}
+	service := NewNotificationService(db, encService, queue, config.NotificationConfig{})
+	event := &models.AuditLog{Action: models.AuditActionLogin, IPAddress: "10.0.0.1", UserID: uintPtr(1), CreatedAt: time.Now()}
+	assessment := &RiskAssessment{
+		Level:    RiskLevelHigh,
+		Blocked:  true,
+		Findings: []RiskFinding{{Rule: "failed_login_burst", Level: RiskLevelHigh, Reason: "3 failed logins within 10m0s"}},
+	}
+	require.NoError(t, service.NotifyRisk(context.Background(), event, assessment))
+
+	var delivery models.NotificationDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, channel.ID, delivery.ChannelID)
+	assert.Equal(t, "Suspicious login flagged as high risk", delivery.Subject)
+	assert.Contains(t, delivery.Body, "from 10.0.0.1")
+	assert.Contains(t, delivery.Body, "and blocked")
+	assert.Contains(t, delivery.Body, "3 failed logins within 10m0s")
+	assert.Len(t, queue.payloads, 1)
+
+	// Nobody to tell about unknown users
+	require.NoError(t, service.NotifyRisk(context.Background(), &models.AuditLog{Action: models.AuditActionLogin}, assessment))
+	assert.Len(t, queue.payloads, 1)
+}
+
+func TestNotificationService_NotifyQueueFailure(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: "https://hooks.example.com", Secret: "s"}, models.NotificationBackupFailed)
+
+	service := NewNotificationService(db, encService, &recordingEnqueuer{err: errors.New("redis down")}, config.NotificationConfig{})
+	require.Error(t, service.Notify(context.Background(), &Notification{Event: models.NotificationBackupFailed, UserID: 1}))
+
+	var delivery models.NotificationDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.NotificationDeliveryFailed, delivery.Status)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, "redis down")
+}
+
+func TestNotificationService_DeliverRetries(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: server.URL, Secret: "s"}, models.NotificationBackupFailed)
+	queue := &recordingEnqueuer{}
+	service := NewNotificationService(db, encService, queue, config.NotificationConfig{MaxAttempts: 3, Timeout: time.Second, AllowPrivateNetworks: true})
+	require.NoError(t, service.Notify(context.Background(), &Notification{Event: models.NotificationBackupFailed, UserID: 1}))
+	require.Len(t, queue.payloads, 1)
+	deliveryID := queue.payloads[0].DeliveryID
+
+	load := func() models.NotificationDelivery {
+		var delivery models.NotificationDelivery
+		require.NoError(t, db.First(&delivery, deliveryID).Error)
+		return delivery
+	}
+
+	// The first attempt fails and is left for the queue to retry
+	require.Error(t, service.Deliver(context.Background(), deliveryID, false))
+	delivery := load()
+	assert.Equal(t, models.NotificationDeliveryRetrying, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, "502")
+
+	require.NoError(t, service.Deliver(context.Background(), deliveryID, false))
+	delivery = load()
+	assert.Equal(t, models.NotificationDeliveryDelivered, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	// Delivered messages are not sent twice
+	require.NoError(t, service.Deliver(context.Background(), deliveryID, false))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNotificationService_DeliverGivesUp(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	channel := createTestChannel(t, db, encService, &models.NotificationChannel{UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: server.URL, Secret: "s"}, models.NotificationBackupFailed, models.NotificationBackupSucceeded)
+	queue := &recordingEnqueuer{}
+	service := NewNotificationService(db, encService, queue, config.NotificationConfig{AllowPrivateNetworks: true})
+	require.NoError(t, service.Notify(context.Background(), &Notification{Event: models.NotificationBackupFailed, UserID: 1}))
+	require.NoError(t, service.Notify(context.Background(), &Notification{Event: models.NotificationBackupSucceeded, UserID: 1}))
+	require.Len(t, queue.payloads, 2)
+
+	require.Error(t, service.Deliver(context.Background(), queue.payloads[0].DeliveryID, true))
+	var delivery models.NotificationDelivery
+	require.NoError(t, db.First(&delivery, queue.payloads[0].DeliveryID).Error)
+	assert.Equal(t, models.NotificationDeliveryFailed, delivery.Status)
+
+	// A deleted channel ends its deliveries without further retries
+	require.NoError(t, db.Delete(channel).Error)
+	require.NoError(t, service.Deliver(context.Background(), queue.payloads[1].DeliveryID, false))
+	var orphaned models.NotificationDelivery
+	require.NoError(t, db.First(&orphaned, queue.payloads[1].DeliveryID).Error)
+	assert.Equal(t, models.NotificationDeliveryFailed, orphaned.Status)
+	assert.Equal(t, "notification channel was deleted", *orphaned.LastError)
+}
+
+func TestNotificationService_SendTest(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	channel := createTestChannel(t, db, encService, &models.NotificationChannel{Name: "Ops room", Type: models.NotificationChannelSlack, UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: server.URL})
+
+	delivery, err := NewNotificationService(db, encService, nil, config.NotificationConfig{AllowPrivateNetworks: true}).SendTest(context.Background(), channel)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationDeliveryDelivered, delivery.Status)
+	assert.Contains(t, payload["text"], `"Ops room"`)
+
+	var count int64
+	require.NoError(t, db.Model(&models.NotificationDelivery{}).Where("channel_id = ?", channel.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestNotificationService_RefusesPrivateNetworks(t *testing.T) {
+	db, encService := setupNotificationTest(t)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	channel := createTestChannel(t, db, encService, &models.NotificationChannel{Name: "Internal", UserID: 1, IsActive: true}, &models.NotificationChannelSettings{URL: server.URL, Secret: "s"})
+
+	// The server listens on loopback, which channels may not reach by default
+	delivery, err := NewNotificationService(db, encService, nil, config.NotificationConfig{}).SendTest(context.Background(), channel)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationDeliveryFailed, delivery.Status)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, errNonPublicAddress.Error())
+	assert.Zero(t, calls.Load())
+}
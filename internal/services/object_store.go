@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrObjectNotFound is returned when an object does not exist in the store
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrPresignNotSupported is returned by stores that cannot hand out direct download URLs
+	ErrPresignNotSupported = errors.New("presigned URLs are not supported by this storage provider")
+)
+
+// ObjectInfo describes an object held in an ObjectStore
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	ContentType  string
+}
+
+// ObjectStore is a provider-neutral store of backup objects.
+// A store is bound to one storage configuration, so keys are relative to its
+// bucket or root directory and already include any configured path prefix.
+type ObjectStore interface {
+	// Put streams data to key, replacing any existing object
+	Put(ctx context.Context, key string, data io.Reader, contentType string) (*ObjectInfo, error)
+	// Get opens an object for streaming; the caller must close the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat returns object metadata or ErrObjectNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List returns up to limit objects whose keys start with prefix
+	List(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// PresignGet returns a time-limited download URL or ErrPresignNotSupported
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// TestConnection checks that the store is reachable
+	TestConnection(ctx context.Context) error
+}
+
+// BucketScoped is implemented by stores whose credentials can reach other buckets
+type BucketScoped interface {
+	InBucket(bucket string) ObjectStore
+}
+
+// ObjectStoreForBucket returns a store addressing the given bucket when the store
+// supports it, so objects stay reachable after their configuration's bucket changes
+func ObjectStoreForBucket(store ObjectStore, bucket string) ObjectStore {
+	if scoped, ok := store.(BucketScoped); ok && bucket != "" {
+		return scoped.InBucket(bucket)
+	}
+	return store
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// maxOutboundRedirects is how many redirects notification and webhook requests follow
+const maxOutboundRedirects = 5
+
+// errNonPublicAddress is returned for connections to addresses outside the public internet
+var errNonPublicAddress = errors.New("address is not publicly routable")
+
+// Networks that are not covered by the netip predicates but are not reachable
+// on the public internet either
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which could reach any IPv4 address
+}
+
+// newOutboundClient creates the HTTP client for URLs that users enter. Unless
+// private networks are allowed, it only connects to public addresses, so the
+// URLs cannot reach the services next to the backend.
+func newOutboundClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	if allowPrivateNetworks {
+		return &http.Client{Timeout: timeout, CheckRedirect: checkOutboundRedirect}
+	}
+	return newGuardedClient(timeout, isPublicAddress)
+}
+
+// newGuardedClient creates an HTTP client that connects only to permitted
+// addresses. The address is checked when connecting, after DNS resolution, so
+// neither hostnames resolving to internal addresses nor redirects get past it.
+func newGuardedClient(timeout time.Duration, permitted func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if addr := addrPort.Addr().Unmap(); !permitted(addr) {
+				return fmt.Errorf("%w: %s", errNonPublicAddress, addr)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on the client's behalf, past the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport, CheckRedirect: checkOutboundRedirect}
+}
+
+// checkOutboundRedirect follows a few redirects to http and https URLs
+func checkOutboundRedirect(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	if len(via) >= maxOutboundRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxOutboundRedirects)
+	}
+	return nil
+}
+
+// isPublicAddress reports whether an address is routable on the public
+// internet, rather than loopback, private, link-local or otherwise reserved
+func isPublicAddress(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
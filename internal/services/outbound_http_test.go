@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	public := []string{"93.184.216.34", "8.8.8.8", "2606:4700:4700::1111"}
+	for _, addr := range public {
+		assert.True(t, isPublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+
+	internal := []string{
+		"127.0.0.1", "::1", // loopback
+		"10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1", // private
+		"169.254.169.254", "fe80::1", // link-local, including cloud metadata
+		"0.0.0.0", "::", "224.0.0.1", "ff02::1", // unspecified and multicast
+		"100.64.0.1", "198.18.0.1", "240.0.0.1", "64:ff9b::a9fe:a9fe", // reserved
+	}
+	for _, addr := range internal {
+		assert.False(t, isPublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestOutboundClient_RefusesNonPublicAddresses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	_, err := newOutboundClient(time.Second, false).Get(server.URL)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errNonPublicAddress)
+
+	// Hostnames are checked by the address they resolve to
+	_, err = newOutboundClient(time.Second, false).Get("http://localhost:" + server.URL[len("http://127.0.0.1:"):])
+	assert.ErrorIs(t, err, errNonPublicAddress)
+	assert.Zero(t, calls.Load())
+
+	// Deployments with endpoints on their own network can allow them
+	resp, err := newOutboundClient(time.Second, true).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestOutboundClient_ChecksRedirects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skip("no second loopback address to listen on")
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached a refused address")
+	}))
+	internal.Listener.Close()
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	redirecting := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer redirecting.Close()
+
+	// Permit the first server only: the redirect opens a connection of its own
+	first := netip.MustParseAddrPort(redirecting.Listener.Addr().String()).Addr()
+	client := newGuardedClient(time.Second, func(addr netip.Addr) bool { return addr == first })
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, redirecting.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errNonPublicAddress)
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultPIISampleSize is the number of values sampled per table during discovery
+const DefaultPIISampleSize = 25
+
+// Sources of a PII classification
+const (
+	PIISourceName          = "name"
+	PIISourceType          = "type"
+	PIISourceSample        = "sample"
+	PIISourceNameAndSample = "name+sample"
+)
+
+const (
+	// piiConfidenceThreshold is the confidence from which a column counts as PII
+	piiConfidenceThreshold = 0.5
+	// piiSampleMatchRatio is the share of sampled values that must match a pattern
+	piiSampleMatchRatio = 0.6
+)
+
+// PIIClassification is the personal data a column was found to hold
+type PIIClassification struct {
+	Type       models.PIIType
+	Confidence float64
+	Source     string
+}
+
+// piiNameRule matches column names that suggest a kind of personal data
+type piiNameRule struct {
+	piiType models.PIIType
+	// Any of these joined token sequences must appear in the column name
+	names []string
+	// categories the column type must have
+	categories []string
+}
+
+var piiNameRules = []piiNameRule{
+	{models.PIITypeEmail, []string{"email", "e_mail", "mail_address", "email_address"}, []string{"string"}},
+	{models.PIITypePhone, []string{"phone", "telephone", "mobile", "cell_phone", "msisdn", "fax"}, []string{"string", "integer", "decimal"}},
+	{models.PIITypeCardNumber, []string{"card_number", "card_no", "card_num", "cc_number", "credit_card", "creditcard", "pan"}, []string{"string", "integer", "decimal"}},
+	{models.PIITypeNationalID, []string{"ssn", "social_security", "national_id", "national_insurance", "nino", "passport", "passport_number", "tax_id", "taxpayer_id", "tin", "id_number"}, []string{"string", "integer", "decimal"}},
+	{models.PIITypeIPAddress, []string{"ip", "ip_address", "ip_addr", "ipaddress", "remote_addr", "remote_address", "client_ip"}, []string{"string", "other"}},
+	{models.PIITypeName, []string{"first_name", "firstname", "last_name", "lastname", "full_name", "fullname", "surname", "given_name", "family_name", "middle_name", "maiden_name"}, []string{"string"}},
+}
+
+// personTables are table name tokens whose bare "name" column is a person's name
+var personTables = []string{"user", "users", "customer", "customers", "employee", "employees", "contact", "contacts",
+	"person", "persons", "people", "member", "members", "patient", "patients", "student", "students", "account", "accounts"}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`)
+	phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ().-]{5,}[0-9]$`)
+	ssnPattern   = regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)
+	ninoPattern  = regexp.MustCompile(`^[A-CEGHJ-PR-TW-Z]{2}\d{6}[A-D]$`)
+)
+
+// ClassifyColumn classifies a column from its name, type and sampled values.
+// It returns nil when the column is unlikely to hold personal data.
+func ClassifyColumn(tableName string, column *models.DatabaseColumn, samples []string) *PIIClassification {
+	category := column.GetDataTypeCategory()
+	dataType := strings.ToLower(column.DataType)
+
+	// Network address types are IP addresses whatever they are called
+	if dataType == "inet" || dataType == "cidr" {
+		return &PIIClassification{Type: models.PIITypeIPAddress, Confidence: 0.8, Source: PIISourceType}
+	}
+
+	nameType, nameConfidence := classifyColumnName(tableName, column.Name, category)
+
+	var sampleType models.PIIType
+	var sampleRatio float64
+	if category == "string" || category == "integer" || category == "decimal" {
+		sampleType, sampleRatio = classifySamples(samples)
+	}
+
+	var result PIIClassification
+	switch {
+	case nameType != "" && sampleType == nameType:
+		result = PIIClassification{Type: nameType, Confidence: 0.95, Source: PIISourceNameAndSample}
+	case sampleType != "":
+		result = PIIClassification{Type: sampleType, Confidence: 0.5 + 0.4*sampleRatio, Source: PIISourceSample}
+	case nameType != "":
+		result = PIIClassification{Type: nameType, Confidence: nameConfidence, Source: PIISourceName}
+		// Values that contradict a name with a checkable format lower the confidence
+		if len(samples) > 0 && nameType != models.PIITypeName {
+			result.Confidence /= 2
+		}
+	}
+
+	if result.Type == "" || result.Confidence < piiConfidenceThreshold {
+		return nil
+	}
+	return &result
+}
+
+// NeedsPIISample reports whether sampled values could change a column's classification
+func NeedsPIISample(column *models.DatabaseColumn) bool {
+	switch column.GetDataTypeCategory() {
+	case "string":
+		return true
+	case "integer", "decimal":
+		// Numbers are only sampled when the name already suggests phone or card numbers
+		nameType, _ := classifyColumnName("", column.Name, column.GetDataTypeCategory())
+		return nameType == models.PIITypePhone || nameType == models.PIITypeCardNumber
+	default:
+		return false
+	}
+}
+
+// classifyColumnName matches a column name against the PII name rules
+func classifyColumnName(tableName, columnName, category string) (models.PIIType, float64) {
+	tokens := nameTokens(columnName)
+	joined := "_" + strings.Join(tokens, "_") + "_"
+
+	for _, rule := range piiNameRules {
+		if !containsString(rule.categories, category) {
+			continue
+		}
+		for _, name := range rule.names {
+			if strings.Contains(joined, "_"+name+"_") {
+				return rule.piiType, 0.7
+			}
+		}
+	}
+
+	// A bare "name" is only a person's name in tables about people
+	if category == "string" && len(tokens) == 1 && tokens[0] == "name" {
+		for _, token := range nameTokens(tableName) {
+			if containsString(personTables, token) {
+				return models.PIITypeName, 0.6
+			}
+		}
+	}
+	return "", 0
+}
+
+// classifySamples finds the PII pattern most sampled values match
+func classifySamples(samples []string) (models.PIIType, float64) {
+	counts := make(map[models.PIIType]int)
+	total := 0
+	for _, sample := range samples {
+		sample = strings.TrimSpace(sample)
+		if sample == "" {
+			continue
+		}
+		total++
+		if piiType := matchPIIValue(sample); piiType != "" {
+			counts[piiType]++
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+
+	var best models.PIIType
+	bestCount := 0
+	for _, piiType := range []models.PIIType{models.PIITypeEmail, models.PIITypeCardNumber, models.PIITypeNationalID, models.PIITypeIPAddress, models.PIITypePhone} {
+		if counts[piiType] > bestCount {
+			best, bestCount = piiType, counts[piiType]
+		}
+	}
+
+	ratio := float64(bestCount) / float64(total)
+	if ratio < piiSampleMatchRatio {
+		return "", 0
+	}
+	return best, ratio
+}
+
+// matchPIIValue returns the kind of personal data a single value looks like
+func matchPIIValue(value string) models.PIIType {
+	switch {
+	case emailPattern.MatchString(value):
+		return models.PIITypeEmail
+	case ssnPattern.MatchString(value), ninoPattern.MatchString(strings.ToUpper(strings.ReplaceAll(value, " ", ""))):
+		return models.PIITypeNationalID
+	case net.ParseIP(value) != nil && strings.ContainsAny(value, ".:"):
+		return models.PIITypeIPAddress
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, value)
+
+	if len(digits) >= 13 && len(digits) <= 19 && strings.Trim(value, "0123456789 -") == "" && luhnValid(digits) {
+		return models.PIITypeCardNumber
+	}
+	// Plain digit runs are more likely identifiers than phone numbers
+	if len(digits) >= 7 && len(digits) <= 15 && phonePattern.MatchString(value) && strings.ContainsAny(value, "+ ()-.") {
+		return models.PIITypePhone
+	}
+	return ""
+}
+
+// luhnValid checks the Luhn checksum used by payment card numbers
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// nameTokens splits snake_case, kebab-case and camelCase names into lowercase words
+func nameTokens(name string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// containsString checks if a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmaskedPIIColumns lists the catalogued PII columns a backup writes in clear,
+// as "table.column". Columns masked by the backup's sanitize rules are left out.
+func UnmaskedPIIColumns(ctx context.Context, db *gorm.DB, conn *models.DatabaseConnection, options *BackupOptions) ([]string, error) {
+	if options == nil {
+		options = &BackupOptions{}
+	}
+	if options.SchemaOnly {
+		return nil, nil
+	}
+
+	var columns []models.DatabaseColumn
+	if err := db.WithContext(ctx).Preload("DatabaseTable").
+		Joins("JOIN database_tables ON database_tables.id = database_columns.database_table_id").
+		Where("database_tables.database_connection_id = ? AND database_tables.deleted_at IS NULL", conn.ID).
+		Where("database_columns.is_pii = ?", true).
+		Find(&columns).Error; err != nil {
+		return nil, fmt.Errorf("failed to load PII columns: %w", err)
+	}
+
+	requested := lowerSet(options.Tables)
+	excluded := lowerSet(options.ExcludeTables)
+
+	var unmasked []string
+	for _, column := range columns {
+		names := tableNames(&column.DatabaseTable)
+		if !coversTable(names, requested, excluded) {
+			continue
+		}
+		if isColumnMasked(options.Sanitize, &column.DatabaseTable, column.Name) {
+			continue
+		}
+		unmasked = append(unmasked, column.DatabaseTable.GetFullName()+"."+column.Name)
+	}
+
+	sort.Strings(unmasked)
+	return unmasked, nil
+}
+
+// isColumnMasked checks if sanitize rules mask a column of a table
+func isColumnMasked(options *SanitizeOptions, table *models.DatabaseTable, column string) bool {
+	if options == nil {
+		return false
+	}
+
+	names := append(tableNames(table), strings.ToLower(table.Name))
+	for _, rule := range options.Rules {
+		if !containsString(names, strings.ToLower(rule.Table)) {
+			continue
+		}
+		for masked := range rule.Masks {
+			if strings.EqualFold(masked, column) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		column   string
+		dataType string
+		samples  []string
+		piiType  models.PIIType
+		source   string
+	}{
+		{"email by name", "users", "email", "varchar", nil, models.PIITypeEmail, PIISourceName},
+		{"email by name and sample", "users", "contactEmail", "text", []string{"ann@corp.io", "bo@mail.org"}, models.PIITypeEmail, PIISourceNameAndSample},
+		{"email by sample", "events", "recipient", "varchar", []string{"ann@corp.io", "bo@mail.org", "n/a"}, models.PIITypeEmail, PIISourceSample},
+		{"email flag is not an address", "users", "email_verified", "boolean", nil, "", ""},
+		{"hashed email values", "users", "email", "varchar", []string{"5f4dcc3b5aa765d6", "e99a18c428cb38d5"}, "", ""},
+		{"phone by sample", "leads", "contact", "varchar", []string{"+1 (555) 010-9999", "+44 20 7946 0958"}, models.PIITypePhone, PIISourceSample},
+		{"plain numbers are not phones", "orders", "reference", "varchar", []string{"1234567890", "2345678901"}, "", ""},
+		{"card number", "payments", "card_number", "varchar", []string{"4111 1111 1111 1111", "5500-0000-0000-0004"}, models.PIITypeCardNumber, PIISourceNameAndSample},
+		{"luhn failures are not cards", "payments", "tracking", "varchar", []string{"4111 1111 1111 1112"}, "", ""},
+		{"ssn", "employees", "ssn", "char", []string{"078-05-1120"}, models.PIITypeNationalID, PIISourceNameAndSample},
+		{"passport number", "travellers", "passportNumber", "varchar", nil, models.PIITypeNationalID, PIISourceName},
+		{"inet type", "sessions", "origin", "inet", nil, models.PIITypeIPAddress, PIISourceType},
+		{"ip by sample", "sessions", "remote", "varchar", []string{"10.0.0.1", "2001:db8::1"}, models.PIITypeIPAddress, PIISourceSample},
+		{"last name", "orders", "last_name", "varchar", nil, models.PIITypeName, PIISourceName},
+		{"bare name in people table", "customers", "name", "varchar", []string{"Ann"}, models.PIITypeName, PIISourceName},
+		{"bare name elsewhere", "products", "name", "varchar", []string{"Widget"}, "", ""},
+		{"tokens match whole words", "accounts", "shipping_time", "varchar", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			column := &models.DatabaseColumn{Name: tt.column, DataType: tt.dataType}
+			classification := ClassifyColumn(tt.table, column, tt.samples)
+			if tt.piiType == "" {
+				assert.Nil(t, classification)
+				return
+			}
+			require.NotNil(t, classification)
+			assert.Equal(t, tt.piiType, classification.Type)
+			assert.Equal(t, tt.source, classification.Source)
+			assert.GreaterOrEqual(t, classification.Confidence, piiConfidenceThreshold)
+		})
+	}
+}
+
+func TestNeedsPIISample(t *testing.T) {
+	assert.True(t, NeedsPIISample(&models.DatabaseColumn{Name: "notes", DataType: "text"}))
+	assert.True(t, NeedsPIISample(&models.DatabaseColumn{Name: "phone", DataType: "bigint"}))
+	assert.False(t, NeedsPIISample(&models.DatabaseColumn{Name: "quantity", DataType: "integer"}))
+	assert.False(t, NeedsPIISample(&models.DatabaseColumn{Name: "created_at", DataType: "timestamp"}))
+}
+
+func TestUnmaskedPIIColumns(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.DatabaseColumn{}))
+
+	conn := &models.DatabaseConnection{Name: "App", Type: models.DatabaseTypePostgreSQL, Host: "localhost", Port: 5432, Database: "app", Username: "app", UserID: 1}
+	require.NoError(t, db.Create(conn).Error)
+
+	users := models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now(),
+		Columns: []models.DatabaseColumn{
+			{Name: "id", DataType: "integer", OrdinalPosition: 1},
+			{Name: "email", DataType: "varchar", OrdinalPosition: 2},
+			{Name: "phone", DataType: "varchar", OrdinalPosition: 3},
+		}}
+	users.Columns[1].SetPIIClassification(models.PIITypeEmail, 0.95, PIISourceNameAndSample)
+	users.Columns[2].SetPIIClassification(models.PIITypePhone, 0.7, PIISourceName)
+	ledger := models.DatabaseTable{Name: "ledger", Schema: "finance", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now(),
+		Columns: []models.DatabaseColumn{{Name: "iban", DataType: "varchar", OrdinalPosition: 1}}}
+	ledger.Columns[0].SetPIIClassification(models.PIITypeNationalID, 0.7, PIISourceName)
+	require.NoError(t, db.Create(&users).Error)
+	require.NoError(t, db.Create(&ledger).Error)
+
+	ctx := context.Background()
+
+	columns, err := UnmaskedPIIColumns(ctx, db, conn, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"finance.ledger.iban", "users.email", "users.phone"}, columns)
+
+	// Masked columns and excluded tables are not written in clear
+	columns, err = UnmaskedPIIColumns(ctx, db, conn, &BackupOptions{
+		ExcludeTables: []string{"finance.ledger"},
+		Sanitize: &SanitizeOptions{Rules: []TableSanitizeRule{
+			{Table: "public.users", Masks: map[string]MaskRule{"EMAIL": {Strategy: MaskHash}}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users.phone"}, columns)
+
+	columns, err = UnmaskedPIIColumns(ctx, db, conn, &BackupOptions{SchemaOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, columns)
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// DefaultJobPageSize is the number of jobs listed per page
+	DefaultJobPageSize = 50
+	// MaxJobPageSize bounds the number of jobs listed per page
+	MaxJobPageSize = 200
+	// PayloadPreviewLength is the length payload previews are cut to
+	PayloadPreviewLength = 256
+)
+
+var (
+	// ErrQueueNotFound is returned for queues that hold no tasks and were never used
+	ErrQueueNotFound = errors.New("queue not found")
+
+	// ErrJobNotFound is returned when a task is not in the expected queue and state
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// payloadSecretKeys are payload keys whose values are hidden from previews
+var payloadSecretKeys = []string{"password", "secret", "token", "credential", "private_key", "access_key", "api_key"}
+
+// QueueAdmin inspects and maintains the job queues
+type QueueAdmin interface {
+	GetQueueStats(ctx context.Context) (*QueueStats, error)
+	ListQueueJobs(ctx context.Context, queue string, state JobState, page, pageSize int) ([]*JobInfo, error)
+	PauseQueue(ctx context.Context, queue string) error
+	UnpauseQueue(ctx context.Context, queue string) error
+	RetryArchivedJobs(ctx context.Context, queue string, ids []string) (int, error)
+	ArchiveRetryJobs(ctx context.Context, queue string, ids []string) (int, error)
+	ListWorkers(ctx context.Context) ([]*WorkerHeartbeat, error)
+}
+
+var _ QueueAdmin = (*QueueService)(nil)
+
+// WorkerHeartbeat describes a worker process that recently reported itself alive
+type WorkerHeartbeat struct {
+	ID          string         `json:"id"`
+	Host        string         `json:"host"`
+	PID         int            `json:"pid"`
+	Concurrency int            `json:"concurrency"`
+	Queues      map[string]int `json:"queues"`
+	Status      string         `json:"status"`
+	StartedAt   time.Time      `json:"started_at"`
+	ActiveTasks []*ActiveTask  `json:"active_tasks"`
+}
+
+// ActiveTask is a task a worker is processing
+type ActiveTask struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Queue     string    `json:"queue"`
+	StartedAt time.Time `json:"started_at"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// ListQueueJobs lists a page of a queue's jobs in a state, with payload previews
+// instead of full payloads
+func (qs *QueueService) ListQueueJobs(ctx context.Context, queue string, state JobState, page, pageSize int) ([]*JobInfo, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultJobPageSize
+	}
+	if pageSize > MaxJobPageSize {
+		pageSize = MaxJobPageSize
+	}
+	opts := []asynq.ListOption{asynq.Page(page), asynq.PageSize(pageSize)}
+
+	var tasks []*asynq.TaskInfo
+	var err error
+	switch state {
+	case JobStatePending:
+		tasks, err = qs.inspector.ListPendingTasks(queue, opts...)
+	case JobStateActive:
+		tasks, err = qs.inspector.ListActiveTasks(queue, opts...)
+	case JobStateScheduled:
+		tasks, err = qs.inspector.ListScheduledTasks(queue, opts...)
+	case JobStateRetry:
+		tasks, err = qs.inspector.ListRetryTasks(queue, opts...)
+	case JobStateArchived:
+		tasks, err = qs.inspector.ListArchivedTasks(queue, opts...)
+	case JobStateCompleted:
+		tasks, err = qs.inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported job state: %s", state)
+	}
+	if err != nil {
+		return nil, queueError(queue, err)
+	}
+
+	jobs := make([]*JobInfo, len(tasks))
+	for i, task := range tasks {
+		job := qs.convertTaskInfoFromInspector(task)
+		job.Payload = nil
+		job.PayloadPreview = PayloadPreview(task.Payload, PayloadPreviewLength)
+		jobs[i] = job
+	}
+	return jobs, nil
+}
+
+// RetryArchivedJobs runs archived jobs of a queue again. Without IDs every
+// archived job of the queue is retried.
+func (qs *QueueService) RetryArchivedJobs(ctx context.Context, queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		count, err := qs.inspector.RunAllArchivedTasks(queue)
+		if err != nil {
+			return 0, queueError(queue, err)
+		}
+		return count, nil
+	}
+	return qs.applyToTasks(queue, ids, asynq.TaskStateArchived, qs.inspector.RunTask)
+}
+
+// ArchiveRetryJobs stops retrying jobs of a queue and archives them. Without
+// IDs every job waiting for a retry is archived.
+func (qs *QueueService) ArchiveRetryJobs(ctx context.Context, queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		count, err := qs.inspector.ArchiveAllRetryTasks(queue)
+		if err != nil {
+			return 0, queueError(queue, err)
+		}
+		return count, nil
+	}
+	return qs.applyToTasks(queue, ids, asynq.TaskStateRetry, qs.inspector.ArchiveTask)
+}
+
+// applyToTasks runs an operation on tasks of a queue that are in the expected state
+func (qs *QueueService) applyToTasks(queue string, ids []string, state asynq.TaskState, operation func(queue, id string) error) (int, error) {
+	count := 0
+	for _, id := range ids {
+		task, err := qs.inspector.GetTaskInfo(queue, id)
+		if err != nil {
+			return count, queueError(queue, err)
+		}
+		if task.State != state {
+			return count, fmt.Errorf("%w: %s is %s", ErrJobNotFound, id, task.State)
+		}
+		if err := operation(queue, id); err != nil {
+			return count, queueError(queue, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ListWorkers lists the worker processes whose heartbeat has not expired
+func (qs *QueueService) ListWorkers(ctx context.Context) ([]*WorkerHeartbeat, error) {
+	servers, err := qs.inspector.Servers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+
+	workers := make([]*WorkerHeartbeat, len(servers))
+	for i, server := range servers {
+		worker := &WorkerHeartbeat{
+			ID:          server.ID,
+			Host:        server.Host,
+			PID:         server.PID,
+			Concurrency: server.Concurrency,
+			Queues:      server.Queues,
+			Status:      server.Status,
+			StartedAt:   server.Started,
+			ActiveTasks: make([]*ActiveTask, len(server.ActiveWorkers)),
+		}
+		for j, active := range server.ActiveWorkers {
+			worker.ActiveTasks[j] = &ActiveTask{
+				ID:        active.TaskID,
+				Type:      active.TaskType,
+				Queue:     active.Queue,
+				StartedAt: active.Started,
+				Deadline:  active.Deadline,
+			}
+		}
+		workers[i] = worker
+	}
+	return workers, nil
+}
+
+// queueError maps inspector errors to the service's errors
+func queueError(queue string, err error) error {
+	switch {
+	case errors.Is(err, asynq.ErrQueueNotFound):
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+	case errors.Is(err, asynq.ErrTaskNotFound):
+		return fmt.Errorf("%w: %v", ErrJobNotFound, err)
+	default:
+		return fmt.Errorf("queue %s: %w", queue, err)
+	}
+}
+
+// PayloadPreview renders a task payload for display: secrets are masked and
+// the result is cut to at most limit characters
+func PayloadPreview(payload []byte, limit int) string {
+	if len(payload) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		if !utf8.Valid(payload) {
+			return fmt.Sprintf("<%d bytes>", len(payload))
+		}
+		return truncatePreview(string(payload), limit)
+	}
+
+	redacted, err := json.Marshal(redactPayload(value))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(payload))
+	}
+	return truncatePreview(string(redacted), limit)
+}
+
+// redactPayload masks the values of secret keys in decoded JSON
+func redactPayload(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if isSecretKey(key) {
+				v[key] = "***"
+				continue
+			}
+			v[key] = redactPayload(nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactPayload(nested)
+		}
+	}
+	return value
+}
+
+// isSecretKey checks if a payload key names a secret
+func isSecretKey(key string) bool {
+	normalized := strings.Join(nameTokens(key), "_")
+	for _, secret := range payloadSecretKeys {
+		if strings.Contains(normalized, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncatePreview cuts a preview to limit characters
+func truncatePreview(preview string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(preview) <= limit {
+		return preview
+	}
+	runes := []rune(preview)
+	return string(runes[:limit]) + "…"
+}
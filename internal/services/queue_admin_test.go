@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadPreview(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"plain values", `{"backup_job_id":7}`, `{"backup_job_id":7}`},
+		{
+			"secrets are masked",
+			`{"connection":{"password":"hunter2","dbPassword":"x"},"accessKeyId":"AKIA","api_token":"t","tables":["users"]}`,
+			`{"accessKeyId":"***","api_token":"***","connection":{"dbPassword":"***","password":"***"},"tables":["users"]}`,
+		},
+		{"secrets in lists", `[{"secret_key":"s"}]`, `[{"secret_key":"***"}]`},
+		{"not json", "raw payload", "raw payload"},
+		{"binary", "\xff\xfe\x00", "<3 bytes>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PayloadPreview([]byte(tt.payload), PayloadPreviewLength))
+		})
+	}
+
+	long := `{"tables":["` + strings.Repeat("a", 300) + `"]}`
+	preview := PayloadPreview([]byte(long), 20)
+	assert.Equal(t, 21, len([]rune(preview)))
+	assert.True(t, strings.HasSuffix(preview, "…"))
+}
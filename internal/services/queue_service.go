@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
 )
@@ -52,6 +53,14 @@ type QueueConfig struct {
 	RedisDB       int
 	Concurrency   int
 	Queues        map[string]int // queue name -> priority
+
+	// ShutdownTimeout is how long a stopping worker lets running jobs
+	// finish before they are requeued; zero keeps asynq's default
+	ShutdownTimeout time.Duration
+
+	// RetryDelays are the delays before retrying failed tasks of a type, by
+	// retry count; other tasks use asynq's default
+	RetryDelays map[string]func(n int, err error) time.Duration
 }
 
 // JobState represents the state of a job
@@ -69,20 +78,21 @@ const (
 
 // JobInfo contains information about a job
 type JobInfo struct {
-	ID          string                 `json:"id"`
-	Type        string                 `json:"type"`
-	Payload     map[string]interface{} `json:"payload"`
-	State       JobState               `json:"state"`
-	Queue       string                 `json:"queue"`
-	MaxRetry    int                    `json:"max_retry"`
-	Retried     int                    `json:"retried"`
-	ProcessedAt *time.Time             `json:"processed_at,omitempty"`
-	FailedAt    *time.Time             `json:"failed_at,omitempty"`
-	CompletedAt *time.Time             `json:"completed_at,omitempty"`
-	NextRunAt   *time.Time             `json:"next_run_at,omitempty"`
-	Timeout     time.Duration          `json:"timeout"`
-	Deadline    *time.Time             `json:"deadline,omitempty"`
-	ErrorMsg    string                 `json:"error_msg,omitempty"`
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	Payload        map[string]interface{} `json:"payload,omitempty"`
+	PayloadPreview string                 `json:"payload_preview,omitempty"`
+	State          JobState               `json:"state"`
+	Queue          string                 `json:"queue"`
+	MaxRetry       int                    `json:"max_retry"`
+	Retried        int                    `json:"retried"`
+	ProcessedAt    *time.Time             `json:"processed_at,omitempty"`
+	FailedAt       *time.Time             `json:"failed_at,omitempty"`
+	CompletedAt    *time.Time             `json:"completed_at,omitempty"`
+	NextRunAt      *time.Time             `json:"next_run_at,omitempty"`
+	Timeout        time.Duration          `json:"timeout"`
+	Deadline       *time.Time             `json:"deadline,omitempty"`
+	ErrorMsg       string                 `json:"error_msg,omitempty"`
 }
 
 // QueueStats contains queue statistics
@@ -492,14 +502,14 @@ func (qs *QueueService) Close() error {
 
 // Shutdown gracefully shuts down the queue service
 func (qs *QueueService) Shutdown(ctx context.Context) error {
-	log.Println("Shutting down queue service...")
+	slog.InfoContext(ctx, "Shutting down queue service")
 	
 	// Wait for any pending operations to complete or timeout
 	select {
 	case <-ctx.Done():
-		log.Println("Queue service shutdown timed out, forcing close")
+		slog.WarnContext(ctx, "Queue service shutdown timed out, forcing close")
 	case <-time.After(2 * time.Second):
-		log.Println("Queue service shutdown grace period completed")
+		slog.InfoContext(ctx, "Queue service shutdown grace period completed")
 	}
 	
 	// Close the service
@@ -598,6 +608,12 @@ func (qs *QueueService) convertTaskInfoFromInspector(taskInfo *asynq.TaskInfo) *
 	if !taskInfo.Deadline.IsZero() {
 		job.Deadline = &taskInfo.Deadline
 	}
+	if !taskInfo.LastFailedAt.IsZero() {
+		job.FailedAt = &taskInfo.LastFailedAt
+	}
+	if !taskInfo.CompletedAt.IsZero() {
+		job.CompletedAt = &taskInfo.CompletedAt
+	}
 
 	return job
 }
@@ -630,10 +646,13 @@ func NewQueueWorker(config *QueueConfig) *QueueWorker {
 	}
 
 	server := asynq.NewServer(redisOpt, asynq.Config{
-		Concurrency: config.Concurrency,
-		Queues:      config.Queues,
+		Concurrency:     config.Concurrency,
+		Queues:          config.Queues,
+		ShutdownTimeout: config.ShutdownTimeout,
+		RetryDelayFunc:  retryDelay(config.RetryDelays),
 		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-			log.Printf("Error processing task %s: %v", task.Type(), err)
+			taskID, _ := asynq.GetTaskID(ctx)
+			slog.ErrorContext(ctx, "Error processing task", logging.KeyTaskID, taskID, logging.KeyTaskType, task.Type(), "error", err)
 		}),
 	})
 
@@ -645,14 +664,30 @@ func NewQueueWorker(config *QueueConfig) *QueueWorker {
 	}
 }
 
+// retryDelay picks the retry delay of a task by its type
+func retryDelay(delays map[string]func(n int, err error) time.Duration) asynq.RetryDelayFunc {
+	return func(n int, err error, task *asynq.Task) time.Duration {
+		if delay, ok := delays[task.Type()]; ok {
+			return delay(n, err)
+		}
+		return asynq.DefaultRetryDelayFunc(n, err, task)
+	}
+}
+
 // RegisterHandler registers a handler for a specific job type
 func (qw *QueueWorker) RegisterHandler(jobType string, handler func(context.Context, *asynq.Task) error) {
 	qw.mux.HandleFunc(jobType, handler)
 }
 
-// Start starts the worker
+// ProcessTask runs a task through the handler registered for its type, as the
+// server does for tasks it takes from a queue
+func (qw *QueueWorker) ProcessTask(ctx context.Context, task *asynq.Task) error {
+	return qw.mux.ProcessTask(ctx, task)
+}
+
+// Start starts processing jobs in the background until Shutdown
 func (qw *QueueWorker) Start() error {
-	return qw.server.Run(qw.mux)
+	return qw.server.Start(qw.mux)
 }
 
 // Stop stops the worker
@@ -660,7 +695,8 @@ func (qw *QueueWorker) Stop() {
 	qw.server.Stop()
 }
 
-// Shutdown gracefully shuts down the worker
+// Shutdown gracefully shuts down the worker. Jobs still running after the
+// shutdown timeout are requeued and their context is cancelled.
 func (qw *QueueWorker) Shutdown() {
 	qw.server.Shutdown()
 }
\ No newline at end of file
@@ -0,0 +1,581 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// Risk levels assigned to audit events
+const (
+	RiskLevelLow      = "low"
+	RiskLevelMedium   = "medium"
+	RiskLevelHigh     = "high"
+	RiskLevelCritical = "critical"
+)
+
+// riskLevelRank orders risk levels so the highest finding wins
+var riskLevelRank = map[string]int{
+	RiskLevelLow:      0,
+	RiskLevelMedium:   1,
+	RiskLevelHigh:     2,
+	RiskLevelCritical: 3,
+}
+
+// RiskFinding describes a single rule match on an audit event
+type RiskFinding struct {
+	Rule   string `json:"rule"`
+	Level  string `json:"level"`
+	Reason string `json:"reason"`
+	Block  bool   `json:"block"`
+}
+
+// RiskAssessment is the combined result of evaluating all rules on an event
+type RiskAssessment struct {
+	Level    string        `json:"level"`
+	Findings []RiskFinding `json:"findings"`
+	Blocked  bool          `json:"blocked"`
+}
+
+// IsSuspicious returns true if any rule matched
+func (ra *RiskAssessment) IsSuspicious() bool {
+	return len(ra.Findings) > 0
+}
+
+// RiskRule evaluates an audit event against the user's recent history
+type RiskRule interface {
+	Name() string
+	// Lookback returns how far back in history the rule needs to look
+	Lookback() time.Duration
+	// Evaluate returns a finding, or nil if the event does not match.
+	// The engine sets the event's CreatedAt from its clock when it is unset.
+	Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding
+}
+
+// AuditHistoryProvider returns previous audit events related to an event
+type AuditHistoryProvider interface {
+	RecentEvents(ctx context.Context, event *models.AuditLog, since time.Time) ([]models.AuditLog, error)
+}
+
+// RiskNotifier is notified when an event is flagged as suspicious
+type RiskNotifier interface {
+	NotifyRisk(ctx context.Context, event *models.AuditLog, assessment *RiskAssessment) error
+}
+
+// RiskEngine scores audit events using a set of heuristic rules
+type RiskEngine struct {
+	rules    []RiskRule
+	history  AuditHistoryProvider
+	notifier RiskNotifier
+	now      func() time.Time
+}
+
+// NewRiskEngine creates a risk engine with the default rules built from configuration
+func NewRiskEngine(cfg config.RiskConfig, history AuditHistoryProvider, notifier RiskNotifier) *RiskEngine {
+	return NewRiskEngineWithRules(DefaultRiskRules(cfg), history, notifier)
+}
+
+// NewRiskEngineWithRules creates a risk engine with an explicit rule set
+func NewRiskEngineWithRules(rules []RiskRule, history AuditHistoryProvider, notifier RiskNotifier) *RiskEngine {
+	return &RiskEngine{
+		rules:    rules,
+		history:  history,
+		notifier: notifier,
+		now:      time.Now,
+	}
+}
+
+// DefaultRiskRules returns the built-in rules enabled by configuration
+func DefaultRiskRules(cfg config.RiskConfig) []RiskRule {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	location := time.UTC
+	if cfg.BusinessTimezone != "" {
+		if loc, err := time.LoadLocation(cfg.BusinessTimezone); err == nil {
+			location = loc
+		}
+	}
+
+	return []RiskRule{
+		&ImpossibleTravelRule{
+			MaxSpeedKmh: cfg.MaxTravelSpeedKmh,
+			Window:      24 * time.Hour,
+			Block:       cfg.BlockImpossibleTravel,
+		},
+		&FailedLoginBurstRule{
+			Threshold: cfg.FailedLoginThreshold,
+			Window:    cfg.FailedLoginWindow,
+			Block:     cfg.BlockFailedLogins,
+		},
+		&MassDownloadRule{
+			Threshold: cfg.MassDownloadThreshold,
+			Window:    cfg.MassDownloadWindow,
+			Block:     cfg.BlockMassDownloads,
+		},
+		&OffHoursRestoreRule{
+			StartHour: cfg.BusinessHoursStart,
+			EndHour:   cfg.BusinessHoursEnd,
+			Location:  location,
+			Block:     cfg.BlockOffHoursRestores,
+		},
+		&NewDeviceRule{
+			Window: cfg.NewDeviceLookback,
+		},
+	}
+}
+
+// SetNotifier sets who is told about suspicious events. It must be called
+// before events are assessed.
+func (re *RiskEngine) SetNotifier(notifier RiskNotifier) {
+	re.notifier = notifier
+}
+
+// Rules returns the configured rules
+func (re *RiskEngine) Rules() []RiskRule {
+	return re.rules
+}
+
+// Evaluate runs all rules against the event without modifying it
+func (re *RiskEngine) Evaluate(ctx context.Context, event *models.AuditLog) (*RiskAssessment, error) {
+	assessment := &RiskAssessment{Level: RiskLevelLow}
+	if len(re.rules) == 0 {
+		return assessment, nil
+	}
+
+	// Rules measure windows from the event time, which unsaved events take from the engine's clock
+	if event.CreatedAt.IsZero() {
+		stamped := *event
+		stamped.CreatedAt = re.now()
+		event = &stamped
+	}
+
+	var history []models.AuditLog
+	if re.history != nil {
+		var lookback time.Duration
+		for _, rule := range re.rules {
+			if rule.Lookback() > lookback {
+				lookback = rule.Lookback()
+			}
+		}
+
+		var err error
+		history, err = re.history.RecentEvents(ctx, event, event.CreatedAt.Add(-lookback))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audit history: %w", err)
+		}
+	}
+
+	for _, rule := range re.rules {
+		finding := rule.Evaluate(event, history)
+		if finding == nil {
+			continue
+		}
+		if finding.Rule == "" {
+			finding.Rule = rule.Name()
+		}
+		assessment.Findings = append(assessment.Findings, *finding)
+		if riskLevelRank[finding.Level] > riskLevelRank[assessment.Level] {
+			assessment.Level = finding.Level
+		}
+		if finding.Block {
+			assessment.Blocked = true
+		}
+	}
+
+	return assessment, nil
+}
+
+// Assess evaluates the event, applies the result to it and sends notifications
+func (re *RiskEngine) Assess(ctx context.Context, event *models.AuditLog) (*RiskAssessment, error) {
+	assessment, err := re.Evaluate(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	ApplyRiskAssessment(event, assessment)
+
+	if assessment.IsSuspicious() && re.notifier != nil {
+		if err := re.notifier.NotifyRisk(ctx, event, assessment); err != nil {
+			slog.ErrorContext(ctx, "Failed to send risk notification", "action", event.Action, "error", err)
+		}
+	}
+
+	return assessment, nil
+}
+
+// ApplyRiskAssessment copies the assessment result onto an audit event
+func ApplyRiskAssessment(event *models.AuditLog, assessment *RiskAssessment) {
+	if event.RiskLevel == "" {
+		event.RiskLevel = RiskLevelLow
+	}
+	if !assessment.IsSuspicious() {
+		return
+	}
+
+	reasons := make([]string, 0, len(assessment.Findings))
+	rules := make([]string, 0, len(assessment.Findings))
+	for _, finding := range assessment.Findings {
+		reasons = append(reasons, finding.Reason)
+		rules = append(rules, finding.Rule)
+	}
+	reason := strings.Join(reasons, "; ")
+
+	event.MarkAsSuspicious(reason)
+	if assessment.Blocked {
+		event.Block(reason)
+	}
+	if riskLevelRank[assessment.Level] > riskLevelRank[event.RiskLevel] {
+		event.RiskLevel = assessment.Level
+	}
+	event.SetMetadata("risk_rules", rules)
+}
+
+// ImpossibleTravelRule flags logins from locations too far apart to be reached in time
+type ImpossibleTravelRule struct {
+	MaxSpeedKmh float64
+	Window      time.Duration
+	Block       bool
+}
+
+// Name returns the rule name
+func (r *ImpossibleTravelRule) Name() string { return "impossible_travel" }
+
+// Lookback returns the history window used by the rule
+func (r *ImpossibleTravelRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate compares the login location with the most recent successful login
+func (r *ImpossibleTravelRule) Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding {
+	if !isSuccessfulLogin(event) || r.MaxSpeedKmh <= 0 {
+		return nil
+	}
+	lat, lon, ok := eventCoordinates(event)
+	if !ok {
+		return nil
+	}
+
+	current := event.CreatedAt
+	var previous *models.AuditLog
+	for i := range history {
+		h := &history[i]
+		if !isSuccessfulLogin(h) || !h.IsFromSameUser(event) || h.IPAddress == event.IPAddress {
+			continue
+		}
+		if _, _, ok := eventCoordinates(h); !ok || h.CreatedAt.After(current) {
+			continue
+		}
+		if previous == nil || h.CreatedAt.After(previous.CreatedAt) {
+			previous = h
+		}
+	}
+	if previous == nil {
+		return nil
+	}
+
+	prevLat, prevLon, _ := eventCoordinates(previous)
+	distance := haversineKm(prevLat, prevLon, lat, lon)
+	elapsed := current.Sub(previous.CreatedAt).Hours()
+	if elapsed <= 0 {
+		elapsed = 1.0 / 3600
+	}
+
+	speed := distance / elapsed
+	if speed <= r.MaxSpeedKmh {
+		return nil
+	}
+
+	return &RiskFinding{
+		Rule:   r.Name(),
+		Level:  RiskLevelHigh,
+		Reason: fmt.Sprintf("login %.0f km from previous login (%s) within %s", distance, previous.IPAddress, current.Sub(previous.CreatedAt).Round(time.Minute)),
+		Block:  r.Block,
+	}
+}
+
+// FailedLoginBurstRule flags repeated failed logins from one user or IP address
+type FailedLoginBurstRule struct {
+	Threshold int
+	Window    time.Duration
+	Block     bool
+}
+
+// Name returns the rule name
+func (r *FailedLoginBurstRule) Name() string { return "failed_login_burst" }
+
+// Lookback returns the history window used by the rule
+func (r *FailedLoginBurstRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate counts failed logins in the window including the current event
+func (r *FailedLoginBurstRule) Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding {
+	if event.Action != models.AuditActionLogin || r.Threshold <= 0 {
+		return nil
+	}
+
+	since := event.CreatedAt.Add(-r.Window)
+	failures := 0
+	if event.IsError() {
+		failures++
+	}
+	for i := range history {
+		h := &history[i]
+		if h.Action != models.AuditActionLogin || !h.IsError() || h.CreatedAt.Before(since) {
+			continue
+		}
+		if h.IPAddress == event.IPAddress || h.IsFromSameUser(event) {
+			failures++
+		}
+	}
+
+	if failures < r.Threshold {
+		return nil
+	}
+
+	level := RiskLevelHigh
+	if failures >= r.Threshold*2 {
+		level = RiskLevelCritical
+	}
+
+	return &RiskFinding{
+		Rule:   r.Name(),
+		Level:  level,
+		Reason: fmt.Sprintf("%d failed logins within %s", failures, r.Window),
+		Block:  r.Block,
+	}
+}
+
+// MassDownloadRule flags users downloading many backup files in a short period
+type MassDownloadRule struct {
+	Threshold int
+	Window    time.Duration
+	Block     bool
+}
+
+// Name returns the rule name
+func (r *MassDownloadRule) Name() string { return "mass_download" }
+
+// Lookback returns the history window used by the rule
+func (r *MassDownloadRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate counts backup file downloads by the same user in the window
+func (r *MassDownloadRule) Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding {
+	if !isBackupDownload(event) || r.Threshold <= 0 || event.UserID == nil {
+		return nil
+	}
+
+	since := event.CreatedAt.Add(-r.Window)
+	downloads := 1
+	for i := range history {
+		h := &history[i]
+		if isBackupDownload(h) && h.IsFromSameUser(event) && !h.CreatedAt.Before(since) {
+			downloads++
+		}
+	}
+
+	if downloads < r.Threshold {
+		return nil
+	}
+
+	return &RiskFinding{
+		Rule:   r.Name(),
+		Level:  RiskLevelHigh,
+		Reason: fmt.Sprintf("%d backup downloads within %s", downloads, r.Window),
+		Block:  r.Block,
+	}
+}
+
+// OffHoursRestoreRule flags restores to production databases outside business hours
+type OffHoursRestoreRule struct {
+	StartHour int
+	EndHour   int
+	Location  *time.Location
+	Block     bool
+}
+
+// Name returns the rule name
+func (r *OffHoursRestoreRule) Name() string { return "off_hours_production_restore" }
+
+// Lookback returns the history window used by the rule
+func (r *OffHoursRestoreRule) Lookback() time.Duration { return 0 }
+
+// Evaluate checks the restore time against business hours on weekdays
+func (r *OffHoursRestoreRule) Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding {
+	if event.Action != models.AuditActionRestore || !isProductionTarget(event) {
+		return nil
+	}
+
+	location := r.Location
+	if location == nil {
+		location = time.UTC
+	}
+	local := event.CreatedAt.In(location)
+
+	weekend := local.Weekday() == time.Saturday || local.Weekday() == time.Sunday
+	if !weekend && local.Hour() >= r.StartHour && local.Hour() < r.EndHour {
+		return nil
+	}
+
+	return &RiskFinding{
+		Rule:   r.Name(),
+		Level:  RiskLevelHigh,
+		Reason: fmt.Sprintf("production restore outside business hours (%s)", local.Format("Mon 15:04 MST")),
+		Block:  r.Block,
+	}
+}
+
+// NewDeviceRule flags successful logins from a device not seen before
+type NewDeviceRule struct {
+	Window time.Duration
+}
+
+// Name returns the rule name
+func (r *NewDeviceRule) Name() string { return "new_device_login" }
+
+// Lookback returns the history window used by the rule
+func (r *NewDeviceRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate compares the device fingerprint with previous successful logins
+func (r *NewDeviceRule) Evaluate(event *models.AuditLog, history []models.AuditLog) *RiskFinding {
+	if !isSuccessfulLogin(event) || event.UserID == nil {
+		return nil
+	}
+	device := deviceFingerprint(event)
+	if device == "" {
+		return nil
+	}
+
+	knownUser := false
+	for i := range history {
+		h := &history[i]
+		if !isSuccessfulLogin(h) || !h.IsFromSameUser(event) {
+			continue
+		}
+		knownUser = true
+		if deviceFingerprint(h) == device {
+			return nil
+		}
+	}
+
+	// First login for a user has nothing to compare against
+	if !knownUser {
+		return nil
+	}
+
+	return &RiskFinding{
+		Rule:   r.Name(),
+		Level:  RiskLevelMedium,
+		Reason: "login from a new device",
+	}
+}
+
+// GormAuditHistoryProvider loads audit history from the database
+type GormAuditHistoryProvider struct {
+	db *gorm.DB
+}
+
+// NewGormAuditHistoryProvider creates a database-backed history provider
+func NewGormAuditHistoryProvider(db *gorm.DB) *GormAuditHistoryProvider {
+	return &GormAuditHistoryProvider{db: db}
+}
+
+// RecentEvents returns events from the same user or IP address since the given time
+func (p *GormAuditHistoryProvider) RecentEvents(ctx context.Context, event *models.AuditLog, since time.Time) ([]models.AuditLog, error) {
+	query := p.db.WithContext(ctx).Where("created_at >= ?", since)
+	if event.UserID != nil {
+		query = query.Where("user_id = ? OR ip_address = ?", *event.UserID, event.IPAddress)
+	} else {
+		query = query.Where("ip_address = ?", event.IPAddress)
+	}
+	if event.ID != 0 {
+		query = query.Where("id <> ?", event.ID)
+	}
+
+	var events []models.AuditLog
+	if err := query.Order("created_at DESC").Limit(1000).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// isSuccessfulLogin checks if an event is a successful login
+func isSuccessfulLogin(event *models.AuditLog) bool {
+	return event.Action == models.AuditActionLogin && event.IsSuccess()
+}
+
+// isBackupDownload checks if an event is a successful backup file download
+func isBackupDownload(event *models.AuditLog) bool {
+	return event.Action == models.AuditActionDownload &&
+		event.Resource == models.AuditResourceBackupFile &&
+		!event.IsError()
+}
+
+// isProductionTarget checks the event metadata for a production environment marker
+func isProductionTarget(event *models.AuditLog) bool {
+	if env, ok := event.GetMetadata("environment"); ok {
+		if s, ok := env.(string); ok && strings.EqualFold(s, "production") {
+			return true
+		}
+	}
+	if prod, ok := event.GetMetadata("production"); ok {
+		if b, ok := prod.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
+// deviceFingerprint identifies the client device of an event
+func deviceFingerprint(event *models.AuditLog) string {
+	if id, ok := event.GetMetadata("device_id"); ok {
+		if s, ok := id.(string); ok && s != "" {
+			return s
+		}
+	}
+	if event.UserAgent != nil {
+		return *event.UserAgent
+	}
+	return ""
+}
+
+// eventCoordinates extracts latitude and longitude from the event location info
+func eventCoordinates(event *models.AuditLog) (float64, float64, bool) {
+	location := event.GetLocationInfo()
+	lat, latOK := toFloat(location["latitude"])
+	lon, lonOK := toFloat(location["longitude"])
+	return lat, lon, latOK && lonOK
+}
+
+// toFloat converts numeric metadata values to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// haversineKm returns the great-circle distance between two points in kilometers
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticHistory is an AuditHistoryProvider backed by a synthetic event stream
+type staticHistory struct {
+	events []models.AuditLog
+	err    error
+}
+
+func (h *staticHistory) RecentEvents(ctx context.Context, event *models.AuditLog, since time.Time) ([]models.AuditLog, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	var result []models.AuditLog
+	for _, e := range h.events {
+		if !e.CreatedAt.Before(since) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// recordingNotifier captures risk notifications
+type recordingNotifier struct {
+	events []*models.AuditLog
+}
+
+func (n *recordingNotifier) NotifyRisk(ctx context.Context, event *models.AuditLog, assessment *RiskAssessment) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func testRiskConfig() config.RiskConfig {
+	return config.RiskConfig{
+		Enabled:               true,
+		MaxTravelSpeedKmh:     900,
+		FailedLoginThreshold:  3,
+		FailedLoginWindow:     10 * time.Minute,
+		MassDownloadThreshold: 5,
+		MassDownloadWindow:    time.Hour,
+		BusinessHoursStart:    8,
+		BusinessHoursEnd:      18,
+		BusinessTimezone:      "UTC",
+		NewDeviceLookback:     90 * 24 * time.Hour,
+		BlockFailedLogins:     true,
+	}
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+func strPtr(v string) *string { return &v }
+
+func loginEvent(userID uint, ip string, status int, at time.Time) models.AuditLog {
+	return models.AuditLog{
+		Action:     models.AuditActionLogin,
+		Resource:   models.AuditResourceSession,
+		Method:     "POST",
+		Path:       "/api/auth/login",
+		IPAddress:  ip,
+		StatusCode: status,
+		UserID:     uintPtr(userID),
+		UserAgent:  strPtr("Mozilla/5.0 (X11; Linux x86_64)"),
+		RiskLevel:  RiskLevelLow,
+		CreatedAt:  at,
+	}
+}
+
+func withLocation(event models.AuditLog, lat, lon float64) models.AuditLog {
+	event.SetMetadata("location", map[string]interface{}{"latitude": lat, "longitude": lon})
+	return event
+}
+
+func TestRiskEngine_ImpossibleTravel(t *testing.T) {
+	now := time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC)
+	history := &staticHistory{events: []models.AuditLog{
+		withLocation(loginEvent(1, "81.2.69.160", 200, now.Add(-time.Hour)), 51.5074, -0.1278), // London
+	}}
+	engine := NewRiskEngine(testRiskConfig(), history, nil)
+
+	// Sydney one hour after London
+	event := withLocation(loginEvent(1, "1.128.0.1", 200, now), -33.8688, 151.2093)
+	assessment, err := engine.Assess(context.Background(), &event)
+	require.NoError(t, err)
+
+	require.Len(t, assessment.Findings, 1)
+	assert.Equal(t, "impossible_travel", assessment.Findings[0].Rule)
+	assert.Equal(t, RiskLevelHigh, event.RiskLevel)
+	assert.True(t, event.IsSuspicious)
+	assert.False(t, event.IsBlocked)
+
+	// Same trip over two days is plausible
+	history.events[0].CreatedAt = now.Add(-23 * time.Hour)
+	event = withLocation(loginEvent(1, "1.128.0.1", 200, now), -33.8688, 151.2093)
+	assessment, err = engine.Evaluate(context.Background(), &event)
+	require.NoError(t, err)
+	assert.Empty(t, assessment.Findings)
+}
+
+func TestRiskEngine_FailedLoginBurst(t *testing.T) {
+	now := time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC)
+	history := &staticHistory{events: []models.AuditLog{
+		loginEvent(1, "10.0.0.1", 401, now.Add(-2*time.Minute)),
+		loginEvent(1, "10.0.0.1", 401, now.Add(-time.Minute)),
+		loginEvent(1, "10.0.0.1", 401, now.Add(-time.Hour)), // outside window
+	}}
+	notifier := &recordingNotifier{}
+	engine := NewRiskEngine(testRiskConfig(), history, notifier)
+
+	event := loginEvent(1, "10.0.0.1", 401, now)
+	assessment, err := engine.Assess(context.Background(), &event)
+	require.NoError(t, err)
+
+	assert.True(t, assessment.Blocked)
+	assert.True(t, event.IsBlocked)
+	assert.Equal(t, RiskLevelHigh, event.RiskLevel)
+	assert.Len(t, notifier.events, 1)
+
+	reason, _ := event.GetMetadata("blocked_reason")
+	assert.Contains(t, reason, "3 failed logins")
+}
+
+func TestRiskEngine_MassDownload(t *testing.T) {
+	now := time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC)
+	download := func(at time.Time) models.AuditLog {
+		return models.AuditLog{
+			Action:     models.AuditActionDownload,
+			Resource:   models.AuditResourceBackupFile,
+			IPAddress:  "10.0.0.1",
+			StatusCode: 200,
+			UserID:     uintPtr(7),
+			CreatedAt:  at,
+		}
+	}
+
+	var events []models.AuditLog
+	for i := 1; i <= 3; i++ {
+		events = append(events, download(now.Add(-time.Duration(i)*time.Minute)))
+	}
+	history := &staticHistory{events: events}
+	engine := NewRiskEngine(testRiskConfig(), history, nil)
+
+	event := download(now)
+	assessment, err := engine.Evaluate(context.Background(), &event)
+	require.NoError(t, err)
+	assert.Empty(t, assessment.Findings)
+
+	history.events = append(history.events, download(now.Add(-5*time.Minute)))
+	assessment, err = engine.Evaluate(context.Background(), &event)
+	require.NoError(t, err)
+	require.Len(t, assessment.Findings, 1)
+	assert.Equal(t, "mass_download", assessment.Findings[0].Rule)
+}
+
+func TestRiskEngine_OffHoursProductionRestore(t *testing.T) {
+	engine := NewRiskEngine(testRiskConfig(), &staticHistory{}, nil)
+
+	restore := func(at time.Time, env string) models.AuditLog {
+		event := models.AuditLog{
+			Action:     models.AuditActionRestore,
+			Resource:   models.AuditResourceDatabaseConnection,
+			IPAddress:  "10.0.0.1",
+			StatusCode: 202,
+			UserID:     uintPtr(1),
+			CreatedAt:  at,
+		}
+		event.SetMetadata("environment", env)
+		return event
+	}
+
+	tests := []struct {
+		name     string
+		event    models.AuditLog
+		expected bool
+	}{
+		{"weekday business hours", restore(time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC), "production"), false},
+		{"weekday night", restore(time.Date(2024, 3, 12, 23, 0, 0, 0, time.UTC), "production"), true},
+		{"weekend", restore(time.Date(2024, 3, 16, 11, 0, 0, 0, time.UTC), "production"), true},
+		{"staging at night", restore(time.Date(2024, 3, 12, 23, 0, 0, 0, time.UTC), "staging"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment, err := engine.Evaluate(context.Background(), &tt.event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, assessment.IsSuspicious())
+		})
+	}
+}
+
+func TestRiskEngine_NewDevice(t *testing.T) {
+	now := time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC)
+	history := &staticHistory{events: []models.AuditLog{
+		loginEvent(1, "10.0.0.1", 200, now.Add(-24*time.Hour)),
+	}}
+	engine := NewRiskEngine(testRiskConfig(), history, nil)
+
+	known := loginEvent(1, "10.0.0.1", 200, now)
+	assessment, err := engine.Evaluate(context.Background(), &known)
+	require.NoError(t, err)
+	assert.Empty(t, assessment.Findings)
+
+	newDevice := loginEvent(1, "10.0.0.1", 200, now)
+	newDevice.UserAgent = strPtr("curl/8.4.0")
+	assessment, err = engine.Assess(context.Background(), &newDevice)
+	require.NoError(t, err)
+	require.Len(t, assessment.Findings, 1)
+	assert.Equal(t, "new_device_login", assessment.Findings[0].Rule)
+	assert.Equal(t, RiskLevelMedium, newDevice.RiskLevel)
+
+	// First ever login has no baseline
+	first := loginEvent(2, "10.0.0.2", 200, now)
+	assessment, err = engine.Evaluate(context.Background(), &first)
+	require.NoError(t, err)
+	assert.Empty(t, assessment.Findings)
+}
+
+func TestRiskEngine_DisabledAndErrors(t *testing.T) {
+	cfg := testRiskConfig()
+	cfg.Enabled = false
+	engine := NewRiskEngine(cfg, &staticHistory{err: errors.New("unused")}, nil)
+	assert.Empty(t, engine.Rules())
+
+	event := loginEvent(1, "10.0.0.1", 401, time.Now())
+	assessment, err := engine.Assess(context.Background(), &event)
+	require.NoError(t, err)
+	assert.False(t, assessment.IsSuspicious())
+
+	engine = NewRiskEngine(testRiskConfig(), &staticHistory{err: errors.New("db down")}, nil)
+	_, err = engine.Assess(context.Background(), &event)
+	assert.Error(t, err)
+}
+
+func TestRiskEngine_UsesEngineClock(t *testing.T) {
+	now := time.Date(2024, 3, 16, 23, 30, 0, 0, time.UTC) // Saturday night
+	history := &staticHistory{events: []models.AuditLog{
+		loginEvent(1, "10.0.0.1", 401, now.Add(-2*time.Minute)),
+		loginEvent(1, "10.0.0.1", 401, now.Add(-time.Minute)),
+	}}
+	engine := NewRiskEngine(testRiskConfig(), history, nil)
+	engine.now = func() time.Time { return now }
+
+	// Unsaved events are scored at the engine's time, not the wall clock
+	event := loginEvent(1, "10.0.0.1", 401, time.Time{})
+	assessment, err := engine.Evaluate(context.Background(), &event)
+	require.NoError(t, err)
+	assert.True(t, assessment.Blocked)
+	assert.True(t, event.CreatedAt.IsZero())
+
+	restore := models.AuditLog{Action: models.AuditActionRestore, Resource: models.AuditResourceDatabaseConnection, StatusCode: 202, UserID: uintPtr(1)}
+	restore.SetMetadata("production", true)
+	assessment, err = engine.Evaluate(context.Background(), &restore)
+	require.NoError(t, err)
+	require.Len(t, assessment.Findings, 1)
+	assert.Equal(t, "off_hours_production_restore", assessment.Findings[0].Rule)
+}
+
+func TestHaversineKm(t *testing.T) {
+	// London to Paris is roughly 344 km
+	distance := haversineKm(51.5074, -0.1278, 48.8566, 2.3522)
+	assert.InDelta(t, 344, distance, 5)
+}
+
+func TestAuditService_Record(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.AuditLog{}))
+
+	engine := NewRiskEngine(testRiskConfig(), NewGormAuditHistoryProvider(db), nil)
+	service := NewAuditService(db, engine)
+	now := time.Now().UTC()
+
+	for i := 0; i < 2; i++ {
+		event := loginEvent(1, "10.0.0.1", 401, now.Add(-time.Duration(2-i)*time.Minute))
+		_, err := service.Record(context.Background(), &event)
+		require.NoError(t, err)
+	}
+
+	event := loginEvent(1, "10.0.0.1", 401, now)
+	assessment, err := service.Record(context.Background(), &event)
+	assert.ErrorIs(t, err, ErrActionBlocked)
+	require.NotNil(t, assessment)
+	assert.True(t, assessment.Blocked)
+
+	var stored models.AuditLog
+	require.NoError(t, db.First(&stored, event.ID).Error)
+	assert.True(t, stored.IsBlocked)
+	assert.True(t, stored.IsSuspicious)
+	assert.Equal(t, RiskLevelHigh, stored.RiskLevel)
+	assert.Contains(t, stored.Metadata, "risk_rules")
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3ObjectStore adapts an S3 service to the ObjectStore interface for a single bucket
+type S3ObjectStore struct {
+	service S3ServiceInterface
+	bucket  string
+}
+
+// NewS3ObjectStore creates an object store backed by the given bucket
+func NewS3ObjectStore(service S3ServiceInterface, bucket string) *S3ObjectStore {
+	return &S3ObjectStore{service: service, bucket: bucket}
+}
+
+// Put uploads an object to the bucket
+func (s *S3ObjectStore) Put(ctx context.Context, key string, data io.Reader, contentType string) (*ObjectInfo, error) {
+	counter := &countingReader{reader: data}
+	var body io.Reader = counter
+	if _, ok := data.(io.Seeker); ok {
+		// Let the uploader size seekable bodies itself
+		body = data
+	}
+
+	result, err := s.service.UploadFile(ctx, s.bucket, key, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	size := result.Size
+	if size == 0 {
+		size = counter.n
+	}
+
+	return &ObjectInfo{
+		Key:          result.Key,
+		Size:         size,
+		LastModified: result.UploadedAt,
+		ETag:         result.ETag,
+		ContentType:  result.ContentType,
+	}, nil
+}
+
+// Get downloads an object from the bucket
+func (s *S3ObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.service.DownloadFile(ctx, s.bucket, key)
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, err
+	}
+	return reader, nil
+}
+
+// Stat returns metadata for an object in the bucket
+func (s *S3ObjectStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.service.GetFileInfo(ctx, s.bucket, key)
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+	}, nil
+}
+
+// List returns objects in the bucket with the given prefix
+func (s *S3ObjectStore) List(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error) {
+	result, err := s.service.ListFiles(ctx, s.bucket, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]ObjectInfo, len(result.Objects))
+	for i, obj := range result.Objects {
+		objects[i] = ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			LastModified: obj.LastModified,
+			ETag:         obj.ETag,
+		}
+	}
+	return objects, nil
+}
+
+// Delete removes an object from the bucket
+func (s *S3ObjectStore) Delete(ctx context.Context, key string) error {
+	return s.service.DeleteFile(ctx, s.bucket, key)
+}
+
+// PresignGet generates a presigned download URL
+func (s *S3ObjectStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return s.service.GeneratePresignedURL(ctx, s.bucket, key, expiry)
+}
+
+// TestConnection checks that the S3 endpoint is reachable
+func (s *S3ObjectStore) TestConnection(ctx context.Context) error {
+	return s.service.TestConnection(ctx)
+}
+
+// InBucket returns a store for another bucket reachable with the same client
+func (s *S3ObjectStore) InBucket(bucket string) ObjectStore {
+	return NewS3ObjectStore(s.service, bucket)
+}
+
+// isS3NotFound checks if an S3 error reports a missing object
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	return errors.As(err, &noSuchKey) || strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "NotFound")
+}
@@ -54,6 +54,7 @@ type S3Config struct {
 	Region          string
 	AccessKey       string
 	SecretKey       string
+	SessionToken    string
 	Endpoint        string
 	UsePathStyle    bool
 	DisableSSL      bool
@@ -142,7 +143,7 @@ func NewS3Service(cfg *S3Config) (*S3Service, error) {
 			config.WithRegion(cfg.Region),
 			config.WithEndpointResolverWithOptions(customResolver),
 			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-				cfg.AccessKey, cfg.SecretKey, "",
+				cfg.AccessKey, cfg.SecretKey, cfg.SessionToken,
 			)),
 		)
 	} else {
@@ -151,7 +152,7 @@ func NewS3Service(cfg *S3Config) (*S3Service, error) {
 			awsCfg, err = config.LoadDefaultConfig(context.TODO(),
 				config.WithRegion(cfg.Region),
 				config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-					cfg.AccessKey, cfg.SecretKey, "",
+					cfg.AccessKey, cfg.SecretKey, cfg.SessionToken,
 				)),
 			)
 		} else {
@@ -174,6 +175,7 @@ func NewS3Service(cfg *S3Config) (*S3Service, error) {
 		if cfg.DisableSSL {
 			o.EndpointOptions.DisableHTTPS = true
 		}
+		o.APIOptions = append(o.APIOptions, traceS3Operations)
 	})
 
 	// Create uploader
@@ -258,9 +260,9 @@ func (s *S3Service) DownloadFile(ctx context.Context, bucket, key string) (io.Re
 		return nil, fmt.Errorf("object key is required")
 	}
 
-	// Create context with timeout
+	// Create context with timeout; it must outlive this call because the body is
+	// streamed by the caller, so it is cancelled when the body is closed
 	downloadCtx, cancel := context.WithTimeout(ctx, s.config.DownloadTimeout)
-	defer cancel()
 
 	result, err := s.client.GetObject(downloadCtx, &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
@@ -268,10 +270,23 @@ func (s *S3Service) DownloadFile(ctx context.Context, bucket, key string) (io.Re
 	})
 
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to download file from S3: %w", err)
 	}
 
-	return result.Body, nil
+	return &cancelOnClose{ReadCloser: result.Body, cancel: cancel}, nil
+}
+
+// cancelOnClose releases a download context once its body has been consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels its context
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
 }
 
 // DeleteFile deletes a file from S3
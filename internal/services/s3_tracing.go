@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceS3Operations starts a span for each S3 API call, covering its retries.
+// Multipart uploads show as one span per part.
+func traceS3Operations(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("S3Tracing", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		operation := awsmiddleware.GetOperationName(ctx)
+		ctx, span := tracing.Start(ctx, "S3."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "aws-api"),
+				attribute.String("rpc.service", "S3"),
+				attribute.String("rpc.method", operation),
+			),
+		)
+
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		tracing.End(span, err)
+		return out, metadata, err
+	}), middleware.After)
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestS3Service_TracesOperations(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service, err := NewS3Service(&S3Config{
+		Region:       "us-east-1",
+		Endpoint:     server.URL,
+		AccessKey:    "test",
+		SecretKey:    "test",
+		UsePathStyle: true,
+	})
+	require.NoError(t, err)
+
+	ctx, parent := tracing.Start(context.Background(), "backup.upload")
+	exists, err := service.BucketExists(ctx, "backups")
+	parent.End()
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "S3.HeadBucket", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
@@ -0,0 +1,840 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+)
+
+// sanitizedInsertBatchSize is the number of rows written per INSERT statement
+const sanitizedInsertBatchSize = 100
+
+// SanitizeOptions turns a backup into a SQL-level export that filters rows and
+// masks columns. Rows are streamed from the source database inside a single
+// read-only snapshot and written as INSERT statements in foreign key order.
+type SanitizeOptions struct {
+	Rules []TableSanitizeRule `json:"rules,omitempty"`
+}
+
+// TableSanitizeRule filters and masks one table of a sanitized export
+type TableSanitizeRule struct {
+	// Table name, with or without schema
+	Table string `json:"table"`
+	// Filter lists conditions rows must match; values are bound as query parameters
+	Filter models.RowFilter `json:"filter,omitempty"`
+	// Masks maps column names to masking rules; an empty strategy picks one from the column type
+	Masks map[string]MaskRule `json:"masks,omitempty"`
+}
+
+// Validate checks the rules before a sanitized export is queued
+func (o *SanitizeOptions) Validate() error {
+	for _, rule := range o.Rules {
+		if strings.TrimSpace(rule.Table) == "" {
+			return fmt.Errorf("sanitize rule has no table")
+		}
+		if err := rule.Filter.Validate(); err != nil {
+			return fmt.Errorf("table %s: %w", rule.Table, err)
+		}
+		for column, mask := range rule.Masks {
+			if mask.Strategy == "" {
+				continue
+			}
+			if err := mask.Validate(); err != nil {
+				return fmt.Errorf("table %s column %s: %w", rule.Table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// AddRule appends a rule, typically a mandatory one from a table permission
+func (o *SanitizeOptions) AddRule(rule TableSanitizeRule) {
+	o.Rules = append(o.Rules, rule)
+}
+
+// SanitizedTableStats describes one exported table
+type SanitizedTableStats struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+	// Where is the row condition the table was read with, with parameter placeholders
+	Where         string   `json:"where,omitempty"`
+	MaskedColumns []string `json:"masked_columns,omitempty"`
+}
+
+// SanitizedExportResult describes a sanitized export
+type SanitizedExportResult struct {
+	Tables []SanitizedTableStats `json:"tables"`
+}
+
+// MaskedColumnCount returns the number of masked columns across all tables
+func (r *SanitizedExportResult) MaskedColumnCount() int {
+	count := 0
+	for _, table := range r.Tables {
+		count += len(table.MaskedColumns)
+	}
+	return count
+}
+
+// SanitizedExporter streams a filtered and masked data export as SQL
+type SanitizedExporter struct {
+	db      *sql.DB
+	dialect sqlDialect
+	masker  *Masker
+}
+
+// NewSanitizedExporter creates an exporter for a PostgreSQL or MySQL database.
+// Hashes are keyed with a random per-export key so masked values cannot be
+// reversed by hashing guesses, while staying consistent within the export.
+func NewSanitizedExporter(db *sql.DB, dbType models.DatabaseType) (*SanitizedExporter, error) {
+	dialect, err := newSQLDialect(dbType)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate masking key: %w", err)
+	}
+
+	return &SanitizedExporter{db: db, dialect: dialect, masker: NewMasker(key)}, nil
+}
+
+// exportTable is a source table and how it is exported
+type exportTable struct {
+	schema string
+	name   string
+	filter models.RowFilter
+	masks  map[string]MaskRule
+	// parents are foreign keys to other exported tables
+	parents []exportForeignKey
+
+	visiting bool
+}
+
+func (t *exportTable) key() string {
+	return strings.ToLower(t.schema + "." + t.name)
+}
+
+func (t *exportTable) fullName() string {
+	return t.schema + "." + t.name
+}
+
+// exportForeignKey is a possibly composite foreign key between source tables
+type exportForeignKey struct {
+	name       string
+	table      string
+	columns    []string
+	refTable   string
+	refColumns []string
+}
+
+// Export writes the sanitized data of the selected tables to w. An empty table
+// list exports every base table except the excluded ones.
+//
+// Child rows are limited to those whose parent rows are exported, by adding
+// the parent's filter as a subquery on the foreign key, so the dump restores
+// with foreign keys enforced. References to tables outside the export are
+// left unchanged.
+func (e *SanitizedExporter) Export(ctx context.Context, w io.Writer, tables, excludeTables []string, options *SanitizeOptions, progress func(float64, string)) (*SanitizedExportResult, error) {
+	if options == nil {
+		options = &SanitizeOptions{}
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	// A repeatable read snapshot keeps parents and children consistent across tables
+	tx, err := e.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	selected, err := e.selectTables(ctx, tx, tables, excludeTables)
+	if err != nil {
+		return nil, err
+	}
+	e.applyRules(selected, options.Rules)
+	if err := e.linkForeignKeys(ctx, tx, selected); err != nil {
+		return nil, err
+	}
+
+	ordered := orderByForeignKeys(selected)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "-- Sanitized data export generated %s\n", time.Now().UTC().Format(time.RFC3339))
+	out.WriteString(e.dialect.header())
+
+	result := &SanitizedExportResult{}
+	for i, table := range ordered {
+		if progress != nil {
+			progress(float64(i)/float64(len(ordered))*100, fmt.Sprintf("exporting table %s", table.fullName()))
+		}
+
+		stats, err := e.exportTable(ctx, tx, out, table, selected)
+		if err != nil {
+			return nil, err
+		}
+		result.Tables = append(result.Tables, *stats)
+	}
+
+	out.WriteString(e.dialect.footer())
+	if err := out.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+	if progress != nil {
+		progress(100, "sanitized export completed")
+	}
+
+	return result, nil
+}
+
+// selectTables lists the source tables and resolves the requested ones
+func (e *SanitizedExporter) selectTables(ctx context.Context, tx *sql.Tx, tables, excludeTables []string) (map[string]*exportTable, error) {
+	rows, err := tx.QueryContext(ctx, e.dialect.tablesQuery())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	all := make(map[string]*exportTable)
+	for rows.Next() {
+		table := &exportTable{masks: make(map[string]MaskRule)}
+		if err := rows.Scan(&table.schema, &table.name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		all[table.key()] = table
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	selected := make(map[string]*exportTable)
+	if len(tables) == 0 {
+		for key, table := range all {
+			selected[key] = table
+		}
+	}
+	for _, name := range tables {
+		table := e.lookupTable(all, name)
+		if table == nil {
+			return nil, fmt.Errorf("table %s not found", name)
+		}
+		selected[table.key()] = table
+	}
+	for _, name := range excludeTables {
+		if table := e.lookupTable(all, name); table != nil {
+			delete(selected, table.key())
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no tables to export")
+	}
+	return selected, nil
+}
+
+// lookupTable finds a table by "schema.name" or by a name in the default schema
+func (e *SanitizedExporter) lookupTable(tables map[string]*exportTable, name string) *exportTable {
+	key := strings.ToLower(name)
+	if table, ok := tables[key]; ok {
+		return table
+	}
+	if !strings.Contains(key, ".") {
+		for _, table := range tables {
+			if strings.ToLower(table.name) == key && e.dialect.isDefaultSchema(table.schema) {
+				return table
+			}
+		}
+	}
+	return nil
+}
+
+// applyRules attaches row filters and column masks to the selected tables
+func (e *SanitizedExporter) applyRules(selected map[string]*exportTable, rules []TableSanitizeRule) {
+	for _, rule := range rules {
+		table := e.lookupTable(selected, rule.Table)
+		if table == nil {
+			// Rules for tables outside the export have nothing to restrict
+			continue
+		}
+		// Every rule's conditions apply, so a requested filter can only narrow a mandatory one
+		table.filter = append(table.filter, rule.Filter...)
+		for column, mask := range rule.Masks {
+			// The first rule for a column wins so mandatory rules can be added first
+			if _, exists := table.masks[strings.ToLower(column)]; !exists {
+				table.masks[strings.ToLower(column)] = mask
+			}
+		}
+	}
+}
+
+// linkForeignKeys records foreign keys between exported tables
+func (e *SanitizedExporter) linkForeignKeys(ctx context.Context, tx *sql.Tx, selected map[string]*exportTable) error {
+	rows, err := tx.QueryContext(ctx, e.dialect.foreignKeysQuery())
+	if err != nil {
+		return fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string]*exportForeignKey)
+	var order []string
+	for rows.Next() {
+		var name, schema, table, column, refSchema, refTable, refColumn string
+		if err := rows.Scan(&name, &schema, &table, &column, &refSchema, &refTable, &refColumn); err != nil {
+			return fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		child := strings.ToLower(schema + "." + table)
+		parent := strings.ToLower(refSchema + "." + refTable)
+		if selected[child] == nil || selected[parent] == nil || child == parent {
+			continue
+		}
+
+		id := child + "|" + name
+		fk, ok := keys[id]
+		if !ok {
+			fk = &exportForeignKey{name: name, table: child, refTable: parent}
+			keys[id] = fk
+			order = append(order, id)
+		}
+		fk.columns = append(fk.columns, column)
+		fk.refColumns = append(fk.refColumns, refColumn)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+
+	for _, id := range order {
+		fk := keys[id]
+		selected[fk.table].parents = append(selected[fk.table].parents, *fk)
+	}
+	return nil
+}
+
+// queryArgs collects the bound parameters of a query
+type queryArgs struct {
+	dialect sqlDialect
+	values  []interface{}
+}
+
+// bind adds a parameter and returns its placeholder
+func (a *queryArgs) bind(value interface{}) string {
+	a.values = append(a.values, value)
+	return a.dialect.placeholder(len(a.values))
+}
+
+// rowFilter builds the WHERE condition of a table: its own filter plus, for
+// every filtered parent, a check that the referenced row is exported too.
+// Foreign keys that form a cycle are not followed.
+func (e *SanitizedExporter) rowFilter(table *exportTable, tables map[string]*exportTable, args *queryArgs) string {
+	if table.visiting {
+		return ""
+	}
+	table.visiting = true
+	defer func() { table.visiting = false }()
+
+	conditions := make([]string, 0, len(table.filter)+len(table.parents))
+	for _, condition := range table.filter {
+		conditions = append(conditions, "("+e.rowCondition(condition, args)+")")
+	}
+
+	for _, fk := range table.parents {
+		parent := tables[fk.refTable]
+		parentFilter := e.rowFilter(parent, tables, args)
+		if parentFilter == "" {
+			continue
+		}
+
+		columns := e.dialect.quoteList(fk.columns)
+		refColumns := e.dialect.quoteList(fk.refColumns)
+		if len(fk.columns) > 1 {
+			columns = "(" + columns + ")"
+		}
+
+		nullChecks := make([]string, len(fk.columns))
+		for i, column := range fk.columns {
+			nullChecks[i] = e.dialect.quote(column) + " IS NULL"
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%s OR %s IN (SELECT %s FROM %s WHERE %s))",
+			strings.Join(nullChecks, " OR "), columns, refColumns,
+			e.dialect.qualify(parent.schema, parent.name), parentFilter))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// rowCondition renders one validated condition with its value bound
+func (e *SanitizedExporter) rowCondition(condition models.RowCondition, args *queryArgs) string {
+	column := e.dialect.quote(condition.Column)
+	switch condition.Operator {
+	case models.RowOperatorIsNull:
+		return column + " IS NULL"
+	case models.RowOperatorNotNull:
+		return column + " IS NOT NULL"
+	case models.RowOperatorIn, models.RowOperatorNotIn:
+		values, _ := condition.Value.([]interface{})
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = args.bind(value)
+		}
+		operator := " IN "
+		if condition.Operator == models.RowOperatorNotIn {
+			operator = " NOT IN "
+		}
+		return column + operator + "(" + strings.Join(placeholders, ", ") + ")"
+	case models.RowOperatorLike:
+		return column + " LIKE " + args.bind(condition.Value)
+	case models.RowOperatorNotEqual:
+		return column + " <> " + args.bind(condition.Value)
+	default:
+		return column + " " + condition.Operator + " " + args.bind(condition.Value)
+	}
+}
+
+// exportTable streams one table as INSERT statements
+func (e *SanitizedExporter) exportTable(ctx context.Context, tx *sql.Tx, out *bufio.Writer, table *exportTable, tables map[string]*exportTable) (*SanitizedTableStats, error) {
+	qualified := e.dialect.qualify(table.schema, table.name)
+	args := &queryArgs{dialect: e.dialect}
+	stats := &SanitizedTableStats{Table: table.fullName(), Where: e.rowFilter(table, tables, args)}
+
+	query := "SELECT * FROM " + qualified
+	if stats.Where != "" {
+		query += " WHERE " + stats.Where
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", stats.Table, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", stats.Table, err)
+	}
+
+	columns := make([]exportColumn, len(columnTypes))
+	names := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = newExportColumn(columnType)
+		names[i] = columnType.Name()
+		if mask, ok := table.masks[strings.ToLower(columnType.Name())]; ok {
+			if mask.Strategy == "" {
+				mask = DefaultMaskRule(&models.DatabaseColumn{Name: columnType.Name(), DataType: columns[i].dataType})
+			}
+			columns[i].mask = &mask
+			stats.MaskedColumns = append(stats.MaskedColumns, columnType.Name())
+		}
+	}
+	if missing := missingMaskColumns(table.masks, names); len(missing) > 0 {
+		return nil, fmt.Errorf("table %s has no columns %s to mask", stats.Table, strings.Join(missing, ", "))
+	}
+
+	fmt.Fprintf(out, "\n-- Data for %s\n", stats.Table)
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", qualified, e.dialect.quoteList(names))
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to read row of %s: %w", stats.Table, err)
+		}
+
+		if stats.Rows%sanitizedInsertBatchSize == 0 {
+			if stats.Rows > 0 {
+				out.WriteString(";\n")
+			}
+			out.WriteString(insert)
+		} else {
+			out.WriteString(",\n")
+		}
+
+		out.WriteString("(")
+		for i, column := range columns {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			value := values[i]
+			if column.mask != nil {
+				value = e.masker.Mask(*column.mask, column.category, column.length, value)
+			}
+			out.WriteString(e.dialect.literal(value, column.category))
+		}
+		out.WriteString(")")
+		stats.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", stats.Table, err)
+	}
+	if stats.Rows > 0 {
+		out.WriteString(";\n")
+	}
+
+	return stats, nil
+}
+
+// missingMaskColumns lists masked columns that the table does not have
+func missingMaskColumns(masks map[string]MaskRule, columns []string) []string {
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		present[strings.ToLower(column)] = true
+	}
+
+	var missing []string
+	for column := range masks {
+		if !present[column] {
+			missing = append(missing, column)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// orderByForeignKeys sorts tables so parents are written before their children.
+// Tables in a foreign key cycle keep alphabetical order.
+func orderByForeignKeys(tables map[string]*exportTable) []*exportTable {
+	keys := make([]string, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ordered []*exportTable
+	done := make(map[string]bool, len(tables))
+	for len(ordered) < len(keys) {
+		progressed := false
+		for _, key := range keys {
+			if done[key] {
+				continue
+			}
+			ready := true
+			for _, fk := range tables[key].parents {
+				if !done[fk.refTable] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[key] = true
+				ordered = append(ordered, tables[key])
+				progressed = true
+			}
+		}
+
+		if !progressed {
+			// Break the cycle with the first remaining table
+			for _, key := range keys {
+				if !done[key] {
+					done[key] = true
+					ordered = append(ordered, tables[key])
+					break
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// exportColumn is a result column and how its values are written
+type exportColumn struct {
+	dataType string
+	category string
+	length   int
+	mask     *MaskRule
+}
+
+// newExportColumn classifies a result column using the catalogue's type categories
+func newExportColumn(columnType *sql.ColumnType) exportColumn {
+	dataType := normalizeDataType(columnType.DatabaseTypeName())
+	column := exportColumn{
+		dataType: dataType,
+		category: (&models.DatabaseColumn{DataType: dataType}).GetDataTypeCategory(),
+	}
+	if length, ok := columnType.Length(); ok && length > 0 && length < 1<<31 {
+		column.length = int(length)
+	}
+	return column
+}
+
+// normalizeDataType maps driver type names to the names used by DatabaseColumn
+func normalizeDataType(name string) string {
+	name = strings.ToLower(name)
+	switch name {
+	case "int2":
+		return "smallint"
+	case "int4":
+		return "integer"
+	case "int8":
+		return "bigint"
+	case "float4":
+		return "real"
+	case "float8":
+		return "double"
+	case "bpchar":
+		return "char"
+	case "unsigned int", "unsigned bigint", "unsigned smallint", "unsigned tinyint", "unsigned mediumint":
+		return strings.TrimPrefix(name, "unsigned ")
+	}
+	return name
+}
+
+// sqlDialect holds the SQL differences between source databases
+type sqlDialect interface {
+	tablesQuery() string
+	// foreignKeysQuery returns constraint, schema, table, column, referenced schema, table and column
+	foreignKeysQuery() string
+	// columnsQuery returns schema, table, column, data type, nullability, maximum length, position and primary key flag
+	columnsQuery() string
+	// textCast converts a column to text for sampling
+	textCast(expression string) string
+	isDefaultSchema(schema string) bool
+	quote(identifier string) string
+	quoteList(identifiers []string) string
+	qualify(schema, table string) string
+	// placeholder returns the marker of the nth bound parameter, counting from 1
+	placeholder(n int) string
+	literal(value interface{}, category string) string
+	header() string
+	footer() string
+}
+
+// newSQLDialect returns the dialect for a database type
+func newSQLDialect(dbType models.DatabaseType) (sqlDialect, error) {
+	switch dbType {
+	case models.DatabaseTypePostgreSQL:
+		return postgresDialect{}, nil
+	case models.DatabaseTypeMySQL:
+		return mysqlDialect{}, nil
+	default:
+		return nil, fmt.Errorf("database type %s is not supported", dbType)
+	}
+}
+
+// postgresDialect writes PostgreSQL data
+type postgresDialect struct{}
+
+func (postgresDialect) tablesQuery() string {
+	return `SELECT table_schema, table_name FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name`
+}
+
+func (postgresDialect) foreignKeysQuery() string {
+	return `SELECT c.conname, ns.nspname, cl.relname, a.attname, fns.nspname, fcl.relname, fa.attname
+FROM pg_constraint c
+JOIN pg_class cl ON cl.oid = c.conrelid
+JOIN pg_namespace ns ON ns.oid = cl.relnamespace
+JOIN pg_class fcl ON fcl.oid = c.confrelid
+JOIN pg_namespace fns ON fns.oid = fcl.relnamespace
+CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = k.fattnum
+WHERE c.contype = 'f' AND ns.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY ns.nspname, cl.relname, c.conname, k.ord`
+}
+
+func (postgresDialect) columnsQuery() string {
+	return `SELECT c.table_schema, c.table_name, c.column_name, c.data_type, c.is_nullable = 'YES',
+c.character_maximum_length, c.ordinal_position,
+EXISTS (SELECT 1 FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage k ON k.constraint_name = tc.constraint_name
+AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+AND tc.table_name = c.table_name AND k.column_name = c.column_name)
+FROM information_schema.columns c
+WHERE c.table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY c.table_schema, c.table_name, c.ordinal_position`
+}
+
+func (postgresDialect) textCast(expression string) string {
+	return "CAST(" + expression + " AS text)"
+}
+
+func (postgresDialect) isDefaultSchema(schema string) bool {
+	return schema == "public"
+}
+
+func (postgresDialect) quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (d postgresDialect) quoteList(identifiers []string) string {
+	return quoteIdentifiers(d, identifiers)
+}
+
+func (d postgresDialect) qualify(schema, table string) string {
+	return d.quote(schema) + "." + d.quote(table)
+}
+
+func (postgresDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) literal(value interface{}, category string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case []byte:
+		if category == "binary" {
+			return `'\x` + hex.EncodeToString(v) + `'::bytea`
+		}
+		return postgresString(string(v))
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999-07:00") + "'"
+	case string:
+		return postgresString(v)
+	default:
+		return numericLiteral(v, postgresString)
+	}
+}
+
+func (postgresDialect) header() string {
+	return "SET client_encoding = 'UTF8';\nSET standard_conforming_strings = on;\nBEGIN;\n"
+}
+
+func (postgresDialect) footer() string {
+	return "\nCOMMIT;\n"
+}
+
+// postgresString quotes a string with standard conforming strings enabled
+func postgresString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// mysqlDialect writes MySQL data
+type mysqlDialect struct{}
+
+func (mysqlDialect) tablesQuery() string {
+	return `SELECT table_schema, table_name FROM information_schema.tables
+WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+ORDER BY table_name`
+}
+
+func (mysqlDialect) foreignKeysQuery() string {
+	return `SELECT constraint_name, table_schema, table_name, column_name,
+referenced_table_schema, referenced_table_name, referenced_column_name
+FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL
+ORDER BY table_name, constraint_name, ordinal_position`
+}
+
+func (mysqlDialect) columnsQuery() string {
+	return `SELECT table_schema, table_name, column_name, data_type, is_nullable = 'YES',
+character_maximum_length, ordinal_position, column_key = 'PRI'
+FROM information_schema.columns
+WHERE table_schema = DATABASE()
+ORDER BY table_name, ordinal_position`
+}
+
+func (mysqlDialect) textCast(expression string) string {
+	return "CAST(" + expression + " AS CHAR)"
+}
+
+func (mysqlDialect) isDefaultSchema(string) bool {
+	// Only the connection's database is listed
+	return true
+}
+
+func (mysqlDialect) quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (d mysqlDialect) quoteList(identifiers []string) string {
+	return quoteIdentifiers(d, identifiers)
+}
+
+func (d mysqlDialect) qualify(_, table string) string {
+	// The dump restores into whichever database it is loaded into
+	return d.quote(table)
+}
+
+func (mysqlDialect) placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) literal(value interface{}, category string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		if category == "binary" {
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+		return mysqlString(string(v))
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case string:
+		return mysqlString(v)
+	default:
+		return numericLiteral(v, mysqlString)
+	}
+}
+
+func (mysqlDialect) header() string {
+	return "SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS = 0;\nSTART TRANSACTION;\n"
+}
+
+func (mysqlDialect) footer() string {
+	return "\nCOMMIT;\nSET FOREIGN_KEY_CHECKS = 1;\n"
+}
+
+// mysqlString quotes a string with MySQL's backslash escapes
+func mysqlString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+	return "'" + replacer.Replace(value) + "'"
+}
+
+// quoteIdentifiers quotes and joins a column list
+func quoteIdentifiers(d sqlDialect, identifiers []string) string {
+	quoted := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		quoted[i] = d.quote(identifier)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// numericLiteral writes numbers unquoted and anything else as a string
+func numericLiteral(value interface{}, quote func(string) string) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return quote(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizedExporter_PostgreSQL(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	exporter, err := NewSanitizedExporter(db, models.DatabaseTypePostgreSQL)
+	require.NoError(t, err)
+	dialect := postgresDialect{}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.tablesQuery()).WillReturnRows(sqlmock.NewRows([]string{"table_schema", "table_name"}).
+		AddRow("public", "order_items").
+		AddRow("public", "orders").
+		AddRow("public", "users").
+		AddRow("public", "audit_events"))
+	mock.ExpectQuery(dialect.foreignKeysQuery()).WillReturnRows(sqlmock.NewRows([]string{"conname", "nspname", "relname", "attname", "fnspname", "frelname", "fattname"}).
+		AddRow("order_items_order_fk", "public", "order_items", "order_id", "public", "orders", "id").
+		AddRow("orders_user_fk", "public", "orders", "user_id", "public", "users", "id").
+		AddRow("users_referrer_fk", "public", "users", "referred_by", "public", "users", "id"))
+
+	usersFilter := `("region" = $1)`
+	ordersFilter := `("user_id" IS NULL OR "user_id" IN (SELECT "id" FROM "public"."users" WHERE ` + usersFilter + `))`
+	itemsFilter := `("order_id" IS NULL OR "order_id" IN (SELECT "id" FROM "public"."orders" WHERE ` + ordersFilter + `))`
+
+	mock.ExpectQuery(`SELECT * FROM "public"."users" WHERE ` + usersFilter).WithArgs("eu").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT4", int64(0)),
+			sqlmock.NewColumn("email").OfType("VARCHAR", ""),
+			sqlmock.NewColumn("name").OfType("TEXT", ""),
+			sqlmock.NewColumn("birth_date").OfType("DATE", time.Time{}),
+			sqlmock.NewColumn("active").OfType("BOOL", false),
+		).
+			AddRow(int64(1), "ann@corp.io", "Ann O'Neil", time.Date(1985, 3, 9, 0, 0, 0, 0, time.UTC), true).
+			AddRow(int64(2), nil, "Bo", nil, false))
+	mock.ExpectQuery(`SELECT * FROM "public"."orders" WHERE ` + ordersFilter).WithArgs("eu").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("INT8", int64(0)),
+			sqlmock.NewColumn("user_id").OfType("INT4", int64(0)),
+			sqlmock.NewColumn("total").OfType("NUMERIC", ""),
+		).AddRow(int64(10), int64(1), "19.99"))
+	mock.ExpectQuery(`SELECT * FROM "public"."order_items" WHERE ` + itemsFilter).WithArgs("eu").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("order_id").OfType("INT8", int64(0)),
+			sqlmock.NewColumn("payload").OfType("BYTEA", []byte{}),
+		).AddRow(int64(10), []byte{0xde, 0xad}))
+	mock.ExpectRollback()
+
+	var out bytes.Buffer
+	var progress []float64
+	result, err := exporter.Export(context.Background(), &out, nil, []string{"audit_events"}, &SanitizeOptions{
+		Rules: []TableSanitizeRule{
+			{Table: "users", Filter: models.RowFilter{{Column: "region", Operator: "=", Value: "eu"}}, Masks: map[string]MaskRule{
+				"email":      {},
+				"name":       {Strategy: MaskFixed, Value: "Redacted"},
+				"birth_date": {Strategy: MaskTruncateDate, Value: "year"},
+			}},
+			{Table: "audit_events", Filter: models.RowFilter{{Column: "id", Operator: models.RowOperatorIsNull}}},
+		},
+	}, func(p float64, _ string) { progress = append(progress, p) })
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, result.Tables, 3)
+	assert.Equal(t, "public.users", result.Tables[0].Table)
+	assert.Equal(t, []string{"email", "name", "birth_date"}, result.Tables[0].MaskedColumns)
+	assert.Equal(t, int64(2), result.Tables[0].Rows)
+	assert.Equal(t, "public.orders", result.Tables[1].Table)
+	assert.Equal(t, itemsFilter, result.Tables[2].Where)
+	assert.Equal(t, 3, result.MaskedColumnCount())
+	assert.Equal(t, float64(100), progress[len(progress)-1])
+
+	dump := out.String()
+	assert.Contains(t, dump, "SET standard_conforming_strings = on;\nBEGIN;\n")
+	assert.Contains(t, dump, `INSERT INTO "public"."users" ("id", "email", "name", "birth_date", "active") VALUES`)
+	assert.Regexp(t, `\(1, '[a-z0-9]{3}@example\.com', 'Redacted', '1985-01-01 00:00:00\+00:00', TRUE\),\n\(2, NULL, 'Redacted', NULL, FALSE\);`, dump)
+	assert.NotContains(t, dump, "ann@corp.io")
+	assert.NotContains(t, dump, "O'Neil")
+	assert.Contains(t, dump, `INSERT INTO "public"."orders" ("id", "user_id", "total") VALUES`+"\n(10, 1, '19.99');")
+	assert.Contains(t, dump, `(10, '\xdead'::bytea);`)
+	assert.NotContains(t, dump, "audit_events")
+	assert.True(t, strings.HasSuffix(dump, "\nCOMMIT;\n"))
+
+	// Parents are written before their children
+	assert.Less(t, strings.Index(dump, `INSERT INTO "public"."users"`), strings.Index(dump, `INSERT INTO "public"."orders"`))
+	assert.Less(t, strings.Index(dump, `INSERT INTO "public"."orders"`), strings.Index(dump, `INSERT INTO "public"."order_items"`))
+}
+
+func TestSanitizedExporter_Errors(t *testing.T) {
+	_, err := NewSanitizedExporter(nil, models.DatabaseTypeSQLite)
+	assert.Error(t, err)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	exporter, err := NewSanitizedExporter(db, models.DatabaseTypeMySQL)
+	require.NoError(t, err)
+	dialect := mysqlDialect{}
+
+	// Filters are conditions with known operators, not SQL
+	_, err = exporter.Export(context.Background(), &bytes.Buffer{}, nil, nil, &SanitizeOptions{
+		Rules: []TableSanitizeRule{{Table: "users", Filter: models.RowFilter{{Column: "id", Operator: "= 1 OR 1 =", Value: 1.0}}}},
+	}, nil)
+	assert.Error(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.tablesQuery()).WillReturnRows(sqlmock.NewRows([]string{"table_schema", "table_name"}).AddRow("shop", "users"))
+	mock.ExpectRollback()
+
+	_, err = exporter.Export(context.Background(), &bytes.Buffer{}, []string{"customers"}, nil, nil, nil)
+	assert.ErrorContains(t, err, "table customers not found")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.tablesQuery()).WillReturnRows(sqlmock.NewRows([]string{"table_schema", "table_name"}).AddRow("shop", "users"))
+	mock.ExpectQuery(dialect.foreignKeysQuery()).WillReturnRows(sqlmock.NewRows([]string{"a", "b", "c", "d", "e", "f", "g"}))
+	mock.ExpectQuery("SELECT * FROM `users`").WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("id").OfType("INT", int64(0)),
+	))
+	mock.ExpectRollback()
+
+	// Masks must name existing columns
+	_, err = exporter.Export(context.Background(), &bytes.Buffer{}, []string{"users"}, nil, &SanitizeOptions{
+		Rules: []TableSanitizeRule{{Table: "shop.users", Masks: map[string]MaskRule{"ssn": {Strategy: MaskNull}}}},
+	}, nil)
+	assert.ErrorContains(t, err, "has no columns ssn to mask")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSanitizeDialect_Literals(t *testing.T) {
+	pg := postgresDialect{}
+	my := mysqlDialect{}
+
+	assert.Equal(t, `'it''s'`, pg.literal("it's", "string"))
+	assert.Equal(t, `'a\b'`, pg.literal([]byte(`a\b`), "string"))
+	assert.Equal(t, `"we""ird"`, pg.quote(`we"ird`))
+	assert.Equal(t, "1.5", pg.literal(1.5, "decimal"))
+
+	assert.Equal(t, `'it\'s \\ \n'`, my.literal("it's \\ \n", "string"))
+	assert.Equal(t, "X'00ff'", my.literal([]byte{0x00, 0xff}, "binary"))
+	assert.Equal(t, "1", my.literal(true, "boolean"))
+	assert.Equal(t, "'2024-02-29 10:30:00'", my.literal(time.Date(2024, 2, 29, 10, 30, 0, 0, time.UTC), "datetime"))
+	assert.Equal(t, "`we``ird`", my.quote("we`ird"))
+}
+
+func TestSanitizedExporter_FiltersAreBound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	exporter, err := NewSanitizedExporter(db, models.DatabaseTypeMySQL)
+	require.NoError(t, err)
+	dialect := mysqlDialect{}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.tablesQuery()).WillReturnRows(sqlmock.NewRows([]string{"table_schema", "table_name"}).AddRow("shop", "users"))
+	mock.ExpectQuery(dialect.foreignKeysQuery()).WillReturnRows(sqlmock.NewRows([]string{"a", "b", "c", "d", "e", "f", "g"}))
+	// Injected SQL stays a value and a mandatory filter cannot be widened by a requested one
+	mock.ExpectQuery("SELECT * FROM `users` WHERE (`tenant` = ?) AND (`region` = ?) AND (`id` NOT IN (?, ?)) AND (`email` IS NOT NULL)").
+		WithArgs("acme", "1=1) OR (1=1", 1.0, 2.0).
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(sqlmock.NewColumn("id").OfType("INT", int64(0))))
+	mock.ExpectRollback()
+
+	result, err := exporter.Export(context.Background(), &bytes.Buffer{}, []string{"users"}, nil, &SanitizeOptions{
+		Rules: []TableSanitizeRule{
+			{Table: "users", Filter: models.RowFilter{{Column: "tenant", Operator: "=", Value: "acme"}}},
+			{Table: "users", Filter: models.RowFilter{
+				{Column: "region", Operator: "=", Value: "1=1) OR (1=1"},
+				{Column: "id", Operator: models.RowOperatorNotIn, Value: []interface{}{1.0, 2.0}},
+				{Column: "email", Operator: models.RowOperatorNotNull},
+			}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.NotContains(t, result.Tables[0].Where, "1=1")
+}
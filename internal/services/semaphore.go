@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultSemaphorePrefix prefixes the Redis keys of semaphores
+const DefaultSemaphorePrefix = "dbackup:semaphore"
+
+// SemaphoreSlot is a counted resource and the number of holders it allows
+type SemaphoreSlot struct {
+	Key   string
+	Limit int
+}
+
+// SemaphoreFullError is returned when a slot has no room left
+type SemaphoreFullError struct {
+	Slot SemaphoreSlot
+}
+
+func (e *SemaphoreFullError) Error() string {
+	return fmt.Sprintf("semaphore %s is full (limit %d)", e.Slot.Key, e.Slot.Limit)
+}
+
+// Semaphore counts holders of shared slots. Places are leased for a TTL so
+// the places of crashed holders free themselves; holders renew their lease
+// while they work.
+type Semaphore interface {
+	// Acquire takes a place in every slot or in none. It returns a
+	// *SemaphoreFullError naming the first full slot. Acquiring again as
+	// the same holder renews its places.
+	Acquire(ctx context.Context, holder string, slots []SemaphoreSlot, ttl time.Duration) error
+	// Renew extends the holder's places
+	Renew(ctx context.Context, holder string, keys []string, ttl time.Duration) error
+	// Release gives the holder's places back
+	Release(ctx context.Context, holder string, keys []string) error
+}
+
+// LocalSemaphore counts holders in memory, for a single process
+type LocalSemaphore struct {
+	mu    sync.Mutex
+	slots map[string]map[string]time.Time
+}
+
+// NewLocalSemaphore creates an in-memory semaphore
+func NewLocalSemaphore() *LocalSemaphore {
+	return &LocalSemaphore{slots: make(map[string]map[string]time.Time)}
+}
+
+// Acquire takes a place in every slot or in none
+func (s *LocalSemaphore) Acquire(ctx context.Context, holder string, slots []SemaphoreSlot, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, slot := range slots {
+		holders := s.slots[slot.Key]
+		for other, expiresAt := range holders {
+			if !expiresAt.After(now) {
+				delete(holders, other)
+			}
+		}
+		if _, held := holders[holder]; !held && len(holders) >= slot.Limit {
+			return &SemaphoreFullError{Slot: slot}
+		}
+	}
+
+	expiresAt := now.Add(ttl)
+	for _, slot := range slots {
+		if s.slots[slot.Key] == nil {
+			s.slots[slot.Key] = make(map[string]time.Time)
+		}
+		s.slots[slot.Key][holder] = expiresAt
+	}
+	return nil
+}
+
+// Renew extends the holder's places
+func (s *LocalSemaphore) Renew(ctx context.Context, holder string, keys []string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	for _, key := range keys {
+		if _, held := s.slots[key][holder]; held {
+			s.slots[key][holder] = expiresAt
+		}
+	}
+	return nil
+}
+
+// Release gives the holder's places back
+func (s *LocalSemaphore) Release(ctx context.Context, holder string, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.slots[key], holder)
+		if len(s.slots[key]) == 0 {
+			delete(s.slots, key)
+		}
+	}
+	return nil
+}
+
+// acquireScript checks every slot before taking a place in any, so a holder
+// never keeps places in some slots while waiting for others. Each slot is a
+// sorted set of holders scored by lease expiry.
+//
+// KEYS: slot keys; ARGV: holder, now (ms), expiry (ms), ttl (ms), limits...
+// Returns 0 when acquired, otherwise the 1-based index of the full slot.
+var acquireScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', ARGV[2])
+	if not redis.call('ZSCORE', key, ARGV[1]) and redis.call('ZCARD', key) >= tonumber(ARGV[i + 4]) then
+		return i
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, ARGV[3], ARGV[1])
+	redis.call('PEXPIRE', key, ARGV[4])
+end
+return 0
+`)
+
+// RedisSemaphore counts holders in Redis so limits hold across workers
+type RedisSemaphore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisSemaphore creates a semaphore on an existing Redis client
+func NewRedisSemaphore(client redis.UniversalClient, prefix string) *RedisSemaphore {
+	if prefix == "" {
+		prefix = DefaultSemaphorePrefix
+	}
+	return &RedisSemaphore{client: client, prefix: prefix}
+}
+
+// Acquire takes a place in every slot or in none
+func (s *RedisSemaphore) Acquire(ctx context.Context, holder string, slots []SemaphoreSlot, ttl time.Duration) error {
+	if len(slots) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	keys := make([]string, len(slots))
+	args := []interface{}{holder, now.UnixMilli(), now.Add(ttl).UnixMilli(), ttl.Milliseconds()}
+	for i, slot := range slots {
+		keys[i] = s.key(slot.Key)
+		args = append(args, slot.Limit)
+	}
+
+	full, err := acquireScript.Run(ctx, s.client, keys, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to acquire semaphore: %w", err)
+	}
+	if full > 0 {
+		return &SemaphoreFullError{Slot: slots[full-1]}
+	}
+	return nil
+}
+
+// Renew extends the holder's places
+func (s *RedisSemaphore) Renew(ctx context.Context, holder string, keys []string, ttl time.Duration) error {
+	expiresAt := float64(time.Now().Add(ttl).UnixMilli())
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZAddXX(ctx, s.key(key), redis.Z{Score: expiresAt, Member: holder})
+			pipe.PExpire(ctx, s.key(key), ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew semaphore: %w", err)
+	}
+	return nil
+}
+
+// Release gives the holder's places back
+func (s *RedisSemaphore) Release(ctx context.Context, holder string, keys []string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZRem(ctx, s.key(key), holder)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release semaphore: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisSemaphore) key(slot string) string {
+	return s.prefix + ":" + slot
+}
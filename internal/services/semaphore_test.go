@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSemaphore runs the behaviour every semaphore shares
+func testSemaphore(t *testing.T, semaphore Semaphore) {
+	ctx := context.Background()
+	host := SemaphoreSlot{Key: "host:db", Limit: 2}
+	connA := SemaphoreSlot{Key: "connection:a", Limit: 1}
+	connB := SemaphoreSlot{Key: "connection:b", Limit: 1}
+
+	require.NoError(t, semaphore.Acquire(ctx, "job-1", []SemaphoreSlot{connA, host}, time.Minute))
+
+	// Acquiring again as the same holder renews instead of counting twice
+	require.NoError(t, semaphore.Acquire(ctx, "job-1", []SemaphoreSlot{connA, host}, time.Minute))
+
+	// A second backup of the same connection has to wait and takes no place on the host
+	err := semaphore.Acquire(ctx, "job-2", []SemaphoreSlot{connA, host}, time.Minute)
+	var full *SemaphoreFullError
+	require.True(t, errors.As(err, &full))
+	assert.Equal(t, connA, full.Slot)
+
+	require.NoError(t, semaphore.Acquire(ctx, "job-3", []SemaphoreSlot{connB, host}, time.Minute))
+
+	// The host is now full
+	err = semaphore.Acquire(ctx, "job-4", []SemaphoreSlot{{Key: "connection:c", Limit: 1}, host}, time.Minute)
+	require.True(t, errors.As(err, &full))
+	assert.Equal(t, host, full.Slot)
+
+	require.NoError(t, semaphore.Release(ctx, "job-1", []string{connA.Key, host.Key}))
+	require.NoError(t, semaphore.Acquire(ctx, "job-2", []SemaphoreSlot{connA, host}, time.Minute))
+
+	// Expired places free themselves unless renewed
+	slot := SemaphoreSlot{Key: "connection:lease", Limit: 1}
+	require.NoError(t, semaphore.Acquire(ctx, "stale", []SemaphoreSlot{slot}, 50*time.Millisecond))
+	require.Error(t, semaphore.Acquire(ctx, "next", []SemaphoreSlot{slot}, time.Minute))
+	require.NoError(t, semaphore.Renew(ctx, "stale", []string{slot.Key}, 150*time.Millisecond))
+	time.Sleep(75 * time.Millisecond)
+	require.Error(t, semaphore.Acquire(ctx, "next", []SemaphoreSlot{slot}, time.Minute))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, semaphore.Acquire(ctx, "next", []SemaphoreSlot{slot}, time.Minute))
+}
+
+func TestLocalSemaphore(t *testing.T) {
+	testSemaphore(t, NewLocalSemaphore())
+}
+
+func TestRedisSemaphore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	prefix := "dbackup:test:semaphore:" + time.Now().Format("150405.000000")
+	testSemaphore(t, NewRedisSemaphore(client, prefix))
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig holds the configuration for an SFTP object store
+type SFTPConfig struct {
+	Address  string // host:port
+	Username string
+	Password string
+	// PrivateKey is a PEM-encoded key used instead of the password when set
+	PrivateKey string
+	// HostKeyFingerprint is the server's SHA256 key fingerprint, e.g. "SHA256:..."
+	HostKeyFingerprint string
+	// KnownHosts holds known_hosts lines for the server, used when no fingerprint is set
+	KnownHosts            string
+	InsecureIgnoreHostKey bool
+	Root                  string
+	Timeout               time.Duration
+}
+
+// SFTPObjectStore stores objects as files on an SFTP server.
+// Each operation opens its own SSH session, so the store holds no connection
+// state and can be cached or dropped freely.
+type SFTPObjectStore struct {
+	config    SFTPConfig
+	sshConfig *ssh.ClientConfig
+}
+
+// NewSFTPObjectStore creates an SFTP object store
+func NewSFTPObjectStore(cfg *SFTPConfig) (*SFTPObjectStore, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("SFTP configuration is required")
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("SFTP address is required")
+	}
+	if cfg.Username == "" {
+		return nil, fmt.Errorf("SFTP username is required")
+	}
+	if cfg.Root == "" {
+		return nil, fmt.Errorf("SFTP root path is required")
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SFTP password or private key is required")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case cfg.HostKeyFingerprint != "":
+		expected := cfg.HostKeyFingerprint
+		if !strings.HasPrefix(expected, "SHA256:") {
+			expected = "SHA256:" + expected
+		}
+		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if actual := ssh.FingerprintSHA256(key); actual != expected {
+				return fmt.Errorf("host key fingerprint mismatch for %s: got %s", hostname, actual)
+			}
+			return nil
+		}
+	case cfg.KnownHosts != "":
+		var err error
+		hostKeyCallback, err = knownHostsCallback(cfg.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("SFTP host key fingerprint or known hosts are required")
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	config := *cfg
+	config.Root = path.Clean(cfg.Root)
+
+	return &SFTPObjectStore{
+		config: config,
+		sshConfig: &ssh.ClientConfig{
+			User:            cfg.Username,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         cfg.Timeout,
+		},
+	}, nil
+}
+
+// knownHostEntry is a host key line from a known_hosts file
+type knownHostEntry struct {
+	marker   string
+	patterns []string
+	key      ssh.PublicKey
+}
+
+// knownHostsCallback verifies host keys against known_hosts lines. Plain,
+// wildcard and hashed host patterns are supported; @revoked keys are refused.
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	var entries []knownHostEntry
+	rest := []byte(knownHosts)
+	for {
+		marker, hosts, key, _, next, err := ssh.ParseKnownHosts(rest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP known hosts: %w", err)
+		}
+		entries = append(entries, knownHostEntry{marker: marker, patterns: hosts, key: key})
+		rest = next
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("SFTP known hosts contain no host keys")
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		candidates := []string{knownhosts.Normalize(hostname)}
+		if remote != nil {
+			candidates = append(candidates, knownhosts.Normalize(remote.String()))
+		}
+
+		known := false
+		for _, entry := range entries {
+			if !entry.matches(candidates) {
+				continue
+			}
+			sameKey := bytes.Equal(entry.key.Marshal(), key.Marshal())
+			switch entry.marker {
+			case "revoked":
+				if sameKey {
+					return fmt.Errorf("host key for %s is revoked", hostname)
+				}
+			case "":
+				known = known || sameKey
+			}
+		}
+		if !known {
+			return fmt.Errorf("host key for %s is not in known hosts: got %s", hostname, ssh.FingerprintSHA256(key))
+		}
+		return nil
+	}, nil
+}
+
+// matches reports whether any candidate address matches the entry's host
+// patterns and none matches a negated pattern
+func (e knownHostEntry) matches(candidates []string) bool {
+	matched := false
+	for _, pattern := range e.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		for _, candidate := range candidates {
+			if !matchKnownHost(pattern, candidate) {
+				continue
+			}
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchKnownHost matches one known_hosts pattern, hashed ("|1|salt|hash") or
+// plain with * and ? wildcards, against a normalized address
+func matchKnownHost(pattern, address string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern[3:], "|")
+		if len(parts) != 2 {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return false
+		}
+		expected, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(address))
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+
+	// Brackets are literal in known_hosts, e.g. "[host]:2222"
+	pattern = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(pattern)
+	matched, err := path.Match(pattern, address)
+	return err == nil && matched
+}
+
+// sftpSession is an SFTP client together with the SSH connection it runs over
+type sftpSession struct {
+	*sftp.Client
+	conn *ssh.Client
+}
+
+// Close closes the SFTP client and its SSH connection
+func (s *sftpSession) Close() error {
+	err := s.Client.Close()
+	if connErr := s.conn.Close(); err == nil {
+		err = connErr
+	}
+	return err
+}
+
+// connect opens a new SFTP session
+func (s *SFTPObjectStore) connect(ctx context.Context) (*sftpSession, error) {
+	dialer := net.Dialer{Timeout: s.config.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SFTP server: %w", err)
+	}
+
+	conn, chans, reqs, err := ssh.NewClientConn(netConn, s.config.Address, s.sshConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("SSH handshake failed: %w", err)
+	}
+	sshClient := ssh.NewClient(conn, chans, reqs)
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("failed to start SFTP subsystem: %w", err)
+	}
+
+	return &sftpSession{Client: client, conn: sshClient}, nil
+}
+
+// Put uploads an object to a temporary file and renames it into place
+func (s *SFTPObjectStore) Put(ctx context.Context, key string, data io.Reader, contentType string) (*ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	if err := session.MkdirAll(path.Dir(target)); err != nil {
+		return nil, fmt.Errorf("failed to create remote directory: %w", err)
+	}
+
+	tmpPath := path.Join(path.Dir(target), fmt.Sprintf("%s%d", tempObjectPrefix, time.Now().UnixNano()))
+	file, err := session.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote file: %w", err)
+	}
+
+	size, err := file.ReadFrom(&contextReader{ctx: ctx, reader: data})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		session.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write remote file: %w", err)
+	}
+
+	if err := s.rename(session, tmpPath, target); err != nil {
+		session.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to move remote file into place: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         size,
+		LastModified: time.Now().UTC(),
+		ContentType:  contentType,
+	}, nil
+}
+
+// rename replaces target atomically when the server supports POSIX renames
+func (s *SFTPObjectStore) rename(session *sftpSession, from, to string) error {
+	if _, ok := session.HasExtension("posix-rename@openssh.com"); ok {
+		return session.PosixRename(from, to)
+	}
+	if err := session.Remove(to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return session.Rename(from, to)
+}
+
+// Get opens a remote file for streaming; closing the reader ends the session
+func (s *SFTPObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := session.Open(target)
+	if err != nil {
+		session.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to open remote file: %w", err)
+	}
+
+	return &sftpReader{File: file, session: session}, nil
+}
+
+// sftpReader closes its session along with the file
+type sftpReader struct {
+	*sftp.File
+	session *sftpSession
+}
+
+// Close closes the file and its session
+func (r *sftpReader) Close() error {
+	err := r.File.Close()
+	if sessionErr := r.session.Close(); err == nil {
+		err = sessionErr
+	}
+	return err
+}
+
+// Stat returns metadata for a remote file
+func (s *SFTPObjectStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	info, err := session.Stat(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+
+	return &ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// List walks the remote tree for objects whose keys start with prefix
+func (s *SFTPObjectStore) List(ctx context.Context, prefix string, limit int) ([]ObjectInfo, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	start := s.config.Root
+	if dir := path.Dir(prefix); dir != "." {
+		var err error
+		if start, err = s.path(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var objects []ObjectInfo
+	walker := session.Walk(start)
+	for walker.Step() && len(objects) < limit {
+		if err := walker.Err(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && walker.Path() == start {
+				break
+			}
+			return nil, fmt.Errorf("failed to list remote files: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		info := walker.Stat()
+		if info.IsDir() || strings.HasPrefix(info.Name(), tempObjectPrefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), s.config.Root), "/")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+	}
+
+	return objects, nil
+}
+
+// Delete removes a remote file
+func (s *SFTPObjectStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if err := session.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete remote file: %w", err)
+	}
+	return nil
+}
+
+// PresignGet is not supported for SFTP servers
+func (s *SFTPObjectStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// TestConnection checks that the server accepts our credentials and the root directory exists
+func (s *SFTPObjectStore) TestConnection(ctx context.Context) error {
+	session, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	info, err := session.Stat(s.config.Root)
+	if err != nil {
+		return fmt.Errorf("remote root directory is unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("remote root path is not a directory: %s", s.config.Root)
+	}
+	return nil
+}
+
+// path maps an object key to a remote path that cannot escape the root
+func (s *SFTPObjectStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" {
+		return "", fmt.Errorf("object key is required")
+	}
+	return path.Join(s.config.Root, cleaned), nil
+}
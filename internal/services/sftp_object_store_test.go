@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startTestSFTPServer serves the local filesystem over SFTP on a loopback port
+// and returns its address and host key fingerprint
+func startTestSFTPServer(t *testing.T, password string) (string, string) {
+	address, hostKey := startTestSFTPServerWithKey(t, password)
+	return address, ssh.FingerprintSHA256(hostKey)
+}
+
+// startTestSFTPServerWithKey is startTestSFTPServer returning the host public key
+func startTestSFTPServerWithKey(t *testing.T, password string) (string, ssh.PublicKey) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == "backup" && string(pass) == password {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSFTPConn(conn, config)
+		}
+	}()
+
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func serveTestSFTPConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(channel)
+					if err == nil {
+						server.Serve()
+						server.Close()
+					}
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestSFTPObjectStore(t *testing.T) {
+	address, fingerprint := startTestSFTPServer(t, "s3cret")
+
+	store, err := NewSFTPObjectStore(&SFTPConfig{
+		Address:            address,
+		Username:           "backup",
+		Password:           "s3cret",
+		HostKeyFingerprint: fingerprint,
+		Root:               t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	exerciseObjectStore(t, store)
+}
+
+func TestSFTPObjectStore_VerifiesHostAndCredentials(t *testing.T) {
+	address, fingerprint := startTestSFTPServer(t, "s3cret")
+	root := t.TempDir()
+
+	_, err := NewSFTPObjectStore(&SFTPConfig{Address: address, Username: "backup", Password: "s3cret", Root: root})
+	assert.Error(t, err, "host key verification must be explicit")
+
+	store, err := NewSFTPObjectStore(&SFTPConfig{
+		Address:            address,
+		Username:           "backup",
+		Password:           "s3cret",
+		HostKeyFingerprint: "SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		Root:               root,
+	})
+	require.NoError(t, err)
+	assert.ErrorContains(t, store.TestConnection(t.Context()), "fingerprint mismatch")
+
+	store, err = NewSFTPObjectStore(&SFTPConfig{
+		Address:            address,
+		Username:           "backup",
+		Password:           "wrong",
+		HostKeyFingerprint: fingerprint,
+		Root:               root,
+	})
+	require.NoError(t, err)
+	assert.Error(t, store.TestConnection(t.Context()))
+
+	store, err = NewSFTPObjectStore(&SFTPConfig{
+		Address:               address,
+		Username:              "backup",
+		Password:              "s3cret",
+		InsecureIgnoreHostKey: true,
+		Root:                  root,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, store.TestConnection(t.Context()))
+}
+
+func TestSFTPObjectStore_KnownHosts(t *testing.T) {
+	address, hostKey := startTestSFTPServerWithKey(t, "s3cret")
+	_, otherKey := startTestSFTPServerWithKey(t, "s3cret")
+	root := t.TempDir()
+
+	connect := func(knownHosts string) error {
+		store, err := NewSFTPObjectStore(&SFTPConfig{
+			Address:    address,
+			Username:   "backup",
+			Password:   "s3cret",
+			KnownHosts: knownHosts,
+			Root:       root,
+		})
+		if err != nil {
+			return err
+		}
+		return store.TestConnection(t.Context())
+	}
+
+	assert.NoError(t, connect(knownhosts.Line([]string{address}, hostKey)))
+	assert.NoError(t, connect("# backup server\n"+knownhosts.Line([]string{"127.0.0.*:*"}, hostKey)))
+	assert.NoError(t, connect(knownhosts.Line([]string{knownhosts.HashHostname(knownhosts.Normalize(address))}, hostKey)))
+
+	assert.ErrorContains(t, connect(knownhosts.Line([]string{address}, otherKey)), "not in known hosts")
+	assert.ErrorContains(t, connect(knownhosts.Line([]string{"backup.example.com"}, hostKey)), "not in known hosts")
+	assert.ErrorContains(t, connect(knownhosts.Line([]string{address}, hostKey)+"\n@revoked "+knownhosts.Line([]string{"*"}, hostKey)), "revoked")
+	assert.Error(t, connect("not a known hosts line"))
+}
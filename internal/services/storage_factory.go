@@ -1,42 +1,150 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/dbackup/backend-go/internal/encryption"
 	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
 )
 
+// ErrNoDefaultStorage is returned when neither the user nor their teams have a default storage configuration
+var ErrNoDefaultStorage = errors.New("no default storage configuration found")
+
+// StorageResolver resolves storage configurations and their object stores for background jobs
+type StorageResolver interface {
+	ResolveStorage(ctx context.Context, uid string, userID uint, teamID *uint) (*models.StorageConfiguration, ObjectStore, error)
+	ResolveStorageByID(ctx context.Context, id uint) (*models.StorageConfiguration, ObjectStore, error)
+	InvalidateCache(uid string)
+}
+
+// cachedObjectStore is a store built from a configuration as of its UpdatedAt
+type cachedObjectStore struct {
+	store     ObjectStore
+	updatedAt time.Time
+}
+
 // StorageFactory creates storage services from configuration
-type StorageFactory struct{}
+type StorageFactory struct {
+	db           *gorm.DB
+	encService   *encryption.Service
+	newS3Service func(cfg *S3Config) (S3ServiceInterface, error)
+	// Directories local storage configurations may point into
+	localRoots []string
+
+	mu    sync.RWMutex
+	cache map[string]cachedObjectStore
+}
 
 // NewStorageFactory creates a new storage factory
-func NewStorageFactory() *StorageFactory {
-	return &StorageFactory{}
+func NewStorageFactory(db *gorm.DB, encService *encryption.Service) *StorageFactory {
+	return &StorageFactory{
+		db:         db,
+		encService: encService,
+		newS3Service: func(cfg *S3Config) (S3ServiceInterface, error) {
+			return NewS3Service(cfg)
+		},
+		cache: make(map[string]cachedObjectStore),
+	}
+}
+
+// WithS3ServiceConstructor overrides how S3 services are constructed, e.g. for tests
+func (f *StorageFactory) WithS3ServiceConstructor(fn func(cfg *S3Config) (S3ServiceInterface, error)) *StorageFactory {
+	f.newS3Service = fn
+	return f
 }
 
-// CreateS3Service creates an S3 service from a storage configuration
+// WithLocalStorageRoots sets the directories local storage configurations may
+// point into. Without any, local storage configurations are refused.
+func (f *StorageFactory) WithLocalStorageRoots(roots []string) *StorageFactory {
+	f.localRoots = nil
+	for _, root := range roots {
+		if root = strings.TrimSpace(root); root != "" {
+			f.localRoots = append(f.localRoots, filepath.Clean(root))
+		}
+	}
+	return f
+}
+
+// checkLocalRoot ensures a local storage directory lies within an allowed root
+func (f *StorageFactory) checkLocalRoot(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("bucket must be an absolute directory path for local storage")
+	}
+	if len(f.localRoots) == 0 {
+		return fmt.Errorf("local storage is not enabled on this server")
+	}
+
+	dir = filepath.Clean(dir)
+	for _, root := range f.localRoots {
+		rel, err := filepath.Rel(root, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("local storage must be within one of the allowed directories: %s", strings.Join(f.localRoots, ", "))
+}
+
+// CreateS3Service creates an S3 service from a stored storage configuration.
+// Credentials are decrypted on a copy so the caller's model is left untouched.
 func (f *StorageFactory) CreateS3Service(config *models.StorageConfiguration) (S3ServiceInterface, error) {
 	if config == nil {
 		return nil, fmt.Errorf("storage configuration is required")
 	}
 
+	decrypted, err := f.decrypt(config)
+	if err != nil {
+		return nil, err
+	}
+	return f.buildS3Service(decrypted)
+}
+
+// decrypt returns a copy of the configuration with plaintext credentials
+func (f *StorageFactory) decrypt(config *models.StorageConfiguration) (*models.StorageConfiguration, error) {
+	decrypted := *config
+	if f.encService != nil {
+		if err := decrypted.DecryptCredentials(f.encService); err != nil {
+			return nil, fmt.Errorf("failed to decrypt storage credentials: %w", err)
+		}
+	}
+	return &decrypted, nil
+}
+
+// buildS3Service creates an S3 service from a decrypted configuration
+func (f *StorageFactory) buildS3Service(decrypted *models.StorageConfiguration) (S3ServiceInterface, error) {
 	var endpoint string
-	if config.Endpoint != nil {
-		endpoint = *config.Endpoint
+	if decrypted.Endpoint != nil {
+		endpoint = *decrypted.Endpoint
+	}
+
+	var sessionToken string
+	if decrypted.SessionToken != nil {
+		sessionToken = *decrypted.SessionToken
 	}
 
 	// Convert storage configuration to S3Config
 	s3Config := &S3Config{
-		Region:          config.Region,
-		AccessKey:       config.AccessKey,
-		SecretKey:       config.SecretKey,
+		Region:          decrypted.Region,
+		AccessKey:       decrypted.AccessKey,
+		SecretKey:       decrypted.SecretKey,
+		SessionToken:    sessionToken,
 		Endpoint:        endpoint,
-		DefaultBucket:   config.Bucket,
-		UsePathStyle:    config.ForcePathStyle,
-		DisableSSL:      !config.UseSSL,
+		DefaultBucket:   decrypted.Bucket,
+		UsePathStyle:    decrypted.ForcePathStyle,
+		DisableSSL:      !decrypted.UseSSL,
 		MaxUploadSize:   100 * 1024 * 1024, // 100MB default
-		UploadTimeout:   config.Timeout,     // Use timeout from config
-		DownloadTimeout: config.Timeout,     // Use timeout from config
+		UploadTimeout:   decrypted.Timeout, // Use timeout from config
+		DownloadTimeout: decrypted.Timeout, // Use timeout from config
 	}
 
 	// Validate configuration
@@ -45,7 +153,7 @@ func (f *StorageFactory) CreateS3Service(config *models.StorageConfiguration) (S
 	}
 
 	// Create S3 service
-	service, err := NewS3Service(s3Config)
+	service, err := f.newS3Service(s3Config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create S3 service: %w", err)
 	}
@@ -53,16 +161,309 @@ func (f *StorageFactory) CreateS3Service(config *models.StorageConfiguration) (S
 	return service, nil
 }
 
+// CreateObjectStore creates the object store for a stored configuration's provider
+func (f *StorageFactory) CreateObjectStore(config *models.StorageConfiguration) (ObjectStore, error) {
+	if config == nil {
+		return nil, fmt.Errorf("storage configuration is required")
+	}
+
+	decrypted, err := f.decrypt(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch decrypted.Provider {
+	case models.StorageProviderLocal:
+		if err := f.checkLocalRoot(decrypted.Bucket); err != nil {
+			return nil, err
+		}
+		return NewLocalObjectStore(decrypted.Bucket)
+	case models.StorageProviderSFTP:
+		return NewSFTPObjectStore(sftpConfigFromStorage(decrypted))
+	default:
+		service, err := f.buildS3Service(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3ObjectStore(service, decrypted.Bucket), nil
+	}
+}
+
+// sftpConfigFromStorage maps a decrypted configuration onto SFTP settings.
+// The endpoint holds the host, the access key the username, the secret key a
+// password or PEM private key, and the bucket the remote root directory.
+func sftpConfigFromStorage(config *models.StorageConfiguration) *SFTPConfig {
+	cfg := &SFTPConfig{
+		Username: config.AccessKey,
+		Root:     config.Bucket,
+		Timeout:  config.Timeout,
+	}
+
+	if config.Endpoint != nil {
+		cfg.Address = sftpAddress(*config.Endpoint)
+	}
+	if config.HostKeyFingerprint != nil {
+		cfg.HostKeyFingerprint = *config.HostKeyFingerprint
+	}
+	if config.KnownHosts != nil {
+		cfg.KnownHosts = *config.KnownHosts
+	}
+	if strings.Contains(config.SecretKey, "PRIVATE KEY-----") {
+		cfg.PrivateKey = config.SecretKey
+	} else {
+		cfg.Password = config.SecretKey
+	}
+
+	return cfg
+}
+
+// sftpAddress normalizes "sftp://host:port", "host:port" or "host" to host:port
+func sftpAddress(endpoint string) string {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	return host
+}
+
+// GetObjectStore returns a cached object store for a stored configuration, building
+// a new one when the configuration has changed since the store was created
+func (f *StorageFactory) GetObjectStore(config *models.StorageConfiguration) (ObjectStore, error) {
+	if config == nil {
+		return nil, fmt.Errorf("storage configuration is required")
+	}
+	if config.UID == "" {
+		return f.CreateObjectStore(config)
+	}
+
+	f.mu.RLock()
+	cached, ok := f.cache[config.UID]
+	f.mu.RUnlock()
+	if ok && cached.updatedAt.Equal(config.UpdatedAt) {
+		return cached.store, nil
+	}
+
+	store, err := f.CreateObjectStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.cache[config.UID] = cachedObjectStore{store: store, updatedAt: config.UpdatedAt}
+	f.mu.Unlock()
+
+	return store, nil
+}
+
+// InvalidateCache drops the cached object store for a storage configuration
+func (f *StorageFactory) InvalidateCache(uid string) {
+	f.mu.Lock()
+	delete(f.cache, uid)
+	f.mu.Unlock()
+}
+
+// ResolveStorage loads the configuration with the given UID that the user can access
+// and returns it with its object store. An empty UID resolves the user's or team's default.
+func (f *StorageFactory) ResolveStorage(ctx context.Context, uid string, userID uint, teamID *uint) (*models.StorageConfiguration, ObjectStore, error) {
+	var config *models.StorageConfiguration
+	if uid == "" {
+		var err error
+		config, err = f.GetDefaultStorageConfiguration(ctx, userID, teamID)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if f.db == nil {
+			return nil, nil, fmt.Errorf("database is required to resolve storage configuration")
+		}
+		db := f.db.WithContext(ctx)
+		teamIDs := db.Model(&models.TeamMember{}).Select("team_id").
+			Where("user_id = ? AND is_active = ?", userID, true)
+
+		config = &models.StorageConfiguration{}
+		err := db.Where("uid = ? AND is_active = ?", uid, true).
+			Where("user_id = ? OR team_id IN (?)", userID, teamIDs).
+			First(config).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load storage configuration %s: %w", uid, err)
+		}
+	}
+
+	store, err := f.GetObjectStore(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, store, nil
+}
+
+// ResolveStorageByID loads a configuration by ID and returns it with its object store.
+// Soft-deleted configurations are included so existing backups stay reachable.
+func (f *StorageFactory) ResolveStorageByID(ctx context.Context, id uint) (*models.StorageConfiguration, ObjectStore, error) {
+	if f.db == nil {
+		return nil, nil, fmt.Errorf("database is required to resolve storage configuration")
+	}
+
+	var config models.StorageConfiguration
+	if err := f.db.WithContext(ctx).Unscoped().First(&config, id).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load storage configuration %d: %w", id, err)
+	}
+
+	store, err := f.GetObjectStore(&config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &config, store, nil
+}
+
 // CreateS3ServiceFromUser creates an S3 service using user's default storage configuration
 func (f *StorageFactory) CreateS3ServiceFromUser(user *models.User) (S3ServiceInterface, error) {
 	if user == nil {
 		return nil, fmt.Errorf("user is required")
 	}
 
-	// For now, we'll need to get the user's storage configuration
-	// This would typically come from the database
-	// TODO: Implement storage configuration retrieval from database
-	return nil, fmt.Errorf("user storage configuration not implemented yet")
+	config, err := f.GetDefaultStorageConfiguration(context.Background(), user.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.CreateS3Service(config)
+}
+
+// GetDefaultStorageConfiguration resolves the default storage configuration for a user.
+// If teamID is set the team default is preferred, otherwise the user's personal default
+// is used before falling back to the default of any team the user belongs to.
+func (f *StorageFactory) GetDefaultStorageConfiguration(ctx context.Context, userID uint, teamID *uint) (*models.StorageConfiguration, error) {
+	if f.db == nil {
+		return nil, fmt.Errorf("database is required to resolve storage configuration")
+	}
+	db := f.db.WithContext(ctx)
+
+	var config models.StorageConfiguration
+	findDefault := func(query *gorm.DB) (bool, error) {
+		err := query.Where("is_default = ? AND is_active = ?", true, true).
+			Order("updated_at DESC").First(&config).Error
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if teamID != nil {
+		found, err := findDefault(db.Where("team_id = ?", *teamID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch team storage configuration: %w", err)
+		}
+		if found {
+			return &config, nil
+		}
+	}
+
+	found, err := findDefault(db.Where("user_id = ? AND team_id IS NULL", userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user storage configuration: %w", err)
+	}
+	if found {
+		return &config, nil
+	}
+
+	teamIDs := db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+	found, err = findDefault(db.Where("team_id IN (?)", teamIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch team storage configuration: %w", err)
+	}
+	if found {
+		return &config, nil
+	}
+
+	return nil, ErrNoDefaultStorage
+}
+
+// TestStorageConfiguration checks connectivity and runs a write/read/delete probe
+func (f *StorageFactory) TestStorageConfiguration(ctx context.Context, config *models.StorageConfiguration) *models.StorageTestResult {
+	start := time.Now()
+	result := &models.StorageTestResult{TestedAt: start}
+
+	finish := func(message string) *models.StorageTestResult {
+		result.Message = message
+		result.Duration = time.Since(start).Milliseconds()
+		return result
+	}
+
+	runStep := func(name string, fn func() error) bool {
+		stepStart := time.Now()
+		err := fn()
+		step := models.StorageTestStep{
+			Name:     name,
+			Success:  err == nil,
+			Duration: time.Since(stepStart).Milliseconds(),
+		}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, step)
+		return err == nil
+	}
+
+	var store ObjectStore
+	if !runStep("configure", func() error {
+		var err error
+		store, err = f.CreateObjectStore(config)
+		return err
+	}) {
+		return finish("Invalid storage configuration")
+	}
+
+	if !runStep("connect", func() error {
+		return store.TestConnection(ctx)
+	}) {
+		return finish("Failed to connect to storage provider")
+	}
+
+	probeKey := config.GetFullPath(fmt.Sprintf(".dbackup-probe/%s-%d", config.UID, time.Now().UnixNano()))
+	probeData := []byte("dbackup connectivity probe " + start.UTC().Format(time.RFC3339))
+
+	if !runStep("write", func() error {
+		_, err := store.Put(ctx, probeKey, bytes.NewReader(probeData), "text/plain")
+		return err
+	}) {
+		return finish("Failed to write probe object")
+	}
+
+	readOK := runStep("read", func() error {
+		reader, err := store.Get(ctx, probeKey)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, probeData) {
+			return fmt.Errorf("probe object content mismatch")
+		}
+		return nil
+	})
+
+	// Always try to clean up the probe object
+	deleteOK := runStep("delete", func() error {
+		return store.Delete(ctx, probeKey)
+	})
+
+	if !readOK {
+		return finish("Failed to read probe object")
+	}
+	if !deleteOK {
+		return finish("Failed to delete probe object")
+	}
+
+	result.Success = true
+	return finish("Storage connection test successful")
 }
 
 // GetSupportedProviders returns a list of supported storage providers
@@ -75,6 +476,8 @@ func (f *StorageFactory) GetSupportedProviders() []string {
 		"wasabi",
 		"linode-object-storage",
 		"custom-s3",
+		"local",
+		"sftp",
 	}
 }
 
@@ -88,16 +491,18 @@ func (f *StorageFactory) ValidateStorageConfiguration(config *models.StorageConf
 		return fmt.Errorf("storage provider is required")
 	}
 
-	if config.Region == "" {
+	if config.Region == "" && config.Provider.IsS3Compatible() {
 		return fmt.Errorf("region is required")
 	}
 
-	if config.AccessKey == "" {
-		return fmt.Errorf("access key is required")
-	}
+	if config.Provider.RequiresCredentials() {
+		if config.AccessKey == "" {
+			return fmt.Errorf("access key is required")
+		}
 
-	if config.SecretKey == "" {
-		return fmt.Errorf("secret key is required")
+		if config.SecretKey == "" {
+			return fmt.Errorf("secret key is required")
+		}
 	}
 
 	if config.Bucket == "" {
@@ -133,6 +538,28 @@ func (f *StorageFactory) ValidateStorageConfiguration(config *models.StorageConf
 			endpoint := fmt.Sprintf("https://s3.%s.wasabisys.com", config.Region)
 			config.Endpoint = &endpoint
 		}
+	case models.StorageProviderLocal:
+		// The bucket is the root directory, e.g. an NFS mount point, and must
+		// lie within a directory the operator allowed
+		if err := f.checkLocalRoot(config.Bucket); err != nil {
+			return err
+		}
+	case models.StorageProviderSFTP:
+		// The endpoint is the server and the bucket the remote root directory
+		if config.Endpoint == nil || *config.Endpoint == "" {
+			return fmt.Errorf("endpoint is required for SFTP")
+		}
+		// The host key is always verified; skip_ssl_verify only concerns TLS
+		hasFingerprint := config.HostKeyFingerprint != nil && *config.HostKeyFingerprint != ""
+		hasKnownHosts := config.KnownHosts != nil && *config.KnownHosts != ""
+		if !hasFingerprint && !hasKnownHosts {
+			return fmt.Errorf("host key fingerprint or known hosts are required for SFTP")
+		}
+		if hasKnownHosts {
+			if _, err := knownHostsCallback(*config.KnownHosts); err != nil {
+				return err
+			}
+		}
 	default:
 		supportedProviders := f.GetSupportedProviders()
 		return fmt.Errorf("unsupported storage provider: %s. Supported providers: %v", config.Provider, supportedProviders)
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryS3Service is an in-memory S3ServiceInterface for tests
+type memoryS3Service struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	connectErr error
+	uploadErr  error
+}
+
+func newMemoryS3Service() *memoryS3Service {
+	return &memoryS3Service{objects: make(map[string][]byte)}
+}
+
+func (m *memoryS3Service) UploadFile(ctx context.Context, bucket, key string, data io.Reader, contentType string) (*S3UploadResult, error) {
+	if m.uploadErr != nil {
+		return nil, m.uploadErr
+	}
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.objects[bucket+"/"+key] = content
+	m.mu.Unlock()
+	return &S3UploadResult{Bucket: bucket, Key: key, Size: int64(len(content))}, nil
+}
+
+func (m *memoryS3Service) DownloadFile(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *memoryS3Service) DeleteFile(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	delete(m.objects, bucket+"/"+key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryS3Service) GetFileInfo(ctx context.Context, bucket, key string) (*S3FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return &S3FileInfo{Key: key, Size: int64(len(content))}, nil
+}
+
+func (m *memoryS3Service) FileExists(ctx context.Context, bucket, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.objects[bucket+"/"+key]
+	return ok, nil
+}
+
+func (m *memoryS3Service) CreateBucket(ctx context.Context, bucket, region string) error {
+	return nil
+}
+
+func (m *memoryS3Service) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryS3Service) ListFiles(ctx context.Context, bucket, prefix string, maxKeys int) (*S3ListResult, error) {
+	return &S3ListResult{}, nil
+}
+
+func (m *memoryS3Service) GeneratePresignedURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("https://%s.example.com/%s?expires=%d", bucket, key, int(expiry.Seconds())), nil
+}
+
+func (m *memoryS3Service) GenerateUploadURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("https://%s.example.com/%s?upload=1", bucket, key), nil
+}
+
+func (m *memoryS3Service) TestConnection(ctx context.Context) error {
+	return m.connectErr
+}
+
+func testStorageConfiguration(t *testing.T, encService *encryption.Service, userID uint) *models.StorageConfiguration {
+	endpoint := "http://localhost:9000"
+	token := "session-token"
+	config := &models.StorageConfiguration{
+		Name:         "Test MinIO",
+		Provider:     models.StorageProviderMinIO,
+		Region:       "us-east-1",
+		Endpoint:     &endpoint,
+		AccessKey:    "access-key",
+		SecretKey:    "secret-key",
+		SessionToken: &token,
+		Bucket:       "backups",
+		UseSSL:       false,
+		IsActive:     true,
+		UserID:       userID,
+	}
+	config.SetDefaultValues()
+	require.NoError(t, config.EncryptCredentials(encService))
+	return config
+}
+
+func TestStorageFactory_CreateS3Service_DecryptsCredentials(t *testing.T) {
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	config := testStorageConfiguration(t, encService, 1)
+	encryptedAccessKey := config.AccessKey
+
+	var captured *S3Config
+	factory := NewStorageFactory(nil, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+		captured = cfg
+		return newMemoryS3Service(), nil
+	})
+
+	service, err := factory.CreateS3Service(config)
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+
+	require.NotNil(t, captured)
+	assert.Equal(t, "access-key", captured.AccessKey)
+	assert.Equal(t, "secret-key", captured.SecretKey)
+	assert.Equal(t, "session-token", captured.SessionToken)
+	assert.True(t, captured.DisableSSL)
+
+	// The caller's model keeps its encrypted values
+	assert.Equal(t, encryptedAccessKey, config.AccessKey)
+}
+
+func TestStorageFactory_GetDefaultStorageConfiguration(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.StorageConfiguration{}, &models.TeamMember{}))
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	factory := NewStorageFactory(db, encService)
+	ctx := context.Background()
+
+	_, err := factory.GetDefaultStorageConfiguration(ctx, 1, nil)
+	assert.True(t, errors.Is(err, ErrNoDefaultStorage))
+
+	// Team default is used when the user has no personal default
+	teamID := uint(10)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: 1, Role: models.TeamRoleMember, IsActive: true}).Error)
+	teamConfig := testStorageConfiguration(t, encService, 2)
+	teamConfig.Name = "Team storage"
+	teamConfig.TeamID = &teamID
+	teamConfig.IsDefault = true
+	require.NoError(t, db.Create(teamConfig).Error)
+
+	config, err := factory.GetDefaultStorageConfiguration(ctx, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, teamConfig.UID, config.UID)
+
+	// Personal default wins over team default
+	personal := testStorageConfiguration(t, encService, 1)
+	personal.Name = "Personal storage"
+	personal.IsDefault = true
+	require.NoError(t, db.Create(personal).Error)
+
+	config, err = factory.GetDefaultStorageConfiguration(ctx, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, personal.UID, config.UID)
+
+	// Explicit team context prefers the team default
+	config, err = factory.GetDefaultStorageConfiguration(ctx, 1, &teamID)
+	require.NoError(t, err)
+	assert.Equal(t, teamConfig.UID, config.UID)
+
+	// CreateS3ServiceFromUser resolves through the same lookup
+	factory.WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+		return newMemoryS3Service(), nil
+	})
+	service, err := factory.CreateS3ServiceFromUser(&models.User{ID: 1})
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+}
+
+func TestStorageFactory_TestStorageConfiguration(t *testing.T) {
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	config := testStorageConfiguration(t, encService, 1)
+	config.UID = "storage-uid"
+
+	t.Run("successful probe", func(t *testing.T) {
+		fake := newMemoryS3Service()
+		factory := NewStorageFactory(nil, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+			return fake, nil
+		})
+
+		result := factory.TestStorageConfiguration(context.Background(), config)
+		assert.True(t, result.Success)
+		require.Len(t, result.Steps, 5)
+		for _, step := range result.Steps {
+			assert.True(t, step.Success, step.Name)
+		}
+		assert.Empty(t, fake.objects, "probe object should be deleted")
+	})
+
+	t.Run("connection failure", func(t *testing.T) {
+		fake := newMemoryS3Service()
+		fake.connectErr = errors.New("access denied")
+		factory := NewStorageFactory(nil, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+			return fake, nil
+		})
+
+		result := factory.TestStorageConfiguration(context.Background(), config)
+		assert.False(t, result.Success)
+		require.Len(t, result.Steps, 2)
+		assert.Equal(t, "connect", result.Steps[1].Name)
+		assert.Equal(t, "access denied", result.Steps[1].Error)
+	})
+
+	t.Run("write failure", func(t *testing.T) {
+		fake := newMemoryS3Service()
+		fake.uploadErr = errors.New("read-only bucket")
+		factory := NewStorageFactory(nil, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+			return fake, nil
+		})
+
+		result := factory.TestStorageConfiguration(context.Background(), config)
+		assert.False(t, result.Success)
+		assert.Equal(t, "Failed to write probe object", result.Message)
+	})
+}
+
+func TestStorageFactory_ResolveStorage_CachesClients(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.StorageConfiguration{}, &models.TeamMember{}))
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	ctx := context.Background()
+
+	var buckets []string
+	factory := NewStorageFactory(db, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+		buckets = append(buckets, cfg.DefaultBucket)
+		return newMemoryS3Service(), nil
+	})
+
+	mine := testStorageConfiguration(t, encService, 1)
+	mine.Bucket = "user-one"
+	require.NoError(t, db.Create(mine).Error)
+	theirs := testStorageConfiguration(t, encService, 2)
+	theirs.Bucket = "user-two"
+	require.NoError(t, db.Create(theirs).Error)
+
+	config, first, err := factory.ResolveStorage(ctx, mine.UID, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "user-one", config.Bucket)
+
+	// Repeated lookups reuse the client
+	_, second, err := factory.ResolveStorage(ctx, mine.UID, 1, nil)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	_, byID, err := factory.ResolveStorageByID(ctx, mine.ID)
+	require.NoError(t, err)
+	assert.Same(t, first, byID)
+	assert.Equal(t, []string{"user-one"}, buckets)
+
+	// Other users' configurations are not accessible
+	_, _, err = factory.ResolveStorage(ctx, theirs.UID, 1, nil)
+	assert.Error(t, err)
+
+	// Changes to the configuration rebuild the client
+	require.NoError(t, db.Model(mine).Updates(map[string]interface{}{
+		"bucket":     "user-one-renamed",
+		"updated_at": mine.UpdatedAt.Add(time.Second),
+	}).Error)
+	config, third, err := factory.ResolveStorage(ctx, mine.UID, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "user-one-renamed", config.Bucket)
+	assert.NotSame(t, first, third)
+
+	// Explicit invalidation drops the cached client
+	factory.InvalidateCache(mine.UID)
+	_, fourth, err := factory.ResolveStorage(ctx, mine.UID, 1, nil)
+	require.NoError(t, err)
+	assert.NotSame(t, third, fourth)
+	assert.Equal(t, []string{"user-one", "user-one-renamed", "user-one-renamed"}, buckets)
+
+	// An empty UID resolves the default configuration
+	_, _, err = factory.ResolveStorage(ctx, "", 1, nil)
+	assert.ErrorIs(t, err, ErrNoDefaultStorage)
+}
+
+func TestStorageFactory_CreateObjectStore_Providers(t *testing.T) {
+	encService := encryption.NewService("test-key-for-testing-123456789012")
+	factory := NewStorageFactory(nil, encService).WithS3ServiceConstructor(func(cfg *S3Config) (S3ServiceInterface, error) {
+		return newMemoryS3Service(), nil
+	})
+
+	store, err := factory.CreateObjectStore(testStorageConfiguration(t, encService, 1))
+	require.NoError(t, err)
+	assert.IsType(t, &S3ObjectStore{}, store)
+
+	root := t.TempDir()
+	local := &models.StorageConfiguration{
+		UID:      "local-config",
+		Provider: models.StorageProviderLocal,
+		Bucket:   filepath.Join(root, "nfs"),
+	}
+
+	// Local storage is refused until the operator allows a root directory
+	assert.ErrorContains(t, factory.ValidateStorageConfiguration(local), "not enabled")
+	_, err = factory.CreateObjectStore(local)
+	assert.Error(t, err)
+	result := factory.TestStorageConfiguration(context.Background(), local)
+	assert.False(t, result.Success)
+
+	factory.WithLocalStorageRoots([]string{root})
+	require.NoError(t, os.Mkdir(local.Bucket, 0o755))
+	require.NoError(t, factory.ValidateStorageConfiguration(local))
+	store, err = factory.CreateObjectStore(local)
+	require.NoError(t, err)
+	assert.IsType(t, &LocalObjectStore{}, store)
+
+	// The full probe runs against the local directory
+	result = factory.TestStorageConfiguration(context.Background(), local)
+	assert.True(t, result.Success, result.Message)
+	assert.Len(t, result.Steps, 5)
+
+	for _, bucket := range []string{"relative/path", "/etc", filepath.Join(root, "..", "escape"), root + "-sibling"} {
+		local.Bucket = bucket
+		assert.Error(t, factory.ValidateStorageConfiguration(local), bucket)
+		_, err = factory.CreateObjectStore(local)
+		assert.Error(t, err, bucket)
+	}
+
+	endpoint := "sftp://backup.example.com:2222"
+	fingerprint := "SHA256:abc"
+	sftpConfig := &models.StorageConfiguration{
+		Provider:           models.StorageProviderSFTP,
+		Endpoint:           &endpoint,
+		AccessKey:          "backup",
+		SecretKey:          "password",
+		Bucket:             "/srv/backups",
+		HostKeyFingerprint: &fingerprint,
+	}
+	require.NoError(t, factory.ValidateStorageConfiguration(sftpConfig))
+
+	mapped := sftpConfigFromStorage(sftpConfig)
+	assert.Equal(t, "backup.example.com:2222", mapped.Address)
+	assert.Equal(t, "password", mapped.Password)
+	assert.Empty(t, mapped.PrivateKey)
+	assert.Equal(t, "backup.example.com:22", sftpAddress("backup.example.com"))
+
+	require.NoError(t, sftpConfig.EncryptCredentials(encService))
+	store, err = factory.CreateObjectStore(sftpConfig)
+	require.NoError(t, err)
+	assert.IsType(t, &SFTPObjectStore{}, store)
+
+	// Skipping TLS verification does not skip host key verification
+	sftpConfig.HostKeyFingerprint = nil
+	sftpConfig.SkipSSLVerify = true
+	assert.Error(t, factory.ValidateStorageConfiguration(sftpConfig))
+	assert.False(t, sftpConfigFromStorage(sftpConfig).InsecureIgnoreHostKey)
+
+	knownHosts := "not a known hosts line"
+	sftpConfig.KnownHosts = &knownHosts
+	assert.Error(t, factory.ValidateStorageConfiguration(sftpConfig))
+	knownHosts = "[backup.example.com]:2222 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	assert.NoError(t, factory.ValidateStorageConfiguration(sftpConfig))
+	assert.Equal(t, knownHosts, sftpConfigFromStorage(sftpConfig).KnownHosts)
+}
+
+func TestS3ObjectStore(t *testing.T) {
+	ctx := context.Background()
+	service := newMemoryS3Service()
+	store := NewS3ObjectStore(service, "primary")
+
+	// Non-seekable bodies are sized by counting
+	info, err := store.Put(ctx, "backups/app.backup", io.LimitReader(bytes.NewReader([]byte("contents")), 8), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Contains(t, service.objects, "primary/backups/app.backup")
+
+	other := ObjectStoreForBucket(store, "archive")
+	_, err = other.Put(ctx, "backups/app.backup", bytes.NewReader([]byte("old")), "")
+	require.NoError(t, err)
+	assert.Contains(t, service.objects, "archive/backups/app.backup")
+
+	reader, err := store.Get(ctx, "backups/app.backup")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "contents", string(data))
+
+	url, err := store.PresignGet(ctx, "backups/app.backup", time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, url, "primary.example.com")
+
+	require.NoError(t, store.Delete(ctx, "backups/app.backup"))
+	assert.NotContains(t, service.objects, "primary/backups/app.backup")
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// Table actions checked by the table policy
+const (
+	TableActionRead    = "read"
+	TableActionBackup  = "backup"
+	TableActionRestore = "restore"
+)
+
+var (
+	// ErrTableAccessDenied is returned when a user lacks a table permission
+	ErrTableAccessDenied = errors.New("table access denied")
+
+	// ErrPermissionManagementDenied is returned when a user may not grant or revoke table permissions
+	ErrPermissionManagementDenied = errors.New("not allowed to manage table permissions")
+
+	// ErrTableNotCatalogued is returned when granting on a table that has not been discovered
+	ErrTableNotCatalogued = errors.New("table has not been discovered for this connection")
+
+	// ErrInvalidTablePermission is returned when a grant request cannot be applied
+	ErrInvalidTablePermission = errors.New("invalid table permission")
+
+	// ErrInvalidTableName is returned when a requested table name is a pattern or ambiguous
+	ErrInvalidTableName = errors.New("invalid table name")
+)
+
+// tablePatternChars are the wildcards and quotes of pg_dump and SQL LIKE
+// patterns; requested table names must name exactly one catalogued table
+const tablePatternChars = `*?[]%"`
+
+// TableAccessError lists the tables a user may not act on
+type TableAccessError struct {
+	Action string
+	Tables []string
+	// AllTables is set when the user may not act on the whole database
+	AllTables bool
+}
+
+func (e *TableAccessError) Error() string {
+	if e.AllTables {
+		return fmt.Sprintf("%s permission required for all tables", e.Action)
+	}
+	return fmt.Sprintf("%s permission denied for tables: %s", e.Action, strings.Join(e.Tables, ", "))
+}
+
+// Is reports the error as ErrTableAccessDenied
+func (e *TableAccessError) Is(target error) bool {
+	return target == ErrTableAccessDenied
+}
+
+// Sources of an effective table permission
+const (
+	PermissionSourceOwner = "owner"
+	PermissionSourceTeam  = "team_role"
+	PermissionSourceGrant = "grant"
+	PermissionSourceNone  = "none"
+)
+
+// EffectiveTablePermission is what a user may do with one table
+type EffectiveTablePermission struct {
+	CanRead    bool   `json:"can_read"`
+	CanBackup  bool   `json:"can_backup"`
+	CanRestore bool   `json:"can_restore"`
+	Source     string `json:"source"`
+
+	// Restrictions carried by a direct grant
+	RowLevelFilter models.RowFilter `json:"row_level_filter,omitempty"`
+	ColumnMask     []string         `json:"column_mask,omitempty"`
+}
+
+// Allows checks if the permission includes an action
+func (p EffectiveTablePermission) Allows(action string) bool {
+	switch action {
+	case TableActionRead:
+		return p.CanRead
+	case TableActionBackup:
+		return p.CanBackup
+	case TableActionRestore:
+		return p.CanRestore
+	default:
+		return false
+	}
+}
+
+// TableAccess holds a user's effective permissions on one connection's tables
+type TableAccess struct {
+	// Default applies to tables without a direct grant
+	Default EffectiveTablePermission
+	grants  map[string]EffectiveTablePermission
+	// restricted lists the names of granted tables with row filters or column masks
+	restricted [][]string
+}
+
+// For returns the effective permission for a table name, with or without schema
+func (a *TableAccess) For(table string) EffectiveTablePermission {
+	if permission, ok := a.grants[strings.ToLower(table)]; ok {
+		return permission
+	}
+	return a.Default
+}
+
+// Allows checks if the user may perform an action on a table
+func (a *TableAccess) Allows(table, action string) bool {
+	return a.For(table).Allows(action)
+}
+
+// SanitizeRules returns the row filters and column masks that grants impose on
+// the tables an export covers. An empty table list covers every table except
+// the excluded ones.
+func (a *TableAccess) SanitizeRules(tables, excludeTables []string) ([]TableSanitizeRule, error) {
+	requested := lowerSet(tables)
+	excluded := lowerSet(excludeTables)
+
+	var rules []TableSanitizeRule
+	for _, names := range a.restricted {
+		if !coversTable(names, requested, excluded) {
+			continue
+		}
+
+		permission := a.grants[names[0]]
+		rule := TableSanitizeRule{Table: names[len(names)-1]}
+		rule.Filter = permission.RowLevelFilter
+		if len(permission.ColumnMask) > 0 {
+			rule.Masks = make(map[string]MaskRule, len(permission.ColumnMask))
+			for _, entry := range permission.ColumnMask {
+				column, mask, err := ParseColumnMask(entry)
+				if err != nil {
+					return nil, fmt.Errorf("table %s: %w", names[0], err)
+				}
+				rule.Masks[column] = mask
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Table < rules[j].Table })
+	return rules, nil
+}
+
+// coversTable checks if a table referred to by names is part of an export
+func coversTable(names []string, requested, excluded map[string]bool) bool {
+	for _, name := range names {
+		if excluded[name] {
+			return false
+		}
+	}
+	if len(requested) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if requested[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerSet builds a lowercase lookup set
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(value)] = true
+	}
+	return set
+}
+
+// TablePolicy resolves effective per-table permissions from connection
+// ownership, team roles and direct table grants.
+//
+// The connection owner always has full access. For everyone else an
+// unexpired direct grant replaces the team role for that table, including
+// its time restriction; expired grants fall back to the team role.
+type TablePolicy struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewTablePolicy creates a new table policy
+func NewTablePolicy(db *gorm.DB) *TablePolicy {
+	return &TablePolicy{db: db, now: time.Now}
+}
+
+// FindConnection loads a connection the user owns or can reach through an active team membership
+func (p *TablePolicy) FindConnection(ctx context.Context, userID uint, uid string) (*models.DatabaseConnection, error) {
+	teamIDs := p.db.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND is_active = ?", userID, true)
+
+	var conn models.DatabaseConnection
+	if err := p.db.WithContext(ctx).Where("uid = ?", uid).
+		Where("user_id = ? OR team_id IN (?)", userID, teamIDs).
+		First(&conn).Error; err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+// EffectivePermissions resolves the user's permissions on every catalogued table of a connection
+func (p *TablePolicy) EffectivePermissions(ctx context.Context, userID uint, conn *models.DatabaseConnection) (*TableAccess, error) {
+	access := &TableAccess{grants: make(map[string]EffectiveTablePermission)}
+
+	if conn.UserID == userID {
+		access.Default = EffectiveTablePermission{CanRead: true, CanBackup: true, CanRestore: true, Source: PermissionSourceOwner}
+		return access, nil
+	}
+
+	member, err := p.teamMember(ctx, userID, conn)
+	if err != nil {
+		return nil, err
+	}
+	access.Default = rolePermission(member)
+
+	var grants []models.TablePermission
+	if err := p.db.WithContext(ctx).Preload("DatabaseTable").
+		Joins("JOIN database_tables ON database_tables.id = table_permissions.database_table_id").
+		Where("table_permissions.user_id = ? AND database_tables.database_connection_id = ?", userID, conn.ID).
+		Where("database_tables.deleted_at IS NULL").
+		Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to load table permissions: %w", err)
+	}
+
+	now := p.now()
+	for _, grant := range grants {
+		if grant.ExpiresAt != nil && !grant.ExpiresAt.After(now) {
+			continue
+		}
+
+		permission := EffectiveTablePermission{Source: PermissionSourceGrant}
+		if grant.AllowsAccessAt(now) {
+			permission.CanRead = grant.CanRead
+			permission.CanBackup = grant.CanBackup
+			permission.CanRestore = grant.CanRestore
+		}
+		if grant.HasRowLevelFilter() {
+			permission.RowLevelFilter = grant.RowLevelFilter
+		}
+		permission.ColumnMask = grant.GetMaskedColumns()
+
+		names := tableNames(&grant.DatabaseTable)
+		for _, name := range names {
+			access.grants[name] = permission
+		}
+		if len(permission.RowLevelFilter) > 0 || len(permission.ColumnMask) > 0 {
+			access.restricted = append(access.restricted, names)
+		}
+	}
+
+	return access, nil
+}
+
+// ResolveTables maps requested table names, with or without schema, onto the
+// full names of the catalogued tables they denote. Patterns, unknown names and
+// tables whose names contain a dot are rejected, so the result can be handed
+// to dump tools without selecting any other table.
+func (p *TablePolicy) ResolveTables(ctx context.Context, conn *models.DatabaseConnection, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var catalogued []models.DatabaseTable
+	if err := p.db.WithContext(ctx).Where("database_connection_id = ?", conn.ID).
+		Find(&catalogued).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tables: %w", err)
+	}
+	byName := make(map[string]*models.DatabaseTable, len(catalogued))
+	for i := range catalogued {
+		for _, name := range tableNames(&catalogued[i]) {
+			byName[name] = &catalogued[i]
+		}
+	}
+
+	resolved := make([]string, 0, len(names))
+	seen := make(map[uint]bool, len(names))
+	for _, name := range names {
+		if strings.ContainsAny(name, tablePatternChars) {
+			return nil, fmt.Errorf("%w: %s must not contain any of %s", ErrInvalidTableName, name, tablePatternChars)
+		}
+		table, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", name, ErrTableNotCatalogued)
+		}
+		if strings.Contains(table.Schema, ".") || strings.Contains(table.Name, ".") {
+			return nil, fmt.Errorf("%w: %s cannot be selected by name", ErrInvalidTableName, name)
+		}
+		if !seen[table.ID] {
+			seen[table.ID] = true
+			resolved = append(resolved, table.GetFullName())
+		}
+	}
+	return resolved, nil
+}
+
+// CheckTables verifies the user may perform an action on the tables a job covers.
+// Listed tables must resolve to catalogued tables. An empty table list covers the
+// whole database except the excluded tables, which also requires the action on
+// tables that have not been catalogued yet.
+func (p *TablePolicy) CheckTables(ctx context.Context, userID uint, conn *models.DatabaseConnection, tables, excludeTables []string, action string) error {
+	access, err := p.EffectivePermissions(ctx, userID, conn)
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+	if len(tables) > 0 {
+		tables, err = p.ResolveTables(ctx, conn, tables)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if !access.Allows(table, action) {
+				denied = append(denied, table)
+			}
+		}
+	} else {
+		excludeTables, err = p.ResolveTables(ctx, conn, excludeTables)
+		if err != nil {
+			return err
+		}
+		if !access.Default.Allows(action) {
+			return &TableAccessError{Action: action, AllTables: true}
+		}
+		if len(access.grants) == 0 {
+			return nil
+		}
+
+		excluded := make(map[string]bool, len(excludeTables))
+		for _, table := range excludeTables {
+			excluded[strings.ToLower(table)] = true
+		}
+
+		var catalogued []models.DatabaseTable
+		if err := p.db.WithContext(ctx).Where("database_connection_id = ?", conn.ID).
+			Find(&catalogued).Error; err != nil {
+			return fmt.Errorf("failed to load tables: %w", err)
+		}
+		for _, table := range catalogued {
+			names := tableNames(&table)
+			if excluded[names[0]] || excluded[names[len(names)-1]] {
+				continue
+			}
+			if !access.Allows(names[0], action) {
+				denied = append(denied, table.GetFullName())
+			}
+		}
+	}
+
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return &TableAccessError{Action: action, Tables: denied}
+	}
+	return nil
+}
+
+// FilterTables keeps the tables the user may read
+func (p *TablePolicy) FilterTables(access *TableAccess, tables []models.DatabaseTable) []models.DatabaseTable {
+	filtered := make([]models.DatabaseTable, 0, len(tables))
+	for _, table := range tables {
+		if access.Allows(tableNames(&table)[0], TableActionRead) {
+			filtered = append(filtered, table)
+		}
+	}
+	return filtered
+}
+
+// CanManagePermissions checks if the user may grant and revoke permissions on a connection's tables
+func (p *TablePolicy) CanManagePermissions(ctx context.Context, userID uint, conn *models.DatabaseConnection) (bool, error) {
+	if conn.UserID == userID {
+		return true, nil
+	}
+	member, err := p.teamMember(ctx, userID, conn)
+	if err != nil {
+		return false, err
+	}
+	return member != nil && member.IsAdmin(), nil
+}
+
+// ListPermissions returns the direct grants on a connection's tables
+func (p *TablePolicy) ListPermissions(ctx context.Context, conn *models.DatabaseConnection) ([]models.TablePermission, error) {
+	var permissions []models.TablePermission
+	if err := p.db.WithContext(ctx).Preload("DatabaseTable").
+		Joins("JOIN database_tables ON database_tables.id = table_permissions.database_table_id").
+		Where("database_tables.database_connection_id = ? AND database_tables.deleted_at IS NULL", conn.ID).
+		Order("table_permissions.id").
+		Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load table permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// Grant creates or replaces a user's permission on a catalogued table. The previous
+// grant, if any, is returned so callers can record the change.
+func (p *TablePolicy) Grant(ctx context.Context, granterID uint, conn *models.DatabaseConnection, req *models.TablePermissionRequest) (*models.TablePermission, *models.TablePermission, error) {
+	if err := p.requireManager(ctx, granterID, conn); err != nil {
+		return nil, nil, err
+	}
+	if err := models.ValidateTimeRestriction(req.TimeRestriction); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTablePermission, err)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(p.now()) {
+		return nil, nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidTablePermission)
+	}
+	if err := req.RowLevelFilter.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTablePermission, err)
+	}
+	for _, entry := range req.ColumnMask {
+		if _, _, err := ParseColumnMask(entry); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTablePermission, err)
+		}
+	}
+
+	if _, err := p.FindConnection(ctx, req.UserID, conn.UID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("%w: user %d cannot access this connection", ErrInvalidTablePermission, req.UserID)
+		}
+		return nil, nil, err
+	}
+
+	table, err := p.findTable(ctx, conn, req.Table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var previous *models.TablePermission
+	permission := &models.TablePermission{UserID: req.UserID, DatabaseTableID: table.ID}
+	var existing models.TablePermission
+	err = p.db.WithContext(ctx).Where("user_id = ? AND database_table_id = ?", req.UserID, table.ID).
+		First(&existing).Error
+	switch {
+	case err == nil:
+		snapshot := existing
+		previous = &snapshot
+		permission = &existing
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil, fmt.Errorf("failed to load table permission: %w", err)
+	}
+
+	req.ApplyTo(permission)
+	permission.GrantedBy = &granterID
+	permission.GrantedAt = p.now()
+
+	if err := p.db.WithContext(ctx).Save(permission).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save table permission: %w", err)
+	}
+	permission.DatabaseTable = *table
+
+	return permission, previous, nil
+}
+
+// Revoke deletes a grant on one of the connection's tables
+func (p *TablePolicy) Revoke(ctx context.Context, revokerID uint, conn *models.DatabaseConnection, permissionID uint) (*models.TablePermission, error) {
+	if err := p.requireManager(ctx, revokerID, conn); err != nil {
+		return nil, err
+	}
+
+	var permission models.TablePermission
+	if err := p.db.WithContext(ctx).Preload("DatabaseTable").
+		Joins("JOIN database_tables ON database_tables.id = table_permissions.database_table_id").
+		Where("table_permissions.id = ? AND database_tables.database_connection_id = ?", permissionID, conn.ID).
+		First(&permission).Error; err != nil {
+		return nil, err
+	}
+
+	if err := p.db.WithContext(ctx).Delete(&permission).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete table permission: %w", err)
+	}
+	return &permission, nil
+}
+
+// requireManager returns ErrPermissionManagementDenied unless the user may manage permissions
+func (p *TablePolicy) requireManager(ctx context.Context, userID uint, conn *models.DatabaseConnection) error {
+	allowed, err := p.CanManagePermissions(ctx, userID, conn)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrPermissionManagementDenied
+	}
+	return nil
+}
+
+// findTable looks up a catalogued table by name, with or without schema
+func (p *TablePolicy) findTable(ctx context.Context, conn *models.DatabaseConnection, name string) (*models.DatabaseTable, error) {
+	var tables []models.DatabaseTable
+	if err := p.db.WithContext(ctx).Where("database_connection_id = ?", conn.ID).
+		Find(&tables).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tables: %w", err)
+	}
+
+	name = strings.ToLower(name)
+	for i := range tables {
+		for _, candidate := range tableNames(&tables[i]) {
+			if candidate == name {
+				return &tables[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrTableNotCatalogued)
+}
+
+// teamMember returns the user's active membership in the connection's team, if any
+func (p *TablePolicy) teamMember(ctx context.Context, userID uint, conn *models.DatabaseConnection) (*models.TeamMember, error) {
+	if conn.TeamID == nil {
+		return nil, nil
+	}
+
+	var member models.TeamMember
+	err := p.db.WithContext(ctx).Where("team_id = ? AND user_id = ? AND is_active = ?", *conn.TeamID, userID, true).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load team membership: %w", err)
+	}
+	return &member, nil
+}
+
+// rolePermission maps a team role to table permissions
+func rolePermission(member *models.TeamMember) EffectiveTablePermission {
+	if member == nil {
+		return EffectiveTablePermission{Source: PermissionSourceNone}
+	}
+
+	permission := EffectiveTablePermission{Source: PermissionSourceTeam}
+	switch member.Role {
+	case models.TeamRoleOwner, models.TeamRoleAdmin:
+		permission.CanRead = true
+		permission.CanBackup = true
+		permission.CanRestore = true
+	case models.TeamRoleMember:
+		permission.CanRead = true
+		permission.CanBackup = member.CanManageBackups
+		permission.CanRestore = member.CanManageBackups
+	case models.TeamRoleGuest:
+		permission.CanRead = true
+	}
+	return permission
+}
+
+// tableNames returns the lowercase names a table can be referred to by, full name first
+func tableNames(table *models.DatabaseTable) []string {
+	full := strings.ToLower(table.GetFullName())
+	names := []string{full}
+	if table.Schema != "" {
+		if qualified := strings.ToLower(table.Schema + "." + table.Name); qualified != full {
+			names = append(names, qualified)
+		}
+	}
+	return names
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupTablePolicyTest creates a team connection owned by user 1 with catalogued tables
+func setupTablePolicyTest(t *testing.T) (*gorm.DB, *TablePolicy, *models.DatabaseConnection) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.TeamMember{}, &models.TablePermission{}))
+
+	teamID := uint(10)
+	conn := &models.DatabaseConnection{
+		Name:     "App",
+		Type:     models.DatabaseTypePostgreSQL,
+		Host:     "localhost",
+		Port:     5432,
+		Database: "app",
+		Username: "app",
+		UserID:   1,
+		TeamID:   &teamID,
+	}
+	require.NoError(t, db.Create(conn).Error)
+
+	for _, table := range []models.DatabaseTable{
+		{Name: "users", Schema: "public"},
+		{Name: "orders", Schema: "public"},
+		{Name: "ledger", Schema: "finance"},
+	} {
+		table.DatabaseConnectionID = conn.ID
+		table.LastDiscoveredAt = time.Now()
+		require.NoError(t, db.Create(&table).Error)
+	}
+
+	members := []models.TeamMember{
+		{TeamID: teamID, UserID: 2, Role: models.TeamRoleAdmin, IsActive: true},
+		{TeamID: teamID, UserID: 3, Role: models.TeamRoleMember, CanManageBackups: true, IsActive: true},
+		{TeamID: teamID, UserID: 4, Role: models.TeamRoleGuest, IsActive: true},
+	}
+	require.NoError(t, db.Create(&members).Error)
+
+	return db, NewTablePolicy(db), conn
+}
+
+func TestTablePolicy_RolePermissions(t *testing.T) {
+	_, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		userID  uint
+		read    bool
+		backup  bool
+		restore bool
+	}{
+		{"owner", 1, true, true, true},
+		{"team admin", 2, true, true, true},
+		{"team member", 3, true, true, true},
+		{"team guest", 4, true, false, false},
+		{"outsider", 5, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access, err := policy.EffectivePermissions(ctx, tt.userID, conn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.read, access.Allows("users", TableActionRead))
+			assert.Equal(t, tt.backup, access.Allows("users", TableActionBackup))
+			assert.Equal(t, tt.restore, access.Allows("finance.ledger", TableActionRestore))
+		})
+	}
+}
+
+func TestTablePolicy_FindConnection(t *testing.T) {
+	_, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	found, err := policy.FindConnection(ctx, 4, conn.UID)
+	require.NoError(t, err)
+	assert.Equal(t, conn.ID, found.ID)
+
+	_, err = policy.FindConnection(ctx, 5, conn.UID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestTablePolicy_DirectGrantsOverrideTeamRole(t *testing.T) {
+	db, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	// Narrow a member to read-only on the ledger
+	noBackup := false
+	_, _, err := policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{
+		UserID:      3,
+		Table:       "finance.ledger",
+		AccessLevel: models.TableAccessRead,
+		CanBackup:   &noBackup,
+		ColumnMask:  []string{"iban"},
+	})
+	require.NoError(t, err)
+
+	// Widen a guest to back up users; read access includes backups
+	_, _, err = policy.Grant(ctx, 2, conn, &models.TablePermissionRequest{
+		UserID:      4,
+		Table:       "users",
+		AccessLevel: models.TableAccessRead,
+	})
+	require.NoError(t, err)
+
+	err = policy.CheckTables(ctx, 3, conn, []string{"users", "finance.ledger"}, nil, TableActionBackup)
+	var accessErr *TableAccessError
+	require.ErrorAs(t, err, &accessErr)
+	assert.ErrorIs(t, err, ErrTableAccessDenied)
+	assert.Equal(t, []string{"finance.ledger"}, accessErr.Tables)
+
+	access, err := policy.EffectivePermissions(ctx, 3, conn)
+	require.NoError(t, err)
+	assert.Equal(t, PermissionSourceGrant, access.For("FINANCE.LEDGER").Source)
+	assert.Equal(t, []string{"iban"}, access.For("finance.ledger").ColumnMask)
+
+	// Masked grants become mandatory sanitize rules for exports that cover them
+	rules, err := access.SanitizeRules(nil, nil)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "finance.ledger", rules[0].Table)
+	assert.Equal(t, map[string]MaskRule{"iban": {}}, rules[0].Masks)
+	rules, err = access.SanitizeRules([]string{"users"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	rules, err = access.SanitizeRules(nil, []string{"FINANCE.LEDGER"})
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	// Excluding the restricted table allows a whole-database backup
+	assert.Error(t, policy.CheckTables(ctx, 3, conn, nil, nil, TableActionBackup))
+	assert.NoError(t, policy.CheckTables(ctx, 3, conn, nil, []string{"finance.ledger"}, TableActionBackup))
+
+	// The guest may back up the granted table but never the whole database
+	assert.NoError(t, policy.CheckTables(ctx, 4, conn, []string{"public.users"}, nil, TableActionBackup))
+	err = policy.CheckTables(ctx, 4, conn, nil, nil, TableActionBackup)
+	require.ErrorAs(t, err, &accessErr)
+	assert.True(t, accessErr.AllTables)
+
+	// Expired grants fall back to the team role
+	require.NoError(t, db.Model(&models.TablePermission{}).Where("user_id = ?", 3).
+		Update("expires_at", time.Now().Add(-time.Hour)).Error)
+	assert.NoError(t, policy.CheckTables(ctx, 3, conn, []string{"finance.ledger"}, nil, TableActionBackup))
+}
+
+func TestTablePolicy_TimeRestrictedGrant(t *testing.T) {
+	_, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	_, _, err := policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{
+		UserID:          3,
+		Table:           "orders",
+		AccessLevel:     models.TableAccessWrite,
+		TimeRestriction: "mon-fri 09:00-17:00",
+	})
+	require.NoError(t, err)
+
+	policy.now = func() time.Time { return time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC) } // Tuesday
+	assert.NoError(t, policy.CheckTables(ctx, 3, conn, []string{"orders"}, nil, TableActionRestore))
+
+	policy.now = func() time.Time { return time.Date(2024, 3, 16, 10, 0, 0, 0, time.UTC) } // Saturday
+	assert.ErrorIs(t, policy.CheckTables(ctx, 3, conn, []string{"orders"}, nil, TableActionRestore), ErrTableAccessDenied)
+}
+
+func TestTablePolicy_GrantAndRevoke(t *testing.T) {
+	_, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	req := &models.TablePermissionRequest{UserID: 4, Table: "orders", AccessLevel: models.TableAccessRead}
+
+	// Only the owner and team admins manage permissions
+	_, _, err := policy.Grant(ctx, 3, conn, req)
+	assert.ErrorIs(t, err, ErrPermissionManagementDenied)
+
+	_, _, err = policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 5, Table: "orders", AccessLevel: models.TableAccessRead})
+	assert.ErrorIs(t, err, ErrInvalidTablePermission)
+
+	_, _, err = policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 4, Table: "missing", AccessLevel: models.TableAccessRead})
+	assert.ErrorIs(t, err, ErrTableNotCatalogued)
+
+	_, _, err = policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 4, Table: "orders", AccessLevel: models.TableAccessRead, TimeRestriction: "weekends"})
+	assert.ErrorIs(t, err, ErrInvalidTablePermission)
+
+	_, _, err = policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 4, Table: "orders", AccessLevel: models.TableAccessRead, ColumnMask: []string{"email:scramble"}})
+	assert.ErrorIs(t, err, ErrInvalidTablePermission)
+
+	_, _, err = policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 4, Table: "orders", AccessLevel: models.TableAccessRead, RowLevelFilter: models.RowFilter{{Column: "id", Operator: "; DELETE FROM orders", Value: 1.0}}})
+	assert.ErrorIs(t, err, ErrInvalidTablePermission)
+
+	permission, previous, err := policy.Grant(ctx, 1, conn, req)
+	require.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.Equal(t, "orders", permission.DatabaseTable.Name)
+	require.NotNil(t, permission.GrantedBy)
+	assert.Equal(t, uint(1), *permission.GrantedBy)
+
+	// Granting again replaces the existing permission
+	req.AccessLevel = models.TableAccessWrite
+	updated, previous, err := policy.Grant(ctx, 2, conn, req)
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, permission.ID, updated.ID)
+	assert.False(t, previous.CanRestore)
+	assert.True(t, updated.CanRestore)
+
+	permissions, err := policy.ListPermissions(ctx, conn)
+	require.NoError(t, err)
+	require.Len(t, permissions, 1)
+
+	_, err = policy.Revoke(ctx, 4, conn, updated.ID)
+	assert.ErrorIs(t, err, ErrPermissionManagementDenied)
+
+	revoked, err := policy.Revoke(ctx, 1, conn, updated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated.ID, revoked.ID)
+
+	_, err = policy.Revoke(ctx, 1, conn, updated.ID)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	permissions, err = policy.ListPermissions(ctx, conn)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+}
+
+func TestTablePolicy_FilterTables(t *testing.T) {
+	db, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	_, _, err := policy.Grant(ctx, 1, conn, &models.TablePermissionRequest{UserID: 4, Table: "finance.ledger", AccessLevel: models.TableAccessNone})
+	require.NoError(t, err)
+
+	var tables []models.DatabaseTable
+	require.NoError(t, db.Order("id").Find(&tables).Error)
+
+	access, err := policy.EffectivePermissions(ctx, 4, conn)
+	require.NoError(t, err)
+	filtered := policy.FilterTables(access, tables)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "users", filtered[0].Name)
+	assert.Equal(t, "orders", filtered[1].Name)
+}
+
+func TestTablePolicy_ResolveTables(t *testing.T) {
+	db, policy, conn := setupTablePolicyTest(t)
+	ctx := context.Background()
+
+	resolved, err := policy.ResolveTables(ctx, conn, []string{"Public.Users", "finance.ledger", "users"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users", "finance.ledger"}, resolved)
+
+	for _, name := range []string{"user*", "public.u%", "us?rs", "[u]sers", `"users"`} {
+		_, err := policy.ResolveTables(ctx, conn, []string{name})
+		assert.ErrorIs(t, err, ErrInvalidTableName, name)
+	}
+	_, err = policy.ResolveTables(ctx, conn, []string{"salaries"})
+	assert.ErrorIs(t, err, ErrTableNotCatalogued)
+
+	// A dot in a catalogued name would make the dump name ambiguous
+	require.NoError(t, db.Create(&models.DatabaseTable{Name: "audit.log", Schema: "public", DatabaseConnectionID: conn.ID, LastDiscoveredAt: time.Now()}).Error)
+	_, err = policy.ResolveTables(ctx, conn, []string{"public.audit.log"})
+	assert.ErrorIs(t, err, ErrInvalidTableName)
+
+	// Unknown tables no longer fall back to the default permission, even for the owner
+	err = policy.CheckTables(ctx, 1, conn, []string{"salaries"}, nil, TableActionBackup)
+	assert.ErrorIs(t, err, ErrTableNotCatalogued)
+	err = policy.CheckTables(ctx, 1, conn, nil, []string{"salar*"}, TableActionBackup)
+	assert.ErrorIs(t, err, ErrInvalidTableName)
+}
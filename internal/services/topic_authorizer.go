@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// TopicAuthorizer decides which WebSocket topics a user may follow. Jobs and
+// database connections follow connection access, team activity requires an
+// active membership and queue statistics are reserved to administrators.
+type TopicAuthorizer struct {
+	db     *gorm.DB
+	policy *TablePolicy
+}
+
+// NewTopicAuthorizer creates a new topic authorizer
+func NewTopicAuthorizer(db *gorm.DB) *TopicAuthorizer {
+	return &TopicAuthorizer{db: db, policy: NewTablePolicy(db)}
+}
+
+// AuthorizeTopic checks the user may follow a topic
+func (a *TopicAuthorizer) AuthorizeTopic(ctx context.Context, userID uint, kind, id string) error {
+	var err error
+	switch kind {
+	case websocket.TopicJob:
+		err = a.authorizeJob(ctx, userID, id)
+	case websocket.TopicDatabase:
+		_, err = a.policy.FindConnection(ctx, userID, id)
+	case websocket.TopicTeam:
+		err = a.authorizeTeam(ctx, userID, id)
+	case websocket.TopicQueue:
+		err = a.authorizeAdmin(ctx, userID)
+	default:
+		return websocket.ErrInvalidTopic
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s:%s", websocket.ErrTopicForbidden, kind, id)
+	}
+	return err
+}
+
+// authorizeJob allows the job's owner and users who can reach its connection
+func (a *TopicAuthorizer) authorizeJob(ctx context.Context, userID uint, jobUID string) error {
+	var job models.BackupJob
+	if err := a.db.WithContext(ctx).Preload("DatabaseConnection").
+		Where("uid = ?", jobUID).First(&job).Error; err != nil {
+		return err
+	}
+	if job.UserID == userID {
+		return nil
+	}
+
+	_, err := a.policy.FindConnection(ctx, userID, job.DatabaseConnection.UID)
+	return err
+}
+
+// authorizeTeam allows active members of the team
+func (a *TopicAuthorizer) authorizeTeam(ctx context.Context, userID uint, rawTeamID string) error {
+	teamID, err := strconv.ParseUint(rawTeamID, 10, 64)
+	if err != nil {
+		return websocket.ErrInvalidTopic
+	}
+
+	var member models.TeamMember
+	return a.db.WithContext(ctx).
+		Where("team_id = ? AND user_id = ? AND is_active = ?", teamID, userID, true).
+		First(&member).Error
+}
+
+// authorizeAdmin allows active administrators
+func (a *TopicAuthorizer) authorizeAdmin(ctx context.Context, userID uint) error {
+	var user models.User
+	return a.db.WithContext(ctx).
+		Where("id = ? AND is_admin = ? AND is_active = ?", userID, true, true).
+		First(&user).Error
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicAuthorizer(t *testing.T) {
+	db, _, conn := setupTablePolicyTest(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.BackupJob{}))
+
+	job := &models.BackupJob{UserID: 3, DatabaseConnectionID: conn.ID}
+	require.NoError(t, db.Create(job).Error)
+
+	require.NoError(t, db.Create(&models.User{ID: 2, Email: "admin@example.com", IsAdmin: true, IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.User{ID: 3, Email: "member@example.com", IsActive: true}).Error)
+
+	authorizer := NewTopicAuthorizer(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		userID  uint
+		topic   string
+		allowed bool
+	}{
+		{"job owner", 3, websocket.JobTopic(job.UID), true},
+		{"teammate on job connection", 4, websocket.JobTopic(job.UID), true},
+		{"outsider on job", 5, websocket.JobTopic(job.UID), false},
+		{"unknown job", 3, websocket.JobTopic("missing"), false},
+		{"connection owner", 1, websocket.DatabaseTopic(conn.UID), true},
+		{"outsider on connection", 5, websocket.DatabaseTopic(conn.UID), false},
+		{"team member", 4, websocket.TeamTopic(10), true},
+		{"other team", 4, websocket.TeamTopic(11), false},
+		{"administrator on queue stats", 2, websocket.QueueStatsTopic, true},
+		{"member on queue stats", 3, websocket.QueueStatsTopic, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, id, err := websocket.ParseTopic(tt.topic)
+			require.NoError(t, err)
+
+			err = authorizer.AuthorizeTopic(ctx, tt.userID, kind, id)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, websocket.ErrTopicForbidden)
+			}
+		})
+	}
+}
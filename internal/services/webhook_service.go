@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookTaskType is the queue task delivering one webhook
+const WebhookTaskType = "webhook:deliver"
+
+// WebhookTaskPayload is the payload of a webhook delivery task
+type WebhookTaskPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// Retry delays of webhook deliveries, doubled on every retry
+const (
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+)
+
+// ErrWebhookQueueUnavailable is returned when deliveries cannot be queued
+var ErrWebhookQueueUnavailable = errors.New("webhook queue is not available")
+
+// WebhookRetryDelay is the exponential backoff of failed webhook deliveries.
+// Up to 10% jitter keeps retries to an endpoint that comes back from
+// arriving all at once.
+func WebhookRetryDelay(n int, err error) time.Duration {
+	delay := webhookRetryMax
+	if n < 20 && webhookRetryBase<<n < webhookRetryMax {
+		delay = webhookRetryBase << n
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+// WebhookPayload is the JSON body of a webhook delivery. Its ID identifies
+// the event across redeliveries.
+type WebhookPayload struct {
+	ID        string              `json:"id"`
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	TeamID    uint                `json:"team_id"`
+	Data      WebhookPayloadData  `json:"data"`
+}
+
+// WebhookPayloadData holds the job and file an event is about
+type WebhookPayloadData struct {
+	BackupJob  map[string]interface{} `json:"backup_job,omitempty"`
+	BackupFile map[string]interface{} `json:"backup_file,omitempty"`
+}
+
+// WebhookService sends backup lifecycle events to the webhook endpoints of
+// teams. Every endpoint subscribed to an event gets a delivery, sent by a
+// queue task and retried with exponential backoff until it succeeds, so
+// events arrive at least once.
+type WebhookService struct {
+	db          *gorm.DB
+	encService  *encryption.Service
+	queue       TaskEnqueuer
+	client      *http.Client
+	now         func() time.Time
+	maxAttempts int
+	timeout     time.Duration
+}
+
+// NewWebhookService creates a webhook service. The queue may be nil where
+// events are neither published nor redelivered.
+func NewWebhookService(db *gorm.DB, encService *encryption.Service, queue TaskEnqueuer, cfg config.WebhookConfig) *WebhookService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &WebhookService{
+		db:          db,
+		encService:  encService,
+		queue:       queue,
+		client:      newOutboundClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+		now:         time.Now,
+		maxAttempts: cfg.MaxAttempts,
+		timeout:     cfg.Timeout,
+	}
+}
+
+// Publish queues an event about a backup job and, when given, one of its
+// files to the endpoints of the job's team. Jobs of personal connections
+// have no team and are not published.
+func (ws *WebhookService) Publish(ctx context.Context, event models.WebhookEvent, job *models.BackupJob, file *models.BackupFile) error {
+	if !event.IsValid() {
+		return fmt.Errorf("unknown webhook event %q", event)
+	}
+	if job == nil && file != nil {
+		job = &file.BackupJob
+	}
+	if job == nil || job.DatabaseConnection.TeamID == nil {
+		return nil
+	}
+	teamID := *job.DatabaseConnection.TeamID
+
+	var endpoints []models.WebhookEndpoint
+	if err := ws.db.WithContext(ctx).Where("team_id = ? AND is_active = ?", teamID, true).Order("id").Find(&endpoints).Error; err != nil {
+		return fmt.Errorf("failed to find webhook endpoints: %w", err)
+	}
+	subscribed := endpoints[:0]
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(event) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+	if ws.queue == nil {
+		return ErrWebhookQueueUnavailable
+	}
+
+	payload, err := newWebhookPayload(event, teamID, job, file, ws.now())
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var errs []error
+	for _, endpoint := range subscribed {
+		delivery := &models.WebhookDelivery{
+			EndpointID: endpoint.ID,
+			EventID:    payload.ID,
+			Event:      event,
+			Payload:    string(body),
+			Status:     models.WebhookDeliveryPending,
+		}
+		if err := ws.queueDelivery(ctx, delivery); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redeliver queues the event of a delivery to its endpoint again, as a new
+// delivery with the same event ID and payload
+func (ws *WebhookService) Redeliver(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	if ws.queue == nil {
+		return nil, ErrWebhookQueueUnavailable
+	}
+
+	redelivery := &models.WebhookDelivery{
+		EndpointID: delivery.EndpointID,
+		EventID:    delivery.EventID,
+		Event:      delivery.Event,
+		Payload:    delivery.Payload,
+		Status:     models.WebhookDeliveryPending,
+		Redelivery: true,
+	}
+	if err := ws.queueDelivery(ctx, redelivery); err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
+
+// queueDelivery records a delivery and queues the task sending it. A
+// delivery that cannot be queued is failed right away.
+func (ws *WebhookService) queueDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := ws.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+
+	_, err := ws.queue.EnqueueJob(ctx, WebhookTaskType, WebhookTaskPayload{DeliveryID: delivery.ID},
+		WithQueue("default"),
+		WithMaxRetry(ws.maxAttempts-1),
+		WithTimeout(2*ws.timeout),
+	)
+	if err != nil {
+		delivery.RecordAttempt(0, fmt.Errorf("failed to queue delivery: %w", err), true)
+		ws.saveDelivery(ctx, delivery)
+		return err
+	}
+	return nil
+}
+
+// Deliver makes one attempt to send a queued delivery. It returns an error
+// for the queue to retry; final is set on the last attempt, after which a
+// failed delivery is given up.
+func (ws *WebhookService) Deliver(ctx context.Context, deliveryID uint, final bool) error {
+	var delivery models.WebhookDelivery
+	if err := ws.db.WithContext(ctx).First(&delivery, deliveryID).Error; err != nil {
+		return fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	if delivery.Status == models.WebhookDeliveryDelivered || delivery.Status == models.WebhookDeliveryFailed {
+		return nil
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := ws.db.WithContext(ctx).First(&endpoint, delivery.EndpointID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted since: nothing left to retry
+			delivery.RecordAttempt(0, errors.New("webhook endpoint was deleted"), true)
+			ws.saveDelivery(ctx, &delivery)
+			return nil
+		}
+		return fmt.Errorf("failed to load webhook endpoint: %w", err)
+	}
+	if !endpoint.IsActive {
+		delivery.RecordAttempt(0, errors.New("webhook endpoint is disabled"), true)
+		ws.saveDelivery(ctx, &delivery)
+		return nil
+	}
+
+	status, err := ws.send(ctx, &endpoint, &delivery)
+	delivery.RecordAttempt(status, err, final)
+	ws.saveDelivery(ctx, &delivery)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook to endpoint %s: %w", endpoint.UID, err)
+	}
+	return nil
+}
+
+// send posts a delivery's payload, signed with the endpoint's secret
+func (ws *WebhookService) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	secret, err := endpoint.GetSecret(ws.encService)
+	if err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := ws.now()
+	header := http.Header{}
+	header.Set(WebhookEventHeader, string(delivery.Event))
+	header.Set(WebhookDeliveryHeader, delivery.UID)
+	header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+
+	sendCtx, cancel := context.WithTimeout(ctx, ws.timeout)
+	defer cancel()
+	return postJSON(sendCtx, ws.client, endpoint.URL, body, header)
+}
+
+// saveDelivery records the outcome of a delivery attempt
+func (ws *WebhookService) saveDelivery(ctx context.Context, delivery *models.WebhookDelivery) {
+	err := ws.db.WithContext(context.WithoutCancel(ctx)).Model(delivery).
+		Select("status", "attempts", "response_status", "last_error", "delivered_at").
+		Updates(delivery).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record webhook delivery", "delivery_uid", delivery.UID, "error", err)
+	}
+}
+
+// newWebhookPayload builds the payload of an event. Relations that would
+// reveal users or credentials are left out, and the connection is given in
+// its public form.
+func newWebhookPayload(event models.WebhookEvent, teamID uint, job *models.BackupJob, file *models.BackupFile, now time.Time) (*WebhookPayload, error) {
+	payload := &WebhookPayload{
+		ID:        uuid.New().String(),
+		Event:     event,
+		CreatedAt: now.UTC(),
+		TeamID:    teamID,
+	}
+
+	var err error
+	if payload.Data.BackupJob, err = webhookObject(job, "user", "backup_files", "database_table"); err != nil {
+		return nil, err
+	}
+	payload.Data.BackupJob["database_connection"] = job.DatabaseConnection.ToPublic()
+
+	if file != nil {
+		if payload.Data.BackupFile, err = webhookObject(file, "backup_job", "storage_configuration"); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// webhookObject encodes a model as a JSON object without the given fields
+func webhookObject(v interface{}, omit ...string) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	for _, field := range omit {
+		delete(object, field)
+	}
+	return object, nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/dbackup/backend-go/internal/encryption"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type webhookEnqueuer struct {
+	payloads []WebhookTaskPayload
+	err      error
+}
+
+func (e *webhookEnqueuer) EnqueueJob(ctx context.Context, jobType string, payload interface{}, opts ...JobOption) (*JobInfo, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.payloads = append(e.payloads, payload.(WebhookTaskPayload))
+	return &JobInfo{ID: "task", Type: jobType}, nil
+}
+
+// setupWebhookTest creates a team with a backup job of one of its
+// connections and a file the job produced
+func setupWebhookTest(t *testing.T) (*gorm.DB, *encryption.Service, *models.BackupJob, *models.BackupFile) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Team{},
+		&models.DatabaseConnection{},
+		&models.BackupJob{},
+		&models.BackupFile{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+	))
+
+	user := &models.User{Email: "owner@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+	team := &models.Team{Name: "Platform", Slug: "platform"}
+	require.NoError(t, db.Create(team).Error)
+
+	conn := &models.DatabaseConnection{
+		Name:     "Orders",
+		Type:     models.DatabaseTypePostgreSQL,
+		Host:     "db.internal",
+		Port:     5432,
+		Database: "orders",
+		Username: "backup",
+		Password: "s3cret-password",
+		UserID:   user.ID,
+		TeamID:   &team.ID,
+	}
+	require.NoError(t, db.Create(conn).Error)
+
+	job := &models.BackupJob{
+		Name:                 "Nightly",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusCompleted,
+		UserID:               user.ID,
+		DatabaseConnectionID: conn.ID,
+	}
+	require.NoError(t, db.Create(job).Error)
+	job.DatabaseConnection = *conn
+	job.User = *user
+
+	file := &models.BackupFile{
+		Name:        "orders-backup",
+		FileType:    "dump",
+		S3Bucket:    "backups",
+		S3Key:       "backups/orders.dump",
+		S3Region:    "us-east-1",
+		BackupJobID: job.ID,
+	}
+	require.NoError(t, db.Create(file).Error)
+
+	return db, encryption.NewService("test-key-for-testing"), job, file
+}
+
+func createTestEndpoint(t *testing.T, db *gorm.DB, encService *encryption.Service, teamID uint, url string, events ...models.WebhookEvent) *models.WebhookEndpoint {
+	endpoint := &models.WebhookEndpoint{Name: "CI", URL: url, Events: events, IsActive: true, TeamID: teamID, UserID: 1}
+	require.NoError(t, endpoint.SetSecret("whsec_test-signing-secret", encService))
+	require.NoError(t, db.Create(endpoint).Error)
+	return endpoint
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	assert.GreaterOrEqual(t, WebhookRetryDelay(0, nil), 30*time.Second)
+	assert.Less(t, WebhookRetryDelay(0, nil), 34*time.Second)
+	assert.GreaterOrEqual(t, WebhookRetryDelay(3, nil), 4*time.Minute)
+	assert.Less(t, WebhookRetryDelay(3, nil), 4*time.Minute+25*time.Second)
+
+	// Late retries are capped
+	assert.GreaterOrEqual(t, WebhookRetryDelay(12, nil), 6*time.Hour)
+	assert.LessOrEqual(t, WebhookRetryDelay(100, nil), 6*time.Hour+36*time.Minute)
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	db, encService, job, file := setupWebhookTest(t)
+	teamID := *job.DatabaseConnection.TeamID
+	completed := createTestEndpoint(t, db, encService, teamID, "https://ci.example.com/hook", models.WebhookBackupCompleted, models.WebhookBackupFailed)
+	createTestEndpoint(t, db, encService, teamID, "https://audit.example.com/hook", models.WebhookBackupStarted)
+	disabled := createTestEndpoint(t, db, encService, teamID, "https://old.example.com/hook", models.WebhookBackupCompleted)
+	require.NoError(t, db.Model(disabled).Update("is_active", false).Error)
+
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{MaxAttempts: 5, Timeout: time.Second})
+
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupCompleted, job, file))
+	require.Len(t, queue.payloads, 1)
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, queue.payloads[0].DeliveryID).Error)
+	assert.Equal(t, completed.ID, delivery.EndpointID)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.NotEmpty(t, delivery.EventID)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &payload))
+	assert.Equal(t, delivery.EventID, payload["id"])
+	assert.Equal(t, "backup.completed", payload["event"])
+	data := payload["data"].(map[string]interface{})
+	backupJob := data["backup_job"].(map[string]interface{})
+	assert.Equal(t, job.UID, backupJob["uid"])
+	assert.NotContains(t, backupJob, "user")
+	assert.NotContains(t, delivery.Payload, "s3cret-password")
+	assert.NotContains(t, delivery.Payload, "owner@example.com")
+	backupFile := data["backup_file"].(map[string]interface{})
+	assert.Equal(t, file.UID, backupFile["uid"])
+	assert.NotContains(t, backupFile, "backup_job")
+
+	// Personal connections have no team webhooks
+	job.DatabaseConnection.TeamID = nil
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupCompleted, job, file))
+	assert.Len(t, queue.payloads, 1)
+
+	require.Error(t, service.Publish(context.Background(), models.WebhookEvent("backup.exploded"), job, nil))
+}
+
+func TestWebhookService_PublishQueueFailure(t *testing.T) {
+	db, encService, job, _ := setupWebhookTest(t)
+	createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, "https://ci.example.com/hook", models.WebhookBackupStarted)
+
+	service := NewWebhookService(db, encService, &webhookEnqueuer{err: errors.New("redis down")}, config.WebhookConfig{})
+	require.Error(t, service.Publish(context.Background(), models.WebhookBackupStarted, job, nil))
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, "redis down")
+}
+
+func TestWebhookService_Deliver(t *testing.T) {
+	var requests []*http.Request
+	var bodies [][]byte
+	statuses := []int{http.StatusBadGateway, http.StatusNoContent}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(statuses[len(requests)-1])
+	}))
+	defer server.Close()
+
+	db, encService, job, _ := setupWebhookTest(t)
+	createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, server.URL, models.WebhookBackupFailed)
+
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{MaxAttempts: 3, Timeout: time.Second, AllowPrivateNetworks: true})
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupFailed, job, nil))
+	require.Len(t, queue.payloads, 1)
+	deliveryID := queue.payloads[0].DeliveryID
+
+	// The endpoint failing leaves the delivery for the queue to retry
+	require.Error(t, service.Deliver(context.Background(), deliveryID, false))
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, deliveryID).Error)
+	assert.Equal(t, models.WebhookDeliveryRetrying, delivery.Status)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, http.StatusBadGateway, *delivery.ResponseStatus)
+
+	require.NoError(t, service.Deliver(context.Background(), deliveryID, false))
+	require.NoError(t, db.First(&delivery, deliveryID).Error)
+	assert.Equal(t, models.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+
+	// Every attempt is signed over its timestamp and body
+	received := requests[1]
+	assert.Equal(t, "backup.failed", received.Header.Get(WebhookEventHeader))
+	assert.Equal(t, delivery.UID, received.Header.Get(WebhookDeliveryHeader))
+	timestamp, err := strconv.ParseInt(received.Header.Get(WebhookTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, SignWebhook("whsec_test-signing-secret", time.Unix(timestamp, 0), bodies[1]), received.Header.Get(WebhookSignatureHeader))
+	assert.Equal(t, delivery.Payload, string(bodies[1]))
+
+	// A delivered webhook is not sent again
+	require.NoError(t, service.Deliver(context.Background(), deliveryID, false))
+	assert.Len(t, requests, 2)
+}
+
+func TestWebhookService_DeliverKeepsNoResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"instance_id":"i-0123456789"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	db, encService, job, _ := setupWebhookTest(t)
+	createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, server.URL, models.WebhookBackupFailed)
+
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{MaxAttempts: 3, Timeout: time.Second, AllowPrivateNetworks: true})
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupFailed, job, nil))
+	require.Error(t, service.Deliver(context.Background(), queue.payloads[0].DeliveryID, false))
+
+	// Only the status is recorded, not what the endpoint answered
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, queue.payloads[0].DeliveryID).Error)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, http.StatusForbidden, *delivery.ResponseStatus)
+	require.NotNil(t, delivery.LastError)
+	assert.NotContains(t, *delivery.LastError, "instance_id")
+}
+
+func TestWebhookService_RefusesPrivateNetworks(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	db, encService, job, _ := setupWebhookTest(t)
+	createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, server.URL, models.WebhookBackupFailed)
+
+	// The endpoint is on loopback, which webhooks may not reach by default
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{MaxAttempts: 3, Timeout: time.Second})
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupFailed, job, nil))
+	err := service.Deliver(context.Background(), queue.payloads[0].DeliveryID, true)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errNonPublicAddress)
+	assert.Zero(t, calls.Load())
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, queue.payloads[0].DeliveryID).Error)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+	assert.Nil(t, delivery.ResponseStatus)
+}
+
+func TestWebhookService_DeliverDeletedEndpoint(t *testing.T) {
+	db, encService, job, _ := setupWebhookTest(t)
+	endpoint := createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, "https://ci.example.com/hook", models.WebhookBackupStarted)
+
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{})
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupStarted, job, nil))
+	require.NoError(t, db.Delete(endpoint).Error)
+
+	require.NoError(t, service.Deliver(context.Background(), queue.payloads[0].DeliveryID, false))
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, queue.payloads[0].DeliveryID).Error)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	db, encService, job, _ := setupWebhookTest(t)
+	createTestEndpoint(t, db, encService, *job.DatabaseConnection.TeamID, "https://ci.example.com/hook", models.WebhookBackupCompleted)
+
+	queue := &webhookEnqueuer{}
+	service := NewWebhookService(db, encService, queue, config.WebhookConfig{})
+	require.NoError(t, service.Publish(context.Background(), models.WebhookBackupCompleted, job, nil))
+
+	var original models.WebhookDelivery
+	require.NoError(t, db.First(&original, queue.payloads[0].DeliveryID).Error)
+
+	redelivery, err := service.Redeliver(context.Background(), &original)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.ID, redelivery.ID)
+	assert.Equal(t, original.EventID, redelivery.EventID)
+	assert.Equal(t, original.Payload, redelivery.Payload)
+	assert.True(t, redelivery.Redelivery)
+	require.Len(t, queue.payloads, 2)
+	assert.Equal(t, redelivery.ID, queue.payloads[1].DeliveryID)
+
+	_, err = NewWebhookService(db, encService, nil, config.WebhookConfig{}).Redeliver(context.Background(), &original)
+	assert.ErrorIs(t, err, ErrWebhookQueueUnavailable)
+}
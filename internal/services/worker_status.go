@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultWorkerStatusPrefix is the Redis key prefix of worker heartbeats
+const DefaultWorkerStatusPrefix = "dbackup:workers"
+
+// WorkerStatus is what a backup worker process reports about itself with each heartbeat
+type WorkerStatus struct {
+	ID          string         `json:"id"`
+	Host        string         `json:"host"`
+	PID         int            `json:"pid"`
+	Concurrency int            `json:"concurrency"`
+	Queues      map[string]int `json:"queues"`
+	StartedAt   time.Time      `json:"started_at"`
+	LastSeenAt  time.Time      `json:"last_seen_at"`
+	// Draining is set once the worker stopped taking jobs to shut down
+	Draining bool `json:"draining"`
+}
+
+// WorkerStatusStore keeps worker heartbeats until they expire
+type WorkerStatusStore interface {
+	// Publish records a heartbeat that expires after ttl unless renewed
+	Publish(ctx context.Context, status *WorkerStatus, ttl time.Duration) error
+	// Remove forgets a worker that stopped
+	Remove(ctx context.Context, id string) error
+	// List returns the workers whose heartbeat has not expired, oldest first
+	List(ctx context.Context) ([]*WorkerStatus, error)
+}
+
+// LocalWorkerStatusStore keeps worker heartbeats in memory, for a single process
+type LocalWorkerStatusStore struct {
+	mu       sync.Mutex
+	statuses map[string]localWorkerStatus
+}
+
+type localWorkerStatus struct {
+	status    WorkerStatus
+	expiresAt time.Time
+}
+
+// NewLocalWorkerStatusStore creates an in-memory worker status store
+func NewLocalWorkerStatusStore() *LocalWorkerStatusStore {
+	return &LocalWorkerStatusStore{statuses: make(map[string]localWorkerStatus)}
+}
+
+// Publish records a heartbeat that expires after ttl unless renewed
+func (s *LocalWorkerStatusStore) Publish(ctx context.Context, status *WorkerStatus, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[status.ID] = localWorkerStatus{status: *status, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Remove forgets a worker that stopped
+func (s *LocalWorkerStatusStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.statuses, id)
+	return nil
+}
+
+// List returns the workers whose heartbeat has not expired, oldest first
+func (s *LocalWorkerStatusStore) List(ctx context.Context) ([]*WorkerStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]*WorkerStatus, 0, len(s.statuses))
+	for id, entry := range s.statuses {
+		if !entry.expiresAt.After(now) {
+			delete(s.statuses, id)
+			continue
+		}
+		status := entry.status
+		statuses = append(statuses, &status)
+	}
+	sortWorkerStatuses(statuses)
+	return statuses, nil
+}
+
+// RedisWorkerStatusStore keeps worker heartbeats in Redis, one expiring key
+// per worker, so API nodes see every worker
+type RedisWorkerStatusStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisWorkerStatusStore creates a worker status store on an existing Redis client
+func NewRedisWorkerStatusStore(client redis.UniversalClient, prefix string) *RedisWorkerStatusStore {
+	if prefix == "" {
+		prefix = DefaultWorkerStatusPrefix
+	}
+	return &RedisWorkerStatusStore{client: client, prefix: prefix}
+}
+
+// Publish records a heartbeat that expires after ttl unless renewed
+func (s *RedisWorkerStatusStore) Publish(ctx context.Context, status *WorkerStatus, ttl time.Duration) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode worker status: %w", err)
+	}
+	if err := s.client.Set(ctx, s.key(status.ID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to publish worker status: %w", err)
+	}
+	return nil
+}
+
+// Remove forgets a worker that stopped
+func (s *RedisWorkerStatusStore) Remove(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.key(id)).Err(); err != nil {
+		return fmt.Errorf("failed to remove worker status: %w", err)
+	}
+	return nil
+}
+
+// List returns the workers whose heartbeat has not expired, oldest first
+func (s *RedisWorkerStatusStore) List(ctx context.Context) ([]*WorkerStatus, error) {
+	var keys []string
+	iter := s.client.Scan(ctx, 0, s.prefix+":*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	if len(keys) == 0 {
+		return []*WorkerStatus{}, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+
+	statuses := make([]*WorkerStatus, 0, len(values))
+	for _, value := range values {
+		// Keys expiring between the scan and the read come back empty
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var status WorkerStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, &status)
+	}
+	sortWorkerStatuses(statuses)
+	return statuses, nil
+}
+
+func (s *RedisWorkerStatusStore) key(id string) string {
+	return s.prefix + ":" + id
+}
+
+func sortWorkerStatuses(statuses []*WorkerStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		if !statuses[i].StartedAt.Equal(statuses[j].StartedAt) {
+			return statuses[i].StartedAt.Before(statuses[j].StartedAt)
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWorkerStatusStore runs the behaviour every worker status store shares
+func testWorkerStatusStore(t *testing.T, store WorkerStatusStore) {
+	ctx := context.Background()
+	started := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	first := &WorkerStatus{ID: "worker-1", Host: "a", PID: 1, Concurrency: 5, Queues: map[string]int{"backups": 2}, StartedAt: started}
+	second := &WorkerStatus{ID: "worker-2", Host: "b", PID: 2, StartedAt: started.Add(time.Minute)}
+	require.NoError(t, store.Publish(ctx, second, time.Minute))
+	require.NoError(t, store.Publish(ctx, first, time.Minute))
+
+	statuses, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "worker-1", statuses[0].ID)
+	assert.Equal(t, map[string]int{"backups": 2}, statuses[0].Queues)
+	assert.True(t, started.Equal(statuses[0].StartedAt))
+	assert.Equal(t, "worker-2", statuses[1].ID)
+
+	// A new heartbeat replaces the previous one
+	first.Draining = true
+	require.NoError(t, store.Publish(ctx, first, time.Minute))
+	statuses, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Draining)
+
+	// Stopped workers are removed, silent ones expire
+	require.NoError(t, store.Remove(ctx, "worker-1"))
+	require.NoError(t, store.Publish(ctx, second, 50*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+
+	statuses, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
+
+func TestLocalWorkerStatusStore(t *testing.T) {
+	testWorkerStatusStore(t, NewLocalWorkerStatusStore())
+}
+
+func TestRedisWorkerStatusStore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	prefix := "dbackup:test:workers:" + time.Now().Format("150405.000000")
+	testWorkerStatusStore(t, NewRedisWorkerStatusStore(client, prefix))
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+type gormPlugin struct{}
+
+// GormPlugin traces the queries run with the context of a traced request or
+// job. Queries without one, such as those of background loops, are not traced.
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+// Name implements gorm.Plugin
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", startQuerySpan("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", endQuerySpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", startQuerySpan("select")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", endQuerySpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", startQuerySpan("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", endQuerySpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuerySpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endQuerySpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", startQuerySpan("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", endQuerySpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuerySpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endQuerySpan),
+	)
+}
+
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		ctx, span := Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", db.Dialector.Name()),
+				attribute.String("db.operation", operation),
+				attribute.String("db.sql.table", db.Statement.Table),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endQuerySpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+
+	// The statement keeps its placeholders, values are never recorded
+	span.SetAttributes(
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
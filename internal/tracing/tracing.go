@@ -0,0 +1,134 @@
+// Package tracing sets up OpenTelemetry tracing, so a backup can be followed
+// from the HTTP request that queued it through the worker phases, database
+// queries and storage calls it took.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/dbackup/backend-go"
+
+// Exporters
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans and stops the
+// exporter; it does nothing when tracing is disabled.
+func Setup(ctx context.Context, cfg config.MonitoringConfig, serviceName string) (func(context.Context) error, error) {
+	// Propagate trace context even when not exporting, so traces continue
+	// through this process to the ones that do
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeExporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeExporter())
+	}, nil
+}
+
+// newExporter creates the exporter of the configuration and a function
+// releasing what it holds once the provider shut down
+func newExporter(ctx context.Context, cfg config.MonitoringConfig) (sdktrace.SpanExporter, func() error, error) {
+	switch cfg.TracingExporter {
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open tracing file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, file.Close, nil
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.TracingExporter)
+	}
+}
+
+// Start starts a span as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End marks the span failed when err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx in a form that can travel in a task
+// payload, or nil when ctx is not traced
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx continuing the trace context injected into carrier
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Middleware starts a span for each HTTP request, continuing the trace of the
+// caller when it sent one. Health checks are left out.
+func Middleware(serviceName string) echo.MiddlewareFunc {
+	return otelecho.Middleware(serviceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		return strings.HasPrefix(c.Path(), "/health")
+	}))
+}
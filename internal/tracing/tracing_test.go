@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordSpans installs a tracer provider keeping the ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), config.MonitoringConfig{
+		TracingEnabled:     true,
+		TracingExporter:    ExporterFile,
+		TracingFile:        path,
+		TracingSampleRatio: 1,
+	}, "dbackup-test")
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "backup.dump")
+	End(span, errors.New("pg_dump: connection refused"))
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"backup.dump"`)
+	assert.Contains(t, string(data), "dbackup-test")
+	assert.Contains(t, string(data), "pg_dump: connection refused")
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.MonitoringConfig{}, "dbackup-test")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), config.MonitoringConfig{TracingEnabled: true, TracingExporter: "zipkin"}, "dbackup-test")
+	assert.Error(t, err)
+}
+
+func TestInjectExtract(t *testing.T) {
+	recordSpans(t)
+	assert.Nil(t, Inject(context.Background()))
+
+	ctx, span := Start(context.Background(), "backup.enqueue")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	// A task payload travels as JSON, the carrier is all that is kept
+	_, child := Start(Extract(context.Background(), carrier), "backup:postgresql")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+
+	assert.Equal(t, context.Background(), Extract(context.Background(), nil))
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+
+	e := echo.New()
+	e.Use(Middleware("dbackup-test"))
+	e.GET("/health", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.POST("/api/backups", func(c echo.Context) error {
+		assert.Contains(t, Inject(c.Request().Context()), "traceparent")
+		return c.NoContent(http.StatusCreated)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/backups", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "POST /api/backups", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+}
+
+type tracedRecord struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin()))
+	require.NoError(t, db.AutoMigrate(&tracedRecord{}))
+
+	// Queries outside a trace are left alone
+	require.NoError(t, db.Create(&tracedRecord{Name: "untraced"}).Error)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := Start(context.Background(), "backup.record")
+	require.NoError(t, db.WithContext(ctx).Create(&tracedRecord{Name: "traced"}).Error)
+	err = db.WithContext(ctx).Where("name = ?", "missing").First(&tracedRecord{}).Error
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	parent.End()
+
+	assert.Equal(t, []string{"gorm.create", "gorm.select", "backup.record"}, spanNames(recorder))
+	for _, span := range recorder.Ended()[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.NotEqual(t, codes.Error, span.Status().Code, "a missing record is not a failure")
+	}
+	for _, attr := range recorder.Ended()[1].Attributes() {
+		if attr.Key == "db.statement" {
+			assert.Contains(t, attr.Value.AsString(), "name = ?")
+			assert.NotContains(t, attr.Value.AsString(), "missing")
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultReplaySize is the number of events kept per stream for replay
+	DefaultReplaySize = 100
+	// DefaultReplayTTL is how long events are kept for replay
+	DefaultReplayTTL = 10 * time.Minute
+	// DefaultRedisPrefix prefixes the Redis channels and keys of the event bus
+	DefaultRedisPrefix = "dbackup:ws"
+
+	// BroadcastStream carries events for every connected client
+	BroadcastStream = "all"
+
+	// sequenceTTL keeps stream sequences well beyond the replay window so
+	// they do not restart while clients still remember them
+	sequenceTTL = 7 * 24 * time.Hour
+)
+
+// UserStream returns the event stream of a user
+func UserStream(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// TeamStream returns the event stream of a team
+func TeamStream(teamID uint) string {
+	return "team:" + strconv.FormatUint(uint64(teamID), 10)
+}
+
+// parseStream splits a stream into its kind and ID
+func parseStream(stream string) (string, uint, bool) {
+	if stream == BroadcastStream {
+		return BroadcastStream, 0, true
+	}
+
+	kind, rawID, found := strings.Cut(stream, ":")
+	if !found || (kind != "user" && kind != "team") {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return kind, uint(id), true
+}
+
+// EventHandler receives an encoded message published on a stream
+type EventHandler func(stream string, payload []byte)
+
+// EventBus distributes WebSocket events between the nodes serving clients.
+// Every message gets the next sequence number of its stream, and recent
+// messages are kept so reconnecting clients can replay what they missed.
+type EventBus interface {
+	// Publish sequences a message and delivers it to every subscriber
+	Publish(ctx context.Context, stream string, message *Message) error
+	// Subscribe registers a handler until the context is cancelled
+	Subscribe(ctx context.Context, handler EventHandler) error
+	// Replay returns the kept messages of a stream published after a sequence number
+	Replay(ctx context.Context, stream string, since uint64) ([][]byte, error)
+	// Close releases the bus
+	Close() error
+}
+
+// sequenceMessage stamps a copy of a message with its stream position
+func sequenceMessage(stream string, seq uint64, message *Message) ([]byte, error) {
+	sequenced := *message
+	sequenced.Stream = stream
+	sequenced.Seq = seq
+	sequenced.Version = ProtocolVersion
+	if sequenced.Timestamp.IsZero() {
+		sequenced.Timestamp = time.Now()
+	}
+	return json.Marshal(&sequenced)
+}
+
+// bufferedEvent is a message kept for replay
+type bufferedEvent struct {
+	seq       uint64
+	payload   []byte
+	published time.Time
+}
+
+// LocalEventBus delivers events within a single process
+type LocalEventBus struct {
+	mutex      sync.Mutex
+	sequences  map[string]uint64
+	buffers    map[string][]bufferedEvent
+	handlers   map[int]EventHandler
+	nextID     int
+	replaySize int
+	replayTTL  time.Duration
+}
+
+// NewLocalEventBus creates an in-process event bus
+func NewLocalEventBus(replaySize int, replayTTL time.Duration) *LocalEventBus {
+	if replaySize <= 0 {
+		replaySize = DefaultReplaySize
+	}
+	if replayTTL <= 0 {
+		replayTTL = DefaultReplayTTL
+	}
+
+	return &LocalEventBus{
+		sequences:  make(map[string]uint64),
+		buffers:    make(map[string][]bufferedEvent),
+		handlers:   make(map[int]EventHandler),
+		replaySize: replaySize,
+		replayTTL:  replayTTL,
+	}
+}
+
+// Publish sequences a message and calls every handler
+func (b *LocalEventBus) Publish(ctx context.Context, stream string, message *Message) error {
+	b.mutex.Lock()
+	seq := b.sequences[stream] + 1
+	payload, err := sequenceMessage(stream, seq, message)
+	if err != nil {
+		b.mutex.Unlock()
+		return err
+	}
+	b.sequences[stream] = seq
+
+	buffer := append(b.buffers[stream], bufferedEvent{seq: seq, payload: payload, published: time.Now()})
+	if len(buffer) > b.replaySize {
+		buffer = buffer[len(buffer)-b.replaySize:]
+	}
+	b.buffers[stream] = buffer
+
+	handlers := make([]EventHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(stream, payload)
+	}
+	return nil
+}
+
+// Subscribe registers a handler until the context is cancelled
+func (b *LocalEventBus) Subscribe(ctx context.Context, handler EventHandler) error {
+	b.mutex.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	b.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mutex.Lock()
+		delete(b.handlers, id)
+		b.mutex.Unlock()
+	}()
+	return nil
+}
+
+// Replay returns the kept messages of a stream published after a sequence number
+func (b *LocalEventBus) Replay(ctx context.Context, stream string, since uint64) ([][]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	cutoff := time.Now().Add(-b.replayTTL)
+	var payloads [][]byte
+	for _, event := range b.buffers[stream] {
+		if event.seq > since && event.published.After(cutoff) {
+			payloads = append(payloads, event.payload)
+		}
+	}
+	return payloads, nil
+}
+
+// Close drops all handlers
+func (b *LocalEventBus) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = make(map[int]EventHandler)
+	return nil
+}
+
+// RedisEventBus shares events between processes through Redis pub/sub.
+// Sequence numbers come from a counter per stream and recent events are
+// kept in a sorted set scored by sequence.
+type RedisEventBus struct {
+	client     redis.UniversalClient
+	prefix     string
+	replaySize int64
+	replayTTL  time.Duration
+}
+
+// NewRedisEventBus creates an event bus on an existing Redis client
+func NewRedisEventBus(client redis.UniversalClient, prefix string, replaySize int, replayTTL time.Duration) *RedisEventBus {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	if replaySize <= 0 {
+		replaySize = DefaultReplaySize
+	}
+	if replayTTL <= 0 {
+		replayTTL = DefaultReplayTTL
+	}
+
+	return &RedisEventBus{
+		client:     client,
+		prefix:     prefix,
+		replaySize: int64(replaySize),
+		replayTTL:  replayTTL,
+	}
+}
+
+// Publish sequences a message, keeps it for replay and publishes it
+func (b *RedisEventBus) Publish(ctx context.Context, stream string, message *Message) error {
+	seqKey := b.key("seq", stream)
+	seq, err := b.client.Incr(ctx, seqKey).Uint64()
+	if err != nil {
+		return fmt.Errorf("failed to sequence event: %w", err)
+	}
+
+	payload, err := sequenceMessage(stream, seq, message)
+	if err != nil {
+		return err
+	}
+
+	replayKey := b.key("replay", stream)
+	_, err = b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, seqKey, sequenceTTL)
+		pipe.ZAdd(ctx, replayKey, redis.Z{Score: float64(seq), Member: payload})
+		pipe.ZRemRangeByRank(ctx, replayKey, 0, -b.replaySize-1)
+		pipe.Expire(ctx, replayKey, b.replayTTL)
+		pipe.Publish(ctx, b.key("events", stream), payload)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe listens on every stream's channel until the context is cancelled
+func (b *RedisEventBus) Subscribe(ctx context.Context, handler EventHandler) error {
+	channelPrefix := b.key("events", "")
+	pubsub := b.client.PSubscribe(ctx, channelPrefix+"*")
+
+	// Wait for the subscription so no event published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		channel := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-channel:
+				if !ok {
+					return
+				}
+				handler(strings.TrimPrefix(msg.Channel, channelPrefix), []byte(msg.Payload))
+			}
+		}
+	}()
+	return nil
+}
+
+// Replay returns the kept messages of a stream published after a sequence number
+func (b *RedisEventBus) Replay(ctx context.Context, stream string, since uint64) ([][]byte, error) {
+	members, err := b.client.ZRangeByScore(ctx, b.key("replay", stream), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatUint(since, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay events: %w", err)
+	}
+
+	payloads := make([][]byte, len(members))
+	for i, member := range members {
+		payloads[i] = []byte(member)
+	}
+	return payloads, nil
+}
+
+// Close closes the Redis client
+func (b *RedisEventBus) Close() error {
+	return b.client.Close()
+}
+
+// key builds a Redis key or channel name
+func (b *RedisEventBus) key(kind, stream string) string {
+	return b.prefix + ":" + kind + ":" + stream
+}
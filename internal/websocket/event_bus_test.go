@@ -0,0 +1,216 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeMessage(t *testing.T, payload []byte) Message {
+	var message Message
+	require.NoError(t, json.Unmarshal(payload, &message))
+	return message
+}
+
+func receive(t *testing.T, conn *Connection) Message {
+	select {
+	case payload := <-conn.Send:
+		return decodeMessage(t, payload)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatalf("connection %s received nothing", conn.ID)
+		return Message{}
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	kind, id, ok := parseStream(UserStream(42))
+	assert.True(t, ok)
+	assert.Equal(t, "user", kind)
+	assert.Equal(t, uint(42), id)
+
+	kind, id, ok = parseStream(TeamStream(7))
+	assert.True(t, ok)
+	assert.Equal(t, "team", kind)
+	assert.Equal(t, uint(7), id)
+
+	_, _, ok = parseStream(BroadcastStream)
+	assert.True(t, ok)
+
+	for _, stream := range []string{"", "user", "user:abc", "job:1"} {
+		_, _, ok = parseStream(stream)
+		assert.False(t, ok, stream)
+	}
+}
+
+func TestLocalEventBus_SequenceAndReplay(t *testing.T) {
+	bus := NewLocalEventBus(3, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var received []Message
+	require.NoError(t, bus.Subscribe(ctx, func(stream string, payload []byte) {
+		received = append(received, decodeMessage(t, payload))
+	}))
+
+	message := &Message{Type: "backup_progress", Data: "step"}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, bus.Publish(ctx, UserStream(1), message))
+	}
+	require.NoError(t, bus.Publish(ctx, UserStream(2), message))
+
+	require.Len(t, received, 6)
+	assert.Equal(t, uint64(5), received[4].Seq)
+	assert.Equal(t, "user:1", received[4].Stream)
+	assert.Equal(t, uint64(1), received[5].Seq, "streams are sequenced independently")
+	assert.False(t, received[0].Timestamp.IsZero())
+	assert.Zero(t, message.Seq, "the published message is not modified")
+
+	// Only the last events are kept
+	payloads, err := bus.Replay(ctx, UserStream(1), 0)
+	require.NoError(t, err)
+	require.Len(t, payloads, 3)
+	assert.Equal(t, uint64(3), decodeMessage(t, payloads[0]).Seq)
+
+	payloads, err = bus.Replay(ctx, UserStream(1), 4)
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, uint64(5), decodeMessage(t, payloads[0]).Seq)
+
+	// Cancelled subscriptions stop receiving
+	cancel()
+	require.Eventually(t, func() bool {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+		return len(bus.handlers) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLocalEventBus_ReplayExpires(t *testing.T) {
+	bus := NewLocalEventBus(10, 20*time.Millisecond)
+	require.NoError(t, bus.Publish(context.Background(), TeamStream(3), &Message{Type: "test"}))
+
+	time.Sleep(30 * time.Millisecond)
+	payloads, err := bus.Replay(context.Background(), TeamStream(3), 0)
+	require.NoError(t, err)
+	assert.Empty(t, payloads)
+}
+
+func TestWebSocketService_FanOutAcrossNodes(t *testing.T) {
+	// Two API nodes sharing one bus
+	bus := NewLocalEventBus(DefaultReplaySize, DefaultReplayTTL)
+	nodeA := NewWebSocketService(createTestJWTManager(), WithEventBus(bus))
+	nodeB := NewWebSocketService(createTestJWTManager(), WithEventBus(bus))
+	defer nodeA.cancel()
+	defer nodeB.cancel()
+
+	owner := &Connection{ID: "owner", UserID: 1, Send: make(chan []byte, 16), Hub: nodeB.hub}
+	teammate := &Connection{ID: "teammate", UserID: 2, TeamIDs: []uint{9}, Send: make(chan []byte, 16), Hub: nodeB.hub}
+	outsider := &Connection{ID: "outsider", UserID: 3, TeamIDs: []uint{4}, Send: make(chan []byte, 16), Hub: nodeA.hub}
+	nodeB.hub.register <- owner
+	nodeB.hub.register <- teammate
+	nodeA.hub.register <- outsider
+	time.Sleep(20 * time.Millisecond)
+
+	// A worker connected to node A reports progress for a user connected to node B
+	require.NoError(t, nodeA.BroadcastBackupProgress(1, &BackupProgressMessage{BackupJobUID: "job-1", Status: "running"}))
+	message := receive(t, owner)
+	assert.Equal(t, "backup_progress", message.Type)
+	assert.Equal(t, "user:1", message.Stream)
+	assert.Equal(t, uint64(1), message.Seq)
+
+	require.NoError(t, nodeA.BroadcastToTeam(9, &Message{Type: "team_activity"}))
+	message = receive(t, teammate)
+	assert.Equal(t, "team_activity", message.Type)
+	assert.Equal(t, "team:9", message.Stream)
+
+	assert.Empty(t, owner.Send)
+	assert.Empty(t, outsider.Send)
+
+	nodeB.hub.unregister <- teammate
+	time.Sleep(20 * time.Millisecond)
+	nodeB.hub.mutex.RLock()
+	assert.NotContains(t, nodeB.hub.teamChannels, uint(9))
+	nodeB.hub.mutex.RUnlock()
+}
+
+func TestConnection_Replay(t *testing.T) {
+	wsService := NewWebSocketService(createTestJWTManager())
+	defer wsService.cancel()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, wsService.BroadcastToUser(1, &Message{Type: "backup_progress"}))
+	}
+	require.NoError(t, wsService.BroadcastToUser(2, &Message{Type: "backup_progress"}))
+	require.NoError(t, wsService.BroadcastToTeam(9, &Message{Type: "team_activity"}))
+
+	// A reconnecting client asks for what it missed since its last sequence numbers
+	conn := &Connection{ID: "reconnected", UserID: 1, TeamIDs: []uint{9}, Send: make(chan []byte, 16), Hub: wsService.hub}
+	conn.handleIncomingMessage(&Message{Type: "replay", Data: map[string]interface{}{
+		"streams": map[string]interface{}{"user:1": 1, "team:9": 0, "user:2": 0},
+	}})
+
+	var replayed []Message
+	for len(conn.Send) > 0 {
+		replayed = append(replayed, decodeMessage(t, <-conn.Send))
+	}
+	require.Len(t, replayed, 4)
+
+	complete := replayed[len(replayed)-1]
+	assert.Equal(t, "replay_complete", complete.Type)
+	assert.Equal(t, map[string]interface{}{"user:1": float64(2), "team:9": float64(1)}, complete.Data.(map[string]interface{})["replayed"],
+		"other users' streams are not replayed")
+
+	var seqs []uint64
+	for _, message := range replayed[:3] {
+		seqs = append(seqs, message.Seq)
+	}
+	assert.ElementsMatch(t, []uint64{2, 3, 1}, seqs)
+}
+
+func TestRedisEventBus(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	prefix := "dbackup:test:" + generateConnectionID()
+	publisher := NewRedisEventBus(redis.NewClient(opts), prefix, 2, time.Minute)
+	subscriber := NewRedisEventBus(redis.NewClient(opts), prefix, 2, time.Minute)
+	defer publisher.Close()
+	defer subscriber.Close()
+
+	received := make(chan Message, 10)
+	require.NoError(t, subscriber.Subscribe(ctx, func(stream string, payload []byte) {
+		assert.Equal(t, "user:1", stream)
+		received <- decodeMessage(t, payload)
+	}))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, publisher.Publish(ctx, UserStream(1), &Message{Type: "backup_progress"}))
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case message := <-received:
+			assert.Equal(t, uint64(i), message.Seq)
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered through Redis")
+		}
+	}
+
+	payloads, err := subscriber.Replay(ctx, UserStream(1), 1)
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, uint64(3), decodeMessage(t, payloads[1]).Seq)
+
+	publisher.client.Del(ctx, publisher.key("seq", UserStream(1)), publisher.key("replay", UserStream(1)))
+}
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	_ "embed"
+	"time"
+)
+
+// ProtocolVersion is the version of the WebSocket message schema. It is
+// bumped whenever a message or event payload changes incompatibly.
+const ProtocolVersion = 1
+
+// ProtocolSchema is the JSON schema of the messages exchanged over the WebSocket
+//
+//go:embed protocol.schema.json
+var ProtocolSchema []byte
+
+// Event types sent by the server
+const (
+	EventBackupProgress    = "backup_progress"
+	EventJobLifecycle      = "job_lifecycle"
+	EventRestoreProgress   = "restore_progress"
+	EventDiscoveryProgress = "discovery_progress"
+	EventSchemaDrift       = "schema_drift"
+	EventStorageHealth     = "storage_health"
+	EventDatabaseHealth    = "database_health"
+	EventQueueStats        = "queue_stats"
+
+	EventPong           = "pong"
+	EventSubscribed     = "subscribed"
+	EventUnsubscribed   = "unsubscribed"
+	EventReplayComplete = "replay_complete"
+	EventServerShutdown = "server_shutdown"
+)
+
+// Job types reported by job lifecycle events
+const (
+	JobTypeBackup  = "backup"
+	JobTypeRestore = "restore"
+)
+
+// Discovery statuses reported by discovery progress events
+const (
+	DiscoveryStatusRunning   = "running"
+	DiscoveryStatusCompleted = "completed"
+	DiscoveryStatusFailed    = "failed"
+)
+
+// Storage health statuses reported by storage health events
+const (
+	StorageStatusHealthy     = "healthy"
+	StorageStatusDegraded    = "degraded"
+	StorageStatusUnreachable = "unreachable"
+)
+
+// JobLifecycleMessage announces a job changing status
+type JobLifecycleMessage struct {
+	JobUID       string     `json:"job_uid"`
+	JobType      string     `json:"job_type"`
+	RestoreUID   string     `json:"restore_uid,omitempty"` // Restores of the backup JobUID names
+	DatabaseUID  string     `json:"database_uid,omitempty"`
+	Status       string     `json:"status"`
+	ErrorCode    *string    `json:"error_code,omitempty"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// RestoreProgressMessage represents restore progress updates
+type RestoreProgressMessage struct {
+	JobUID          string  `json:"job_uid"`
+	RestoreUID      string  `json:"restore_uid"`
+	BackupFileUID   string  `json:"backup_file_uid,omitempty"`
+	DatabaseUID     string  `json:"database_uid,omitempty"`
+	Status          string  `json:"status"`
+	Progress        float64 `json:"progress"`
+	ProgressMessage string  `json:"progress_message"`
+}
+
+// DiscoveryProgressMessage represents table discovery progress on a database connection
+type DiscoveryProgressMessage struct {
+	DatabaseUID      string  `json:"database_uid"`
+	Status           string  `json:"status"`
+	TablesDiscovered int     `json:"tables_discovered"`
+	TablesTotal      int     `json:"tables_total,omitempty"`
+	Message          string  `json:"message,omitempty"`
+	ErrorMessage     *string `json:"error_message,omitempty"`
+}
+
+// SchemaDriftMessage reports tables and columns that changed since the last discovery
+type SchemaDriftMessage struct {
+	DatabaseUID   string       `json:"database_uid"`
+	AddedTables   []string     `json:"added_tables,omitempty"`
+	RemovedTables []string     `json:"removed_tables,omitempty"`
+	ChangedTables []TableDrift `json:"changed_tables,omitempty"`
+	DetectedAt    time.Time    `json:"detected_at"`
+}
+
+// TableDrift lists the column changes of a table
+type TableDrift struct {
+	Table          string   `json:"table"`
+	AddedColumns   []string `json:"added_columns,omitempty"`
+	RemovedColumns []string `json:"removed_columns,omitempty"`
+	ChangedColumns []string `json:"changed_columns,omitempty"`
+}
+
+// StorageHealthMessage reports the result of a storage health check
+type StorageHealthMessage struct {
+	StorageUID string    `json:"storage_uid"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	LatencyMs  int64     `json:"latency_ms,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// DatabaseHealthMessage reports a change in the health of a database
+// connection, with the same statuses as storage health
+type DatabaseHealthMessage struct {
+	DatabaseUID   string    `json:"database_uid"`
+	Status        string    `json:"status"`
+	Message       string    `json:"message,omitempty"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	LatencyMs     int64     `json:"latency_ms,omitempty"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// QueueStatsMessage is a snapshot of the job queues
+type QueueStatsMessage struct {
+	Pending   int64                  `json:"pending"`
+	Active    int64                  `json:"active"`
+	Scheduled int64                  `json:"scheduled"`
+	Retry     int64                  `json:"retry"`
+	Archived  int64                  `json:"archived"`
+	Completed int64                  `json:"completed"`
+	Failed    int64                  `json:"failed"`
+	Queues    map[string]QueueCounts `json:"queues,omitempty"`
+}
+
+// QueueCounts are the task counts of a single queue
+type QueueCounts struct {
+	Size      int64 `json:"size"`
+	Pending   int64 `json:"pending"`
+	Active    int64 `json:"active"`
+	Scheduled int64 `json:"scheduled"`
+	Retry     int64 `json:"retry"`
+	Archived  int64 `json:"archived"`
+	Paused    bool  `json:"paused"`
+}
@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Subprotocol is the WebSocket subprotocol of this API. Clients that
+	// authenticate with a bearer subprotocol must offer it as well, since the
+	// server never echoes the token back.
+	Subprotocol = "dbackup.v1"
+
+	// bearerProtocolPrefix marks the subprotocol that carries the access token
+	bearerProtocolPrefix = "bearer."
+
+	// accessTokenCookie is the cookie the rest of the API authenticates with
+	accessTokenCookie = "access_token"
+
+	// DefaultMaxConnectionsPerUser bounds the connections a user may open on a node
+	DefaultMaxConnectionsPerUser = 10
+	// DefaultSessionCheckInterval is how often long-lived connections are revalidated
+	DefaultSessionCheckInterval = time.Minute
+)
+
+// Close codes sent when the server ends a connection's session
+const (
+	CloseTokenExpired   = 4001
+	CloseSessionRevoked = 4003
+)
+
+// ErrSessionRevoked is returned by session validators when a user may no longer stay connected
+var ErrSessionRevoked = errors.New("session revoked")
+
+// SessionValidator checks that the session behind a token is still valid, for
+// instance that the token was not revoked and the user was not deactivated.
+// It returns ErrSessionRevoked (or an error wrapping it) to end the session;
+// other errors are treated as temporary.
+type SessionValidator func(ctx context.Context, claims *auth.Claims) error
+
+// WithSessionValidator sets how sessions are revalidated on connect and periodically after
+func WithSessionValidator(validator SessionValidator) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.sessionValidator = validator
+	}
+}
+
+// WithSessionCheckInterval sets how often long-lived connections are revalidated
+func WithSessionCheckInterval(interval time.Duration) ServiceOption {
+	return func(ws *WebSocketService) {
+		if interval > 0 {
+			ws.sessionCheckInterval = interval
+		}
+	}
+}
+
+// WithAllowedOrigins sets the browser origins allowed to connect besides the
+// API's own. A "*" entry allows any origin.
+func WithAllowedOrigins(origins []string) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.allowedOrigins = origins
+	}
+}
+
+// WithMaxConnectionsPerUser bounds the connections a user may open on this node.
+// Zero or less removes the limit.
+func WithMaxConnectionsPerUser(limit int) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.maxConnectionsPerUser = limit
+	}
+}
+
+// requestToken finds the access token of an upgrade request. A bearer
+// subprotocol comes first, then the access token cookie, then the legacy
+// token query parameter.
+func requestToken(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, bearerProtocolPrefix); ok && token != "" {
+			return token
+		}
+	}
+
+	if cookie, err := r.Cookie(accessTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	return r.URL.Query().Get("token")
+}
+
+// checkOrigin accepts requests without an Origin (non-browser clients), from
+// the API's own host and from the allowed origins
+func (ws *WebSocketService) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range ws.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSession runs the session validator, if any
+func (ws *WebSocketService) validateSession(ctx context.Context, claims *auth.Claims) error {
+	if ws.sessionValidator == nil {
+		return nil
+	}
+	return ws.sessionValidator(ctx, claims)
+}
+
+// expiry returns a channel that fires when the connection's token expires.
+// Connections without an expiring token get a channel that never fires.
+func (c *Connection) expiry() (<-chan time.Time, func()) {
+	if c.claims == nil || c.claims.ExpiresAt == nil {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(time.Until(c.claims.ExpiresAt.Time))
+	return timer.C, func() { timer.Stop() }
+}
+
+// sessionRevoked revalidates the connection's session. Validation failures
+// other than a revocation keep the connection open.
+func (c *Connection) sessionRevoked() bool {
+	if c.service == nil || c.claims == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	err := c.service.validateSession(ctx, c.claims)
+	if err == nil {
+		return false
+	}
+	if !errors.Is(err, ErrSessionRevoked) {
+		slog.ErrorContext(c.logContext(), "Failed to revalidate WebSocket session", "error", err)
+		return false
+	}
+	return true
+}
+
+// closeWithCode tells the client why the server ends the connection
+func (c *Connection) closeWithCode(code int, reason string) {
+	slog.InfoContext(c.logContext(), "Closing WebSocket connection", "reason", reason)
+	c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+}
@@ -0,0 +1,220 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ws?token=query-token", nil)
+	assert.Equal(t, "query-token", requestToken(req))
+
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "cookie-token"})
+	assert.Equal(t, "cookie-token", requestToken(req))
+
+	req.Header.Set("Sec-WebSocket-Protocol", "dbackup.v1, bearer.protocol-token")
+	assert.Equal(t, "protocol-token", requestToken(req))
+
+	req = httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "dbackup.v1, bearer.")
+	assert.Empty(t, requestToken(req))
+}
+
+func TestWebSocketService_CheckOrigin(t *testing.T) {
+	wsService := NewWebSocketService(createTestJWTManager(), WithAllowedOrigins([]string{"https://app.example.com/"}))
+	defer wsService.cancel()
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://api.example.com", true},
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"https://evil.example.com", false},
+		{"https://app.example.com.evil.io", false},
+		{"::not a url", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://api.example.com/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		assert.Equal(t, tt.allowed, wsService.checkOrigin(req), tt.origin)
+	}
+
+	anyOrigin := NewWebSocketService(createTestJWTManager(), WithAllowedOrigins([]string{"*"}))
+	defer anyOrigin.cancel()
+	req := httptest.NewRequest("GET", "http://api.example.com/ws", nil)
+	req.Header.Set("Origin", "https://elsewhere.io")
+	assert.True(t, anyOrigin.checkOrigin(req))
+}
+
+// startWebSocketServer serves a WebSocket service and returns its ws:// URL
+func startWebSocketServer(t *testing.T, wsService *WebSocketService) string {
+	e := echo.New()
+	e.GET("/ws", wsService.HandleWebSocket)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// readCloseCode reads until the server closes the connection and returns the close code
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+			return closeErr.Code
+		}
+	}
+}
+
+func TestHandleWebSocket_CookieAndSubprotocol(t *testing.T) {
+	jwtManager := createTestJWTManager()
+	wsService := NewWebSocketService(jwtManager)
+	defer wsService.cancel()
+	wsURL := startWebSocketServer(t, wsService)
+
+	token, err := createTestToken(jwtManager, 7, "user@example.com")
+	require.NoError(t, err)
+
+	// Browsers send the access token cookie
+	header := http.Header{}
+	header.Set("Cookie", "access_token="+token)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	conn.Close()
+
+	// Other clients carry the token in a subprotocol, which is never echoed back
+	dialer := websocket.Dialer{Subprotocols: []string{Subprotocol, bearerProtocolPrefix + token}}
+	conn, resp, err := dialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, Subprotocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+	conn.Close()
+
+	// Disallowed origins are refused
+	header = http.Header{}
+	header.Set("Cookie", "access_token="+token)
+	header.Set("Origin", "https://evil.example.com")
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL, header)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestHandleWebSocket_ConnectionLimit(t *testing.T) {
+	jwtManager := createTestJWTManager()
+	wsService := NewWebSocketService(jwtManager, WithMaxConnectionsPerUser(1))
+	defer wsService.cancel()
+	wsURL := startWebSocketServer(t, wsService)
+
+	token, err := createTestToken(jwtManager, 7, "user@example.com")
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("Cookie", "access_token="+token)
+
+	first, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool { return wsService.GetUserConnectionCount(7) == 1 }, time.Second, 5*time.Millisecond)
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestHandleWebSocket_SessionRevoked(t *testing.T) {
+	var revoked atomic.Bool
+	validator := func(ctx context.Context, claims *auth.Claims) error {
+		if revoked.Load() {
+			return ErrSessionRevoked
+		}
+		return nil
+	}
+
+	jwtManager := createTestJWTManager()
+	wsService := NewWebSocketService(jwtManager, WithSessionValidator(validator), WithSessionCheckInterval(20*time.Millisecond))
+	defer wsService.cancel()
+	wsURL := startWebSocketServer(t, wsService)
+
+	token, err := createTestToken(jwtManager, 7, "user@example.com")
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("Cookie", "access_token="+token)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	revoked.Store(true)
+	assert.Equal(t, CloseSessionRevoked, readCloseCode(t, conn))
+
+	// Revoked sessions cannot reconnect
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandleWebSocket_TemporaryValidationFailure(t *testing.T) {
+	var calls atomic.Int32
+	validator := func(ctx context.Context, claims *auth.Claims) error {
+		if calls.Add(1) > 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}
+
+	jwtManager := createTestJWTManager()
+	wsService := NewWebSocketService(jwtManager, WithSessionValidator(validator), WithSessionCheckInterval(10*time.Millisecond))
+	defer wsService.cancel()
+	wsURL := startWebSocketServer(t, wsService)
+
+	token, err := createTestToken(jwtManager, 7, "user@example.com")
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("Cookie", "access_token="+token)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Failed revalidations keep the connection open
+	require.Eventually(t, func() bool { return calls.Load() > 3 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, conn.WriteJSON(Message{Type: "ping"}))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var pong Message
+	require.NoError(t, conn.ReadJSON(&pong))
+	assert.Equal(t, EventPong, pong.Type)
+}
+
+func TestHandleWebSocket_TokenExpiry(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret-key", 2*time.Second, time.Hour)
+	wsService := NewWebSocketService(jwtManager)
+	defer wsService.cancel()
+	wsURL := startWebSocketServer(t, wsService)
+
+	token, err := createTestToken(jwtManager, 7, "user@example.com")
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("Cookie", "access_token="+token)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, CloseTokenExpired, readCloseCode(t, conn))
+}
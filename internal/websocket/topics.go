@@ -0,0 +1,238 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of topics a connection can subscribe to
+const (
+	TopicJob      = "job"
+	TopicDatabase = "database"
+	TopicTeam     = "team"
+	TopicQueue    = "queue"
+)
+
+// QueueStatsTopic carries queue statistics snapshots
+const QueueStatsTopic = "queue:stats"
+
+// maxSubscriptions bounds the topics a single connection may follow
+const maxSubscriptions = 100
+
+var (
+	// ErrInvalidTopic is returned for topics that are not understood
+	ErrInvalidTopic = errors.New("invalid topic")
+
+	// ErrTopicForbidden is returned when a user may not follow a topic
+	ErrTopicForbidden = errors.New("topic not allowed")
+)
+
+// JobTopic returns the topic of a backup or restore job
+func JobTopic(jobUID string) string {
+	return TopicJob + ":" + jobUID
+}
+
+// DatabaseTopic returns the topic of a database connection
+func DatabaseTopic(databaseUID string) string {
+	return TopicDatabase + ":" + databaseUID
+}
+
+// TeamTopic returns the topic of a team's activity
+func TeamTopic(teamID uint) string {
+	return TopicTeam + ":" + strconv.FormatUint(uint64(teamID), 10)
+}
+
+// TopicStream returns the stream of events that are only sent to a topic's subscribers
+func TopicStream(topic string) string {
+	return "topic:" + topic
+}
+
+// ParseTopic splits a topic into its kind and ID
+func ParseTopic(topic string) (string, string, error) {
+	kind, id, found := strings.Cut(topic, ":")
+	if !found || id == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+	}
+
+	switch kind {
+	case TopicJob, TopicDatabase:
+	case TopicTeam:
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return "", "", fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+		}
+	case TopicQueue:
+		if topic != QueueStatsTopic {
+			return "", "", fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+		}
+	default:
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+	}
+	return kind, id, nil
+}
+
+// TopicAuthorizer decides whether a user may follow a topic. It returns
+// ErrTopicForbidden (or an error wrapping it) when the user may not.
+type TopicAuthorizer interface {
+	AuthorizeTopic(ctx context.Context, userID uint, kind, id string) error
+}
+
+// WithTopicAuthorizer sets how subscriptions are authorized. Without it
+// every subscription is refused.
+func WithTopicAuthorizer(authorizer TopicAuthorizer) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.hub.authorizer = authorizer
+	}
+}
+
+// SubscriptionRequest asks to follow or stop following topics
+type SubscriptionRequest struct {
+	Topics []string `json:"topics"`
+}
+
+// SubscriptionResult answers a subscription request
+type SubscriptionResult struct {
+	Topics   []string          `json:"topics"`
+	Rejected map[string]string `json:"rejected,omitempty"`
+}
+
+// eventTopics are the topics an encoded event is about
+type eventTopics struct {
+	Topics []string `json:"topics"`
+}
+
+// handleSubscribe authorizes and adds the requested topics to the connection
+func (c *Connection) handleSubscribe(msg *Message) {
+	request := decodeSubscriptionRequest(msg)
+	result := SubscriptionResult{Topics: []string{}, Rejected: make(map[string]string)}
+
+	for _, topic := range request.Topics {
+		if err := c.authorizeTopic(topic); err != nil {
+			slog.WarnContext(c.logContext(), "WebSocket subscription denied", "topic", topic, "error", err)
+			result.Rejected[topic] = subscriptionError(err)
+			continue
+		}
+		if !c.Hub.subscribe(c, topic) {
+			result.Rejected[topic] = "too many subscriptions"
+			continue
+		}
+		result.Topics = append(result.Topics, topic)
+	}
+
+	if len(result.Rejected) == 0 {
+		result.Rejected = nil
+	}
+	c.sendMessage(&Message{Type: EventSubscribed, Data: result})
+}
+
+// handleUnsubscribe removes topics from the connection
+func (c *Connection) handleUnsubscribe(msg *Message) {
+	request := decodeSubscriptionRequest(msg)
+	for _, topic := range request.Topics {
+		c.Hub.unsubscribe(c, topic)
+	}
+	c.sendMessage(&Message{Type: EventUnsubscribed, Data: SubscriptionResult{Topics: request.Topics}})
+}
+
+// authorizeTopic checks the connection's user may follow a topic
+func (c *Connection) authorizeTopic(topic string) error {
+	kind, id, err := ParseTopic(topic)
+	if err != nil {
+		return err
+	}
+	if c.Hub.authorizer == nil {
+		return ErrTopicForbidden
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.Hub.authorizer.AuthorizeTopic(ctx, c.UserID, kind, id)
+}
+
+// subscriptionError is the reason given to the client for a refused topic
+func subscriptionError(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidTopic):
+		return "invalid topic"
+	case errors.Is(err, ErrTopicForbidden):
+		return "forbidden"
+	default:
+		// Lookup failures are not detailed to the client
+		return "unavailable"
+	}
+}
+
+// decodeSubscriptionRequest reads the topics of a subscribe or unsubscribe message
+func decodeSubscriptionRequest(msg *Message) SubscriptionRequest {
+	var request SubscriptionRequest
+	if raw, err := json.Marshal(msg.Data); err == nil {
+		json.Unmarshal(raw, &request)
+	}
+	return request
+}
+
+// subscribe adds a connection to a topic's subscribers
+func (h *Hub) subscribe(conn *Connection, topic string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if conn.topics == nil {
+		conn.topics = make(map[string]bool)
+	}
+	if !conn.topics[topic] && len(conn.topics) >= maxSubscriptions {
+		return false
+	}
+	conn.topics[topic] = true
+
+	if h.topicChannels[topic] == nil {
+		h.topicChannels[topic] = make(map[*Connection]bool)
+	}
+	h.topicChannels[topic][conn] = true
+	return true
+}
+
+// unsubscribe removes a connection from a topic's subscribers
+func (h *Hub) unsubscribe(conn *Connection, topic string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.removeSubscription(conn, topic)
+}
+
+// HasSubscribers checks if a connection on this node is subscribed to a topic
+func (ws *WebSocketService) HasSubscribers(topic string) bool {
+	ws.hub.mutex.RLock()
+	defer ws.hub.mutex.RUnlock()
+	return len(ws.hub.topicChannels[topic]) > 0
+}
+
+// removeSubscription drops a subscription. The caller holds the hub mutex.
+func (h *Hub) removeSubscription(conn *Connection, topic string) {
+	delete(conn.topics, topic)
+	if subscribers, exists := h.topicChannels[topic]; exists {
+		delete(subscribers, conn)
+		if len(subscribers) == 0 {
+			delete(h.topicChannels, topic)
+		}
+	}
+}
+
+// accepts checks if a connection wants an event about the given topics.
+// Connections without subscriptions receive every event of their streams;
+// once subscribed they only receive events about their topics and events
+// that are not about any topic. The caller holds the hub mutex.
+func (c *Connection) accepts(topics []string) bool {
+	if len(c.topics) == 0 || len(topics) == 0 {
+		return true
+	}
+	for _, topic := range topics {
+		if c.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicGrants allows users the topics listed for them
+type topicGrants map[uint][]string
+
+func (g topicGrants) AuthorizeTopic(ctx context.Context, userID uint, kind, id string) error {
+	for _, topic := range g[userID] {
+		if topic == kind+":"+id {
+			return nil
+		}
+	}
+	return ErrTopicForbidden
+}
+
+func TestParseTopic(t *testing.T) {
+	for _, topic := range []string{JobTopic("abc"), DatabaseTopic("db-1"), TeamTopic(4), QueueStatsTopic} {
+		_, _, err := ParseTopic(topic)
+		assert.NoError(t, err, topic)
+	}
+
+	kind, id, err := ParseTopic("job:4f1c")
+	require.NoError(t, err)
+	assert.Equal(t, TopicJob, kind)
+	assert.Equal(t, "4f1c", id)
+
+	for _, topic := range []string{"", "job", "job:", "team:abc", "queue:depth", "storage:1"} {
+		_, _, err := ParseTopic(topic)
+		assert.ErrorIs(t, err, ErrInvalidTopic, topic)
+	}
+}
+
+func newTopicTestService(t *testing.T, grants topicGrants) *WebSocketService {
+	wsService := NewWebSocketService(createTestJWTManager(), WithTopicAuthorizer(grants))
+	t.Cleanup(wsService.cancel)
+	return wsService
+}
+
+func registerConnection(ws *WebSocketService, id string, userID uint, teamIDs ...uint) *Connection {
+	conn := &Connection{ID: id, UserID: userID, TeamIDs: teamIDs, Send: make(chan []byte, 16), Hub: ws.hub}
+	ws.hub.register <- conn
+	return conn
+}
+
+func TestConnection_Subscribe(t *testing.T) {
+	wsService := newTopicTestService(t, topicGrants{1: {"job:a", "database:db"}})
+	conn := registerConnection(wsService, "client", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	conn.handleIncomingMessage(&Message{Type: "subscribe", Data: map[string]interface{}{
+		"topics": []string{"job:a", "job:b", "storage:1"},
+	}})
+
+	reply := receive(t, conn)
+	assert.Equal(t, EventSubscribed, reply.Type)
+	assert.Equal(t, ProtocolVersion, reply.Version)
+	assert.Equal(t, map[string]interface{}{
+		"topics":   []interface{}{"job:a"},
+		"rejected": map[string]interface{}{"job:b": "forbidden", "storage:1": "invalid topic"},
+	}, reply.Data)
+
+	wsService.hub.mutex.RLock()
+	assert.Contains(t, wsService.hub.topicChannels, "job:a")
+	assert.NotContains(t, wsService.hub.topicChannels, "job:b")
+	wsService.hub.mutex.RUnlock()
+
+	conn.handleIncomingMessage(&Message{Type: "unsubscribe", Data: map[string]interface{}{"topics": []string{"job:a"}}})
+	assert.Equal(t, EventUnsubscribed, receive(t, conn).Type)
+
+	wsService.hub.mutex.RLock()
+	assert.Empty(t, wsService.hub.topicChannels)
+	assert.Empty(t, conn.topics)
+	wsService.hub.mutex.RUnlock()
+}
+
+func TestConnection_SubscribeWithoutAuthorizer(t *testing.T) {
+	wsService := NewWebSocketService(createTestJWTManager())
+	defer wsService.cancel()
+
+	conn := &Connection{ID: "client", UserID: 1, Send: make(chan []byte, 16), Hub: wsService.hub}
+	conn.handleIncomingMessage(&Message{Type: "subscribe", Data: map[string]interface{}{"topics": []string{"job:a"}}})
+
+	reply := receive(t, conn)
+	assert.Equal(t, map[string]interface{}{"job:a": "forbidden"}, reply.Data.(map[string]interface{})["rejected"])
+}
+
+func TestHub_DeliverByTopic(t *testing.T) {
+	wsService := newTopicTestService(t, topicGrants{
+		1: {"job:a"},
+		2: {"job:a", "team:9", "queue:stats"},
+	})
+
+	// The owner follows one job; a second tab of the owner has no subscriptions
+	owner := registerConnection(wsService, "owner", 1)
+	ownerTab := registerConnection(wsService, "owner-tab", 1)
+	teammate := registerConnection(wsService, "teammate", 2, 9)
+	teammateTab := registerConnection(wsService, "teammate-tab", 2, 9)
+	time.Sleep(20 * time.Millisecond)
+
+	subscribe := func(conn *Connection, topics ...string) {
+		conn.handleIncomingMessage(&Message{Type: "subscribe", Data: map[string]interface{}{"topics": topics}})
+		require.Equal(t, EventSubscribed, receive(t, conn).Type)
+	}
+	subscribe(owner, "job:a")
+	subscribe(teammate, "job:a", "queue:stats")
+
+	// Progress of job a reaches its subscribers, including the teammate, and
+	// the owner's unsubscribed tab
+	require.NoError(t, wsService.BroadcastBackupProgress(1, &BackupProgressMessage{BackupJobUID: "a", DatabaseUID: "db", Status: "running"}))
+	for _, conn := range []*Connection{owner, ownerTab, teammate} {
+		message := receive(t, conn)
+		assert.Equal(t, EventBackupProgress, message.Type, conn.ID)
+		assert.Equal(t, []string{"job:a", "database:db"}, message.Topics)
+	}
+	assert.Empty(t, teammateTab.Send, "events of other users only reach topic subscribers")
+
+	// Progress of another job does not reach the owner's subscribed connection
+	require.NoError(t, wsService.PublishRestoreProgress(1, &RestoreProgressMessage{JobUID: "b", Status: "running"}))
+	assert.Equal(t, EventRestoreProgress, receive(t, ownerTab).Type)
+	assert.Empty(t, owner.Send)
+	assert.Empty(t, teammate.Send)
+
+	// Team events reach members without subscriptions and subscribers of the team topic only
+	require.NoError(t, wsService.BroadcastToTeam(9, &Message{Type: "team_activity"}))
+	assert.Equal(t, "team_activity", receive(t, teammateTab).Type)
+	assert.Empty(t, teammate.Send)
+
+	subscribe(teammate, "team:9")
+	require.NoError(t, wsService.BroadcastToTeam(9, &Message{Type: "team_activity"}))
+	assert.Equal(t, "team_activity", receive(t, teammate).Type)
+	assert.Equal(t, "team_activity", receive(t, teammateTab).Type)
+
+	// Queue statistics only go to their subscribers
+	require.NoError(t, wsService.PublishQueueStats(&QueueStatsMessage{Pending: 3}))
+	message := receive(t, teammate)
+	assert.Equal(t, EventQueueStats, message.Type)
+	assert.Equal(t, "topic:queue:stats", message.Stream)
+	for _, conn := range []*Connection{owner, ownerTab, teammateTab} {
+		assert.Empty(t, conn.Send, conn.ID)
+	}
+
+	// Subscriptions are dropped with the connection
+	wsService.hub.unregister <- teammate
+	time.Sleep(20 * time.Millisecond)
+	wsService.hub.mutex.RLock()
+	assert.NotContains(t, wsService.hub.topicChannels, QueueStatsTopic)
+	assert.Len(t, wsService.hub.topicChannels["job:a"], 1)
+	wsService.hub.mutex.RUnlock()
+}
+
+func TestConnection_ReplayTopicStream(t *testing.T) {
+	wsService := newTopicTestService(t, topicGrants{1: {"queue:stats"}})
+	require.NoError(t, wsService.PublishQueueStats(&QueueStatsMessage{Pending: 1}))
+
+	conn := &Connection{ID: "client", UserID: 1, Send: make(chan []byte, 16), Hub: wsService.hub}
+	replay := &Message{Type: "replay", Data: map[string]interface{}{"streams": map[string]interface{}{"topic:queue:stats": 0}}}
+
+	// Topic streams are only replayed to subscribers
+	conn.handleIncomingMessage(replay)
+	assert.Equal(t, map[string]interface{}{}, receive(t, conn).Data.(map[string]interface{})["replayed"])
+
+	conn.handleIncomingMessage(&Message{Type: "subscribe", Data: map[string]interface{}{"topics": []string{QueueStatsTopic}}})
+	receive(t, conn)
+	conn.handleIncomingMessage(replay)
+	assert.Equal(t, EventQueueStats, receive(t, conn).Type)
+	assert.Equal(t, EventReplayComplete, receive(t, conn).Type)
+}
+
+func TestProtocolSchema(t *testing.T) {
+	var schema struct {
+		Version int                        `json:"version"`
+		Defs    map[string]json.RawMessage `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(ProtocolSchema, &schema))
+	assert.Equal(t, ProtocolVersion, schema.Version)
+
+	var server struct {
+		AllOf []struct {
+			If struct {
+				Properties struct {
+					Type struct {
+						Const string   `json:"const"`
+						Enum  []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"if"`
+		} `json:"allOf"`
+	}
+	require.NoError(t, json.Unmarshal(schema.Defs["serverMessage"], &server))
+
+	documented := make(map[string]bool)
+	for _, rule := range server.AllOf {
+		documented[rule.If.Properties.Type.Const] = true
+		for _, eventType := range rule.If.Properties.Type.Enum {
+			documented[eventType] = true
+		}
+	}
+	for _, eventType := range []string{
+		EventBackupProgress, EventJobLifecycle, EventRestoreProgress, EventDiscoveryProgress,
+		EventSchemaDrift, EventStorageHealth, EventQueueStats,
+		EventPong, EventSubscribed, EventUnsubscribed, EventReplayComplete, EventServerShutdown,
+	} {
+		assert.True(t, documented[eventType], "%s is not documented", eventType)
+	}
+}
+
+func TestWebSocketService_HasSubscribers(t *testing.T) {
+	wsService := newTopicTestService(t, topicGrants{1: {"queue:stats"}})
+	conn := registerConnection(wsService, "client", 1)
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, wsService.HasSubscribers(QueueStatsTopic))
+
+	conn.handleIncomingMessage(&Message{Type: "subscribe", Data: map[string]interface{}{"topics": []string{QueueStatsTopic}}})
+	receive(t, conn)
+	assert.True(t, wsService.HasSubscribers(QueueStatsTopic))
+
+	conn.handleIncomingMessage(&Message{Type: "unsubscribe", Data: map[string]interface{}{"topics": []string{QueueStatsTopic}}})
+	receive(t, conn)
+	assert.False(t, wsService.HasSubscribers(QueueStatsTopic))
+}
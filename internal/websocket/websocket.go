@@ -3,12 +3,15 @@ package websocket
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dbackup/backend-go/internal/auth"
+	"github.com/dbackup/backend-go/internal/logging"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
@@ -21,6 +24,38 @@ type WebSocketService struct {
 	hub         *Hub
 	mutex       sync.RWMutex
 	jwtManager  *auth.JWTManager
+
+	// Events reach the hubs of all nodes through the bus
+	bus          EventBus
+	teamResolver TeamResolver
+	cancel       context.CancelFunc
+
+	// Connection admission and session revalidation
+	sessionValidator      SessionValidator
+	sessionCheckInterval  time.Duration
+	allowedOrigins        []string
+	maxConnectionsPerUser int
+}
+
+// TeamResolver returns the teams a user belongs to
+type TeamResolver func(ctx context.Context, userID uint) ([]uint, error)
+
+// ServiceOption configures a WebSocketService
+type ServiceOption func(*WebSocketService)
+
+// WithEventBus sets the bus events are published on. Without it events
+// only reach clients connected to the same process.
+func WithEventBus(bus EventBus) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.bus = bus
+	}
+}
+
+// WithTeamResolver sets how connections learn their user's teams for team-wide events
+func WithTeamResolver(resolver TeamResolver) ServiceOption {
+	return func(ws *WebSocketService) {
+		ws.teamResolver = resolver
+	}
 }
 
 // Connection represents a WebSocket connection
@@ -32,6 +67,25 @@ type Connection struct {
 	Send     chan []byte
 	Hub      *Hub
 	LastPing time.Time
+	TeamIDs  []uint
+
+	// Topics the connection subscribed to, guarded by the hub mutex
+	topics map[string]bool
+
+	// Token the connection authenticated with, revalidated while it is open
+	claims  *auth.Claims
+	service *WebSocketService
+
+	// Context of the request that opened the connection, carrying its log fields
+	ctx context.Context
+}
+
+// logContext returns the context connection logs are written with
+func (c *Connection) logContext() context.Context {
+	if c.ctx == nil {
+		return logging.With(context.Background(), logging.KeyUserID, c.UserID)
+	}
+	return c.ctx
 }
 
 // Hub manages all WebSocket connections
@@ -51,6 +105,18 @@ type Hub struct {
 	// User-specific channels for targeted messaging
 	userChannels map[uint]map[*Connection]bool
 
+	// Team channels for team-wide messaging
+	teamChannels map[uint]map[*Connection]bool
+
+	// Subscribers of each topic
+	topicChannels map[string]map[*Connection]bool
+
+	// Bus that replays missed events to reconnecting clients
+	bus EventBus
+
+	// Authorizes topic subscriptions
+	authorizer TopicAuthorizer
+
 	// Mutex for thread-safe operations
 	mutex sync.RWMutex
 }
@@ -61,11 +127,25 @@ type Message struct {
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
 	UserID    *uint       `json:"user_id,omitempty"`
+	Version   int         `json:"version,omitempty"`
+
+	// Topics the event is about, matched against the connection's subscriptions
+	Topics []string `json:"topics,omitempty"`
+
+	// Position of the message in its stream, used to replay missed events
+	Stream string `json:"stream,omitempty"`
+	Seq    uint64 `json:"seq,omitempty"`
+}
+
+// ReplayRequest asks for the events of streams published after the given sequence numbers
+type ReplayRequest struct {
+	Streams map[string]uint64 `json:"streams"`
 }
 
 // BackupProgressMessage represents backup progress updates
 type BackupProgressMessage struct {
 	BackupJobUID    string  `json:"backup_job_uid"`
+	DatabaseUID     string  `json:"database_uid,omitempty"`
 	Status          string  `json:"status"`
 	Progress        float64 `json:"progress"`
 	ProgressMessage string  `json:"progress_message"`
@@ -75,27 +155,44 @@ type BackupProgressMessage struct {
 }
 
 // NewWebSocketService creates a new WebSocket service
-func NewWebSocketService(jwtManager *auth.JWTManager) *WebSocketService {
+func NewWebSocketService(jwtManager *auth.JWTManager, opts ...ServiceOption) *WebSocketService {
 	hub := &Hub{
 		connections:  make(map[*Connection]bool),
 		broadcast:    make(chan []byte, 256), // Add buffer to prevent blocking
 		register:     make(chan *Connection),
 		unregister:   make(chan *Connection),
 		userChannels: make(map[uint]map[*Connection]bool),
+		teamChannels: make(map[uint]map[*Connection]bool),
+		topicChannels: make(map[string]map[*Connection]bool),
 	}
 
 	service := &WebSocketService{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// In production, implement proper origin checking
-				return true
-			},
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    []string{Subprotocol},
 		},
-		connections: make(map[string]*Connection),
-		hub:         hub,
-		jwtManager:  jwtManager,
+		connections:           make(map[string]*Connection),
+		hub:                   hub,
+		jwtManager:            jwtManager,
+		sessionCheckInterval:  DefaultSessionCheckInterval,
+		maxConnectionsPerUser: DefaultMaxConnectionsPerUser,
+	}
+	service.upgrader.CheckOrigin = service.checkOrigin
+
+	for _, opt := range opts {
+		opt(service)
+	}
+	if service.bus == nil {
+		service.bus = NewLocalEventBus(DefaultReplaySize, DefaultReplayTTL)
+	}
+	hub.bus = service.bus
+
+	// Deliver events published by any node to this node's connections
+	ctx, cancel := context.WithCancel(context.Background())
+	service.cancel = cancel
+	if err := service.bus.Subscribe(ctx, hub.deliver); err != nil {
+		slog.ErrorContext(ctx, "WebSocket event subscription failed", "error", err)
 	}
 
 	// Start the hub
@@ -104,27 +201,60 @@ func NewWebSocketService(jwtManager *auth.JWTManager) *WebSocketService {
 	return service
 }
 
+// Hub returns the hub holding this node's connections
+func (ws *WebSocketService) Hub() *Hub {
+	return ws.hub
+}
+
+// Close stops receiving events and releases the event bus
+func (ws *WebSocketService) Close() error {
+	ws.cancel()
+	return ws.bus.Close()
+}
+
 // HandleWebSocket handles WebSocket connections
 func (ws *WebSocketService) HandleWebSocket(c echo.Context) error {
 	// Authenticate the WebSocket connection using JWT token
-	token := c.QueryParam("token")
+	token := requestToken(c.Request())
 	if token == "" {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication token required")
 	}
 
 	// Validate the JWT token
-	user, err := ws.authenticateToken(token)
+	claims, err := ws.jwtManager.ValidateToken(token)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid authentication token")
 	}
+	if err := ws.validateSession(c.Request().Context(), claims); err != nil {
+		if errors.Is(err, ErrSessionRevoked) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Session is no longer valid")
+		}
+		slog.ErrorContext(c.Request().Context(), "Failed to validate WebSocket session", logging.KeyUserID, claims.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Unable to verify session")
+	}
+	user := userFromClaims(claims)
+
+	// Checked before the upgrade; concurrent upgrades may briefly exceed the limit
+	if ws.maxConnectionsPerUser > 0 && ws.GetUserConnectionCount(user.ID) >= ws.maxConnectionsPerUser {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "Too many WebSocket connections")
+	}
 
 	// Upgrade HTTP connection to WebSocket
 	conn, err := ws.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.WarnContext(c.Request().Context(), "WebSocket upgrade failed", logging.KeyUserID, user.ID, "error", err)
 		return err
 	}
 
+	// Team-wide events are delivered to members' connections
+	var teamIDs []uint
+	if ws.teamResolver != nil {
+		teamIDs, err = ws.teamResolver(c.Request().Context(), user.ID)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "Failed to resolve teams of WebSocket user", logging.KeyUserID, user.ID, "error", err)
+		}
+	}
+
 	// Create connection object
 	connection := &Connection{
 		ID:       generateConnectionID(),
@@ -134,6 +264,10 @@ func (ws *WebSocketService) HandleWebSocket(c echo.Context) error {
 		Send:     make(chan []byte, 256),
 		Hub:      ws.hub,
 		LastPing: time.Now(),
+		TeamIDs:  teamIDs,
+		claims:   claims,
+		service:  ws,
+		ctx:      logging.With(context.WithoutCancel(c.Request().Context()), logging.KeyUserID, user.ID),
 	}
 
 	// Register connection
@@ -159,68 +293,101 @@ func (ws *WebSocketService) authenticateToken(tokenString string) (*models.User,
 		return nil, err
 	}
 
-	// Create user object from claims
-	user := &models.User{
+	return userFromClaims(claims), nil
+}
+
+// userFromClaims creates a user object from token claims
+func userFromClaims(claims *auth.Claims) *models.User {
+	return &models.User{
 		ID:    claims.UserID,
 		Email: claims.Email,
 	}
-
-	return user, nil
 }
 
-// BroadcastToUser sends a message to all connections for a specific user
+// BroadcastToUser sends a message to all connections for a specific user on every node
 func (ws *WebSocketService) BroadcastToUser(userID uint, message *Message) error {
-	ws.hub.mutex.RLock()
-	userConnections, exists := ws.hub.userChannels[userID]
-	ws.hub.mutex.RUnlock()
+	return ws.bus.Publish(context.Background(), UserStream(userID), message)
+}
 
-	if !exists {
-		return nil // No connections for this user
-	}
+// BroadcastToTeam sends a message to the connections of all members of a team on every node
+func (ws *WebSocketService) BroadcastToTeam(teamID uint, message *Message) error {
+	return ws.bus.Publish(context.Background(), TeamStream(teamID), message)
+}
 
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		return err
-	}
+// BroadcastBackupProgress sends backup progress updates to user and the job's subscribers
+func (ws *WebSocketService) BroadcastBackupProgress(userID uint, progress *BackupProgressMessage) error {
+	return ws.publishToUser(userID, EventBackupProgress, progress, JobTopic(progress.BackupJobUID), databaseTopic(progress.DatabaseUID))
+}
 
-	for conn := range userConnections {
-		select {
-		case conn.Send <- messageBytes:
-		default:
-			close(conn.Send)
-			delete(userConnections, conn)
-		}
-	}
+// PublishJobLifecycle announces a job status change to its user and subscribers
+func (ws *WebSocketService) PublishJobLifecycle(userID uint, event *JobLifecycleMessage) error {
+	return ws.publishToUser(userID, EventJobLifecycle, event, JobTopic(event.JobUID), databaseTopic(event.DatabaseUID))
+}
 
-	return nil
+// PublishRestoreProgress sends restore progress updates to user and the job's subscribers
+func (ws *WebSocketService) PublishRestoreProgress(userID uint, progress *RestoreProgressMessage) error {
+	return ws.publishToUser(userID, EventRestoreProgress, progress, JobTopic(progress.JobUID), databaseTopic(progress.DatabaseUID))
 }
 
-// BroadcastBackupProgress sends backup progress updates to user
-func (ws *WebSocketService) BroadcastBackupProgress(userID uint, progress *BackupProgressMessage) error {
+// PublishDiscoveryProgress sends table discovery progress to user and the database's subscribers
+func (ws *WebSocketService) PublishDiscoveryProgress(userID uint, progress *DiscoveryProgressMessage) error {
+	return ws.publishToUser(userID, EventDiscoveryProgress, progress, DatabaseTopic(progress.DatabaseUID))
+}
+
+// PublishSchemaDrift reports schema changes to user and the database's subscribers
+func (ws *WebSocketService) PublishSchemaDrift(userID uint, drift *SchemaDriftMessage) error {
+	return ws.publishToUser(userID, EventSchemaDrift, drift, DatabaseTopic(drift.DatabaseUID))
+}
+
+// PublishStorageHealth reports a storage health check to the storage's owner
+func (ws *WebSocketService) PublishStorageHealth(userID uint, health *StorageHealthMessage) error {
+	return ws.publishToUser(userID, EventStorageHealth, health)
+}
+
+// PublishDatabaseHealth reports a database health change to the connection's
+// owner and subscribers
+func (ws *WebSocketService) PublishDatabaseHealth(userID uint, health *DatabaseHealthMessage) error {
+	return ws.publishToUser(userID, EventDatabaseHealth, health, DatabaseTopic(health.DatabaseUID))
+}
+
+// PublishQueueStats sends a queue statistics snapshot to the queue stats subscribers
+func (ws *WebSocketService) PublishQueueStats(stats *QueueStatsMessage) error {
 	message := &Message{
-		Type:      "backup_progress",
-		Data:      progress,
+		Type:      EventQueueStats,
+		Data:      stats,
 		Timestamp: time.Now(),
-		UserID:    &userID,
+		Topics:    []string{QueueStatsTopic},
 	}
-
-	return ws.BroadcastToUser(userID, message)
+	return ws.bus.Publish(context.Background(), TopicStream(QueueStatsTopic), message)
 }
 
-// Broadcast sends a message to all connected clients
-func (ws *WebSocketService) Broadcast(message *Message) error {
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		return err
+// publishToUser publishes a typed event on a user's stream
+func (ws *WebSocketService) publishToUser(userID uint, eventType string, data interface{}, topics ...string) error {
+	message := &Message{
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now(),
+		UserID:    &userID,
 	}
+	for _, topic := range topics {
+		if topic != "" {
+			message.Topics = append(message.Topics, topic)
+		}
+	}
+	return ws.BroadcastToUser(userID, message)
+}
 
-	select {
-	case ws.hub.broadcast <- messageBytes:
-	default:
-		return echo.NewHTTPError(http.StatusInternalServerError, "Broadcast channel full")
+// databaseTopic returns the topic of a database connection, if known
+func databaseTopic(databaseUID string) string {
+	if databaseUID == "" {
+		return ""
 	}
+	return DatabaseTopic(databaseUID)
+}
 
-	return nil
+// Broadcast sends a message to all connected clients on every node
+func (ws *WebSocketService) Broadcast(message *Message) error {
+	return ws.bus.Publish(context.Background(), BroadcastStream, message)
 }
 
 // GetConnectionCount returns the number of active connections
@@ -256,10 +423,16 @@ func (h *Hub) run() {
 				h.userChannels[connection.UserID] = make(map[*Connection]bool)
 			}
 			h.userChannels[connection.UserID][connection] = true
+
+			for _, teamID := range connection.TeamIDs {
+				if h.teamChannels[teamID] == nil {
+					h.teamChannels[teamID] = make(map[*Connection]bool)
+				}
+				h.teamChannels[teamID][connection] = true
+			}
 			h.mutex.Unlock()
 
-			log.Printf("WebSocket connection registered for user %d (total: %d)", 
-				connection.UserID, len(h.connections))
+			slog.InfoContext(connection.logContext(), "WebSocket connection registered", "connections", len(h.connections))
 
 		case connection := <-h.unregister:
 			h.mutex.Lock()
@@ -274,11 +447,21 @@ func (h *Hub) run() {
 						delete(h.userChannels, connection.UserID)
 					}
 				}
+				for _, teamID := range connection.TeamIDs {
+					if teamConns, exists := h.teamChannels[teamID]; exists {
+						delete(teamConns, connection)
+						if len(teamConns) == 0 {
+							delete(h.teamChannels, teamID)
+						}
+					}
+				}
+				for topic := range connection.topics {
+					h.removeSubscription(connection, topic)
+				}
 			}
 			h.mutex.Unlock()
 
-			log.Printf("WebSocket connection unregistered for user %d (total: %d)", 
-				connection.UserID, len(h.connections))
+			slog.InfoContext(connection.logContext(), "WebSocket connection unregistered", "connections", len(h.connections))
 
 		case message := <-h.broadcast:
 			h.mutex.RLock()
@@ -295,6 +478,71 @@ func (h *Hub) run() {
 	}
 }
 
+// deliver sends an event from the bus to this node's connections on its
+// stream and to the subscribers of the topics it is about
+func (h *Hub) deliver(stream string, payload []byte) {
+	var event eventTopics
+	json.Unmarshal(payload, &event)
+
+	// Topic streams only reach subscribers
+	if topic, ok := strings.CutPrefix(stream, "topic:"); ok {
+		h.mutex.RLock()
+		defer h.mutex.RUnlock()
+		h.sendTo(h.topicChannels[topic], payload, stream)
+		return
+	}
+
+	kind, id, ok := parseStream(stream)
+	if !ok {
+		slog.Warn("Dropping WebSocket event for unknown stream", "stream", stream)
+		return
+	}
+
+	if kind == BroadcastStream {
+		select {
+		case h.broadcast <- payload:
+		default:
+			slog.Warn("WebSocket broadcast channel full, dropping event")
+		}
+		return
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	topics := event.Topics
+	targets := h.userChannels[id]
+	if kind == "team" {
+		targets = h.teamChannels[id]
+		topics = append(topics, TeamTopic(id))
+	}
+
+	recipients := make(map[*Connection]bool)
+	for conn := range targets {
+		if conn.accepts(topics) {
+			recipients[conn] = true
+		}
+	}
+	for _, topic := range topics {
+		for conn := range h.topicChannels[topic] {
+			recipients[conn] = true
+		}
+	}
+	h.sendTo(recipients, payload, stream)
+}
+
+// sendTo queues an event on connections. The caller holds the hub mutex.
+func (h *Hub) sendTo(connections map[*Connection]bool, payload []byte, stream string) {
+	for conn := range connections {
+		select {
+		case conn.Send <- payload:
+		default:
+			// Slow clients catch up through replay
+			slog.WarnContext(conn.logContext(), "WebSocket send buffer full, dropping event", "stream", stream)
+		}
+	}
+}
+
 // Connection methods
 
 const (
@@ -330,7 +578,7 @@ func (c *Connection) readPump() {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				slog.WarnContext(c.logContext(), "WebSocket error", "error", err)
 			}
 			break
 		}
@@ -346,8 +594,12 @@ func (c *Connection) readPump() {
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Connection) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	sessionTicker := time.NewTicker(c.sessionCheckInterval())
+	expired, stopExpiry := c.expiry()
 	defer func() {
 		ticker.Stop()
+		sessionTicker.Stop()
+		stopExpiry()
 		c.Conn.Close()
 	}()
 
@@ -382,32 +634,120 @@ func (c *Connection) writePump() {
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+
+		case <-expired:
+			c.closeWithCode(CloseTokenExpired, "token expired")
+			return
+
+		case <-sessionTicker.C:
+			if c.sessionRevoked() {
+				c.closeWithCode(CloseSessionRevoked, "session revoked")
+				return
+			}
 		}
 	}
 }
 
+// sessionCheckInterval returns how often the connection's session is revalidated
+func (c *Connection) sessionCheckInterval() time.Duration {
+	if c.service == nil || c.service.sessionCheckInterval <= 0 {
+		return DefaultSessionCheckInterval
+	}
+	return c.service.sessionCheckInterval
+}
+
 // handleIncomingMessage processes messages received from the client
 func (c *Connection) handleIncomingMessage(msg *Message) {
 	switch msg.Type {
 	case "ping":
 		// Respond with pong
-		response := &Message{
-			Type:      "pong",
-			Data:      "pong",
-			Timestamp: time.Now(),
+		c.sendMessage(&Message{Type: EventPong, Data: "pong"})
+	case "replay":
+		c.handleReplay(msg)
+	case "subscribe":
+		c.handleSubscribe(msg)
+	case "unsubscribe":
+		c.handleUnsubscribe(msg)
+	default:
+		slog.WarnContext(c.logContext(), "Unknown WebSocket message type", "type", msg.Type)
+	}
+}
+
+// handleReplay sends the events the client missed on the streams it may read
+func (c *Connection) handleReplay(msg *Message) {
+	var request ReplayRequest
+	if raw, err := json.Marshal(msg.Data); err == nil {
+		json.Unmarshal(raw, &request)
+	}
+
+	replayed := make(map[string]uint64, len(request.Streams))
+	for stream, since := range request.Streams {
+		if !c.canReadStream(stream) || c.Hub.bus == nil {
+			continue
 		}
-		if responseBytes, err := json.Marshal(response); err == nil {
+
+		payloads, err := c.Hub.bus.Replay(context.Background(), stream, since)
+		if err != nil {
+			slog.ErrorContext(c.logContext(), "Failed to replay WebSocket stream", "stream", stream, "error", err)
+			continue
+		}
+		for _, payload := range payloads {
 			select {
-			case c.Send <- responseBytes:
+			case c.Send <- payload:
 			default:
-				// Channel full, skip
+				// Channel full, the client can ask again
 			}
 		}
-	case "subscribe":
-		// Handle subscription requests (for future use)
-		log.Printf("User %d subscribed to updates", c.UserID)
+		replayed[stream] = uint64(len(payloads))
+	}
+
+	c.sendMessage(&Message{Type: EventReplayComplete, Data: map[string]interface{}{"replayed": replayed}})
+}
+
+// sendMessage queues a reply to the client, skipping it when the channel is full
+func (c *Connection) sendMessage(message *Message) {
+	message.Version = ProtocolVersion
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+
+	responseBytes, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	select {
+	case c.Send <- responseBytes:
 	default:
-		log.Printf("Unknown message type from user %d: %s", c.UserID, msg.Type)
+		// Channel full, skip
+	}
+}
+
+// canReadStream checks if a connection may receive a stream's events
+func (c *Connection) canReadStream(stream string) bool {
+	// Topic streams were authorized when subscribing
+	if topic, ok := strings.CutPrefix(stream, "topic:"); ok {
+		c.Hub.mutex.RLock()
+		defer c.Hub.mutex.RUnlock()
+		return c.topics[topic]
+	}
+
+	kind, id, ok := parseStream(stream)
+	if !ok {
+		return false
+	}
+
+	switch kind {
+	case BroadcastStream:
+		return true
+	case "user":
+		return id == c.UserID
+	default:
+		for _, teamID := range c.TeamIDs {
+			if teamID == id {
+				return true
+			}
+		}
+		return false
 	}
 }
 
@@ -428,21 +768,21 @@ func generateRandomString(length int) string {
 
 // Shutdown gracefully closes all WebSocket connections
 func (h *Hub) Shutdown(ctx context.Context) error {
-	log.Println("Shutting down WebSocket hub...")
+	slog.InfoContext(ctx, "Shutting down WebSocket hub")
 
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
 	// Send close message to all connections
 	closeMessage := &Message{
-		Type:      "server_shutdown",
+		Type:      EventServerShutdown,
 		Data:      "Server is shutting down",
 		Timestamp: time.Now(),
 	}
 
 	closeBytes, err := json.Marshal(closeMessage)
 	if err != nil {
-		log.Printf("Error marshaling close message: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal close message", "error", err)
 	}
 
 	// Notify all connections about shutdown
@@ -462,7 +802,9 @@ func (h *Hub) Shutdown(ctx context.Context) error {
 	// Clear all connections
 	h.connections = make(map[*Connection]bool)
 	h.userChannels = make(map[uint]map[*Connection]bool)
+	h.teamChannels = make(map[uint]map[*Connection]bool)
+	h.topicChannels = make(map[string]map[*Connection]bool)
 
-	log.Println("WebSocket hub shutdown completed")
+	slog.InfoContext(ctx, "WebSocket hub shutdown completed")
 	return nil
 }
\ No newline at end of file
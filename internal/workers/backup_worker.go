@@ -1,18 +1,28 @@
 package workers
 
 import (
-	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/metrics"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/tracing"
 	"github.com/dbackup/backend-go/internal/websocket"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -20,27 +30,50 @@ import (
 type BackupWorker struct {
 	db            *gorm.DB
 	backupService services.BackupServiceInterface
-	s3Service     services.S3ServiceInterface
+	storage       services.StorageResolver
 	queueService  services.QueueServiceInterface
 	wsService     *websocket.WebSocketService
+	tablePolicy   *services.TablePolicy
+	concurrency   *services.BackupConcurrency
+	scheduler     *services.BackupScheduler
+	metrics       *metrics.Metrics
+	cipher        *services.BackupCipher
+	notifier      Notifier
+	webhooks      WebhookPublisher
+
+	// Handlers running and whether the worker is shutting down
+	inFlight sync.WaitGroup
+	draining atomic.Bool
 }
 
-// BackupTaskPayload represents the payload for a backup task
+// BackupTaskPayload represents the payload for a backup task.
+// Storage is referenced by UID only so credentials never enter the queue;
+// an empty StorageUID uses the user's or team's default configuration.
 type BackupTaskPayload struct {
-	BackupJobID  uint                         `json:"backup_job_id"`
-	UserID       uint                         `json:"user_id"`
-	DatabaseUID  string                       `json:"database_uid"`
-	Options      *services.BackupOptions      `json:"options,omitempty"`
-	StorageConfig *models.StorageConfiguration `json:"storage_config,omitempty"`
+	BackupJobID uint                    `json:"backup_job_id"`
+	UserID      uint                    `json:"user_id"`
+	DatabaseUID string                  `json:"database_uid"`
+	Options     *services.BackupOptions `json:"options,omitempty"`
+	StorageUID  string                  `json:"storage_uid,omitempty"`
+
+	// Storage configurations that receive asynchronous copies after the primary upload
+	ReplicaStorageUIDs []string `json:"replica_storage_uids,omitempty"`
+
+	// Trace context of the request that queued the backup, continued by the worker
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 // RestoreTaskPayload represents the payload for a restore task
 type RestoreTaskPayload struct {
+	RestoreJobID  uint                    `json:"restore_job_id"`
 	BackupJobID   uint                    `json:"backup_job_id"`
 	UserID        uint                    `json:"user_id"`
 	DatabaseUID   string                  `json:"database_uid"`
 	BackupFileUID string                  `json:"backup_file_uid"`
 	Options       *services.RestoreOptions `json:"options,omitempty"`
+
+	// Trace context of the request that queued the restore, continued by the worker
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 // Job type constants
@@ -51,27 +84,39 @@ const (
 	TypeRestoreMySQL     = "restore:mysql"
 	TypeCleanupBackups   = "cleanup:backups"
 	TypeScheduledBackup  = "scheduled:backup"
+	TypeReplicateBackup  = "replicate:backup"
 )
 
+// errPIIEncryptionRequired rejects storing unmasked personal data without client-side encryption
+var errPIIEncryptionRequired = errors.New("connection requires client-side encryption for backups containing PII")
+
 // NewBackupWorker creates a new backup worker
-func NewBackupWorker(db *gorm.DB, backupService services.BackupServiceInterface, s3Service services.S3ServiceInterface, queueService services.QueueServiceInterface, wsService *websocket.WebSocketService) *BackupWorker {
+func NewBackupWorker(db *gorm.DB, backupService services.BackupServiceInterface, storage services.StorageResolver, queueService services.QueueServiceInterface, wsService *websocket.WebSocketService) *BackupWorker {
 	return &BackupWorker{
 		db:            db,
 		backupService: backupService,
-		s3Service:     s3Service,
+		storage:       storage,
 		queueService:  queueService,
 		wsService:     wsService,
+		tablePolicy:   services.NewTablePolicy(db),
 	}
 }
 
 // RegisterHandlers registers all backup-related job handlers
 func (bw *BackupWorker) RegisterHandlers(worker *services.QueueWorker) {
-	worker.RegisterHandler(TypeBackupPostgreSQL, bw.HandleBackupPostgreSQL)
-	worker.RegisterHandler(TypeBackupMySQL, bw.HandleBackupMySQL)
-	worker.RegisterHandler(TypeRestorePostgreSQL, bw.HandleRestorePostgreSQL)
-	worker.RegisterHandler(TypeRestoreMySQL, bw.HandleRestoreMySQL)
-	worker.RegisterHandler(TypeCleanupBackups, bw.HandleCleanupBackups)
-	worker.RegisterHandler(TypeScheduledBackup, bw.HandleScheduledBackup)
+	worker.RegisterHandler(TypeBackupPostgreSQL, bw.handler(bw.HandleBackupPostgreSQL))
+	worker.RegisterHandler(TypeBackupMySQL, bw.handler(bw.HandleBackupMySQL))
+	worker.RegisterHandler(TypeRestorePostgreSQL, bw.handler(bw.HandleRestorePostgreSQL))
+	worker.RegisterHandler(TypeRestoreMySQL, bw.handler(bw.HandleRestoreMySQL))
+	worker.RegisterHandler(TypeCleanupBackups, bw.handler(bw.HandleCleanupBackups))
+	worker.RegisterHandler(TypeScheduledBackup, bw.handler(bw.HandleScheduledBackup))
+	worker.RegisterHandler(TypeReplicateBackup, bw.handler(bw.HandleReplicateBackup))
+}
+
+// handler wraps a job handler to be tracked for draining, traced and logged
+// with the task's fields
+func (bw *BackupWorker) handler(handler func(context.Context, *asynq.Task) error) func(context.Context, *asynq.Task) error {
+	return bw.track(traced(logged(handler)))
 }
 
 // HandleBackupPostgreSQL handles PostgreSQL backup jobs
@@ -81,25 +126,47 @@ func (bw *BackupWorker) HandleBackupPostgreSQL(ctx context.Context, task *asynq.
 		return fmt.Errorf("failed to unmarshal backup payload: %w", err)
 	}
 
-	log.Printf("Processing PostgreSQL backup job %d for user %d", payload.BackupJobID, payload.UserID)
+	slog.InfoContext(ctx, "Processing PostgreSQL backup job", "backup_job_id", payload.BackupJobID, logging.KeyUserID, payload.UserID)
 
 	// Load backup job from database
 	var backupJob models.BackupJob
-	if err := bw.db.Preload("User").Preload("DatabaseConnection").First(&backupJob, payload.BackupJobID).Error; err != nil {
+	ctx, err := bw.loadBackupJob(ctx, &backupJob, payload.BackupJobID)
+	if err != nil {
 		return fmt.Errorf("failed to load backup job: %w", err)
 	}
 
+	// Permissions may have been revoked since the job was queued
+	if err := bw.authorizeBackupTables(ctx, &payload, &backupJob); err != nil {
+		return err
+	}
+
+	// Wait for a free place when too many backups are running
+	lease, deferred, err := bw.acquireBackupSlots(ctx, task, &payload, &backupJob)
+	if err != nil || deferred {
+		return err
+	}
+	defer bw.releaseBackupSlots(lease, &backupJob)
+
 	// Update job status to running
 	backupJob.Start()
 	if err := bw.db.Save(&backupJob).Error; err != nil {
 		return fmt.Errorf("failed to update backup job status: %w", err)
 	}
+	defer bw.observeJob(metrics.JobBackup, string(models.DatabaseTypePostgreSQL), &backupJob, time.Now())
 	bw.sendBackupProgressUpdate(&backupJob)
+	bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 
 	// Set up progress callback
 	if payload.Options == nil {
 		payload.Options = &services.BackupOptions{}
 	}
+	if err := bw.applyTableRestrictions(ctx, &payload, &backupJob); err != nil {
+		backupJob.Fail(err.Error(), "PERMISSION_DENIED")
+		bw.db.Save(&backupJob)
+		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
+		return fmt.Errorf("backup failed: %w", err)
+	}
 	payload.Options.ProgressCallback = func(progress float64, message string) {
 		backupJob.UpdateProgress(progress, message)
 		bw.db.Save(&backupJob)
@@ -108,22 +175,43 @@ func (bw *BackupWorker) HandleBackupPostgreSQL(ctx context.Context, task *asynq.
 	}
 
 	// Perform the backup
-	result, err := bw.backupService.CreatePostgreSQLBackup(ctx, &backupJob.DatabaseConnection, payload.Options)
+	dumpCtx, span := tracing.Start(ctx, "backup.dump")
+	result, err := bw.backupService.CreatePostgreSQLBackup(dumpCtx, &backupJob.DatabaseConnection, payload.Options)
+	tracing.End(span, err)
 	if err != nil {
+		if bw.interrupted(ctx, &backupJob) {
+			return fmt.Errorf("backup interrupted: %w", err)
+		}
 		backupJob.Fail(err.Error(), "BACKUP_FAILED")
 		bw.db.Save(&backupJob)
 		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 		return fmt.Errorf("backup failed: %w", err)
 	}
 
-	// Upload backup to S3
-	if err := bw.uploadBackupToS3(ctx, &backupJob, result, payload.StorageConfig); err != nil {
-		backupJob.Fail(err.Error(), "UPLOAD_FAILED")
+	// Flag personal data the backup contains in clear
+	if err := bw.flagPIIColumns(ctx, payload.Options, &backupJob, result); err != nil {
+		backupJob.Fail(err.Error(), "BACKUP_FAILED")
 		bw.db.Save(&backupJob)
 		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	// Upload backup to storage
+	if err := bw.uploadBackup(ctx, &backupJob, result, payload.StorageUID, payload.ReplicaStorageUIDs); err != nil {
+		if bw.interrupted(ctx, &backupJob) {
+			return fmt.Errorf("upload interrupted: %w", err)
+		}
+		backupJob.Fail(err.Error(), uploadFailureCode(err))
+		bw.db.Save(&backupJob)
+		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 		return fmt.Errorf("upload failed: %w", err)
 	}
 
+	bw.observeBackupSize(string(models.DatabaseTypePostgreSQL), result)
+
 	// Update job with results
 	backupJob.SetSizeInfo(result.OriginalSize, func() int64 {
 		if result.CompressedSize != nil {
@@ -137,8 +225,9 @@ func (bw *BackupWorker) HandleBackupPostgreSQL(ctx context.Context, task *asynq.
 		return fmt.Errorf("failed to save completed backup job: %w", err)
 	}
 	bw.sendBackupProgressUpdate(&backupJob)
+	bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 
-	log.Printf("PostgreSQL backup job %d completed successfully", payload.BackupJobID)
+	slog.InfoContext(ctx, "PostgreSQL backup job completed", "backup_job_id", payload.BackupJobID)
 	return nil
 }
 
@@ -149,25 +238,47 @@ func (bw *BackupWorker) HandleBackupMySQL(ctx context.Context, task *asynq.Task)
 		return fmt.Errorf("failed to unmarshal backup payload: %w", err)
 	}
 
-	log.Printf("Processing MySQL backup job %d for user %d", payload.BackupJobID, payload.UserID)
+	slog.InfoContext(ctx, "Processing MySQL backup job", "backup_job_id", payload.BackupJobID, logging.KeyUserID, payload.UserID)
 
 	// Load backup job from database
 	var backupJob models.BackupJob
-	if err := bw.db.Preload("User").Preload("DatabaseConnection").First(&backupJob, payload.BackupJobID).Error; err != nil {
+	ctx, err := bw.loadBackupJob(ctx, &backupJob, payload.BackupJobID)
+	if err != nil {
 		return fmt.Errorf("failed to load backup job: %w", err)
 	}
 
+	// Permissions may have been revoked since the job was queued
+	if err := bw.authorizeBackupTables(ctx, &payload, &backupJob); err != nil {
+		return err
+	}
+
+	// Wait for a free place when too many backups are running
+	lease, deferred, err := bw.acquireBackupSlots(ctx, task, &payload, &backupJob)
+	if err != nil || deferred {
+		return err
+	}
+	defer bw.releaseBackupSlots(lease, &backupJob)
+
 	// Update job status to running
 	backupJob.Start()
 	if err := bw.db.Save(&backupJob).Error; err != nil {
 		return fmt.Errorf("failed to update backup job status: %w", err)
 	}
+	defer bw.observeJob(metrics.JobBackup, string(models.DatabaseTypeMySQL), &backupJob, time.Now())
 	bw.sendBackupProgressUpdate(&backupJob)
+	bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 
 	// Set up progress callback
 	if payload.Options == nil {
 		payload.Options = &services.BackupOptions{}
 	}
+	if err := bw.applyTableRestrictions(ctx, &payload, &backupJob); err != nil {
+		backupJob.Fail(err.Error(), "PERMISSION_DENIED")
+		bw.db.Save(&backupJob)
+		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
+		return fmt.Errorf("backup failed: %w", err)
+	}
 	payload.Options.ProgressCallback = func(progress float64, message string) {
 		backupJob.UpdateProgress(progress, message)
 		bw.db.Save(&backupJob)
@@ -176,22 +287,43 @@ func (bw *BackupWorker) HandleBackupMySQL(ctx context.Context, task *asynq.Task)
 	}
 
 	// Perform the backup
-	result, err := bw.backupService.CreateMySQLBackup(ctx, &backupJob.DatabaseConnection, payload.Options)
+	dumpCtx, span := tracing.Start(ctx, "backup.dump")
+	result, err := bw.backupService.CreateMySQLBackup(dumpCtx, &backupJob.DatabaseConnection, payload.Options)
+	tracing.End(span, err)
 	if err != nil {
+		if bw.interrupted(ctx, &backupJob) {
+			return fmt.Errorf("backup interrupted: %w", err)
+		}
 		backupJob.Fail(err.Error(), "BACKUP_FAILED")
 		bw.db.Save(&backupJob)
 		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 		return fmt.Errorf("backup failed: %w", err)
 	}
 
-	// Upload backup to S3
-	if err := bw.uploadBackupToS3(ctx, &backupJob, result, payload.StorageConfig); err != nil {
-		backupJob.Fail(err.Error(), "UPLOAD_FAILED")
+	// Flag personal data the backup contains in clear
+	if err := bw.flagPIIColumns(ctx, payload.Options, &backupJob, result); err != nil {
+		backupJob.Fail(err.Error(), "BACKUP_FAILED")
 		bw.db.Save(&backupJob)
 		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	// Upload backup to storage
+	if err := bw.uploadBackup(ctx, &backupJob, result, payload.StorageUID, payload.ReplicaStorageUIDs); err != nil {
+		if bw.interrupted(ctx, &backupJob) {
+			return fmt.Errorf("upload interrupted: %w", err)
+		}
+		backupJob.Fail(err.Error(), uploadFailureCode(err))
+		bw.db.Save(&backupJob)
+		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 		return fmt.Errorf("upload failed: %w", err)
 	}
 
+	bw.observeBackupSize(string(models.DatabaseTypeMySQL), result)
+
 	// Update job with results
 	backupJob.SetSizeInfo(result.OriginalSize, func() int64 {
 		if result.CompressedSize != nil {
@@ -205,8 +337,9 @@ func (bw *BackupWorker) HandleBackupMySQL(ctx context.Context, task *asynq.Task)
 		return fmt.Errorf("failed to save completed backup job: %w", err)
 	}
 	bw.sendBackupProgressUpdate(&backupJob)
+	bw.sendJobLifecycleUpdate(ctx, &backupJob, websocket.JobTypeBackup)
 
-	log.Printf("MySQL backup job %d completed successfully", payload.BackupJobID)
+	slog.InfoContext(ctx, "MySQL backup job completed", "backup_job_id", payload.BackupJobID)
 	return nil
 }
 
@@ -217,31 +350,50 @@ func (bw *BackupWorker) HandleRestorePostgreSQL(ctx context.Context, task *asynq
 		return fmt.Errorf("failed to unmarshal restore payload: %w", err)
 	}
 
-	log.Printf("Processing PostgreSQL restore job %d for user %d", payload.BackupJobID, payload.UserID)
+	slog.InfoContext(ctx, "Processing PostgreSQL restore job", "backup_job_id", payload.BackupJobID, logging.KeyUserID, payload.UserID)
 
 	// Load backup job and file from database
 	var backupJob models.BackupJob
-	if err := bw.db.Preload("User").Preload("DatabaseConnection").First(&backupJob, payload.BackupJobID).Error; err != nil {
+	ctx, err := bw.loadBackupJob(ctx, &backupJob, payload.BackupJobID)
+	if err != nil {
 		return fmt.Errorf("failed to load backup job: %w", err)
 	}
 
+	// The restore runs under its own record; the backup's status is left alone
+	var restoreJob models.RestoreJob
+	if err := bw.db.WithContext(ctx).First(&restoreJob, payload.RestoreJobID).Error; err != nil {
+		return fmt.Errorf("failed to load restore job: %w", err)
+	}
+
+	// The restore covers the tables the backup was taken from
+	if err := bw.authorizeTables(ctx, payload.UserID, &backupJob, backupJob.Tables, backupJob.ExcludeTables, services.TableActionRestore); err != nil {
+		if isTableRejection(err) {
+			restoreJob.Fail(err.Error(), "PERMISSION_DENIED")
+			bw.db.Save(&restoreJob)
+			bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
+		}
+		return err
+	}
+
 	var backupFile models.BackupFile
-	if err := bw.db.Where("uid = ?", payload.BackupFileUID).First(&backupFile).Error; err != nil {
+	if err := bw.db.Preload("Locations").Where("uid = ?", payload.BackupFileUID).First(&backupFile).Error; err != nil {
 		return fmt.Errorf("failed to load backup file: %w", err)
 	}
 
-	// Update job status to running
-	backupJob.Start()
-	if err := bw.db.Save(&backupJob).Error; err != nil {
-		return fmt.Errorf("failed to update backup job status: %w", err)
+	// Update restore status to running
+	restoreJob.Start()
+	if err := bw.db.Save(&restoreJob).Error; err != nil {
+		return fmt.Errorf("failed to update restore job status: %w", err)
 	}
-	bw.sendBackupProgressUpdate(&backupJob)
+	defer bw.observeRestore(string(models.DatabaseTypePostgreSQL), &restoreJob, time.Now())
+	bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 
-	// Download backup from S3
-	tempPath, err := bw.downloadBackupFromS3(ctx, &backupFile)
+	// Download backup from storage
+	tempPath, err := bw.downloadBackup(ctx, &backupJob, &backupFile)
 	if err != nil {
-		backupJob.Fail(err.Error(), "DOWNLOAD_FAILED")
-		bw.db.Save(&backupJob)
+		restoreJob.Fail(err.Error(), "DOWNLOAD_FAILED")
+		bw.db.Save(&restoreJob)
+		bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 		return fmt.Errorf("download failed: %w", err)
 	}
 	defer bw.cleanupTempFile(tempPath)
@@ -251,27 +403,29 @@ func (bw *BackupWorker) HandleRestorePostgreSQL(ctx context.Context, task *asynq
 		payload.Options = &services.RestoreOptions{}
 	}
 	payload.Options.ProgressCallback = func(progress float64, message string) {
-		backupJob.UpdateProgress(progress, message)
-		bw.db.Save(&backupJob)
+		restoreJob.UpdateProgress(progress, message)
+		bw.db.Save(&restoreJob)
 		// Send WebSocket progress update
-		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendRestoreProgressUpdate(&backupJob, &restoreJob, backupFile.UID)
 	}
 
 	// Perform the restore
 	err = bw.backupService.RestorePostgreSQLBackup(ctx, &backupJob.DatabaseConnection, tempPath, payload.Options)
 	if err != nil {
-		backupJob.Fail(err.Error(), "RESTORE_FAILED")
-		bw.db.Save(&backupJob)
+		restoreJob.Fail(err.Error(), "RESTORE_FAILED")
+		bw.db.Save(&restoreJob)
+		bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 		return fmt.Errorf("restore failed: %w", err)
 	}
 
-	// Update job status
-	backupJob.Complete()
-	if err := bw.db.Save(&backupJob).Error; err != nil {
+	// Update restore status
+	restoreJob.Complete()
+	if err := bw.db.Save(&restoreJob).Error; err != nil {
 		return fmt.Errorf("failed to save completed restore job: %w", err)
 	}
+	bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 
-	log.Printf("PostgreSQL restore job %d completed successfully", payload.BackupJobID)
+	slog.InfoContext(ctx, "PostgreSQL restore job completed", "backup_job_id", payload.BackupJobID)
 	return nil
 }
 
@@ -282,31 +436,50 @@ func (bw *BackupWorker) HandleRestoreMySQL(ctx context.Context, task *asynq.Task
 		return fmt.Errorf("failed to unmarshal restore payload: %w", err)
 	}
 
-	log.Printf("Processing MySQL restore job %d for user %d", payload.BackupJobID, payload.UserID)
+	slog.InfoContext(ctx, "Processing MySQL restore job", "backup_job_id", payload.BackupJobID, logging.KeyUserID, payload.UserID)
 
 	// Load backup job and file from database
 	var backupJob models.BackupJob
-	if err := bw.db.Preload("User").Preload("DatabaseConnection").First(&backupJob, payload.BackupJobID).Error; err != nil {
+	ctx, err := bw.loadBackupJob(ctx, &backupJob, payload.BackupJobID)
+	if err != nil {
 		return fmt.Errorf("failed to load backup job: %w", err)
 	}
 
+	// The restore runs under its own record; the backup's status is left alone
+	var restoreJob models.RestoreJob
+	if err := bw.db.WithContext(ctx).First(&restoreJob, payload.RestoreJobID).Error; err != nil {
+		return fmt.Errorf("failed to load restore job: %w", err)
+	}
+
+	// The restore covers the tables the backup was taken from
+	if err := bw.authorizeTables(ctx, payload.UserID, &backupJob, backupJob.Tables, backupJob.ExcludeTables, services.TableActionRestore); err != nil {
+		if isTableRejection(err) {
+			restoreJob.Fail(err.Error(), "PERMISSION_DENIED")
+			bw.db.Save(&restoreJob)
+			bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
+		}
+		return err
+	}
+
 	var backupFile models.BackupFile
-	if err := bw.db.Where("uid = ?", payload.BackupFileUID).First(&backupFile).Error; err != nil {
+	if err := bw.db.Preload("Locations").Where("uid = ?", payload.BackupFileUID).First(&backupFile).Error; err != nil {
 		return fmt.Errorf("failed to load backup file: %w", err)
 	}
 
-	// Update job status to running
-	backupJob.Start()
-	if err := bw.db.Save(&backupJob).Error; err != nil {
-		return fmt.Errorf("failed to update backup job status: %w", err)
+	// Update restore status to running
+	restoreJob.Start()
+	if err := bw.db.Save(&restoreJob).Error; err != nil {
+		return fmt.Errorf("failed to update restore job status: %w", err)
 	}
-	bw.sendBackupProgressUpdate(&backupJob)
+	defer bw.observeRestore(string(models.DatabaseTypeMySQL), &restoreJob, time.Now())
+	bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 
-	// Download backup from S3
-	tempPath, err := bw.downloadBackupFromS3(ctx, &backupFile)
+	// Download backup from storage
+	tempPath, err := bw.downloadBackup(ctx, &backupJob, &backupFile)
 	if err != nil {
-		backupJob.Fail(err.Error(), "DOWNLOAD_FAILED")
-		bw.db.Save(&backupJob)
+		restoreJob.Fail(err.Error(), "DOWNLOAD_FAILED")
+		bw.db.Save(&restoreJob)
+		bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 		return fmt.Errorf("download failed: %w", err)
 	}
 	defer bw.cleanupTempFile(tempPath)
@@ -316,54 +489,61 @@ func (bw *BackupWorker) HandleRestoreMySQL(ctx context.Context, task *asynq.Task
 		payload.Options = &services.RestoreOptions{}
 	}
 	payload.Options.ProgressCallback = func(progress float64, message string) {
-		backupJob.UpdateProgress(progress, message)
-		bw.db.Save(&backupJob)
+		restoreJob.UpdateProgress(progress, message)
+		bw.db.Save(&restoreJob)
 		// Send WebSocket progress update
-		bw.sendBackupProgressUpdate(&backupJob)
+		bw.sendRestoreProgressUpdate(&backupJob, &restoreJob, backupFile.UID)
 	}
 
 	// Perform the restore
 	err = bw.backupService.RestoreMySQLBackup(ctx, &backupJob.DatabaseConnection, tempPath, payload.Options)
 	if err != nil {
-		backupJob.Fail(err.Error(), "RESTORE_FAILED")
-		bw.db.Save(&backupJob)
+		restoreJob.Fail(err.Error(), "RESTORE_FAILED")
+		bw.db.Save(&restoreJob)
+		bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 		return fmt.Errorf("restore failed: %w", err)
 	}
 
-	// Update job status
-	backupJob.Complete()
-	if err := bw.db.Save(&backupJob).Error; err != nil {
+	// Update restore status
+	restoreJob.Complete()
+	if err := bw.db.Save(&restoreJob).Error; err != nil {
 		return fmt.Errorf("failed to save completed restore job: %w", err)
 	}
+	bw.sendRestoreLifecycleUpdate(ctx, &backupJob, &restoreJob)
 
-	log.Printf("MySQL restore job %d completed successfully", payload.BackupJobID)
+	slog.InfoContext(ctx, "MySQL restore job completed", "backup_job_id", payload.BackupJobID)
 	return nil
 }
 
 // HandleCleanupBackups handles backup cleanup jobs
 func (bw *BackupWorker) HandleCleanupBackups(ctx context.Context, task *asynq.Task) error {
-	log.Printf("Processing backup cleanup job")
+	slog.InfoContext(ctx, "Processing backup cleanup job")
 
 	// Find expired backup files
 	var expiredFiles []models.BackupFile
 	now := time.Now()
-	if err := bw.db.Where("expires_at IS NOT NULL AND expires_at < ?", now).Find(&expiredFiles).Error; err != nil {
+	if err := bw.db.Preload("Locations").Preload("BackupJob.DatabaseConnection").Where("expires_at IS NOT NULL AND expires_at < ?", now).Find(&expiredFiles).Error; err != nil {
 		return fmt.Errorf("failed to find expired backup files: %w", err)
 	}
 
 	cleaned := 0
 	for _, file := range expiredFiles {
-		// Delete from S3
-		if err := bw.s3Service.DeleteFile(ctx, file.S3Bucket, file.S3Key); err != nil {
-			log.Printf("Failed to delete S3 file %s/%s: %v", file.S3Bucket, file.S3Key, err)
+		// Delete the primary copy and every replica; keep the record for the next run if any remain
+		if err := bw.deleteBackupFileCopies(ctx, &file); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete copies of backup file", "backup_file_id", file.ID, "error", err)
 			continue
 		}
 
 		// Delete from database
+		if err := bw.db.Where("backup_file_id = ?", file.ID).Delete(&models.BackupFileLocation{}).Error; err != nil {
+			slog.ErrorContext(ctx, "Failed to delete location records of backup file", "backup_file_id", file.ID, "error", err)
+			continue
+		}
 		if err := bw.db.Delete(&file).Error; err != nil {
-			log.Printf("Failed to delete backup file record %d: %v", file.ID, err)
+			slog.ErrorContext(ctx, "Failed to delete backup file record", "backup_file_id", file.ID, "error", err)
 			continue
 		}
+		bw.publishFileDeleted(ctx, &file)
 
 		cleaned++
 	}
@@ -380,14 +560,14 @@ func (bw *BackupWorker) HandleCleanupBackups(ctx context.Context, task *asynq.Ta
 		if file.ShouldBeArchived(archiveAfterDays) {
 			file.Archive()
 			if err := bw.db.Save(&file).Error; err != nil {
-				log.Printf("Failed to archive backup file %d: %v", file.ID, err)
+				slog.ErrorContext(ctx, "Failed to archive backup file", "backup_file_id", file.ID, "error", err)
 				continue
 			}
 			archived++
 		}
 	}
 
-	log.Printf("Cleanup completed: %d files deleted, %d files archived", cleaned, archived)
+	slog.InfoContext(ctx, "Cleanup completed", "deleted", cleaned, "archived", archived)
 	return nil
 }
 
@@ -398,30 +578,72 @@ func (bw *BackupWorker) HandleScheduledBackup(ctx context.Context, task *asynq.T
 		return fmt.Errorf("failed to unmarshal scheduled backup payload: %w", err)
 	}
 
-	log.Printf("Processing scheduled backup for user %d", payload.UserID)
+	slog.InfoContext(ctx, "Processing scheduled backup", logging.KeyUserID, payload.UserID)
 
-	// Find database connection by UID
-	var dbConn models.DatabaseConnection
-	if err := bw.db.Where("uid = ? AND user_id = ?", payload.DatabaseUID, payload.UserID).First(&dbConn).Error; err != nil {
+	// Backups scheduled through the API were created with their job, which
+	// keeps its priority and options; recurring schedules get a new job
+	var backupJob models.BackupJob
+	if payload.BackupJobID != 0 {
+		if err := bw.db.Preload("DatabaseConnection").First(&backupJob, payload.BackupJobID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				slog.InfoContext(ctx, "Scheduled backup job no longer exists, skipping", "backup_job_id", payload.BackupJobID)
+				return nil
+			}
+			return fmt.Errorf("failed to load scheduled backup job: %w", err)
+		}
+		if backupJob.Status != models.BackupStatusPending {
+			slog.InfoContext(ctx, "Scheduled backup job is no longer pending, skipping", "backup_job_id", backupJob.ID, "status", backupJob.Status)
+			return nil
+		}
+	} else if err := bw.db.Where("uid = ? AND user_id = ?", payload.DatabaseUID, payload.UserID).First(&backupJob.DatabaseConnection).Error; err != nil {
 		return fmt.Errorf("failed to find database connection: %w", err)
 	}
-
-	// Create a new backup job
-	backupJob := &models.BackupJob{
-		Name:                 fmt.Sprintf("Scheduled backup - %s", dbConn.Name),
-		Type:                 models.BackupTypeFull,
-		Status:               models.BackupStatusPending,
-		UserID:               payload.UserID,
-		DatabaseConnectionID: dbConn.ID,
-		IsScheduled:          true,
+	dbConn := backupJob.DatabaseConnection
+
+	// The health monitor pauses schedules while their database or storage is down
+	var storageConfig models.StorageConfiguration
+	skip := ""
+	if dbConn.SchedulesPausedAt != nil {
+		slog.WarnContext(ctx, "Skipping scheduled backup of unhealthy database connection", "database_uid", dbConn.UID, "paused_at", dbConn.SchedulesPausedAt)
+		skip = "Skipped while the database connection is unhealthy"
+	} else if payload.StorageUID != "" {
+		err := bw.db.Select("id", "health_status").Where("uid = ?", payload.StorageUID).First(&storageConfig).Error
+		if err == nil && storageConfig.HealthStatus == models.HealthStatusUnhealthy {
+			slog.WarnContext(ctx, "Skipping scheduled backup to unhealthy storage", "database_uid", dbConn.UID, "storage_uid", payload.StorageUID)
+			skip = "Skipped while the storage is unhealthy"
+		}
+	}
+	if skip != "" {
+		if backupJob.ID != 0 {
+			backupJob.Fail(skip, "SCHEDULE_PAUSED")
+			bw.db.Save(&backupJob)
+		}
+		return nil
 	}
 
-	if err := bw.db.Create(backupJob).Error; err != nil {
-		return fmt.Errorf("failed to create scheduled backup job: %w", err)
+	if backupJob.ID == 0 {
+		backupJob = models.BackupJob{
+			Name:                 fmt.Sprintf("Scheduled backup - %s", dbConn.Name),
+			Type:                 models.BackupTypeFull,
+			Status:               models.BackupStatusPending,
+			UserID:               payload.UserID,
+			DatabaseConnectionID: dbConn.ID,
+			IsScheduled:          true,
+			ReplicaStorageUIDs:   payload.ReplicaStorageUIDs,
+		}
+		if storageConfig.ID != 0 {
+			backupJob.StorageConfigurationID = &storageConfig.ID
+		}
+		if err := bw.db.Create(&backupJob).Error; err != nil {
+			return fmt.Errorf("failed to create scheduled backup job: %w", err)
+		}
+		payload.BackupJobID = backupJob.ID
 	}
 
-	// Update payload with the new backup job ID
-	payload.BackupJobID = backupJob.ID
+	// Schedules replicate to the connection's replica storage unless they name their own
+	if len(payload.ReplicaStorageUIDs) == 0 {
+		payload.ReplicaStorageUIDs = dbConn.ReplicaStorageUIDs
+	}
 
 	// Determine backup type based on database type
 	var jobType string
@@ -434,58 +656,87 @@ func (bw *BackupWorker) HandleScheduledBackup(ctx context.Context, task *asynq.T
 		return fmt.Errorf("unsupported database type: %s", dbConn.Type)
 	}
 
-	// Enqueue the actual backup job
-	_, err := bw.queueService.EnqueueJob(ctx, jobType, payload, services.WithQueue("backups"))
+	// Enqueue the actual backup job, continuing the trace of the schedule
+	payload.TraceContext = tracing.Inject(ctx)
+	jobInfo, err := bw.enqueueBackupTask(ctx, jobType, backupJob.ID, payload, []services.JobOption{services.WithQueue(services.BackupLaneDefault)}, nil)
 	if err != nil {
 		backupJob.Fail(err.Error(), "ENQUEUE_FAILED")
-		bw.db.Save(backupJob)
+		bw.db.Save(&backupJob)
 		return fmt.Errorf("failed to enqueue backup job: %w", err)
 	}
 
-	log.Printf("Scheduled backup job %d enqueued successfully", backupJob.ID)
+	backupJob.QueueName = jobInfo.Queue
+	backupJob.QueueTaskID = jobInfo.ID
+	if err := bw.db.Save(&backupJob).Error; err != nil {
+		slog.WarnContext(ctx, "Failed to record queue task of backup job", "backup_job_id", backupJob.ID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Scheduled backup job enqueued", "backup_job_id", backupJob.ID)
 	return nil
 }
 
-// uploadBackupToS3 uploads a backup file to S3 storage
-func (bw *BackupWorker) uploadBackupToS3(ctx context.Context, job *models.BackupJob, result *services.BackupResult, storageConfig *models.StorageConfiguration) error {
-	if storageConfig == nil {
-		// Use default storage configuration for the user
-		if err := bw.db.Where("user_id = ? AND is_default = ?", job.UserID, true).First(&storageConfig).Error; err != nil {
-			return fmt.Errorf("no default storage configuration found for user: %w", err)
-		}
+// uploadBackup uploads a backup file to its primary storage and schedules replicas
+func (bw *BackupWorker) uploadBackup(ctx context.Context, job *models.BackupJob, result *services.BackupResult, storageUID string, replicaUIDs []string) error {
+	// Resolve the job's storage configuration, falling back to the user's or team's default
+	storageConfig, store, err := bw.storage.ResolveStorage(ctx, storageUID, job.UserID, job.DatabaseConnection.TeamID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve storage configuration: %w", err)
 	}
 
-	// Read backup file
-	backupData, err := bw.readBackupFile(result.FilePath)
+	if requiresClientSideEncryption(job, result.Metadata["contains_pii"] == "true") && !storageConfig.ClientSideEncryption {
+		return fmt.Errorf("%w: storage %s: %w", errPIIEncryptionRequired, storageConfig.Name, asynq.SkipRetry)
+	}
+
+	// Encrypt the file before it leaves the worker when the storage requires it
+	uploadPath, encryptionKey, err := bw.sealBackup(result.FilePath, storageConfig)
 	if err != nil {
-		return fmt.Errorf("failed to read backup file: %w", err)
+		return fmt.Errorf("failed to encrypt backup file: %w", err)
+	}
+	if uploadPath != result.FilePath {
+		defer bw.cleanupTempFile(uploadPath)
 	}
 
-	// Generate S3 key
-	timestamp := time.Now().Format("2006/01/02")
-	s3Key := fmt.Sprintf("backups/%s/%s/%s", timestamp, job.DatabaseConnection.Database, job.UID+".backup")
-	if storageConfig.PathPrefix != nil && *storageConfig.PathPrefix != "" {
-		s3Key = fmt.Sprintf("%s/%s", *storageConfig.PathPrefix, s3Key)
+	backupData, err := os.Open(uploadPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	defer backupData.Close()
+	stat, err := backupData.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
 	}
 
-	// Upload to S3
-	uploadResult, err := bw.s3Service.UploadFile(ctx, storageConfig.Bucket, s3Key, bytes.NewReader(backupData), "application/octet-stream")
+	// Generate object key
+	timestamp := time.Now().Format("2006/01/02")
+	objectKey := fmt.Sprintf("backups/%s/%s/%s", timestamp, job.DatabaseConnection.Database, job.UID+".backup")
+	storageKey := withPathPrefix(storageConfig, objectKey)
+
+	// Upload to storage
+	uploadCtx, span := tracing.Start(ctx, "backup.upload", trace.WithAttributes(
+		attribute.String("storage.provider", string(storageConfig.Provider)),
+		attribute.Int64("backup.size", stat.Size()),
+	))
+	uploaded, err := store.Put(uploadCtx, storageKey, backupData, "application/octet-stream")
+	tracing.End(span, err)
 	if err != nil {
-		return fmt.Errorf("failed to upload to S3: %w", err)
+		return fmt.Errorf("failed to upload to %s: %w", storageConfig.GetProviderDisplayName(), err)
 	}
 
 	// Create backup file record
 	backupFile := &models.BackupFile{
-		Name:         fmt.Sprintf("%s-backup-%s", job.DatabaseConnection.Database, time.Now().Format("20060102-150405")),
-		OriginalName: result.FilePath,
-		FileType:     "dump",
-		S3Bucket:     uploadResult.Bucket,
-		S3Key:        uploadResult.Key,
-		S3Region:     storageConfig.Region,
-		Size:         &result.OriginalSize,
-		BackupJobID:  job.ID,
-		IsEncrypted:  storageConfig.ClientSideEncryption,
-		IsCompressed: result.CompressedSize != nil,
+		Name:                   fmt.Sprintf("%s-backup-%s", job.DatabaseConnection.Database, time.Now().Format("20060102-150405")),
+		OriginalName:           result.FilePath,
+		FileType:               "dump",
+		S3Bucket:               storageConfig.Bucket,
+		S3Key:                  uploaded.Key,
+		S3Region:               storageConfig.Region,
+		Size:                   &result.OriginalSize,
+		BackupJobID:            job.ID,
+		StorageConfigurationID: &storageConfig.ID,
+		IsEncrypted:            encryptionKey != nil,
+		EncryptionKey:          encryptionKey,
+		IsCompressed:           result.CompressedSize != nil,
+		CreatedAt:              time.Now(),
 	}
 
 	if storageConfig.Endpoint != nil {
@@ -502,29 +753,79 @@ func (bw *BackupWorker) uploadBackupToS3(ctx context.Context, job *models.Backup
 		backupFile.SetChecksum(result.Checksum)
 	}
 
+	if len(result.Metadata) > 0 {
+		backupFile.Metadata = make(map[string]interface{}, len(result.Metadata))
+		for key, value := range result.Metadata {
+			backupFile.Metadata[key] = value
+		}
+		if columns, ok := result.Metadata["pii_columns"]; ok {
+			backupFile.Metadata["contains_pii"] = true
+			backupFile.Metadata["pii_columns"] = strings.Split(columns, ",")
+		}
+	}
+
 	// Set retention policy (30 days default)
 	backupFile.SetRetentionPolicy(30)
 
-	if err := bw.db.Create(backupFile).Error; err != nil {
+	primary := models.BackupFileLocation{
+		Bucket:                 storageConfig.Bucket,
+		Key:                    uploaded.Key,
+		Region:                 storageConfig.Region,
+		Endpoint:               storageConfig.Endpoint,
+		IsPrimary:              true,
+		StorageConfigurationID: &storageConfig.ID,
+	}
+	primary.MarkAvailable(stat.Size())
+
+	recordCtx, span := tracing.Start(ctx, "backup.record")
+	err = bw.db.WithContext(recordCtx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(backupFile).Error; err != nil {
+			return err
+		}
+		primary.BackupFileID = backupFile.ID
+		return tx.Create(&primary).Error
+	})
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("failed to create backup file record: %w", err)
 	}
 
-	log.Printf("Backup uploaded to S3: %s/%s", uploadResult.Bucket, uploadResult.Key)
+	slog.InfoContext(ctx, "Backup uploaded", "provider", storageConfig.GetProviderDisplayName(), "bucket", storageConfig.Bucket, "key", uploaded.Key)
+	bw.checkStorageQuota(ctx, job, storageConfig, *backupFile.Size)
+
+	// Replicas are copied asynchronously and never fail the backup itself
+	bw.scheduleReplicas(ctx, job, backupFile, storageConfig, objectKey, replicaUIDs)
 	return nil
 }
 
-// downloadBackupFromS3 downloads a backup file from S3 to a temporary location
-func (bw *BackupWorker) downloadBackupFromS3(ctx context.Context, backupFile *models.BackupFile) (string, error) {
-	// Download from S3
-	reader, err := bw.s3Service.DownloadFile(ctx, backupFile.S3Bucket, backupFile.S3Key)
+// downloadBackup downloads a backup file from storage to a temporary location
+func (bw *BackupWorker) downloadBackup(ctx context.Context, job *models.BackupJob, backupFile *models.BackupFile) (string, error) {
+	// Download from the primary, falling back to replicas
+	reader, _, err := bw.openBackupFile(ctx, job, backupFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to download from S3: %w", err)
+		return "", fmt.Errorf("failed to download from storage: %w", err)
 	}
 	defer reader.Close()
 
+	plain, err := bw.cipher.OpenFile(reader, backupFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt backup file: %w", err)
+	}
+
+	// Files uploaded compressed are restored from their dump
+	var dump io.Reader = plain
+	if backupFile.IsCompressed {
+		gz, err := gzip.NewReader(plain)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress backup file: %w", err)
+		}
+		defer gz.Close()
+		dump = gz
+	}
+
 	// Write to temporary file
-	tempPath := fmt.Sprintf("/tmp/restore_%s_%d", backupFile.UID, time.Now().Unix())
-	if err := bw.writeBackupFileFromReader(tempPath, reader); err != nil {
+	tempPath, err := bw.writeTempFile(fmt.Sprintf("restore_%s_*", backupFile.UID), dump)
+	if err != nil {
 		return "", fmt.Errorf("failed to write temporary file: %w", err)
 	}
 
@@ -535,55 +836,188 @@ func (bw *BackupWorker) downloadBackupFromS3(ctx context.Context, backupFile *mo
 	return tempPath, nil
 }
 
-// Helper methods for file operations
-func (bw *BackupWorker) readBackupFile(filePath string) ([]byte, error) {
-	// Implementation would read the file from disk
-	// For now, return empty data to satisfy interface
-	return []byte{}, nil
+// authorizeBackupTables checks the task's user may still back up the requested tables
+// and pins the requested names to the catalogued tables they denote, so dump tools
+// never treat them as patterns. Denied jobs are failed and not retried.
+func (bw *BackupWorker) authorizeBackupTables(ctx context.Context, payload *BackupTaskPayload, backupJob *models.BackupJob) error {
+	var tables, excludeTables []string
+	if payload.Options != nil {
+		tables = payload.Options.Tables
+		excludeTables = payload.Options.ExcludeTables
+	}
+
+	err := bw.authorizeTables(ctx, payload.UserID, backupJob, tables, excludeTables, services.TableActionBackup)
+	if err == nil && payload.Options != nil {
+		if payload.Options.Tables, err = bw.tablePolicy.ResolveTables(ctx, &backupJob.DatabaseConnection, tables); err == nil {
+			payload.Options.ExcludeTables, err = bw.tablePolicy.ResolveTables(ctx, &backupJob.DatabaseConnection, excludeTables)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+	}
+	if err != nil && isTableRejection(err) {
+		backupJob.Fail(err.Error(), "PERMISSION_DENIED")
+		bw.db.Save(backupJob)
+		bw.sendBackupProgressUpdate(backupJob)
+		bw.sendJobLifecycleUpdate(ctx, backupJob, websocket.JobTypeBackup)
+	}
+	return err
+}
+
+// isTableRejection checks if a table check failed because of the request rather
+// than an outage: a denied, unknown or pattern table name
+func isTableRejection(err error) bool {
+	return errors.Is(err, services.ErrTableAccessDenied) ||
+		errors.Is(err, services.ErrTableNotCatalogued) ||
+		errors.Is(err, services.ErrInvalidTableName)
 }
 
-func (bw *BackupWorker) writeBackupFile(filePath string, data []byte) error {
-	// Implementation would write data to file
-	// For now, return nil to satisfy interface
+// applyTableRestrictions adds the row filters and column masks of the user's
+// table grants to the backup. Restricted tables are only ever exported
+// sanitized, so a raw dump becomes a sanitized one when it covers them.
+func (bw *BackupWorker) applyTableRestrictions(ctx context.Context, payload *BackupTaskPayload, backupJob *models.BackupJob) error {
+	access, err := bw.tablePolicy.EffectivePermissions(ctx, payload.UserID, &backupJob.DatabaseConnection)
+	if err != nil {
+		return fmt.Errorf("failed to check table permissions: %w", err)
+	}
+
+	rules, err := access.SanitizeRules(payload.Options.Tables, payload.Options.ExcludeTables)
+	if err != nil {
+		return fmt.Errorf("invalid table restriction: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	if payload.Options.Sanitize == nil {
+		payload.Options.Sanitize = &services.SanitizeOptions{}
+	}
+	// Mandatory rules go first so their masks win over requested ones
+	payload.Options.Sanitize.Rules = append(rules, payload.Options.Sanitize.Rules...)
 	return nil
 }
 
-func (bw *BackupWorker) writeBackupFileFromReader(filePath string, reader io.Reader) error {
-	// Implementation would write reader to file
-	// For now, return nil to satisfy interface
+// flagPIIColumns records the catalogued PII columns a backup contains unmasked
+func (bw *BackupWorker) flagPIIColumns(ctx context.Context, options *services.BackupOptions, backupJob *models.BackupJob, result *services.BackupResult) error {
+	columns, err := services.UnmaskedPIIColumns(ctx, bw.db, &backupJob.DatabaseConnection, options)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["contains_pii"] = "true"
+	result.Metadata["pii_columns"] = strings.Join(columns, ",")
 	return nil
 }
 
+// requiresClientSideEncryption checks if a backup may only be stored with client-side encryption
+func requiresClientSideEncryption(job *models.BackupJob, containsPII bool) bool {
+	return containsPII && job.DatabaseConnection.RequirePIIEncryption
+}
+
+// uploadFailureCode returns the job error code for a failed upload
+func uploadFailureCode(err error) string {
+	if errors.Is(err, errPIIEncryptionRequired) {
+		return "PII_ENCRYPTION_REQUIRED"
+	}
+	return "UPLOAD_FAILED"
+}
+
+// authorizeTables checks a user may perform an action on a job's tables
+func (bw *BackupWorker) authorizeTables(ctx context.Context, userID uint, backupJob *models.BackupJob, tables, excludeTables []string, action string) error {
+	err := bw.tablePolicy.CheckTables(ctx, userID, &backupJob.DatabaseConnection, tables, excludeTables, action)
+	if err == nil {
+		return nil
+	}
+	if isTableRejection(err) {
+		slog.WarnContext(ctx, "Rejecting job", "action", action, "backup_job_id", backupJob.ID, logging.KeyUserID, userID, "error", err)
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	return fmt.Errorf("failed to check table permissions: %w", err)
+}
+
+// writeTempFile writes a reader to a new temporary file and returns its path
+func (bw *BackupWorker) writeTempFile(pattern string, reader io.Reader) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// cleanupTempFile removes a temporary file
 func (bw *BackupWorker) cleanupTempFile(filePath string) {
-	// Implementation would remove the temporary file
-	log.Printf("Cleaning up temporary file: %s", filePath)
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to remove temporary file", "path", filePath, "error", err)
+	}
 }
 
-// EnqueueBackupJob is a helper method to enqueue backup jobs
+// EnqueueBackupJob is a helper method to enqueue backup jobs in the lane of
+// their priority
 func (bw *BackupWorker) EnqueueBackupJob(ctx context.Context, jobType string, payload *BackupTaskPayload, options ...services.JobOption) (*services.JobInfo, error) {
 	// Set default options if not provided
 	defaultOptions := []services.JobOption{
-		services.WithQueue("backups"),
+		services.WithQueue(services.BackupLaneDefault),
 		services.WithMaxRetry(3),
 		services.WithTimeout(30 * time.Minute),
 	}
-	
-	allOptions := append(defaultOptions, options...)
 
-	return bw.queueService.EnqueueJob(ctx, jobType, payload, allOptions...)
+	ctx, span := tracing.Start(ctx, "backup.enqueue", trace.WithSpanKind(trace.SpanKindProducer))
+	payload.TraceContext = tracing.Inject(ctx)
+	jobInfo, err := bw.enqueueBackupTask(ctx, jobType, payload.BackupJobID, payload, defaultOptions, options)
+	tracing.End(span, err)
+	return jobInfo, err
 }
 
-// EnqueueScheduledBackupJob is a helper method to enqueue scheduled backup jobs
+// EnqueueScheduledBackupJob queues a backup job to run at a later time. When
+// it is due the job is queued in the lane of its priority and charged to its
+// tenant's fair share like any other backup.
 func (bw *BackupWorker) EnqueueScheduledBackupJob(ctx context.Context, payload *BackupTaskPayload, scheduledTime time.Time, options ...services.JobOption) (*services.JobInfo, error) {
 	defaultOptions := []services.JobOption{
-		services.WithQueue("scheduled"),
+		services.WithQueue(QueueScheduled),
 		services.WithMaxRetry(2),
 		services.WithTimeout(35 * time.Minute),
 	}
 	
 	allOptions := append(defaultOptions, options...)
 
-	return bw.queueService.EnqueueScheduledJob(ctx, TypeScheduledBackup, payload, scheduledTime, allOptions...)
+	ctx, span := tracing.Start(ctx, "backup.enqueue", trace.WithSpanKind(trace.SpanKindProducer))
+	payload.TraceContext = tracing.Inject(ctx)
+	jobInfo, err := bw.queueService.EnqueueScheduledJob(ctx, TypeScheduledBackup, payload, scheduledTime, allOptions...)
+	tracing.End(span, err)
+	return jobInfo, err
+}
+
+// EnqueueRestoreJob is a helper method to enqueue restore jobs. Someone is
+// usually waiting on a restore, so it goes to the high lane, and it is not
+// retried since a failed restore may have left the database half written.
+func (bw *BackupWorker) EnqueueRestoreJob(ctx context.Context, jobType string, payload *RestoreTaskPayload, options ...services.JobOption) (*services.JobInfo, error) {
+	defaultOptions := []services.JobOption{
+		services.WithQueue(services.BackupLaneHigh),
+		services.WithMaxRetry(0),
+		services.WithTimeout(60 * time.Minute),
+	}
+
+	allOptions := append(defaultOptions, options...)
+
+	ctx, span := tracing.Start(ctx, "restore.enqueue", trace.WithSpanKind(trace.SpanKindProducer))
+	payload.TraceContext = tracing.Inject(ctx)
+	jobInfo, err := bw.queueService.EnqueueJob(ctx, jobType, payload, allOptions...)
+	tracing.End(span, err)
+	return jobInfo, err
 }
 
 // GetBackupJobStatus returns the current status of a backup job
@@ -603,6 +1037,7 @@ func (bw *BackupWorker) sendBackupProgressUpdate(backupJob *models.BackupJob) {
 
 	progressMsg := &websocket.BackupProgressMessage{
 		BackupJobUID:    backupJob.UID,
+		DatabaseUID:     backupJob.DatabaseConnection.UID,
 		Status:          string(backupJob.Status),
 		Progress:        backupJob.Progress,
 		ProgressMessage: backupJob.CurrentStep,
@@ -617,6 +1052,78 @@ func (bw *BackupWorker) sendBackupProgressUpdate(backupJob *models.BackupJob) {
 	// Send progress update to user via WebSocket
 	err := bw.wsService.BroadcastBackupProgress(backupJob.UserID, progressMsg)
 	if err != nil {
-		log.Printf("Failed to send WebSocket backup progress update: %v", err)
+		slog.Warn("Failed to send WebSocket backup progress update", "error", err)
+	}
+}
+// sendJobLifecycleUpdate announces a job status change over WebSocket
+func (bw *BackupWorker) sendJobLifecycleUpdate(ctx context.Context, backupJob *models.BackupJob, jobType string) {
+	bw.notifyJob(ctx, backupJob)
+	bw.publishJob(ctx, backupJob)
+
+	if bw.wsService == nil {
+		return // WebSocket service not available
+	}
+
+	event := &websocket.JobLifecycleMessage{
+		JobUID:       backupJob.UID,
+		JobType:      jobType,
+		DatabaseUID:  backupJob.DatabaseConnection.UID,
+		Status:       string(backupJob.Status),
+		ErrorCode:    backupJob.ErrorCode,
+		ErrorMessage: backupJob.ErrorMessage,
+		StartedAt:    backupJob.StartedAt,
+		CompletedAt:  backupJob.CompletedAt,
+	}
+
+	if err := bw.wsService.PublishJobLifecycle(backupJob.UserID, event); err != nil {
+		slog.Warn("Failed to send WebSocket job lifecycle update", "error", err)
+	}
+}
+
+// sendRestoreLifecycleUpdate announces a restore status change over
+// WebSocket. Events carry the UID of the backup restored, which clients follow.
+func (bw *BackupWorker) sendRestoreLifecycleUpdate(ctx context.Context, backupJob *models.BackupJob, restoreJob *models.RestoreJob) {
+	bw.notifyRestore(ctx, backupJob, restoreJob)
+	bw.publishRestore(ctx, backupJob, restoreJob)
+
+	if bw.wsService == nil {
+		return // WebSocket service not available
+	}
+
+	event := &websocket.JobLifecycleMessage{
+		JobUID:       backupJob.UID,
+		JobType:      websocket.JobTypeRestore,
+		RestoreUID:   restoreJob.UID,
+		DatabaseUID:  backupJob.DatabaseConnection.UID,
+		Status:       string(restoreJob.Status),
+		ErrorCode:    restoreJob.ErrorCode,
+		ErrorMessage: restoreJob.ErrorMessage,
+		StartedAt:    restoreJob.StartedAt,
+		CompletedAt:  restoreJob.CompletedAt,
 	}
-}
\ No newline at end of file
+
+	if err := bw.wsService.PublishJobLifecycle(restoreJob.UserID, event); err != nil {
+		slog.Warn("Failed to send WebSocket job lifecycle update", "error", err)
+	}
+}
+
+// sendRestoreProgressUpdate sends WebSocket progress updates for restore jobs
+func (bw *BackupWorker) sendRestoreProgressUpdate(backupJob *models.BackupJob, restoreJob *models.RestoreJob, backupFileUID string) {
+	if bw.wsService == nil {
+		return // WebSocket service not available
+	}
+
+	progressMsg := &websocket.RestoreProgressMessage{
+		JobUID:          backupJob.UID,
+		RestoreUID:      restoreJob.UID,
+		BackupFileUID:   backupFileUID,
+		DatabaseUID:     backupJob.DatabaseConnection.UID,
+		Status:          string(restoreJob.Status),
+		Progress:        restoreJob.Progress,
+		ProgressMessage: restoreJob.CurrentStep,
+	}
+
+	if err := bw.wsService.PublishRestoreProgress(restoreJob.UserID, progressMsg); err != nil {
+		slog.Warn("Failed to send WebSocket restore progress update", "error", err)
+	}
+}
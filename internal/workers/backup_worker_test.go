@@ -1,14 +1,19 @@
 package workers
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/dbackup/backend-go/internal/encryption"
 	"github.com/dbackup/backend-go/internal/models"
 	"github.com/dbackup/backend-go/internal/services"
 	"github.com/dbackup/backend-go/internal/websocket"
@@ -16,7 +21,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // Mock services for testing
@@ -303,23 +310,163 @@ func (m *MockWebSocketService) GetUserConnectionCount(userID uint) int {
 	return args.Int(0)
 }
 
+// MockStorageResolver for testing
+type MockStorageResolver struct {
+	mock.Mock
+}
+
+func (m *MockStorageResolver) ResolveStorage(ctx context.Context, uid string, userID uint, teamID *uint) (*models.StorageConfiguration, services.ObjectStore, error) {
+	args := m.Called(ctx, uid, userID, teamID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.StorageConfiguration), args.Get(1).(services.ObjectStore), args.Error(2)
+}
+
+func (m *MockStorageResolver) ResolveStorageByID(ctx context.Context, id uint) (*models.StorageConfiguration, services.ObjectStore, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.StorageConfiguration), args.Get(1).(services.ObjectStore), args.Error(2)
+}
+
+func (m *MockStorageResolver) InvalidateCache(uid string) {
+	m.Called(uid)
+}
+
+// Test helper functions
+func setupTestDB(tb testing.TB) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(tb, err)
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.DatabaseConnection{},
+		&models.DatabaseTable{},
+		&models.DatabaseColumn{},
+		&models.TablePermission{},
+		&models.StorageConfiguration{},
+		&models.BackupJob{},
+		&models.BackupFile{},
+		&models.BackupFileLocation{},
+		&models.RestoreJob{},
+		&models.HealthCheck{},
+		&models.NotificationChannel{},
+		&models.NotificationSubscription{},
+		&models.NotificationDelivery{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+	)
+	require.NoError(tb, err)
+
+	return db
+}
+
+func createTestBackupJob(tb testing.TB, db *gorm.DB, dbType models.DatabaseType) *models.BackupJob {
+	user := &models.User{Email: "worker@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(tb, db.Create(user).Error)
+
+	dbConn := &models.DatabaseConnection{
+		Name:     "Test Database",
+		Type:     dbType,
+		Host:     "localhost",
+		Port:     5432,
+		Database: "testdb",
+		Username: "testuser",
+		Password: "testpass",
+		UserID:   user.ID,
+	}
+	require.NoError(tb, db.Create(dbConn).Error)
+
+	job := &models.BackupJob{
+		Name:                 "Test Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		UserID:               user.ID,
+		DatabaseConnectionID: dbConn.ID,
+	}
+	require.NoError(tb, db.Create(job).Error)
+	job.DatabaseConnection = *dbConn
+
+	return job
+}
+
+func createTestStorageConfiguration(tb testing.TB, db *gorm.DB, userID uint, bucket string) *models.StorageConfiguration {
+	config := &models.StorageConfiguration{
+		Name:      bucket,
+		Provider:  models.StorageProviderAWS,
+		Region:    "us-east-1",
+		Bucket:    bucket,
+		AccessKey: "encrypted-access-key",
+		SecretKey: "encrypted-secret-key",
+		IsActive:  true,
+		UserID:    userID,
+	}
+	require.NoError(tb, db.Create(config).Error)
+	return config
+}
+
+// testBackupCipher encrypts test uploads under a fixed master key
+func testBackupCipher() *services.BackupCipher {
+	return services.NewBackupCipher(encryption.NewService("test-key-for-testing"))
+}
+
+// writeTestDump writes a dump file for the worker to upload
+func writeTestDump(tb testing.TB) string {
+	path := filepath.Join(tb.TempDir(), "test.backup")
+	require.NoError(tb, os.WriteFile(path, []byte("backup data"), 0o600))
+	return path
+}
+
+func createTestBackupFile(tb testing.TB, db *gorm.DB, job *models.BackupJob, storage *models.StorageConfiguration) *models.BackupFile {
+	file := &models.BackupFile{
+		Name:         "testdb-backup",
+		OriginalName: "/tmp/test.backup",
+		FileType:     "dump",
+		S3Bucket:     storage.Bucket,
+		S3Key:        "backups/test.backup",
+		S3Region:     storage.Region,
+		BackupJobID:  job.ID,
+
+		StorageConfigurationID: &storage.ID,
+	}
+	require.NoError(tb, db.Create(file).Error)
+	return file
+}
+
+func createTestRestoreJob(tb testing.TB, db *gorm.DB, job *models.BackupJob, fileID uint) *models.RestoreJob {
+	restoreJob := &models.RestoreJob{
+		Status:       models.BackupStatusPending,
+		BackupJobID:  job.ID,
+		BackupFileID: fileID,
+		UserID:       job.UserID,
+	}
+	require.NoError(tb, db.Create(restoreJob).Error)
+	return restoreJob
+}
+
 func TestNewBackupWorker(t *testing.T) {
-	mockDB := &gorm.DB{}
+	db := setupTestDB(t)
 	mockBackupService := &MockBackupService{}
-	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
 	mockQueueService := &MockQueueService{}
 
-	worker := NewBackupWorker(mockDB, mockBackupService, mockS3Service, mockQueueService, nil)
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, mockQueueService, nil)
 
 	assert.NotNil(t, worker)
-	assert.Equal(t, mockDB, worker.db)
+	assert.Equal(t, db, worker.db)
 	assert.Equal(t, mockBackupService, worker.backupService)
-	assert.Equal(t, mockS3Service, worker.s3Service)
+	assert.Equal(t, mockStorage, worker.storage)
 	assert.Equal(t, mockQueueService, worker.queueService)
 }
 
 func TestBackupWorker_RegisterHandlers(t *testing.T) {
-	worker := NewBackupWorker(&gorm.DB{}, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, nil)
+	worker := NewBackupWorker(setupTestDB(t), &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
 	
 	// Create a properly initialized queue worker
 	queueWorker := services.NewQueueWorker(nil)
@@ -329,31 +476,27 @@ func TestBackupWorker_RegisterHandlers(t *testing.T) {
 }
 
 func TestBackupWorker_HandleBackupPostgreSQL_Success(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "user-bucket")
+
 	// Setup mocks
 	mockBackupService := &MockBackupService{}
 	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	// Create a worker with simplified DB mock
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
 
 	// Create test payload
 	payload := BackupTaskPayload{
-		BackupJobID: 1,
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
 		Options: &services.BackupOptions{
 			Format: "custom",
 		},
-		StorageConfig: &models.StorageConfiguration{
-			Bucket: "test-bucket",
-			Region: "us-east-1",
-		},
+		StorageUID: storage.UID,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -363,32 +506,38 @@ func TestBackupWorker_HandleBackupPostgreSQL_Success(t *testing.T) {
 
 	// Set up expectations
 	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
-		FilePath:     "/tmp/test.backup",
+		FilePath:     writeTestDump(t),
 		OriginalSize: 1024,
 		Duration:     5 * time.Minute,
 		Checksum:     "sha256:test",
 	}, nil)
 
-	mockS3Service.On("UploadFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
-		Bucket: "test-bucket",
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+
+	mockS3Service.On("UploadFile", mock.Anything, "user-bucket", mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
+		Bucket: "user-bucket",
 		Key:    "backups/test.backup",
 	}, nil)
 
-	// Note: In real tests, we'd need to properly mock the database operations
-	// This is a simplified test focusing on the main logic flow
-	
-	ctx := context.Background()
-	
-	// This test is more about ensuring the method doesn't panic and follows the right flow
-	// In a real implementation, we'd need a more sophisticated DB mocking strategy
-	err = worker.HandleBackupPostgreSQL(ctx, task)
-	
-	// The test will fail due to DB operations, but we can verify the mocks were called correctly
-	mockBackupService.AssertCalled(t, "CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything)
+	err = worker.HandleBackupPostgreSQL(context.Background(), task)
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+	mockS3Service.AssertExpectations(t)
+
+	var completed models.BackupJob
+	require.NoError(t, db.First(&completed, job.ID).Error)
+	assert.Equal(t, models.BackupStatusCompleted, completed.Status)
+
+	var file models.BackupFile
+	require.NoError(t, db.Where("backup_job_id = ?", job.ID).First(&file).Error)
+	assert.Equal(t, "user-bucket", file.S3Bucket)
+	require.NotNil(t, file.StorageConfigurationID)
+	assert.Equal(t, storage.ID, *file.StorageConfigurationID)
 }
 
 func TestBackupWorker_HandleBackupPostgreSQL_InvalidPayload(t *testing.T) {
-	worker := NewBackupWorker(&gorm.DB{}, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, nil)
+	worker := NewBackupWorker(setupTestDB(t), &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
 	
 	// Create task with invalid payload
 	task := asynq.NewTask(TypeBackupPostgreSQL, []byte("invalid json"))
@@ -401,23 +550,23 @@ func TestBackupWorker_HandleBackupPostgreSQL_InvalidPayload(t *testing.T) {
 }
 
 func TestBackupWorker_HandleBackupMySQL_Success(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypeMySQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "default-bucket")
+
 	// Setup mocks
 	mockBackupService := &MockBackupService{}
 	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
 
-	// Create test payload
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
+
+	// Without a storage UID the worker falls back to the default configuration
 	payload := BackupTaskPayload{
-		BackupJobID: 1,
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
 		Options: &services.BackupOptions{
 			SingleTransaction: true,
 		},
@@ -430,37 +579,44 @@ func TestBackupWorker_HandleBackupMySQL_Success(t *testing.T) {
 
 	// Set up expectations
 	mockBackupService.On("CreateMySQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
-		FilePath:     "/tmp/test.sql",
+		FilePath:     writeTestDump(t),
 		OriginalSize: 2048,
 		Duration:     3 * time.Minute,
 		Checksum:     "sha256:mysql-test",
 	}, nil)
 
-	ctx := context.Background()
-	
-	// This will fail due to DB operations, but we test the backup service call
-	err = worker.HandleBackupMySQL(ctx, task)
-	
+	mockStorage.On("ResolveStorage", mock.Anything, "", job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+
+	mockS3Service.On("UploadFile", mock.Anything, "default-bucket", mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
+		Bucket: "default-bucket",
+		Key:    "backups/test.sql",
+	}, nil)
+
+	err = worker.HandleBackupMySQL(context.Background(), task)
+	require.NoError(t, err)
+
 	mockBackupService.AssertCalled(t, "CreateMySQLBackup", mock.Anything, mock.Anything, mock.Anything)
+	mockStorage.AssertExpectations(t)
 }
 
 func TestBackupWorker_HandleRestorePostgreSQL_Success(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "restore-bucket")
+	file := createTestBackupFile(t, db, job, storage)
+
 	mockBackupService := &MockBackupService{}
 	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
 
 	payload := RestoreTaskPayload{
-		BackupJobID:   1,
-		UserID:        1,
-		DatabaseUID:   "test-db-uid",
-		BackupFileUID: "test-file-uid",
+		RestoreJobID:  createTestRestoreJob(t, db, job, file.ID).ID,
+		BackupJobID:   job.ID,
+		UserID:        job.UserID,
+		DatabaseUID:   job.DatabaseConnection.UID,
+		BackupFileUID: file.UID,
 		Options: &services.RestoreOptions{
 			CleanFirst: true,
 		},
@@ -471,20 +627,67 @@ func TestBackupWorker_HandleRestorePostgreSQL_Success(t *testing.T) {
 
 	task := asynq.NewTask(TypeRestorePostgreSQL, payloadBytes)
 
-	// Set up expectations
+	// The file is downloaded from the storage it was uploaded to
+	mockStorage.On("ResolveStorageByID", mock.Anything, storage.ID).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
 	mockReader := io.NopCloser(strings.NewReader("backup data"))
-	mockS3Service.On("DownloadFile", mock.Anything, mock.Anything, mock.Anything).Return(mockReader, nil)
+	mockS3Service.On("DownloadFile", mock.Anything, "restore-bucket", file.S3Key).Return(mockReader, nil)
 	mockBackupService.On("RestorePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	ctx := context.Background()
-	err = worker.HandleRestorePostgreSQL(ctx, task)
-	
-	// Will fail due to DB operations, but verify service calls
-	mockS3Service.AssertCalled(t, "DownloadFile", mock.Anything, mock.Anything, mock.Anything)
+	err = worker.HandleRestorePostgreSQL(context.Background(), task)
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+	mockS3Service.AssertExpectations(t)
+	mockBackupService.AssertExpectations(t)
+}
+
+func TestBackupWorker_HandleRestorePostgreSQL_RestoresDump(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "restore-bucket")
+	file := createTestBackupFile(t, db, job, storage)
+
+	// The stored object is the compressed dump, encrypted under the file's data key
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte("CREATE TABLE users (id int);"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	var sealed bytes.Buffer
+	key, err := testBackupCipher().Seal(&sealed, &compressed)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(file).Updates(map[string]interface{}{"is_compressed": true, "is_encrypted": true, "encryption_key": key}).Error)
+
+	mockBackupService := &MockBackupService{}
+	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
+
+	mockStorage.On("ResolveStorageByID", mock.Anything, storage.ID).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("DownloadFile", mock.Anything, "restore-bucket", file.S3Key).Return(io.NopCloser(bytes.NewReader(sealed.Bytes())), nil)
+
+	// The restore tool gets the plain dump
+	var restoredFrom string
+	mockBackupService.On("RestorePostgreSQLBackup", mock.Anything, mock.Anything, mock.MatchedBy(func(path string) bool {
+		restoredFrom = path
+		data, err := os.ReadFile(path)
+		return err == nil && string(data) == "CREATE TABLE users (id int);"
+	}), mock.Anything).Return(nil)
+
+	restoreJob := createTestRestoreJob(t, db, job, file.ID)
+	payloadBytes, err := json.Marshal(RestoreTaskPayload{RestoreJobID: restoreJob.ID, BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID, BackupFileUID: file.UID})
+	require.NoError(t, err)
+	require.NoError(t, worker.HandleRestorePostgreSQL(context.Background(), asynq.NewTask(TypeRestorePostgreSQL, payloadBytes)))
+	mockBackupService.AssertExpectations(t)
+
+	// and the temporary file is removed afterwards
+	_, err = os.Stat(restoredFrom)
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestBackupWorker_HandleRestoreMySQL_InvalidPayload(t *testing.T) {
-	worker := NewBackupWorker(&gorm.DB{}, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, nil)
+	worker := NewBackupWorker(setupTestDB(t), &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
 	
 	task := asynq.NewTask(TypeRestoreMySQL, []byte("invalid json"))
 	
@@ -496,33 +699,41 @@ func TestBackupWorker_HandleRestoreMySQL_InvalidPayload(t *testing.T) {
 }
 
 func TestBackupWorker_HandleCleanupBackups(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "cleanup-bucket")
+	file := createTestBackupFile(t, db, job, storage)
+	require.NoError(t, db.Model(file).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+
 	mockS3Service := &MockS3Service{}
-	
-	worker := &BackupWorker{
-		db:        &gorm.DB{},
-		s3Service: mockS3Service,
-	}
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, storage.ID).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("DeleteFile", mock.Anything, "cleanup-bucket", file.S3Key).Return(nil)
+
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
 
 	task := asynq.NewTask(TypeCleanupBackups, []byte("{}"))
-	
-	ctx := context.Background()
-	err := worker.HandleCleanupBackups(ctx, task)
-	
-	// Will fail due to DB operations, but method should execute
-	assert.Error(t, err) // Expected due to DB mock limitations
+	err := worker.HandleCleanupBackups(context.Background(), task)
+	require.NoError(t, err)
+
+	mockS3Service.AssertExpectations(t)
+
+	var count int64
+	db.Model(&models.BackupFile{}).Count(&count)
+	assert.Equal(t, int64(0), count)
 }
 
 func TestBackupWorker_HandleScheduledBackup_Success(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
 	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:           &gorm.DB{},
-		queueService: mockQueueService,
-	}
+
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
 
 	payload := BackupTaskPayload{
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		StorageUID:  "storage-uid",
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -530,22 +741,122 @@ func TestBackupWorker_HandleScheduledBackup_Success(t *testing.T) {
 
 	task := asynq.NewTask(TypeScheduledBackup, payloadBytes)
 
-	// Set up expectations
-	mockQueueService.On("EnqueueJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&services.JobInfo{
+	// The storage UID is carried through to the backup job
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, mock.MatchedBy(func(p BackupTaskPayload) bool {
+		return p.StorageUID == "storage-uid" && p.BackupJobID != 0
+	}), mock.Anything).Return(&services.JobInfo{
 		ID:   "test-job-id",
 		Type: TypeBackupPostgreSQL,
 	}, nil)
 
-	ctx := context.Background()
-	err = worker.HandleScheduledBackup(ctx, task)
-	
-	// Will fail due to DB operations, but verify queue service call would be made
-	// In practice, this would work with proper DB mocking
-	assert.Error(t, err) // Expected due to DB mock limitations
+	err = worker.HandleScheduledBackup(context.Background(), task)
+	require.NoError(t, err)
+
+	mockQueueService.AssertExpectations(t)
+}
+
+func TestBackupWorker_HandleScheduledBackup_ConnectionReplicas(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	job.DatabaseConnection.ReplicaStorageUIDs = []string{"replica-uid"}
+	require.NoError(t, db.Model(&job.DatabaseConnection).Select("replica_storage_uids").Updates(&job.DatabaseConnection).Error)
+	mockQueueService := &MockQueueService{}
+
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+
+	// Schedules without replicas of their own copy to the connection's replica storage
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, mock.MatchedBy(func(p BackupTaskPayload) bool {
+		return len(p.ReplicaStorageUIDs) == 1 && p.ReplicaStorageUIDs[0] == "replica-uid"
+	}), mock.Anything).Return(&services.JobInfo{ID: "test-job-id", Type: TypeBackupPostgreSQL}, nil)
+
+	payloadBytes, err := json.Marshal(BackupTaskPayload{UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID})
+	require.NoError(t, err)
+	require.NoError(t, worker.HandleScheduledBackup(context.Background(), asynq.NewTask(TypeScheduledBackup, payloadBytes)))
+
+	mockQueueService.AssertExpectations(t)
+}
+
+func TestBackupWorker_HandleScheduledBackup_PausedWhileUnhealthy(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "backups")
+	mockQueueService := &MockQueueService{}
+
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+
+	schedule := func(storageUID string) error {
+		payloadBytes, err := json.Marshal(BackupTaskPayload{
+			UserID:      job.UserID,
+			DatabaseUID: job.DatabaseConnection.UID,
+			StorageUID:  storageUID,
+		})
+		require.NoError(t, err)
+		return worker.HandleScheduledBackup(context.Background(), asynq.NewTask(TypeScheduledBackup, payloadBytes))
+	}
+
+	// A paused connection skips its schedule
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("id = ?", job.DatabaseConnectionID).Update("schedules_paused_at", time.Now()).Error)
+	require.NoError(t, schedule(""))
+
+	// So does a backup to unhealthy storage
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("id = ?", job.DatabaseConnectionID).Update("schedules_paused_at", nil).Error)
+	require.NoError(t, db.Model(storage).Update("health_status", models.HealthStatusUnhealthy).Error)
+	require.NoError(t, schedule(storage.UID))
+
+	var scheduled int64
+	require.NoError(t, db.Model(&models.BackupJob{}).Where("is_scheduled = ?", true).Count(&scheduled).Error)
+	assert.Zero(t, scheduled)
+	mockQueueService.AssertNotCalled(t, "EnqueueJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBackupWorker_ScheduledBackupPausedThroughQueue(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	require.NoError(t, db.Model(job).Update("is_scheduled", true).Error)
+	mockQueueService := &MockQueueService{}
+
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+
+	// Capture the task the API schedules
+	var enqueued *asynq.Task
+	var queue string
+	mockQueueService.On("EnqueueScheduledJob", mock.Anything, TypeScheduledBackup, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			payloadBytes, err := json.Marshal(args.Get(2))
+			require.NoError(t, err)
+			enqueued = asynq.NewTask(args.String(1), payloadBytes)
+			queue = optionQueue(args.Get(4).([]services.JobOption))
+		}).
+		Return(&services.JobInfo{ID: "scheduled-task", Type: TypeScheduledBackup}, nil)
+
+	_, err := worker.EnqueueScheduledBackupJob(context.Background(), &BackupTaskPayload{
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+	}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, enqueued)
+
+	// The queue is served, so the worker takes the task when it is due
+	assert.Positive(t, Queues()[queue], "queue %q is not served", queue)
+
+	// The database went down in the meantime
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("id = ?", job.DatabaseConnectionID).Update("schedules_paused_at", time.Now()).Error)
+
+	queueWorker := services.NewQueueWorker(&services.QueueConfig{RedisAddr: "localhost:0", Concurrency: 1, Queues: Queues()})
+	worker.RegisterHandlers(queueWorker)
+	require.NoError(t, queueWorker.ProcessTask(context.Background(), enqueued))
+
+	var skipped models.BackupJob
+	require.NoError(t, db.First(&skipped, job.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, skipped.Status)
+	require.NotNil(t, skipped.ErrorCode)
+	assert.Equal(t, "SCHEDULE_PAUSED", *skipped.ErrorCode)
+	mockQueueService.AssertNotCalled(t, "EnqueueJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestBackupWorker_HandleScheduledBackup_InvalidPayload(t *testing.T) {
-	worker := NewBackupWorker(&gorm.DB{}, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, nil)
+	worker := NewBackupWorker(setupTestDB(t), &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
 	
 	task := asynq.NewTask(TypeScheduledBackup, []byte("invalid json"))
 	
@@ -618,14 +929,17 @@ func TestBackupWorker_EnqueueScheduledBackupJob(t *testing.T) {
 }
 
 func TestBackupWorker_GetBackupJobStatus(t *testing.T) {
-	// This test would require proper DB mocking to work correctly
-	worker := NewBackupWorker(&gorm.DB{}, &MockBackupService{}, &MockS3Service{}, &MockQueueService{}, nil)
-	
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
+
 	ctx := context.Background()
-	_, err := worker.GetBackupJobStatus(ctx, 1)
-	
-	// Will fail due to DB operations, but method should execute
-	assert.Error(t, err) // Expected due to DB mock limitations
+	status, err := worker.GetBackupJobStatus(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, job.UID, status.UID)
+
+	_, err = worker.GetBackupJobStatus(ctx, job.ID+100)
+	assert.Error(t, err)
 }
 
 func TestJobType_Constants(t *testing.T) {
@@ -646,10 +960,7 @@ func TestBackupTaskPayload_Structure(t *testing.T) {
 			Format:     "custom",
 			SchemaOnly: true,
 		},
-		StorageConfig: &models.StorageConfiguration{
-			Bucket: "test-bucket",
-			Region: "us-east-1",
-		},
+		StorageUID: "test-storage-uid",
 	}
 
 	assert.Equal(t, uint(1), payload.BackupJobID)
@@ -658,8 +969,13 @@ func TestBackupTaskPayload_Structure(t *testing.T) {
 	assert.NotNil(t, payload.Options)
 	assert.Equal(t, "custom", payload.Options.Format)
 	assert.True(t, payload.Options.SchemaOnly)
-	assert.NotNil(t, payload.StorageConfig)
-	assert.Equal(t, "test-bucket", payload.StorageConfig.Bucket)
+	assert.Equal(t, "test-storage-uid", payload.StorageUID)
+
+	// Only the storage reference is serialized into the task
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"storage_uid":"test-storage-uid"`)
+	assert.NotContains(t, string(data), "storage_config")
 }
 
 func TestRestoreTaskPayload_Structure(t *testing.T) {
@@ -684,21 +1000,17 @@ func TestRestoreTaskPayload_Structure(t *testing.T) {
 }
 
 func TestBackupWorker_BackupServiceFailure(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
 	mockBackupService := &MockBackupService{}
-	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
 
 	payload := BackupTaskPayload{
-		BackupJobID: 1,
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -712,30 +1024,31 @@ func TestBackupWorker_BackupServiceFailure(t *testing.T) {
 	ctx := context.Background()
 	err = worker.HandleBackupPostgreSQL(ctx, task)
 	
-	// Should fail due to backup service error (after DB error)
 	assert.Error(t, err)
 	mockBackupService.AssertCalled(t, "CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything)
+	mockStorage.AssertNotCalled(t, "ResolveStorage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	var failed models.BackupJob
+	require.NoError(t, db.First(&failed, job.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, failed.Status)
 }
 
 func TestBackupWorker_S3UploadFailure(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "test-bucket")
+
 	mockBackupService := &MockBackupService{}
 	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
 
 	payload := BackupTaskPayload{
-		BackupJobID: 1,
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
-		StorageConfig: &models.StorageConfiguration{
-			Bucket: "test-bucket",
-		},
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		StorageUID:  storage.UID,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -749,18 +1062,274 @@ func TestBackupWorker_S3UploadFailure(t *testing.T) {
 		OriginalSize: 1024,
 	}, nil)
 
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
 	mockS3Service.On("UploadFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("s3 upload failed"))
 
 	ctx := context.Background()
 	err = worker.HandleBackupPostgreSQL(ctx, task)
 	
-	// Will get DB error first, but we can verify the calls would be made
 	assert.Error(t, err)
-	mockBackupService.AssertCalled(t, "CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything)
-	_ = worker // use the variable
+	assert.Contains(t, err.Error(), "upload failed")
+
+	var failed models.BackupJob
+	require.NoError(t, db.First(&failed, job.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, failed.Status)
+}
+
+func TestBackupWorker_StorageResolutionFailure(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	mockBackupService := &MockBackupService{}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+
+	payload := BackupTaskPayload{
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
+		FilePath:     "/tmp/test.backup",
+		OriginalSize: 1024,
+	}, nil)
+	mockStorage.On("ResolveStorage", mock.Anything, "", job.UserID, (*uint)(nil)).Return(nil, nil, services.ErrNoDefaultStorage)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes))
+	assert.ErrorIs(t, err, services.ErrNoDefaultStorage)
 }
 
 // Integration-style test helpers
+func TestBackupWorker_TablePermissionDenied(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	job.Status = models.BackupStatusCompleted
+	job.Tables = []string{"users"}
+	require.NoError(t, db.Save(job).Error)
+	require.NoError(t, db.Create(&models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: job.DatabaseConnectionID, LastDiscoveredAt: time.Now()}).Error)
+
+	// A team guest may read the shared connection but not back it up or restore it
+	teamID := uint(7)
+	require.NoError(t, db.Model(&job.DatabaseConnection).Update("team_id", teamID).Error)
+	guest := &models.User{Email: "guest@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(guest).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: guest.ID, Role: models.TeamRoleGuest, IsActive: true}).Error)
+
+	mockBackupService := &MockBackupService{}
+	mockStorage := &MockStorageResolver{}
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+
+	restoreJob := createTestRestoreJob(t, db, job, 0)
+	restorePayload, err := json.Marshal(RestoreTaskPayload{
+		RestoreJobID:  restoreJob.ID,
+		BackupJobID:   job.ID,
+		UserID:        guest.ID,
+		DatabaseUID:   job.DatabaseConnection.UID,
+		BackupFileUID: "missing",
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleRestorePostgreSQL(context.Background(), asynq.NewTask(TypeRestorePostgreSQL, restorePayload))
+	assert.ErrorIs(t, err, services.ErrTableAccessDenied)
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+	mockBackupService.AssertNotCalled(t, "RestorePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// The source backup is left untouched, and the restore is failed
+	var source models.BackupJob
+	require.NoError(t, db.First(&source, job.ID).Error)
+	assert.Equal(t, models.BackupStatusCompleted, source.Status)
+	require.NoError(t, db.First(restoreJob, restoreJob.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, restoreJob.Status)
+	require.NotNil(t, restoreJob.ErrorCode)
+	assert.Equal(t, "PERMISSION_DENIED", *restoreJob.ErrorCode)
+
+	pending := &models.BackupJob{
+		Name:                 "Guest Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		UserID:               guest.ID,
+		DatabaseConnectionID: job.DatabaseConnectionID,
+	}
+	require.NoError(t, db.Create(pending).Error)
+
+	backupPayload, err := json.Marshal(BackupTaskPayload{
+		BackupJobID: pending.ID,
+		UserID:      guest.ID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		Options:     &services.BackupOptions{Tables: []string{"users"}},
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, backupPayload))
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+	mockBackupService.AssertNotCalled(t, "CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything)
+
+	var denied models.BackupJob
+	require.NoError(t, db.First(&denied, pending.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, denied.Status)
+	require.NotNil(t, denied.ErrorCode)
+	assert.Equal(t, "PERMISSION_DENIED", *denied.ErrorCode)
+}
+
+func TestBackupWorker_TableRestrictionsSanitizeBackup(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	teamID := uint(8)
+	require.NoError(t, db.Model(&job.DatabaseConnection).Update("team_id", teamID).Error)
+	analyst := &models.User{Email: "analyst@example.com", Password: "hashed_password", IsActive: true}
+	require.NoError(t, db.Create(analyst).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: teamID, UserID: analyst.ID, Role: models.TeamRoleMember, CanManageBackups: true, IsActive: true}).Error)
+
+	users := &models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: job.DatabaseConnectionID, LastDiscoveredAt: time.Now()}
+	require.NoError(t, db.Create(users).Error)
+	filter := models.RowFilter{{Column: "region", Operator: models.RowOperatorEqual, Value: "eu"}}
+	require.NoError(t, db.Create(&models.TablePermission{
+		UserID:          analyst.ID,
+		DatabaseTableID: users.ID,
+		AccessLevel:     models.TableAccessRead,
+		CanRead:         true,
+		CanBackup:       true,
+		RowLevelFilter:  filter,
+		ColumnMask:      []string{"email", "phone:null"},
+		GrantedAt:       time.Now(),
+	}).Error)
+
+	pending := &models.BackupJob{
+		Name:                 "Analyst Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		UserID:               analyst.ID,
+		DatabaseConnectionID: job.DatabaseConnectionID,
+	}
+	require.NoError(t, db.Create(pending).Error)
+
+	mockBackupService := &MockBackupService{}
+	var options *services.BackupOptions
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { options = args.Get(2).(*services.BackupOptions) }).
+		Return(nil, errors.New("source unavailable"))
+	worker := NewBackupWorker(db, mockBackupService, &MockStorageResolver{}, &MockQueueService{}, nil)
+
+	// The requested mask on email is overridden by the mandatory one
+	payload, err := json.Marshal(BackupTaskPayload{
+		BackupJobID: pending.ID,
+		UserID:      analyst.ID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		Options: &services.BackupOptions{Sanitize: &services.SanitizeOptions{Rules: []services.TableSanitizeRule{
+			{Table: "users", Masks: map[string]services.MaskRule{"email": {Strategy: services.MaskFixed, Value: "x"}}},
+		}}},
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload))
+	assert.ErrorContains(t, err, "source unavailable")
+
+	require.NotNil(t, options)
+	require.NotNil(t, options.Sanitize)
+	require.Len(t, options.Sanitize.Rules, 2)
+	mandatory := options.Sanitize.Rules[0]
+	assert.Equal(t, "public.users", mandatory.Table)
+	assert.Equal(t, filter, mandatory.Filter)
+	assert.Equal(t, map[string]services.MaskRule{"email": {}, "phone": {Strategy: services.MaskNull}}, mandatory.Masks)
+
+	// Backups that leave the restricted table out stay raw dumps
+	options = nil
+	payload, err = json.Marshal(BackupTaskPayload{
+		BackupJobID: pending.ID,
+		UserID:      analyst.ID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		Options:     &services.BackupOptions{ExcludeTables: []string{"users"}},
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload))
+	assert.Error(t, err)
+	require.NotNil(t, options)
+	assert.Nil(t, options.Sanitize)
+}
+
+func TestBackupWorker_FlagsUnmaskedPII(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "user-bucket")
+
+	users := &models.DatabaseTable{Name: "users", Schema: "public", DatabaseConnectionID: job.DatabaseConnectionID, LastDiscoveredAt: time.Now(),
+		Columns: []models.DatabaseColumn{{Name: "email", DataType: "varchar", OrdinalPosition: 1}}}
+	users.Columns[0].SetPIIClassification(models.PIITypeEmail, 0.95, services.PIISourceNameAndSample)
+	require.NoError(t, db.Create(users).Error)
+
+	mockBackupService := &MockBackupService{}
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
+		FilePath:     writeTestDump(t),
+		OriginalSize: 1024,
+		Metadata:     map[string]string{"format": "custom"},
+	}, nil)
+	mockS3Service := &MockS3Service{}
+	mockS3Service.On("UploadFile", mock.Anything, "user-bucket", mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
+		Bucket: "user-bucket",
+		Key:    "backups/test.backup",
+	}, nil)
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
+
+	payload, err := json.Marshal(BackupTaskPayload{
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		Options:     &services.BackupOptions{},
+		StorageUID:  storage.UID,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload)))
+
+	var file models.BackupFile
+	require.NoError(t, db.Where("backup_job_id = ?", job.ID).First(&file).Error)
+	assert.Equal(t, true, file.Metadata["contains_pii"])
+	assert.Equal(t, []interface{}{"users.email"}, file.Metadata["pii_columns"])
+	assert.Equal(t, "custom", file.Metadata["format"])
+
+	// Connections requiring encryption refuse storage without it
+	require.NoError(t, db.Model(&job.DatabaseConnection).Update("require_pii_encryption", true).Error)
+	storage.ClientSideEncryption = false
+	pending := &models.BackupJob{
+		Name:                 "PII Backup",
+		Type:                 models.BackupTypeFull,
+		Status:               models.BackupStatusPending,
+		UserID:               job.UserID,
+		DatabaseConnectionID: job.DatabaseConnectionID,
+	}
+	require.NoError(t, db.Create(pending).Error)
+
+	payload, err = json.Marshal(BackupTaskPayload{
+		BackupJobID: pending.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		Options:     &services.BackupOptions{},
+		StorageUID:  storage.UID,
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload))
+	assert.ErrorIs(t, err, errPIIEncryptionRequired)
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+	mockS3Service.AssertNumberOfCalls(t, "UploadFile", 1)
+
+	var failed models.BackupJob
+	require.NoError(t, db.First(&failed, pending.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, failed.Status)
+	require.NotNil(t, failed.ErrorCode)
+	assert.Equal(t, "PII_ENCRYPTION_REQUIRED", *failed.ErrorCode)
+}
+
 func TestBackupWorker_Integration_JobFlow(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -805,30 +1374,35 @@ func TestBackupWorker_ProgressCallback_Functionality(t *testing.T) {
 
 // Benchmark tests
 func BenchmarkBackupWorker_HandleBackupPostgreSQL(b *testing.B) {
+	db := setupTestDB(b)
+	job := createTestBackupJob(b, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(b, db, job.UserID, "bench-bucket")
+
 	mockBackupService := &MockBackupService{}
 	mockS3Service := &MockS3Service{}
-	mockQueueService := &MockQueueService{}
-	
-	worker := &BackupWorker{
-		db:            &gorm.DB{},
-		backupService: mockBackupService,
-		s3Service:     mockS3Service,
-		queueService:  mockQueueService,
-	}
+	mockStorage := &MockStorageResolver{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
 
 	payload := BackupTaskPayload{
-		BackupJobID: 1,
-		UserID:      1,
-		DatabaseUID: "test-db-uid",
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
 	}
 
 	payloadBytes, _ := json.Marshal(payload)
 	task := asynq.NewTask(TypeBackupPostgreSQL, payloadBytes)
 
 	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
-		FilePath:     "/tmp/test.backup",
+		FilePath:     writeTestDump(b),
 		OriginalSize: 1024,
 	}, nil)
+	mockStorage.On("ResolveStorage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("UploadFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
+		Bucket: "bench-bucket",
+		Key:    "backups/test.backup",
+	}, nil)
 
 	ctx := context.Background()
 
@@ -836,4 +1410,4 @@ func BenchmarkBackupWorker_HandleBackupPostgreSQL(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		worker.HandleBackupPostgreSQL(ctx, task)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+)
+
+// SetConcurrency limits how many backups run at once. Without it backups
+// start as soon as a worker picks them up.
+func (bw *BackupWorker) SetConcurrency(concurrency *services.BackupConcurrency) {
+	bw.concurrency = concurrency
+}
+
+// acquireBackupSlots takes the backup's concurrency places. When a limit is
+// reached the backup is queued again for later rather than failed, and the
+// reason it waits is shown as its current step.
+func (bw *BackupWorker) acquireBackupSlots(ctx context.Context, task *asynq.Task, payload *BackupTaskPayload, backupJob *models.BackupJob) (*services.BackupLease, bool, error) {
+	if bw.concurrency == nil {
+		return nil, false, nil
+	}
+
+	lease, err := bw.concurrency.Acquire(ctx, backupJob)
+	if err == nil {
+		return lease, false, nil
+	}
+
+	var limitErr *services.ConcurrencyLimitError
+	if !errors.As(err, &limitErr) {
+		return nil, false, fmt.Errorf("failed to acquire backup concurrency: %w", err)
+	}
+
+	backupJob.CurrentStep = limitErr.WaitingReason()
+	deferred, deferErr := bw.deferTask(ctx, task, payload, bw.concurrency.RetryDelay())
+	if deferErr == nil {
+		// Keep track of the task so the job can still change lanes
+		backupJob.QueueName = deferred.Queue
+		backupJob.QueueTaskID = deferred.ID
+	}
+	if err := bw.db.Save(backupJob).Error; err != nil {
+		slog.WarnContext(ctx, "Failed to update waiting backup job", "backup_job_id", backupJob.ID, "error", err)
+	}
+	bw.sendBackupProgressUpdate(backupJob)
+
+	if deferErr != nil {
+		// Let the queue retry the task instead
+		return nil, false, fmt.Errorf("%w (and deferring failed: %v)", limitErr, deferErr)
+	}
+
+	slog.InfoContext(ctx, "Backup job deferred", "backup_job_id", backupJob.ID, "reason", limitErr.WaitingReason())
+	return nil, true, nil
+}
+
+// deferTask queues a task again to run after a delay, in the same queue and
+// without using up its retries
+func (bw *BackupWorker) deferTask(ctx context.Context, task *asynq.Task, payload interface{}, delay time.Duration) (*services.JobInfo, error) {
+	if bw.queueService == nil {
+		return nil, errors.New("queue service not available")
+	}
+
+	var options []services.JobOption
+	if queue, ok := asynq.GetQueueName(ctx); ok {
+		options = append(options, services.WithQueue(queue))
+	}
+	if maxRetry, ok := asynq.GetMaxRetry(ctx); ok {
+		options = append(options, services.WithMaxRetry(maxRetry))
+	}
+
+	return bw.queueService.EnqueueScheduledJob(ctx, task.Type(), payload, time.Now().Add(delay), options...)
+}
+
+// releaseBackupSlots gives the backup's concurrency places back
+func (bw *BackupWorker) releaseBackupSlots(lease *services.BackupLease, backupJob *models.BackupJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := lease.Release(ctx); err != nil {
+		slog.Error("Failed to release concurrency places of backup job", "backup_job_id", backupJob.ID, "error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupWorker_DefersBackupAtConcurrencyLimit(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	mockBackupService := &MockBackupService{}
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, mockBackupService, &MockStorageResolver{}, mockQueueService, nil)
+
+	semaphore := services.NewLocalSemaphore()
+	worker.SetConcurrency(services.NewBackupConcurrency(db, semaphore, services.BackupConcurrencyLimits{
+		PerConnection: 1,
+		RetryDelay:    time.Minute,
+	}))
+
+	// Another backup of the same database is running
+	running := &models.BackupJob{ID: job.ID + 100, UserID: job.UserID, DatabaseConnection: job.DatabaseConnection}
+	concurrency := services.NewBackupConcurrency(db, semaphore, services.BackupConcurrencyLimits{PerConnection: 1})
+	lease, err := concurrency.Acquire(context.Background(), running)
+	require.NoError(t, err)
+	defer lease.Release(context.Background())
+
+	payload := BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID}
+	payloadBytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	before := time.Now()
+	mockQueueService.On("EnqueueScheduledJob", mock.Anything, TypeBackupPostgreSQL, mock.Anything, mock.MatchedBy(func(processAt time.Time) bool {
+		return processAt.After(before.Add(59 * time.Second))
+	}), mock.Anything).Return(&services.JobInfo{ID: "deferred"}, nil)
+
+	require.NoError(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes)))
+	mockQueueService.AssertExpectations(t)
+	mockBackupService.AssertNotCalled(t, "CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything)
+
+	// The job waits rather than failing and says why
+	var waiting models.BackupJob
+	require.NoError(t, db.First(&waiting, job.ID).Error)
+	assert.Equal(t, models.BackupStatusPending, waiting.Status)
+	assert.Equal(t, "Waiting: 1 backup already running on database Test Database", waiting.CurrentStep)
+	assert.Equal(t, "deferred", waiting.QueueTaskID)
+}
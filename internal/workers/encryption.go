@@ -0,0 +1,48 @@
+package workers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+)
+
+// SetBackupCipher enables client-side encryption of uploads. Without it backups
+// to storage that requires client-side encryption fail.
+func (bw *BackupWorker) SetBackupCipher(cipher *services.BackupCipher) {
+	bw.cipher = cipher
+}
+
+// sealBackup encrypts a backup file for storage with client-side encryption and
+// returns the path to upload with the file's wrapped data key. Files for other
+// storage are uploaded as written.
+func (bw *BackupWorker) sealBackup(filePath string, storageConfig *models.StorageConfiguration) (string, *string, error) {
+	if !storageConfig.ClientSideEncryption {
+		return filePath, nil, nil
+	}
+	if bw.cipher == nil {
+		return "", nil, fmt.Errorf("storage %s requires client-side encryption but no backup cipher is configured", storageConfig.Name)
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.enc")
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := bw.cipher.Seal(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", nil, err
+	}
+	return dst.Name(), &key, nil
+}
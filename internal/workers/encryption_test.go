@@ -0,0 +1,83 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupWorker_EncryptsUploads(t *testing.T) {
+	for _, encrypt := range []bool{true, false} {
+		db := setupTestDB(t)
+		job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+		storage := createTestStorageConfiguration(t, db, job.UserID, t.TempDir())
+		storage.ClientSideEncryption = encrypt
+		store, err := services.NewLocalObjectStore(storage.Bucket)
+		require.NoError(t, err)
+
+		mockBackupService := &MockBackupService{}
+		mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
+			FilePath:     writeTestDump(t),
+			OriginalSize: 11,
+		}, nil)
+		mockStorage := &MockStorageResolver{}
+		mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, store, nil)
+		worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+		worker.SetBackupCipher(testBackupCipher())
+
+		payload, err := json.Marshal(BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID, StorageUID: storage.UID})
+		require.NoError(t, err)
+		require.NoError(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload)))
+
+		var file models.BackupFile
+		require.NoError(t, db.Where("backup_job_id = ?", job.ID).First(&file).Error)
+		assert.Equal(t, encrypt, file.IsEncrypted)
+		assert.Equal(t, encrypt, file.EncryptionKey != nil)
+
+		reader, err := store.Get(context.Background(), file.S3Key)
+		require.NoError(t, err)
+		stored, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+		if !encrypt {
+			assert.Equal(t, "backup data", string(stored))
+			continue
+		}
+		assert.NotContains(t, string(stored), "backup data")
+
+		// The stored data key opens the uploaded bytes
+		opened, err := testBackupCipher().OpenFile(io.NopCloser(bytes.NewReader(stored)), &file)
+		require.NoError(t, err)
+		plain, err := io.ReadAll(opened)
+		require.NoError(t, err)
+		assert.Equal(t, "backup data", string(plain))
+	}
+}
+
+func TestBackupWorker_EncryptionRequiresCipher(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, t.TempDir())
+	store, err := services.NewLocalObjectStore(storage.Bucket)
+	require.NoError(t, err)
+
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, store, nil)
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
+
+	err = worker.uploadBackup(context.Background(), job, &services.BackupResult{FilePath: writeTestDump(t), OriginalSize: 11}, storage.UID, nil)
+	assert.ErrorContains(t, err, "no backup cipher is configured")
+
+	files, err := store.List(context.Background(), "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
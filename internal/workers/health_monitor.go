@@ -0,0 +1,415 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// Health monitor defaults, used for zero configuration values
+const (
+	DefaultHealthCheckInterval = 15 * time.Minute
+	DefaultHealthCheckRetry    = time.Minute
+	DefaultHealthCheckFailures = 3
+	DefaultHealthCheckBatch    = 20
+)
+
+// healthCheckTimeout bounds a single connection or storage test
+const healthCheckTimeout = time.Minute
+
+// healthCheckJitter spreads the next checks by up to this fraction of the
+// delay, so resources created together are not tested together forever
+const healthCheckJitter = 0.1
+
+// ConnectionTester tests database connections
+type ConnectionTester interface {
+	TestConnection(ctx context.Context, conn *models.DatabaseConnection) (*models.TestConnectionResult, error)
+}
+
+// StorageTester tests storage configurations
+type StorageTester interface {
+	TestStorageConfiguration(ctx context.Context, config *models.StorageConfiguration) *models.StorageTestResult
+}
+
+// HealthBroadcaster publishes health changes to WebSocket subscribers
+type HealthBroadcaster interface {
+	PublishDatabaseHealth(userID uint, health *websocket.DatabaseHealthMessage) error
+	PublishStorageHealth(userID uint, health *websocket.StorageHealthMessage) error
+}
+
+// HealthMonitorConfig configures a HealthMonitor
+type HealthMonitorConfig struct {
+	// Interval between checks of a healthy resource
+	Interval time.Duration
+	// Retry is the first delay after a failure, doubled on each further
+	// failure up to Interval
+	Retry time.Duration
+	// Failures is how many consecutive failures make a resource unhealthy
+	Failures int
+	// Batch is the most resources of each kind tested per round
+	Batch int
+	// Retention is how long check history is kept; zero keeps it forever
+	Retention time.Duration
+}
+
+// HealthMonitor periodically re-tests active database connections and
+// storage configurations. It records each check in the health history,
+// pauses the schedules of connections that keep failing and reports when a
+// resource goes down or recovers. Due resources are claimed before they are
+// tested, so several workers can run a monitor side by side.
+type HealthMonitor struct {
+	db          *gorm.DB
+	connections ConnectionTester
+	storage     StorageTester
+	ws          HealthBroadcaster
+	cfg         HealthMonitorConfig
+	notifier    Notifier
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewHealthMonitor creates a new health monitor. The broadcaster may be nil.
+func NewHealthMonitor(db *gorm.DB, connections ConnectionTester, storage StorageTester, ws HealthBroadcaster, cfg HealthMonitorConfig) *HealthMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthCheckInterval
+	}
+	if cfg.Retry <= 0 {
+		cfg.Retry = DefaultHealthCheckRetry
+	}
+	if cfg.Retry > cfg.Interval {
+		cfg.Retry = cfg.Interval
+	}
+	if cfg.Failures <= 0 {
+		cfg.Failures = DefaultHealthCheckFailures
+	}
+	if cfg.Batch <= 0 {
+		cfg.Batch = DefaultHealthCheckBatch
+	}
+	return &HealthMonitor{
+		db:          db,
+		connections: connections,
+		storage:     storage,
+		ws:          ws,
+		cfg:         cfg,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start begins monitoring in the background
+func (m *HealthMonitor) Start() {
+	go m.run()
+}
+
+// Stop ends monitoring, cancelling the running checks, and waits for the
+// round in progress to finish
+func (m *HealthMonitor) Stop(ctx context.Context) error {
+	m.once.Do(func() { close(m.stop) })
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *HealthMonitor) run() {
+	defer close(m.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Failing resources are due again after Retry, so look that often
+	ticker := time.NewTicker(m.cfg.Retry)
+	defer ticker.Stop()
+
+	for {
+		m.RunOnce(ctx)
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce tests the resources that are due and prunes old history
+func (m *HealthMonitor) RunOnce(ctx context.Context) {
+	now := time.Now()
+
+	var connections []models.DatabaseConnection
+	if err := m.due(now).Find(&connections).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to find database connections to check", "error", err)
+	}
+	var configs []models.StorageConfiguration
+	if err := m.due(now).Find(&configs).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to find storage configurations to check", "error", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range connections {
+		conn := &connections[i]
+		if !m.claim(conn, now) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.checkConnection(ctx, conn)
+		}()
+	}
+	for i := range configs {
+		config := &configs[i]
+		if !m.claim(config, now) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.checkStorage(ctx, config)
+		}()
+	}
+	wg.Wait()
+
+	m.prune(ctx, now)
+}
+
+// due selects the active resources whose next check has come, the longest
+// waiting first
+func (m *HealthMonitor) due(now time.Time) *gorm.DB {
+	return m.db.
+		Where("is_active = ?", true).
+		Where("next_health_check_at IS NULL OR next_health_check_at <= ?", now).
+		Order("next_health_check_at IS NOT NULL, next_health_check_at, id").
+		Limit(m.cfg.Batch)
+}
+
+// claim moves a due resource's next check past this round. It fails when
+// another monitor claimed the resource first.
+func (m *HealthMonitor) claim(model interface{}, now time.Time) bool {
+	lease := now.Add(m.cfg.Interval)
+	result := m.db.Model(model).
+		Where("next_health_check_at IS NULL OR next_health_check_at <= ?", now).
+		UpdateColumn("next_health_check_at", lease)
+	if result.Error != nil {
+		slog.Error("Failed to claim health check", "error", result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// checkConnection tests a database connection and records the result
+func (m *HealthMonitor) checkConnection(ctx context.Context, conn *models.DatabaseConnection) {
+	ctx = logging.With(ctx, logging.KeyUserID, conn.UserID)
+	testCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := m.connections.TestConnection(testCtx, conn)
+	if ctx.Err() != nil {
+		// Stopping; the claim makes the connection due again later
+		return
+	}
+
+	check := &models.HealthCheck{
+		TargetType: models.HealthCheckTargetDatabase,
+		TargetID:   conn.ID,
+		LatencyMs:  time.Since(start).Milliseconds(),
+	}
+	message := ""
+	switch {
+	case err != nil:
+		message = err.Error()
+	case !result.Success:
+		message = result.Message
+		if result.Error != "" {
+			message = result.Error
+		}
+	default:
+		check.Success = true
+		check.LatencyMs = result.ResponseTime.Milliseconds()
+		if result.DatabaseInfo != nil {
+			check.ServerVersion = result.DatabaseInfo.Version
+		}
+	}
+
+	before := healthEventStatus(conn.HealthStatus, conn.ConsecutiveFailures)
+	changed := conn.RecordHealthCheck(check.Success, message, m.cfg.Failures)
+	next := time.Now().Add(m.nextCheckDelay(conn.ConsecutiveFailures))
+	conn.NextHealthCheckAt = &next
+	check.CheckedAt = *conn.LastTestedAt
+	if !check.Success {
+		check.Error = &message
+	}
+
+	if err := m.db.Model(conn).
+		Select("last_tested_at", "last_test_error", "health_status", "consecutive_failures", "next_health_check_at", "schedules_paused_at").
+		UpdateColumns(conn).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to record database health", "database_uid", conn.UID, "error", err)
+		return
+	}
+	m.record(ctx, check)
+
+	if changed {
+		if conn.HealthStatus == models.HealthStatusUnhealthy {
+			slog.WarnContext(ctx, "Database connection is down, schedules paused", "database_uid", conn.UID, "failures", conn.ConsecutiveFailures, "error", message)
+		} else {
+			slog.InfoContext(ctx, "Database connection recovered, schedules resumed", "database_uid", conn.UID)
+		}
+		m.notifyConnection(ctx, conn, message)
+	}
+
+	status := healthEventStatus(conn.HealthStatus, conn.ConsecutiveFailures)
+	if m.ws == nil || status == before {
+		return
+	}
+	if err := m.ws.PublishDatabaseHealth(conn.UserID, &websocket.DatabaseHealthMessage{
+		DatabaseUID:   conn.UID,
+		Status:        status,
+		Message:       message,
+		ServerVersion: check.ServerVersion,
+		LatencyMs:     check.LatencyMs,
+		CheckedAt:     check.CheckedAt,
+	}); err != nil {
+		slog.WarnContext(ctx, "Failed to publish database health", "database_uid", conn.UID, "error", err)
+	}
+}
+
+// checkStorage tests a storage configuration and records the result
+func (m *HealthMonitor) checkStorage(ctx context.Context, config *models.StorageConfiguration) {
+	ctx = logging.With(ctx, logging.KeyUserID, config.UserID)
+	testCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result := m.storage.TestStorageConfiguration(testCtx, config)
+	if ctx.Err() != nil {
+		return
+	}
+
+	check := &models.HealthCheck{
+		TargetType: models.HealthCheckTargetStorage,
+		TargetID:   config.ID,
+		Success:    result.Success,
+		LatencyMs:  result.Duration,
+	}
+	message := ""
+	if !result.Success {
+		message = storageTestError(result)
+	}
+
+	before := healthEventStatus(config.HealthStatus, config.ConsecutiveFailures)
+	changed := config.RecordHealthCheck(check.Success, message, m.cfg.Failures)
+	next := time.Now().Add(m.nextCheckDelay(config.ConsecutiveFailures))
+	config.NextHealthCheckAt = &next
+	check.CheckedAt = *config.LastTestedAt
+	if !check.Success {
+		check.Error = &message
+	}
+
+	if err := m.db.Model(config).
+		Select("last_tested_at", "last_test_error", "health_status", "consecutive_failures", "next_health_check_at").
+		UpdateColumns(config).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to record storage health", "storage_uid", config.UID, "error", err)
+		return
+	}
+	m.record(ctx, check)
+
+	if changed {
+		if config.HealthStatus == models.HealthStatusUnhealthy {
+			slog.WarnContext(ctx, "Storage configuration is down", "storage_uid", config.UID, "failures", config.ConsecutiveFailures, "error", message)
+		} else {
+			slog.InfoContext(ctx, "Storage configuration recovered", "storage_uid", config.UID)
+		}
+	}
+
+	status := healthEventStatus(config.HealthStatus, config.ConsecutiveFailures)
+	if m.ws == nil || status == before {
+		return
+	}
+	if err := m.ws.PublishStorageHealth(config.UserID, &websocket.StorageHealthMessage{
+		StorageUID: config.UID,
+		Status:     status,
+		Message:    message,
+		LatencyMs:  check.LatencyMs,
+		CheckedAt:  check.CheckedAt,
+	}); err != nil {
+		slog.WarnContext(ctx, "Failed to publish storage health", "storage_uid", config.UID, "error", err)
+	}
+}
+
+// record adds a check to the health history
+func (m *HealthMonitor) record(ctx context.Context, check *models.HealthCheck) {
+	if err := m.db.Create(check).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to record health check", "target_type", check.TargetType, "target_id", check.TargetID, "error", err)
+	}
+}
+
+// prune deletes health history older than the retention
+func (m *HealthMonitor) prune(ctx context.Context, now time.Time) {
+	if m.cfg.Retention <= 0 {
+		return
+	}
+	if err := m.db.Where("checked_at < ?", now.Add(-m.cfg.Retention)).Delete(&models.HealthCheck{}).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to prune health history", "error", err)
+	}
+}
+
+// nextCheckDelay is the time until the next check after the given number of
+// consecutive failures: the interval while healthy, and a backoff doubling
+// from the retry delay up to the interval while failing, both with jitter
+func (m *HealthMonitor) nextCheckDelay(failures int) time.Duration {
+	delay := m.cfg.Interval
+	if failures > 0 {
+		delay = m.cfg.Retry
+		for i := 1; i < failures && delay < m.cfg.Interval; i++ {
+			delay *= 2
+		}
+		if delay > m.cfg.Interval {
+			delay = m.cfg.Interval
+		}
+	}
+	jitter := time.Duration((rand.Float64()*2 - 1) * healthCheckJitter * float64(delay))
+	return delay + jitter
+}
+
+// healthEventStatus is the status reported to clients: degraded while a
+// resource fails but is not yet unhealthy
+func healthEventStatus(status models.HealthStatus, failures int) string {
+	switch {
+	case status == models.HealthStatusUnhealthy:
+		return websocket.StorageStatusUnreachable
+	case failures > 0:
+		return websocket.StorageStatusDegraded
+	case status == models.HealthStatusHealthy:
+		return websocket.StorageStatusHealthy
+	default:
+		return ""
+	}
+}
+
+// storageTestError describes why a storage test failed, preferring the
+// error of the step that failed
+func storageTestError(result *models.StorageTestResult) string {
+	for _, step := range result.Steps {
+		if !step.Success && step.Error != "" {
+			return step.Name + ": " + step.Error
+		}
+	}
+	return result.Message
+}
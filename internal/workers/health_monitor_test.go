@@ -0,0 +1,300 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeConnectionTester struct {
+	mu    sync.Mutex
+	err   error
+	fail  bool
+	tests int
+}
+
+func (f *fakeConnectionTester) TestConnection(ctx context.Context, conn *models.DatabaseConnection) (*models.TestConnectionResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tests++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.fail {
+		return &models.TestConnectionResult{Message: "Connection failed", Error: "connection refused"}, nil
+	}
+	return &models.TestConnectionResult{
+		Success:      true,
+		ResponseTime: 12 * time.Millisecond,
+		DatabaseInfo: &models.DatabaseInfoResult{Version: "PostgreSQL 16.2"},
+	}, nil
+}
+
+func (f *fakeConnectionTester) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+type fakeStorageTester struct {
+	fail bool
+}
+
+func (f *fakeStorageTester) TestStorageConfiguration(ctx context.Context, config *models.StorageConfiguration) *models.StorageTestResult {
+	if f.fail {
+		return &models.StorageTestResult{
+			Message:  "Storage test failed",
+			Duration: 30,
+			Steps:    []models.StorageTestStep{{Name: "write", Error: "access denied"}},
+		}
+	}
+	return &models.StorageTestResult{Success: true, Message: "ok", Duration: 25}
+}
+
+type recordingHealthBroadcaster struct {
+	mu       sync.Mutex
+	database []*websocket.DatabaseHealthMessage
+	storage  []*websocket.StorageHealthMessage
+}
+
+func (b *recordingHealthBroadcaster) PublishDatabaseHealth(userID uint, health *websocket.DatabaseHealthMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.database = append(b.database, health)
+	return nil
+}
+
+func (b *recordingHealthBroadcaster) PublishStorageHealth(userID uint, health *websocket.StorageHealthMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.storage = append(b.storage, health)
+	return nil
+}
+
+func (b *recordingHealthBroadcaster) databaseStatuses() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var statuses []string
+	for _, health := range b.database {
+		statuses = append(statuses, health.Status)
+	}
+	return statuses
+}
+
+func setupHealthMonitorDB(t *testing.T) *gorm.DB {
+	db := setupTestDB(t)
+	// Every connection to an in-memory database sees its own database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+// makeDue lets every resource be checked again
+func makeDue(t *testing.T, db *gorm.DB) {
+	past := time.Now().Add(-time.Second)
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("1 = 1").Update("next_health_check_at", past).Error)
+	require.NoError(t, db.Model(&models.StorageConfiguration{}).Where("1 = 1").Update("next_health_check_at", past).Error)
+}
+
+func TestHealthMonitor_RecordsHistory(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "backups")
+
+	connections := &fakeConnectionTester{}
+	broadcaster := &recordingHealthBroadcaster{}
+	monitor := NewHealthMonitor(db, connections, &fakeStorageTester{}, broadcaster, HealthMonitorConfig{Interval: time.Hour})
+
+	monitor.RunOnce(context.Background())
+
+	var conn models.DatabaseConnection
+	require.NoError(t, db.First(&conn, job.DatabaseConnectionID).Error)
+	assert.Equal(t, models.HealthStatusHealthy, conn.HealthStatus)
+	require.NotNil(t, conn.LastTestedAt)
+	require.NotNil(t, conn.NextHealthCheckAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *conn.NextHealthCheckAt, 7*time.Minute)
+
+	var checks []models.HealthCheck
+	require.NoError(t, db.Order("target_type").Find(&checks).Error)
+	require.Len(t, checks, 2)
+	assert.Equal(t, models.HealthCheckTargetDatabase, checks[0].TargetType)
+	assert.Equal(t, conn.ID, checks[0].TargetID)
+	assert.True(t, checks[0].Success)
+	assert.Equal(t, int64(12), checks[0].LatencyMs)
+	assert.Equal(t, "PostgreSQL 16.2", checks[0].ServerVersion)
+	assert.Equal(t, models.HealthCheckTargetStorage, checks[1].TargetType)
+	assert.Equal(t, storage.ID, checks[1].TargetID)
+	assert.Equal(t, int64(25), checks[1].LatencyMs)
+
+	// The first success is reported, and nothing is due again yet
+	assert.Equal(t, []string{websocket.StorageStatusHealthy}, broadcaster.databaseStatuses())
+	monitor.RunOnce(context.Background())
+	assert.Equal(t, 1, connections.tests)
+}
+
+func TestHealthMonitor_PausesSchedulesUntilRecovery(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	connections := &fakeConnectionTester{}
+	broadcaster := &recordingHealthBroadcaster{}
+	monitor := NewHealthMonitor(db, connections, &fakeStorageTester{}, broadcaster, HealthMonitorConfig{Failures: 2})
+
+	load := func() models.DatabaseConnection {
+		var conn models.DatabaseConnection
+		require.NoError(t, db.First(&conn, job.DatabaseConnectionID).Error)
+		return conn
+	}
+
+	monitor.RunOnce(context.Background())
+	connections.setFail(true)
+
+	makeDue(t, db)
+	monitor.RunOnce(context.Background())
+	conn := load()
+	assert.Equal(t, models.HealthStatusHealthy, conn.HealthStatus)
+	assert.Equal(t, 1, conn.ConsecutiveFailures)
+	assert.Nil(t, conn.SchedulesPausedAt)
+
+	makeDue(t, db)
+	monitor.RunOnce(context.Background())
+	conn = load()
+	assert.Equal(t, models.HealthStatusUnhealthy, conn.HealthStatus)
+	require.NotNil(t, conn.SchedulesPausedAt)
+	require.NotNil(t, conn.LastTestError)
+	assert.Equal(t, "connection refused", *conn.LastTestError)
+
+	// Still failing: no new event
+	makeDue(t, db)
+	monitor.RunOnce(context.Background())
+
+	connections.setFail(false)
+	makeDue(t, db)
+	monitor.RunOnce(context.Background())
+	conn = load()
+	assert.Equal(t, models.HealthStatusHealthy, conn.HealthStatus)
+	assert.Nil(t, conn.SchedulesPausedAt)
+
+	assert.Equal(t, []string{
+		websocket.StorageStatusHealthy,
+		websocket.StorageStatusDegraded,
+		websocket.StorageStatusUnreachable,
+		websocket.StorageStatusHealthy,
+	}, broadcaster.databaseStatuses())
+
+	var failed int64
+	require.NoError(t, db.Model(&models.HealthCheck{}).Where("success = ?", false).Count(&failed).Error)
+	assert.Equal(t, int64(3), failed)
+}
+
+func TestHealthMonitor_StorageFailures(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "backups")
+
+	broadcaster := &recordingHealthBroadcaster{}
+	monitor := NewHealthMonitor(db, &fakeConnectionTester{err: errors.New("decrypt failed")}, &fakeStorageTester{fail: true}, broadcaster, HealthMonitorConfig{Failures: 1})
+	monitor.RunOnce(context.Background())
+
+	var config models.StorageConfiguration
+	require.NoError(t, db.First(&config, storage.ID).Error)
+	assert.Equal(t, models.HealthStatusUnhealthy, config.HealthStatus)
+	require.NotNil(t, config.LastTestError)
+	assert.Equal(t, "write: access denied", *config.LastTestError)
+
+	require.Len(t, broadcaster.storage, 1)
+	assert.Equal(t, storage.UID, broadcaster.storage[0].StorageUID)
+	assert.Equal(t, websocket.StorageStatusUnreachable, broadcaster.storage[0].Status)
+
+	var check models.HealthCheck
+	require.NoError(t, db.Where("target_type = ?", models.HealthCheckTargetDatabase).First(&check).Error)
+	assert.False(t, check.Success)
+	require.NotNil(t, check.Error)
+	assert.Equal(t, "decrypt failed", *check.Error)
+}
+
+func TestHealthMonitor_NextCheckDelay(t *testing.T) {
+	monitor := NewHealthMonitor(nil, nil, nil, nil, HealthMonitorConfig{Interval: 10 * time.Minute, Retry: time.Minute})
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 10 * time.Minute},
+		{failures: 1, expected: time.Minute},
+		{failures: 2, expected: 2 * time.Minute},
+		{failures: 4, expected: 8 * time.Minute},
+		{failures: 5, expected: 10 * time.Minute},
+		{failures: 40, expected: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := monitor.nextCheckDelay(tt.failures)
+			assert.InDelta(t, float64(tt.expected), float64(delay), float64(tt.expected)/10, "failures %d", tt.failures)
+		}
+	}
+}
+
+func TestHealthMonitor_ClaimsOnce(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	monitor := NewHealthMonitor(db, nil, nil, nil, HealthMonitorConfig{})
+
+	now := time.Now()
+	conn := job.DatabaseConnection
+	assert.True(t, monitor.claim(&conn, now))
+	other := job.DatabaseConnection
+	assert.False(t, monitor.claim(&other, now))
+}
+
+func TestHealthMonitor_SkipsInactive(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("id = ?", job.DatabaseConnectionID).Update("is_active", false).Error)
+
+	connections := &fakeConnectionTester{}
+	NewHealthMonitor(db, connections, &fakeStorageTester{}, nil, HealthMonitorConfig{}).RunOnce(context.Background())
+	assert.Zero(t, connections.tests)
+}
+
+func TestHealthMonitor_PrunesHistory(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	require.NoError(t, db.Create(&models.HealthCheck{TargetType: models.HealthCheckTargetDatabase, TargetID: 1, CheckedAt: time.Now().Add(-48 * time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.HealthCheck{TargetType: models.HealthCheckTargetDatabase, TargetID: 1, CheckedAt: time.Now().Add(-time.Hour)}).Error)
+
+	NewHealthMonitor(db, &fakeConnectionTester{}, &fakeStorageTester{}, nil, HealthMonitorConfig{Retention: 24 * time.Hour}).RunOnce(context.Background())
+
+	var count int64
+	require.NoError(t, db.Model(&models.HealthCheck{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestHealthMonitor_StartStop(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	connections := &fakeConnectionTester{}
+	monitor := NewHealthMonitor(db, connections, &fakeStorageTester{}, nil, HealthMonitorConfig{})
+	monitor.Start()
+
+	require.Eventually(t, func() bool {
+		connections.mu.Lock()
+		defer connections.mu.Unlock()
+		return connections.tests == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, monitor.Stop(ctx))
+	require.NoError(t, monitor.Stop(ctx))
+}
@@ -0,0 +1,119 @@
+package workers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+)
+
+// DefaultHeartbeatInterval is how often a worker reports itself alive
+const DefaultHeartbeatInterval = 10 * time.Second
+
+// heartbeatMisses is how many heartbeats a worker may miss before it counts as gone
+const heartbeatMisses = 3
+
+// Heartbeat periodically publishes a worker's status until it stops
+type Heartbeat struct {
+	store    services.WorkerStatusStore
+	interval time.Duration
+
+	mu     sync.Mutex
+	status services.WorkerStatus
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewWorkerStatus describes this worker process as it starts
+func NewWorkerStatus(concurrency int, queues map[string]int) *services.WorkerStatus {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return &services.WorkerStatus{
+		ID:          fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+		Host:        host,
+		PID:         os.Getpid(),
+		Concurrency: concurrency,
+		Queues:      queues,
+		StartedAt:   time.Now(),
+	}
+}
+
+// NewHeartbeat creates a heartbeat publishing the given status
+func NewHeartbeat(store services.WorkerStatusStore, status *services.WorkerStatus, interval time.Duration) *Heartbeat {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	return &Heartbeat{
+		store:    store,
+		interval: interval,
+		status:   *status,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start publishes the first heartbeat and keeps publishing in the background
+func (h *Heartbeat) Start() {
+	h.beat()
+	go h.run()
+}
+
+// SetDraining reports that the worker stopped taking jobs to shut down
+func (h *Heartbeat) SetDraining() {
+	h.mu.Lock()
+	h.status.Draining = true
+	h.mu.Unlock()
+	h.beat()
+}
+
+// Stop ends the heartbeat and removes the worker's status
+func (h *Heartbeat) Stop(ctx context.Context) error {
+	h.once.Do(func() { close(h.stop) })
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return h.store.Remove(ctx, h.status.ID)
+}
+
+func (h *Heartbeat) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+// beat publishes the current status once
+func (h *Heartbeat) beat() {
+	h.mu.Lock()
+	h.status.LastSeenAt = time.Now()
+	status := h.status
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+	defer cancel()
+
+	if err := h.store.Publish(ctx, &status, h.interval*heartbeatMisses); err != nil {
+		slog.Warn("Failed to publish worker heartbeat", "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	store := services.NewLocalWorkerStatusStore()
+	status := NewWorkerStatus(5, Queues())
+	heartbeat := NewHeartbeat(store, status, 20*time.Millisecond)
+	ctx := context.Background()
+
+	heartbeat.Start()
+	workers, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.Equal(t, status.ID, workers[0].ID)
+	assert.Equal(t, 5, workers[0].Concurrency)
+	assert.False(t, workers[0].Draining)
+	firstSeen := workers[0].LastSeenAt
+
+	// The heartbeat keeps the status fresh
+	time.Sleep(100 * time.Millisecond)
+	workers, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.True(t, workers[0].LastSeenAt.After(firstSeen))
+
+	heartbeat.SetDraining()
+	workers, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.True(t, workers[0].Draining)
+
+	// A stopped worker disappears right away
+	require.NoError(t, heartbeat.Stop(ctx))
+	workers, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, workers)
+}
@@ -0,0 +1,37 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// logged runs a handler with the task's ID and type in the fields of its logs
+func logged(handler func(context.Context, *asynq.Task) error) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		taskID, _ := asynq.GetTaskID(ctx)
+		return handler(logging.With(ctx, logging.KeyTaskID, taskID, logging.KeyTaskType, task.Type()), task)
+	}
+}
+
+// loadBackupJob loads a job with its user and connection, and returns ctx
+// with the job, its user and team in the fields of its logs
+func (bw *BackupWorker) loadBackupJob(ctx context.Context, backupJob *models.BackupJob, id uint) (context.Context, error) {
+	spanCtx, span := tracing.Start(ctx, "backup.load_job", trace.WithAttributes(attribute.Int("backup.job_id", int(id))))
+	err := bw.db.WithContext(spanCtx).Preload("User").Preload("DatabaseConnection").First(backupJob, id).Error
+	tracing.End(span, err)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = logging.With(ctx, logging.KeyJobUID, backupJob.UID, logging.KeyUserID, backupJob.UserID)
+	if backupJob.DatabaseConnection.TeamID != nil {
+		ctx = logging.With(ctx, logging.KeyTeamID, *backupJob.DatabaseConnection.TeamID)
+	}
+	return ctx, nil
+}
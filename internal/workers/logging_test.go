@@ -0,0 +1,53 @@
+package workers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/logging"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldValues(ctx context.Context) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, field := range logging.Fields(ctx) {
+		values[field.Key] = field.Value.Any()
+	}
+	return values
+}
+
+func TestLogged_AddsTaskFields(t *testing.T) {
+	var fields map[string]interface{}
+	handler := logged(func(ctx context.Context, task *asynq.Task) error {
+		fields = fieldValues(ctx)
+		return nil
+	})
+
+	require.NoError(t, handler(context.Background(), asynq.NewTask(TypeCleanupBackups, nil)))
+	assert.Equal(t, TypeCleanupBackups, fields[logging.KeyTaskType])
+	assert.Contains(t, fields, logging.KeyTaskID)
+}
+
+func TestBackupWorker_LoadBackupJobAddsJobFields(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	teamID := uint(4)
+	require.NoError(t, db.Model(&models.DatabaseConnection{}).Where("id = ?", job.DatabaseConnectionID).Update("team_id", teamID).Error)
+
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
+
+	var loaded models.BackupJob
+	ctx, err := worker.loadBackupJob(context.Background(), &loaded, job.ID)
+	require.NoError(t, err)
+
+	fields := fieldValues(ctx)
+	assert.Equal(t, job.UID, fields[logging.KeyJobUID])
+	assert.EqualValues(t, job.UserID, fields[logging.KeyUserID])
+	assert.EqualValues(t, teamID, fields[logging.KeyTeamID])
+
+	_, err = worker.loadBackupJob(context.Background(), &loaded, job.ID+100)
+	assert.Error(t, err)
+}
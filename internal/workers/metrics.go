@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"time"
+
+	"github.com/dbackup/backend-go/internal/metrics"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+)
+
+// SetMetrics records job outcomes, durations and sizes. Without it nothing is recorded.
+func (bw *BackupWorker) SetMetrics(m *metrics.Metrics) {
+	bw.metrics = m
+}
+
+// observeJob records a backup once its handler returns, by the status the
+// job was left in
+func (bw *BackupWorker) observeJob(kind, engine string, backupJob *models.BackupJob, started time.Time) {
+	bw.metrics.ObserveJob(kind, engine, jobOutcome(backupJob.Status), time.Since(started))
+}
+
+// observeRestore records a restore once its handler returns, by the status
+// the restore was left in
+func (bw *BackupWorker) observeRestore(engine string, restoreJob *models.RestoreJob, started time.Time) {
+	bw.metrics.ObserveJob(metrics.JobRestore, engine, jobOutcome(restoreJob.Status), time.Since(started))
+}
+
+// jobOutcome is the metrics outcome of a job left in a status
+func jobOutcome(status models.BackupJobStatus) string {
+	switch status {
+	case models.BackupStatusCompleted:
+		return metrics.OutcomeCompleted
+	case models.BackupStatusPending:
+		return metrics.OutcomeInterrupted
+	}
+	return metrics.OutcomeFailed
+}
+
+// observeBackupSize records the size of a dump and of the file uploaded from it
+func (bw *BackupWorker) observeBackupSize(engine string, result *services.BackupResult) {
+	uploaded := result.OriginalSize
+	if result.CompressedSize != nil {
+		uploaded = *result.CompressedSize
+	}
+	bw.metrics.ObserveBackupSize(engine, result.OriginalSize, uploaded)
+}
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/metrics"
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupWorker_RecordsJobMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	mockBackupService := &MockBackupService{}
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("pg_dump: connection refused"))
+
+	m := metrics.New()
+	worker := NewBackupWorker(db, mockBackupService, &MockStorageResolver{}, &MockQueueService{}, nil)
+	worker.SetMetrics(m)
+
+	payload, err := json.Marshal(BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID})
+	require.NoError(t, err)
+	require.Error(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payload)))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `dbackup_jobs_total{engine="postgresql",kind="backup",outcome="failed"} 1`)
+	assert.Contains(t, string(body), `dbackup_job_duration_seconds_count{engine="postgresql",kind="backup",outcome="failed"} 1`)
+	assert.NotContains(t, string(body), "dbackup_backup_dumped_bytes_total{")
+}
@@ -0,0 +1,216 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+)
+
+// storageQuotaWarning is the share of a storage quota that, once crossed by
+// an upload, warns the owner the storage is nearly full
+const storageQuotaWarning = 0.9
+
+// unretriedFailures are the error codes of jobs failed without a retry
+var unretriedFailures = map[string]bool{
+	"PERMISSION_DENIED":       true,
+	"PII_ENCRYPTION_REQUIRED": true,
+}
+
+// Notifier sends notifications to the channels subscribed to their events
+type Notifier interface {
+	Notify(ctx context.Context, n *services.Notification) error
+}
+
+// NotificationDeliverer sends one queued notification delivery
+type NotificationDeliverer interface {
+	Deliver(ctx context.Context, deliveryID uint, final bool) error
+}
+
+// NotificationWorker runs the queue tasks delivering notifications
+type NotificationWorker struct {
+	deliverer NotificationDeliverer
+}
+
+// NewNotificationWorker creates a new notification worker
+func NewNotificationWorker(deliverer NotificationDeliverer) *NotificationWorker {
+	return &NotificationWorker{deliverer: deliverer}
+}
+
+// RegisterHandlers registers the notification delivery handler
+func (nw *NotificationWorker) RegisterHandlers(worker *services.QueueWorker) {
+	worker.RegisterHandler(services.NotificationTaskType, traced(logged(nw.HandleDeliverNotification)))
+}
+
+// HandleDeliverNotification sends a notification delivery. A failed send
+// returns its error so the queue retries it with backoff; the last attempt
+// marks the delivery failed.
+func (nw *NotificationWorker) HandleDeliverNotification(ctx context.Context, task *asynq.Task) error {
+	var payload services.NotificationTaskPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal notification payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	return nw.deliverer.Deliver(ctx, payload.DeliveryID, finalAttempt(ctx))
+}
+
+// finalAttempt reports whether the running task is not retried if it fails.
+// Outside the queue every run is the last.
+func finalAttempt(ctx context.Context) bool {
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, ok := asynq.GetMaxRetry(ctx)
+	return !ok || retried >= maxRetry
+}
+
+// failureFinal reports whether a failed job is given up on: the queue does
+// not retry it, either because this was the last attempt or because its
+// error is not retried
+func failureFinal(ctx context.Context, backupJob *models.BackupJob) bool {
+	return finalAttempt(ctx) || (backupJob.ErrorCode != nil && unretriedFailures[*backupJob.ErrorCode])
+}
+
+// SetNotifier sends notifications when backups and restores finish and when
+// storage nears its quota. Without it none are sent.
+func (bw *BackupWorker) SetNotifier(n Notifier) {
+	bw.notifier = n
+}
+
+// notifyJob tells subscribers about a finished backup. A failed backup is
+// only reported once the queue gives up on it, not on every retry.
+func (bw *BackupWorker) notifyJob(ctx context.Context, backupJob *models.BackupJob) {
+	if bw.notifier == nil {
+		return
+	}
+
+	data := map[string]string{
+		"database": backupJob.DatabaseConnection.Name,
+		"job":      backupJob.Name,
+		"duration": backupJob.GetFormattedDuration(),
+	}
+	var event models.NotificationEvent
+	switch {
+	case backupJob.Status == models.BackupStatusCompleted:
+		event = models.NotificationBackupSucceeded
+		data["size"] = backupJob.GetFormattedSize()
+	case backupJob.Status == models.BackupStatusFailed:
+		code := ""
+		if backupJob.ErrorCode != nil {
+			code = *backupJob.ErrorCode
+		}
+		if !failureFinal(ctx, backupJob) {
+			return
+		}
+		event = models.NotificationBackupFailed
+		data["error_code"] = code
+		if backupJob.ErrorMessage != nil {
+			data["error"] = *backupJob.ErrorMessage
+		}
+	default:
+		return
+	}
+
+	bw.notify(ctx, &services.Notification{
+		Event:                event,
+		UserID:               backupJob.UserID,
+		TeamID:               backupJob.DatabaseConnection.TeamID,
+		DatabaseConnectionID: &backupJob.DatabaseConnectionID,
+		Data:                 data,
+	})
+}
+
+// notifyRestore tells subscribers about a completed restore of a backup
+func (bw *BackupWorker) notifyRestore(ctx context.Context, backupJob *models.BackupJob, restoreJob *models.RestoreJob) {
+	if bw.notifier == nil || restoreJob.Status != models.BackupStatusCompleted {
+		return
+	}
+
+	bw.notify(ctx, &services.Notification{
+		Event:                models.NotificationRestoreCompleted,
+		UserID:               restoreJob.UserID,
+		TeamID:               backupJob.DatabaseConnection.TeamID,
+		DatabaseConnectionID: &backupJob.DatabaseConnectionID,
+		Data: map[string]string{
+			"database": backupJob.DatabaseConnection.Name,
+			"job":      backupJob.Name,
+			"duration": restoreJob.GetFormattedDuration(),
+		},
+	})
+}
+
+// checkStorageQuota warns when an upload takes a storage configuration past
+// storageQuotaWarning of its quota. Only the upload that crosses the line
+// warns, so a nearly full storage is not reported on every backup.
+func (bw *BackupWorker) checkStorageQuota(ctx context.Context, job *models.BackupJob, config *models.StorageConfiguration, uploaded int64) {
+	if bw.notifier == nil || config.QuotaBytes == nil || *config.QuotaBytes <= 0 {
+		return
+	}
+
+	var used int64
+	if err := bw.db.WithContext(ctx).Model(&models.BackupFileLocation{}).
+		Where("storage_configuration_id = ? AND status = ?", config.ID, models.BackupFileLocationStatusAvailable).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error; err != nil {
+		slog.WarnContext(ctx, "Failed to measure storage usage", "storage_uid", config.UID, "error", err)
+		return
+	}
+
+	limit := float64(*config.QuotaBytes) * storageQuotaWarning
+	if float64(used) < limit || float64(used-uploaded) >= limit {
+		return
+	}
+
+	bw.notify(ctx, &services.Notification{
+		Event:  models.NotificationStorageQuotaNearing,
+		UserID: job.UserID,
+		TeamID: config.TeamID,
+		Data: map[string]string{
+			"storage": config.Name,
+			"used":    models.FormatBytes(used),
+			"quota":   models.FormatBytes(*config.QuotaBytes),
+			"percent": strconv.FormatInt(used*100 / *config.QuotaBytes, 10),
+		},
+	})
+}
+
+// notify sends a notification, logging rather than failing the job on errors
+func (bw *BackupWorker) notify(ctx context.Context, n *services.Notification) {
+	n.OccurredAt = time.Now()
+	if err := bw.notifier.Notify(ctx, n); err != nil {
+		slog.WarnContext(ctx, "Failed to send notification", "event", n.Event, "error", err)
+	}
+}
+
+// SetNotifier sends notifications when database connections go down or
+// recover. Without it none are sent.
+func (m *HealthMonitor) SetNotifier(n Notifier) {
+	m.notifier = n
+}
+
+// notifyConnection tells subscribers a connection went down or recovered
+func (m *HealthMonitor) notifyConnection(ctx context.Context, conn *models.DatabaseConnection, message string) {
+	if m.notifier == nil {
+		return
+	}
+
+	n := &services.Notification{
+		Event:                models.NotificationConnectionRecovered,
+		UserID:               conn.UserID,
+		TeamID:               conn.TeamID,
+		DatabaseConnectionID: &conn.ID,
+		Data:                 map[string]string{"database": conn.Name},
+		OccurredAt:           time.Now(),
+	}
+	if conn.HealthStatus == models.HealthStatusUnhealthy {
+		n.Event = models.NotificationConnectionUnhealthy
+		n.Data["failures"] = strconv.Itoa(conn.ConsecutiveFailures)
+		n.Data["error"] = message
+	}
+	if err := m.notifier.Notify(ctx, n); err != nil {
+		slog.WarnContext(ctx, "Failed to send notification", "event", n.Event, "database_uid", conn.UID, "error", err)
+	}
+}
@@ -0,0 +1,252 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []*services.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *services.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) events() []models.NotificationEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var events []models.NotificationEvent
+	for _, notification := range n.notifications {
+		events = append(events, notification.Event)
+	}
+	return events
+}
+
+type recordingDeliverer struct {
+	deliveryID uint
+	final      bool
+	err        error
+}
+
+func (d *recordingDeliverer) Deliver(ctx context.Context, deliveryID uint, final bool) error {
+	d.deliveryID = deliveryID
+	d.final = final
+	return d.err
+}
+
+func TestNotificationWorker_HandleDeliverNotification(t *testing.T) {
+	deliverer := &recordingDeliverer{}
+	worker := NewNotificationWorker(deliverer)
+
+	payload, err := json.Marshal(services.NotificationTaskPayload{DeliveryID: 42})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.HandleDeliverNotification(context.Background(), asynq.NewTask(services.NotificationTaskType, payload)))
+	assert.Equal(t, uint(42), deliverer.deliveryID)
+	assert.True(t, deliverer.final, "a delivery run outside the queue is its last attempt")
+
+	// Send failures are returned for the queue to retry
+	deliverer.err = errors.New("endpoint returned 503")
+	assert.ErrorIs(t, worker.HandleDeliverNotification(context.Background(), asynq.NewTask(services.NotificationTaskType, payload)), deliverer.err)
+
+	err = worker.HandleDeliverNotification(context.Background(), asynq.NewTask(services.NotificationTaskType, []byte("{")))
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+}
+
+func TestBackupWorker_NotifiesBackupOutcome(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "user-bucket")
+
+	mockBackupService := &MockBackupService{}
+	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	notifier := &recordingNotifier{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+	worker.SetBackupCipher(testBackupCipher())
+	worker.SetNotifier(notifier)
+
+	payloadBytes, err := json.Marshal(BackupTaskPayload{
+		BackupJobID: job.ID,
+		UserID:      job.UserID,
+		DatabaseUID: job.DatabaseConnection.UID,
+		StorageUID:  storage.UID,
+	})
+	require.NoError(t, err)
+
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
+		FilePath:     writeTestDump(t),
+		OriginalSize: 1024,
+	}, nil).Once()
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("UploadFile", mock.Anything, "user-bucket", mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{
+		Bucket: "user-bucket",
+		Key:    "backups/test.backup",
+	}, nil)
+
+	require.NoError(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes)))
+	require.Equal(t, []models.NotificationEvent{models.NotificationBackupSucceeded}, notifier.events())
+
+	succeeded := notifier.notifications[0]
+	assert.Equal(t, job.UserID, succeeded.UserID)
+	require.NotNil(t, succeeded.DatabaseConnectionID)
+	assert.Equal(t, job.DatabaseConnectionID, *succeeded.DatabaseConnectionID)
+	assert.Equal(t, "Test Database", succeeded.Data["database"])
+	assert.Equal(t, "1.0 KB", succeeded.Data["size"])
+	assert.False(t, succeeded.OccurredAt.IsZero())
+
+	// Run the job again and let the dump fail
+	require.NoError(t, db.Model(job).Update("status", models.BackupStatusPending).Error)
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("pg_dump: connection refused")).Once()
+
+	require.Error(t, worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes)))
+	require.Equal(t, []models.NotificationEvent{models.NotificationBackupSucceeded, models.NotificationBackupFailed}, notifier.events())
+
+	failed := notifier.notifications[1]
+	assert.Equal(t, "pg_dump: connection refused", failed.Data["error"])
+	assert.Equal(t, "BACKUP_FAILED", failed.Data["error_code"])
+}
+
+func TestBackupWorker_NotifyJob(t *testing.T) {
+	errorMessage := "backup failed"
+	tests := []struct {
+		name   string
+		status models.BackupStatus
+		want   []models.NotificationEvent
+	}{
+		{"backup running", models.BackupStatusRunning, nil},
+		{"backup completed", models.BackupStatusCompleted, []models.NotificationEvent{models.NotificationBackupSucceeded}},
+		{"backup failed", models.BackupStatusFailed, []models.NotificationEvent{models.NotificationBackupFailed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			worker := NewBackupWorker(nil, nil, nil, nil, nil)
+			worker.SetNotifier(notifier)
+
+			job := &models.BackupJob{Name: "Nightly", Status: tt.status, UserID: 7, DatabaseConnectionID: 3, ErrorMessage: &errorMessage}
+			worker.notifyJob(context.Background(), job)
+			assert.Equal(t, tt.want, notifier.events())
+		})
+	}
+}
+
+func TestBackupWorker_NotifyRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.BackupStatus
+		want   []models.NotificationEvent
+	}{
+		{"restore running", models.BackupStatusRunning, nil},
+		{"restore completed", models.BackupStatusCompleted, []models.NotificationEvent{models.NotificationRestoreCompleted}},
+		{"restore failed", models.BackupStatusFailed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			worker := NewBackupWorker(nil, nil, nil, nil, nil)
+			worker.SetNotifier(notifier)
+
+			job := &models.BackupJob{Name: "Nightly", Status: models.BackupStatusCompleted, UserID: 7, DatabaseConnectionID: 3}
+			worker.notifyRestore(context.Background(), job, &models.RestoreJob{Status: tt.status, UserID: 7})
+			assert.Equal(t, tt.want, notifier.events())
+		})
+	}
+}
+
+func TestBackupWorker_StorageQuotaWarning(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "backups")
+	quota := int64(1000)
+	storage.QuotaBytes = &quota
+
+	notifier := &recordingNotifier{}
+	worker := NewBackupWorker(db, nil, nil, nil, nil)
+	worker.SetNotifier(notifier)
+
+	file := createTestBackupFile(t, db, job, storage)
+	store := func(size int64, status models.BackupFileLocationStatus) {
+		require.NoError(t, db.Create(&models.BackupFileLocation{
+			Bucket:                 storage.Bucket,
+			Key:                    "backups/" + time.Now().Format(time.RFC3339Nano),
+			Status:                 status,
+			Size:                   &size,
+			BackupFileID:           file.ID,
+			StorageConfigurationID: &storage.ID,
+		}).Error)
+	}
+
+	// Deleted copies do not count
+	store(800, models.BackupFileLocationStatusAvailable)
+	store(500, models.BackupFileLocationStatusDeleted)
+	worker.checkStorageQuota(context.Background(), job, storage, 800)
+	assert.Empty(t, notifier.events())
+
+	// The upload crossing 90% warns once
+	store(150, models.BackupFileLocationStatusAvailable)
+	worker.checkStorageQuota(context.Background(), job, storage, 150)
+	require.Equal(t, []models.NotificationEvent{models.NotificationStorageQuotaNearing}, notifier.events())
+	assert.Equal(t, "95", notifier.notifications[0].Data["percent"])
+	assert.Equal(t, "backups", notifier.notifications[0].Data["storage"])
+
+	store(20, models.BackupFileLocationStatusAvailable)
+	worker.checkStorageQuota(context.Background(), job, storage, 20)
+	assert.Len(t, notifier.events(), 1)
+
+	// Without a quota nothing is measured
+	storage.QuotaBytes = nil
+	worker.checkStorageQuota(context.Background(), job, storage, 20)
+	assert.Len(t, notifier.events(), 1)
+}
+
+func TestHealthMonitor_NotifiesConnectionChanges(t *testing.T) {
+	db := setupHealthMonitorDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+
+	connections := &fakeConnectionTester{}
+	notifier := &recordingNotifier{}
+	monitor := NewHealthMonitor(db, connections, &fakeStorageTester{}, nil, HealthMonitorConfig{Failures: 2})
+	monitor.SetNotifier(notifier)
+
+	// The first success is not a recovery
+	monitor.RunOnce(context.Background())
+	assert.Empty(t, notifier.events())
+
+	connections.setFail(true)
+	for i := 0; i < 3; i++ {
+		makeDue(t, db)
+		monitor.RunOnce(context.Background())
+	}
+	require.Equal(t, []models.NotificationEvent{models.NotificationConnectionUnhealthy}, notifier.events())
+
+	down := notifier.notifications[0]
+	require.NotNil(t, down.DatabaseConnectionID)
+	assert.Equal(t, job.DatabaseConnectionID, *down.DatabaseConnectionID)
+	assert.Equal(t, "2", down.Data["failures"])
+	assert.Equal(t, "connection refused", down.Data["error"])
+
+	connections.setFail(false)
+	makeDue(t, db)
+	monitor.RunOnce(context.Background())
+	assert.Equal(t, []models.NotificationEvent{models.NotificationConnectionUnhealthy, models.NotificationConnectionRecovered}, notifier.events())
+}
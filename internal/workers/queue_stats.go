@@ -0,0 +1,129 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/websocket"
+)
+
+// DefaultQueueStatsInterval is how often queue statistics are streamed
+const DefaultQueueStatsInterval = 5 * time.Second
+
+// QueueStatsSource provides queue statistics
+type QueueStatsSource interface {
+	GetQueueStats(ctx context.Context) (*services.QueueStats, error)
+}
+
+// QueueStatsBroadcaster publishes queue statistics to WebSocket subscribers
+type QueueStatsBroadcaster interface {
+	HasSubscribers(topic string) bool
+	PublishQueueStats(stats *websocket.QueueStatsMessage) error
+}
+
+// QueueStatsPublisher periodically streams queue statistics on the queue
+// stats topic. Each API node only reads the queues while one of its own
+// connections is subscribed, so idle dashboards cost nothing.
+type QueueStatsPublisher struct {
+	source   QueueStatsSource
+	ws       QueueStatsBroadcaster
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewQueueStatsPublisher creates a new queue statistics publisher
+func NewQueueStatsPublisher(source QueueStatsSource, ws QueueStatsBroadcaster, interval time.Duration) *QueueStatsPublisher {
+	if interval <= 0 {
+		interval = DefaultQueueStatsInterval
+	}
+	return &QueueStatsPublisher{
+		source:   source,
+		ws:       ws,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins streaming statistics in the background
+func (p *QueueStatsPublisher) Start() {
+	go p.run()
+}
+
+// Stop ends streaming and waits for the running publication to finish
+func (p *QueueStatsPublisher) Stop(ctx context.Context) error {
+	p.once.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *QueueStatsPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.publish()
+		}
+	}
+}
+
+// publish sends one snapshot if anyone on this node is watching
+func (p *QueueStatsPublisher) publish() {
+	if !p.ws.HasSubscribers(websocket.QueueStatsTopic) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+
+	stats, err := p.source.GetQueueStats(ctx)
+	if err != nil {
+		slog.Warn("Failed to read queue stats", "error", err)
+		return
+	}
+	if err := p.ws.PublishQueueStats(queueStatsMessage(stats)); err != nil {
+		slog.Warn("Failed to publish queue stats", "error", err)
+	}
+}
+
+// queueStatsMessage converts queue statistics to their WebSocket event
+func queueStatsMessage(stats *services.QueueStats) *websocket.QueueStatsMessage {
+	message := &websocket.QueueStatsMessage{
+		Pending:   stats.Pending,
+		Active:    stats.Active,
+		Scheduled: stats.Scheduled,
+		Retry:     stats.Retry,
+		Archived:  stats.Archived,
+		Completed: stats.Completed,
+		Failed:    stats.Failed,
+		Queues:    make(map[string]websocket.QueueCounts, len(stats.Queues)),
+	}
+	for name, queue := range stats.Queues {
+		message.Queues[name] = websocket.QueueCounts{
+			Size:      queue.Size,
+			Pending:   queue.Pending,
+			Active:    queue.Active,
+			Scheduled: queue.Scheduled,
+			Retry:     queue.Retry,
+			Archived:  queue.Archived,
+			Paused:    queue.Paused,
+		}
+	}
+	return message
+}
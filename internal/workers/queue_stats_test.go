@@ -0,0 +1,79 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticQueueStats struct {
+	reads atomic.Int32
+}
+
+func (s *staticQueueStats) GetQueueStats(ctx context.Context) (*services.QueueStats, error) {
+	s.reads.Add(1)
+	return &services.QueueStats{
+		Pending: 4,
+		Retry:   1,
+		Queues: map[string]*services.QueueInfo{
+			"backups": {Name: "backups", Size: 5, Pending: 4, Retry: 1, Paused: true},
+		},
+	}, nil
+}
+
+type recordingBroadcaster struct {
+	subscribed atomic.Bool
+	mu         sync.Mutex
+	published  []*websocket.QueueStatsMessage
+}
+
+func (b *recordingBroadcaster) HasSubscribers(topic string) bool {
+	return topic == websocket.QueueStatsTopic && b.subscribed.Load()
+}
+
+func (b *recordingBroadcaster) PublishQueueStats(stats *websocket.QueueStatsMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, stats)
+	return nil
+}
+
+func (b *recordingBroadcaster) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.published)
+}
+
+func TestQueueStatsPublisher(t *testing.T) {
+	source := &staticQueueStats{}
+	broadcaster := &recordingBroadcaster{}
+	publisher := NewQueueStatsPublisher(source, broadcaster, 10*time.Millisecond)
+	publisher.Start()
+
+	// Nothing is read while nobody watches
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, source.reads.Load())
+	assert.Zero(t, broadcaster.count())
+
+	broadcaster.subscribed.Store(true)
+	require.Eventually(t, func() bool { return broadcaster.count() >= 2 }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, publisher.Stop(ctx))
+	require.NoError(t, publisher.Stop(ctx))
+
+	broadcaster.mu.Lock()
+	message := broadcaster.published[0]
+	broadcaster.mu.Unlock()
+	assert.Equal(t, int64(4), message.Pending)
+	assert.Equal(t, int64(1), message.Retry)
+	assert.Equal(t, websocket.QueueCounts{Size: 5, Pending: 4, Retry: 1, Paused: true}, message.Queues["backups"])
+}
@@ -0,0 +1,245 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// ReplicateTaskPayload represents the payload for copying a backup file to a replica location
+type ReplicateTaskPayload struct {
+	BackupFileID uint `json:"backup_file_id"`
+	LocationID   uint `json:"location_id"`
+
+	// Trace context of the backup that scheduled the copy
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+// HandleReplicateBackup copies a backup file from an available copy to a pending replica location
+func (bw *BackupWorker) HandleReplicateBackup(ctx context.Context, task *asynq.Task) error {
+	var payload ReplicateTaskPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal replicate payload: %w", err)
+	}
+
+	var location models.BackupFileLocation
+	if err := bw.db.First(&location, payload.LocationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The backup file was cleaned up before replication ran
+			slog.InfoContext(ctx, "Replica location no longer exists, skipping", "location_id", payload.LocationID)
+			return nil
+		}
+		return fmt.Errorf("failed to load replica location: %w", err)
+	}
+
+	if location.IsAvailable() {
+		return nil
+	}
+
+	var backupFile models.BackupFile
+	if err := bw.db.Preload("Locations").Preload("BackupJob.DatabaseConnection").First(&backupFile, location.BackupFileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.InfoContext(ctx, "Backup file no longer exists, skipping replication", "backup_file_id", location.BackupFileID)
+			return nil
+		}
+		return fmt.Errorf("failed to load backup file: %w", err)
+	}
+
+	location.Attempts++
+	size, err := bw.copyToLocation(ctx, &backupFile, &location)
+	if err != nil {
+		location.MarkFailed(err.Error())
+		bw.db.Save(&location)
+		return fmt.Errorf("replication failed: %w", err)
+	}
+
+	location.MarkAvailable(size)
+	if err := bw.db.Save(&location).Error; err != nil {
+		return fmt.Errorf("failed to save replica location: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Backup file replicated", "backup_file_id", backupFile.ID, "bucket", location.Bucket, "key", location.Key)
+	return nil
+}
+
+// scheduleReplicas creates pending replica locations and enqueues their copy tasks.
+// Failures are recorded on the location rather than returned.
+func (bw *BackupWorker) scheduleReplicas(ctx context.Context, job *models.BackupJob, backupFile *models.BackupFile, primary *models.StorageConfiguration, objectKey string, replicaUIDs []string) {
+	seen := map[uint]bool{primary.ID: true}
+
+	for _, uid := range replicaUIDs {
+		config, _, err := bw.storage.ResolveStorage(ctx, uid, job.UserID, job.DatabaseConnection.TeamID)
+		if err != nil {
+			slog.WarnContext(ctx, "Skipping replica storage", "storage_uid", uid, "backup_file_id", backupFile.ID, "error", err)
+			continue
+		}
+		if seen[config.ID] {
+			continue
+		}
+		seen[config.ID] = true
+		// Replicas are copies of the primary's bytes, so they are encrypted exactly when it is
+		if requiresClientSideEncryption(job, backupFile.Metadata["contains_pii"] == true) && backupFile.EncryptionKey == nil {
+			slog.WarnContext(ctx, "Skipping replica storage", "storage_uid", uid, "backup_file_id", backupFile.ID, "error", errPIIEncryptionRequired)
+			continue
+		}
+
+		location := &models.BackupFileLocation{
+			Bucket:                 config.Bucket,
+			Key:                    withPathPrefix(config, objectKey),
+			Region:                 config.Region,
+			Endpoint:               config.Endpoint,
+			Status:                 models.BackupFileLocationStatusPending,
+			BackupFileID:           backupFile.ID,
+			StorageConfigurationID: &config.ID,
+		}
+		if err := bw.db.Create(location).Error; err != nil {
+			slog.ErrorContext(ctx, "Failed to create replica location", "backup_file_id", backupFile.ID, "error", err)
+			continue
+		}
+
+		payload := ReplicateTaskPayload{BackupFileID: backupFile.ID, LocationID: location.ID, TraceContext: tracing.Inject(ctx)}
+		_, err = bw.queueService.EnqueueJob(ctx, TypeReplicateBackup, payload,
+			services.WithQueue(QueueReplication),
+			services.WithMaxRetry(5),
+			services.WithTimeout(60*time.Minute),
+		)
+		if err != nil {
+			location.MarkFailed(fmt.Sprintf("failed to enqueue replication: %v", err))
+			bw.db.Save(location)
+			slog.ErrorContext(ctx, "Failed to enqueue replication", "backup_file_id", backupFile.ID, "error", err)
+		}
+	}
+}
+
+// copyToLocation streams a backup file from a readable copy to the target location
+func (bw *BackupWorker) copyToLocation(ctx context.Context, backupFile *models.BackupFile, target *models.BackupFileLocation) (int64, error) {
+	if target.StorageConfigurationID == nil {
+		return 0, fmt.Errorf("replica location %d has no storage configuration", target.ID)
+	}
+
+	_, destination, err := bw.storage.ResolveStorageByID(ctx, *target.StorageConfigurationID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve replica storage: %w", err)
+	}
+	destination = services.ObjectStoreForBucket(destination, target.Bucket)
+
+	reader, source, err := bw.openBackupFile(ctx, &backupFile.BackupJob, backupFile)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	result, err := destination.Put(ctx, target.Key, reader, "application/octet-stream")
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload replica: %w", err)
+	}
+
+	if source.Size != nil && result.Size > 0 && result.Size != *source.Size {
+		return 0, fmt.Errorf("replica size mismatch: expected %d bytes, wrote %d", *source.Size, result.Size)
+	}
+
+	size := result.Size
+	if size == 0 && source.Size != nil {
+		size = *source.Size
+	}
+	return size, nil
+}
+
+// openBackupFile opens the first readable copy of a backup file, trying the primary before replicas
+func (bw *BackupWorker) openBackupFile(ctx context.Context, job *models.BackupJob, backupFile *models.BackupFile) (io.ReadCloser, *models.BackupFileLocation, error) {
+	locations := backupFile.ReadableLocations()
+	if len(locations) == 0 {
+		return nil, nil, fmt.Errorf("backup file %s has no available copies", backupFile.UID)
+	}
+
+	var lastErr error
+	for i := range locations {
+		location := &locations[i]
+
+		store, err := bw.resolveLocationStorage(ctx, job, location)
+		if err == nil {
+			var reader io.ReadCloser
+			reader, err = store.Get(ctx, location.Key)
+			if err == nil {
+				return reader, location, nil
+			}
+		}
+
+		slog.WarnContext(ctx, "Copy of backup file is unavailable", "backup_file_uid", backupFile.UID, "bucket", location.Bucket, "key", location.Key, "error", err)
+		lastErr = err
+	}
+
+	return nil, nil, fmt.Errorf("all copies of backup file %s are unavailable: %w", backupFile.UID, lastErr)
+}
+
+// deleteBackupFileCopies deletes the primary copy and every replica of a backup file.
+// Locations that were deleted are marked so a later retry skips them.
+func (bw *BackupWorker) deleteBackupFileCopies(ctx context.Context, backupFile *models.BackupFile) error {
+	locations := []models.BackupFileLocation{backupFile.PrimaryLocation()}
+	for _, location := range backupFile.Locations {
+		if !location.IsPrimary {
+			locations = append(locations, location)
+		}
+	}
+
+	var errs []error
+	for i := range locations {
+		location := &locations[i]
+		if location.Status == models.BackupFileLocationStatusDeleted {
+			continue
+		}
+
+		store, err := bw.resolveLocationStorage(ctx, &backupFile.BackupJob, location)
+		if err == nil {
+			err = store.Delete(ctx, location.Key)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", location.Bucket, location.Key, err))
+			continue
+		}
+
+		if location.ID != 0 {
+			location.MarkDeleted()
+			bw.db.Save(location)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveLocationStorage returns the object store holding a copy.
+// Copies uploaded before storage was tracked per file use the job owner's default.
+func (bw *BackupWorker) resolveLocationStorage(ctx context.Context, job *models.BackupJob, location *models.BackupFileLocation) (services.ObjectStore, error) {
+	var (
+		store services.ObjectStore
+		err   error
+	)
+	if location.StorageConfigurationID != nil {
+		_, store, err = bw.storage.ResolveStorageByID(ctx, *location.StorageConfigurationID)
+	} else {
+		_, store, err = bw.storage.ResolveStorage(ctx, "", job.UserID, job.DatabaseConnection.TeamID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage configuration: %w", err)
+	}
+	// Copies stay in the bucket they were written to even if the configuration changed
+	return services.ObjectStoreForBucket(store, location.Bucket), nil
+}
+
+// withPathPrefix prepends the configuration's path prefix to an object key
+func withPathPrefix(config *models.StorageConfiguration, key string) string {
+	if config.PathPrefix != nil && *config.PathPrefix != "" {
+		return fmt.Sprintf("%s/%s", *config.PathPrefix, key)
+	}
+	return key
+}
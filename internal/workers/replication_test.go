@@ -0,0 +1,286 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createTestReplica(t *testing.T, db *gorm.DB, file *models.BackupFile, storage *models.StorageConfiguration, status models.BackupFileLocationStatus) *models.BackupFileLocation {
+	location := &models.BackupFileLocation{
+		Bucket:                 storage.Bucket,
+		Key:                    file.S3Key,
+		Region:                 storage.Region,
+		Status:                 status,
+		BackupFileID:           file.ID,
+		StorageConfigurationID: &storage.ID,
+	}
+	require.NoError(t, db.Create(location).Error)
+	return location
+}
+
+func TestBackupWorker_Backup_SchedulesReplicas(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	primary := createTestStorageConfiguration(t, db, job.UserID, "primary-bucket")
+	secondary := createTestStorageConfiguration(t, db, job.UserID, "eu-bucket")
+	prefix := "replicas"
+	secondary.PathPrefix = &prefix
+
+	mockBackupService := &MockBackupService{}
+	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockQueueService := &MockQueueService{}
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, mockQueueService, nil)
+	worker.SetBackupCipher(testBackupCipher())
+
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{
+		FilePath:     writeTestDump(t),
+		OriginalSize: 1024,
+	}, nil)
+	mockStorage.On("ResolveStorage", mock.Anything, primary.UID, job.UserID, (*uint)(nil)).Return(primary, services.NewS3ObjectStore(mockS3Service, primary.Bucket), nil)
+	mockStorage.On("ResolveStorage", mock.Anything, secondary.UID, job.UserID, (*uint)(nil)).Return(secondary, services.NewS3ObjectStore(&MockS3Service{}, secondary.Bucket), nil)
+	mockStorage.On("ResolveStorage", mock.Anything, "missing", job.UserID, (*uint)(nil)).Return(nil, nil, errors.New("not found"))
+	var uploadedKey string
+	mockS3Service.On("UploadFile", mock.Anything, "primary-bucket", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { uploadedKey = args.String(2) }).
+		Return(&services.S3UploadResult{Bucket: "primary-bucket", Key: "backups/primary.backup"}, nil)
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeReplicateBackup, mock.Anything, mock.Anything).Return(&services.JobInfo{ID: "replicate-1"}, nil)
+
+	payload := BackupTaskPayload{
+		BackupJobID:        job.ID,
+		UserID:             job.UserID,
+		DatabaseUID:        job.DatabaseConnection.UID,
+		StorageUID:         primary.UID,
+		ReplicaStorageUIDs: []string{secondary.UID, primary.UID, "missing"},
+	}
+	payloadBytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	err = worker.HandleBackupPostgreSQL(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes))
+	require.NoError(t, err)
+
+	var file models.BackupFile
+	require.NoError(t, db.Preload("Locations").Where("backup_job_id = ?", job.ID).First(&file).Error)
+	require.NotNil(t, file.ExpiresAt)
+	assert.True(t, file.ExpiresAt.After(time.Now()), "retention should be counted from the upload time")
+
+	// One primary copy and one replica; the duplicate and unresolvable UIDs are skipped
+	require.Len(t, file.Locations, 2)
+	locations := map[string]models.BackupFileLocation{}
+	for _, location := range file.Locations {
+		locations[location.Bucket] = location
+	}
+
+	assert.True(t, locations["primary-bucket"].IsPrimary)
+	assert.Equal(t, models.BackupFileLocationStatusAvailable, locations["primary-bucket"].Status)
+
+	replica := locations["eu-bucket"]
+	assert.False(t, replica.IsPrimary)
+	assert.Equal(t, models.BackupFileLocationStatusPending, replica.Status)
+	assert.True(t, strings.HasPrefix(uploadedKey, "backups/"))
+	assert.Equal(t, "replicas/"+uploadedKey, replica.Key)
+
+	mockQueueService.AssertNumberOfCalls(t, "EnqueueJob", 1)
+	mockQueueService.AssertCalled(t, "EnqueueJob", mock.Anything, TypeReplicateBackup, ReplicateTaskPayload{BackupFileID: file.ID, LocationID: replica.ID}, mock.Anything)
+}
+
+func TestBackupWorker_HandleReplicateBackup(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	primary := createTestStorageConfiguration(t, db, job.UserID, "primary-bucket")
+	secondary := createTestStorageConfiguration(t, db, job.UserID, "eu-bucket")
+	file := createTestBackupFile(t, db, job, primary)
+	replica := createTestReplica(t, db, file, secondary, models.BackupFileLocationStatusPending)
+
+	source := &MockS3Service{}
+	destination := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, primary.ID).Return(primary, services.NewS3ObjectStore(source, primary.Bucket), nil)
+	mockStorage.On("ResolveStorageByID", mock.Anything, secondary.ID).Return(secondary, services.NewS3ObjectStore(destination, secondary.Bucket), nil)
+
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
+
+	payloadBytes, err := json.Marshal(ReplicateTaskPayload{BackupFileID: file.ID, LocationID: replica.ID})
+	require.NoError(t, err)
+	task := asynq.NewTask(TypeReplicateBackup, payloadBytes)
+
+	t.Run("upload failure marks the replica failed", func(t *testing.T) {
+		source.On("DownloadFile", mock.Anything, "primary-bucket", file.S3Key).Return(io.NopCloser(strings.NewReader("backup data")), nil).Once()
+		destination.On("UploadFile", mock.Anything, "eu-bucket", replica.Key, mock.Anything, mock.Anything).Return(nil, errors.New("region unavailable")).Once()
+
+		err := worker.HandleReplicateBackup(context.Background(), task)
+		assert.Error(t, err)
+
+		var stored models.BackupFileLocation
+		require.NoError(t, db.First(&stored, replica.ID).Error)
+		assert.Equal(t, models.BackupFileLocationStatusFailed, stored.Status)
+		assert.Equal(t, 1, stored.Attempts)
+		require.NotNil(t, stored.ErrorMessage)
+		assert.Contains(t, *stored.ErrorMessage, "region unavailable")
+	})
+
+	t.Run("retry copies the primary to the replica", func(t *testing.T) {
+		source.On("DownloadFile", mock.Anything, "primary-bucket", file.S3Key).Return(io.NopCloser(strings.NewReader("backup data")), nil).Once()
+		destination.On("UploadFile", mock.Anything, "eu-bucket", replica.Key, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				content, _ := io.ReadAll(args.Get(3).(io.Reader))
+				assert.Equal(t, "backup data", string(content))
+			}).
+			Return(&services.S3UploadResult{Bucket: "eu-bucket", Key: replica.Key, Size: 11}, nil).Once()
+
+		err := worker.HandleReplicateBackup(context.Background(), task)
+		require.NoError(t, err)
+
+		var stored models.BackupFileLocation
+		require.NoError(t, db.First(&stored, replica.ID).Error)
+		assert.Equal(t, models.BackupFileLocationStatusAvailable, stored.Status)
+		assert.Equal(t, 2, stored.Attempts)
+		assert.NotNil(t, stored.ReplicatedAt)
+		assert.Nil(t, stored.ErrorMessage)
+	})
+
+	t.Run("missing location is skipped", func(t *testing.T) {
+		payloadBytes, err := json.Marshal(ReplicateTaskPayload{BackupFileID: file.ID, LocationID: 999})
+		require.NoError(t, err)
+		assert.NoError(t, worker.HandleReplicateBackup(context.Background(), asynq.NewTask(TypeReplicateBackup, payloadBytes)))
+	})
+}
+
+func TestBackupWorker_Restore_FallsBackToReplica(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	primary := createTestStorageConfiguration(t, db, job.UserID, "primary-bucket")
+	failed := createTestStorageConfiguration(t, db, job.UserID, "failed-bucket")
+	secondary := createTestStorageConfiguration(t, db, job.UserID, "eu-bucket")
+	file := createTestBackupFile(t, db, job, primary)
+	createTestReplica(t, db, file, failed, models.BackupFileLocationStatusFailed)
+	createTestReplica(t, db, file, secondary, models.BackupFileLocationStatusAvailable)
+
+	primaryS3 := &MockS3Service{}
+	replicaS3 := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockBackupService := &MockBackupService{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, primary.ID).Return(primary, services.NewS3ObjectStore(primaryS3, primary.Bucket), nil)
+	mockStorage.On("ResolveStorageByID", mock.Anything, secondary.ID).Return(secondary, services.NewS3ObjectStore(replicaS3, secondary.Bucket), nil)
+	primaryS3.On("DownloadFile", mock.Anything, "primary-bucket", file.S3Key).Return(nil, errors.New("connection refused"))
+	replicaS3.On("DownloadFile", mock.Anything, "eu-bucket", file.S3Key).Return(io.NopCloser(strings.NewReader("backup data")), nil)
+	mockBackupService.On("RestorePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, &MockQueueService{}, nil)
+
+	payloadBytes, err := json.Marshal(RestoreTaskPayload{
+		RestoreJobID:  createTestRestoreJob(t, db, job, file.ID).ID,
+		BackupJobID:   job.ID,
+		UserID:        job.UserID,
+		DatabaseUID:   job.DatabaseConnection.UID,
+		BackupFileUID: file.UID,
+	})
+	require.NoError(t, err)
+
+	err = worker.HandleRestorePostgreSQL(context.Background(), asynq.NewTask(TypeRestorePostgreSQL, payloadBytes))
+	require.NoError(t, err)
+
+	replicaS3.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "ResolveStorageByID", mock.Anything, failed.ID)
+
+	// With every copy unavailable the restore fails
+	replicaS3.ExpectedCalls = nil
+	replicaS3.On("DownloadFile", mock.Anything, "eu-bucket", file.S3Key).Return(nil, errors.New("timeout"))
+	err = worker.HandleRestorePostgreSQL(context.Background(), asynq.NewTask(TypeRestorePostgreSQL, payloadBytes))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "all copies")
+}
+
+func TestBackupWorker_HandleCleanupBackups_DeletesReplicas(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	primary := createTestStorageConfiguration(t, db, job.UserID, "primary-bucket")
+	secondary := createTestStorageConfiguration(t, db, job.UserID, "eu-bucket")
+	file := createTestBackupFile(t, db, job, primary)
+	replica := createTestReplica(t, db, file, secondary, models.BackupFileLocationStatusAvailable)
+	require.NoError(t, db.Model(file).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+
+	primaryS3 := &MockS3Service{}
+	replicaS3 := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, primary.ID).Return(primary, services.NewS3ObjectStore(primaryS3, primary.Bucket), nil)
+	mockStorage.On("ResolveStorageByID", mock.Anything, secondary.ID).Return(secondary, services.NewS3ObjectStore(replicaS3, secondary.Bucket), nil)
+	primaryS3.On("DeleteFile", mock.Anything, "primary-bucket", file.S3Key).Return(nil)
+
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
+	task := asynq.NewTask(TypeCleanupBackups, []byte("{}"))
+
+	// A replica that can't be deleted keeps the record for the next run
+	replicaS3.On("DeleteFile", mock.Anything, "eu-bucket", replica.Key).Return(errors.New("access denied")).Once()
+	require.NoError(t, worker.HandleCleanupBackups(context.Background(), task))
+
+	var count int64
+	db.Model(&models.BackupFile{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	replicaS3.On("DeleteFile", mock.Anything, "eu-bucket", replica.Key).Return(nil).Once()
+	require.NoError(t, worker.HandleCleanupBackups(context.Background(), task))
+
+	db.Model(&models.BackupFile{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.BackupFileLocation{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	replicaS3.AssertExpectations(t)
+}
+
+func TestBackupWorker_ReplicateAndCleanup_LocalStorage(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	primary := createTestStorageConfiguration(t, db, job.UserID, "primary-bucket")
+	nfs := createTestStorageConfiguration(t, db, job.UserID, t.TempDir())
+	require.NoError(t, db.Model(nfs).Update("provider", models.StorageProviderLocal).Error)
+	file := createTestBackupFile(t, db, job, primary)
+	replica := createTestReplica(t, db, file, nfs, models.BackupFileLocationStatusPending)
+
+	localStore, err := services.NewLocalObjectStore(nfs.Bucket)
+	require.NoError(t, err)
+
+	source := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, primary.ID).Return(primary, services.NewS3ObjectStore(source, primary.Bucket), nil)
+	mockStorage.On("ResolveStorageByID", mock.Anything, nfs.ID).Return(nfs, localStore, nil)
+	source.On("DownloadFile", mock.Anything, "primary-bucket", file.S3Key).Return(io.NopCloser(strings.NewReader("backup data")), nil).Once()
+
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
+
+	payloadBytes, err := json.Marshal(ReplicateTaskPayload{BackupFileID: file.ID, LocationID: replica.ID})
+	require.NoError(t, err)
+	require.NoError(t, worker.HandleReplicateBackup(context.Background(), asynq.NewTask(TypeReplicateBackup, payloadBytes)))
+
+	var stored models.BackupFileLocation
+	require.NoError(t, db.First(&stored, replica.ID).Error)
+	assert.Equal(t, models.BackupFileLocationStatusAvailable, stored.Status)
+	require.NotNil(t, stored.Size)
+	assert.Equal(t, int64(11), *stored.Size)
+
+	info, err := localStore.Stat(context.Background(), replica.Key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), info.Size)
+
+	// Cleanup removes the copy from the local directory as well
+	require.NoError(t, db.Model(file).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+	source.On("DeleteFile", mock.Anything, "primary-bucket", file.S3Key).Return(nil)
+	require.NoError(t, worker.HandleCleanupBackups(context.Background(), asynq.NewTask(TypeCleanupBackups, []byte("{}"))))
+
+	_, err = localStore.Stat(context.Background(), replica.Key)
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+}
@@ -0,0 +1,159 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+)
+
+// ErrBackupNotQueued is returned when a backup job is no longer waiting in
+// the queue, so its lane cannot change
+var ErrBackupNotQueued = errors.New("backup job is no longer waiting in the queue")
+
+// Queues beside the backup lanes
+const (
+	// QueueDefault is asynq's default queue, which holds cleanups,
+	// notifications and webhook deliveries
+	QueueDefault = "default"
+	// QueueScheduled holds backups scheduled for later until they are due
+	QueueScheduled = "scheduled"
+	// QueueReplication holds copies of finished backups to replica storage
+	QueueReplication = "replication"
+)
+
+// Weights of the queues beside the backup lanes. Scheduled tasks only queue
+// their backup in its lane, so they are quick and should not wait; replicas
+// of finished backups can wait behind new backups.
+const (
+	defaultQueueWeight     = 4
+	scheduledQueueWeight   = 4
+	replicationQueueWeight = 1
+)
+
+// Queues returns the queues a worker processes with their weights: the
+// backup lanes and the queues of other tasks
+func Queues() map[string]int {
+	queues := map[string]int{
+		QueueDefault:     defaultQueueWeight,
+		QueueScheduled:   scheduledQueueWeight,
+		QueueReplication: replicationQueueWeight,
+	}
+	for lane, weight := range services.BackupLaneWeights {
+		queues[lane] = weight
+	}
+	return queues
+}
+
+// SetScheduler routes backups to priority lanes and spaces out tenants that
+// queue more than their fair share. Without it every backup goes to the
+// default lane as soon as it is queued.
+func (bw *BackupWorker) SetScheduler(scheduler *services.BackupScheduler) {
+	bw.scheduler = scheduler
+}
+
+// scheduleBackup picks the lane and start time of a backup job. It returns
+// nil when no scheduler is set.
+func (bw *BackupWorker) scheduleBackup(ctx context.Context, backupJobID uint) (*services.BackupSchedule, error) {
+	if bw.scheduler == nil {
+		return nil, nil
+	}
+
+	var backupJob models.BackupJob
+	if err := bw.db.WithContext(ctx).Preload("DatabaseConnection").First(&backupJob, backupJobID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load backup job: %w", err)
+	}
+
+	schedule, err := bw.scheduler.Schedule(ctx, &backupJob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule backup job: %w", err)
+	}
+	return schedule, nil
+}
+
+// enqueueBackupTask queues a backup task in its lane, holding it back until
+// its fair share start time when that is still to come
+func (bw *BackupWorker) enqueueBackupTask(ctx context.Context, jobType string, backupJobID uint, payload interface{}, defaults, options []services.JobOption) (*services.JobInfo, error) {
+	schedule, err := bw.scheduleBackup(ctx, backupJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	allOptions := append([]services.JobOption{}, defaults...)
+	if schedule != nil {
+		allOptions = append(allOptions, services.WithQueue(schedule.Lane))
+	}
+	allOptions = append(allOptions, options...)
+
+	if schedule != nil && schedule.ProcessAt.After(time.Now()) {
+		return bw.queueService.EnqueueScheduledJob(ctx, jobType, payload, schedule.ProcessAt, allOptions...)
+	}
+	return bw.queueService.EnqueueJob(ctx, jobType, payload, allOptions...)
+}
+
+// ReprioritizeBackupJob moves a waiting backup job to the lane of its current
+// priority. The caller saves the job, whose queue fields are updated. It
+// returns ErrBackupNotQueued when the job has already started.
+func (bw *BackupWorker) ReprioritizeBackupJob(ctx context.Context, backupJob *models.BackupJob) error {
+	// Backups scheduled for later pick their lane when they are due
+	if backupJob.IsScheduled && backupJob.QueueTaskID == "" {
+		return nil
+	}
+	if backupJob.QueueTaskID == "" {
+		return ErrBackupNotQueued
+	}
+
+	task, err := bw.queueService.GetJob(ctx, backupJob.QueueTaskID)
+	if err != nil {
+		return ErrBackupNotQueued
+	}
+	if task.State != services.JobStatePending && task.State != services.JobStateScheduled {
+		return ErrBackupNotQueued
+	}
+
+	lane := services.BackupLaneDefault
+	if bw.scheduler != nil {
+		if backupJob.DatabaseConnection.ID == 0 {
+			if err := bw.db.WithContext(ctx).First(&backupJob.DatabaseConnection, backupJob.DatabaseConnectionID).Error; err != nil {
+				return fmt.Errorf("failed to load database connection: %w", err)
+			}
+		}
+		if lane, err = bw.scheduler.Lane(ctx, backupJob); err != nil {
+			return err
+		}
+	}
+	if lane == task.Queue {
+		return nil
+	}
+
+	// Deleting fails once a worker has picked the task up
+	if err := bw.queueService.CancelJob(ctx, task.ID); err != nil {
+		return ErrBackupNotQueued
+	}
+
+	options := []services.JobOption{
+		services.WithQueue(lane),
+		services.WithMaxRetry(task.MaxRetry),
+		services.WithTimeout(task.Timeout),
+	}
+	var moved *services.JobInfo
+	if task.NextRunAt != nil && task.NextRunAt.After(time.Now()) {
+		moved, err = bw.queueService.EnqueueScheduledJob(ctx, task.Type, task.Payload, *task.NextRunAt, options...)
+	} else {
+		moved, err = bw.queueService.EnqueueJob(ctx, task.Type, task.Payload, options...)
+	}
+	if err != nil {
+		// The old task is gone, so the job would otherwise wait forever
+		backupJob.Fail(err.Error(), "ENQUEUE_FAILED")
+		return fmt.Errorf("failed to requeue backup job: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Backup job moved to another lane", "backup_job_id", backupJob.ID, "from", task.Queue, "to", moved.Queue)
+	backupJob.QueueName = moved.Queue
+	backupJob.QueueTaskID = moved.ID
+	return nil
+}
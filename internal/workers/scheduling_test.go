@@ -0,0 +1,271 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// optionQueue returns the queue job options select, the last one winning as in asynq
+func optionQueue(opts []services.JobOption) string {
+	info := &asynq.TaskInfo{}
+	for _, opt := range opts {
+		opt(info)
+	}
+	return info.Queue
+}
+
+func inQueue(queue string) interface{} {
+	return mock.MatchedBy(func(opts []services.JobOption) bool {
+		return optionQueue(opts) == queue
+	})
+}
+
+func TestQueues(t *testing.T) {
+	queues := Queues()
+	for lane, weight := range services.BackupLaneWeights {
+		assert.Equal(t, weight, queues[lane], lane)
+	}
+
+	assert.Equal(t, defaultQueueWeight, queues[QueueDefault])
+	assert.Equal(t, scheduledQueueWeight, queues[QueueScheduled])
+	assert.Equal(t, replicationQueueWeight, queues[QueueReplication])
+	assert.Len(t, queues, len(services.BackupLaneWeights)+3)
+}
+
+// TestQueues_ServesEveryEnqueuedQueue checks that every queue named in a
+// WithQueue call of the module is processed by the worker. Queues picked at
+// run time are backup lanes, which TestQueues covers.
+func TestQueues_ServesEveryEnqueuedQueue(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+
+	// String constants of the module, by name
+	constants := map[string]string{}
+	var calls []*ast.CallExpr
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "vendor" || strings.HasPrefix(d.Name(), ".")) && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.ValueSpec:
+				for i, name := range node.Names {
+					if i < len(node.Values) {
+						if lit, ok := node.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+							constants[name.Name], _ = strconv.Unquote(lit.Value)
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if name := callName(node.Fun); name == "WithQueue" && len(node.Args) == 1 {
+					calls = append(calls, node)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	served := Queues()
+	checked := 0
+	for _, call := range calls {
+		var queue string
+		switch arg := call.Args[0].(type) {
+		case *ast.BasicLit:
+			queue, _ = strconv.Unquote(arg.Value)
+		case *ast.Ident, *ast.SelectorExpr:
+			value, ok := constants[callName(arg)]
+			if !ok {
+				continue
+			}
+			queue = value
+		default:
+			continue
+		}
+		checked++
+		assert.Contains(t, served, queue, "queue used at %s is not served", fset.Position(call.Pos()))
+	}
+	assert.NotZero(t, checked)
+}
+
+// callName returns the name of an identifier or the selected name of a selector
+func callName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
+func TestBackupWorker_EnqueueBackupJobRoutesToLane(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	require.NoError(t, db.Model(job).Update("priority", 10).Error)
+
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+	worker.SetScheduler(services.NewBackupScheduler(db, services.NewLocalFairClock(), services.BackupSchedulingConfig{
+		Slot:  time.Minute,
+		Burst: 1,
+	}))
+
+	payload := &BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID}
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, payload, inQueue(services.BackupLaneCritical)).
+		Return(&services.JobInfo{ID: "now", Queue: services.BackupLaneCritical}, nil).Twice()
+	mockQueueService.On("EnqueueScheduledJob", mock.Anything, TypeBackupPostgreSQL, payload, mock.MatchedBy(func(processAt time.Time) bool {
+		return processAt.After(time.Now().Add(50 * time.Second))
+	}), inQueue(services.BackupLaneCritical)).Return(&services.JobInfo{ID: "later", Queue: services.BackupLaneCritical}, nil).Once()
+
+	ctx := context.Background()
+
+	// The burst starts right away, further backups of the tenant are held back
+	for _, expected := range []string{"now", "now", "later"} {
+		info, err := worker.EnqueueBackupJob(ctx, TypeBackupPostgreSQL, payload)
+		require.NoError(t, err)
+		assert.Equal(t, expected, info.ID)
+	}
+	mockQueueService.AssertExpectations(t)
+
+	// Callers can still pick a queue
+	mockQueueService.On("EnqueueScheduledJob", mock.Anything, TypeBackupPostgreSQL, payload, mock.Anything, inQueue("maintenance")).
+		Return(&services.JobInfo{ID: "custom"}, nil).Once()
+	info, err := worker.EnqueueBackupJob(ctx, TypeBackupPostgreSQL, payload, services.WithQueue("maintenance"))
+	require.NoError(t, err)
+	assert.Equal(t, "custom", info.ID)
+}
+
+func TestBackupWorker_ReprioritizeBackupJob(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	job.QueueName = services.BackupLaneDefault
+	job.QueueTaskID = "task-1"
+	job.Priority = 10
+
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+	worker.SetScheduler(services.NewBackupScheduler(db, services.NewLocalFairClock(), services.BackupSchedulingConfig{}))
+
+	payload := map[string]interface{}{"backup_job_id": float64(job.ID)}
+	mockQueueService.On("GetJob", mock.Anything, "task-1").Return(&services.JobInfo{
+		ID:       "task-1",
+		Type:     TypeBackupPostgreSQL,
+		Payload:  payload,
+		State:    services.JobStatePending,
+		Queue:    services.BackupLaneDefault,
+		MaxRetry: 3,
+		Timeout:  30 * time.Minute,
+	}, nil)
+	mockQueueService.On("CancelJob", mock.Anything, "task-1").Return(nil).Once()
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, payload, inQueue(services.BackupLaneCritical)).
+		Return(&services.JobInfo{ID: "task-2", Queue: services.BackupLaneCritical}, nil).Once()
+
+	ctx := context.Background()
+	require.NoError(t, worker.ReprioritizeBackupJob(ctx, job))
+	assert.Equal(t, services.BackupLaneCritical, job.QueueName)
+	assert.Equal(t, "task-2", job.QueueTaskID)
+	mockQueueService.AssertExpectations(t)
+
+	// Jobs already in the lane of their priority stay where they are
+	mockQueueService.On("GetJob", mock.Anything, "task-2").Return(&services.JobInfo{
+		ID:    "task-2",
+		State: services.JobStatePending,
+		Queue: services.BackupLaneCritical,
+	}, nil)
+	require.NoError(t, worker.ReprioritizeBackupJob(ctx, job))
+	assert.Equal(t, "task-2", job.QueueTaskID)
+}
+
+func TestBackupWorker_ReprioritizeStartedBackupJob(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	job.Priority = 10
+
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+	ctx := context.Background()
+
+	// Never queued
+	assert.ErrorIs(t, worker.ReprioritizeBackupJob(ctx, job), ErrBackupNotQueued)
+
+	// Picked up by a worker
+	job.QueueTaskID = "active"
+	mockQueueService.On("GetJob", mock.Anything, "active").Return(&services.JobInfo{ID: "active", State: services.JobStateActive}, nil)
+	assert.ErrorIs(t, worker.ReprioritizeBackupJob(ctx, job), ErrBackupNotQueued)
+
+	// Picked up between looking and moving it
+	job.QueueTaskID = "racing"
+	mockQueueService.On("GetJob", mock.Anything, "racing").Return(&services.JobInfo{
+		ID:    "racing",
+		State: services.JobStatePending,
+		Queue: services.BackupLaneLow,
+	}, nil)
+	mockQueueService.On("CancelJob", mock.Anything, "racing").Return(errors.New("task is active"))
+	assert.ErrorIs(t, worker.ReprioritizeBackupJob(ctx, job), ErrBackupNotQueued)
+	assert.Equal(t, "racing", job.QueueTaskID)
+	mockQueueService.AssertNotCalled(t, "EnqueueJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBackupWorker_ScheduledBackupRunsInLane(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	require.NoError(t, db.Model(job).Updates(map[string]interface{}{"priority": 2, "is_scheduled": true}).Error)
+
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, &MockBackupService{}, &MockStorageResolver{}, mockQueueService, nil)
+	worker.SetScheduler(services.NewBackupScheduler(db, services.NewLocalFairClock(), services.BackupSchedulingConfig{}))
+
+	// While it waits for its time the job has no lane, so its priority just changes
+	require.NoError(t, worker.ReprioritizeBackupJob(context.Background(), job))
+
+	// When due, the job created with the request is queued in the lane of its priority
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, mock.MatchedBy(func(p BackupTaskPayload) bool {
+		return p.BackupJobID == job.ID
+	}), inQueue(services.BackupLaneLow)).Return(&services.JobInfo{ID: "task-1", Queue: services.BackupLaneLow}, nil).Once()
+
+	payloadBytes, err := json.Marshal(BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID})
+	require.NoError(t, err)
+	require.NoError(t, worker.HandleScheduledBackup(context.Background(), asynq.NewTask(TypeScheduledBackup, payloadBytes)))
+	mockQueueService.AssertExpectations(t)
+
+	var jobs []models.BackupJob
+	require.NoError(t, db.Find(&jobs).Error)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, services.BackupLaneLow, jobs[0].QueueName)
+	assert.Equal(t, "task-1", jobs[0].QueueTaskID)
+
+	// A cancelled job is not started when its time comes
+	require.NoError(t, db.Model(job).Update("status", models.BackupStatusCancelled).Error)
+	require.NoError(t, worker.HandleScheduledBackup(context.Background(), asynq.NewTask(TypeScheduledBackup, payloadBytes)))
+	mockQueueService.AssertNumberOfCalls(t, "EnqueueJob", 1)
+}
@@ -0,0 +1,58 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/hibiken/asynq"
+)
+
+// Drain marks the worker as shutting down. Backups cancelled from then on
+// are checkpointed as pending rather than failed, since asynq requeues their
+// tasks for another worker.
+func (bw *BackupWorker) Drain() {
+	bw.draining.Store(true)
+}
+
+// Wait blocks until every job handler has returned, so checkpoints are
+// written before the database closes. Call it after the queue worker shut down.
+func (bw *BackupWorker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		bw.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track counts a handler as in flight while it runs
+func (bw *BackupWorker) track(handler func(context.Context, *asynq.Task) error) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		bw.inFlight.Add(1)
+		defer bw.inFlight.Done()
+		return handler(ctx, task)
+	}
+}
+
+// interrupted checkpoints a backup cut short by the worker shutting down and
+// reports whether it did
+func (bw *BackupWorker) interrupted(ctx context.Context, backupJob *models.BackupJob) bool {
+	if !bw.draining.Load() || ctx.Err() == nil {
+		return false
+	}
+
+	backupJob.Interrupt()
+	if err := bw.db.Save(backupJob).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to checkpoint interrupted backup job", "backup_job_id", backupJob.ID, "error", err)
+	}
+	bw.sendBackupProgressUpdate(backupJob)
+	slog.WarnContext(ctx, "Backup job interrupted by shutdown, it resumes on the next worker", "backup_job_id", backupJob.ID)
+	return true
+}
@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// runCancelledBackup starts a backup whose dump runs until its context is
+// cancelled, cancels it after calling beforeCancel and returns the handler's error
+func runCancelledBackup(t *testing.T, worker *BackupWorker, backupService *MockBackupService, job *models.BackupJob, beforeCancel func()) error {
+	started := make(chan struct{})
+	backupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(nil, context.Canceled)
+
+	payload, err := json.Marshal(BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID})
+	require.NoError(t, err)
+	task := asynq.NewTask(TypeBackupPostgreSQL, payload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	handler := worker.track(worker.HandleBackupPostgreSQL)
+	go func() { errs <- handler(ctx, task) }()
+
+	<-started
+	beforeCancel()
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	require.NoError(t, worker.Wait(waitCtx))
+	return <-errs
+}
+
+func TestBackupWorker_CheckpointsBackupInterruptedByShutdown(t *testing.T) {
+	db := setupTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	backupService := &MockBackupService{}
+	worker := NewBackupWorker(db, backupService, &MockStorageResolver{}, &MockQueueService{}, nil)
+
+	err = runCancelledBackup(t, worker, backupService, job, worker.Drain)
+	assert.ErrorContains(t, err, "interrupted")
+
+	var interrupted models.BackupJob
+	require.NoError(t, db.First(&interrupted, job.ID).Error)
+	assert.Equal(t, models.BackupStatusPending, interrupted.Status)
+	assert.Nil(t, interrupted.StartedAt)
+	assert.Nil(t, interrupted.ErrorCode)
+	assert.Contains(t, interrupted.CurrentStep, "Interrupted")
+}
+
+func TestBackupWorker_CancelledBackupFailsWhenNotDraining(t *testing.T) {
+	db := setupTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	backupService := &MockBackupService{}
+	worker := NewBackupWorker(db, backupService, &MockStorageResolver{}, &MockQueueService{}, nil)
+
+	err = runCancelledBackup(t, worker, backupService, job, func() {})
+	assert.ErrorContains(t, err, "backup failed")
+
+	var failed models.BackupJob
+	require.NoError(t, db.First(&failed, job.ID).Error)
+	assert.Equal(t, models.BackupStatusFailed, failed.Status)
+}
+
+func TestBackupWorker_WaitGivesUp(t *testing.T) {
+	worker := NewBackupWorker(setupTestDB(t), &MockBackupService{}, &MockStorageResolver{}, &MockQueueService{}, nil)
+
+	release := make(chan struct{})
+	defer close(release)
+	running := make(chan struct{})
+	handler := worker.track(func(ctx context.Context, task *asynq.Task) error {
+		close(running)
+		<-release
+		return nil
+	})
+	go handler(context.Background(), asynq.NewTask(TypeCleanupBackups, nil))
+	<-running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, worker.Wait(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,39 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traced runs a handler in a span continuing the trace of whoever queued the
+// task, taken from the trace context of its payload
+func traced(handler func(context.Context, *asynq.Task) error) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		var carrier struct {
+			TraceContext map[string]string `json:"trace_context"`
+		}
+		// A payload the handler cannot read fails there, not here
+		_ = json.Unmarshal(task.Payload(), &carrier)
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		retryCount, _ := asynq.GetRetryCount(ctx)
+		ctx, span := tracing.Start(tracing.Extract(ctx, carrier.TraceContext), task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "asynq"),
+				attribute.String("messaging.operation", "process"),
+				attribute.String("messaging.message.id", taskID),
+				attribute.Int("messaging.retry_count", retryCount),
+			),
+		)
+
+		err := handler(ctx, task)
+		tracing.End(span, err)
+		return err
+	}
+}
@@ -0,0 +1,82 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/dbackup/backend-go/internal/tracing"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestBackupWorker_TracesBackupAcrossQueue(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	db := setupTestDB(t)
+	require.NoError(t, db.Use(tracing.GormPlugin()))
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "user-bucket")
+
+	dumpPath := filepath.Join(t.TempDir(), "test.backup")
+	require.NoError(t, os.WriteFile(dumpPath, []byte("dump"), 0o600))
+
+	mockBackupService := &MockBackupService{}
+	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockQueueService := &MockQueueService{}
+	worker := NewBackupWorker(db, mockBackupService, mockStorage, mockQueueService, nil)
+	worker.SetBackupCipher(testBackupCipher())
+
+	mockBackupService.On("CreatePostgreSQLBackup", mock.Anything, mock.Anything, mock.Anything).Return(&services.BackupResult{FilePath: dumpPath, OriginalSize: 4}, nil)
+	mockStorage.On("ResolveStorage", mock.Anything, storage.UID, job.UserID, (*uint)(nil)).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("UploadFile", mock.Anything, "user-bucket", mock.Anything, mock.Anything, mock.Anything).Return(&services.S3UploadResult{Bucket: "user-bucket", Key: "backups/test.backup"}, nil)
+	mockQueueService.On("EnqueueJob", mock.Anything, TypeBackupPostgreSQL, mock.Anything, mock.Anything).Return(&services.JobInfo{ID: "task-1"}, nil)
+
+	// The request queues the backup within its trace
+	requestCtx, request := tracing.Start(context.Background(), "POST /api/backups")
+	payload := &BackupTaskPayload{BackupJobID: job.ID, UserID: job.UserID, DatabaseUID: job.DatabaseConnection.UID, StorageUID: storage.UID}
+	_, err := worker.EnqueueBackupJob(requestCtx, TypeBackupPostgreSQL, payload)
+	require.NoError(t, err)
+	request.End()
+	require.NotEmpty(t, payload.TraceContext)
+
+	// The worker picks it up from the queue
+	payloadBytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.NoError(t, traced(worker.HandleBackupPostgreSQL)(context.Background(), asynq.NewTask(TypeBackupPostgreSQL, payloadBytes)))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	parents := map[string][]trace.SpanID{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, request.SpanContext().TraceID(), span.SpanContext().TraceID(), span.Name())
+		spans[span.Name()] = span
+		parents[span.Name()] = append(parents[span.Name()], span.Parent().SpanID())
+	}
+
+	consumer := spans[TypeBackupPostgreSQL]
+	require.NotNil(t, consumer)
+	assert.Equal(t, trace.SpanKindConsumer, consumer.SpanKind())
+	assert.Equal(t, spans["backup.enqueue"].SpanContext().SpanID(), consumer.Parent().SpanID())
+	for _, phase := range []string{"backup.load_job", "backup.dump", "backup.upload", "backup.record"} {
+		require.Contains(t, spans, phase)
+		assert.Equal(t, consumer.SpanContext().SpanID(), spans[phase].Parent().SpanID(), phase)
+	}
+	assert.Contains(t, parents["gorm.select"], spans["backup.load_job"].SpanContext().SpanID())
+	assert.Contains(t, parents["gorm.create"], spans["backup.record"].SpanContext().SpanID())
+}
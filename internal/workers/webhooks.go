@@ -0,0 +1,115 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+)
+
+// WebhookPublisher queues backup lifecycle events to team webhook endpoints
+type WebhookPublisher interface {
+	Publish(ctx context.Context, event models.WebhookEvent, job *models.BackupJob, file *models.BackupFile) error
+}
+
+// WebhookDeliverer sends one queued webhook delivery
+type WebhookDeliverer interface {
+	Deliver(ctx context.Context, deliveryID uint, final bool) error
+}
+
+// WebhookWorker runs the queue tasks delivering webhooks
+type WebhookWorker struct {
+	deliverer WebhookDeliverer
+}
+
+// NewWebhookWorker creates a new webhook worker
+func NewWebhookWorker(deliverer WebhookDeliverer) *WebhookWorker {
+	return &WebhookWorker{deliverer: deliverer}
+}
+
+// RegisterHandlers registers the webhook delivery handler
+func (ww *WebhookWorker) RegisterHandlers(worker *services.QueueWorker) {
+	worker.RegisterHandler(services.WebhookTaskType, traced(logged(ww.HandleDeliverWebhook)))
+}
+
+// HandleDeliverWebhook sends a webhook delivery. A failed send returns its
+// error so the queue retries it with exponential backoff; the last attempt
+// marks the delivery failed.
+func (ww *WebhookWorker) HandleDeliverWebhook(ctx context.Context, task *asynq.Task) error {
+	var payload services.WebhookTaskPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	return ww.deliverer.Deliver(ctx, payload.DeliveryID, finalAttempt(ctx))
+}
+
+// SetWebhooks publishes backup and restore events to team webhooks. Without
+// it none are published.
+func (bw *BackupWorker) SetWebhooks(p WebhookPublisher) {
+	bw.webhooks = p
+}
+
+// publishJob publishes the lifecycle event of a backup. Like notifications,
+// a failed backup is only published once it is given up on.
+func (bw *BackupWorker) publishJob(ctx context.Context, backupJob *models.BackupJob) {
+	if bw.webhooks == nil {
+		return
+	}
+
+	var event models.WebhookEvent
+	var file *models.BackupFile
+	switch {
+	case backupJob.Status == models.BackupStatusRunning:
+		event = models.WebhookBackupStarted
+	case backupJob.Status == models.BackupStatusCompleted:
+		event = models.WebhookBackupCompleted
+		file = bw.latestBackupFile(ctx, backupJob)
+	case backupJob.Status == models.BackupStatusFailed:
+		if !failureFinal(ctx, backupJob) {
+			return
+		}
+		event = models.WebhookBackupFailed
+	default:
+		return
+	}
+
+	bw.publish(ctx, event, backupJob, file)
+}
+
+// publishRestore publishes the completion of a restore of a backup
+func (bw *BackupWorker) publishRestore(ctx context.Context, backupJob *models.BackupJob, restoreJob *models.RestoreJob) {
+	if bw.webhooks == nil || restoreJob.Status != models.BackupStatusCompleted {
+		return
+	}
+	bw.publish(ctx, models.WebhookRestoreCompleted, backupJob, nil)
+}
+
+// publishFileDeleted publishes the deletion of an expired backup file
+func (bw *BackupWorker) publishFileDeleted(ctx context.Context, file *models.BackupFile) {
+	if bw.webhooks == nil {
+		return
+	}
+	bw.publish(ctx, models.WebhookBackupFileDeleted, nil, file)
+}
+
+// latestBackupFile loads the file a completed backup produced, if any
+func (bw *BackupWorker) latestBackupFile(ctx context.Context, backupJob *models.BackupJob) *models.BackupFile {
+	var file models.BackupFile
+	if err := bw.db.WithContext(ctx).Where("backup_job_id = ?", backupJob.ID).Order("id DESC").First(&file).Error; err != nil {
+		slog.WarnContext(ctx, "Failed to load backup file for webhook", "backup_job_id", backupJob.ID, "error", err)
+		return nil
+	}
+	return &file
+}
+
+// publish queues a webhook event, logging rather than failing the job on errors
+func (bw *BackupWorker) publish(ctx context.Context, event models.WebhookEvent, backupJob *models.BackupJob, file *models.BackupFile) {
+	if err := bw.webhooks.Publish(ctx, event, backupJob, file); err != nil {
+		slog.WarnContext(ctx, "Failed to publish webhook event", "event", event, "error", err)
+	}
+}
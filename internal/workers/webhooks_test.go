@@ -0,0 +1,154 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbackup/backend-go/internal/models"
+	"github.com/dbackup/backend-go/internal/services"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type publishedEvent struct {
+	event models.WebhookEvent
+	job   *models.BackupJob
+	file  *models.BackupFile
+}
+
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []publishedEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event models.WebhookEvent, job *models.BackupJob, file *models.BackupFile) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, publishedEvent{event: event, job: job, file: file})
+	return nil
+}
+
+func (p *recordingPublisher) events() []models.WebhookEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var events []models.WebhookEvent
+	for _, published := range p.published {
+		events = append(events, published.event)
+	}
+	return events
+}
+
+func TestWebhookWorker_HandleDeliverWebhook(t *testing.T) {
+	deliverer := &recordingDeliverer{}
+	worker := NewWebhookWorker(deliverer)
+
+	payload, err := json.Marshal(services.WebhookTaskPayload{DeliveryID: 7})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.HandleDeliverWebhook(context.Background(), asynq.NewTask(services.WebhookTaskType, payload)))
+	assert.Equal(t, uint(7), deliverer.deliveryID)
+	assert.True(t, deliverer.final)
+
+	deliverer.err = errors.New("endpoint returned 500")
+	assert.ErrorIs(t, worker.HandleDeliverWebhook(context.Background(), asynq.NewTask(services.WebhookTaskType, payload)), deliverer.err)
+
+	err = worker.HandleDeliverWebhook(context.Background(), asynq.NewTask(services.WebhookTaskType, []byte("[")))
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+}
+
+func TestBackupWorker_PublishJob(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.BackupStatus
+		want   []models.WebhookEvent
+	}{
+		{"backup pending", models.BackupStatusPending, nil},
+		{"backup running", models.BackupStatusRunning, []models.WebhookEvent{models.WebhookBackupStarted}},
+		{"backup failed", models.BackupStatusFailed, []models.WebhookEvent{models.WebhookBackupFailed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &recordingPublisher{}
+			worker := NewBackupWorker(nil, nil, nil, nil, nil)
+			worker.SetWebhooks(publisher)
+
+			job := &models.BackupJob{Name: "Nightly", Status: tt.status, UserID: 7, DatabaseConnectionID: 3}
+			worker.publishJob(context.Background(), job)
+			assert.Equal(t, tt.want, publisher.events())
+		})
+	}
+}
+
+func TestBackupWorker_PublishRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.BackupStatus
+		want   []models.WebhookEvent
+	}{
+		{"restore running", models.BackupStatusRunning, nil},
+		{"restore completed", models.BackupStatusCompleted, []models.WebhookEvent{models.WebhookRestoreCompleted}},
+		{"restore failed", models.BackupStatusFailed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &recordingPublisher{}
+			worker := NewBackupWorker(nil, nil, nil, nil, nil)
+			worker.SetWebhooks(publisher)
+
+			job := &models.BackupJob{Name: "Nightly", Status: models.BackupStatusCompleted, UserID: 7, DatabaseConnectionID: 3}
+			worker.publishRestore(context.Background(), job, &models.RestoreJob{Status: tt.status, UserID: 7})
+			assert.Equal(t, tt.want, publisher.events())
+		})
+	}
+}
+
+func TestBackupWorker_PublishesCompletedBackupWithFile(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "backups")
+	file := createTestBackupFile(t, db, job, storage)
+
+	publisher := &recordingPublisher{}
+	worker := NewBackupWorker(db, nil, nil, nil, nil)
+	worker.SetWebhooks(publisher)
+
+	job.Status = models.BackupStatusCompleted
+	worker.publishJob(context.Background(), job)
+	require.Equal(t, []models.WebhookEvent{models.WebhookBackupCompleted}, publisher.events())
+	require.NotNil(t, publisher.published[0].file)
+	assert.Equal(t, file.ID, publisher.published[0].file.ID)
+}
+
+func TestBackupWorker_PublishesDeletedBackupFiles(t *testing.T) {
+	db := setupTestDB(t)
+	job := createTestBackupJob(t, db, models.DatabaseTypePostgreSQL)
+	storage := createTestStorageConfiguration(t, db, job.UserID, "cleanup-bucket")
+	file := createTestBackupFile(t, db, job, storage)
+	require.NoError(t, db.Model(file).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+
+	mockS3Service := &MockS3Service{}
+	mockStorage := &MockStorageResolver{}
+	mockStorage.On("ResolveStorageByID", mock.Anything, storage.ID).Return(storage, services.NewS3ObjectStore(mockS3Service, storage.Bucket), nil)
+	mockS3Service.On("DeleteFile", mock.Anything, "cleanup-bucket", file.S3Key).Return(nil)
+
+	publisher := &recordingPublisher{}
+	worker := NewBackupWorker(db, &MockBackupService{}, mockStorage, &MockQueueService{}, nil)
+	worker.SetWebhooks(publisher)
+
+	require.NoError(t, worker.HandleCleanupBackups(context.Background(), asynq.NewTask(TypeCleanupBackups, []byte("{}"))))
+	require.Equal(t, []models.WebhookEvent{models.WebhookBackupFileDeleted}, publisher.events())
+
+	deleted := publisher.published[0]
+	assert.Nil(t, deleted.job)
+	require.NotNil(t, deleted.file)
+	assert.Equal(t, file.UID, deleted.file.UID)
+	assert.Equal(t, job.DatabaseConnection.UID, deleted.file.BackupJob.DatabaseConnection.UID, "the team is found through the job's connection")
+}
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations so binaries run the
+// migrations they were built with, wherever they are started from.
+package migrations
+
+import "embed"
+
+// FS holds the SQL migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -58,7 +58,7 @@ func setupTestAuthServer() *echo.Echo {
 	totpManager := auth.NewTOTPManager("dbackup-test")
 
 	// Setup auth routes
-	routes.SetupAuthRoutes(e, jwtManager, passwordHasher, totpManager)
+	routes.SetupAuthRoutes(e, jwtManager, passwordHasher, totpManager, nil)
 
 	return e
 }
@@ -56,7 +56,7 @@ func setupTestTwoFAServer() *echo.Echo {
 	totpManager := auth.NewTOTPManager("dbackup-test")
 
 	// Setup auth routes (includes 2FA routes)
-	routes.SetupAuthRoutes(e, jwtManager, passwordHasher, totpManager)
+	routes.SetupAuthRoutes(e, jwtManager, passwordHasher, totpManager, nil)
 
 	return e
 }